@@ -0,0 +1,722 @@
+// Typed client for the deep-research web server's API, following
+// pkg/server/openapi.json (served at /api/openapi.json). Dependency-free: it
+// needs only fetch, so it runs in browsers, Node 18+, Deno, and Bun.
+
+export interface ErrorResponse {
+  error: { code: string; message: string };
+}
+
+export interface StatusResponse {
+  status: string;
+}
+
+export interface ResearchRequest {
+  topic: string;
+  loops?: number;
+  parallel?: number;
+  contextLen?: number;
+  deepMode?: boolean;
+  resultLinks?: boolean;
+  minResults?: number;
+  delayMs?: number;
+  simpleMode?: boolean;
+  maxPages?: number;
+  extractionSchema?: string;
+  extractEntities?: boolean;
+  findConflicts?: boolean;
+  collectImages?: boolean;
+  evaluateReport?: boolean;
+  reviseBelow?: number;
+  singlePassReport?: boolean;
+  dedupThreshold?: number;
+  queryDedup?: number;
+  relevanceFilter?: "" | "keywords" | "llm"; // Drop search results unrelated to the topic
+  currency?: string; // Convert the prices found to this currency, e.g. "EUR"
+  rates?: "" | "ecb"; // Exchange rates for currency: built-in or the ECB's daily rates
+  geoArea?: string; // Flag or drop listings outside this place, e.g. "Cluj-Napoca, Romania"
+  geoRadius?: number; // km from geoArea's center (0 = the place's bounds)
+  geoFilter?: "" | "flag" | "drop"; // What happens to listings outside geoArea (default flag)
+  fixedQueryOrder?: boolean; // Search the planned queries in order instead of by yield
+  archiveSources?: boolean; // Save every fetched page's text under results/<job id>/sources/
+  archiveHtml?: boolean; // Also save each page as downloaded; implies archiveSources
+  autoApprove?: boolean;
+  askQuestions?: boolean; // Wait in "awaiting_answers" for answer() before planning
+  seedUrls?: string[];
+  followLinks?: boolean;
+  nextPages?: number; // Deep mode or followLinks: also read this many next pages of each listing (0 = off, at most 50)
+  crawlDepth?: number; // Follow in-domain next, pagination, and category links this many hops (0 = off, at most 5)
+  crawlPages?: number; // crawlDepth: most item pages collected per start page (0 = 30)
+  listingsPerQuery?: number; // Deep mode: most listing pages read per search query (0 = 5)
+  sitemapSites?: string[];
+  sitemapPattern?: string;
+  sitemapPages?: number;
+  compare?: string[];
+  includeDomains?: string[];
+  excludeDomains?: string[];
+  categories?: string[];
+  searxEngines?: string[];
+  timeRange?: "" | "day" | "week" | "month" | "year";
+  maxAgeDays?: number;
+  profile?: string;
+  planningPrompt?: string;
+  reportStructure?: string;
+  reportTemplate?: string;
+  reportLanguage?: string;
+  maxLlmCalls?: number;
+  maxHttpRequests?: number;
+  maxMinutes?: number;
+  reserveMinutes?: number;
+  noCache?: boolean;
+  collection?: string;
+  onlyNew?: boolean;
+  documents?: string[]; // IDs from uploadDocuments
+  callSettings?: Partial<Record<CallType, CallSettings>>;
+}
+
+export type CallType = "planning" | "summarization" | "compression" | "report";
+
+export interface CallSettings {
+  temperature?: number; // 0-2 (default: 0)
+  maxTokens?: number; // 0 = the backend's
+  systemPrompt?: string; // Replaces the built-in system prompt
+}
+
+export type JobStatus =
+  | "idle"
+  | "queued"
+  | "planning"
+  | "awaiting_answers"
+  | "awaiting_approval"
+  | "running"
+  | "complete"
+  | "error"
+  | "cancelled"
+  | "interrupted";
+
+export interface ResearchJob {
+  id: string;
+  topic: string;
+  status: JobStatus;
+  progress: ProgressEvent;
+  questions?: string[]; // awaiting_answers: the planner's clarifying questions
+  plan?: ResearchPlan;
+  result?: ResearchResult;
+  error?: string;
+  startedAt: string;
+  config: ResearchRequest;
+}
+
+export interface QueuedJob extends ResearchJob {
+  position: number; // 1 = next to start
+}
+
+export interface QuestionAnswer {
+  question: string;
+  answer: string;
+}
+
+export interface ComparisonPlan {
+  criteria: string[];
+  entities: { name: string; queries: string[] }[];
+}
+
+export interface SitemapPlan {
+  pattern: string;
+  found: number;
+  matched: number;
+  pages: string[];
+}
+
+export interface ResearchPlan {
+  clarifying_questions: string[];
+  understanding_summary: string;
+  research_steps: string[];
+  expected_outcome: string;
+  search_queries?: string[];
+  answers?: QuestionAnswer[];
+  comparison?: ComparisonPlan;
+  sitemap?: SitemapPlan;
+}
+
+export interface AnswerRequest {
+  answers: string[]; // One per clarifying question, in order ("" = unanswered)
+  feedback?: string;
+}
+
+export interface ProgressEvent {
+  phase: string; // e.g. "planning", "searching", "writing_report", "report_chunk", "complete", "error", "cancelled"
+  round: number;
+  totalRounds: number;
+  urlsFound: number;
+  targetURLs: number;
+  message: string;
+  percent: number;
+  errors: string[] | null;
+  errorCount: number;
+  step?: "search" | "fetch" | "summarize" | "query_done";
+  query?: string;
+  queryIndex?: number;
+  totalQueries?: number;
+  page?: number;
+  url?: string;
+  duplicates?: number;
+  remainingQueries?: number;
+  etaSeconds?: number;
+  stat?: QueryStat; // query_done: what the query yielded
+  tokens?: number; // Prompt and completion tokens used so far
+  cost?: number; // USD so far, at the server's --prompt-price and --completion-price
+  chunk?: string; // report_chunk: Markdown to append to the report streamed so far
+  restart?: boolean; // report_chunk: discard the report streamed so far
+}
+
+export interface JobEvent extends ProgressEvent {
+  jobId?: string;
+  seq: number; // Position in the job's event log (1 = first)
+}
+
+export interface Source {
+  Title: string;
+  URL: string;
+  Snippet?: string;
+  Summary?: string;
+  FetchedAt?: string;
+  ArchiveURL?: string;
+  Published?: string;
+  Document?: boolean; // Provided by the user (POST /api/documents), not a web page
+  Price?: { Value: number; Currency: string }; // Its price in the run's currency (request's currency)
+  Location?: { Address: string; Place: string; Lat: number; Lon: number; Distance: number; Outside?: boolean }; // Where its listing is (request's geoArea)
+}
+
+export interface CitationCheck {
+  Cited?: number[];
+  InvalidRefs?: number[];
+  UnknownURLs?: string[];
+}
+
+export interface ReportClaim {
+  Text: string;
+  Section?: string;
+  Sources: number[]; // 1-based positions in Sources
+  SourceURLs: string[];
+}
+
+export interface Usage {
+  LLMCalls: number;
+  HTTPRequests: number;
+  Duration: number; // Nanoseconds
+  Exhausted?: string;
+  QueriesSkipped?: number;
+  DeadEndQueries?: number; // Skipped once the research stalled, their patterns having found nothing new
+  PromptTokens?: number; // Whole run, planning and report included
+  CompletionTokens?: number;
+  Tokens?: Record<string, TokenUsage>; // By call type: planning, summarization, compression, report
+  Cost?: number; // USD at the server's --prompt-price and --completion-price
+}
+
+export interface TokenUsage {
+  PromptTokens: number;
+  CompletionTokens: number;
+}
+
+export interface Comparison {
+  Entities: string[];
+  Criteria: string[];
+  Cells: string[][]; // Cells[i][j] is criterion i for entity j
+}
+
+export interface KnownSource {
+  URL: string;
+  Title: string;
+  Summary?: string;
+  Record?: Record<string, unknown>;
+  FirstSeen: string;
+  LastSeen: string;
+  JobID?: string;
+}
+
+export interface Changes {
+  Collection: string;
+  Since: string;
+  New: number[] | null;
+  Gone: KnownSource[] | null;
+  Updated: { Source: number; Field: string; Old: string; New: string }[] | null;
+}
+
+export interface Entity {
+  Name: string;
+  Type: "person" | "company" | "organization" | "product" | "location";
+  Sources?: number[];
+}
+
+export interface Relation {
+  From: string;
+  To: string;
+  Type: string;
+  Sources?: number[];
+}
+
+export interface Claim {
+  Value: string;
+  Sources: number[];
+}
+
+export interface Fact {
+  Fact: string;
+  Claims: Claim[]; // Most cited first; more than one means the sources disagree
+}
+
+export interface QueryStat {
+  Query: string;
+  Results: number;
+  NewURLs: number;
+  Duplicates: number;
+  Pages: number;
+  Errors: number;
+  Irrelevant: number; // Dropped as unrelated to the topic (relevanceFilter)
+}
+
+export interface Evaluation {
+  Score: number; // Scores are out of 10
+  Coverage: number;
+  Specificity: number;
+  Citations: number;
+  CitationDensity: number; // Citations per 100 words
+  Gaps?: string[];
+  Revised: boolean;
+  InitialScore?: number;
+  Queries?: string[];
+}
+
+export interface Image {
+  URL: string;
+  PageURL: string;
+  Alt: string;
+}
+
+export interface ResearchResult {
+  Report: string; // Markdown
+  Sources: Source[] | null;
+  Records: Record<string, unknown>[] | null;
+  RecordFields?: string[];
+  Citations: CitationCheck;
+  Claims?: ReportClaim[];
+  Usage: Usage;
+  Comparison?: Comparison;
+  Changes?: Changes;
+  Entities?: Entity[];
+  Relations?: Relation[];
+  Conflicts?: Fact[];
+  Consensus?: Fact[];
+  QueryStats?: QueryStat[];
+  Images?: Image[];
+  Evaluation?: Evaluation;
+  Templated?: boolean;
+}
+
+export interface Draft {
+  Report: string;
+  Sources: Source[] | null;
+  Round: number;
+  TotalRounds: number;
+  Citations: CitationCheck;
+  WrittenAt: string;
+}
+
+export interface FollowUpRequest {
+  question: string;
+  id?: string;
+  maxSearches?: number | null; // Omitted = the server default, 0 = none
+}
+
+export interface FollowUpAnswer {
+  Question: string;
+  Answer: string; // Markdown, citing Sources by number
+  Queries?: string[];
+  Sources: Source[] | null;
+  Citations: CitationCheck;
+}
+
+export interface DiffRequest {
+  earlier: string; // Job id of the earlier run
+  later: string; // Job id of the later run
+  noSummary?: boolean; // Skip the LLM's "what's new" summary
+}
+
+export interface ValueChange {
+  URL: string;
+  Title: string;
+  Field: string;
+  Old: string;
+  New: string;
+}
+
+export interface ResultDiff {
+  Added: Source[] | null;
+  Removed: Source[] | null;
+  Changed: ValueChange[] | null;
+  Unchanged: number;
+  Summary?: string; // Markdown
+}
+
+export interface Job {
+  id: string;
+  topic: string;
+  status: string;
+  error?: string;
+  config?: ResearchRequest;
+  plan?: ResearchPlan;
+  progress?: ProgressEvent;
+  result?: ResearchResult;
+  tags?: string[];
+  startedAt: string;
+  updatedAt: string;
+}
+
+export interface JobSummary {
+  id: string;
+  topic: string;
+  status: string;
+  sourceCount: number;
+  hasReport: boolean;
+  tags: string[];
+  durationSeconds: number; // From start to the last update (the finish, for finished jobs)
+  startedAt: string;
+  updatedAt: string;
+}
+
+export interface JobFilter {
+  query?: string;
+  tag?: string;
+}
+
+export interface TagsRequest {
+  tags: string[];
+}
+
+export interface CollectionSummary {
+  name: string;
+  sourceCount: number;
+  lastRun: string;
+}
+
+export interface DocumentSummary {
+  id: string;
+  name: string;
+  words: number;
+  createdAt: string;
+}
+
+/** A report saved under results/ by the server or the CLI */
+export interface ReportFile {
+  /** Job ID, the files' base name */
+  id: string;
+  topic?: string;
+  /** Saved formats, the main file's first */
+  formats: ExportFormat[];
+  size: number;
+  modifiedAt: string;
+}
+
+export interface Profile {
+  name: string;
+  description: string;
+  loops?: number;
+  parallel?: number;
+  minResults?: number;
+  maxPages?: number;
+  listingsPerQuery?: number;
+  deepMode?: boolean;
+  resultLinks?: boolean;
+  simpleMode?: boolean;
+  extractionSchema?: string;
+  includeDomains?: string[];
+  excludeDomains?: string[];
+  categories?: string[];
+  timeRange?: string;
+  planning?: string;
+  report?: string;
+  template?: string;
+  builtin: boolean;
+}
+
+export interface AuthStatus {
+  required: boolean;
+  user?: string;
+}
+
+export interface HealthReport {
+  status: "ok" | "unavailable";
+  checks: {
+    name: string;
+    url: string;
+    ok: boolean;
+    latencyMs: number;
+    detail?: string;
+    error?: string;
+  }[];
+}
+
+export interface ModelInfo {
+  id: string;
+  /** Tokens a prompt and its answer may take (omitted when not reported) */
+  contextLength?: number;
+  loaded?: boolean;
+}
+
+export interface ModelsResponse {
+  provider: string;
+  url: string;
+  /** The configured model */
+  model: string;
+  found: boolean;
+  /** The served model it is, e.g. "llama3:latest" for "llama3" */
+  id?: string;
+  contextLength?: number;
+  models: ModelInfo[];
+  /** The context length checked against the model's */
+  contextLen: number;
+  warnings?: string[];
+}
+
+export type ExportFormat = "md" | "html" | "pdf" | "csv" | "xlsx" | "dot" | "graphml";
+
+/** An API error response (the server's ErrorResponse envelope) */
+export class DeepResearchError extends Error {
+  constructor(
+    readonly status: number,
+    readonly code: string,
+    message: string,
+  ) {
+    super(`${code} (${status}): ${message}`);
+    this.name = "DeepResearchError";
+  }
+}
+
+/** Calls a deep-research server, e.g. new DeepResearchClient("http://localhost:8080", token) */
+export class DeepResearchClient {
+  private readonly baseURL: string;
+
+  constructor(
+    baseURL: string,
+    private readonly token = "",
+    private readonly fetchImpl: typeof fetch = globalThis.fetch.bind(globalThis),
+  ) {
+    this.baseURL = baseURL.replace(/\/+$/, "");
+  }
+
+  /** Starts a job and waits for its plan; a queued job has position 1 or more */
+  startResearch(req: ResearchRequest): Promise<QueuedJob> {
+    return this.json("POST", "/api/research", req);
+  }
+
+  approve(): Promise<StatusResponse> {
+    return this.json("POST", "/api/approve");
+  }
+
+  revise(feedback: string): Promise<ResearchJob> {
+    return this.json("POST", "/api/revise", { feedback });
+  }
+
+  /** Answers the questions of a job awaiting answers (askQuestions), or of the plan awaiting approval, and plans from them */
+  answer(req: AnswerRequest): Promise<ResearchJob> {
+    return this.json("POST", "/api/answer", req);
+  }
+
+  /** Answers the questions of a job awaiting answers (askQuestions) and builds its plan, like answer() */
+  answersBeforePlan(req: AnswerRequest): Promise<ResearchJob> {
+    return this.json("POST", "/api/answers", req);
+  }
+
+  setQueries(queries: string[]): Promise<ResearchJob> {
+    return this.json("POST", "/api/plan/queries", { queries });
+  }
+
+  cancel(): Promise<StatusResponse> {
+    return this.json("POST", "/api/cancel");
+  }
+
+  finish(): Promise<StatusResponse> {
+    return this.json("POST", "/api/finish");
+  }
+
+  reset(): Promise<StatusResponse> {
+    return this.json("POST", "/api/reset");
+  }
+
+  status(): Promise<ResearchJob> {
+    return this.json("GET", "/api/status");
+  }
+
+  /** A job's results (no id = the current job's) */
+  results(id?: string): Promise<ResearchResult> {
+    return this.json("GET", jobPath(id, "results", "/api/results"));
+  }
+
+  /** Downloads a job's report, sources, records, or knowledge graph */
+  async export(format: ExportFormat, id?: string): Promise<Blob> {
+    const path = `${jobPath(id, "export", "/api/results/export")}?format=${format}`;
+    return (await this.send("GET", path)).blob();
+  }
+
+  /** The reports saved under results/, newest first */
+  reports(): Promise<ReportFile[]> {
+    return this.json("GET", "/api/reports");
+  }
+
+  /** Downloads a saved report file (no format = its main one) */
+  async downloadReport(id: string, format?: ExportFormat): Promise<Blob> {
+    const path = `/api/reports/${encodeURIComponent(id)}/download` + (format ? `?format=${format}` : "");
+    return (await this.send("GET", path)).blob();
+  }
+
+  partial(): Promise<Draft> {
+    return this.json("GET", "/api/results/partial");
+  }
+
+  /** Asks a question about a job's report (no id = the current job's) */
+  followUp(req: FollowUpRequest, id?: string): Promise<FollowUpAnswer> {
+    return this.json("POST", jobPath(id, "followup", "/api/followup"), req);
+  }
+
+  /** Compares two finished jobs: new and removed sources, changed values, and what's new */
+  diff(req: DiffRequest): Promise<ResultDiff> {
+    return this.json("POST", "/api/diff", req);
+  }
+
+  /** Lists past jobs, most recent first: query matches words in the topic, tags, or report */
+  jobs(filter: JobFilter = {}): Promise<JobSummary[]> {
+    const params = new URLSearchParams();
+    if (filter.query) params.set("query", filter.query);
+    if (filter.tag) params.set("tag", filter.tag);
+    const query = params.toString();
+    return this.json("GET", "/api/jobs" + (query ? `?${query}` : ""));
+  }
+
+  /** Replaces a job's tags (lowercased, spaces become hyphens); returns them sorted */
+  setTags(id: string, tags: string[]): Promise<TagsRequest> {
+    return this.json("PUT", `/api/jobs/${encodeURIComponent(id)}/tags`, { tags });
+  }
+
+  job(id: string): Promise<Job> {
+    return this.json("GET", `/api/jobs/${encodeURIComponent(id)}`);
+  }
+
+  collections(): Promise<CollectionSummary[]> {
+    return this.json("GET", "/api/collections");
+  }
+
+  /** Uploads PDF, Markdown, or text files; attach them to a job by ID in ResearchRequest.documents */
+  uploadDocuments(files: File[]): Promise<DocumentSummary[]> {
+    const form = new FormData();
+    for (const file of files) {
+      form.append("file", file);
+    }
+    return this.json("POST", "/api/documents", form);
+  }
+
+  documents(): Promise<DocumentSummary[]> {
+    return this.json("GET", "/api/documents");
+  }
+
+  deleteDocument(id: string): Promise<StatusResponse> {
+    return this.json("DELETE", `/api/documents/${encodeURIComponent(id)}`);
+  }
+
+  queue(): Promise<QueuedJob[]> {
+    return this.json("GET", "/api/queue");
+  }
+
+  removeQueued(id: string): Promise<StatusResponse> {
+    return this.json("DELETE", `/api/queue/${encodeURIComponent(id)}`);
+  }
+
+  profiles(): Promise<Profile[]> {
+    return this.json("GET", "/api/profiles");
+  }
+
+  /** The LLM server's models, checking the configured model against contextLen (default 32768) */
+  models(contextLen?: number): Promise<ModelsResponse> {
+    return this.json("GET", "/api/models" + (contextLen ? `?ctx=${contextLen}` : ""));
+  }
+
+  authStatus(): Promise<AuthStatus> {
+    return this.json("GET", "/api/auth/status");
+  }
+
+  /** /readyz when ready is set (a 503 DeepResearchError when a dependency is down), else /healthz */
+  health(ready = false): Promise<HealthReport> {
+    return this.json("GET", ready ? "/readyz" : "/healthz");
+  }
+
+  /**
+   * Follows a job's progress (no id = the current job), yielding each event
+   * after seq `since` until the job completes. Uses fetch rather than
+   * EventSource so the bearer token can be sent.
+   */
+  async *events(id?: string, since = 0, signal?: AbortSignal): AsyncGenerator<JobEvent> {
+    const path = jobPath(id, "events", "/api/progress") + (since > 0 ? `?since=${since}` : "");
+    const response = await this.send("GET", path, undefined, signal);
+    if (!response.body) {
+      return;
+    }
+    const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffered = "";
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) {
+        return;
+      }
+      buffered += value;
+      let end;
+      while ((end = buffered.indexOf("\n")) !== -1) {
+        const line = buffered.slice(0, end).replace(/\r$/, "");
+        buffered = buffered.slice(end + 1);
+        if (line.startsWith("data: ")) {
+          yield JSON.parse(line.slice("data: ".length)) as JobEvent;
+        }
+      }
+    }
+  }
+
+  private async json<T>(method: string, path: string, body?: unknown): Promise<T> {
+    return (await this.send(method, path, body)).json() as Promise<T>;
+  }
+
+  private async send(method: string, path: string, body?: unknown, signal?: AbortSignal): Promise<Response> {
+    const headers: Record<string, string> = {};
+    const multipart = body instanceof FormData; // fetch sets the boundary
+    if (body !== undefined && !multipart) {
+      headers["Content-Type"] = "application/json";
+    }
+    if (this.token) {
+      headers["Authorization"] = `Bearer ${this.token}`;
+    }
+    const response = await this.fetchImpl(this.baseURL + path, {
+      method,
+      headers,
+      body: body === undefined ? undefined : multipart ? (body as FormData) : JSON.stringify(body),
+      signal,
+    });
+    if (!response.ok) {
+      throw await decodeError(response);
+    }
+    return response;
+  }
+}
+
+function jobPath(id: string | undefined, action: string, current: string): string {
+  return id ? `/api/jobs/${encodeURIComponent(id)}/${action}` : current;
+}
+
+async function decodeError(response: Response): Promise<DeepResearchError> {
+  const text = await response.text();
+  try {
+    const envelope = JSON.parse(text) as ErrorResponse;
+    if (envelope.error?.code) {
+      return new DeepResearchError(response.status, envelope.error.code, envelope.error.message);
+    }
+  } catch {
+    // Not the envelope, e.g. a proxy's error page
+  }
+  return new DeepResearchError(response.status, `http_${response.status}`, text.trim());
+}
@@ -2,11 +2,19 @@ package agent
 
 import (
 	"context"
+	"deep-research/pkg/document"
+	"deep-research/pkg/geo"
 	"deep-research/pkg/llm"
+	"deep-research/pkg/logging"
+	"deep-research/pkg/price"
+	"deep-research/pkg/retry"
 	"deep-research/pkg/search"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
+	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -24,7 +32,7 @@ func stripThinkTags(s string) string {
 
 // ProgressEvent represents a progress update during research
 type ProgressEvent struct {
-	Phase       string   `json:"phase"`       // "planning", "searching", "compressing", "writing_report"
+	Phase       string   `json:"phase"`       // "planning", "searching", "writing_report"
 	Round       int      `json:"round"`       // Current round number
 	TotalRounds int      `json:"totalRounds"` // Total rounds configured
 	URLsFound   int      `json:"urlsFound"`   // Unique URLs found so far
@@ -33,172 +41,230 @@ type ProgressEvent struct {
 	Percent     int      `json:"percent"`     // Estimated progress percentage
 	Errors      []string `json:"errors"`      // Search errors encountered this round
 	ErrorCount  int      `json:"errorCount"`  // Total error count
+
+	// Detail sent while searching: what is being searched or fetched right now
+	Step             string     `json:"step,omitempty"`             // StepSearch, StepFetch, StepSummarize, or StepQueryDone
+	Query            string     `json:"query,omitempty"`            // Query being searched (or whose result is being fetched)
+	QueryIndex       int        `json:"queryIndex,omitempty"`       // 1-based position of Query among the run's queries
+	TotalQueries     int        `json:"totalQueries,omitempty"`     // Queries planned for the run (0 = not known up front)
+	Page             int        `json:"page,omitempty"`             // Result page being requested
+	URL              string     `json:"url,omitempty"`              // Page being fetched or summarized
+	Duplicates       int        `json:"duplicates,omitempty"`       // Results skipped so far as already seen or near-duplicate
+	RemainingQueries int        `json:"remainingQueries,omitempty"` // Queries not yet searched
+	ETASeconds       int        `json:"etaSeconds,omitempty"`       // Estimated seconds left in the search phase (0 = unknown)
+	Stat             *QueryStat `json:"stat,omitempty"`             // StepQueryDone: what the query yielded
+
+	// Set on every event: what the researcher's chat calls have used so far
+	Tokens int     `json:"tokens,omitempty"` // Prompt and completion tokens, as the backend reported them
+	Cost   float64 `json:"cost,omitempty"`   // USD at Config.PromptPrice and CompletionPrice (0 = no prices set)
+
+	// Sent as PhaseReportChunk while the report is written (Config.StreamReport)
+	Chunk   string `json:"chunk,omitempty"`   // Markdown to append to the report streamed so far
+	Restart bool   `json:"restart,omitempty"` // Discard the report streamed so far; it is being written again
 }
 
 // Config holds the agent configuration
 type Config struct {
-	MaxLoops      int
-	ParallelQuery int
-	DeepMode      bool // When true, fetch and summarize each page individually
-	ResultLinks   bool // When true, emphasize including direct links in results
-	SimpleMode    bool // When true, use simple/quick research (not recommended)
-	MinResults    int  // Minimum unique URLs to find before stopping
-	DelayMs       int  // Milliseconds delay between HTTP requests (rate limiting)
-	MaxPages      int  // Number of SearXNG result pages to fetch per query (0 = auto)
-	ContextLength int  // LLM context length in tokens (for compression management)
-	OnProgress    func(ProgressEvent) // Callback for progress updates (optional, for UI)
-}
-
-// maxContextChars returns the estimated max characters based on context length
-// Uses conservative 3.5 chars per token estimate
-func (c Config) maxContextChars() int {
-	if c.ContextLength <= 0 {
-		return 32768 * 3 // Default 32k tokens * 3 chars = 98k chars
-	}
-	return int(float64(c.ContextLength) * 3.5)
+	MaxLoops         int
+	ParallelQuery    int
+	DeepMode         bool                // When true, fetch and summarize each page individually
+	ResultLinks      bool                // When true, emphasize including direct links in results
+	SimpleMode       bool                // When true, use simple/quick research (not recommended)
+	MinResults       int                 // Minimum unique URLs to find before stopping
+	DelayMs          int                 // Milliseconds delay between search requests (rate limiting)
+	MaxPages         int                 // Number of SearXNG result pages to fetch per query (0 = auto)
+	ContextLength    int                 // LLM context length in tokens (sizes prompts; larger reports are written section by section)
+	CheckpointPath   string              // File to persist exhaustive-run state to after each round (optional)
+	SourcesDir       string              // Save the text of every fetched page here, listed in index.jsonl, to audit the report later ("" = off)
+	ArchiveHTML      bool                // SourcesDir: also save each page as downloaded (HTML or PDF)
+	ExtractionSchema string              // Deep mode: fields to extract per page (JSON schema or "price, address, url")
+	ExtractEntities  bool                // Extract the people, companies, products, and locations in the findings and the relations between them
+	FindConflicts    bool                // Compare the sources' claims about the same facts; the report lists where they disagree
+	CollectImages    bool                // Also search SearXNG's images category per query; the report gets a Media appendix of what it finds
+	EvaluateReport   bool                // Critique the report against the plan's expected outcome; below ReviseBelow, search for its gaps and revise it once
+	ReviseBelow      int                 // EvaluateReport: score out of 10 under which the report is revised (0 = DefaultReviseBelow)
+	SinglePassReport bool                // Write the report in one prompt when the findings fit, instead of outlining it and writing each section separately
+	DedupThreshold   float64             // Deep mode: cosine similarity at which fetched pages count as near-duplicates (0 = off, needs an embedding model)
+	QueryDedup       float64             // Cosine similarity at which planned queries count as the same; one per cluster is kept (0 = off, needs an embedding model)
+	RelevanceFilter  string              // Drop search results unrelated to the topic before they reach the context: RelevanceKeywords or RelevanceLLM ("" = off)
+	FixedQueryOrder  bool                // Search the planned queries in order, instead of running those of high-yield platforms and words first and skipping dead ends
+	SummarizerModel  string              // Deep mode: model for per-page summaries, e.g. a fast 3B model (empty = main model)
+	SummarizerURL    string              // Base URL serving SummarizerModel (empty = main model's server)
+	WriterModel      string              // Model for planning and the final report (empty = main model)
+	WriterURL        string              // Base URL serving WriterModel (empty = main model's server)
+	SeedURLs         []string            // Research these pages instead of searching (no queries are generated)
+	CompareEntities  []string            // Research each of these separately and write a comparison (at least two)
+	FollowLinks      bool                // SeedURLs and SitemapSites: also fetch the item links found on each page
+	CrawlDepth       int                 // Deep mode, SeedURLs, and SitemapSites: follow in-domain "next", pagination, and category links this many hops from each index page, collecting the items they list (0 = off, at most MaxCrawlDepth)
+	CrawlPages       int                 // CrawlDepth: most item links collected per start page (0 = DefaultCrawlPages)
+	PriceCurrency    string              // Convert the prices found in records, summaries, and snippets to this currency, e.g. EUR, so listings can be sorted and compared by price (empty = off)
+	PriceRates       price.Rates         // PriceCurrency: exchange rates (nil = price.DefaultRates())
+	GeoArea          string              // Locate the listings and flag or drop those outside this place, e.g. "Cluj-Napoca, Romania" or "46.77,23.59" (empty = off)
+	GeoRadius        float64             // GeoArea: km from its center (0 = the place's bounds from the geocoder, else DefaultGeoRadius)
+	GeoFilter        string              // GeoArea: GeoFlag marks the sources outside it in the source list, GeoDrop leaves them out ("" = GeoFlag)
+	Geocoder         geo.Geocoder        // GeoArea: geocodes the area and the listings' addresses (nil = Nominatim at geo.DefaultNominatimURL)
+	NextPages        int                 // Deep mode, FollowLinks: also read up to this many next pages of each listing index page (rel=next, "next" links, page= numbers) for its items (0 = off, at most MaxNextPages)
+	SitemapSites     []string            // Research pages listed in these sites' sitemaps instead of searching (domains, site URLs, or sitemap URLs)
+	SitemapPattern   string              // SitemapSites: regular expression the page URLs must match (empty = the planner picks one for the topic)
+	MaxSitemapPages  int                 // SitemapSites: most pages researched, most recently changed first (0 = 100)
+	IncludeDomains   []string            // Only use search results and pages from these domains and their subdomains (empty = all)
+	ExcludeDomains   []string            // Never use search results or pages from these domains (e.g. pinterest.com)
+	Categories       []string            // SearXNG categories to search, e.g. news or science (empty = instance default)
+	Engines          []string            // SearXNG engines to query, e.g. google or wikipedia (empty = instance default)
+	TimeRange        string              // SearXNG: only results from the last day, week, month, or year (empty = any time)
+	MaxAgeDays       int                 // Drop sources published more than this many days ago, by the date on the page, in its URL, or from the engine (0 = keep all; undated sources are kept)
+	PlanningPrompt   string              // Extra planner instructions for this kind of research (e.g. from a profile)
+	ReportStructure  string              // How the report should be structured (e.g. from a profile; empty = the writer decides)
+	ReportTemplate   string              // text/template the finished report is rendered with (see ReportData; empty = the report as written)
+	ReportLanguage   string              // Language for page summaries and the report, e.g. "English", whatever the topic and sources are in (empty = the model decides)
+	OnProgress       func(ProgressEvent) // Callback for progress updates (optional, for UI)
+	StreamReport     bool                // Send the report to OnProgress paragraph by paragraph as it is written (PhaseReportChunk events)
+	Logger           *slog.Logger        // Where progress messages go (nil = console on stdout at info level; logging.Discard() silences them)
+	MaxLLMCalls      int                 // Stop researching after this many LLM calls and write the report (0 = no limit)
+	MaxHTTPRequests  int                 // Stop researching after this many searches and page fetches (0 = no limit)
+	MaxDuration      time.Duration       // Finish the run, report included, within this long of starting research (0 = no limit); queries that won't fit are dropped
+	ReportReserve    time.Duration       // MaxDuration: time kept for compressing findings and writing the report (0 = a quarter of MaxDuration, at least 1m)
+	Knowledge        *Knowledge          // What earlier runs of this topic's collection found; the report gets a "What Changed" section (nil = not part of a collection)
+	OnlyNew          bool                // With Knowledge: skip results and pages the collection already has, so only new sources are researched
+	Documents        []document.Document // The user's own material: the planner reads it, and the report draws on it and cites it as sources
+	FetchWorkers     int                 // Deep mode: pages (and link lists) fetched at once across all queries (0 = DefaultFetchWorkers, negative = unbounded)
+	SummarizeWorkers int                 // Deep mode: page summaries and record extractions sent to the LLM at once (0 = DefaultSummarizeWorkers, negative = unbounded)
+	PlanRepairs      int                 // Times a planning call's response that isn't valid JSON (a plan, queries, a decision) is sent back with the parse error for the model to correct (0 = llm.JSONRepairs, negative = none)
+	RoundDigestChars int                 // Exhaustive mode: a round's results longer than this many characters enter the research context as a digest of at most this length, the raw results staying in the findings log (0 = the raw results)
+	PromptPrice      float64             // USD per million prompt tokens, to report the run's cost (0 = not priced)
+	CompletionPrice  float64             // USD per million completion tokens
+
+	// Deep mode: most listing pages read per search query, or in exhaustive mode
+	// item links taken from each listing index page among a query's results
+	// (0 = DefaultListingsPerQuery; times 1 + NextPages, CrawlPages with a crawl)
+	MaxListingsPerQuery int
+
+	// Temperature, max tokens, and system prompt per type of LLM call (keys: CallTypes;
+	// a missing type uses the backend's settings, temperature 0)
+	CallSettings map[string]CallSettings
+
+	// Backend per type of LLM call (keys: CallTypes; see NewCallProviders), e.g. a
+	// hosted model writing the report while summaries stay local; a missing type
+	// uses the role's model (WriterModel, SummarizerModel)
+	CallProviders map[string]llm.Provider
 }
 
-// Source represents a single source URL with its title
+// Source represents a single source URL with its title and what it contributed
 type Source struct {
-	Title string
-	URL   string
+	Title      string
+	URL        string
+	Snippet    string        `json:",omitempty"` // Search engine snippet
+	Summary    string        `json:",omitempty"` // Deep mode: LLM summary of the fetched page
+	FetchedAt  time.Time     `json:",omitzero"`  // Deep mode: when the page was fetched
+	ArchiveURL string        `json:",omitempty"` // Deep mode: the Wayback Machine snapshot read because the page was gone
+	Published  time.Time     `json:",omitzero"`  // Publication date found on the page, in its URL, or by the search engine
+	Document   bool          `json:",omitempty"` // A document the user provided (Config.Documents), not a web page
+	Price      *price.Amount `json:",omitempty"` // Price found in its record, summary, or snippet, in Config.PriceCurrency
+	Location   *Location     `json:",omitempty"` // Where its listing is, with Config.GeoArea
 }
 
 // ResearchPlan contains the clarified query and research plan
 type ResearchPlan struct {
-	ClarifyingQuestions  []string `json:"clarifying_questions"`
-	UnderstandingSummary string   `json:"understanding_summary"`
-	ResearchSteps        []string `json:"research_steps"`
-	ExpectedOutcome      string   `json:"expected_outcome"`
-	SearchQueries        []string `json:"search_queries,omitempty"` // Pre-generated queries for exhaustive mode
+	ClarifyingQuestions  []string         `json:"clarifying_questions"`
+	UnderstandingSummary string           `json:"understanding_summary"`
+	ResearchSteps        []string         `json:"research_steps"`
+	ExpectedOutcome      string           `json:"expected_outcome"`
+	SearchQueries        []string         `json:"search_queries,omitempty"` // Pre-generated queries for exhaustive mode
+	Answers              []QuestionAnswer `json:"answers,omitempty"`        // User answers the plan was built from
+	Comparison           *ComparisonPlan  `json:"comparison,omitempty"`     // Criteria and per-entity queries (Config.CompareEntities)
+	Sitemap              *SitemapPlan     `json:"sitemap,omitempty"`        // Pages picked from the sitemaps (Config.SitemapSites)
 }
 
 // ResearchResult contains the final report and all sources
 type ResearchResult struct {
-	Report  string
-	Sources []Source
+	Report       string
+	Sources      []Source
+	Records      []map[string]any // Structured records extracted per page (deep mode + ExtractionSchema)
+	RecordFields []string         `json:",omitempty"` // The records' fields in schema order
+	Citations    CitationCheck    // How the report's [n] citations and links matched Sources
+	Claims       []ReportClaim    `json:",omitempty"` // The report's cited statements, each with its sources' URLs
+	Usage        Usage            // LLM calls, HTTP requests, and time the research took (see Config budgets)
+	Comparison   *Comparison      `json:",omitempty"` // Criteria x entities matrix (comparative runs)
+	Changes      *Changes         `json:",omitempty"` // What differs from the collection's earlier runs (Config.Knowledge)
+	Entities     []Entity         `json:",omitempty"` // Knowledge graph nodes (Config.ExtractEntities)
+	Relations    []Relation       `json:",omitempty"` // Knowledge graph edges between Entities
+	Conflicts    []Fact           `json:",omitempty"` // Facts the sources disagree on (Config.FindConflicts)
+	Consensus    []Fact           `json:",omitempty"` // Facts two or more sources agree on (Config.FindConflicts)
+	QueryStats   []QueryStat      `json:",omitempty"` // What each search query yielded, in the order they ran
+	Images       []Image          `json:",omitempty"` // Images found for the report's Media appendix (Config.CollectImages)
+	Evaluation   *Evaluation      `json:",omitempty"` // Critique of the final report (Config.EvaluateReport)
+	Templated    bool             `json:",omitempty"` // Report was rendered with Config.ReportTemplate, bibliography included
 }
 
 // DeepResearcher is the main agent struct
 type DeepResearcher struct {
-	llmClient *llm.Client
-	searcher  search.Searcher
-	config    Config
-	sources   []Source          // Track all sources found during research
-	seenURLs  map[string]bool   // Deduplication: track URLs already processed
-	mu        sync.Mutex        // Mutex for thread-safe access to seenURLs and sources
+	llmClient          llm.Provider
+	summarizer         llm.Provider     // Deep mode per-page summaries (Config.SummarizerModel)
+	writer             llm.Provider     // Planning and the final report (Config.WriterModel)
+	searcher           search.Searcher
+	config             Config
+	log                *slog.Logger     // Config.Logger, or the default console logger
+	sources            []Source         // Track all sources found during research
+	records            []map[string]any // Structured records extracted in deep mode
+	seenURLs           map[string]bool  // Deduplication: track URLs already processed
+	pageVectors        [][]float64      // Embeddings of kept pages (near-duplicate detection)
+	findings           []string         // Round summaries, retrieved per report section when the context doesn't fit one prompt
+	images             []Image          // Images collected for the Media appendix (Config.CollectImages)
+	queryStats         []QueryStat      // Per-query yield of the running research
+	embeddingsDisabled bool             // Set after the first embedding failure
+	tokenizerDisabled  bool             // Set after the first tokenizer failure (falls back to estimates)
+	progress           runProgress      // What the running research has gathered, for DraftReport
+	searchProgress     searchProgress   // Where the running research's search phase stands, for progress events
+	mu                 sync.Mutex       // Mutex for thread-safe access to seenURLs and sources
+	draft              *Draft           // Last DraftReport, reused until the run moves on
+	draftMu            sync.Mutex       // Serializes DraftReport calls
+	budget             budget           // LLM calls and HTTP requests spent by the running research
+	tokens             tokenCounter     // Tokens used by the researcher's chat calls (Usage.Tokens)
+	archive            sourceArchive    // Pages saved for auditing the report (Config.SourcesDir)
+	pause              pauseGate        // Holds searches, fetches, and LLM calls while paused (Pause)
+	template           *reportTemplate  // Config.ReportTemplate, parsed (nil = none)
+	fetchPool          workerPool       // Bounds concurrent page fetches (Config.FetchWorkers)
+	summarizePool      workerPool       // Bounds concurrent per-page LLM calls (Config.SummarizeWorkers)
 }
 
 // NewDeepResearcher creates a new agent
-func NewDeepResearcher(l *llm.Client, s search.Searcher, cfg Config) *DeepResearcher {
+func NewDeepResearcher(l llm.Provider, s search.Searcher, cfg Config) *DeepResearcher {
+	log := cfg.Logger
+	if log == nil {
+		log = logging.Default()
+	}
+	var tmpl *reportTemplate
+	if cfg.ReportTemplate != "" {
+		var err error
+		if tmpl, err = parseReportTemplate(cfg.ReportTemplate); err != nil {
+			log.Warn("⚠️ Ignoring the report template", "error", err)
+		}
+	}
 	return &DeepResearcher{
-		llmClient: l,
-		searcher:  s,
-		config:    cfg,
-		sources:   make([]Source, 0),
-		seenURLs:  make(map[string]bool),
+		llmClient:     l,
+		summarizer:    roleProvider(l, log, "summarizer", cfg.SummarizerURL, cfg.SummarizerModel),
+		writer:        roleProvider(l, log, "writer", cfg.WriterURL, cfg.WriterModel),
+		searcher:      s,
+		config:        cfg,
+		log:           log,
+		sources:       make([]Source, 0),
+		seenURLs:      make(map[string]bool),
+		fetchPool:     newWorkerPool(cfg.FetchWorkers, DefaultFetchWorkers),
+		summarizePool: newWorkerPool(cfg.SummarizeWorkers, DefaultSummarizeWorkers),
+		template:      tmpl,
 	}
 }
 
 // emitProgress sends a progress event if a callback is configured
 func (a *DeepResearcher) emitProgress(event ProgressEvent) {
 	if a.config.OnProgress != nil {
+		total, _ := a.tokens.totals()
+		event.Tokens = total.Total()
+		event.Cost = a.config.cost(total)
 		a.config.OnProgress(event)
 	}
 }
 
-// compressContext uses LLM to compress research context when it gets too large
-// targetRatio is the target compression ratio (e.g., 0.5 for 50% reduction)
-func (a *DeepResearcher) compressContext(context string, targetRatio float64) (string, error) {
-	maxChars := a.config.maxContextChars()
-	// Reserve space for the compression prompt itself (~500 chars) and response
-	maxInputChars := int(float64(maxChars) * 0.6)
-	
-	// If context fits in a single compression call, do it directly
-	if len(context) <= maxInputChars {
-		return a.compressContextDirect(context, targetRatio)
-	}
-	
-	// Context too large - use chunked compression
-	fmt.Printf("📦 Context too large for single compression (%d chars), using chunked approach...\n", len(context))
-	return a.compressContextChunked(context, targetRatio)
-}
-
-// compressContextDirect compresses context that fits within model limits
-func (a *DeepResearcher) compressContextDirect(context string, targetRatio float64) (string, error) {
-	targetChars := int(float64(len(context)) * targetRatio)
-	
-	prompt := fmt.Sprintf(`Compress this research context to ~%d characters. PRESERVE: URLs, prices, names, numbers, dates, specific facts. REMOVE: redundancy, verbose descriptions. Output ONLY compressed text:
-
-%s`, targetChars, context)
-
-	resp, err := a.llmClient.Chat([]llm.Message{
-		{Role: "system", Content: "Compress text. Output only the result."},
-		{Role: "user", Content: prompt},
-	})
-	if err != nil {
-		return context, fmt.Errorf("compression failed: %w", err)
-	}
-
-	compressed := stripThinkTags(resp)
-	compressed = strings.TrimSpace(compressed)
-	
-	if len(compressed) < 200 {
-		return context, fmt.Errorf("compression produced too small output (%d chars)", len(compressed))
-	}
-	
-	fmt.Printf("📦 Compressed: %d → %d chars (%.0f%% reduction)\n", 
-		len(context), len(compressed), (1-float64(len(compressed))/float64(len(context)))*100)
-	
-	return compressed, nil
-}
-
-// compressContextChunked splits large context into chunks, compresses each, then combines
-func (a *DeepResearcher) compressContextChunked(context string, targetRatio float64) (string, error) {
-	maxChars := a.config.maxContextChars()
-	// Each chunk should be small enough to compress with room for prompt
-	chunkSize := int(float64(maxChars) * 0.5)
-	if chunkSize < 2000 {
-		chunkSize = 2000
-	}
-	
-	// Split context into chunks (try to split on double newlines to preserve structure)
-	chunks := splitContextIntoChunks(context, chunkSize)
-	fmt.Printf("📦 Split into %d chunks for compression\n", len(chunks))
-	
-	var compressedParts []string
-	for i, chunk := range chunks {
-		fmt.Printf("   Compressing chunk %d/%d (%d chars)...\n", i+1, len(chunks), len(chunk))
-		
-		compressed, err := a.compressContextDirect(chunk, targetRatio)
-		if err != nil {
-			// On error, aggressively truncate this chunk
-			fmt.Printf("   ⚠️ Chunk %d compression failed, truncating\n", i+1)
-			truncated := chunk
-			if len(chunk) > chunkSize/4 {
-				truncated = chunk[:chunkSize/4] + "\n[...truncated...]\n"
-			}
-			compressedParts = append(compressedParts, truncated)
-			continue
-		}
-		compressedParts = append(compressedParts, compressed)
-	}
-	
-	result := strings.Join(compressedParts, "\n\n---\n\n")
-	
-	// If still too large, recursively compress again
-	maxTarget := int(float64(maxChars) * 0.6)
-	if len(result) > maxTarget {
-		fmt.Printf("📦 Combined result still too large (%d chars), compressing again...\n", len(result))
-		return a.compressContext(result, targetRatio)
-	}
-	
-	fmt.Printf("📦 Chunked compression complete: %d → %d chars (%.0f%% reduction)\n",
-		len(context), len(result), (1-float64(len(result))/float64(len(context)))*100)
-	
-	return result, nil
-}
-
 // splitContextIntoChunks splits text into chunks, trying to break on paragraph boundaries
 func splitContextIntoChunks(text string, maxChunkSize int) []string {
 	if len(text) <= maxChunkSize {
@@ -237,6 +303,21 @@ func splitContextIntoChunks(text string, maxChunkSize int) []string {
 
 // CreatePlan generates a research plan with clarifying questions
 func (a *DeepResearcher) CreatePlan(topic string, additionalContext string) (ResearchPlan, error) {
+	return a.CreatePlanWithContext(context.Background(), topic, additionalContext)
+}
+
+// CreatePlanWithContext generates a research plan with cancellation support
+func (a *DeepResearcher) CreatePlanWithContext(ctx context.Context, topic string, additionalContext string) (ResearchPlan, error) {
+	if len(a.config.SeedURLs) > 0 {
+		return a.seedPlan(topic, additionalContext), nil
+	}
+	if len(a.config.SitemapSites) > 0 {
+		return a.sitemapPlan(ctx, topic, additionalContext)
+	}
+	if len(a.config.CompareEntities) > 0 {
+		return a.comparisonPlan(ctx, topic, additionalContext)
+	}
+
 	contextInfo := ""
 	if additionalContext != "" {
 		contextInfo = fmt.Sprintf("\n\nAdditional context from user:\n%s", additionalContext)
@@ -246,6 +327,7 @@ func (a *DeepResearcher) CreatePlan(topic string, additionalContext string) (Res
 	if a.config.ResultLinks {
 		linkEmphasis = "\n\nIMPORTANT: The user wants results with DIRECT LINKS. Focus on finding specific listing/item URLs, not general category pages. Each result must have its own clickable link."
 	}
+	linkEmphasis += a.planningGuidance(ctx)
 
 	prompt := fmt.Sprintf(`You are a Deep Research AI planning a comprehensive research task.%s
 
@@ -272,30 +354,69 @@ Respond ONLY with valid JSON:
   "expected_outcome": "..."
 }`, linkEmphasis, topic, contextInfo)
 
-	resp, err := a.llmClient.Chat([]llm.Message{
+	var plan ResearchPlan
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.writer, []llm.Message{
 		{Role: "system", Content: "You are a research planning assistant. Output only valid JSON."},
 		{Role: "user", Content: prompt},
-	})
+	}, planSchema, &plan)
 	if err != nil {
-		return ResearchPlan{}, err
+		return ResearchPlan{}, jsonError("research plan", err)
 	}
 
-	resp = stripThinkTags(resp)
-	resp = strings.TrimPrefix(resp, "```json")
-	resp = strings.TrimPrefix(resp, "```")
-	resp = strings.TrimSuffix(resp, "```")
-	resp = strings.TrimSpace(resp)
+	return plan, nil
+}
 
-	var plan ResearchPlan
-	if err := json.Unmarshal([]byte(resp), &plan); err != nil {
-		return ResearchPlan{}, fmt.Errorf("failed to parse research plan: %w. Response: %s", err, resp)
+
+// planningGuidance returns Config.PlanningPrompt and the provided documents as a
+// planner prompt suffix ("" if there are neither)
+func (a *DeepResearcher) planningGuidance(ctx context.Context) string {
+	guidance := a.documentBrief(ctx)
+	if a.config.PlanningPrompt != "" {
+		guidance += "\n\nResearch profile instructions:\n" + strings.TrimSpace(a.config.PlanningPrompt)
 	}
+	return guidance
+}
 
-	return plan, nil
+// reportGuidance returns Config.ReportStructure as a report prompt suffix ("" if unset)
+func (a *DeepResearcher) reportGuidance() string {
+	if a.config.ReportStructure == "" {
+		return ""
+	}
+	return "\n\nReport structure:\n" + strings.TrimSpace(a.config.ReportStructure)
+}
+
+// languageDirective is appended to the summarize and report prompts when
+// Config.ReportLanguage is set. Searches stay in the topic's language (e.g. a
+// local market's), so sources often aren't in the report's.
+func (a *DeepResearcher) languageDirective() string {
+	language := strings.TrimSpace(a.config.ReportLanguage)
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\nLANGUAGE: Write in %s, titles and headings included, even where the topic or sources are in another language. Translate the facts; keep names, addresses, quoted terms, and URLs as they are.", language)
 }
 
 // Run executes the deep research loop (after plan is approved)
 func (a *DeepResearcher) Run(topic string, plan ResearchPlan) (ResearchResult, error) {
+	return a.RunWithContext(context.Background(), topic, plan)
+}
+
+// RunWithContext executes the deep research loop; cancelling ctx aborts in-flight searches and LLM calls.
+// When ctx is cancelled (unless with ErrDiscarded), a Config budget runs out, or Finish is called, research stops
+// and the report is written from what was found.
+func (a *DeepResearcher) RunWithContext(parent context.Context, topic string, plan ResearchPlan) (ResearchResult, error) {
+	if len(a.config.SeedURLs) > 0 {
+		return a.runSeeds(parent, topic, plan, a.config.SeedURLs)
+	}
+	if len(a.config.SitemapSites) > 0 {
+		return a.runSitemap(parent, topic, plan)
+	}
+	if len(a.config.CompareEntities) > 0 {
+		return a.runComparative(parent, topic, plan)
+	}
+	ctx, stopBudget := a.startBudget(parent)
+	defer stopBudget()
+
 	// Build context with the approved plan
 	context := fmt.Sprintf(`User Query: %s
 
@@ -307,49 +428,108 @@ Research Plan:
 Knowledge so far:
 None.`, topic, plan.UnderstandingSummary, plan.ExpectedOutcome, strings.Join(plan.ResearchSteps, "; "))
 	
-	a.sources = make([]Source, 0) // Reset sources for each run
+	a.sources = a.documentSources() // Reset sources for each run
+	a.records = nil
+	a.findings = nil
+	a.queryStats = nil
+	a.images = nil
+	a.startProgress(topic, context, 0)
+	a.resetSearchProgress(searchProgress{totalRounds: a.config.MaxLoops})
 	
-	fmt.Printf("🧠 Starting Deep Research for: %s\n", topic)
+	a.log.Info("🧠 Starting Deep Research", "topic", topic)
 
+	cancelled := false
 	for i := 0; i < a.config.MaxLoops; i++ {
-		fmt.Printf("\n--- Round %d/%d ---\n", i+1, a.config.MaxLoops)
+		// Check for cancellation at start of each round
+		if parent.Err() != nil {
+			a.log.Warn("⚠️ Research cancelled - proceeding to write report", "results", len(a.sources))
+			cancelled = true
+			break
+		}
+		a.log.Info("--- Round ---", "round", i+1, "of", a.config.MaxLoops)
 
 		// Step 1: DECIDE
-		decision, err := a.decide(context)
+		decision, err := a.decide(ctx, context)
+		if budgetExhausted(ctx) != nil {
+			break
+		}
+		if parent.Err() != nil {
+			a.log.Warn("⚠️ Research cancelled mid-round, proceeding to write report", "results", len(a.sources))
+			cancelled = true
+			break
+		}
 		if err != nil {
 			return ResearchResult{}, fmt.Errorf("decision failed: %w", err)
 		}
 
 		if decision.FinalAnswer {
-			fmt.Println("✅ Sufficient information gathered.")
+			a.log.Info("✅ Sufficient information gathered")
 			break
 		}
 
 		if len(decision.Queries) == 0 {
-			fmt.Println("⚠️ No queries generated, but not final. Stopping to avoid loop.")
+			a.log.Warn("⚠️ No queries generated, but not final. Stopping to avoid loop")
 			break
 		}
 
 		// Step 2: ACT (Parallel Search)
-		fmt.Printf("🔎 Searching for: %v\n", decision.Queries)
-		searchResults := a.parallelSearch(decision.Queries)
+		a.log.Info("🔎 Searching", "queries", decision.Queries)
+		a.startSearchBatch(i+1, 0, len(decision.Queries))
+		searchResults := a.parallelSearch(ctx, decision.Queries)
 
 		// Step 3: LEARN (Summarize)
-		summary, err := a.summarize(topic, searchResults)
+		summary, err := a.summarize(ctx, topic, searchResults)
+		if budgetExhausted(ctx) != nil {
+			break
+		}
+		if parent.Err() != nil {
+			// The round's search results never made it into a summary
+			a.log.Warn("⚠️ Research cancelled mid-round, proceeding to write report", "results", len(a.sources))
+			cancelled = true
+			break
+		}
 		if err != nil {
 			return ResearchResult{}, fmt.Errorf("summarization failed: %w", err)
 		}
 
 		context += fmt.Sprintf("\n\nRound %d Findings:\n%s", i+1, summary)
+		a.addFindings(summary)
+		a.updateProgress(context, i+1)
 	}
 
 	// Final Report
-	fmt.Println("\n✍️ Writing Final Report...")
-	report, err := a.writeReport(topic, context)
+	usage := a.finishBudget(ctx)
+	stopReason := usage.Exhausted
+	if cancelled {
+		stopReason = "search cancelled"
+	}
+	if stopReason != "" {
+		context += fmt.Sprintf("\n\n--- NOTE: Research stopped early (%s). Results may be incomplete. ---\n", stopReason)
+	}
+	// A cancelled run still gets its partial report, so detach the report from the cancellation
+	reportCtx, err := reportContext(parent)
 	if err != nil {
 		return ResearchResult{}, err
 	}
-	return ResearchResult{Report: report, Sources: a.sources}, nil
+	a.normalizePrices(a.sources, a.records)
+	var areaNote string
+	a.sources, a.records, areaNote = a.filterArea(reportCtx, a.sources, a.records)
+	context += areaNote
+	conflicts, consensus := a.analyzeClaims(reportCtx, a.sources)
+	context += conflictNote(conflicts)
+	if cancelled {
+		a.log.Info("✍️ Writing Partial Report", "reason", stopReason)
+	} else {
+		a.log.Info("✍️ Writing Final Report")
+	}
+	report, err := a.writeReport(reportCtx, topic, context, a.sources)
+	if err != nil {
+		return ResearchResult{}, err
+	}
+	result := a.finishResult(reportCtx, topic, plan, report, gathered{sources: a.sources, records: a.records, images: a.images, queryStats: a.queryStats, conflicts: conflicts, consensus: consensus})
+	a.sources = result.Sources
+	result.Usage = a.withTokens(usage)
+	return result, nil
 }
 
 type decisionResponse struct {
@@ -357,7 +537,7 @@ type decisionResponse struct {
 	Queries     []string `json:"queries"`
 }
 
-func (a *DeepResearcher) decide(context string) (decisionResponse, error) {
+func (a *DeepResearcher) decide(ctx context.Context, context string) (decisionResponse, error) {
 	prompt := fmt.Sprintf(`You are a Deep Research AI. Your goal is to answer the user's query comprehensively.
 
 Current Knowledge:
@@ -374,56 +554,38 @@ Respond ONLY with a valid JSON object in this format:
 }
 `, context)
 
-	resp, err := a.llmClient.Chat([]llm.Message{
+	var decision decisionResponse
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.llmClient, []llm.Message{
 		{Role: "system", Content: "You are a helpful research assistant. Output only JSON."},
 		{Role: "user", Content: prompt},
-	})
+	}, decisionSchema, &decision)
 	if err != nil {
-		return decisionResponse{}, err
-	}
-
-	// Clean up response if it contains markdown code blocks
-	resp = strings.TrimPrefix(resp, "```json")
-	resp = strings.TrimPrefix(resp, "```")
-	resp = strings.TrimSuffix(resp, "```")
-	resp = strings.TrimSpace(resp)
-
-	// Remove <think>...</think> blocks if present (common in reasoning models)
-	// Handle both single line and multi-line think blocks
-	if start := strings.Index(resp, "<think>"); start != -1 {
-		if end := strings.Index(resp, "</think>"); end != -1 {
-			// Keep only what's AFTER the closing tag
-			resp = resp[end+8:]
-		}
-	}
-	resp = strings.TrimSpace(resp)
-
-	var decision decisionResponse
-	if err := json.Unmarshal([]byte(resp), &decision); err != nil {
-		// Fallback: try to parse just the queries if JSON fails, or just return error
-		// For robustness, we could retry or use a simpler format.
-		return decisionResponse{}, fmt.Errorf("failed to parse JSON decision: %w. Response was: %s", err, resp)
+		return decisionResponse{}, jsonError("JSON decision", err)
 	}
 
 	return decision, nil
 }
 
 // summarizePage uses LLM to create a short summary of a single page's content
-func (a *DeepResearcher) summarizePage(url, title, content string) string {
+func (a *DeepResearcher) summarizePage(ctx context.Context, url, title, content string) string {
 	if len(content) < 100 {
 		return content // Too short to summarize
 	}
 	
-	prompt := fmt.Sprintf(`Summarize this webpage content in 2-3 sentences. Extract ONLY specific facts, prices, addresses, dates, or key data points. Be extremely concise.
+	prompt := fmt.Sprintf(`Summarize this webpage content in 2-3 sentences. Extract ONLY specific facts, prices, addresses, dates, or key data points. Be extremely concise.%s
 
 Title: %s
 URL: %s
 Content:
 %s
 
-Summary (2-3 sentences, facts only):`, title, url, content)
+Summary (2-3 sentences, facts only):`, a.languageDirective(), title, url, content)
 
-	resp, err := a.llmClient.Chat([]llm.Message{
+	if err := a.summarizePool.acquire(ctx); err != nil {
+		return content[:min(len(content), 300)]
+	}
+	defer a.summarizePool.release()
+	resp, err := a.chat(a.withCall(ctx, CallSummarization), a.summarizer, []llm.Message{
 		{Role: "user", Content: prompt},
 	})
 	if err != nil {
@@ -432,7 +594,17 @@ Summary (2-3 sentences, facts only):`, title, url, content)
 	return stripThinkTags(resp)
 }
 
-func (a *DeepResearcher) parallelSearch(queries []string) string {
+// searchContext attaches the configured SearXNG categories, engines, and time range to ctx
+func (a *DeepResearcher) searchContext(ctx context.Context) context.Context {
+	return search.WithFilters(ctx, search.Filters{
+		Categories: a.config.Categories,
+		Engines:    a.config.Engines,
+		TimeRange:  a.config.TimeRange,
+	})
+}
+
+func (a *DeepResearcher) parallelSearch(ctx context.Context, queries []string) string {
+	ctx = a.searchContext(ctx)
 	var wg sync.WaitGroup
 	var mu sync.Mutex // Mutex for thread-safe source collection
 	resultsChan := make(chan string, len(queries))
@@ -445,19 +617,39 @@ func (a *DeepResearcher) parallelSearch(queries []string) string {
 	linkExtractor, canExtract := a.searcher.(search.LinkExtractor)
 	useDeepMode := a.config.DeepMode && canFetch
 
-	for _, q := range queries {
+	for qi, q := range queries {
 		wg.Add(1)
-		go func(query string) {
+		go func(qi int, query string) {
 			defer wg.Done()
 			sem <- struct{}{} // Acquire
 			defer func() { <-sem }() // Release
 
-			res, err := a.searcher.Search(query)
+			stat := QueryStat{Query: query}
+			defer func() {
+				a.recordQueries(stat)
+				a.emitQueryDone(qi, stat)
+			}()
+
+			err := a.spend(ctx, false)
+			var res []search.Result
+			if err == nil {
+				stat.Pages++
+				a.emitSearchProgress(StepSearch, qi, query, 1, "")
+				res, err = a.searcher.Search(ctx, query)
+			}
 			if err != nil {
+				stat.Errors++
 				resultsChan <- fmt.Sprintf("Error searching '%s': %v", query, err)
 				return
 			}
 
+			stat.Results = len(res)
+			res = a.filterResults(res)
+			relevant := a.relevantResults(ctx, query, res)
+			stat.Irrelevant = len(res) - len(relevant)
+			res = relevant
+			a.collectImages(res)
+			a.searchImages(ctx, query)
 			if len(res) == 0 {
 				resultsChan <- fmt.Sprintf("No results found for '%s'", query)
 				return
@@ -468,10 +660,20 @@ func (a *DeepResearcher) parallelSearch(queries []string) string {
 			
 			if useDeepMode && canExtract {
 				// DEEP MODE: Extract individual listing links from index pages, then fetch each
-				fmt.Printf("   🔗 [DEEP] Extracting individual listings from search results...\n")
+				a.log.Debug("🔗 [DEEP] Extracting individual listings from search results", "query", query)
 				
 				listingsProcessed := 0
-				maxListingsPerQuery := 5
+				maxListingsPerQuery := a.config.listingItems(a.config.listingsPerQuery())
+
+				// Pages are summarized while the next ones are fetched
+				type listing struct {
+					page   *deepPage
+					direct *search.Result // The search result, when an index page was read as a listing itself
+				}
+				var read []listing
+				summarizer := a.summarizeStage(ctx, func(url string) {
+					a.emitSearchProgress(StepSummarize, qi, query, 0, url)
+				})
 				
 				for _, r := range res {
 					if listingsProcessed >= maxListingsPerQuery {
@@ -479,20 +681,17 @@ func (a *DeepResearcher) parallelSearch(queries []string) string {
 					}
 					
 					// Extract listing links from this index page
-					fmt.Printf("   📄 [DEEP] Extracting links from: %s\n", r.URL)
-					links, err := linkExtractor.ExtractListingLinks(r.URL, 5)
+					a.log.Debug("📄 [DEEP] Extracting links", "url", r.URL)
+					links, err := a.listingLinks(ctx, linkExtractor, r.URL, maxListingsPerQuery)
 					
 					if err != nil || len(links) == 0 {
 						// Fallback: treat this URL as a listing itself (might be a direct listing)
-						fmt.Printf("   📄 [DEEP] No sub-links found, fetching page directly\n")
-						if rawContent, err := fetcher.FetchPageContent(r.URL, 6000); err == nil && len(rawContent) > 50 {
-							fmt.Printf("   🧠 [DEEP] Summarizing %d chars...\n", len(rawContent))
-							summary := a.summarizePage(r.URL, r.Title, rawContent)
-							sb.WriteString(fmt.Sprintf("- Title: %s\n  URL: %s\n  Details: %s\n", r.Title, r.URL, summary))
-							
-							mu.Lock()
-							a.sources = append(a.sources, Source{Title: r.Title, URL: r.URL})
-							mu.Unlock()
+						a.log.Debug("📄 [DEEP] No sub-links found, fetching page directly", "url", r.URL)
+						a.emitSearchProgress(StepFetch, qi, query, 0, r.URL)
+						if rawContent, archiveURL, published, err := a.fetchPage(ctx, fetcher, r.URL); err == nil && len(rawContent) > 50 && !a.isNearDuplicate(ctx, r.URL, rawContent) {
+							page := &deepPage{url: r.URL, title: r.Title, content: rawContent, archiveURL: archiveURL, fetchedAt: time.Now(), published: published}
+							summarizer.send(page)
+							read = append(read, listing{page: page, direct: &r})
 							listingsProcessed++
 						}
 						continue
@@ -503,24 +702,39 @@ func (a *DeepResearcher) parallelSearch(queries []string) string {
 						if listingsProcessed >= maxListingsPerQuery {
 							break
 						}
-						
-						fmt.Printf("   🏠 [DEEP] Fetching listing: %s\n", link.URL)
-						rawContent, err := fetcher.FetchPageContent(link.URL, 6000)
-						if err != nil || len(rawContent) < 50 {
+						if !a.config.allowsURL(link.URL) {
 							continue
 						}
 						
-						fmt.Printf("   🧠 [DEEP] Summarizing listing...\n")
-						summary := a.summarizePage(link.URL, link.Title, rawContent)
-						
-						sb.WriteString(fmt.Sprintf("- LISTING: %s\n  URL: %s\n  Details: %s\n", link.Title, link.URL, summary))
-						
-						mu.Lock()
-						a.sources = append(a.sources, Source{Title: link.Title, URL: link.URL})
-						mu.Unlock()
+						a.log.Debug("🏠 [DEEP] Fetching listing", "url", link.URL)
+						a.emitSearchProgress(StepFetch, qi, query, 0, link.URL)
+						rawContent, archiveURL, published, err := a.fetchPage(ctx, fetcher, link.URL)
+						if err != nil || len(rawContent) < 50 || a.isNearDuplicate(ctx, link.URL, rawContent) {
+							continue
+						}
+						page := &deepPage{url: link.URL, title: link.Title, content: rawContent, archiveURL: archiveURL, fetchedAt: time.Now(), published: published}
+						summarizer.send(page)
+						read = append(read, listing{page: page})
 						listingsProcessed++
 					}
 				}
+				summarizer.wait()
+
+				for _, l := range read {
+					p := l.page
+					source := Source{Title: p.title, URL: p.url, Summary: p.summary, FetchedAt: p.fetchedAt, ArchiveURL: p.archiveURL, Published: p.published}
+					if l.direct != nil {
+						source.Snippet = l.direct.Content
+						sb.WriteString(fmt.Sprintf("- Title: %s\n  URL: %s\n  Details: %s\n", p.title, p.url, p.summary))
+					} else {
+						sb.WriteString(fmt.Sprintf("- LISTING: %s\n  URL: %s\n  Details: %s\n", p.title, p.url, p.summary))
+					}
+					
+					mu.Lock()
+					a.sources = append(a.sources, source)
+					mu.Unlock()
+					stat.NewURLs++
+				}
 				
 				if listingsProcessed == 0 {
 					sb.WriteString("  (No individual listings could be extracted)\n")
@@ -535,13 +749,14 @@ func (a *DeepResearcher) parallelSearch(queries []string) string {
 					sb.WriteString(fmt.Sprintf("- Title: %s\n  URL: %s\n  Summary: %s\n", r.Title, r.URL, content))
 					
 					mu.Lock()
-					a.sources = append(a.sources, Source{Title: r.Title, URL: r.URL})
+					a.sources = append(a.sources, Source{Title: r.Title, URL: r.URL, Snippet: r.Content, Published: resultPublished(r)})
 					mu.Unlock()
+					stat.NewURLs++
 				}
 			}
 			
 			resultsChan <- sb.String()
-		}(q)
+		}(qi, q)
 	}
 
 	wg.Wait()
@@ -560,7 +775,7 @@ func (a *DeepResearcher) parallelSearch(queries []string) string {
 	return combinedResults.String()
 }
 
-func (a *DeepResearcher) summarize(topic, searchResults string) (string, error) {
+func (a *DeepResearcher) summarize(ctx context.Context, topic, searchResults string) (string, error) {
 	linkEmphasis := ""
 	if a.config.ResultLinks {
 		linkEmphasis = "\n\nCRITICAL: Extract and preserve ALL specific listing URLs (not category pages). Each item MUST have its own direct link in the format: [Title](URL)"
@@ -575,11 +790,11 @@ Extract and summarize SPECIFIC, CONCRETE information from these results:
 - Quote specific data points when available
 - If you see listings, extract: title, price, key details, and exact URL%s
 
-Keep it dense and factual. Cite the exact URL for each piece of information.
+Keep it dense and factual. Cite the exact URL for each piece of information.%s
 Do not use <think> tags.
-`, topic, searchResults, linkEmphasis)
+`, topic, searchResults, linkEmphasis, a.languageDirective())
 
-	resp, err := a.llmClient.Chat([]llm.Message{
+	resp, err := a.chat(a.withCall(ctx, CallSummarization), a.llmClient, []llm.Message{
 		{Role: "user", Content: prompt},
 	})
 	if err != nil {
@@ -588,66 +803,94 @@ Do not use <think> tags.
 	return stripThinkTags(resp), nil
 }
 
-func (a *DeepResearcher) writeReport(topic, context string) (string, error) {
-	maxChars := a.config.maxContextChars()
-	// Reserve ~40% of context for system prompt, topic, and response (more conservative)
-	maxContextChars := int(float64(maxChars) * 0.5)
+// writeReport writes the final report from the research context, citing the
+// sources by their 1-based position as [n]. The report is outlined and each
+// section written from only its findings (writeReportFromOutline), so the context
+// is never compressed. Drafts and SinglePassReport runs write the report in one
+// prompt while the context fits. When the backend still rejects a prompt as too
+// large, the budget is halved and the report retried; if that keeps failing, it
+// is written in parts and merged (writeReportMapReduce).
+func (a *DeepResearcher) writeReport(ctx context.Context, topic, context string, sources []Source) (string, error) {
+	// Reserve half of the context window for the prompt, topic, and response
+	budget := a.config.maxContextTokens() / 2
 	
-	// Retry loop with increasingly aggressive compression
-	maxRetries := 3
-	currentContext := context
+	// Halvings of the budget tried before writing the report in parts
+	maxBisections := 3
+
+	// Provided documents are data like the findings; when they don't fit, their
+	// chunks reach the sections they're relevant to instead
+	context += a.documentContext(sources)
+	context += priceNote(sources)
+
+	// The web UI shows the report as it is written
+	stream := a.newReportStream(ctx)
 	
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if len(currentContext) > maxContextChars {
-			fmt.Printf("📦 Report attempt %d: context (%d chars) exceeds limit (%d), compressing...\n", 
-				attempt, len(currentContext), maxContextChars)
-			
-			// Each retry compresses more aggressively
-			targetRatio := 0.5 / float64(attempt) // 0.5, 0.25, 0.167
-			compressed, err := a.compressContext(currentContext, targetRatio)
-			if err != nil {
-				fmt.Printf("⚠️ Compression attempt %d failed: %v\n", attempt, err)
-				// Hard truncate as fallback
-				if len(currentContext) > maxContextChars {
-					currentContext = currentContext[:maxContextChars]
-					fmt.Printf("   Hard truncated to %d chars\n", maxContextChars)
+	for attempt := 1; ; attempt++ {
+		// The numbered source list gets up to a third of the budget; sources
+		// past the cut can't be cited
+		sourcesText := sourceList(sources)
+		if tokens := a.countTokens(ctx, sourcesText); tokens > budget/3 {
+			sourcesText = a.truncateToTokens(ctx, sourcesText, budget/3)
+			sourcesText = sourcesText[:strings.LastIndexByte(sourcesText, '\n')+1]
+		}
+		maxContextTokens := budget - a.countTokens(ctx, sourcesText)
+
+		var report string
+		var err error
+		tokens := a.countTokens(ctx, context)
+		// A template's sections are written one by one, as outlined
+		singlePass := a.config.SinglePassReport && a.templateGuidance() == "" || isDraft(ctx)
+		if !singlePass || tokens > maxContextTokens {
+			if tokens > maxContextTokens {
+				a.log.Info("📚 Report context exceeds limit", "attempt", attempt, "tokens", tokens, "limit", maxContextTokens)
+			}
+			// The query and plan lead the context; they and any notes on the run brief every section
+			brief := a.truncateToTokens(ctx, context, budget/8)
+			for _, note := range contextNotes(context) {
+				if !strings.Contains(brief, note) {
+					brief += "\n" + note
 				}
-			} else {
-				currentContext = compressed
 			}
-		}
-		
-		// Try to generate the report
-		linkEmphasis := ""
-		if a.config.ResultLinks {
-			linkEmphasis = "\n\nCRITICAL: Include direct clickable links [Title](URL) for each item."
-		}
+			report, err = a.writeReportFromOutline(ctx, topic, brief, sources, budget, stream)
+		} else {
+			linkEmphasis := ""
+			if a.config.ResultLinks {
+				linkEmphasis = "\n\nCRITICAL: Include direct clickable links [Title](URL) for each item."
+			}
 
-		prompt := fmt.Sprintf(`Write a research report for: %s
+			prompt := fmt.Sprintf(`Write a research report for: %s
 
 Data:
 %s
 
-Format with Markdown. Include source URLs.%s`, topic, currentContext, linkEmphasis)
-
-		resp, err := a.llmClient.Chat([]llm.Message{
-			{Role: "user", Content: prompt},
-		})
+Sources:
+%s
+Format with Markdown. Cite sources inline by their number in square brackets, e.g. [3] or [2, 5], right after the facts they support. Only cite numbers from the Sources list and only link URLs that appear in it - never invent URLs. Don't add a references section; the bibliography is appended automatically.%s%s%s`, topic, context, sourcesText, linkEmphasis, a.reportGuidance(), a.languageDirective())
+
+			var resp string
+			resp, err = a.chatStream(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+				{Role: "user", Content: prompt},
+			}, stream)
+			stream.end()
+			report = stripThinkTags(resp)
+		}
 		
-		if err != nil {
-			if attempt < maxRetries && (strings.Contains(err.Error(), "context") || strings.Contains(err.Error(), "token")) {
-				fmt.Printf("⚠️ Report generation failed (attempt %d): %v\n", attempt, err)
-				// Reduce context size more aggressively for next attempt
-				maxContextChars = maxContextChars / 2
+		var overflow *llm.ContextOverflowError
+		if errors.As(err, &overflow) {
+			a.log.Warn("📏 Report prompt overflowed the model's context", "attempt", attempt, "budget", budget, "context_length", overflow.ContextLength, "prompt_tokens", overflow.PromptTokens)
+			budget = shrinkBudget(budget, overflow)
+			stream.restart()
+			if attempt <= maxBisections && budget >= minReportBudget {
 				continue
 			}
-			return "", fmt.Errorf("report generation failed after %d attempts: %w", attempt, err)
+			return a.writeReportMapReduce(ctx, topic, context, sources, budget)
+		}
+		if err != nil {
+			return "", fmt.Errorf("report generation failed: %w", err)
 		}
 		
-		return stripThinkTags(resp), nil
+		return report, nil
 	}
-	
-	return "", fmt.Errorf("failed to generate report after %d attempts", maxRetries)
 }
 
 // ========== EXHAUSTIVE MODE FUNCTIONS ==========
@@ -681,7 +924,7 @@ type QueryExpansion struct {
 }
 
 // generateQueryExpansions uses LLM to generate domain-specific synonyms and platforms
-func (a *DeepResearcher) generateQueryExpansions(topic string, baseQueries []string) (QueryExpansion, error) {
+func (a *DeepResearcher) generateQueryExpansions(ctx context.Context, topic string, baseQueries []string) (QueryExpansion, error) {
 	prompt := fmt.Sprintf(`Analyze this research topic and base queries to generate search expansion data.
 
 Topic: "%s"
@@ -716,26 +959,20 @@ Respond ONLY with valid JSON:
   "platforms": ["site:example1.com", "site:example2.com"]
 }`, topic, baseQueries)
 
-	resp, err := a.llmClient.Chat([]llm.Message{
+	var expansion QueryExpansion
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.llmClient, []llm.Message{
 		{Role: "system", Content: "You are a search optimization expert. Output only valid JSON. Be comprehensive with synonyms and platforms relevant to the specific topic and language."},
 		{Role: "user", Content: prompt},
-	})
-	if err != nil {
-		return QueryExpansion{}, err
-	}
-
-	resp = stripThinkTags(resp)
-	resp = strings.TrimPrefix(resp, "```json")
-	resp = strings.TrimPrefix(resp, "```")
-	resp = strings.TrimSuffix(resp, "```")
-	resp = strings.TrimSpace(resp)
-
-	var expansion QueryExpansion
-	if err := json.Unmarshal([]byte(resp), &expansion); err != nil {
+	}, queryExpansionSchema, &expansion)
+	var parseErr *llm.ParseError
+	if errors.As(err, &parseErr) {
 		// Return empty expansion on parse error - will just use base queries
-		fmt.Printf("   ⚠️ Could not parse query expansions, using base queries only\n")
+		a.log.Warn("⚠️ Could not parse query expansions, using base queries only")
 		return QueryExpansion{Synonyms: make(map[string][]string), Platforms: []string{}}, nil
 	}
+	if err != nil {
+		return QueryExpansion{}, err
+	}
 
 	return expansion, nil
 }
@@ -808,6 +1045,21 @@ func expandQueriesWithLLM(baseQueries []string, expansion QueryExpansion) []stri
 
 // CreatePlanExhaustive generates a research plan with pre-generated search queries
 func (a *DeepResearcher) CreatePlanExhaustive(topic string, additionalContext string) (ResearchPlan, error) {
+	return a.CreatePlanExhaustiveWithContext(context.Background(), topic, additionalContext)
+}
+
+// CreatePlanExhaustiveWithContext generates an exhaustive research plan with cancellation support
+func (a *DeepResearcher) CreatePlanExhaustiveWithContext(ctx context.Context, topic string, additionalContext string) (ResearchPlan, error) {
+	if len(a.config.SeedURLs) > 0 {
+		return a.seedPlan(topic, additionalContext), nil
+	}
+	if len(a.config.SitemapSites) > 0 {
+		return a.sitemapPlan(ctx, topic, additionalContext)
+	}
+	if len(a.config.CompareEntities) > 0 {
+		return a.comparisonPlan(ctx, topic, additionalContext)
+	}
+
 	contextInfo := ""
 	if additionalContext != "" {
 		contextInfo = fmt.Sprintf("\n\nAdditional context from user:\n%s", additionalContext)
@@ -815,7 +1067,7 @@ func (a *DeepResearcher) CreatePlanExhaustive(topic string, additionalContext st
 
 	prompt := fmt.Sprintf(`You are a Deep Research AI planning an EXHAUSTIVE data collection task.
 
-User's research request: "%s"%s
+User's research request: "%s"%s%s
 
 Your goal is to find AS MANY results as possible. Generate a research plan focused on comprehensive coverage.
 
@@ -839,44 +1091,35 @@ Respond ONLY with valid JSON:
   "research_steps": ["step1", "step2", "step3"],
   "expected_outcome": "...",
   "search_queries": ["short query 1", "short query 2", ...]
-}`, topic, contextInfo)
+}`, topic, contextInfo, a.planningGuidance(ctx))
 
-	resp, err := a.llmClient.Chat([]llm.Message{
+	var plan ResearchPlan
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.writer, []llm.Message{
 		{Role: "system", Content: "You are a research planning assistant. Output only valid JSON. Focus on generating diverse, comprehensive search queries without site: prefixes."},
 		{Role: "user", Content: prompt},
-	})
+	}, exhaustivePlanSchema, &plan)
 	if err != nil {
-		return ResearchPlan{}, err
-	}
-
-	resp = stripThinkTags(resp)
-	resp = strings.TrimPrefix(resp, "```json")
-	resp = strings.TrimPrefix(resp, "```")
-	resp = strings.TrimSuffix(resp, "```")
-	resp = strings.TrimSpace(resp)
-
-	var plan ResearchPlan
-	if err := json.Unmarshal([]byte(resp), &plan); err != nil {
-		return ResearchPlan{}, fmt.Errorf("failed to parse research plan: %w. Response: %s", err, resp)
+		return ResearchPlan{}, jsonError("research plan", err)
 	}
 
 	// Use LLM to generate domain-specific expansions
 	if len(plan.SearchQueries) > 0 {
-		fmt.Printf("🔍 Generating query expansions for topic...\n")
-		expansion, err := a.generateQueryExpansions(topic, plan.SearchQueries)
+		a.log.Info("🔍 Generating query expansions for topic")
+		expansion, err := a.generateQueryExpansions(ctx, topic, plan.SearchQueries)
 		if err != nil {
-			fmt.Printf("   ⚠️ Could not generate expansions: %v\n", err)
+			a.log.Warn("⚠️ Could not generate expansions", "error", err)
 			// Continue with base queries only
 		} else {
 			if len(expansion.Platforms) > 0 {
-				fmt.Printf("   📡 Found %d relevant platforms\n", len(expansion.Platforms))
+				a.log.Debug("📡 Found relevant platforms", "count", len(expansion.Platforms))
 			}
 			if len(expansion.Synonyms) > 0 {
-				fmt.Printf("   📝 Found synonyms for %d terms\n", len(expansion.Synonyms))
+				a.log.Debug("📝 Found synonyms", "terms", len(expansion.Synonyms))
 			}
 			plan.SearchQueries = expandQueriesWithLLM(plan.SearchQueries, expansion)
 		}
-		fmt.Printf("📋 Expanded to %d search queries\n", len(plan.SearchQueries))
+		plan.SearchQueries = a.dedupQueries(ctx, plan.SearchQueries)
+		a.log.Info("📋 Expanded search queries", "count", len(plan.SearchQueries))
 	}
 
 	return plan, nil
@@ -895,10 +1138,59 @@ func (a *DeepResearcher) RunExhaustive(topic string, plan ResearchPlan) (Researc
 // - Shows live progress
 // - On cancellation: proceeds to write report with results collected so far
 func (a *DeepResearcher) RunExhaustiveWithContext(ctx context.Context, topic string, plan ResearchPlan) (ResearchResult, error) {
-	// Reset state
+	if len(a.config.SeedURLs) > 0 {
+		return a.runSeeds(ctx, topic, plan, a.config.SeedURLs)
+	}
+	if len(a.config.SitemapSites) > 0 {
+		return a.runSitemap(ctx, topic, plan)
+	}
+	if len(a.config.CompareEntities) > 0 {
+		return a.runComparative(ctx, topic, plan)
+	}
+	return a.runExhaustive(ctx, &Checkpoint{Topic: topic, Plan: plan})
+}
+
+// ResumeExhaustive continues an exhaustive run from a checkpoint file
+func (a *DeepResearcher) ResumeExhaustive(checkpointPath string) (ResearchResult, error) {
+	return a.ResumeExhaustiveWithContext(context.Background(), checkpointPath)
+}
+
+// ResumeExhaustiveWithContext continues an exhaustive run from a checkpoint file with cancellation support.
+// Checkpointing continues into the same file unless Config.CheckpointPath says otherwise.
+func (a *DeepResearcher) ResumeExhaustiveWithContext(ctx context.Context, checkpointPath string) (ResearchResult, error) {
+	cp, err := LoadCheckpoint(checkpointPath)
+	if err != nil {
+		return ResearchResult{}, err
+	}
+	if a.config.CheckpointPath == "" {
+		a.config.CheckpointPath = checkpointPath
+	}
+	a.log.Info("♻️ Resuming from checkpoint", "round", cp.Round+1, "query", cp.QueryIndex, "queries", len(cp.Plan.SearchQueries), "sources", len(cp.Sources))
+	return a.runExhaustive(ctx, cp)
+}
+
+// runExhaustive is the shared exhaustive loop; a fresh run starts from an empty checkpoint
+func (a *DeepResearcher) runExhaustive(parent context.Context, cp *Checkpoint) (ResearchResult, error) {
+	topic := cp.Topic
+	plan := cp.Plan
+	ctx, stopBudget := a.startBudget(parent)
+	defer stopBudget()
+
+	// Reset state (restoring anything carried over from a checkpoint)
 	a.mu.Lock()
-	a.sources = make([]Source, 0)
+	a.sources = make([]Source, 0, len(cp.Sources))
+	a.sources = append(a.sources, cp.Sources...)
+	if len(a.sources) == 0 {
+		a.sources = a.documentSources()
+	}
+	a.records = append([]map[string]any(nil), cp.Records...)
+	a.findings = nil
+	a.queryStats = append([]QueryStat(nil), cp.QueryStats...)
+	a.images = append([]Image(nil), cp.Images...)
 	a.seenURLs = make(map[string]bool)
+	for _, u := range cp.SeenURLs {
+		a.seenURLs[u] = true
+	}
 	a.mu.Unlock()
 
 	if len(plan.SearchQueries) == 0 {
@@ -916,16 +1208,18 @@ func (a *DeepResearcher) RunExhaustiveWithContext(ctx context.Context, topic str
 		Percent:     5,
 	})
 
-	fmt.Printf("\n🔥 Starting Exhaustive Research for: %s\n", topic)
+	a.log.Info("🔥 Starting Exhaustive Research", "topic", topic)
 	pagesDesc := "auto (until empty)"
 	if a.config.MaxPages > 0 {
 		pagesDesc = fmt.Sprintf("%d", a.config.MaxPages)
 	}
-	fmt.Printf("📋 Processing %d search queries, pages: %s\n", len(plan.SearchQueries), pagesDesc)
-	fmt.Printf("🎯 Target: %d unique results | ⏱️ Delay: %dms between requests\n\n", a.config.MinResults, a.config.DelayMs)
+	a.log.Info("📋 Processing search queries", "queries", len(plan.SearchQueries), "pages", pagesDesc)
+	a.log.Info("🎯 Target", "unique_results", a.config.MinResults, "delay", time.Duration(a.config.DelayMs)*time.Millisecond)
 
-	// Build initial context
-	researchContext := fmt.Sprintf(`User Query: %s
+	// Build initial context (or pick up where the checkpoint left off)
+	researchContext := cp.Context
+	if researchContext == "" {
+		researchContext = fmt.Sprintf(`User Query: %s
 
 Research Plan:
 - Understanding: %s
@@ -933,27 +1227,57 @@ Research Plan:
 
 Knowledge gathered:
 `, topic, plan.UnderstandingSummary, plan.ExpectedOutcome)
+	}
+
+	a.startProgress(topic, researchContext, cp.Round)
+	a.resetSearchProgress(searchProgress{
+		totalQueries:   len(plan.SearchQueries),
+		plannedQueries: cp.QueryIndex + (a.config.MaxLoops-cp.Round)*a.config.ParallelQuery, // The rounds may end the run before the queries do
+		totalRounds:    a.config.MaxLoops,
+		targetURLs:     a.config.MinResults,
+		queriesDone:    cp.QueryIndex,
+		duplicates:     cp.TotalDuplicates,
+	})
+
+	// Checkpointed runs append round results to a findings log on disk instead
+	// of researchContext (checkpoints from before the log carry them in Context)
+	var findings *findingsLog
+	if a.config.CheckpointPath != "" && (cp.Context == "" || cp.FindingsLog != "") {
+		path := cp.FindingsLog
+		if path == "" {
+			path = findingsLogPath(a.config.CheckpointPath)
+		}
+		if l, err := openFindingsLog(path, cp.Round); err != nil {
+			a.log.Warn("⚠️ Keeping findings in memory", "error", err)
+		} else {
+			findings = l
+			a.mu.Lock()
+			a.progress.findings = l
+			a.mu.Unlock()
+		}
+	}
 
 	queriesPerRound := a.config.ParallelQuery
 	totalQueries := len(plan.SearchQueries)
-	queryIndex := 0
+	queryIndex := cp.QueryIndex
 	
 	// Stats tracking
-	totalURLsFound := 0
-	totalDuplicates := 0
+	totalURLsFound := len(cp.Sources)
+	totalDuplicates := cp.TotalDuplicates
 	cancelled := false
+	requeued := make(map[string]bool) // Throttled queries already given another turn
 
-	for round := 0; round < a.config.MaxLoops && queryIndex < totalQueries; round++ {
+	for round := cp.Round; round < a.config.MaxLoops && queryIndex < totalQueries; round++ {
 		// Check for cancellation at start of each round
 		select {
 		case <-ctx.Done():
-			fmt.Printf("\n⚠️ Research cancelled - proceeding to write report with %d results collected\n", len(a.sources))
+			a.log.Warn("⚠️ Research cancelled - proceeding to write report", "results", len(a.sources))
 			cancelled = true
 			goto writeReport
 		default:
 		}
 
-		fmt.Printf("=== Round %d/%d ===\n", round+1, a.config.MaxLoops)
+		a.log.Info("=== Round ===", "round", round+1, "of", a.config.MaxLoops)
 
 		// Get queries for this round
 		endIndex := queryIndex + queriesPerRound
@@ -968,7 +1292,7 @@ Knowledge gathered:
 		currentURLs := len(a.sources)
 		a.mu.Unlock()
 		
-		progressPercent := 5 + (round * 80 / a.config.MaxLoops) // 5-85% for search phase
+		progressPercent := a.searchPercent()
 		a.emitProgress(ProgressEvent{
 			Phase:       "searching",
 			Round:       round + 1,
@@ -979,16 +1303,17 @@ Knowledge gathered:
 			Percent:     progressPercent,
 		})
 
-		fmt.Printf("🔎 Processing queries %d-%d of %d\n", queryIndex-len(roundQueries)+1, queryIndex, totalQueries)
+		a.log.Info("🔎 Processing queries", "from", queryIndex-len(roundQueries)+1, "to", queryIndex, "of", totalQueries)
 
 		// Process queries with pagination (supports mid-search cancellation)
-		roundResults, newURLs, duplicates, searchErrors, searchCancelled := a.searchWithPagination(ctx, roundQueries)
+		a.startSearchBatch(round+1, queryIndex-len(roundQueries), len(roundQueries))
+		roundResults, newURLs, duplicates, searchErrors, throttled, searchCancelled := a.searchWithPagination(ctx, roundQueries)
 		totalURLsFound += newURLs
 		totalDuplicates += duplicates
 
 		// Check if cancelled during search
 		if searchCancelled {
-			fmt.Printf("\n⚠️ Search cancelled mid-round, proceeding to report generation...\n")
+			a.log.Warn("⚠️ Search cancelled mid-round, proceeding to report generation")
 			cancelled = true
 			goto writeReport
 		}
@@ -1009,65 +1334,63 @@ Knowledge gathered:
 		}
 
 		if roundResults != "" {
-			researchContext += fmt.Sprintf("\n--- Round %d Results ---\n%s", round+1, roundResults)
-		}
-
-		// Context compression check: compress when context exceeds 50% of max capacity
-		maxChars := a.config.maxContextChars()
-		compressionThreshold := int(float64(maxChars) * 0.5)
-		if len(researchContext) > compressionThreshold {
-			a.emitProgress(ProgressEvent{
-				Phase:       "compressing",
-				Round:       round + 1,
-				TotalRounds: a.config.MaxLoops,
-				URLsFound:   currentURLs,
-				TargetURLs:  a.config.MinResults,
-				Message:     "Compressing context to fit model limits...",
-				Percent:     progressPercent,
-			})
-			
-			fmt.Printf("📦 Context size (%d chars) exceeds threshold (%d), compressing...\n", 
-				len(researchContext), compressionThreshold)
-			compressed, err := a.compressContext(researchContext, 0.5)
-			if err != nil {
-				fmt.Printf("⚠️ Context compression failed: %v (continuing with full context)\n", err)
-			} else {
-				researchContext = compressed
+			digest := a.roundDigest(ctx, topic, round+1, roundResults)
+			logged := false
+			if findings != nil {
+				if err := findings.append(round+1, roundResults, digest); err != nil {
+					a.log.Warn("⚠️ Could not log findings; keeping the round in memory", "error", err)
+				} else {
+					logged = true
+				}
+			}
+			if !logged {
+				researchContext += roundSection(round+1, roundResults, digest)
 			}
 		}
 
+		totalQueries = a.requeueThrottled(&plan, totalQueries, throttled, requeued)
+		totalQueries = a.scheduleQueries(&plan, queryIndex, totalQueries, newURLs, len(roundQueries))
+		a.updateProgress(researchContext, round+1)
+		a.saveCheckpoint(topic, plan, round+1, queryIndex, researchContext, totalDuplicates)
+
+		// With a deadline, keep only the queries the time left still fits
+		totalQueries = a.pruneQueries(queryIndex, totalQueries, queryIndex-cp.QueryIndex)
+
 		// Check if we've hit the minimum
 		a.mu.Lock()
 		currentUniqueCount := len(a.sources)
 		a.mu.Unlock()
 
-		fmt.Printf("📊 Round %d complete: %d new URLs, %d duplicates skipped\n", round+1, newURLs, duplicates)
-		fmt.Printf("📈 Total progress: %d unique listings", currentUniqueCount)
+		a.log.Info("📊 Round complete", "round", round+1, "new_urls", newURLs, "duplicates", duplicates)
+		a.log.Info("📈 Total progress", "unique", currentUniqueCount, "target", a.config.MinResults)
 		
 		if currentUniqueCount >= a.config.MinResults {
-			fmt.Printf(" ✅ Target reached!\n\n")
-			fmt.Printf("🎯 Stopping early: found %d unique listings (target: %d)\n", currentUniqueCount, a.config.MinResults)
+			a.log.Info("🎯 Target reached, stopping early", "unique", currentUniqueCount, "target", a.config.MinResults)
 			break
 		}
-		fmt.Printf(" (target: %d)\n\n", a.config.MinResults)
 	}
 
 writeReport:
 	// Final stats
+	usage := a.finishBudget(ctx)
+	stopReason := "search cancelled"
+	if usage.Exhausted != "" {
+		stopReason = usage.Exhausted
+	}
 	a.mu.Lock()
 	finalCount := len(a.sources)
 	a.mu.Unlock()
 
 	if cancelled {
-		fmt.Printf("\n📊 Partial stats (cancelled): %d unique URLs collected, %d duplicates skipped\n", finalCount, totalDuplicates)
+		a.log.Info("📊 Partial stats (cancelled)", "unique_urls", finalCount, "duplicates", totalDuplicates)
 	} else {
-		fmt.Printf("\n📊 Final stats: %d unique URLs collected, %d duplicates skipped\n", finalCount, totalDuplicates)
+		a.log.Info("📊 Final stats", "unique_urls", finalCount, "duplicates", totalDuplicates)
 	}
 
 	// Emit writing report event
 	reportMessage := "Writing final report..."
 	if cancelled {
-		reportMessage = "Writing partial report (search cancelled)..."
+		reportMessage = fmt.Sprintf("Writing partial report (%s)...", stopReason)
 	}
 	a.emitProgress(ProgressEvent{
 		Phase:       "writing_report",
@@ -1079,50 +1402,88 @@ writeReport:
 		Percent:     90,
 	})
 
+	// Read the logged rounds back for the report (the log itself is kept)
+	if findings != nil {
+		if logged, err := findings.context(); err != nil {
+			a.log.Warn("⚠️ Could not read the findings log; writing the report from the sources", "error", err)
+		} else {
+			researchContext += logged
+		}
+		a.log.Info("🗂️ Raw findings kept", "path", findings.path)
+	}
+
 	// Write report
 	if cancelled {
-		fmt.Println("\n✍️ Writing Partial Report (search was cancelled)...")
+		a.log.Info("✍️ Writing Partial Report", "reason", stopReason)
 		// Add note to context about partial results
-		researchContext += "\n\n--- NOTE: Research was cancelled early. Results may be incomplete. ---\n"
+		researchContext += fmt.Sprintf("\n\n--- NOTE: Research stopped early (%s). Results may be incomplete. ---\n", stopReason)
 	} else {
-		fmt.Println("\n✍️ Writing Final Report...")
+		a.log.Info("✍️ Writing Final Report")
+	}
+	if usage.QueriesSkipped > 0 {
+		researchContext += fmt.Sprintf("\n\n--- NOTE: %d planned search queries were skipped to finish by the deadline. Results may be incomplete. ---\n", usage.QueriesSkipped)
 	}
-	report, err := a.writeReport(topic, researchContext)
+	// A cancelled search still gets its partial report, so detach the report from the cancellation
+	// (budgets only cover the research phase)
+	reportCtx, err := reportContext(parent)
 	if err != nil {
 		return ResearchResult{}, err
 	}
-
 	a.mu.Lock()
 	sources := make([]Source, len(a.sources))
 	copy(sources, a.sources)
+	records := append([]map[string]any(nil), a.records...)
+	queryStats := append([]QueryStat(nil), a.queryStats...)
+	images := append([]Image(nil), a.images...)
 	a.mu.Unlock()
 
+	a.normalizePrices(sources, records)
+	sources, records, areaNote := a.filterArea(reportCtx, sources, records)
+	researchContext += areaNote
+	conflicts, consensus := a.analyzeClaims(reportCtx, sources)
+	researchContext += conflictNote(conflicts)
+	report, err := a.writeReport(reportCtx, topic, researchContext, sources)
+	if err != nil {
+		return ResearchResult{}, err
+	}
+	result := a.finishResult(reportCtx, topic, plan, report, gathered{sources: sources, records: records, images: images, queryStats: queryStats, conflicts: conflicts, consensus: consensus})
+	result.Usage = a.withTokens(usage)
+
+	// Run finished cleanly - the checkpoint is no longer needed (a run stopped by a budget can be resumed)
+	if a.config.CheckpointPath != "" && !cancelled && usage.QueriesSkipped == 0 {
+		os.Remove(a.config.CheckpointPath)
+	}
+
 	// Emit complete event
 	a.emitProgress(ProgressEvent{
 		Phase:       "complete",
 		Round:       a.config.MaxLoops,
 		TotalRounds: a.config.MaxLoops,
-		URLsFound:   len(sources),
+		URLsFound:   len(result.Sources),
 		TargetURLs:  a.config.MinResults,
-		Message:     fmt.Sprintf("Research complete! Found %d unique results.", len(sources)),
+		Message:     fmt.Sprintf("Research complete! Found %d unique results.", len(result.Sources)),
 		Percent:     100,
 	})
 
-	return ResearchResult{Report: report, Sources: sources}, nil
+	return result, nil
 }
 
 // searchWithPagination searches queries across multiple pages with rate limiting
-// Returns early with partial results if context is cancelled
-func (a *DeepResearcher) searchWithPagination(ctx context.Context, queries []string) (string, int, int, []string, bool) {
+// Returns early with partial results if context is cancelled. Queries whose
+// first page was still throttled (429/503) after the retries are returned
+// separately from the errors so the caller can reschedule them.
+func (a *DeepResearcher) searchWithPagination(ctx context.Context, queries []string) (string, int, int, []string, []string, bool) {
+	ctx = a.searchContext(ctx)
 	var results strings.Builder
 	newURLs := 0
 	duplicates := 0
 	var searchErrors []string
+	var throttled []string
 	cancelled := false
 
 	// Check if searcher supports pagination
 	type paginatedSearcher interface {
-		SearchWithPage(query string, page int) ([]search.Result, error)
+		SearchWithPage(ctx context.Context, query string, page int) ([]search.Result, error)
 	}
 	pagSearcher, canPaginate := a.searcher.(paginatedSearcher)
 	
@@ -1130,8 +1491,16 @@ func (a *DeepResearcher) searchWithPagination(ctx context.Context, queries []str
 	fetcher, canFetch := a.searcher.(search.ContentFetcher)
 	useDeepMode := a.config.DeepMode && canFetch
 
+	// Recorded once the queries are done, including when cancelled mid-way
+	var stats []QueryStat
+	defer func() { a.recordQueries(stats...) }()
+
 queryLoop:
-	for _, query := range queries {
+	for qi, query := range queries {
+		stats = append(stats, QueryStat{Query: query})
+		stat := &stats[len(stats)-1]
+		rescheduled := false // Throttled; the query is searched again later
+
 		// Check for cancellation before each query
 		select {
 		case <-ctx.Done():
@@ -1158,77 +1527,162 @@ queryLoop:
 			if a.config.DelayMs > 0 {
 				time.Sleep(time.Duration(a.config.DelayMs) * time.Millisecond)
 			}
+			if canPaginate || page == 1 {
+				if a.spend(ctx, false) != nil {
+					cancelled = true
+					break queryLoop
+				}
+			}
 
 			var searchResults []search.Result
 			var err error
 			
+			if canPaginate || page == 1 {
+				stat.Pages++
+				a.emitSearchProgress(StepSearch, qi, query, page, "")
+			}
 			if canPaginate {
-				searchResults, err = pagSearcher.SearchWithPage(query, page)
+				searchResults, err = pagSearcher.SearchWithPage(ctx, query, page)
 			} else {
 				if page == 1 {
-					searchResults, err = a.searcher.Search(query)
+					searchResults, err = a.searcher.Search(ctx, query)
 				} else {
 					break // Skip pagination if not supported
 				}
 			}
 
 			if err != nil {
+				stat.Errors++
+				if page == 1 && retry.IsThrottled(err) && ctx.Err() == nil {
+					a.log.Info("⏸️ Search throttled; rescheduling the query", "query", query, "error", err)
+					throttled = append(throttled, query)
+					rescheduled = true
+					break
+				}
 				errMsg := fmt.Sprintf("Search '%s': %v", truncateQuery(query, 30), err)
-				fmt.Printf("   ❌ Error searching '%s' (page %d): %v\n", query, page, err)
+				a.log.Warn("❌ Search failed", "query", query, "page", page, "error", err)
 				searchErrors = append(searchErrors, errMsg)
 				break // Stop this query on error
 			}
 
 			if len(searchResults) == 0 {
 				if page == 1 {
-					fmt.Printf("   [%s] page %d → 0 results\n", truncateQuery(query, 40), page)
+					a.log.Debug("🔎 No results", "query", truncateQuery(query, 40), "page", page)
 				}
 				break // No more results for this query
 			}
 
-			fmt.Printf("   [%s] page %d → %d results\n", truncateQuery(query, 40), page, len(searchResults))
+			stat.Results += len(searchResults)
+			kept := a.filterResults(searchResults)
+			if filtered := len(searchResults) - len(kept); filtered > 0 {
+				a.log.Debug("🔎 Results", "query", truncateQuery(query, 40), "page", page, "results", len(kept), "filtered", filtered)
+			} else {
+				a.log.Debug("🔎 Results", "query", truncateQuery(query, 40), "page", page, "results", len(searchResults))
+			}
+			searchResults = kept
+			a.collectImages(searchResults)
+			if page == 1 {
+				a.searchImages(ctx, query)
+			}
 
-			// Process results
+			// Keep the results not seen before
+			var fresh []search.Result
 			for _, r := range searchResults {
 				normalizedURL := normalizeURL(r.URL)
 				
 				a.mu.Lock()
 				if a.seenURLs[normalizedURL] {
+					a.searchProgress.duplicates++
 					a.mu.Unlock()
 					duplicates++
+					stat.Duplicates++
 					continue
 				}
 				a.seenURLs[normalizedURL] = true
 				a.mu.Unlock()
+				fresh = append(fresh, r)
+			}
+			relevant := a.relevantResults(ctx, query, fresh)
+			stat.Irrelevant += len(fresh) - len(relevant)
+			fresh = relevant
+			if useDeepMode {
+				// Listing index pages among the results add the items they lead to
+				fresh = append(fresh, a.listingResults(ctx, fresh)...)
+			}
 
+			// Deep mode: fetch and summarize their pages concurrently (bounded by
+			// the worker pools; per-host throttling is up to the searcher, see
+			// search.RateLimitedSearcher)
+			var pages []deepPage
+			if useDeepMode {
+				pages = a.readPages(ctx, fetcher, qi, query, page, fresh)
+			}
+
+			// Process results, in the engine's order
+			for i, r := range fresh {
+				source := Source{Title: r.Title, URL: r.URL, Snippet: r.Content, Published: resultPublished(r)}
+				if pages != nil && pages[i].tooOld {
+					continue
+				}
+				if pages != nil && pages[i].duplicate {
+					a.countDuplicate()
+					duplicates++
+					stat.Duplicates++
+					continue
+				}
 				newURLs++
+				stat.NewURLs++
 
 				// Add to results
-				if useDeepMode {
-					// Fetch and summarize page content
-					if a.config.DelayMs > 0 {
-						time.Sleep(time.Duration(a.config.DelayMs) * time.Millisecond)
-					}
-					content, err := fetcher.FetchPageContent(r.URL, 6000)
-					if err == nil && len(content) > 50 {
-						summary := a.summarizePage(r.URL, r.Title, content)
-						results.WriteString(fmt.Sprintf("- LISTING: %s\n  URL: %s\n  Details: %s\n\n", r.Title, r.URL, summary))
-					} else {
-						results.WriteString(fmt.Sprintf("- %s\n  URL: %s\n  Snippet: %s\n\n", r.Title, r.URL, r.Content))
+				if pages != nil && pages[i].content != "" {
+					source.FetchedAt = pages[i].fetchedAt
+					source.ArchiveURL = pages[i].archiveURL
+					source.Summary = pages[i].summary
+					if !pages[i].published.IsZero() {
+						source.Published = pages[i].published
 					}
+					results.WriteString(fmt.Sprintf("- LISTING: %s\n  URL: %s\n  Details: %s\n\n", r.Title, r.URL, source.Summary))
 				} else {
 					results.WriteString(fmt.Sprintf("- %s\n  URL: %s\n  Snippet: %s\n\n", r.Title, r.URL, r.Content))
 				}
 
 				// Track source
 				a.mu.Lock()
-				a.sources = append(a.sources, Source{Title: r.Title, URL: r.URL})
+				a.sources = append(a.sources, source)
 				a.mu.Unlock()
 			}
 		}
+		if !rescheduled {
+			a.emitQueryDone(qi, *stat)
+		}
 	}
 
-	return results.String(), newURLs, duplicates, searchErrors, cancelled
+	return results.String(), newURLs, duplicates, searchErrors, throttled, cancelled
+}
+
+// requeueThrottled gives queries the search engine throttled another turn
+// after the other total planned ones (once each, tracked in requeued), so the
+// engine has time to recover instead of the queries being lost. Returns the
+// new number of planned queries.
+func (a *DeepResearcher) requeueThrottled(plan *ResearchPlan, total int, throttled []string, requeued map[string]bool) int {
+	var again []string
+	for _, q := range throttled {
+		if !requeued[q] {
+			requeued[q] = true
+			again = append(again, q)
+		}
+	}
+	if len(again) == 0 {
+		return total
+	}
+	a.log.Info("🔁 Rescheduled throttled queries", "queries", len(again))
+	plan.SearchQueries = slices.Insert(plan.SearchQueries, total, again...)
+
+	a.mu.Lock()
+	a.searchProgress.totalQueries += len(again)
+	a.searchProgress.plannedQueries += len(again)
+	a.mu.Unlock()
+	return total + len(again)
 }
 
 // truncateQuery truncates a query for display
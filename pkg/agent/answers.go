@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"strings"
+)
+
+// QuestionAnswer is the user's answer to one of the plan's clarifying questions
+type QuestionAnswer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// questionsResponse is the planner's answer to ClarifyingQuestions
+type questionsResponse struct {
+	ClarifyingQuestions []string `json:"clarifying_questions"`
+}
+
+// ClarifyingQuestions asks the planner what it needs to know about topic before
+// planning, so the plan can be built from the answers (CreatePlanWithAnswers)
+// instead of revised after them. A specific topic gets no questions; neither
+// do runs of seed URLs, sitemaps, and comparisons, whose plans don't ask any.
+func (a *DeepResearcher) ClarifyingQuestions(ctx context.Context, topic string) ([]string, error) {
+	if len(a.config.SeedURLs) > 0 || len(a.config.SitemapSites) > 0 || len(a.config.CompareEntities) > 0 {
+		return nil, nil
+	}
+
+	prompt := fmt.Sprintf(`You are a Deep Research AI about to plan a research task.%s
+
+User's research request: "%s"
+
+Before planning, what do you need to know to find exactly what the user wants? Ask 2-4 short, specific questions whose answers change what gets searched for (price ranges, locations, time frames, must-have criteria, ...). Don't ask what the request already says; if it is specific enough, ask none.
+
+Respond ONLY with valid JSON:
+{"clarifying_questions": ["question1", "question2"]}`, a.planningGuidance(ctx), topic)
+
+	var resp questionsResponse
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.writer, []llm.Message{
+		{Role: "system", Content: "You are a research planning assistant. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, questionsSchema, &resp)
+	if err != nil {
+		return nil, jsonError("clarifying questions", err)
+	}
+
+	var questions []string
+	for _, q := range resp.ClarifyingQuestions {
+		if q = strings.TrimSpace(q); q != "" {
+			questions = append(questions, q)
+		}
+	}
+	return questions, nil
+}
+
+// CreatePlanWithAnswers regenerates the plan from the user's per-question answers
+// (plus optional free-text feedback). Uses the exhaustive planner unless
+// Config.SimpleMode is set. The answers are stored on the returned plan so later
+// revisions can build on them.
+func (a *DeepResearcher) CreatePlanWithAnswers(ctx context.Context, topic string, answers []QuestionAnswer, additionalContext string) (ResearchPlan, error) {
+	hint := formatAnswers(answers)
+	if additionalContext != "" {
+		if hint != "" {
+			hint += "\n\n"
+		}
+		hint += additionalContext
+	}
+
+	var plan ResearchPlan
+	var err error
+	if a.config.SimpleMode {
+		plan, err = a.CreatePlanWithContext(ctx, topic, hint)
+	} else {
+		plan, err = a.CreatePlanExhaustiveWithContext(ctx, topic, hint)
+	}
+	if err != nil {
+		return ResearchPlan{}, err
+	}
+
+	plan.Answers = answers
+	return plan, nil
+}
+
+// formatAnswers renders answered questions as planner context
+func formatAnswers(answers []QuestionAnswer) string {
+	if len(answers) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The user answered these clarifying questions. Treat the answers as firm requirements, do NOT ask these questions again, and only ask clarifying questions that are still open:\n")
+	for _, qa := range answers {
+		sb.WriteString(fmt.Sprintf("- Q: %s\n  A: %s\n", qa.Question, qa.Answer))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// MergeAnswers combines earlier answers with new ones; a new answer to the same
+// question replaces the old one, and blank answers are ignored
+func MergeAnswers(previous, updated []QuestionAnswer) []QuestionAnswer {
+	merged := make([]QuestionAnswer, 0, len(previous)+len(updated))
+	index := make(map[string]int)
+	for _, qa := range append(append([]QuestionAnswer(nil), previous...), updated...) {
+		qa.Question = strings.TrimSpace(qa.Question)
+		qa.Answer = strings.TrimSpace(qa.Answer)
+		if qa.Question == "" || qa.Answer == "" {
+			continue
+		}
+		key := strings.ToLower(qa.Question)
+		if i, ok := index[key]; ok {
+			merged[i] = qa
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, qa)
+	}
+	return merged
+}
@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/search"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is the cause a run's research context is cancelled with
+// once Config.MaxLLMCalls or MaxHTTPRequests runs out, or when only the
+// report's share of Config.MaxDuration is left
+var ErrBudgetExhausted = errors.New("research budget exhausted")
+
+// Usage is what the research phase of a run spent against the Config budgets.
+// The final report is written after research stops and is not counted, except
+// in the token totals, which cover the whole run.
+type Usage struct {
+	LLMCalls         int                       // Chat completions (page summaries, decisions, extraction, ...)
+	HTTPRequests     int                       // Searches, page fetches, link extractions (cache hits included), and snippet enrichment's page reads
+	Duration         time.Duration             // Wall-clock time of the research phase
+	Exhausted        string                    `json:",omitempty"` // The budget that stopped research early, or ErrFinished's text after Finish ("" = none)
+	QueriesSkipped   int                       `json:",omitempty"` // Planned queries dropped to finish by the deadline
+	DeadEndQueries   int                       `json:",omitempty"` // Planned queries skipped once the research stalled, their platform or words having found nothing new
+	PromptTokens     int                       `json:",omitempty"` // Prompt tokens of the run's chat calls, planning and report included, as the backend reported them
+	CompletionTokens int                       `json:",omitempty"` // Tokens those calls generated
+	Tokens           map[string]llm.TokenUsage `json:",omitempty"` // The same by call type (CallPlanning, ...)
+	Cost             float64                   `json:",omitempty"` // USD at Config.PromptPrice and CompletionPrice (0 = no prices set)
+}
+
+// budget tracks one run's spending; inactive outside the research phase
+type budget struct {
+	mu             sync.Mutex
+	active         bool
+	llmCalls       int
+	httpRequests   int
+	queriesSkipped int
+	deadEndQueries int
+	started        time.Time
+	deadline       time.Time // When research must stop to leave time for the report (zero = no deadline)
+	cancel         context.CancelCauseFunc
+}
+
+// startBudget begins counting a run's LLM calls and HTTP requests. The returned
+// context is cancelled as soon as a budget runs out, with an ErrBudgetExhausted
+// cause, so the run loops stop as they do on cancellation and write the report
+// from what they have. With MaxDuration, research stops early enough to leave
+// the report its reserve. The context also counts the page requests searches
+// make on their own (search.WithRequestBudget). stop releases the context.
+func (a *DeepResearcher) startBudget(parent context.Context) (ctx context.Context, stop func()) {
+	started := time.Now()
+	ctx, cancel := context.WithCancelCause(parent)
+	stops := []func(){func() { cancel(nil) }}
+	ctx = search.WithRequestBudget(ctx, func(ctx context.Context) error { return a.spend(ctx, false) })
+
+	var researchDeadline time.Time
+	if deadline := a.config.runDeadline(started); !deadline.IsZero() {
+		reserve := a.config.reportReserve(deadline.Sub(started))
+		researchDeadline = deadline.Add(-reserve)
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadlineCause(ctx, researchDeadline,
+			fmt.Errorf("%w: time limit (%s) near, keeping %s for the report", ErrBudgetExhausted, a.config.MaxDuration, reserve.Round(time.Second)))
+		stops = append(stops, cancelDeadline)
+		a.log.Info("⏱️ Research deadline", "finish_by", deadline.Format(time.TimeOnly), "research_until", researchDeadline.Format(time.TimeOnly), "report_reserve", reserve.Round(time.Second))
+	}
+	stop = func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+
+	a.budget.mu.Lock()
+	a.budget.active = true
+	a.budget.llmCalls = 0
+	a.budget.httpRequests = 0
+	a.budget.queriesSkipped = 0
+	a.budget.deadEndQueries = 0
+	a.budget.started = started
+	a.budget.deadline = researchDeadline
+	a.budget.cancel = cancel
+	a.budget.mu.Unlock()
+	return ctx, stop
+}
+
+// finishBudget ends the research phase and reports what it spent
+func (a *DeepResearcher) finishBudget(ctx context.Context) Usage {
+	a.budget.mu.Lock()
+	a.budget.active = false
+	usage := Usage{
+		LLMCalls:       a.budget.llmCalls,
+		HTTPRequests:   a.budget.httpRequests,
+		Duration:       time.Since(a.budget.started),
+		QueriesSkipped: a.budget.queriesSkipped,
+		DeadEndQueries: a.budget.deadEndQueries,
+	}
+	a.budget.mu.Unlock()
+
+	if err := budgetExhausted(ctx); errors.Is(err, ErrFinished) {
+		usage.Exhausted = err.Error()
+		a.log.Info("⏹️ Research finished on request, writing the report from what was gathered", "llm_calls", usage.LLMCalls, "http_requests", usage.HTTPRequests)
+	} else if err != nil {
+		usage.Exhausted = err.Error()
+		a.log.Warn("💸 Budget exhausted, writing the report from what was gathered", "reason", err, "llm_calls", usage.LLMCalls, "http_requests", usage.HTTPRequests)
+	}
+	return usage
+}
+
+// budgetExhausted returns why ctx was cancelled if a budget ran out or Finish
+// was called, else nil
+func budgetExhausted(ctx context.Context) error {
+	if err := context.Cause(ctx); errors.Is(err, ErrBudgetExhausted) || errors.Is(err, ErrFinished) {
+		return err
+	}
+	return nil
+}
+
+// spend counts one LLM call or HTTP request, refusing it (and stopping the
+// research phase) once its budget is used up. While the research is paused it
+// waits for Resume.
+func (a *DeepResearcher) spend(ctx context.Context, llmCall bool) error {
+	if err := a.pause.wait(ctx); err != nil {
+		return err
+	}
+	a.budget.mu.Lock()
+	defer a.budget.mu.Unlock()
+	if !a.budget.active {
+		return nil
+	}
+
+	count, limit, name := &a.budget.httpRequests, a.config.MaxHTTPRequests, "HTTP request"
+	if llmCall {
+		count, limit, name = &a.budget.llmCalls, a.config.MaxLLMCalls, "LLM call"
+	}
+	if limit > 0 && *count >= limit {
+		err := fmt.Errorf("%w: %s limit (%d) reached", ErrBudgetExhausted, name, limit)
+		a.budget.cancel(err)
+		return err
+	}
+	*count++
+	return nil
+}
+
+// chat sends messages to p, counting the call against Config.MaxLLMCalls
+// (draft reports written on request are not counted). The call type of ctx
+// (see withCall) picks its Config.CallSettings, and its Config.CallProviders
+// over p.
+func (a *DeepResearcher) chat(ctx context.Context, p llm.Provider, messages []llm.Message) (string, error) {
+	if !isDraft(ctx) {
+		if err := a.spend(ctx, true); err != nil {
+			return "", err
+		}
+	}
+	return callProvider(ctx, p).Chat(ctx, callMessages(ctx, messages))
+}
+
+// chatJSON asks p for JSON matching schema and decodes it into out (see
+// llm.ChatJSON). Every request, repairs included, counts like a chat call.
+func (a *DeepResearcher) chatJSON(ctx context.Context, p llm.Provider, messages []llm.Message, schema llm.Schema, out any) error {
+	return llm.ChatJSON(ctx, budgetedProvider{a: a, Provider: callProvider(ctx, p)}, callMessages(ctx, messages), schema, out)
+}
+
+// jsonError is the error of a failed chatJSON: "failed to parse <what>" with the
+// model's response when it didn't parse, else err itself
+func jsonError(what string, err error) error {
+	var parseErr *llm.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("failed to parse %s: %w", what, err)
+	}
+	return err
+}
+
+// budgetedProvider counts the requests llm.ChatJSON sends against Config.MaxLLMCalls
+type budgetedProvider struct {
+	a *DeepResearcher
+	llm.Provider
+}
+
+func (b budgetedProvider) Chat(ctx context.Context, messages []llm.Message) (string, error) {
+	return b.a.chat(ctx, b.Provider, messages)
+}
+
+func (b budgetedProvider) ChatJSON(ctx context.Context, messages []llm.Message, schema llm.Schema) (string, error) {
+	if !isDraft(ctx) {
+		if err := b.a.spend(ctx, true); err != nil {
+			return "", err
+		}
+	}
+	return llm.RequestJSON(ctx, b.Provider, messages, schema)
+}
+
+// fetchPage fetches a page's text, counting the request against Config.MaxHTTPRequests,
+// once a fetch worker is free. archiveURL is the Wayback Machine snapshot read
+// instead when the page was gone; published is the page's publication date
+// (zero = unknown). A page older than Config.MaxAgeDays fails with errTooOld;
+// the others are saved to Config.SourcesDir.
+func (a *DeepResearcher) fetchPage(ctx context.Context, fetcher search.ContentFetcher, pageURL string) (content, archiveURL string, published time.Time, err error) {
+	if err := a.fetchPool.acquire(ctx); err != nil {
+		return "", "", time.Time{}, err
+	}
+	defer a.fetchPool.release()
+	if err := a.spend(ctx, false); err != nil {
+		return "", "", time.Time{}, err
+	}
+	ctx, archive := search.WithArchiveInfo(ctx)
+	ctx, info := search.WithPageInfo(ctx)
+	content, err = fetcher.FetchPageContent(ctx, pageURL, 6000)
+	if err == nil && a.config.tooOld(info.Published) {
+		a.log.Debug("⏳ Skipping old page", "url", pageURL, "published", info.Published.Format(time.DateOnly))
+		return "", "", info.Published, errTooOld
+	}
+	if err == nil {
+		a.archivePage(pageURL, content, archive.SnapshotURL, info)
+	}
+	return content, archive.SnapshotURL, info.Published, err
+}
+
+// extractLinks extracts a page's item links, counting the request against
+// Config.MaxHTTPRequests, once a fetch worker is free
+func (a *DeepResearcher) extractLinks(ctx context.Context, extractor search.LinkExtractor, pageURL string, maxLinks int) ([]search.ListingLink, error) {
+	if err := a.fetchPool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.fetchPool.release()
+	if err := a.spend(ctx, false); err != nil {
+		return nil, err
+	}
+	return extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+}
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Call types, the keys of Config.CallSettings
+const (
+	CallPlanning      = "planning"      // Research plans, search queries, research decisions, report outlines
+	CallSummarization = "summarization" // Page and round summaries, record extraction, claims, knowledge graphs
+	CallCompression   = "compression"   // Partial reports condensing findings too large for one prompt, and their merges
+	CallReport        = "report"        // The report and its sections, comparisons, follow-up answers, report critiques and revisions
+)
+
+// CallTypes are the accepted Config.CallSettings keys
+var CallTypes = []string{CallPlanning, CallSummarization, CallCompression, CallReport}
+
+// CallSettings override the LLM settings of one type of call, e.g. a report
+// written at temperature 0.7 while the JSON planning calls stay at 0
+type CallSettings struct {
+	Temperature  *float64 `json:"temperature,omitempty"`  // nil = the backend's (0)
+	MaxTokens    int      `json:"maxTokens,omitempty"`    // Longest response (0 = the backend's)
+	SystemPrompt string   `json:"systemPrompt,omitempty"` // Replaces the calls' own system prompt (empty = keep it)
+}
+
+// ValidateCallSettings rejects unknown call types and out-of-range settings
+func ValidateCallSettings(settings map[string]CallSettings) error {
+	for callType, s := range settings {
+		if !slices.Contains(CallTypes, callType) {
+			return fmt.Errorf("unknown call type %q (use %s)", callType, strings.Join(CallTypes, ", "))
+		}
+		if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+			return fmt.Errorf("%s temperature must be between 0 and 2", callType)
+		}
+		if s.MaxTokens < 0 {
+			return fmt.Errorf("%s max tokens must not be negative", callType)
+		}
+	}
+	return nil
+}
+
+// NewCallProviders creates the Config.CallProviders of models, which maps call
+// types to a model ("provider:model" or a model of the main provider, see
+// llm.ParseModelRef). provider and cfg are the main provider's name and settings.
+func NewCallProviders(provider string, cfg llm.Config, models map[string]string) (map[string]llm.Provider, error) {
+	providers := make(map[string]llm.Provider, len(models))
+	for callType, model := range models {
+		if !slices.Contains(CallTypes, callType) {
+			return nil, fmt.Errorf("unknown call type %q (use %s)", callType, strings.Join(CallTypes, ", "))
+		}
+		p, err := llm.NewModelProvider(provider, cfg, llm.ParseModelRef(model))
+		if err != nil {
+			return nil, fmt.Errorf("%s model %q: %w", callType, model, err)
+		}
+		providers[callType] = p
+	}
+	return providers, nil
+}
+
+// RepairLimit converts a repair count where 0 means none, like --plan-repairs,
+// to Config.PlanRepairs
+func RepairLimit(repairs int) int {
+	if repairs == 0 {
+		return -1
+	}
+	return repairs
+}
+
+type (
+	callKey         struct{}
+	callProviderKey struct{}
+)
+
+// withCall returns ctx for LLM calls of callType: they use its Config.CallSettings
+// and Config.CallProviders, or the backend's settings and the role's provider
+// when it has none (even inside a call of another type), and the tokens they use
+// are counted under callType
+func (a *DeepResearcher) withCall(ctx context.Context, callType string) context.Context {
+	s := a.config.CallSettings[callType]
+	opts := llm.Options{Temperature: s.Temperature, MaxTokens: s.MaxTokens}
+	if callType == CallPlanning && a.config.PlanRepairs != 0 {
+		repairs := max(a.config.PlanRepairs, 0)
+		opts.JSONRepairs = &repairs
+	}
+	ctx = llm.WithOptions(ctx, opts)
+	ctx = llm.WithUsageRecorder(ctx, func(u llm.TokenUsage) { a.tokens.add(callType, u) })
+	ctx = context.WithValue(ctx, callProviderKey{}, a.config.CallProviders[callType])
+	return context.WithValue(ctx, callKey{}, s)
+}
+
+// callProvider returns the provider of the call type of ctx (see withCall), or p
+// when the call type has none
+func callProvider(ctx context.Context, p llm.Provider) llm.Provider {
+	if override, _ := ctx.Value(callProviderKey{}).(llm.Provider); override != nil {
+		return override
+	}
+	return p
+}
+
+// callMessages returns messages with the system prompt of the call type of ctx
+// (see withCall) in place of their own, or messages when it has none
+func callMessages(ctx context.Context, messages []llm.Message) []llm.Message {
+	s, _ := ctx.Value(callKey{}).(CallSettings)
+	if s.SystemPrompt == "" {
+		return messages
+	}
+	out := make([]llm.Message, 0, len(messages)+1)
+	out = append(out, llm.Message{Role: "system", Content: s.SystemPrompt})
+	for _, m := range messages {
+		if m.Role != "system" {
+			out = append(out, m)
+		}
+	}
+	return out
+}
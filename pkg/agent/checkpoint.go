@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Checkpoint is a snapshot of an exhaustive run that can be resumed later
+type Checkpoint struct {
+	Topic           string           `json:"topic"`
+	Plan            ResearchPlan     `json:"plan"`
+	Round           int              `json:"round"`      // Next round to run
+	QueryIndex      int              `json:"queryIndex"` // Next query to process
+	SeenURLs        []string         `json:"seenURLs"`
+	Sources         []Source         `json:"sources"`
+	Records         []map[string]any `json:"records,omitempty"`
+	Context         string           `json:"context"`
+	FindingsLog     string           `json:"findingsLog,omitempty"` // Round results are appended here rather than to Context
+	TotalDuplicates int              `json:"totalDuplicates"`
+	QueryStats      []QueryStat      `json:"queryStats,omitempty"`
+	Images          []Image          `json:"images,omitempty"`
+	SavedAt         time.Time        `json:"savedAt"`
+}
+
+// LoadCheckpoint reads a checkpoint file from disk
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// Save writes the checkpoint atomically (temp file + rename) so a crash
+// mid-write never leaves a corrupt checkpoint behind
+func (cp *Checkpoint) Save(path string) error {
+	cp.SavedAt = time.Now()
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create checkpoint directory: %w", err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to move checkpoint into place: %w", err)
+	}
+	return nil
+}
+
+// saveCheckpoint persists the current run state if checkpointing is enabled
+func (a *DeepResearcher) saveCheckpoint(topic string, plan ResearchPlan, round, queryIndex int, researchContext string, totalDuplicates int) {
+	if a.config.CheckpointPath == "" {
+		return
+	}
+
+	a.mu.Lock()
+	seen := make([]string, 0, len(a.seenURLs))
+	for u := range a.seenURLs {
+		seen = append(seen, u)
+	}
+	sources := make([]Source, len(a.sources))
+	copy(sources, a.sources)
+	records := append([]map[string]any(nil), a.records...)
+	queryStats := append([]QueryStat(nil), a.queryStats...)
+	images := append([]Image(nil), a.images...)
+	findings := a.progress.findings
+	a.mu.Unlock()
+
+	cp := &Checkpoint{
+		Topic:           topic,
+		Plan:            plan,
+		Round:           round,
+		QueryIndex:      queryIndex,
+		SeenURLs:        seen,
+		Sources:         sources,
+		Records:         records,
+		Context:         researchContext,
+		TotalDuplicates: totalDuplicates,
+		QueryStats:      queryStats,
+		Images:          images,
+	}
+	if findings != nil {
+		cp.FindingsLog = findings.path
+	}
+	if err := cp.Save(a.config.CheckpointPath); err != nil {
+		a.log.Warn("⚠️ Could not save checkpoint", "error", err)
+		return
+	}
+	a.log.Debug("💾 Checkpoint saved", "path", a.config.CheckpointPath)
+}
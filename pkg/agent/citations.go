@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CitationCheck is the outcome of verifying a report's citations against the
+// collected sources. Citation numbers are 1-based positions in ResearchResult.Sources.
+type CitationCheck struct {
+	Cited       []int    `json:",omitempty"` // Sources the report cites as [n]
+	InvalidRefs []int    `json:",omitempty"` // [n] markers that don't refer to any source
+	UnknownURLs []string `json:",omitempty"` // Linked URLs that aren't among the sources (likely invented)
+}
+
+var (
+	// citationRe matches [3] and [2, 5]
+	citationRe = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+	// linkRe matches Markdown links and bare URLs
+	linkRe = regexp.MustCompile(`\[[^\]\n]*\]\((https?://[^\s)]+)\)|https?://[^\s<>()\[\]"'` + "`" + `]+`)
+)
+
+// unverifiedMarker is appended to links that don't match a collected source
+const unverifiedMarker = " *(unverified link)*"
+
+// sourceList numbers the sources for the report writer, one per line. Duplicate
+// URLs keep their first number, matching the bibliography.
+func sourceList(sources []Source) string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for i, src := range sources {
+		if seen[src.URL] {
+			continue
+		}
+		seen[src.URL] = true
+		title := strings.Join(strings.Fields(src.Title), " ")
+		if title == "" {
+			title = src.URL
+		}
+		if src.Document {
+			fmt.Fprintf(&b, "[%d] %s - provided document\n", i+1, title)
+			continue
+		}
+		var details []string
+		if src.Price != nil {
+			details = append(details, src.Price.String())
+		}
+		if src.Location != nil {
+			details = append(details, src.Location.String())
+		}
+		details = append(details, src.URL)
+		fmt.Fprintf(&b, "[%d] %s - %s\n", i+1, title, strings.Join(details, " - "))
+	}
+	return b.String()
+}
+
+// citationKey normalizes a URL for matching report links to sources (scheme,
+// "www.", tracking parameters, and trailing slashes are ignored)
+func citationKey(rawURL string) string {
+	u, err := url.Parse(normalizeURL(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSuffix(rawURL, "/"))
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	key := host + u.EscapedPath()
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// verifyCitations checks every [n] citation and linked URL in the report against
+// the sources. Links to URLs that were never collected are marked in place, and a
+// note summarizing the problems is appended to the report.
+func (a *DeepResearcher) verifyCitations(report string, sources []Source) (string, CitationCheck) {
+	var check CitationCheck
+
+	for _, loc := range citationRe.FindAllStringSubmatchIndex(report, -1) {
+		if loc[1] < len(report) && report[loc[1]] == '(' {
+			continue // [3](url) is a link, not a citation
+		}
+		for _, field := range strings.Split(report[loc[2]:loc[3]], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				continue
+			}
+			if n < 1 || n > len(sources) {
+				if !slices.Contains(check.InvalidRefs, n) {
+					check.InvalidRefs = append(check.InvalidRefs, n)
+				}
+			} else if !slices.Contains(check.Cited, n) {
+				check.Cited = append(check.Cited, n)
+			}
+		}
+	}
+	slices.Sort(check.Cited)
+	slices.Sort(check.InvalidRefs)
+
+	known := make(map[string]bool, len(sources))
+	for _, src := range sources {
+		known[citationKey(src.URL)] = true
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range linkRe.FindAllStringSubmatchIndex(report, -1) {
+		start, end := loc[0], loc[1]
+		link := report[start:end]
+		if loc[2] >= 0 {
+			link = report[loc[2]:loc[3]] // [text](url)
+		} else {
+			// Bare URL: sentence punctuation isn't part of it
+			trimmed := strings.TrimRight(link, ".,;:!?*_")
+			end -= len(link) - len(trimmed)
+			link = trimmed
+		}
+		out.WriteString(report[last:end])
+		last = end
+		if known[citationKey(link)] {
+			continue
+		}
+		out.WriteString(unverifiedMarker)
+		if !slices.Contains(check.UnknownURLs, link) {
+			check.UnknownURLs = append(check.UnknownURLs, link)
+		}
+	}
+	out.WriteString(report[last:])
+	report = out.String()
+
+	var problems []string
+	if n := len(check.UnknownURLs); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d linked URL(s) don't match any collected source and may be invented (marked *unverified link*).", n))
+	}
+	if len(check.InvalidRefs) > 0 {
+		refs := make([]string, len(check.InvalidRefs))
+		for i, n := range check.InvalidRefs {
+			refs[i] = strconv.Itoa(n)
+		}
+		problems = append(problems, fmt.Sprintf("Citation(s) %s don't refer to any collected source.", strings.Join(refs, ", ")))
+	}
+	if len(problems) > 0 {
+		report = strings.TrimRight(report, "\n") + "\n\n> **Citation check:** " + strings.Join(problems, " ") + "\n"
+	}
+
+	if len(check.UnknownURLs) > 0 || len(check.InvalidRefs) > 0 {
+		a.log.Warn("🔗 Citations", "cited", len(check.Cited), "sources", len(sources), "unverified_links", len(check.UnknownURLs), "invalid_citations", len(check.InvalidRefs))
+	} else {
+		a.log.Info("🔗 Citations", "cited", len(check.Cited), "sources", len(sources))
+	}
+
+	return report, check
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ReportClaim is a statement of the report traced to the sources it cites, so
+// its figures can be audited without rereading every source. Claims come from
+// the report as the writer wrote it, before the appended sections and
+// Config.ReportTemplate: a templated report may word, order, or leave out
+// sections differently, so a claim's Section need not be a heading of
+// ResearchResult.Report.
+type ReportClaim struct {
+	Text       string   // The sentence, list item, or table row, citations removed
+	Section    string   `json:",omitempty"` // Heading of the report section it is in
+	Sources    []int    // The sources it cites (1-based positions in ResearchResult.Sources)
+	SourceURLs []string // Their URLs, in the same order
+}
+
+var (
+	// claimEndRe matches the end of a sentence: its punctuation, the citations
+	// right after it, and the space before the next sentence
+	claimEndRe = regexp.MustCompile(`[.!?](?:\s*\[\d+(?:\s*,\s*\d+)*\])*(?:\s+|$)`)
+	// listMarkerRe matches a list item's or quote's marker
+	listMarkerRe = regexp.MustCompile(`^(?:[-*+]|\d+[.)]|>)\s+`)
+	// headingRe matches a Markdown heading's marker
+	headingRe = regexp.MustCompile(`^#{1,6}(?:\s+|$)`)
+)
+
+// reportClaims maps the report's cited statements to their sources, section by
+// section as the writer wrote them: every sentence, list item, or table row
+// with a valid [n] citation is a claim. Statements without one aren't traced,
+// and neither is anything the report lost on the way: a map-reduce report
+// (writeReportMapReduce) only has the statements and headings its final merge
+// kept, and its partial reports' sections are gone.
+func reportClaims(report string, sources []Source) []ReportClaim {
+	var claims []ReportClaim
+	seen := make(map[string]bool)
+	section := ""
+	inCode := false
+	for _, line := range strings.Split(report, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~"):
+			inCode = !inCode
+			continue
+		case inCode || line == "":
+			continue
+		case headingRe.MatchString(line):
+			section = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+
+		var statements []string
+		if strings.HasPrefix(line, "|") {
+			statements = []string{strings.ReplaceAll(strings.Trim(line, "|"), "|", ";")}
+		} else {
+			line = listMarkerRe.ReplaceAllString(line, "")
+			last := 0
+			for _, loc := range claimEndRe.FindAllStringIndex(line, -1) {
+				statements = append(statements, line[last:loc[1]])
+				last = loc[1]
+			}
+			statements = append(statements, line[last:])
+		}
+
+		for _, statement := range statements {
+			text, numbers := stripCitations(statement, len(sources))
+			if len(numbers) == 0 || text == "" || seen[text] {
+				continue
+			}
+			seen[text] = true
+			claim := ReportClaim{Text: text, Section: section, Sources: numbers}
+			for _, n := range numbers {
+				claim.SourceURLs = append(claim.SourceURLs, sources[n-1].URL)
+			}
+			claims = append(claims, claim)
+		}
+	}
+	return claims
+}
+
+// stripCitations removes the [n] citations from a statement and its Markdown
+// emphasis, returning the plain text and the valid source numbers it cites
+func stripCitations(statement string, sourceCount int) (string, []int) {
+	var numbers []int
+	var text strings.Builder
+	last := 0
+	for _, loc := range citationRe.FindAllStringSubmatchIndex(statement, -1) {
+		if loc[1] < len(statement) && statement[loc[1]] == '(' {
+			continue // [3](url) is a link, not a citation
+		}
+		for _, field := range strings.Split(statement[loc[2]:loc[3]], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err == nil && n >= 1 && n <= sourceCount && !slices.Contains(numbers, n) {
+				numbers = append(numbers, n)
+			}
+		}
+		text.WriteString(statement[last:loc[0]])
+		last = loc[1]
+	}
+	text.WriteString(statement[last:])
+
+	plain := strings.NewReplacer("**", "", "__", "").Replace(text.String())
+	plain = strings.Join(strings.Fields(plain), " ")
+	for _, p := range []string{".", ",", ";", ":", "!", "?"} {
+		plain = strings.ReplaceAll(plain, " "+p, p)
+	}
+	return strings.Trim(plain, " ;"), numbers
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+// claimTexts is each claim as its section, text, and sources
+func claimTexts(claims []ReportClaim) [][3]any {
+	var got [][3]any
+	for _, c := range claims {
+		got = append(got, [3]any{c.Section, c.Text, c.Sources})
+	}
+	return got
+}
+
+func TestReportClaims(t *testing.T) {
+	sources := []Source{{URL: "https://a.example"}, {URL: "https://b.example"}, {URL: "https://c.example"}}
+	tests := []struct {
+		name   string
+		report string
+		want   [][3]any
+	}{
+		{
+			"sentences",
+			"Rent is 500 EUR [1]. Deposits are two months [2]! Is parking included? Yes [1, 3].",
+			[][3]any{
+				{"", "Rent is 500 EUR.", []int{1}},
+				{"", "Deposits are two months!", []int{2}},
+				{"", "Yes.", []int{1, 3}},
+			},
+		},
+		{
+			"citations after the punctuation stay with their sentence",
+			"Rent is 500 EUR. [1] Deposits are two months.[2]",
+			[][3]any{
+				{"", "Rent is 500 EUR.", []int{1}},
+				{"", "Deposits are two months.", []int{2}},
+			},
+		},
+		{
+			"decimals and versions don't end a sentence",
+			"The rate rose to 3.5% in v2.1 [2]. Nothing cited here.",
+			[][3]any{
+				{"", "The rate rose to 3.5% in v2.1.", []int{2}},
+			},
+		},
+		{
+			"list items and quotes",
+			"- **Cheapest**: 450 EUR [1]\n* Largest: 90 m2 [2]\n1. First pick [3]\n2) Second pick [1]\n> Quoted [2]",
+			[][3]any{
+				{"", "Cheapest: 450 EUR", []int{1}},
+				{"", "Largest: 90 m2", []int{2}},
+				{"", "First pick", []int{3}},
+				{"", "Second pick", []int{1}},
+				{"", "Quoted", []int{2}},
+			},
+		},
+		{
+			"table rows",
+			"| Listing | Price |\n|---|---|\n| Flat A | 500 EUR [1] |\n| Flat B | 650 EUR [2, 3] |\n| Flat C | unknown |",
+			[][3]any{
+				{"", "Flat A; 500 EUR", []int{1}},
+				{"", "Flat B; 650 EUR", []int{2, 3}},
+			},
+		},
+		{
+			"sections",
+			"# Report\n\nIntro [1].\n\n## Prices\n\nFrom 400 EUR [2].\n\n### Central\n\n- 600 EUR [3]",
+			[][3]any{
+				{"Report", "Intro.", []int{1}},
+				{"Prices", "From 400 EUR.", []int{2}},
+				{"Central", "600 EUR", []int{3}},
+			},
+		},
+		{
+			"code blocks, invalid citations, links, and repeats",
+			"```\nnot a claim [1].\n```\n~~~\nnor this [2].\n~~~\nOut of range [7]. See [3](https://c.example) for more. #1 pick [1]. Out of range [7].\nThe same [2]. The same [2].",
+			[][3]any{
+				{"", "#1 pick.", []int{1}},
+				{"", "The same.", []int{2}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := claimTexts(reportClaims(tt.report, sources))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("claims =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportClaimsSourceURLs(t *testing.T) {
+	sources := []Source{{URL: "https://a.example"}, {URL: "https://b.example"}}
+	claims := reportClaims("Both agree [2, 1].", sources)
+	if len(claims) != 1 {
+		t.Fatalf("got %d claims, want 1", len(claims))
+	}
+	want := []string{"https://b.example", "https://a.example"}
+	if !reflect.DeepEqual(claims[0].SourceURLs, want) {
+		t.Errorf("SourceURLs = %v, want %v", claims[0].SourceURLs, want)
+	}
+}
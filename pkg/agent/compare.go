@@ -0,0 +1,484 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ComparisonPlan is what a comparative run researches: the criteria the
+// entities are compared on and a query set per entity
+type ComparisonPlan struct {
+	Criteria []string        `json:"criteria"`
+	Entities []EntityQueries `json:"entities"`
+}
+
+// EntityQueries are the search queries planned for one compared entity
+type EntityQueries struct {
+	Name    string   `json:"name"`
+	Queries []string `json:"queries"`
+}
+
+// Comparison is the criteria x entities matrix of a comparative run
+type Comparison struct {
+	Entities []string   // Column order
+	Criteria []string   // Row order
+	Cells    [][]string // Cells[i][j] is criterion i for entity j, citing sources as [n]
+}
+
+// entityFindings is what the research found about one entity
+type entityFindings struct {
+	name        string
+	summary     string // Facts relevant to the criteria ("" = nothing found or not researched)
+	first, last int    // Its sources are Sources[first:last]
+	researched  bool
+}
+
+// comparisonPlan asks the planner for the criteria to compare Config.CompareEntities
+// on and the queries to research each of them with
+func (a *DeepResearcher) comparisonPlan(ctx context.Context, topic, additionalContext string) (ResearchPlan, error) {
+	names := a.config.CompareEntities
+	contextInfo := ""
+	if additionalContext != "" {
+		contextInfo = fmt.Sprintf("\n\nAdditional context from user:\n%s", additionalContext)
+	}
+
+	prompt := fmt.Sprintf(`You are a Deep Research AI planning a side-by-side comparison.
+
+User's research request: "%s"
+Entities to compare: %s%s%s
+
+Output a JSON object with:
+1. "clarifying_questions": List of 1-3 questions about what matters most in the comparison
+2. "understanding_summary": A 1-2 sentence summary of what the comparison should decide
+3. "criteria": 5-10 concrete criteria to compare every entity on (e.g. price, battery life, license), the ones the user cares about first
+4. "queries": For EACH entity, 3-6 SHORT search queries (2-5 words, no "site:" prefixes) that find its data on the criteria. Use the entity names exactly as given as keys.
+
+Respond ONLY with valid JSON:
+{
+  "clarifying_questions": ["question1"],
+  "understanding_summary": "...",
+  "criteria": ["criterion1", "criterion2"],
+  "queries": {"entity name": ["short query 1", "short query 2"]}
+}`, topic, strings.Join(names, ", "), contextInfo, a.planningGuidance(ctx))
+
+	var planned struct {
+		ClarifyingQuestions  []string            `json:"clarifying_questions"`
+		UnderstandingSummary string              `json:"understanding_summary"`
+		Criteria             []string            `json:"criteria"`
+		Queries              map[string][]string `json:"queries"`
+	}
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.writer, []llm.Message{
+		{Role: "system", Content: "You are a research planning assistant. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, comparisonPlanSchema, &planned)
+	if err != nil {
+		return ResearchPlan{}, jsonError("comparison plan", err)
+	}
+	if len(planned.Criteria) == 0 {
+		return ResearchPlan{}, fmt.Errorf("comparison plan has no criteria")
+	}
+
+	// Keep the user's entity order; an entity the planner skipped is searched by name
+	comparison := &ComparisonPlan{Criteria: planned.Criteria}
+	steps := make([]string, 0, len(names)+2)
+	for _, name := range names {
+		queries := planned.Queries[name]
+		if len(queries) == 0 {
+			for key, q := range planned.Queries {
+				if strings.EqualFold(key, name) {
+					queries = q
+				}
+			}
+		}
+		if len(queries) == 0 {
+			queries = []string{name}
+			for _, c := range planned.Criteria[:min(3, len(planned.Criteria))] {
+				queries = append(queries, name+" "+c)
+			}
+		}
+		comparison.Entities = append(comparison.Entities, EntityQueries{Name: name, Queries: queries})
+		steps = append(steps, fmt.Sprintf("Research %s: %s", name, strings.Join(queries, "; ")))
+	}
+	steps = append(steps,
+		fmt.Sprintf("Compare them on: %s", strings.Join(planned.Criteria, ", ")),
+		"Write a section per entity")
+
+	return ResearchPlan{
+		ClarifyingQuestions:  planned.ClarifyingQuestions,
+		UnderstandingSummary: planned.UnderstandingSummary,
+		ResearchSteps:        steps,
+		ExpectedOutcome:      fmt.Sprintf("A comparison matrix (%d criteria x %d entities) with cited values, plus a section per entity", len(planned.Criteria), len(names)),
+		Comparison:           comparison,
+	}, nil
+}
+
+// RunComparative researches each entity separately and writes a comparison report
+func (a *DeepResearcher) RunComparative(topic string, entities []string) (ResearchResult, error) {
+	return a.RunComparativeWithContext(context.Background(), topic, entities)
+}
+
+// RunComparativeWithContext plans a query set per entity (replacing
+// Config.CompareEntities) and runs the comparison without an approval step.
+// To review the plan first, set Config.CompareEntities and use CreatePlan and
+// RunWithContext (or the exhaustive variants) instead.
+func (a *DeepResearcher) RunComparativeWithContext(ctx context.Context, topic string, entities []string) (ResearchResult, error) {
+	if len(entities) < 2 {
+		return ResearchResult{}, errors.New("a comparison needs at least two entities")
+	}
+	a.config.CompareEntities = entities
+	plan, err := a.comparisonPlan(ctx, topic, "")
+	if err != nil {
+		return ResearchResult{}, fmt.Errorf("comparison planning failed: %w", err)
+	}
+	return a.runComparative(ctx, topic, plan)
+}
+
+// runComparative researches each entity of plan.Comparison in turn (one round
+// each), then writes a criteria x entities matrix and a section per entity.
+// On cancellation (or when a budget runs out) the report covers the entities
+// researched so far.
+func (a *DeepResearcher) runComparative(parent context.Context, topic string, plan ResearchPlan) (ResearchResult, error) {
+	if plan.Comparison == nil || len(plan.Comparison.Entities) == 0 {
+		return ResearchResult{}, fmt.Errorf("no comparison in plan - set Config.CompareEntities before planning")
+	}
+	entities := plan.Comparison.Entities
+	criteria := plan.Comparison.Criteria
+
+	a.mu.Lock()
+	a.sources = a.documentSources()
+	a.records = nil
+	a.findings = nil
+	a.queryStats = nil
+	a.images = nil
+	a.seenURLs = make(map[string]bool)
+	a.mu.Unlock()
+
+	names := make([]string, len(entities))
+	for i, e := range entities {
+		names[i] = e.Name
+	}
+	researchContext := fmt.Sprintf("User Query: %s\n\nComparing: %s\nCriteria: %s\n", topic, strings.Join(names, ", "), strings.Join(criteria, ", "))
+	a.startProgress(topic, researchContext, 0)
+	ctx, stopBudget := a.startBudget(parent)
+	defer stopBudget()
+
+	a.log.Info("⚖️ Starting Comparative Research", "topic", topic, "entities", strings.Join(names, ", "))
+	totalQueries := 0
+	for _, e := range entities {
+		totalQueries += len(e.Queries)
+	}
+	a.resetSearchProgress(searchProgress{totalQueries: totalQueries, plannedQueries: totalQueries, totalRounds: len(entities)})
+	queriesDone := 0
+
+	findings := make([]entityFindings, len(entities))
+	for i, e := range entities {
+		findings[i] = entityFindings{name: e.Name}
+		if ctx.Err() != nil {
+			continue
+		}
+
+		a.emitProgress(ProgressEvent{
+			Phase:       "searching",
+			Round:       i + 1,
+			TotalRounds: len(entities),
+			URLsFound:   a.sourceCount(),
+			Message:     fmt.Sprintf("Researching %s (%d/%d)", e.Name, i+1, len(entities)),
+			Percent:     a.searchPercent(),
+		})
+		// With a deadline, the entities left share the queries there's still time for
+		queries := e.Queries
+		if fit, perQuery, ok := a.queriesThatFit(queriesDone); ok {
+			if share := max(fit/(len(entities)-i), 1); share < len(queries) {
+				a.skipQueries(len(queries)-share, share, perQuery)
+				queries = queries[:share]
+			}
+		}
+		a.log.Info("⚖️ Researching entity", "entity", e.Name, "queries", len(queries))
+
+		first := a.sourceCount()
+		a.startSearchBatch(i+1, queriesDone, len(queries))
+		results, _, _, _, throttled, _ := a.searchWithPagination(ctx, queries)
+		if len(throttled) > 0 && ctx.Err() == nil {
+			// One more try for the queries the engine throttled, now that it's had a while
+			more, _, _, _, _, _ := a.searchWithPagination(ctx, throttled)
+			results += more
+		}
+		queriesDone += len(queries)
+		findings[i].first, findings[i].last = first, a.sourceCount()
+		findings[i].researched = ctx.Err() == nil
+		if strings.TrimSpace(results) == "" {
+			continue
+		}
+
+		summary, err := a.summarizeEntity(ctx, topic, e.Name, criteria, results)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.log.Warn("⚠️ Entity summary failed, using raw results", "entity", e.Name, "error", err)
+			}
+			summary = a.truncateToTokens(ctx, results, a.config.maxContextTokens()/8)
+		}
+		findings[i].summary = summary
+		researchContext += fmt.Sprintf("\n--- %s ---\n%s\n", e.Name, summary)
+		a.addFindings(summary)
+		a.updateProgress(researchContext, i+1)
+	}
+
+	usage := a.finishBudget(ctx)
+	cancelled := ctx.Err() != nil
+	stopReason := "cancelled"
+	if usage.Exhausted != "" {
+		stopReason = usage.Exhausted
+	}
+
+	a.mu.Lock()
+	sources := make([]Source, len(a.sources))
+	copy(sources, a.sources)
+	records := append([]map[string]any(nil), a.records...)
+	queryStats := append([]QueryStat(nil), a.queryStats...)
+	images := append([]Image(nil), a.images...)
+	a.mu.Unlock()
+
+	if len(sources) == 0 && cancelled {
+		return ResearchResult{}, context.Cause(ctx)
+	}
+	a.normalizePrices(sources, records)
+
+	// A cancelled run still gets its partial report
+	reportMessage := "Writing comparison report..."
+	reportCtx, err := reportContext(parent)
+	if err != nil {
+		return ResearchResult{}, err
+	}
+	// Each entity's sources are numbered in place, so those outside Config.GeoArea
+	// are located but never dropped
+	a.locateSources(reportCtx, sources, records)
+	if cancelled {
+		reportMessage = fmt.Sprintf("Writing partial comparison (%s)...", stopReason)
+	}
+	a.emitProgress(ProgressEvent{
+		Phase:       "writing_report",
+		Round:       len(entities),
+		TotalRounds: len(entities),
+		URLsFound:   len(sources),
+		Message:     reportMessage,
+		Percent:     85,
+	})
+	a.log.Info("✍️ Writing Comparison Report")
+
+	comparison, summary, err := a.compareEntities(reportCtx, topic, criteria, findings, sources)
+	if err != nil {
+		return ResearchResult{}, fmt.Errorf("comparison matrix failed: %w", err)
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "# %s\n\n", strings.Join(names, " vs "))
+	if summary != "" {
+		report.WriteString(summary + "\n\n")
+	}
+	if cancelled {
+		fmt.Fprintf(&report, "> **Note:** Research stopped early (%s). Entities without findings are marked as not researched.\n\n", stopReason)
+	}
+	if usage.QueriesSkipped > 0 {
+		fmt.Fprintf(&report, "> **Note:** %d planned search queries were skipped to finish by the deadline.\n\n", usage.QueriesSkipped)
+	}
+	report.WriteString("## Comparison Matrix\n\n")
+	report.WriteString(RenderComparisonTable(comparison))
+
+	for i, f := range findings {
+		a.emitProgress(ProgressEvent{
+			Phase:     "writing_report",
+			URLsFound: len(sources),
+			Message:   fmt.Sprintf("Writing the %s section (%d/%d)", f.name, i+1, len(findings)),
+			Percent:   85 + 10*i/len(findings),
+		})
+		section, err := a.writeEntitySection(reportCtx, topic, criteria, f, sources)
+		if err != nil {
+			return ResearchResult{}, fmt.Errorf("writing the %s section failed: %w", f.name, err)
+		}
+		fmt.Fprintf(&report, "\n## %s\n\n%s\n", f.name, section)
+	}
+
+	conflicts, consensus := a.analyzeClaims(reportCtx, sources)
+	result := a.finishResult(reportCtx, topic, plan, report.String(), gathered{sources: sources, records: records, images: images, queryStats: queryStats, conflicts: conflicts, consensus: consensus})
+	result.Comparison = &comparison
+	result.Usage = a.withTokens(usage)
+
+	a.emitProgress(ProgressEvent{
+		Phase:     "complete",
+		URLsFound: len(result.Sources),
+		Message:   fmt.Sprintf("Comparison complete! %d entities, %d sources.", len(entities), len(result.Sources)),
+		Percent:   100,
+	})
+
+	return result, nil
+}
+
+// summarizeEntity condenses one entity's search results to the facts bearing on the criteria
+func (a *DeepResearcher) summarizeEntity(ctx context.Context, topic, entity string, criteria []string, results string) (string, error) {
+	results = a.truncateToTokens(ctx, results, a.config.maxContextTokens()/2)
+	prompt := fmt.Sprintf(`Here are search results about "%s", gathered for the comparison: %s
+
+%s
+
+Extract the SPECIFIC facts about %s for each of these criteria: %s.
+- Exact figures, prices, versions, dates, and names
+- The exact URL each fact comes from
+- Say "not found" for a criterion the results don't cover; don't guess
+Ignore results about other products or entities.%s
+Do not use <think> tags.
+`, entity, topic, results, entity, strings.Join(criteria, ", "), a.languageDirective())
+
+	resp, err := a.chat(a.withCall(ctx, CallSummarization), a.summarizer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return "", err
+	}
+	return stripThinkTags(resp), nil
+}
+
+// compareEntities asks the writer for the comparison matrix and a short overall summary
+func (a *DeepResearcher) compareEntities(ctx context.Context, topic string, criteria []string, findings []entityFindings, sources []Source) (Comparison, string, error) {
+	names := make([]string, len(findings))
+	perEntity := a.config.maxContextTokens() / 4 / len(findings)
+	var sb strings.Builder
+	for i, f := range findings {
+		names[i] = f.name
+		fmt.Fprintf(&sb, "### %s\n", f.name)
+		if f.summary == "" {
+			sb.WriteString("(no findings)\n\n")
+			continue
+		}
+		sb.WriteString(a.truncateToTokens(ctx, f.summary, perEntity))
+		sb.WriteString("\nSources:\n")
+		sb.WriteString(a.truncateToTokens(ctx, numberedSources(sources, f.first, f.last), perEntity/4))
+		sb.WriteString("\n")
+	}
+
+	prompt := fmt.Sprintf(`Build a comparison matrix for: %s
+
+Entities (columns, in this order): %s
+Criteria (rows): %s
+
+Findings per entity:
+%s
+Respond ONLY with valid JSON: one row per criterion with exactly one value per entity, in the column order above. Keep values short (a figure or a few words) and cite the supporting source number right after each value, e.g. "$29/month [4]". Only cite numbers listed under that entity's Sources. Use "unknown" when the findings don't say. Also give a 2-4 sentence "summary" of how the entities compare and which suits which need.%s
+{
+  "summary": "...",
+  "rows": [{"criterion": "...", "values": ["...", "..."]}]
+}`, topic, strings.Join(names, ", "), strings.Join(criteria, ", "), sb.String(), a.languageDirective())
+
+	var matrix struct {
+		Summary string `json:"summary"`
+		Rows    []struct {
+			Criterion string   `json:"criterion"`
+			Values    []string `json:"values"`
+		} `json:"rows"`
+	}
+	err := a.chatJSON(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+		{Role: "system", Content: "You are a research analyst. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, comparisonMatrixSchema, &matrix)
+	if err != nil {
+		return Comparison{}, "", jsonError("comparison matrix", err)
+	}
+
+	comparison := Comparison{Entities: names}
+	for _, row := range matrix.Rows {
+		cells := make([]string, len(names))
+		for j := range cells {
+			switch {
+			case !findings[j].researched && findings[j].summary == "":
+				cells[j] = "not researched"
+			case j < len(row.Values):
+				cells[j] = row.Values[j]
+			default:
+				cells[j] = "unknown"
+			}
+		}
+		comparison.Criteria = append(comparison.Criteria, row.Criterion)
+		comparison.Cells = append(comparison.Cells, cells)
+	}
+	return comparison, strings.TrimSpace(matrix.Summary), nil
+}
+
+// writeEntitySection writes the report section about one entity from its findings
+func (a *DeepResearcher) writeEntitySection(ctx context.Context, topic string, criteria []string, f entityFindings, sources []Source) (string, error) {
+	if f.summary == "" {
+		if f.researched {
+			return "_The research found nothing usable about this entity._", nil
+		}
+		return "_Not researched: research stopped before reaching this entity._", nil
+	}
+
+	prompt := fmt.Sprintf(`Write the section about %s in a comparison report for: %s
+
+The comparison covers: %s
+
+Findings:
+%s
+
+Sources:
+%s
+Write 2-4 paragraphs of Markdown without a heading: what %s is, how it does on each criterion, and its main strengths and weaknesses. Cite sources inline by their number in square brackets, e.g. [3] or [2, 5], right after the facts they support. Only cite numbers from the Sources list and only link URLs that appear in it - never invent URLs.%s`,
+		f.name, topic, strings.Join(criteria, ", "),
+		a.truncateToTokens(ctx, f.summary, a.config.maxContextTokens()/4),
+		a.truncateToTokens(ctx, numberedSources(sources, f.first, f.last), a.config.maxContextTokens()/8),
+		f.name, a.reportGuidance()+a.languageDirective())
+
+	resp, err := a.chat(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(stripThinkTags(resp))
+	// The heading is added by the caller
+	if strings.HasPrefix(text, "#") {
+		if _, rest, ok := strings.Cut(text, "\n"); ok {
+			text = strings.TrimSpace(rest)
+		}
+	}
+	return text, nil
+}
+
+// numberedSources lists sources[first:last] as "[n] Title - URL" lines, numbered
+// by their position in the full list so citations match the bibliography
+func numberedSources(sources []Source, first, last int) string {
+	var b strings.Builder
+	for i := first; i < last && i < len(sources); i++ {
+		title := strings.Join(strings.Fields(sources[i].Title), " ")
+		if title == "" {
+			title = sources[i].URL
+		}
+		fmt.Fprintf(&b, "[%d] %s - %s\n", i+1, title, sources[i].URL)
+	}
+	return b.String()
+}
+
+// RenderComparisonTable renders the comparison as a markdown table with a row per criterion and a column per entity
+func RenderComparisonTable(c Comparison) string {
+	if len(c.Entities) == 0 || len(c.Criteria) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	header := make([]string, len(c.Entities))
+	for j, e := range c.Entities {
+		header[j] = formatRecordValue("", e)
+	}
+	sb.WriteString("| Criterion | " + strings.Join(header, " | ") + " |\n")
+	sb.WriteString("|" + strings.Repeat("---|", len(c.Entities)+1) + "\n")
+	for i, criterion := range c.Criteria {
+		cells := make([]string, 0, len(c.Entities)+1)
+		cells = append(cells, "**"+formatRecordValue("", criterion)+"**")
+		for _, v := range c.Cells[i] {
+			cells = append(cells, formatRecordValue("", v))
+		}
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Claim is one value the sources give for a fact
+type Claim struct {
+	Value   string
+	Sources []int // Numbers of the sources stating it
+}
+
+// Fact is a checkable fact (a price, date, figure, or spec) that more than one
+// source makes a claim about
+type Fact struct {
+	Fact   string  // What the claims are about, e.g. "Monthly price of the Pro plan"
+	Claims []Claim // Distinct values, most cited first; more than one means the sources disagree
+}
+
+// claimsResponse is what the model returns for one batch of findings
+type claimsResponse struct {
+	Facts []struct {
+		Fact   string `json:"fact"`
+		Claims []struct {
+			Value   string `json:"value"`
+			Sources []int  `json:"sources"`
+		} `json:"claims"`
+	} `json:"facts"`
+}
+
+// analyzeClaims groups the claims the sources make about the same facts, a batch
+// of findings per LLM call, and returns the facts they disagree on and the ones
+// two or more sources agree on. A failed batch is skipped; nothing is returned
+// unless Config.FindConflicts is set.
+func (a *DeepResearcher) analyzeClaims(ctx context.Context, sources []Source) (conflicts, consensus []Fact) {
+	if !a.config.FindConflicts {
+		return nil, nil
+	}
+	// Round summaries don't say which source stated what
+	var findings []finding
+	for _, f := range a.reportFindings(sources) {
+		if f.source > 0 {
+			findings = append(findings, f)
+		}
+	}
+	if len(findings) < 2 {
+		return nil, nil
+	}
+	a.log.Info("⚖️ Comparing the sources' claims", "findings", len(findings))
+
+	// Leave room for the prompt and a long JSON answer
+	batches := batchFindings(findings, a.config.maxContextTokens()/3)
+
+	var facts []Fact
+	byFact := make(map[string]int)
+	for i, batch := range batches {
+		if ctx.Err() != nil {
+			break
+		}
+		resp, err := a.analyzeClaimsBatch(ctx, batch, len(sources))
+		if err != nil {
+			a.log.Warn("⚠️ Claim analysis failed", "batch", i+1, "of", len(batches), "error", err)
+			continue
+		}
+		for _, f := range resp.Facts {
+			name := strings.Join(strings.Fields(f.Fact), " ")
+			if name == "" {
+				continue
+			}
+			j, ok := byFact[strings.ToLower(name)]
+			if !ok {
+				j = len(facts)
+				byFact[strings.ToLower(name)] = j
+				facts = append(facts, Fact{Fact: name})
+			}
+			for _, c := range f.Claims {
+				facts[j].Claims = addClaim(facts[j].Claims, c.Value, c.Sources, len(sources))
+			}
+		}
+	}
+
+	for _, f := range facts {
+		sort.SliceStable(f.Claims, func(i, j int) bool { return len(f.Claims[i].Sources) > len(f.Claims[j].Sources) })
+		switch {
+		case len(f.Claims) > 1:
+			conflicts = append(conflicts, f)
+		case len(f.Claims) == 1 && len(f.Claims[0].Sources) > 1:
+			consensus = append(consensus, f)
+		}
+	}
+
+	a.log.Info("⚖️ Claim analysis", "conflicts", len(conflicts), "consensus", len(consensus))
+	return conflicts, consensus
+}
+
+// addClaim adds the valid source numbers for value to the claim with the same
+// value (ignoring case and spacing), or as a new claim
+func addClaim(claims []Claim, value string, sources []int, sourceCount int) []Claim {
+	value = strings.Join(strings.Fields(value), " ")
+	numbers := mergeSourceNumbers(nil, sources, sourceCount)
+	if value == "" || len(numbers) == 0 {
+		return claims
+	}
+	for i, c := range claims {
+		if strings.EqualFold(c.Value, value) {
+			claims[i].Sources = mergeSourceNumbers(c.Sources, numbers, sourceCount)
+			return claims
+		}
+	}
+	return append(claims, Claim{Value: value, Sources: numbers})
+}
+
+// analyzeClaimsBatch asks the LLM for the facts the sources in one batch of findings make claims about
+func (a *DeepResearcher) analyzeClaimsBatch(ctx context.Context, batch []finding, sourceCount int) (claimsResponse, error) {
+	texts := make([]string, len(batch))
+	for i, f := range batch {
+		texts[i] = f.text
+	}
+
+	prompt := fmt.Sprintf(`Compare what these research findings claim about the same facts. Sources are numbered [1] to [%d].
+
+Findings:
+%s
+
+Rules:
+- Look for specific, checkable facts that more than one source states: prices, dates, quantities, measurements, specifications, rankings.
+- Group the claims about the same fact, and name the fact precisely (the thing, the property, and the variant or time it applies to), e.g. "Monthly price of the Pro plan in 2024", not "Price".
+- List each distinct value once, with the numbers of all the sources that state it. Values that mean the same in other words or units are one value.
+- Values that differ because they describe different things (another model, year, or region) are different facts, not a disagreement.
+- Skip facts only one source states. Only include what the findings state. Do NOT guess.
+
+Respond ONLY with valid JSON:
+{
+  "facts": [{"fact": "...", "claims": [{"value": "...", "sources": [1, 4]}, {"value": "...", "sources": [2]}]}]
+}`, sourceCount, strings.Join(texts, "\n\n"))
+
+	var resp claimsResponse
+	err := a.chatJSON(a.withCall(ctx, CallSummarization), a.llmClient, []llm.Message{
+		{Role: "system", Content: "You are a fact-checker comparing research sources. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, claimsSchema, &resp)
+	if err != nil {
+		return claimsResponse{}, jsonError("claims", err)
+	}
+	return resp, nil
+}
+
+// conflictNote is the research context note that tells the report writer which
+// facts the sources disagree on, so it doesn't settle them silently
+func conflictNote(conflicts []Fact) string {
+	if len(conflicts) == 0 {
+		return ""
+	}
+	facts := make([]string, len(conflicts))
+	for i, f := range conflicts {
+		values := make([]string, len(f.Claims))
+		for j, c := range f.Claims {
+			values[j] = c.Value + " " + citeNumbers(c.Sources)
+		}
+		facts[i] = f.Fact + ": " + strings.Join(values, " vs. ")
+	}
+	return fmt.Sprintf("\n\n--- NOTE: The sources disagree on these facts: %s. Where the report states one of them, give each value with its sources instead of picking one. ---\n", strings.Join(facts, "; "))
+}
+
+// appendConflicts adds a section listing each fact the sources disagree on, with every value and its sources
+func appendConflicts(report string, conflicts []Fact) string {
+	if len(conflicts) == 0 {
+		return report
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\n## Conflicting Information\n\nThe sources disagree on these facts; check them before relying on either value.\n")
+	for _, f := range conflicts {
+		fmt.Fprintf(&sb, "\n**%s**\n\n", f.Fact)
+		for _, c := range f.Claims {
+			fmt.Fprintf(&sb, "- %s %s\n", c.Value, citeNumbers(c.Sources))
+		}
+	}
+	return report + sb.String()
+}
+
+// citeNumbers formats source numbers as an inline citation, e.g. [2, 5]
+func citeNumbers(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = strconv.Itoa(n)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
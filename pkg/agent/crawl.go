@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/search"
+	"fmt"
+)
+
+const (
+	// MaxCrawlDepth caps Config.CrawlDepth
+	MaxCrawlDepth = 5
+
+	// DefaultCrawlPages is how many item links a crawl collects per start page
+	// when Config.CrawlPages is 0
+	DefaultCrawlPages = 30
+
+	// maxCrawlIndexPages caps the index pages a crawl reads per start page, so
+	// a site with endless listings can't use up the fetch budget
+	maxCrawlIndexPages = 20
+)
+
+// ValidateCrawl rejects a crawl depth or page limit out of range
+func ValidateCrawl(depth, pages int) error {
+	if depth < 0 || depth > MaxCrawlDepth {
+		return fmt.Errorf("crawl depth must be between 0 and %d", MaxCrawlDepth)
+	}
+	if pages < 0 {
+		return fmt.Errorf("crawl pages must not be negative")
+	}
+	return nil
+}
+
+// listingItems is how many item links are taken from a start page that would
+// give fallback on its own: Config.CrawlPages with a crawl, fallback for each
+// page read with Config.NextPages
+func (c Config) listingItems(fallback int) int {
+	switch {
+	case c.CrawlDepth > 0 && c.CrawlPages > 0:
+		return c.CrawlPages
+	case c.CrawlDepth > 0:
+		return DefaultCrawlPages
+	default:
+		return fallback * (1 + c.NextPages)
+	}
+}
+
+// listingLinks returns the item links of an index page: with Config.CrawlDepth
+// (and a searcher that can crawl) those of the pages the crawl reaches, with
+// Config.NextPages those of the page and its next pages, else the page's own
+func (a *DeepResearcher) listingLinks(ctx context.Context, extractor search.LinkExtractor, pageURL string, maxLinks int) ([]search.ListingLink, error) {
+	crawler, canCrawl := a.searcher.(search.Crawler)
+	if !canCrawl || (a.config.CrawlDepth == 0 && a.config.NextPages == 0) {
+		return a.extractLinks(ctx, extractor, pageURL, maxLinks)
+	}
+	first, err := a.crawlLinks(ctx, crawler, pageURL, maxLinks)
+	if err != nil {
+		return nil, err
+	}
+	return a.followListing(ctx, crawler, pageURL, first, maxLinks), nil
+}
+
+// followListing collects up to maxItems item links of the listing whose index
+// page start was read as first: by crawling with Config.CrawlDepth, else by
+// following its next pages
+func (a *DeepResearcher) followListing(ctx context.Context, crawler search.Crawler, start string, first search.PageLinks, maxItems int) []search.ListingLink {
+	if a.config.CrawlDepth > 0 {
+		return a.crawl(ctx, crawler, start, first, maxItems)
+	}
+	return a.paginate(ctx, crawler, start, first, maxItems)
+}
+
+// crawl collects up to maxItems item links starting from an index page (read
+// as first), following the in-domain index pages it links to ("next" pages
+// first) for up to Config.CrawlDepth hops. Pages are read breadth first, so
+// the start page's own items and its next page come before deeper categories.
+// Index pages and items already seen in this run are skipped.
+func (a *DeepResearcher) crawl(ctx context.Context, crawler search.Crawler, start string, first search.PageLinks, maxItems int) []search.ListingLink {
+	var items []search.ListingLink
+	seen := map[string]bool{normalizeURL(start): true}
+	level := []string{start}
+	read := 0
+	for depth := 0; depth <= a.config.CrawlDepth && len(level) > 0; depth++ {
+		var next []string
+		for _, page := range level {
+			if len(items) >= maxItems || read >= maxCrawlIndexPages || ctx.Err() != nil {
+				break
+			}
+			read++
+			links := first
+			if page != start {
+				var err error
+				if links, err = a.crawlLinks(ctx, crawler, page, maxItems-len(items)); err != nil {
+					a.log.Debug("⚠️ Crawl skipped a page", "url", page, "error", err)
+					continue
+				}
+			}
+			for _, item := range links.Items {
+				key := normalizeURL(item.URL)
+				if seen[key] || a.wasSeen(key) || !a.config.allowsURL(item.URL) {
+					continue
+				}
+				seen[key] = true
+				items = append(items, item)
+			}
+			for _, p := range links.Pages {
+				key := normalizeURL(p)
+				if seen[key] || !a.config.allowsURL(p) || !a.claimURL(p) {
+					continue
+				}
+				seen[key] = true
+				next = append(next, p)
+			}
+		}
+		level = next
+	}
+	a.log.Info("🕸️ Crawled", "url", start, "index_pages", read, "items", len(items))
+	return items[:min(len(items), maxItems)]
+}
+
+// crawlLinks reads an index page's links for a crawl, counting the request
+// against the fetch pool and the budget
+func (a *DeepResearcher) crawlLinks(ctx context.Context, crawler search.Crawler, pageURL string, maxLinks int) (search.PageLinks, error) {
+	if err := a.fetchPool.acquire(ctx); err != nil {
+		return search.PageLinks{}, err
+	}
+	defer a.fetchPool.release()
+	if err := a.spend(ctx, false); err != nil {
+		return search.PageLinks{}, err
+	}
+	return crawler.CrawlLinks(ctx, pageURL, maxLinks)
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"time"
+)
+
+// minReportReserve is the least time kept for the report when Config.ReportReserve is unset
+const minReportReserve = time.Minute
+
+// runDeadline returns when a run started at start must be finished, report
+// included: start + Config.MaxDuration (zero = no limit)
+func (c Config) runDeadline(start time.Time) time.Time {
+	if c.MaxDuration <= 0 {
+		return time.Time{}
+	}
+	return start.Add(c.MaxDuration)
+}
+
+// reportReserve returns how long before the run's deadline research stops, so
+// compressing the findings and writing the report still fit: Config.ReportReserve,
+// or a quarter of the time left (at least minReportReserve, at most half of it)
+func (c Config) reportReserve(left time.Duration) time.Duration {
+	if c.ReportReserve > 0 {
+		return c.ReportReserve
+	}
+	return min(max(left/4, minReportReserve), left/2)
+}
+
+// queriesThatFit estimates how many more queries fit before the research
+// deadline, from how long the searched queries took so far. ok is false
+// without a deadline or before any query finished.
+func (a *DeepResearcher) queriesThatFit(searched int) (n int, perQuery time.Duration, ok bool) {
+	a.budget.mu.Lock()
+	deadline, started := a.budget.deadline, a.budget.started
+	a.budget.mu.Unlock()
+
+	if deadline.IsZero() || searched <= 0 {
+		return 0, 0, false
+	}
+	perQuery = time.Since(started) / time.Duration(searched)
+	if perQuery <= 0 {
+		return 0, 0, false
+	}
+	return max(int(time.Until(deadline)/perQuery), 0), perQuery, true
+}
+
+// pruneQueries cuts the run's remaining queries (of total, from next on) to the
+// number the deadline leaves time for, estimated from the searched queries. It
+// returns the new total; the dropped queries are counted in Usage.QueriesSkipped.
+func (a *DeepResearcher) pruneQueries(next, total, searched int) int {
+	fit, perQuery, ok := a.queriesThatFit(searched)
+	if !ok || next+fit >= total {
+		return total
+	}
+
+	a.skipQueries(total-next-fit, fit, perQuery)
+	return next + fit
+}
+
+// skipQueries records planned queries dropped to finish by the deadline
+func (a *DeepResearcher) skipQueries(dropped, kept int, perQuery time.Duration) {
+	a.log.Info("⏱️ Dropping queries to finish by the deadline", "kept", kept, "dropped", dropped, "per_query", perQuery.Round(time.Second))
+	a.budget.mu.Lock()
+	a.budget.queriesSkipped += dropped
+	a.budget.mu.Unlock()
+
+	a.mu.Lock()
+	a.searchProgress.totalQueries -= dropped
+	a.mu.Unlock()
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"math"
+)
+
+// DefaultDedupThreshold is the cosine similarity used when near-duplicate
+// detection is enabled without an explicit threshold
+const DefaultDedupThreshold = 0.95
+
+// maxEmbeddingChars caps how much of a page is embedded; the lead of a page is
+// enough to recognise mirrors and syndicated copies
+const maxEmbeddingChars = 4000
+
+// isNearDuplicate embeds a fetched page and reports whether it is a near-duplicate
+// of a page already kept in this run (mirror sites, syndicated listings). Kept pages
+// are remembered for later comparisons. Always false when DedupThreshold is 0 or the
+// LLM provider cannot compute embeddings.
+func (a *DeepResearcher) isNearDuplicate(ctx context.Context, pageURL, content string) bool {
+	if a.config.DedupThreshold <= 0 {
+		return false
+	}
+	embedder, ok := a.llmClient.(llm.Embedder)
+	if !ok {
+		return false
+	}
+
+	a.mu.Lock()
+	disabled := a.embeddingsDisabled
+	a.mu.Unlock()
+	if disabled {
+		return false
+	}
+
+	if len(content) > maxEmbeddingChars {
+		content = content[:maxEmbeddingChars]
+	}
+	vectors, err := embedder.Embeddings(ctx, []string{content})
+	if err != nil || len(vectors) == 0 {
+		// Don't retry on every page - one failure usually means no embedding model is loaded
+		a.mu.Lock()
+		if !a.embeddingsDisabled {
+			a.log.Warn("⚠️ Embeddings unavailable, near-duplicate detection disabled", "error", err)
+		}
+		a.embeddingsDisabled = true
+		a.mu.Unlock()
+		return false
+	}
+	vector := vectors[0]
+
+	// Compare and record under one lock so two concurrent copies can't both be kept
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, kept := range a.pageVectors {
+		if sim := llm.CosineSimilarity(vector, kept); sim >= a.config.DedupThreshold {
+			a.log.Debug("♊ Near-duplicate, skipping", "url", pageURL, "similarity", math.Round(sim*100)/100)
+			return true
+		}
+	}
+	a.pageVectors = append(a.pageVectors, vector)
+	return false
+}
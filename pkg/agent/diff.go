@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"strings"
+)
+
+// ResultDiff is how a later research result on a topic differs from an earlier one
+type ResultDiff struct {
+	Added     []Source      // Sources of the later run the earlier one didn't have
+	Removed   []Source      // Sources of the earlier run the later one didn't find
+	Changed   []ValueChange // Extracted values that differ on a page both runs read
+	Unchanged int           // Sources both runs found
+	Summary   string        `json:",omitempty"` // What's new, written by the LLM (see DeepResearcher.Diff)
+}
+
+// ValueChange is an extracted field whose value differs between two runs for the same page
+type ValueChange struct {
+	URL   string
+	Title string
+	Field string
+	Old   string
+	New   string
+}
+
+// DiffResults compares an earlier result with a later one on the same topic:
+// their sources by URL (tracking parameters and trailing slashes aside) and the
+// records extracted from the pages both runs read, e.g. a listing's price
+func DiffResults(earlier, later ResearchResult) ResultDiff {
+	var diff ResultDiff
+	before := make(map[string]bool, len(earlier.Sources))
+	for _, src := range earlier.Sources {
+		before[normalizeURL(src.URL)] = true
+	}
+	oldRecords := recordsByURL(earlier.Records)
+	newRecords := recordsByURL(later.Records)
+
+	seen := make(map[string]bool, len(later.Sources))
+	for _, src := range later.Sources {
+		key := normalizeURL(src.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !before[key] {
+			diff.Added = append(diff.Added, src)
+			continue
+		}
+		diff.Unchanged++
+		old, rec := oldRecords[key], newRecords[key]
+		for _, f := range changedFields(old, rec) {
+			diff.Changed = append(diff.Changed, ValueChange{URL: src.URL, Title: src.Title, Field: f, Old: formatRecordValue(f, old[f]), New: formatRecordValue(f, rec[f])})
+		}
+	}
+	for _, src := range earlier.Sources {
+		if key := normalizeURL(src.URL); !seen[key] {
+			seen[key] = true
+			diff.Removed = append(diff.Removed, src)
+		}
+	}
+	return diff
+}
+
+// Diff compares two results (see DiffResults) and has the writer summarize
+// what's new in the later one: new findings, values that moved, what went away
+func (a *DeepResearcher) Diff(ctx context.Context, topic string, earlier, later ResearchResult) (ResultDiff, error) {
+	diff := DiffResults(earlier, later)
+	a.log.Info("🔀 Comparing runs", "added", len(diff.Added), "removed", len(diff.Removed), "changed", len(diff.Changed), "unchanged", diff.Unchanged)
+
+	// Half the context for the data, like the report: the changes first, then what's left for the two reports
+	budget := a.config.maxContextTokens() / 2
+	changes := a.truncateToTokens(ctx, diff.Markdown(""), budget/2)
+	reportBudget := (budget - a.countTokens(ctx, changes)) / 2
+	prompt := fmt.Sprintf(`Two research runs on the same topic are compared: %s
+
+What changed between the runs' sources and extracted data:
+%s
+
+The earlier run's report:
+%s
+
+The later run's report:
+%s
+
+Write a short "What's New" summary in Markdown for someone who read the earlier report: the new findings, the values that changed (with old and new values), and what is no longer found, most important first. Say plainly if little changed. Only use facts from the material above and only link URLs that appear in it - never invent URLs. Don't repeat what both reports say.%s`,
+		topic, changes, a.truncateToTokens(ctx, earlier.Report, reportBudget), a.truncateToTokens(ctx, later.Report, reportBudget), a.languageDirective())
+
+	resp, err := a.chat(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return diff, fmt.Errorf("summarizing the changes failed: %w", err)
+	}
+	diff.Summary = stripThinkTags(resp)
+	return diff, nil
+}
+
+// Markdown renders the diff as a report titled for topic (no title when topic is empty)
+func (d ResultDiff) Markdown(topic string) string {
+	var sb strings.Builder
+	if topic != "" {
+		fmt.Fprintf(&sb, "# What's New: %s\n\n", topic)
+	}
+	if d.Summary != "" {
+		sb.WriteString(d.Summary + "\n\n")
+	}
+	fmt.Fprintf(&sb, "%d new, %d removed, and %d unchanged sources; %d changed values.\n", len(d.Added), len(d.Removed), d.Unchanged, len(d.Changed))
+
+	if len(d.Changed) > 0 {
+		fmt.Fprintf(&sb, "\n## Changed Values (%d)\n\n", len(d.Changed))
+		for _, c := range d.Changed {
+			fmt.Fprintf(&sb, "- [%s](%s): %s %s → %s\n", sourceLabel(c.Title, c.URL), c.URL, c.Field, c.Old, c.New)
+		}
+	}
+	if len(d.Added) > 0 {
+		fmt.Fprintf(&sb, "\n## New Sources (%d)\n\n", len(d.Added))
+		for _, src := range d.Added {
+			fmt.Fprintf(&sb, "- [%s](%s)%s\n", sourceLabel(src.Title, src.URL), src.URL, sourceBrief(src))
+		}
+	}
+	if len(d.Removed) > 0 {
+		fmt.Fprintf(&sb, "\n## No Longer Found (%d)\n\n", len(d.Removed))
+		for _, src := range d.Removed {
+			fmt.Fprintf(&sb, "- [%s](%s)%s\n", sourceLabel(src.Title, src.URL), src.URL, sourceBrief(src))
+		}
+	}
+	return sb.String()
+}
+
+// sourceBrief is a source's summary (or snippet) as a list item suffix, cut to a line
+func sourceBrief(src Source) string {
+	text := src.Summary
+	if text == "" {
+		text = src.Snippet
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+	if len(text) > 200 {
+		text = strings.ToValidUTF8(text[:200], "") + "..."
+	}
+	return " - " + text
+}
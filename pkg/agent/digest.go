@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultRoundDigestChars is --round-digest-chars' default
+const DefaultRoundDigestChars = 6000
+
+// roundDigest condenses a round's raw results to at most Config.RoundDigestChars
+// characters for the research context, so the context grows by a bounded step
+// per round and nothing has to be compressed in one lump later. Every listing
+// keeps its URL, price, and key figures; a round already short enough is its
+// own digest (""). When the summarizer fails or overshoots, the digest is the
+// results cut at a line.
+func (a *DeepResearcher) roundDigest(ctx context.Context, topic string, round int, results string) string {
+	limit := a.config.RoundDigestChars
+	if limit <= 0 || utf8.RuneCountInString(results) <= limit {
+		return ""
+	}
+
+	prompt := fmt.Sprintf(`These are the raw search results of round %d of research on "%s":
+
+%s
+
+Condense them into a digest of at most %d characters for the researcher's notes:
+- One line per listing or page worth keeping: [Title](exact URL) followed by its price, address, dates, figures, and specifications, copied exactly
+- Never shorten, rewrite, or drop a URL of a specific listing or page; drop search, category, and home pages first
+- Merge duplicates, and leave out results unrelated to the topic
+- No introduction or conclusion
+Do not use <think> tags.`, round, topic, a.truncateToTokens(ctx, results, a.config.maxContextTokens()/2), limit)
+
+	resp, err := a.chat(a.withCall(ctx, CallSummarization), a.summarizer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	digest := strings.TrimSpace(stripThinkTags(resp))
+	switch {
+	case err != nil:
+		a.log.Warn("⚠️ Round digest failed; keeping the start of the round", "round", round, "error", err)
+		digest = results
+	case digest == "":
+		digest = results
+	}
+	if utf8.RuneCountInString(digest) > limit {
+		digest = cutAtLine(digest, limit)
+	}
+	a.log.Info("🧾 Round digested", "round", round, "raw_chars", utf8.RuneCountInString(results), "digest_chars", utf8.RuneCountInString(digest))
+	return digest
+}
+
+// roundSection is a round's section of the research context: its digest when
+// it has one, else its raw results
+func roundSection(round int, results, digest string) string {
+	if digest != "" {
+		return fmt.Sprintf("\n--- Round %d Digest ---\n%s", round, digest)
+	}
+	return fmt.Sprintf("\n--- Round %d Results ---\n%s", round, results)
+}
+
+// cutAtLine cuts text to at most limit characters, at the last line break
+// that keeps it (mid-line only when the first line is longer)
+func cutAtLine(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	cut := string(runes[:limit])
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \n")
+}
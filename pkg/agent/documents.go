@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/document"
+	"deep-research/pkg/llm"
+	"fmt"
+	"strings"
+)
+
+// documentScheme prefixes the URL of a provided document's source, e.g. "document:brief.pdf"
+const documentScheme = "document:"
+
+// documentSources returns the provided documents (Config.Documents) as the first
+// sources of a run, so the report cites them by number like the web pages
+func (a *DeepResearcher) documentSources() []Source {
+	sources := make([]Source, 0, len(a.config.Documents))
+	seen := make(map[string]int)
+	for _, d := range a.config.Documents {
+		name := d.Name
+		if seen[d.Name]++; seen[d.Name] > 1 {
+			name = fmt.Sprintf("%s (%d)", d.Name, seen[d.Name])
+		}
+		snippet := strings.Join(strings.Fields(d.Text), " ")
+		if len(snippet) > 300 {
+			snippet = strings.ToValidUTF8(snippet[:300], "") + "..."
+		}
+		sources = append(sources, Source{Title: name, URL: documentScheme + name, Snippet: snippet, Document: true})
+	}
+	return sources
+}
+
+// sourceDocument returns the provided document behind a document source
+func (a *DeepResearcher) sourceDocument(src Source) (document.Document, bool) {
+	if !src.Document {
+		return document.Document{}, false
+	}
+	for i, s := range a.documentSources() {
+		if s.URL == src.URL {
+			return a.config.Documents[i], true
+		}
+	}
+	return document.Document{}, false
+}
+
+// documentChunks splits a document's text into chunks of about maxTokens,
+// at paragraph breaks where it can
+func documentChunks(text string, maxTokens int) []string {
+	var chunks []string
+	var chunk strings.Builder
+	tokens := 0
+	flush := func() {
+		if chunk.Len() > 0 {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+			tokens = 0
+		}
+	}
+	for _, para := range strings.Split(text, "\n\n") {
+		paraTokens := llm.EstimateTokens(para)
+		if tokens > 0 && tokens+paraTokens > maxTokens {
+			flush()
+		}
+		if paraTokens > maxTokens {
+			// A paragraph too long for one chunk is cut between words
+			words := strings.Fields(para)
+			per := max(len(words)*maxTokens/paraTokens, 1)
+			for len(words) > 0 {
+				n := min(per, len(words))
+				chunks = append(chunks, strings.Join(words[:n], " "))
+				words = words[n:]
+			}
+			continue
+		}
+		if chunk.Len() > 0 {
+			chunk.WriteString("\n\n")
+		}
+		chunk.WriteString(para)
+		tokens += paraTokens
+	}
+	flush()
+	return chunks
+}
+
+// documentBrief returns the provided documents as a planner prompt suffix ("" if
+// there are none), cut to a quarter of the context window between them
+func (a *DeepResearcher) documentBrief(ctx context.Context) string {
+	if len(a.config.Documents) == 0 {
+		return ""
+	}
+	budget := a.config.maxContextTokens() / 4 / len(a.config.Documents)
+	var b strings.Builder
+	b.WriteString("\n\nDocuments provided by the user (what they already know; plan research that extends and checks them instead of repeating them):")
+	for i, src := range a.documentSources() {
+		fmt.Fprintf(&b, "\n\n### %s\n%s", src.Title, a.truncateToTokens(ctx, a.config.Documents[i].Text, budget))
+	}
+	return b.String()
+}
+
+// documentContext returns the text of the document sources as a report data
+// suffix ("" if there are none). When it doesn't fit, the report is outlined and
+// each section gets the chunks relevant to it (see reportFindings).
+func (a *DeepResearcher) documentContext(sources []Source) string {
+	var b strings.Builder
+	for i, src := range sources {
+		d, ok := a.sourceDocument(src)
+		if !ok {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("\n\nDocuments provided by the user (cite them by number like the web sources):")
+		}
+		fmt.Fprintf(&b, "\n\n[%d] %s (provided document)\n%s", i+1, src.Title, d.Text)
+	}
+	return b.String()
+}
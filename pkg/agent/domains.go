@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"deep-research/pkg/search"
+	"net/url"
+	"strings"
+)
+
+// filterResults drops the search results the domain filters exclude (and, with
+// OnlyNew, those the collection already has, and with MaxAgeDays, those known
+// to be too old)
+func (a *DeepResearcher) filterResults(results []search.Result) []search.Result {
+	if len(a.config.IncludeDomains) == 0 && len(a.config.ExcludeDomains) == 0 && !a.config.OnlyNew && a.config.MaxAgeDays <= 0 {
+		return results
+	}
+	kept := make([]search.Result, 0, len(results))
+	for _, r := range results {
+		if a.config.allowsURL(r.URL) && !a.config.tooOld(resultPublished(r)) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// allowsURL reports whether a search result or page may be used under the
+// IncludeDomains/ExcludeDomains filters. A domain matches itself and its
+// subdomains; exclusions win over inclusions. With OnlyNew, URLs the
+// collection already has are not allowed either.
+func (c Config) allowsURL(rawURL string) bool {
+	if c.OnlyNew {
+		if _, known := c.Knowledge.Lookup(rawURL); known {
+			return false
+		}
+	}
+	if len(c.IncludeDomains) == 0 && len(c.ExcludeDomains) == 0 {
+		return true
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return len(c.IncludeDomains) == 0
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	if matchesDomain(host, c.ExcludeDomains) {
+		return false
+	}
+	return len(c.IncludeDomains) == 0 || matchesDomain(host, c.IncludeDomains)
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one
+func matchesDomain(host string, domains []string) bool {
+	if host == "" {
+		return false
+	}
+	for _, d := range domains {
+		d = cleanDomain(d)
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanDomain reduces a domain filter entry to a bare host name, so
+// "https://www.pinterest.com/", "*.pinterest.com", and "pinterest.com" are equivalent
+func cleanDomain(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	if i := strings.Index(d, "://"); i != -1 {
+		d = d[i+3:]
+	}
+	if i := strings.IndexAny(d, "/?#"); i != -1 {
+		d = d[:i]
+	}
+	if i := strings.LastIndexByte(d, ':'); i != -1 {
+		d = d[:i]
+	}
+	d = strings.TrimPrefix(d, "*.")
+	d = strings.TrimPrefix(d, ".")
+	return strings.TrimPrefix(d, "www.")
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoDraft is returned by DraftReport before a run has gathered anything
+var ErrNoDraft = errors.New("no findings gathered yet")
+
+// Draft is a report written mid-run from what has been gathered so far
+type Draft struct {
+	Report      string
+	Sources     []Source
+	Round       int           // Rounds finished when the draft was written
+	TotalRounds int           // Config.MaxLoops (0 for seed URL runs, the entity count for comparisons)
+	Citations   CitationCheck // How the draft's [n] citations and links matched Sources
+	WrittenAt   time.Time
+}
+
+// runProgress is what DraftReport needs from the running research
+type runProgress struct {
+	topic    string
+	context  string       // The research context so far ("" = build it from the sources)
+	round    int          // Rounds finished
+	findings *findingsLog // Where the rounds' results are, when they aren't in context
+}
+
+type draftKey struct{}
+
+// isDraft reports whether report writing runs for DraftReport (which must not
+// emit the run's report-writing progress events)
+func isDraft(ctx context.Context) bool {
+	return ctx.Value(draftKey{}) != nil
+}
+
+// startProgress resets the draft state at the start of a run
+func (a *DeepResearcher) startProgress(topic, context string, round int) {
+	a.mu.Lock()
+	a.progress = runProgress{topic: topic, context: context, round: round}
+	a.mu.Unlock()
+
+	a.draftMu.Lock()
+	a.draft = nil
+	a.draftMu.Unlock()
+}
+
+// updateProgress records the research context after a finished round
+func (a *DeepResearcher) updateProgress(context string, round int) {
+	a.mu.Lock()
+	a.progress.context = context
+	a.progress.round = round
+	a.mu.Unlock()
+}
+
+// DraftReport writes a report from what the current run has gathered so far,
+// so a long run can be checked mid-way. The draft is kept until the next round
+// finishes (or new sources arrive); asking again before then returns it unchanged.
+// It is safe to call while the run is in progress.
+func (a *DeepResearcher) DraftReport(ctx context.Context) (Draft, error) {
+	a.draftMu.Lock()
+	defer a.draftMu.Unlock()
+
+	a.mu.Lock()
+	progress := a.progress
+	sources := make([]Source, len(a.sources))
+	copy(sources, a.sources)
+	a.mu.Unlock()
+
+	if progress.topic == "" || (progress.round == 0 && len(sources) == 0) {
+		return Draft{}, ErrNoDraft
+	}
+	if a.draft != nil && a.draft.Round == progress.round && len(a.draft.Sources) == len(sources) {
+		return *a.draft, nil
+	}
+
+	researchContext := progress.context
+	if researchContext != "" && progress.findings != nil {
+		logged, err := progress.findings.context()
+		if err != nil {
+			return Draft{}, fmt.Errorf("draft report failed: %w", err)
+		}
+		researchContext += logged
+	}
+	if researchContext == "" {
+		var details []string
+		for _, f := range a.reportFindings(sources) {
+			details = append(details, f.text)
+		}
+		researchContext = fmt.Sprintf("User Query: %s\n\nFindings so far:\n%s", progress.topic, strings.Join(details, "\n\n"))
+	}
+	stage := fmt.Sprintf("%d sources collected", len(sources))
+	switch {
+	case len(a.config.CompareEntities) > 0:
+		stage = fmt.Sprintf("%d of %d entities researched, %s", progress.round, len(a.config.CompareEntities), stage)
+	case a.config.MaxLoops > 0 && len(a.config.SeedURLs) == 0 && len(a.config.SitemapSites) == 0:
+		stage = fmt.Sprintf("round %d of %d, %s", progress.round, a.config.MaxLoops, stage)
+	}
+	researchContext += fmt.Sprintf("\n\n--- NOTE: Research is still in progress (%s). This is a draft; say where findings are thin. ---\n", stage)
+
+	a.log.Info("📝 Writing draft report", "stage", stage)
+	report, err := a.writeReport(context.WithValue(ctx, draftKey{}, true), progress.topic, researchContext, sources)
+	if err != nil {
+		return Draft{}, fmt.Errorf("draft report failed: %w", err)
+	}
+	report, citations := a.verifyCitations(report, sources)
+
+	draft := Draft{
+		Report:      report,
+		Sources:     sources,
+		Round:       progress.round,
+		TotalRounds: a.config.MaxLoops,
+		Citations:   citations,
+		WrittenAt:   time.Now(),
+	}
+	if len(a.config.SeedURLs) > 0 || len(a.config.SitemapSites) > 0 {
+		draft.TotalRounds = 0
+	}
+	if len(a.config.CompareEntities) > 0 {
+		draft.TotalRounds = len(a.config.CompareEntities)
+	}
+	a.draft = &draft
+	return draft, nil
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// EntityTypes are the kinds of entities Config.ExtractEntities looks for
+var EntityTypes = []string{"person", "company", "organization", "product", "location"}
+
+// Entity is a person, company, organization, product, or location the findings mention
+type Entity struct {
+	Name    string
+	Type    string // One of EntityTypes
+	Sources []int  `json:",omitempty"` // Numbers of the sources mentioning it
+}
+
+// Relation links two entities, e.g. "Acme Corp" -acquired-> "Widgets Ltd"
+type Relation struct {
+	From    string // Entity name
+	To      string // Entity name
+	Type    string // Short verb phrase, e.g. "founded" or "headquartered in"
+	Sources []int  `json:",omitempty"` // Numbers of the sources stating it
+}
+
+// graphResponse is what the model returns for one batch of findings
+type graphResponse struct {
+	Entities []struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Sources []int  `json:"sources"`
+	} `json:"entities"`
+	Relations []struct {
+		From    string `json:"from"`
+		To      string `json:"to"`
+		Type    string `json:"type"`
+		Sources []int  `json:"sources"`
+	} `json:"relations"`
+}
+
+// extractGraph pulls the entities and relations out of the run's findings, a
+// batch of findings per LLM call, merging entities by name. A failed batch is
+// skipped; nothing is returned unless Config.ExtractEntities is set.
+func (a *DeepResearcher) extractGraph(ctx context.Context, sources []Source) ([]Entity, []Relation) {
+	if !a.config.ExtractEntities {
+		return nil, nil
+	}
+	findings := a.reportFindings(sources)
+	if len(findings) == 0 {
+		return nil, nil
+	}
+	a.log.Info("🕸️ Extracting entities and relations", "findings", len(findings))
+
+	// Leave room for the prompt and a long JSON answer
+	batches := batchFindings(findings, a.config.maxContextTokens()/3)
+
+	var entities []Entity
+	var relations []Relation
+	byName := make(map[string]int)
+	byLink := make(map[string]int)
+	for i, batch := range batches {
+		if ctx.Err() != nil {
+			break
+		}
+		resp, err := a.extractGraphBatch(ctx, batch, len(sources))
+		if err != nil {
+			a.log.Warn("⚠️ Entity extraction failed", "batch", i+1, "of", len(batches), "error", err)
+			continue
+		}
+		for _, e := range resp.Entities {
+			name := strings.Join(strings.Fields(e.Name), " ")
+			kind := strings.ToLower(strings.TrimSpace(e.Type))
+			if name == "" || !slices.Contains(EntityTypes, kind) {
+				continue
+			}
+			key := strings.ToLower(name)
+			if j, ok := byName[key]; ok {
+				entities[j].Sources = mergeSourceNumbers(entities[j].Sources, e.Sources, len(sources))
+				continue
+			}
+			byName[key] = len(entities)
+			entities = append(entities, Entity{Name: name, Type: kind, Sources: mergeSourceNumbers(nil, e.Sources, len(sources))})
+		}
+		for _, r := range resp.Relations {
+			from, okFrom := byName[strings.ToLower(strings.Join(strings.Fields(r.From), " "))]
+			to, okTo := byName[strings.ToLower(strings.Join(strings.Fields(r.To), " "))]
+			kind := strings.Join(strings.Fields(strings.ToLower(r.Type)), " ")
+			if !okFrom || !okTo || from == to || kind == "" {
+				continue
+			}
+			key := fmt.Sprintf("%d|%d|%s", from, to, kind)
+			if j, ok := byLink[key]; ok {
+				relations[j].Sources = mergeSourceNumbers(relations[j].Sources, r.Sources, len(sources))
+				continue
+			}
+			byLink[key] = len(relations)
+			relations = append(relations, Relation{From: entities[from].Name, To: entities[to].Name, Type: kind, Sources: mergeSourceNumbers(nil, r.Sources, len(sources))})
+		}
+	}
+
+	a.log.Info("🕸️ Knowledge graph", "entities", len(entities), "relations", len(relations))
+	return entities, relations
+}
+
+// extractGraphBatch asks the LLM for the entities and relations in one batch of findings
+func (a *DeepResearcher) extractGraphBatch(ctx context.Context, batch []finding, sourceCount int) (graphResponse, error) {
+	texts := make([]string, len(batch))
+	for i, f := range batch {
+		texts[i] = f.text
+	}
+
+	prompt := fmt.Sprintf(`Extract the entities and the relations between them from these research findings. Sources are numbered [1] to [%d].
+
+Findings:
+%s
+
+Rules:
+- Entities are people, companies, organizations, products, and locations that matter to the findings. "type" is one of: %s.
+- Use each entity's full, most common name, the same way every time it appears.
+- Relations connect two of the entities you list, with a short lowercase verb phrase as "type" (e.g. "founded", "acquired", "invested in", "headquartered in", "competes with").
+- Only include what the findings state. Do NOT guess.
+- "sources" lists the numbers of the sources that mention the entity or state the relation.
+
+Respond ONLY with valid JSON:
+{
+  "entities": [{"name": "...", "type": "company", "sources": [1]}],
+  "relations": [{"from": "...", "to": "...", "type": "...", "sources": [1]}]
+}`, sourceCount, strings.Join(texts, "\n\n"), strings.Join(EntityTypes, ", "))
+
+	var graph graphResponse
+	err := a.chatJSON(a.withCall(ctx, CallSummarization), a.llmClient, []llm.Message{
+		{Role: "system", Content: "You extract knowledge graphs from research findings. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, graphSchema, &graph)
+	if err != nil {
+		return graphResponse{}, jsonError("entities", err)
+	}
+	return graph, nil
+}
+
+// mergeSourceNumbers adds the valid source numbers in add to have, sorted and without repeats
+func mergeSourceNumbers(have, add []int, sourceCount int) []int {
+	for _, n := range add {
+		if n >= 1 && n <= sourceCount && !slices.Contains(have, n) {
+			have = append(have, n)
+		}
+	}
+	sort.Ints(have)
+	return have
+}
@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultReviseBelow is the evaluation score (out of 10) under which an
+// evaluated report is revised
+const DefaultReviseBelow = 7
+
+// maxGapSearches caps the searches a revision runs for the gaps the evaluation found
+const maxGapSearches = 3
+
+// Evaluation is a critique of the report against the plan's expected outcome (Config.EvaluateReport)
+type Evaluation struct {
+	Score           int      // Overall, out of 10
+	Coverage        int      // How fully the report delivers the expected outcome, out of 10
+	Specificity     int      // Concrete names, figures, and dates rather than generalities, out of 10
+	Citations       int      // How well its claims are backed by cited sources, out of 10
+	CitationDensity float64  // Measured: [n] citations per 100 words of the report
+	Gaps            []string `json:",omitempty"` // What the report misses or is vague about
+	Revised         bool     // The report was revised after scoring below Config.ReviseBelow
+	InitialScore    int      `json:",omitempty"` // Revised reports: the score before the revision
+	Queries         []string `json:",omitempty"` // Searches run for the gaps during the revision
+}
+
+// evaluationResponse is what the model returns for a critique
+type evaluationResponse struct {
+	Coverage    int      `json:"coverage"`
+	Specificity int      `json:"specificity"`
+	Citations   int      `json:"citations"`
+	Score       int      `json:"score"`
+	Gaps        []string `json:"gaps"`
+	Queries     []string `json:"queries"`
+}
+
+// evaluateAndRevise critiques the report (Config.EvaluateReport) and, when it
+// scores under Config.ReviseBelow, runs a few searches for the gaps found and
+// revises the report once. Returns the report, the sources (followed by any the
+// searches added), and the final evaluation (nil when not enabled or it failed).
+func (a *DeepResearcher) evaluateAndRevise(ctx context.Context, topic string, plan ResearchPlan, report string, sources []Source) (string, []Source, *Evaluation) {
+	if !a.config.EvaluateReport {
+		return report, sources, nil
+	}
+	a.log.Info("🧐 Evaluating the report", "expected_outcome", plan.ExpectedOutcome)
+	eval, queries, err := a.evaluateReport(ctx, topic, plan, report, sources)
+	if err != nil {
+		a.log.Warn("⚠️ Report evaluation failed", "error", err)
+		return report, sources, nil
+	}
+	threshold := a.config.ReviseBelow
+	if threshold <= 0 {
+		threshold = DefaultReviseBelow
+	}
+	a.log.Info("🧐 Report evaluation", "score", eval.Score, "coverage", eval.Coverage, "specificity", eval.Specificity, "citations", eval.Citations, "gaps", len(eval.Gaps))
+	if eval.Score >= threshold || ctx.Err() != nil {
+		return report, sources, &eval
+	}
+
+	// Search for what's missing; new sources are numbered after the report's
+	var added []Source
+	var searched []string
+	if a.searcher != nil {
+		for _, query := range queries[:min(len(queries), maxGapSearches)] {
+			if ctx.Err() != nil {
+				break
+			}
+			added = append(added, a.followUpSearch(ctx, query, append(sources[:len(sources):len(sources)], added...))...)
+			searched = append(searched, query)
+		}
+	}
+	all := append(sources[:len(sources):len(sources)], added...)
+
+	a.log.Info("✍️ Revising the report", "score", eval.Score, "threshold", threshold, "new_sources", len(added))
+	revised, err := a.reviseReport(ctx, topic, plan, report, eval, all, len(sources))
+	if err != nil {
+		a.log.Warn("⚠️ Report revision failed; keeping the original", "error", err)
+		return report, sources, &eval
+	}
+
+	final, _, err := a.evaluateReport(ctx, topic, plan, revised, all)
+	if err != nil {
+		a.log.Warn("⚠️ Evaluating the revision failed", "error", err)
+		final = eval
+	}
+	final.Revised = true
+	final.InitialScore = eval.Score
+	final.Queries = searched
+	a.log.Info("🧐 Revised report evaluation", "score", final.Score, "was", eval.Score)
+	return revised, all, &final
+}
+
+// evaluateReport asks the writer to score the report against the plan's
+// expected outcome and name its gaps, with searches that would fill them
+func (a *DeepResearcher) evaluateReport(ctx context.Context, topic string, plan ResearchPlan, report string, sources []Source) (Evaluation, []string, error) {
+	density := citationDensity(report)
+	budget := a.config.maxContextTokens() / 2
+
+	prompt := fmt.Sprintf(`Critique this research report.
+
+Research question: %s
+Expected outcome: %s
+Planned steps: %s
+Sources available: %d (the report cites %.1f of them per 100 words)
+
+Report:
+%s
+
+Score each from 1 (poor) to 10 (excellent):
+- coverage: how fully the report delivers the expected outcome and answers the question
+- specificity: concrete names, figures, prices, and dates rather than generalities
+- citations: whether its claims are backed by inline [n] citations
+- score: overall quality
+
+List the gaps: what the expected outcome needs that the report misses or is vague about (empty if none). For the gaps, give up to %d targeted web search queries that would find the missing information.
+
+Respond ONLY with valid JSON:
+{"coverage": 7, "specificity": 6, "citations": 8, "score": 7, "gaps": ["..."], "queries": ["..."]}`,
+		topic, plan.ExpectedOutcome, strings.Join(plan.ResearchSteps, "; "), len(sources), density,
+		a.truncateToTokens(ctx, report, budget), maxGapSearches)
+
+	var resp evaluationResponse
+	err := a.chatJSON(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+		{Role: "system", Content: "You are a demanding research editor. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, evaluationSchema, &resp)
+	if err != nil {
+		return Evaluation{}, nil, jsonError("evaluation", err)
+	}
+
+	eval := Evaluation{
+		Score:           clampScore(resp.Score),
+		Coverage:        clampScore(resp.Coverage),
+		Specificity:     clampScore(resp.Specificity),
+		Citations:       clampScore(resp.Citations),
+		CitationDensity: density,
+	}
+	for _, gap := range resp.Gaps {
+		if gap = strings.Join(strings.Fields(gap), " "); gap != "" {
+			eval.Gaps = append(eval.Gaps, gap)
+		}
+	}
+	return eval, CleanQueries(resp.Queries), nil
+}
+
+// reviseReport rewrites the report to address the evaluation's gaps, drawing on
+// the sources the gap searches added (those numbered after known)
+func (a *DeepResearcher) reviseReport(ctx context.Context, topic string, plan ResearchPlan, report string, eval Evaluation, sources []Source, known int) (string, error) {
+	budget := a.config.maxContextTokens() / 2
+	if tokens := a.countTokens(ctx, report); tokens > budget*2/3 {
+		return "", fmt.Errorf("the report (%d tokens) is too long to revise in one prompt", tokens)
+	}
+
+	var added strings.Builder
+	for i, src := range sources[known:] {
+		fmt.Fprintf(&added, "[%d] %s - %s\n%s\n\n", known+i+1, src.Title, src.URL, src.Snippet)
+	}
+	newSources := "None."
+	if added.Len() > 0 {
+		newSources = a.truncateToTokens(ctx, added.String(), budget/6)
+	}
+	gaps := "- " + strings.Join(eval.Gaps, "\n- ")
+	if len(eval.Gaps) == 0 {
+		gaps = "(none listed; improve specificity and citations)"
+	}
+
+	prompt := fmt.Sprintf(`Revise this research report on: %s
+
+Expected outcome: %s
+
+Report:
+%s
+
+An editor scored it %d/10 (coverage %d, specificity %d, citations %d) and found these gaps:
+%s
+
+New search results for the gaps:
+%s
+
+Rewrite the whole report, keeping its structure and everything it gets right. Fill the gaps with the new results where they help, make vague statements specific where the report or new results allow, and say plainly what the research could not find. Keep the existing [n] citations; cite the new results by their numbers. Only cite numbers that appear in the report or the new results and only link URLs that appear in them - never invent URLs. Don't add a references section.%s%s`,
+		topic, plan.ExpectedOutcome, report, eval.Score, eval.Coverage, eval.Specificity, eval.Citations, gaps, newSources, a.reportGuidance(), a.languageDirective())
+
+	resp, err := a.chat(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return "", fmt.Errorf("report revision failed: %w", err)
+	}
+	revised := strings.TrimSpace(stripThinkTags(resp))
+	if revised == "" {
+		return "", errors.New("report revision was empty")
+	}
+	return revised, nil
+}
+
+// citationDensity is the report's [n] citation markers per 100 words
+func citationDensity(report string) float64 {
+	words := len(strings.Fields(report))
+	if words == 0 {
+		return 0
+	}
+	citations := 0
+	for _, loc := range citationRe.FindAllStringIndex(report, -1) {
+		if loc[1] < len(report) && report[loc[1]] == '(' {
+			continue // [3](url) is a link, not a citation
+		}
+		citations++
+	}
+	return math.Round(float64(citations)/float64(words)*1000) / 10
+}
+
+// clampScore keeps a model's score within 1-10
+func clampScore(score int) int {
+	return min(max(score, 1), 10)
+}
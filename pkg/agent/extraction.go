@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// extractionFields parses Config.ExtractionSchema into an ordered field list.
+// Accepts a JSON schema object (uses "properties"), a JSON array of field names,
+// or a plain comma-separated list like "price, address, sqm, url".
+func (c Config) extractionFields() []string {
+	schema := strings.TrimSpace(c.ExtractionSchema)
+	if schema == "" {
+		return nil
+	}
+
+	var fields []string
+	if strings.HasPrefix(schema, "{") {
+		var obj struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Required   []string                   `json:"required"`
+		}
+		if err := json.Unmarshal([]byte(schema), &obj); err == nil {
+			// Required fields first (in schema order), then the rest alphabetically
+			seen := make(map[string]bool)
+			for _, f := range obj.Required {
+				if _, ok := obj.Properties[f]; ok && !seen[f] {
+					fields = append(fields, f)
+					seen[f] = true
+				}
+			}
+			var rest []string
+			for f := range obj.Properties {
+				if !seen[f] {
+					rest = append(rest, f)
+				}
+			}
+			sort.Strings(rest)
+			fields = append(fields, rest...)
+		}
+	} else if strings.HasPrefix(schema, "[") {
+		json.Unmarshal([]byte(schema), &fields)
+	} else {
+		for _, f := range strings.Split(schema, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	// Always keep the page URL so every record can be traced back to its source
+	hasURL := false
+	for _, f := range fields {
+		if strings.EqualFold(f, "url") {
+			hasURL = true
+			break
+		}
+	}
+	if len(fields) > 0 && !hasURL {
+		fields = append(fields, "url")
+	}
+	return fields
+}
+
+// extractRecord asks the LLM to fill the extraction schema from a single page
+func (a *DeepResearcher) extractRecord(ctx context.Context, pageURL, title, content string) (map[string]any, error) {
+	fields := a.config.extractionFields()
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	schemaHint := strings.Join(fields, ", ")
+	if strings.HasPrefix(strings.TrimSpace(a.config.ExtractionSchema), "{") {
+		schemaHint = a.config.ExtractionSchema
+	}
+
+	prompt := fmt.Sprintf(`Extract a structured record from this webpage.
+
+Fields: %s
+
+Title: %s
+URL: %s
+Content:
+%s
+
+Rules:
+- Output ONE JSON object with exactly these keys: %s
+- Use null for any field not present on the page. Do NOT guess.
+- Keep values short: numbers as numbers when possible, text as plain strings.
+- The "url" field is the page URL above.
+
+Respond ONLY with valid JSON.`, schemaHint, title, pageURL, content, strings.Join(fields, ", "))
+
+	var raw map[string]any
+	err := a.chatJSON(a.withCall(ctx, CallSummarization), a.llmClient, []llm.Message{
+		{Role: "system", Content: "You extract structured data from web pages. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, recordSchema(fields), &raw)
+	if err != nil {
+		return nil, jsonError("extracted record", err)
+	}
+
+	// Keep only schema fields (the model sometimes adds extras) and pin the URL
+	record := make(map[string]any, len(fields))
+	for _, f := range fields {
+		record[f] = raw[f]
+		if strings.EqualFold(f, "url") {
+			record[f] = pageURL
+		}
+	}
+	return record, nil
+}
+
+// collectRecord extracts a record for a deep-mode page and stores it on the researcher
+func (a *DeepResearcher) collectRecord(ctx context.Context, pageURL, title, content string) {
+	if a.config.ExtractionSchema == "" {
+		return
+	}
+	if err := a.summarizePool.acquire(ctx); err != nil {
+		return
+	}
+	record, err := a.extractRecord(ctx, pageURL, title, content)
+	a.summarizePool.release()
+	if err != nil {
+		a.log.Warn("⚠️ [DEEP] Record extraction failed", "url", pageURL, "error", err)
+		return
+	}
+	if record == nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.records = append(a.records, record)
+	a.mu.Unlock()
+}
+
+// RenderRecordsTable renders extracted records as a markdown table with one column per field
+func RenderRecordsTable(fields []string, records []map[string]any) string {
+	if len(fields) == 0 || len(records) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| " + strings.Join(fields, " | ") + " |\n")
+	sb.WriteString("|" + strings.Repeat("---|", len(fields)) + "\n")
+	for _, rec := range records {
+		cells := make([]string, len(fields))
+		for i, f := range fields {
+			cells[i] = formatRecordValue(f, rec[f])
+		}
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return sb.String()
+}
+
+// formatRecordValue renders a single record value as a markdown table cell
+func formatRecordValue(field string, v any) string {
+	if v == nil {
+		return ""
+	}
+
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case float64:
+		s = strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", val), "0"), ".")
+	default:
+		b, _ := json.Marshal(val)
+		s = string(b)
+	}
+
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\n", " ")
+	if strings.EqualFold(field, "url") && strings.HasPrefix(s, "http") {
+		return fmt.Sprintf("[link](%s)", s)
+	}
+	return s
+}
+
+// appendRecordsTable adds the extracted records table to the report when records exist
+func (a *DeepResearcher) appendRecordsTable(report string, records []map[string]any) string {
+	table := RenderRecordsTable(a.config.recordFields(), records)
+	if table == "" {
+		return report
+	}
+	return report + fmt.Sprintf("\n\n## Extracted Records (%d)\n\n%s", len(records), table)
+}
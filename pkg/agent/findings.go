@@ -0,0 +1,409 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxFindingChars is the size findings are chunked to; small enough that each
+// section's retrieval can pick many of them
+const maxFindingChars = 1500
+
+// embeddingBatchSize is how many findings are embedded per request
+const embeddingBatchSize = 32
+
+// finding is one retrievable chunk of research: a source's summary or snippet,
+// or part of a round summary
+type finding struct {
+	text   string
+	source int            // 1-based number of the source it details (0 = a round summary)
+	tokens int            // Estimated token count
+	vector []float64      // Embedding (nil when retrieval is lexical)
+	terms  map[string]int // Word counts for lexical ranking
+	length int            // Number of words
+}
+
+// reportOutline is the writer's plan for a report too large for one prompt
+type reportOutline struct {
+	Title    string           `json:"title"`
+	Sections []outlineSection `json:"sections"`
+}
+
+// outlineSection is one section of a reportOutline
+type outlineSection struct {
+	Heading string `json:"heading"`
+	Focus   string `json:"focus"`   // What the section covers (the query further findings are retrieved with)
+	Sources []int  `json:"sources"` // Numbers of the sources whose findings belong in the section
+}
+
+// addFindings records a round summary as retrievable findings for the report
+func (a *DeepResearcher) addFindings(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, chunk := range splitContextIntoChunks(text, maxFindingChars) {
+		if chunk = strings.TrimSpace(chunk); chunk != "" {
+			a.findings = append(a.findings, chunk)
+		}
+	}
+}
+
+// reportFindings returns one finding per cited source (numbered like sourceList)
+// followed by the round summaries
+func (a *DeepResearcher) reportFindings(sources []Source) []finding {
+	var out []finding
+	seen := make(map[string]bool)
+	for i, src := range sources {
+		if seen[src.URL] {
+			continue
+		}
+		seen[src.URL] = true
+		title := strings.Join(strings.Fields(src.Title), " ")
+		if d, ok := a.sourceDocument(src); ok {
+			// A provided document is retrieved a chunk at a time
+			for _, chunk := range documentChunks(d.Text, a.config.maxContextTokens()/8) {
+				out = append(out, finding{text: fmt.Sprintf("[%d] %s (provided document)\n%s", i+1, title, chunk), source: i + 1})
+			}
+			continue
+		}
+		detail := src.Summary
+		if detail == "" {
+			detail = src.Snippet
+		}
+		text := fmt.Sprintf("[%d] %s - %s\n%s", i+1, title, src.URL, strings.TrimSpace(detail))
+		out = append(out, finding{text: text, source: i + 1})
+	}
+
+	a.mu.Lock()
+	for _, text := range a.findings {
+		out = append(out, finding{text: text})
+	}
+	a.mu.Unlock()
+
+	for i := range out {
+		out[i].tokens = llm.EstimateTokens(out[i].text)
+	}
+	return out
+}
+
+// indexFindings embeds the findings for retrieval. Without an embedding model
+// (or after the first embedding failure) findings are ranked lexically instead
+// and false is returned.
+func (a *DeepResearcher) indexFindings(ctx context.Context, findings []finding) bool {
+	for i := range findings {
+		findings[i].terms = termCounts(findings[i].text)
+		for _, n := range findings[i].terms {
+			findings[i].length += n
+		}
+	}
+
+	embedder, ok := a.llmClient.(llm.Embedder)
+	a.mu.Lock()
+	disabled := a.embeddingsDisabled
+	a.mu.Unlock()
+	if !ok || disabled {
+		return false
+	}
+
+	for start := 0; start < len(findings); start += embeddingBatchSize {
+		batch := findings[start:min(start+embeddingBatchSize, len(findings))]
+		inputs := make([]string, len(batch))
+		for i, f := range batch {
+			inputs[i] = f.text[:min(len(f.text), maxEmbeddingChars)]
+		}
+		vectors, err := embedder.Embeddings(ctx, inputs)
+		if err == nil && len(vectors) != len(batch) {
+			err = fmt.Errorf("got %d embeddings for %d inputs", len(vectors), len(batch))
+		}
+		if err != nil {
+			a.log.Warn("🔤 Ranking findings by keywords (embeddings unavailable)", "error", err)
+			a.mu.Lock()
+			a.embeddingsDisabled = true
+			a.mu.Unlock()
+			for i := range findings {
+				findings[i].vector = nil
+			}
+			return false
+		}
+		for i, v := range vectors {
+			batch[i].vector = v
+		}
+	}
+	return true
+}
+
+// retrieveFindings returns the findings most relevant to query that fit in
+// maxTokens, in their original order. Findings that don't match at all are left out.
+func (a *DeepResearcher) retrieveFindings(ctx context.Context, findings []finding, query string, maxTokens int, embedded bool) []finding {
+	scores := make([]float64, len(findings))
+	scored := false
+	if embedded {
+		if embedder, ok := a.llmClient.(llm.Embedder); ok {
+			if vectors, err := embedder.Embeddings(ctx, []string{query}); err == nil && len(vectors) == 1 {
+				for i, f := range findings {
+					scores[i] = llm.CosineSimilarity(vectors[0], f.vector)
+				}
+				scored = true
+			}
+		}
+	}
+	if !scored {
+		scores = bm25Scores(findings, query)
+	}
+
+	order := make([]int, len(findings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	var picked []int
+	used := 0
+	for _, i := range order {
+		if scores[i] <= 0 || used+findings[i].tokens > maxTokens {
+			continue
+		}
+		picked = append(picked, i)
+		used += findings[i].tokens
+	}
+	sort.Ints(picked)
+
+	out := make([]finding, len(picked))
+	for i, idx := range picked {
+		out[i] = findings[idx]
+	}
+	return out
+}
+
+// termCounts splits text into lowercase word counts for lexical ranking,
+// folding simple plurals ("prices" counts as "price")
+func termCounts(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = word[:len(word)-1]
+		}
+		if len(word) > 1 {
+			counts[word]++
+		}
+	}
+	return counts
+}
+
+// bm25Scores ranks findings against query with Okapi BM25
+func bm25Scores(findings []finding, query string) []float64 {
+	const k1, b = 1.2, 0.75
+
+	total := 0
+	docFreq := make(map[string]int)
+	for _, f := range findings {
+		total += f.length
+		for term := range f.terms {
+			docFreq[term]++
+		}
+	}
+	avgLen := float64(total) / float64(max(len(findings), 1))
+
+	scores := make([]float64, len(findings))
+	for term := range termCounts(query) {
+		df := docFreq[term]
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(len(findings))-float64(df)+0.5)/(float64(df)+0.5))
+		for i, f := range findings {
+			tf := float64(f.terms[term])
+			if tf == 0 {
+				continue
+			}
+			scores[i] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(f.length)/avgLen))
+		}
+	}
+	return scores
+}
+
+// writeReportFromOutline writes a report in two stages: the writer drafts an
+// outline that assigns the sources to sections, then writes each section from
+// only its findings, and the sections are stitched together. brief is the query
+// and plan, budget the prompt's token budget. Sections are streamed as they are
+// written when stream is set.
+func (a *DeepResearcher) writeReportFromOutline(ctx context.Context, topic, brief string, sources []Source, budget int, stream *reportStream) (string, error) {
+	findings := a.reportFindings(sources)
+	embedded := a.indexFindings(ctx, findings)
+	method := "keywords"
+	if embedded {
+		method = "embeddings"
+	}
+	a.log.Info("🗂️ Outlining the report, then writing each section from its findings", "findings", len(findings), "ranked_by", method)
+
+	// The outline sees every source title (as many as fit) but none of the details
+	titles := a.truncateToTokens(ctx, sourceList(sources), budget/2)
+	outline, err := a.outlineReport(ctx, topic, brief, titles)
+	if err != nil {
+		a.log.Warn("⚠️ Outline failed, writing a single section", "error", err)
+		outline = reportOutline{Title: topic, Sections: []outlineSection{{Heading: "Findings", Focus: topic}}}
+	}
+	outline = a.templateOutline(outline)
+
+	headings := make([]string, len(outline.Sections))
+	for i, s := range outline.Sections {
+		headings[i] = "- " + s.Heading
+	}
+
+	linkEmphasis := ""
+	if a.config.ResultLinks {
+		linkEmphasis = "\n\nCRITICAL: Include direct clickable links [Title](URL) for each item."
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "# %s\n\n", outline.Title)
+	stream.send(report.String())
+	for i, section := range outline.Sections {
+		if !isDraft(ctx) {
+			a.emitProgress(ProgressEvent{
+				Phase:     "writing_report",
+				URLsFound: len(sources),
+				Message:   fmt.Sprintf("Writing section %d/%d: %s", i+1, len(outline.Sections), section.Heading),
+				Percent:   90 + i*9/len(outline.Sections),
+			})
+		}
+
+		header := fmt.Sprintf(`You are writing one section of a research report on: %s
+
+%s
+
+Report outline:
+%s
+
+Write ONLY the section "%s" (it should cover: %s), starting with the heading "## %s".
+
+Findings:
+`, topic, brief, strings.Join(headings, "\n"), section.Heading, section.Focus, section.Heading)
+		footer := "\nUse only these findings. Cite sources inline by their number in square brackets, e.g. [3] or [2, 5], right after the facts they support. Only cite numbers that appear in the findings and only link URLs that appear in them - never invent URLs. Don't repeat what other sections of the outline cover and don't add a references section." + linkEmphasis + a.languageDirective()
+
+		// The section's findings get whatever the instructions leave of the budget
+		available := budget - a.countTokens(ctx, header+footer)
+		relevant := a.sectionFindings(ctx, findings, section, available, embedded)
+		texts := make([]string, len(relevant))
+		for j, f := range relevant {
+			texts[j] = f.text
+		}
+		if len(relevant) == 0 {
+			a.log.Warn("⚠️ Section has no supporting findings, skipped", "section", i+1, "of", len(outline.Sections), "heading", section.Heading)
+			continue
+		}
+		data := a.truncateToTokens(ctx, strings.Join(texts, "\n\n"), available)
+		a.log.Info("✍️ Writing section", "section", i+1, "of", len(outline.Sections), "heading", section.Heading, "findings", len(relevant))
+
+		stream.section(section.Heading)
+		resp, err := a.chatStream(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+			{Role: "user", Content: header + data + "\n" + footer},
+		}, stream)
+		stream.end()
+		if err != nil {
+			return "", fmt.Errorf("writing section %q failed: %w", section.Heading, err)
+		}
+		text := strings.TrimSpace(stripThinkTags(resp))
+		if !strings.HasPrefix(text, "#") {
+			text = "## " + section.Heading + "\n\n" + text
+		}
+		report.WriteString(text)
+		report.WriteString("\n\n")
+	}
+	return strings.TrimSpace(report.String()), nil
+}
+
+// batchFindings splits findings, in order, into batches of at most budget tokens
+// (a finding larger than that gets a batch of its own)
+func batchFindings(findings []finding, budget int) [][]finding {
+	var batches [][]finding
+	var batch []finding
+	tokens := 0
+	for _, f := range findings {
+		if len(batch) > 0 && tokens+f.tokens > budget {
+			batches = append(batches, batch)
+			batch, tokens = nil, 0
+		}
+		batch = append(batch, f)
+		tokens += f.tokens
+	}
+	return append(batches, batch)
+}
+
+// contextNotes returns the "--- NOTE: ... ---" lines added to a research context
+// (e.g. that research stopped early), which every section's writer should see
+func contextNotes(context string) []string {
+	var notes []string
+	for _, line := range strings.Split(context, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "--- NOTE:") {
+			notes = append(notes, line)
+		}
+	}
+	return notes
+}
+
+// sectionFindings returns the findings for one section that fit in maxTokens:
+// those of the sources the outline assigned to it, then the ones most relevant
+// to its heading and focus
+func (a *DeepResearcher) sectionFindings(ctx context.Context, findings []finding, section outlineSection, maxTokens int, embedded bool) []finding {
+	assigned := make(map[int]bool, len(section.Sources))
+	for _, n := range section.Sources {
+		assigned[n] = true
+	}
+
+	var picked, rest []finding
+	used := 0
+	for _, f := range findings {
+		if f.source > 0 && assigned[f.source] && used+f.tokens <= maxTokens {
+			picked = append(picked, f)
+			used += f.tokens
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	if used < maxTokens && len(rest) > 0 {
+		picked = append(picked, a.retrieveFindings(ctx, rest, section.Heading+" "+section.Focus, maxTokens-used, embedded)...)
+	}
+	return picked
+}
+
+// outlineReport asks the writer for the report's title and sections
+func (a *DeepResearcher) outlineReport(ctx context.Context, topic, brief, titles string) (reportOutline, error) {
+	prompt := fmt.Sprintf(`Plan the outline of a research report for: %s
+
+%s
+
+Sources collected:
+%s
+Respond ONLY with valid JSON: a title and 3-8 sections, starting with a summary of the key findings. "focus" says what each section covers, specifically enough to find its supporting sources. "sources" lists the numbers of the sources whose details belong in the section; a source may belong to several sections.%s
+{
+  "title": "...",
+  "sections": [{"heading": "...", "focus": "...", "sources": [1, 2]}]
+}`, topic, brief, titles, a.reportGuidance()+a.templateGuidance()+a.languageDirective())
+
+	var outline reportOutline
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.writer, []llm.Message{
+		{Role: "system", Content: "You are a research report planner. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, outlineSchema, &outline)
+	if err != nil {
+		return reportOutline{}, jsonError("report outline", err)
+	}
+	if len(outline.Sections) == 0 {
+		return reportOutline{}, fmt.Errorf("report outline has no sections")
+	}
+	if outline.Title == "" {
+		outline.Title = topic
+	}
+	return outline, nil
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// findingsLogEntry is one line of a findings log: the raw results of a round
+// and, when they were too long, their digest
+type findingsLogEntry struct {
+	Round  int       `json:"round"`
+	Text   string    `json:"text"`
+	Digest string    `json:"digest,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// findingsLog is an append-only JSONL file of an exhaustive run's round
+// results. The run keeps only the query and plan in memory and reads the
+// rounds back (their digests, where they have one) when a report is written,
+// so an hours-long run doesn't grow its research context (and checkpoint)
+// without bound, and the raw results outlive whatever digests and report
+// compression do to them.
+type findingsLog struct {
+	path string
+	mu   sync.Mutex // Serializes appends
+}
+
+// findingsLogPath is where the findings log of the run checkpointing to checkpointPath goes
+func findingsLogPath(checkpointPath string) string {
+	return strings.TrimSuffix(checkpointPath, ".checkpoint.json") + ".findings.jsonl"
+}
+
+// openFindingsLog opens the findings log at path for a run that has finished
+// round rounds, dropping entries of later rounds (written before a crash the
+// checkpoint didn't see) and any line cut short
+func openFindingsLog(path string, round int) (*findingsLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create findings log directory: %w", err)
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &findingsLog{path: path}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open findings log: %w", err)
+	}
+	defer f.Close()
+
+	// Keep the leading entries of finished rounds; only the round number is decoded
+	var keep int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break // io.EOF, possibly after a line cut short
+		}
+		var entry struct {
+			Round int `json:"round"`
+		}
+		if json.Unmarshal(line, &entry) != nil || entry.Round > round {
+			break
+		}
+		keep += int64(len(line))
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > keep {
+		if err := os.Truncate(path, keep); err != nil {
+			return nil, fmt.Errorf("failed to trim findings log: %w", err)
+		}
+	}
+	return &findingsLog{path: path}, nil
+}
+
+// append adds a round's results and their digest ("" = none) as one line
+func (l *findingsLog) append(round int, text, digest string) error {
+	line, err := json.Marshal(findingsLogEntry{Round: round, Text: text, Digest: digest, At: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal findings: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open findings log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write findings log: %w", err)
+	}
+	return f.Close()
+}
+
+// context reads the logged rounds back as research context sections, oldest
+// first: a round's digest when it has one, else its results
+func (l *findingsLog) context() (string, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open findings log: %w", err)
+	}
+	defer f.Close()
+
+	var sb strings.Builder
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break // A line still being written isn't finished
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read findings log: %w", err)
+		}
+		var entry findingsLogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		sb.WriteString(roundSection(entry.Round, entry.Text, entry.Digest))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrFinished is the cause a run's research context is cancelled with by
+// Finish: research stops and the report is written from what was gathered
+var ErrFinished = errors.New("finish requested")
+
+// ErrDiscarded, as the cause a run's context is cancelled with
+// (context.WithCancelCause), stops the run without writing a report. A plain
+// cancellation still gets the partial report.
+var ErrDiscarded = errors.New("research cancelled and discarded")
+
+// Finish stops the research phase now, as a spent budget would: the searches
+// and fetches in flight are abandoned, and the report is written from what was
+// gathered with the full pipeline (the run's checkpoint is kept, so resume can
+// continue it). A paused run is resumed. Returns false outside the research
+// phase, e.g. while the report is being written.
+func (a *DeepResearcher) Finish() bool {
+	a.budget.mu.Lock()
+	active := a.budget.active
+	if active {
+		a.budget.cancel(ErrFinished)
+	}
+	a.budget.mu.Unlock()
+	if !active {
+		return false
+	}
+	a.log.Info("⏹️ Finish requested")
+	a.Resume()
+	return true
+}
+
+// reportContext is the context a run's report is written in. A cancelled run
+// still gets its partial report, so the report is detached from the
+// cancellation, unless ErrDiscarded was its cause.
+func reportContext(parent context.Context) (context.Context, error) {
+	if errors.Is(context.Cause(parent), ErrDiscarded) {
+		return nil, ErrDiscarded
+	}
+	if parent.Err() != nil {
+		return context.WithoutCancel(parent), nil
+	}
+	return parent, nil
+}
+
+// gathered is what a run collected besides the report text, for finishResult
+type gathered struct {
+	sources    []Source
+	records    []map[string]any
+	images     []Image
+	queryStats []QueryStat
+	conflicts  []Fact // From analyzeClaims
+	consensus  []Fact
+}
+
+// finishResult turns a written report into the run's result, the same way for
+// every kind of run: it evaluates and revises the report (Config.EvaluateReport),
+// checks its citations, maps its claims, extracts the knowledge graph, appends
+// the conflicts, changes, records, media, and query yield sections, and renders
+// Config.ReportTemplate. The caller fills in Usage once no more LLM calls are due,
+// and anything particular to its kind of run.
+func (a *DeepResearcher) finishResult(ctx context.Context, topic string, plan ResearchPlan, report string, g gathered) ResearchResult {
+	report, sources, evaluation := a.evaluateAndRevise(ctx, topic, plan, report, g.sources)
+	report, citations := a.verifyCitations(report, sources)
+	claims := reportClaims(report, sources)
+	changes := a.changes(sources, g.records)
+	entities, relations := a.extractGraph(ctx, sources)
+	report = appendConflicts(report, g.conflicts)
+	report = appendChanges(report, changes, sources)
+	report = a.appendRecordsTable(report, g.records)
+	report = appendMedia(report, g.images)
+	report = appendQueryStats(report, g.queryStats)
+	report, templated := a.applyTemplate(topic, report, sources)
+	return ResearchResult{
+		Report:       report,
+		Sources:      sources,
+		Records:      g.records,
+		RecordFields: a.config.recordFields(),
+		Citations:    citations,
+		Claims:       claims,
+		QueryStats:   g.queryStats,
+		Changes:      changes,
+		Entities:     entities,
+		Relations:    relations,
+		Conflicts:    g.conflicts,
+		Consensus:    g.consensus,
+		Images:       g.images,
+		Evaluation:   evaluation,
+		Templated:    templated,
+	}
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultFollowUpSearches is how many supplemental searches FollowUp may run
+const DefaultFollowUpSearches = 3
+
+// followUpResultsPerSearch caps the new sources taken from each supplemental search
+const followUpResultsPerSearch = 5
+
+// FollowUpAnswer is the answer to a question about a finished report
+type FollowUpAnswer struct {
+	Question  string
+	Answer    string        // Markdown, citing Sources by number like the report
+	Queries   []string      `json:",omitempty"` // Supplemental searches that were run
+	Sources   []Source      // The report's sources followed by the ones the searches added
+	Citations CitationCheck // How the answer's [n] citations and links matched Sources
+}
+
+// FollowUp answers a question about a finished report from its research: the
+// report, its sources, and (for the researcher that ran it) the round summaries.
+// When those don't cover the question, up to DefaultFollowUpSearches targeted
+// searches fill the gaps.
+func (a *DeepResearcher) FollowUp(ctx context.Context, result ResearchResult, question string) (FollowUpAnswer, error) {
+	return a.FollowUpWithSearches(ctx, result, question, DefaultFollowUpSearches)
+}
+
+// FollowUpWithSearches is FollowUp with a limit on supplemental searches (0 = answer from the report's research only)
+func (a *DeepResearcher) FollowUpWithSearches(ctx context.Context, result ResearchResult, question string, maxSearches int) (FollowUpAnswer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return FollowUpAnswer{}, errors.New("question is required")
+	}
+	answer := FollowUpAnswer{Question: question, Sources: result.Sources}
+	a.log.Info("❓ Follow-up", "question", question)
+
+	// The report itself is searchable too, for what it concluded from the sources
+	findings := a.reportFindings(result.Sources)
+	for _, chunk := range splitContextIntoChunks(result.Report, maxFindingChars) {
+		if chunk = strings.TrimSpace(chunk); chunk != "" {
+			text := "From the report:\n" + chunk
+			findings = append(findings, finding{text: text, tokens: llm.EstimateTokens(text)})
+		}
+	}
+	embedded := a.indexFindings(ctx, findings)
+
+	// Half the budget for findings, like the report; supplemental results get a share of it
+	budget := a.config.maxContextTokens() / 2
+	relevant := a.retrieveFindings(ctx, findings, question, budget*3/4, embedded)
+	texts := make([]string, 0, len(relevant))
+	for _, f := range relevant {
+		texts = append(texts, f.text)
+	}
+
+	if maxSearches > 0 && a.searcher != nil {
+		queries, err := a.followUpQueries(ctx, question, strings.Join(texts, "\n\n"), maxSearches)
+		if err != nil {
+			a.log.Warn("⚠️ Skipping follow-up searches", "error", err)
+		}
+		var added []Source
+		for _, query := range queries {
+			sources := a.followUpSearch(ctx, query, append(answer.Sources[:len(answer.Sources):len(answer.Sources)], added...))
+			added = append(added, sources...)
+			answer.Queries = append(answer.Queries, query)
+		}
+		if len(added) > 0 {
+			var extra []string
+			for i, src := range added {
+				extra = append(extra, fmt.Sprintf("[%d] %s - %s\n%s", len(answer.Sources)+i+1, src.Title, src.URL, src.Snippet))
+			}
+			texts = append(texts, a.truncateToTokens(ctx, strings.Join(extra, "\n\n"), budget/4))
+			answer.Sources = append(answer.Sources[:len(answer.Sources):len(answer.Sources)], added...)
+		}
+	}
+
+	if len(texts) == 0 {
+		answer.Answer = "The research behind this report has nothing on that question."
+		return answer, nil
+	}
+
+	prompt := fmt.Sprintf(`Answer this follow-up question about a research report: %s
+
+Findings:
+%s
+
+Answer in Markdown using only these findings, as briefly as the question allows. Cite sources inline by their number in square brackets, e.g. [3] or [2, 5], right after the facts they support. Only cite numbers that appear in the findings and only link URLs that appear in them - never invent URLs. If the findings don't answer the question, say what is missing.`, question, strings.Join(texts, "\n\n"))
+
+	resp, err := a.chat(a.withCall(ctx, CallReport), a.writer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return FollowUpAnswer{}, fmt.Errorf("answering follow-up failed: %w", err)
+	}
+	answer.Answer, answer.Citations = a.verifyCitations(stripThinkTags(resp), answer.Sources)
+	return answer, nil
+}
+
+// followUpQueries asks the writer which searches would fill the gaps the
+// findings leave in answering the question (none when they already answer it)
+func (a *DeepResearcher) followUpQueries(ctx context.Context, question, findings string, maxSearches int) ([]string, error) {
+	prompt := fmt.Sprintf(`A user asked a follow-up question about a research report: %s
+
+What the research found that may be relevant:
+%s
+
+If these findings answer the question, respond with no queries. Otherwise give up to %d targeted web search queries that would find the missing information.
+Respond ONLY with valid JSON:
+{"queries": ["..."]}`, question, findings, maxSearches)
+
+	var parsed struct {
+		Queries []string `json:"queries"`
+	}
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.writer, []llm.Message{
+		{Role: "system", Content: "You are a research assistant. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, queriesSchema, &parsed)
+	if err != nil {
+		return nil, jsonError("follow-up queries", err)
+	}
+	queries := CleanQueries(parsed.Queries)
+	return queries[:min(len(queries), maxSearches)], nil
+}
+
+// followUpSearch runs one supplemental search and returns the results that
+// aren't among the known sources, as new sources
+func (a *DeepResearcher) followUpSearch(ctx context.Context, query string, known []Source) []Source {
+	results, err := a.searcher.Search(a.searchContext(ctx), query)
+	if err != nil {
+		a.log.Warn("❌ Search failed", "query", query, "error", err)
+		return nil
+	}
+
+	seen := make(map[string]bool, len(known))
+	for _, src := range known {
+		seen[citationKey(src.URL)] = true
+	}
+	var added []Source
+	for _, r := range a.filterResults(results) {
+		key := citationKey(r.URL)
+		if seen[key] || r.URL == "" {
+			continue
+		}
+		seen[key] = true
+		added = append(added, Source{Title: r.Title, URL: r.URL, Snippet: r.Content})
+		if len(added) == followUpResultsPerSearch {
+			break
+		}
+	}
+	a.log.Info("🔎 Follow-up search", "query", query, "new_sources", len(added))
+	return added
+}
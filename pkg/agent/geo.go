@@ -0,0 +1,310 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/geo"
+	"deep-research/pkg/llm"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Config.GeoFilter values
+const (
+	GeoFlag = "flag" // Mark the sources outside the area in the source list, so the report can say so
+	GeoDrop = "drop" // Leave the sources and records outside the area out of the report
+)
+
+// GeoFilters are the accepted Config.GeoFilter values besides "" (GeoFlag)
+var GeoFilters = []string{GeoFlag, GeoDrop}
+
+const (
+	// DefaultGeoRadius is the area's radius in km when Config.GeoRadius is 0
+	// and the geocoder doesn't know the area's bounds
+	DefaultGeoRadius = 10
+
+	// locationBatch is how many sources one LLM call finds the locations of
+	locationBatch = 20
+
+	// maxGeocodes caps the addresses geocoded per run (Nominatim answers one a second)
+	maxGeocodes = 200
+
+	// distanceField is the record field the distance from the area is stored in
+	distanceField = "distance_km"
+)
+
+// locationFieldWords are the (lowercased) word prefixes of record fields that
+// hold a listing's address or part of it
+var locationFieldWords = []string{"address", "location", "city", "town", "neighborhood", "neighbourhood", "district", "zip", "postal", "postcode", "adres", "localit", "oras", "oraș", "cartier", "zona"}
+
+// Location is where a source's listing is, found for Config.GeoArea
+type Location struct {
+	Address  string  // As the page or its record states it
+	Place    string  // The geocoder's name for it
+	Lat, Lon float64 // Where the geocoder put it
+	Distance float64 // km from the area's center
+	Outside  bool    `json:",omitempty"` // Outside the area
+}
+
+// String formats the location for the source list, e.g. "Florești (9 km away,
+// outside the area)"
+func (l Location) String() string {
+	if l.Outside {
+		return fmt.Sprintf("%s (%.0f km away, outside the area)", l.Address, l.Distance)
+	}
+	return fmt.Sprintf("%s (%.0f km away)", l.Address, l.Distance)
+}
+
+// locationResponse is the summarizer's answer to locatePages
+type locationResponse struct {
+	Locations []struct {
+		Source   int    `json:"source"`
+		Location string `json:"location"`
+	} `json:"locations"`
+}
+
+// ValidateGeo rejects a negative radius, an unknown filter, or either without an area
+func ValidateGeo(area string, radius float64, filter string) error {
+	if radius < 0 {
+		return fmt.Errorf("geo radius must not be negative")
+	}
+	if filter != "" && !slices.Contains(GeoFilters, filter) {
+		return fmt.Errorf("unknown geo filter %q (use %s)", filter, strings.Join(GeoFilters, ", "))
+	}
+	if area == "" && (radius > 0 || filter != "") {
+		return fmt.Errorf("a geo radius or filter needs a geo area")
+	}
+	return nil
+}
+
+// filterArea checks the sources' listings against Config.GeoArea (see
+// locateSources). With GeoDrop the sources outside the area and their records
+// are left out; otherwise they stay, marked in the source list. Sources
+// without an address are kept. The returned note tells the writer about the
+// sources outside the area.
+func (a *DeepResearcher) filterArea(ctx context.Context, sources []Source, records []map[string]any) ([]Source, []map[string]any, string) {
+	extent, outside := a.locateSources(ctx, sources, records)
+	if outside == 0 {
+		return sources, records, ""
+	}
+	if a.config.GeoFilter != GeoDrop {
+		return sources, records, fmt.Sprintf("\n\n--- NOTE: The sources marked \"outside the area\" in the source list are located outside %s (%s). Don't present them as matches; mention them only as alternatives nearby, saying where they are. ---\n", a.config.GeoArea, extent)
+	}
+
+	var kept []Source
+	var dropped []string
+	outsideURLs := make(map[string]bool)
+	for _, src := range sources {
+		if src.Location != nil && src.Location.Outside {
+			dropped = append(dropped, src.URL)
+			outsideURLs[normalizeURL(src.URL)] = true
+			continue
+		}
+		kept = append(kept, src)
+	}
+	var keptRecords []map[string]any
+	for _, rec := range records {
+		if u, _ := rec["url"].(string); !outsideURLs[normalizeURL(u)] {
+			keptRecords = append(keptRecords, rec)
+		}
+	}
+	a.log.Info("📍 Dropped sources outside the area", "area", a.config.GeoArea, "dropped", len(dropped), "kept", len(kept))
+	return kept, keptRecords, fmt.Sprintf("\n\n--- NOTE: These listings are located outside %s (%s) and were left out of the source list; leave them out of the report: %s ---\n", a.config.GeoArea, extent, strings.Join(dropped[:min(len(dropped), 30)], ", "))
+}
+
+// locateSources locates the sources' listings for Config.GeoArea. A source's
+// address comes from its record's address fields, else the summarizer reads it
+// from its summary or snippet; it is geocoded and the source gets a Location
+// (its record a distanceField). It returns the area's extent for the writer
+// and how many sources are outside it.
+func (a *DeepResearcher) locateSources(ctx context.Context, sources []Source, records []map[string]any) (string, int) {
+	if a.config.GeoArea == "" || len(sources) == 0 {
+		return "", 0
+	}
+	geocoder := a.config.Geocoder
+	if geocoder == nil {
+		geocoder = geo.NewNominatim(geo.DefaultNominatimURL)
+	}
+	area, err := a.geoArea(ctx, geocoder)
+	if err != nil {
+		a.log.Warn("⚠️ Geo area not found; keeping all sources", "area", a.config.GeoArea, "error", err)
+		return "", 0
+	}
+
+	addresses := a.sourceAddresses(ctx, sources, records)
+	places := make(map[string]*geo.Place)
+	located, outside := 0, 0
+	for _, i := range slices.Sorted(maps.Keys(addresses)) {
+		address := addresses[i]
+		place, ok := places[address]
+		if !ok {
+			if len(places) >= maxGeocodes {
+				continue
+			}
+			if p, err := geocoder.Geocode(ctx, address, &area.Point); err == nil {
+				place = &p
+			} else if err != geo.ErrNotFound {
+				a.log.Debug("⚠️ Geocoding failed", "address", address, "error", err)
+			}
+			places[address] = place
+		}
+		if place == nil {
+			continue
+		}
+		distance := geo.Distance(area.Point, place.Point)
+		sources[i].Location = &Location{
+			Address:  address,
+			Place:    place.Name,
+			Lat:      place.Point.Lat,
+			Lon:      place.Point.Lon,
+			Distance: math.Round(distance*10) / 10,
+			Outside:  !a.config.inArea(area, place.Point),
+		}
+		located++
+		if sources[i].Location.Outside {
+			outside++
+		}
+	}
+
+	distances := make(map[string]*Location)
+	for _, src := range sources {
+		if src.Location != nil {
+			distances[normalizeURL(src.URL)] = src.Location
+		}
+	}
+	if len(a.config.extractionFields()) > 0 {
+		for u, rec := range recordsByURL(records) {
+			if loc := distances[u]; loc != nil {
+				rec[distanceField] = loc.Distance
+			}
+		}
+	}
+	if located > 0 {
+		a.log.Info("📍 Located sources", "area", a.config.GeoArea, "located", located, "outside", outside)
+	}
+	return a.config.areaExtent(area), outside
+}
+
+// geoArea finds Config.GeoArea: coordinates as given, else geocoded
+func (a *DeepResearcher) geoArea(ctx context.Context, geocoder geo.Geocoder) (geo.Place, error) {
+	if p, ok := geo.ParsePoint(a.config.GeoArea); ok {
+		return geo.Place{Name: a.config.GeoArea, Point: p}, nil
+	}
+	return geocoder.Geocode(ctx, a.config.GeoArea, nil)
+}
+
+// inArea reports whether p lies in the area: within Config.GeoRadius of its
+// center, else within its bounds, else within DefaultGeoRadius
+func (c Config) inArea(area geo.Place, p geo.Point) bool {
+	switch {
+	case c.GeoRadius > 0:
+		return geo.Distance(area.Point, p) <= c.GeoRadius
+	case area.Box != nil:
+		return area.Box.Contains(p)
+	default:
+		return geo.Distance(area.Point, p) <= DefaultGeoRadius
+	}
+}
+
+// areaExtent describes the area's extent for the writer, e.g. "more than 15 km from it"
+func (c Config) areaExtent(area geo.Place) string {
+	switch {
+	case c.GeoRadius > 0:
+		return "more than " + strconv.FormatFloat(c.GeoRadius, 'f', -1, 64) + " km from it"
+	case area.Box != nil:
+		return "outside its bounds"
+	default:
+		return fmt.Sprintf("more than %d km from it", DefaultGeoRadius)
+	}
+}
+
+// sourceAddresses finds the addresses of the sources' listings, by index: from
+// their records' address fields, else read by the summarizer from their
+// summaries or snippets. Provided documents have none.
+func (a *DeepResearcher) sourceAddresses(ctx context.Context, sources []Source, records []map[string]any) map[int]string {
+	addresses := make(map[int]string)
+	byURL := recordsByURL(records)
+	var unread []int
+	for i, src := range sources {
+		if src.Document {
+			continue
+		}
+		if address := recordAddress(byURL[normalizeURL(src.URL)]); address != "" {
+			addresses[i] = address
+		} else if src.Summary != "" || src.Snippet != "" {
+			unread = append(unread, i)
+		}
+	}
+	for batch := range slices.Chunk(unread, locationBatch) {
+		found, err := a.locatePages(ctx, sources, batch)
+		if err != nil {
+			a.log.Debug("⚠️ Reading locations failed", "sources", len(batch), "error", err)
+			continue
+		}
+		maps.Copy(addresses, found)
+	}
+	return addresses
+}
+
+// locatePages asks the summarizer where the listings of the sources at indexes are
+func (a *DeepResearcher) locatePages(ctx context.Context, sources []Source, indexes []int) (map[int]string, error) {
+	var list strings.Builder
+	for n, i := range indexes {
+		text := sources[i].Summary
+		if text == "" {
+			text = sources[i].Snippet
+		}
+		runes := []rune(strings.Join(strings.Fields(text), " "))
+		fmt.Fprintf(&list, "%d. %s\n   %s\n   %s\n", n+1, sources[i].Title, sources[i].URL, string(runes[:min(len(runes), 600)]))
+	}
+
+	prompt := fmt.Sprintf(`Where is the place, property, or business each of these pages is about? Give its location as the page states it: a street address, a neighborhood with its town, a town, or a postal code, with the town, region, and country where the page names or clearly implies them. Leave out pages that don't state a location, and pages about many places; don't guess.
+
+%s
+Respond ONLY with valid JSON:
+{"locations": [{"source": 1, "location": "Strada Horea 5, Cluj-Napoca, Romania"}]}`, list.String())
+
+	var resp locationResponse
+	err := a.chatJSON(a.withCall(ctx, CallSummarization), a.summarizer, []llm.Message{
+		{Role: "system", Content: "You find the locations of listings for a researcher. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, locationSchema, &resp)
+	if err != nil {
+		return nil, jsonError("locations", err)
+	}
+
+	found := make(map[int]string)
+	for _, l := range resp.Locations {
+		location := strings.TrimSpace(l.Location)
+		if l.Source >= 1 && l.Source <= len(indexes) && location != "" {
+			found[indexes[l.Source-1]] = location
+		}
+	}
+	return found, nil
+}
+
+// recordAddress joins a record's address fields (street, town, postal code,
+// ...), in field order ("" without a record or an address)
+func recordAddress(rec map[string]any) string {
+	var parts []string
+	for _, k := range slices.Sorted(maps.Keys(rec)) {
+		if k == distanceField || !hasFieldWord(k, locationFieldWords) {
+			continue
+		}
+		switch v := rec[k].(type) {
+		case string:
+			if v = strings.TrimSpace(v); v != "" && !slices.Contains(parts, v) {
+				parts = append(parts, v)
+			}
+		case float64:
+			// A postal code extracted as a number
+			if v > 0 && v == math.Trunc(v) {
+				parts = append(parts, strconv.FormatFloat(v, 'f', 0, 64))
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
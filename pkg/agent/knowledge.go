@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Knowledge is what earlier runs on a topic found: every source of a named
+// collection with the facts taken from it. Passed as Config.Knowledge, it lets a
+// run skip what is already known (Config.OnlyNew) and report what changed.
+type Knowledge struct {
+	Collection string
+	LastRun    time.Time     // When the collection was last updated (zero = no run yet)
+	Sources    []KnownSource // In the order they were first seen
+	index      map[string]int
+}
+
+// KnownSource is a source of a collection and the latest facts taken from it
+type KnownSource struct {
+	URL       string
+	Title     string
+	Summary   string         `json:",omitempty"` // Latest page summary, or search snippet
+	Record    map[string]any `json:",omitempty"` // Latest structured record extracted from the page
+	FirstSeen time.Time
+	LastSeen  time.Time
+	JobID     string `json:",omitempty"` // Job that last saw it
+}
+
+// NewKnowledge returns a collection's knowledge with its sources indexed by URL
+func NewKnowledge(collection string, lastRun time.Time, sources []KnownSource) *Knowledge {
+	k := &Knowledge{Collection: collection, LastRun: lastRun, Sources: sources, index: make(map[string]int, len(sources))}
+	for i, src := range sources {
+		k.index[normalizeURL(src.URL)] = i
+	}
+	return k
+}
+
+// Lookup returns the known source at rawURL (tracking parameters and trailing slashes aside)
+func (k *Knowledge) Lookup(rawURL string) (KnownSource, bool) {
+	if k == nil {
+		return KnownSource{}, false
+	}
+	i, ok := k.index[normalizeURL(rawURL)]
+	if !ok {
+		return KnownSource{}, false
+	}
+	return k.Sources[i], true
+}
+
+// Update returns the collection after a run: the result's sources are added or
+// refreshed with their latest title, summary, and record
+func (k *Knowledge) Update(result ResearchResult, jobID string, at time.Time) *Knowledge {
+	var collection string
+	var sources []KnownSource
+	if k != nil {
+		collection = k.Collection
+		sources = append(sources, k.Sources...)
+	}
+	updated := NewKnowledge(collection, at, sources)
+
+	records := recordsByURL(result.Records)
+	for _, src := range result.Sources {
+		if src.Document {
+			continue
+		}
+		key := normalizeURL(src.URL)
+		summary := src.Summary
+		if summary == "" {
+			summary = src.Snippet
+		}
+		i, ok := updated.index[key]
+		if !ok {
+			i = len(updated.Sources)
+			updated.index[key] = i
+			updated.Sources = append(updated.Sources, KnownSource{URL: src.URL, FirstSeen: at})
+		}
+		known := &updated.Sources[i]
+		if src.Title != "" {
+			known.Title = src.Title
+		}
+		if summary != "" {
+			known.Summary = strings.Join(strings.Fields(summary), " ")
+		}
+		if rec, ok := records[key]; ok {
+			known.Record = rec
+		}
+		known.LastSeen = at
+		known.JobID = jobID
+	}
+	return updated
+}
+
+// Changes is how a run's sources differ from the collection's earlier runs
+type Changes struct {
+	Collection string
+	Since      time.Time     // The previous run (zero = the collection's first run)
+	New        []int         // Numbers of the sources not seen before
+	Gone       []KnownSource // Sources the previous run found that this one didn't (not tracked with Config.OnlyNew)
+	Updated    []FactChange  // Extracted fields whose value changed since a source was last seen
+}
+
+// FactChange is an extracted field of a known source whose value changed
+type FactChange struct {
+	Source int // The source's number
+	Field  string
+	Old    string
+	New    string
+}
+
+// changes compares the run's sources and records with Config.Knowledge
+// (nil when the run isn't part of a collection)
+func (a *DeepResearcher) changes(sources []Source, records []map[string]any) *Changes {
+	k := a.config.Knowledge
+	if k == nil {
+		return nil
+	}
+	changes := &Changes{Collection: k.Collection, Since: k.LastRun}
+	current := recordsByURL(records)
+	seen := make(map[string]bool)
+	for i, src := range sources {
+		key := normalizeURL(src.URL)
+		if seen[key] || src.Document {
+			continue
+		}
+		seen[key] = true
+
+		known, ok := k.Lookup(src.URL)
+		if !ok {
+			changes.New = append(changes.New, i+1)
+			continue
+		}
+		rec := current[key]
+		for _, f := range changedFields(known.Record, rec) {
+			changes.Updated = append(changes.Updated, FactChange{Source: i + 1, Field: f, Old: formatRecordValue(f, known.Record[f]), New: formatRecordValue(f, rec[f])})
+		}
+	}
+
+	if !a.config.OnlyNew && !k.LastRun.IsZero() {
+		for _, known := range k.Sources {
+			if !known.LastSeen.Before(k.LastRun) && !seen[normalizeURL(known.URL)] {
+				changes.Gone = append(changes.Gone, known)
+			}
+		}
+	}
+	if n := len(changes.New); n > 0 || len(changes.Gone) > 0 || len(changes.Updated) > 0 {
+		a.log.Info("🆕 Changes since last run", "collection", k.Collection, "new", n, "gone", len(changes.Gone), "updated", len(changes.Updated))
+	}
+	return changes
+}
+
+// changedFields returns the fields, sorted, that both records of a page have
+// with different values (the url field aside; nil when either record is missing)
+func changedFields(old, rec map[string]any) []string {
+	var fields []string
+	for f, v := range rec {
+		prev, had := old[f]
+		if !had || prev == nil || v == nil || strings.EqualFold(f, "url") || reflect.DeepEqual(prev, v) {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// recordsByURL indexes extracted records by their "url" field; the first record of a page wins
+func recordsByURL(records []map[string]any) map[string]map[string]any {
+	byURL := make(map[string]map[string]any, len(records))
+	for _, rec := range records {
+		for f, v := range rec {
+			if u, ok := v.(string); ok && u != "" && strings.EqualFold(f, "url") {
+				if _, dup := byURL[normalizeURL(u)]; !dup {
+					byURL[normalizeURL(u)] = rec
+				}
+			}
+		}
+	}
+	return byURL
+}
+
+// appendChanges adds the "What Changed Since Last Run" section for runs that are part of a collection
+func appendChanges(report string, changes *Changes, sources []Source) string {
+	if changes == nil {
+		return report
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## What Changed Since Last Run\n\n")
+	if changes.Since.IsZero() {
+		fmt.Fprintf(&sb, "First run of collection %q: all %d sources are new.\n", changes.Collection, len(changes.New))
+		return report + sb.String()
+	}
+	fmt.Fprintf(&sb, "Compared with the previous run of collection %q on %s.\n", changes.Collection, changes.Since.Format("2006-01-02 15:04"))
+	if len(changes.New) == 0 && len(changes.Gone) == 0 && len(changes.Updated) == 0 {
+		sb.WriteString("\nNothing changed: no new sources, and every source found last time was found again.\n")
+		return report + sb.String()
+	}
+
+	if len(changes.New) > 0 {
+		fmt.Fprintf(&sb, "\n**New sources (%d):**\n\n", len(changes.New))
+		for _, n := range changes.New {
+			src := sources[n-1]
+			fmt.Fprintf(&sb, "- [%d] %s\n", n, sourceLabel(src.Title, src.URL))
+		}
+	}
+	if len(changes.Updated) > 0 {
+		fmt.Fprintf(&sb, "\n**Updated facts (%d):**\n\n", len(changes.Updated))
+		for _, c := range changes.Updated {
+			src := sources[c.Source-1]
+			fmt.Fprintf(&sb, "- [%d] %s: %s %s → %s\n", c.Source, sourceLabel(src.Title, src.URL), c.Field, c.Old, c.New)
+		}
+	}
+	if len(changes.Gone) > 0 {
+		fmt.Fprintf(&sb, "\n**No longer found (%d):**\n\n", len(changes.Gone))
+		for _, known := range changes.Gone {
+			fmt.Fprintf(&sb, "- [%s](%s)\n", sourceLabel(known.Title, known.URL), known.URL)
+		}
+	}
+	return report + sb.String()
+}
+
+// sourceLabel is a source's title, or its URL when it has none
+func sourceLabel(title, url string) string {
+	if title = strings.Join(strings.Fields(title), " "); title != "" {
+		return title
+	}
+	return url
+}
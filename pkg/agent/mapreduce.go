@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// minReportBudget is the smallest prompt budget (in tokens) the report writer
+// bisects down to before falling back to writeReportMapReduce
+const minReportBudget = 1024
+
+// shrinkBudget halves a prompt budget after the backend rejected a prompt as too
+// large, or cuts it to half of the context window the backend reported if smaller
+func shrinkBudget(budget int, overflow *llm.ContextOverflowError) int {
+	next := budget / 2
+	if overflow.ContextLength > 0 {
+		next = min(next, overflow.ContextLength/2)
+	}
+	return next
+}
+
+// writeReportMapReduce is the last resort for a report whose prompts keep
+// overflowing the model's context. The research context is split into chunks
+// that fit budget and a partial report is written from each (map); the partials
+// are then merged a few at a time until one report is left (reduce). Unlike
+// truncation this keeps every finding in play. A chunk that still overflows is
+// split in half and retried.
+func (a *DeepResearcher) writeReportMapReduce(ctx context.Context, topic, researchContext string, sources []Source, budget int) (string, error) {
+	budget = max(budget, minReportBudget)
+	brief := a.truncateToTokens(ctx, researchContext, budget/8)
+
+	chunkChars := charsForTokens(researchContext, a.countTokens(ctx, researchContext), budget/2)
+	chunks := splitContextIntoChunks(researchContext, max(chunkChars, 500))
+	a.log.Info("🧩 Writing the report in parts", "chunks", len(chunks), "budget", budget)
+
+	var partials []string
+	for i, chunk := range chunks {
+		if !isDraft(ctx) {
+			a.emitProgress(ProgressEvent{
+				Phase:     "writing_report",
+				URLsFound: len(sources),
+				Message:   fmt.Sprintf("Writing partial report %d/%d", i+1, len(chunks)),
+				Percent:   90 + i*6/len(chunks),
+			})
+		}
+		parts, err := a.writePartialReport(ctx, topic, brief, chunk, sources, budget)
+		if err != nil {
+			return "", fmt.Errorf("partial report %d/%d failed: %w", i+1, len(chunks), err)
+		}
+		partials = append(partials, parts...)
+	}
+	if len(partials) == 0 {
+		return "", errors.New("no partial reports were written")
+	}
+
+	// Merge until a single group is left; that last merge writes the final report
+	for round := 1; ; round++ {
+		groups := a.groupByTokens(ctx, partials, budget/2)
+		final := len(groups) == 1
+		if !final && len(groups) == len(partials) {
+			// Every partial fills a merge prompt on its own: shorten them so pairs fit
+			for i := range partials {
+				partials[i] = a.truncateToTokens(ctx, partials[i], budget/5)
+			}
+			continue
+		}
+
+		if !isDraft(ctx) {
+			a.emitProgress(ProgressEvent{
+				Phase:     "writing_report",
+				URLsFound: len(sources),
+				Message:   fmt.Sprintf("Merging %d partial reports", len(partials)),
+				Percent:   96,
+			})
+		}
+		a.log.Info("🧩 Merging partial reports", "round", round, "partials", len(partials), "groups", len(groups))
+
+		merged := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if len(group) == 1 && !final {
+				merged = append(merged, group[0])
+				continue
+			}
+			text, err := a.mergePartialReports(ctx, topic, brief, group, final)
+			if err != nil {
+				return "", fmt.Errorf("merging partial reports failed: %w", err)
+			}
+			merged = append(merged, text)
+		}
+		if final {
+			return merged[0], nil
+		}
+		partials = merged
+	}
+}
+
+// writePartialReport writes up one chunk of the research context. If the backend
+// still rejects the prompt as too large, the chunk is split in half and each half
+// written up separately.
+func (a *DeepResearcher) writePartialReport(ctx context.Context, topic, brief, chunk string, sources []Source, budget int) ([]string, error) {
+	linkEmphasis := ""
+	if a.config.ResultLinks {
+		linkEmphasis = "\n\nCRITICAL: Include direct clickable links [Title](URL) for each item."
+	}
+	chunkSources := a.truncateToTokens(ctx, sourcesInText(sources, chunk), budget/4)
+	if chunkSources == "" {
+		chunkSources = "(none listed - only cite [n] numbers that appear in the findings)\n"
+	}
+
+	prompt := fmt.Sprintf(`Write the part of a research report on: %s that these findings support.
+
+%s
+
+Findings:
+%s
+
+Sources:
+%s
+Cover every concrete fact in these findings (figures, names, prices, dates, links). The parts are merged into one report later, so skip introductions and conclusions. Cite sources inline by their number in square brackets, e.g. [3] or [2, 5], right after the facts they support. Only cite numbers from the Sources list and only link URLs that appear in it - never invent URLs.%s%s`, topic, brief, chunk, chunkSources, linkEmphasis, a.languageDirective())
+
+	resp, err := a.chat(a.withCall(ctx, CallCompression), a.writer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	var overflow *llm.ContextOverflowError
+	if errors.As(err, &overflow) && len(chunk) > 1000 {
+		a.log.Warn("📏 Partial report overflowed, splitting its findings in half", "chars", len(chunk))
+		var parts []string
+		for _, half := range splitContextIntoChunks(chunk, len(chunk)/2+1) {
+			p, err := a.writePartialReport(ctx, topic, brief, half, sources, budget)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, p...)
+		}
+		return parts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []string{strings.TrimSpace(stripThinkTags(resp))}, nil
+}
+
+// mergePartialReports combines partial reports into one. The final merge writes
+// the finished report; earlier ones only combine parts for the next round.
+func (a *DeepResearcher) mergePartialReports(ctx context.Context, topic, brief string, partials []string, final bool) (string, error) {
+	var parts strings.Builder
+	for i, p := range partials {
+		fmt.Fprintf(&parts, "--- Part %d ---\n%s\n\n", i+1, p)
+	}
+
+	task := "Combine these partial reports into one, to be merged with others later: skip introductions and conclusions."
+	if final {
+		task = "Combine these partial reports into the final research report: start with a summary of the key findings and organize the rest with Markdown headings."
+		if a.config.ResultLinks {
+			task += "\n\nCRITICAL: Include direct clickable links [Title](URL) for each item."
+		}
+		task += a.reportGuidance()
+	}
+
+	prompt := fmt.Sprintf(`These are partial reports, each written from part of the research on: %s
+
+%s
+
+%s
+%s
+Keep every fact and its [n] citation exactly as numbered - the numbers refer to one shared source list. Merge overlapping points and remove repetition, but don't drop facts. Only link URLs that appear in the parts - never invent URLs. Don't add a references section; the bibliography is appended automatically.%s`, topic, brief, parts.String(), task, a.languageDirective())
+
+	callType := CallCompression
+	if final {
+		callType = CallReport
+	}
+	resp, err := a.chat(a.withCall(ctx, callType), a.writer, []llm.Message{
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stripThinkTags(resp)), nil
+}
+
+// groupByTokens splits texts, in order, into groups of at most maxTokens each
+// (a text larger than maxTokens gets a group of its own)
+func (a *DeepResearcher) groupByTokens(ctx context.Context, texts []string, maxTokens int) [][]string {
+	var groups [][]string
+	var group []string
+	size := 0
+	for _, t := range texts {
+		tokens := a.countTokens(ctx, t)
+		if len(group) > 0 && size+tokens > maxTokens {
+			groups = append(groups, group)
+			group, size = nil, 0
+		}
+		group = append(group, t)
+		size += tokens
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// sourcesInText numbers the sources whose URLs appear in text, like sourceList,
+// so a partial report can cite them by their position in the full list
+func sourcesInText(sources []Source, text string) string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for i, src := range sources {
+		title := strings.Join(strings.Fields(src.Title), " ")
+		if src.Document {
+			if !seen[src.URL] && strings.Contains(text, title+" (provided document)") {
+				seen[src.URL] = true
+				fmt.Fprintf(&b, "[%d] %s - provided document\n", i+1, title)
+			}
+			continue
+		}
+		if seen[src.URL] || !strings.Contains(text, src.URL) {
+			continue
+		}
+		seen[src.URL] = true
+		if title == "" {
+			title = src.URL
+		}
+		fmt.Fprintf(&b, "[%d] %s - %s\n", i+1, title, src.URL)
+	}
+	return b.String()
+}
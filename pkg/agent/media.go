@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/search"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxImages caps the images collected into a report's media appendix
+const maxImages = 24
+
+// Image is a picture found by an image search (Config.CollectImages)
+type Image struct {
+	URL     string // The image itself
+	PageURL string // The page showing it
+	Alt     string // Its title or alt text
+}
+
+// searchImages runs query once more in SearXNG's images category and collects
+// what it finds, unless the run already searches that category or has all the
+// images it keeps. ctx carries the run's search filters (searchContext).
+func (a *DeepResearcher) searchImages(ctx context.Context, query string) {
+	if !a.config.CollectImages || slices.Contains(a.config.Categories, search.CategoryImages) {
+		return
+	}
+	a.mu.Lock()
+	full := len(a.images) >= maxImages
+	a.mu.Unlock()
+	if full || a.spend(ctx, false) != nil {
+		return
+	}
+
+	filters := search.FiltersFromContext(ctx)
+	filters.Categories = []string{search.CategoryImages}
+	results, err := a.searcher.Search(search.WithFilters(ctx, filters), query)
+	if err != nil {
+		a.log.Debug("🖼️ Image search failed", "query", query, "error", err)
+		return
+	}
+	a.collectImages(results)
+}
+
+// collectImages keeps the image results among results (Config.CollectImages),
+// once per image and up to maxImages
+func (a *DeepResearcher) collectImages(results []search.Result) {
+	if !a.config.CollectImages {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, r := range results {
+		if len(a.images) >= maxImages {
+			return
+		}
+		if r.ImageURL == "" || !a.config.allowsURL(r.URL) || slices.ContainsFunc(a.images, func(img Image) bool { return img.URL == r.ImageURL }) {
+			continue
+		}
+		a.images = append(a.images, Image{URL: r.ImageURL, PageURL: r.URL, Alt: strings.TrimSpace(r.Title)})
+	}
+}
+
+// appendMedia adds a Media section listing the collected images, each linked
+// to the page it was found on
+func appendMedia(report string, images []Image) string {
+	if len(images) == 0 {
+		return report
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\n## Media\n\nImages found while researching the topic; check each page for its license before reusing one.\n\n")
+	for _, img := range images {
+		alt := strings.NewReplacer("[", "(", "]", ")", "\n", " ").Replace(img.Alt)
+		if alt == "" {
+			alt = "Image"
+		}
+		fmt.Fprintf(&sb, "- ![%s](%s) [%s](%s)\n", alt, img.URL, alt, img.PageURL)
+	}
+	return report + sb.String()
+}
@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"deep-research/pkg/llm"
+	"log/slog"
+)
+
+// roleProvider returns the client for one role (summarizer, writer): the main
+// client pointed at another model/server when one is configured, the main
+// client itself otherwise or when the provider can't switch models
+func roleProvider(l llm.Provider, log *slog.Logger, role, baseURL, model string) llm.Provider {
+	if baseURL == "" && model == "" {
+		return l
+	}
+	selector, ok := l.(llm.ModelSelector)
+	if !ok {
+		log.Warn("⚠️ LLM provider can't switch models, using the main model", "role", role)
+		return l
+	}
+	return selector.WithModel(baseURL, model)
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/search"
+	"fmt"
+	"sync"
+)
+
+// MaxNextPages caps Config.NextPages
+const MaxNextPages = 50
+
+// DefaultListingsPerQuery is Config.MaxListingsPerQuery's default
+const DefaultListingsPerQuery = 5
+
+// ValidateNextPages rejects a next-page limit out of range
+func ValidateNextPages(n int) error {
+	if n < 0 || n > MaxNextPages {
+		return fmt.Errorf("next pages must be between 0 and %d", MaxNextPages)
+	}
+	return nil
+}
+
+// listingsPerQuery is Config.MaxListingsPerQuery, or its default when unset
+func (c Config) listingsPerQuery() int {
+	if c.MaxListingsPerQuery <= 0 {
+		return DefaultListingsPerQuery
+	}
+	return c.MaxListingsPerQuery
+}
+
+// paginate collects up to maxItems item links from a listing's index page
+// (read as first) and up to Config.NextPages of its next pages (rel=next,
+// "next"/"›" links, or the following page= number). A next page already seen
+// in this run, as a search result of its own or from another listing, ends
+// the listing there; items seen before are skipped.
+func (a *DeepResearcher) paginate(ctx context.Context, crawler search.Crawler, start string, first search.PageLinks, maxItems int) []search.ListingLink {
+	var items []search.ListingLink
+	seen := make(map[string]bool)
+	page, read := start, 0
+	for page != "" && read <= a.config.NextPages && len(items) < maxItems && ctx.Err() == nil {
+		links := first
+		if page != start {
+			var err error
+			if links, err = a.crawlLinks(ctx, crawler, page, maxItems-len(items)); err != nil {
+				a.log.Debug("⚠️ Next page unreadable", "url", page, "error", err)
+				break
+			}
+		}
+		read++
+		for _, item := range links.Items {
+			key := normalizeURL(item.URL)
+			if seen[key] || a.wasSeen(key) || !a.config.allowsURL(item.URL) {
+				continue
+			}
+			seen[key] = true
+			items = append(items, item)
+		}
+		page = ""
+		if links.Next != "" && a.config.allowsURL(links.Next) && a.claimURL(links.Next) {
+			page = links.Next
+		}
+	}
+	if read > 1 {
+		a.log.Info("📃 Followed next pages", "url", start, "pages", read, "items", len(items))
+	}
+	return items[:min(len(items), maxItems)]
+}
+
+// listingResults reads the listings behind a query's results in deep mode
+// (Config.NextPages, Config.CrawlDepth): each result that is a listing's index
+// page, by its URL or because it has a next page, adds the item pages it leads
+// to (see listingItems), minus those seen before. The items are marked seen.
+func (a *DeepResearcher) listingResults(ctx context.Context, results []search.Result) []search.Result {
+	crawler, ok := a.searcher.(search.Crawler)
+	if !ok || (a.config.CrawlDepth == 0 && a.config.NextPages == 0) {
+		return nil
+	}
+	maxItems := a.config.listingItems(a.config.listingsPerQuery())
+	found := make([][]search.ListingLink, len(results))
+	var wg sync.WaitGroup
+	for i, r := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first, err := a.crawlLinks(ctx, crawler, r.URL, maxItems)
+			if err != nil || (first.Next == "" && !search.IsIndexPage(r.URL)) {
+				return
+			}
+			found[i] = a.followListing(ctx, crawler, r.URL, first, maxItems)
+		}()
+	}
+	wg.Wait()
+
+	var items []search.Result
+	for _, links := range found {
+		for _, link := range links {
+			if a.claimURL(link.URL) {
+				items = append(items, search.Result{Title: link.Title, URL: link.URL})
+			}
+		}
+	}
+	return items
+}
+
+// wasSeen reports whether a normalized URL was already seen in this run
+func (a *DeepResearcher) wasSeen(normalizedURL string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.seenURLs[normalizedURL]
+}
+
+// claimURL marks a URL as seen in this run; false if it already was
+func (a *DeepResearcher) claimURL(rawURL string) bool {
+	key := normalizeURL(rawURL)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.seenURLs[key] {
+		return false
+	}
+	a.seenURLs[key] = true
+	return true
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate holds the research's searches, page fetches, and LLM calls while
+// paused. The zero value is running.
+type pauseGate struct {
+	mu     sync.Mutex
+	resume chan struct{} // Closed by Resume; nil while running
+}
+
+// wait blocks while the gate is paused
+func (g *pauseGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause holds the research's next searches, page fetches, and LLM calls until
+// Resume (those in flight finish). Budgets and deadlines keep running.
+func (a *DeepResearcher) Pause() {
+	a.pause.mu.Lock()
+	defer a.pause.mu.Unlock()
+	if a.pause.resume == nil {
+		a.pause.resume = make(chan struct{})
+		a.log.Info("⏸️ Research paused")
+	}
+}
+
+// Resume lets paused research continue
+func (a *DeepResearcher) Resume() {
+	a.pause.mu.Lock()
+	defer a.pause.mu.Unlock()
+	if a.pause.resume != nil {
+		close(a.pause.resume)
+		a.pause.resume = nil
+		a.log.Info("▶️ Research resumed")
+	}
+}
+
+// Paused reports whether the research is paused
+func (a *DeepResearcher) Paused() bool {
+	a.pause.mu.Lock()
+	defer a.pause.mu.Unlock()
+	return a.pause.resume != nil
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/search"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFetchWorkers is how many pages deep mode fetches at once when Config.FetchWorkers is unset
+	DefaultFetchWorkers = 8
+	// DefaultSummarizeWorkers is how many page summaries and extractions run at once when Config.SummarizeWorkers is unset
+	DefaultSummarizeWorkers = 4
+)
+
+// WorkerLimit converts a limit where 0 means unlimited, like --fetch-concurrency,
+// to Config.FetchWorkers or Config.SummarizeWorkers
+func WorkerLimit(limit int) int {
+	if limit == 0 {
+		return -1
+	}
+	return limit
+}
+
+// workerPool bounds how many of one kind of task run at once across the whole
+// run, whichever query or seed URL they come from. A nil pool doesn't bound them.
+type workerPool chan struct{}
+
+// newWorkerPool returns a pool of size workers: fallback for 0, unbounded (nil) when negative
+func newWorkerPool(size, fallback int) workerPool {
+	switch {
+	case size < 0:
+		return nil
+	case size == 0:
+		size = fallback
+	}
+	return make(workerPool, size)
+}
+
+// acquire waits for a free worker; it fails only when ctx is done first
+func (p workerPool) acquire(ctx context.Context) error {
+	if p == nil {
+		return context.Cause(ctx)
+	}
+	select {
+	case p <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// release frees the worker taken by acquire
+func (p workerPool) release() {
+	if p != nil {
+		<-p
+	}
+}
+
+// pipelineBuffer is how many fetched pages may wait for the summarize stage
+// before the fetch stage waits too
+const pipelineBuffer = 16
+
+// deepPage is what deep mode got from one search result's page
+type deepPage struct {
+	url        string
+	title      string
+	content    string // "" when the fetch failed or the page had no readable text
+	summary    string
+	archiveURL string
+	fetchedAt  time.Time
+	published  time.Time // Zero when neither the page nor its URL has a date
+	duplicate  bool      // Near-duplicate of a page already kept (Config.DedupThreshold)
+	tooOld     bool      // Published before Config.MaxAgeDays
+}
+
+// pagePipeline is deep mode's summarize stage: the fetch stage sends it each
+// page as soon as it is read and goes on to the next, while its workers
+// summarize the pages (and extract their records), so page downloads (I/O
+// bound) overlap with LLM summaries (GPU bound) instead of alternating
+type pagePipeline struct {
+	pages chan *deepPage
+	wg    sync.WaitGroup
+}
+
+// summarizeStage starts a pipeline's summarize stage, with as many workers as
+// Config.SummarizeWorkers allows; progress, when set, is called as each page's
+// summary starts
+func (a *DeepResearcher) summarizeStage(ctx context.Context, progress func(url string)) *pagePipeline {
+	p := &pagePipeline{pages: make(chan *deepPage, pipelineBuffer)}
+	workers := cap(a.summarizePool)
+	if workers == 0 {
+		workers = pipelineBuffer // Unbounded pool
+	}
+	for range workers {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for page := range p.pages {
+				if progress != nil {
+					progress(page.url)
+				}
+				a.log.Debug("🧠 [DEEP] Summarizing page", "url", page.url, "chars", len(page.content))
+				page.summary = a.summarizePage(ctx, page.url, page.title, page.content)
+				a.collectRecord(ctx, page.url, page.title, page.content)
+			}
+		}()
+	}
+	return p
+}
+
+// send hands a fetched page to the summarize stage, which fills in its summary
+func (p *pagePipeline) send(page *deepPage) {
+	p.pages <- page
+}
+
+// wait closes the pipeline and waits until every page sent is summarized
+func (p *pagePipeline) wait() {
+	close(p.pages)
+	p.wg.Wait()
+}
+
+// readPages fetches and deduplicates the pages of one result page's new results
+// concurrently, as far as the fetch workers allow, and summarizes them in a
+// summarize stage meanwhile; pages[i] is results[i]'s
+func (a *DeepResearcher) readPages(ctx context.Context, fetcher search.ContentFetcher, qi int, query string, page int, results []search.Result) []deepPage {
+	pages := make([]deepPage, len(results))
+	summarizer := a.summarizeStage(ctx, func(url string) {
+		a.emitSearchProgress(StepSummarize, qi, query, page, url)
+	})
+	var wg sync.WaitGroup
+	for i, r := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.emitSearchProgress(StepFetch, qi, query, page, r.URL)
+			content, archiveURL, published, err := a.fetchPage(ctx, fetcher, r.URL)
+			if errors.Is(err, errTooOld) {
+				pages[i].tooOld = true
+				return
+			}
+			if err != nil || len(content) <= 50 {
+				return
+			}
+			if a.isNearDuplicate(ctx, r.URL, content) {
+				pages[i].duplicate = true
+				return
+			}
+			pages[i] = deepPage{url: r.URL, title: r.Title, content: content, archiveURL: archiveURL, fetchedAt: time.Now(), published: published}
+			summarizer.send(&pages[i])
+		}()
+	}
+	wg.Wait()
+	summarizer.wait()
+	return pages
+}
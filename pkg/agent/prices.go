@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"cmp"
+	"deep-research/pkg/price"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// priceFieldWords are the (lowercased) words of record fields that hold a price
+var priceFieldWords = []string{"price", "cost", "rent", "fee", "pret", "preț", "preis", "prix", "precio"}
+
+// ValidatePriceCurrency rejects a target currency the rate table has no rate for
+func ValidatePriceCurrency(currency string, rates price.Rates) error {
+	if currency == "" {
+		return nil
+	}
+	if rates == nil {
+		rates = price.DefaultRates()
+	}
+	if !rates.Has(currency) {
+		return fmt.Errorf("no exchange rate for %s", strings.ToUpper(currency))
+	}
+	return nil
+}
+
+// priceField is the record field normalized prices are stored in, e.g.
+// price_eur ("" without Config.PriceCurrency or an extraction schema)
+func (c Config) priceField() string {
+	if c.PriceCurrency == "" || len(c.extractionFields()) == 0 {
+		return ""
+	}
+	return "price_" + strings.ToLower(c.PriceCurrency)
+}
+
+// recordFields are the records' fields in order: the extraction schema's, then
+// the normalized price and the distance from Config.GeoArea
+func (c Config) recordFields() []string {
+	fields := c.extractionFields()
+	if f := c.priceField(); f != "" {
+		fields = append(slices.Clip(fields), f)
+	}
+	if c.GeoArea != "" && len(fields) > 0 {
+		fields = append(slices.Clip(fields), distanceField)
+	}
+	return fields
+}
+
+// normalizePrices converts the prices found for the sources and records to
+// Config.PriceCurrency. A record's price comes from its price field (a number
+// takes its currency from a currency field or the page's text); a source's
+// from its record, else its summary, else its snippet. Each source gets the
+// converted Price, each record a priceField value, and the records are sorted
+// by it, cheapest first (those without a price last).
+func (a *DeepResearcher) normalizePrices(sources []Source, records []map[string]any) {
+	if a.config.PriceCurrency == "" {
+		return
+	}
+	rates := a.config.PriceRates
+	if rates == nil {
+		rates = price.DefaultRates()
+	}
+	target := strings.ToUpper(a.config.PriceCurrency)
+
+	// The currency written on each page, for records holding a bare number
+	pageCurrency := make(map[string]string)
+	for _, src := range sources {
+		if amount, ok := sourcePrice(src); ok {
+			pageCurrency[normalizeURL(src.URL)] = amount.Currency
+		}
+	}
+
+	field := a.config.priceField()
+	byURL := make(map[string]price.Amount)
+	for u, rec := range recordsByURL(records) {
+		amount, ok := recordPrice(rec, field, pageCurrency[u])
+		if !ok {
+			continue
+		}
+		converted, ok := rates.Convert(amount, target)
+		if !ok {
+			continue
+		}
+		if field != "" {
+			rec[field] = converted.Value
+		}
+		byURL[u] = converted
+	}
+
+	found := 0
+	for i, src := range sources {
+		if src.Document {
+			continue
+		}
+		converted, ok := byURL[normalizeURL(src.URL)]
+		if !ok {
+			amount, ok := sourcePrice(src)
+			if !ok {
+				continue
+			}
+			if converted, ok = rates.Convert(amount, target); !ok {
+				continue
+			}
+		}
+		sources[i].Price = &converted
+		found++
+	}
+
+	if field != "" {
+		slices.SortStableFunc(records, func(x, y map[string]any) int {
+			px, okx := x[field].(float64)
+			py, oky := y[field].(float64)
+			switch {
+			case okx && oky:
+				return cmp.Compare(px, py)
+			case okx:
+				return -1
+			case oky:
+				return 1
+			}
+			return 0
+		})
+	}
+	if found > 0 {
+		a.log.Info("💱 Normalized prices", "sources", found, "currency", target)
+	}
+}
+
+// priceNote tells the writer the source list's prices share a currency ("" when
+// no source has one)
+func priceNote(sources []Source) string {
+	for _, src := range sources {
+		if src.Price != nil {
+			return fmt.Sprintf("\n\n--- NOTE: The prices in the source list are converted to %s. Compare, rank, and filter listings by them, and quote prices in %s. ---\n", src.Price.Currency, src.Price.Currency)
+		}
+	}
+	return ""
+}
+
+// sourcePrice finds the price in a source's summary, else its snippet
+func sourcePrice(src Source) (price.Amount, bool) {
+	if amount, ok := price.Parse(src.Summary); ok {
+		return amount, true
+	}
+	return price.Parse(src.Snippet)
+}
+
+// recordPrice reads a record's price: the first price field (other than the
+// normalized one) holding a price with its currency, or a number in the
+// currency of the record's currency field, else pageCurrency
+func recordPrice(rec map[string]any, normalized, pageCurrency string) (price.Amount, bool) {
+	currency := pageCurrency
+	for k, v := range rec {
+		if s, ok := v.(string); ok && strings.Contains(strings.ToLower(k), "currency") {
+			if amount, ok := price.Parse("1 " + s); ok {
+				currency = amount.Currency
+			}
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(rec)) {
+		if k == normalized || !isPriceField(k) {
+			continue
+		}
+		switch v := rec[k].(type) {
+		case string:
+			if amount, ok := price.Parse(v); ok {
+				return amount, true
+			}
+			if n, ok := parseBareNumber(v); ok && currency != "" {
+				return price.Amount{Value: n, Currency: currency}, true
+			}
+		case float64:
+			if v > 0 && currency != "" {
+				return price.Amount{Value: v, Currency: currency}, true
+			}
+		}
+	}
+	return price.Amount{}, false
+}
+
+// isPriceField reports whether a record field holds a price: one of its words
+// (price, monthly_rent, pricePerNight) starts with a word of priceFieldWords
+func isPriceField(field string) bool {
+	return hasFieldWord(field, priceFieldWords)
+}
+
+// hasFieldWord reports whether one of a record field's words starts with one of prefixes
+func hasFieldWord(field string, prefixes []string) bool {
+	words := strings.FieldsFunc(strings.ToLower(field), func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, w := range words {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(w, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseBareNumber reads a price written without a currency, e.g. "1.250"
+func parseBareNumber(s string) (float64, bool) {
+	amount, ok := price.Parse(strings.TrimSpace(s) + " EUR")
+	return amount.Value, ok
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+// Progress event steps while searching (ProgressEvent.Step)
+const (
+	StepSearch    = "search"     // Requesting a page of search results
+	StepFetch     = "fetch"      // Fetching a result's page (deep mode)
+	StepSummarize = "summarize"  // Summarizing a fetched page (deep mode)
+	StepQueryDone = "query_done" // A query's searches (and deep-mode pages) are done; ProgressEvent.Stat has its yield
+)
+
+// Progress bar shares (ProgressEvent.Percent): planning runs up to
+// percentSearch, the search phase up to percentReport, and compressing the
+// findings and writing the report take the rest
+const (
+	percentSearch = 5
+	percentReport = 85
+)
+
+// guessNewResultsPerPage is how many results of a result page are guessed to
+// be new (fetched and summarized in deep mode) before the run's first query
+// shows the real yield
+const guessNewResultsPerPage = 5
+
+// searchProgress is where the running research's search phase stands, so the
+// events sent for each result page and fetched page can say how far along it is.
+// When the queries are planned up front, the percent comes from the work done:
+// the queries finished, plus the current one's share estimated from the steps
+// (result pages, fetches, summaries) the earlier queries took.
+type searchProgress struct {
+	round          int
+	totalRounds    int
+	totalQueries   int       // Queries planned for the run (0 = not known up front, e.g. simple mode)
+	plannedQueries int       // Queries the run's rounds can reach (at most totalQueries)
+	targetURLs     int       // Sources that end the search early (0 = none)
+	queriesDone    int       // Queries searched before the current batch
+	startDone      int       // queriesDone when the run (re)started, so resumed queries don't skew the ETA
+	batchSize      int       // Queries in the current batch
+	duplicates     int       // Results skipped as already seen or near-duplicate
+	started        time.Time // When the run's search phase (re)started
+	stepsGuess     float64   // Steps per query assumed until a query has finished
+	steps          int       // Steps taken since the run (re)started
+	current        int       // Index among the run's queries of the one the last step was for
+	finished       int       // Queries finished since the run (re)started
+	finishedSteps  int       // Steps those queries took
+	percent        int       // Last percent sent; the bar never moves back
+}
+
+// resetSearchProgress starts tracking a run's search phase from p (its queries,
+// rounds, target, and the queries done and duplicates carried over from a checkpoint)
+func (a *DeepResearcher) resetSearchProgress(p searchProgress) {
+	pages := a.config.MaxPages
+	if pages <= 0 {
+		pages = 3 // Auto pagination usually runs dry within a few pages
+	}
+	p.stepsGuess = float64(pages)
+	if a.config.DeepMode {
+		p.stepsGuess *= 1 + 2*guessNewResultsPerPage // Each new result is fetched and summarized
+	}
+	p.plannedQueries = min(p.plannedQueries, p.totalQueries)
+	p.startDone = p.queriesDone
+	p.current = p.queriesDone
+	p.started = time.Now()
+	p.percent = percentSearch
+
+	a.mu.Lock()
+	a.searchProgress = p
+	a.mu.Unlock()
+}
+
+// startSearchBatch records the batch of queries about to be searched: its round
+// and how many of the run's queries came before it
+func (a *DeepResearcher) startSearchBatch(round, queriesDone, batchSize int) {
+	a.mu.Lock()
+	a.searchProgress.round = round
+	a.searchProgress.queriesDone = queriesDone
+	a.searchProgress.batchSize = batchSize
+	a.mu.Unlock()
+}
+
+// searchPercent is the search phase's progress so far, for events between steps
+func (a *DeepResearcher) searchPercent() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.searchProgress.estimate(0, len(a.sources))
+}
+
+// step counts a step of the current batch's query at index i and returns the
+// progress percent. Queries are searched one after another when they're
+// planned up front, so a step of a later query means the earlier ones are done.
+func (p *searchProgress) step(i, urls int) int {
+	if done := p.queriesDone + i; p.totalQueries > 0 && done > p.current {
+		p.finished += done - p.current
+		p.finishedSteps = p.steps
+		p.current = done
+	}
+	p.steps++
+	return p.estimate(i, urls)
+}
+
+// estimate is the search phase's percent: the share of the planned queries
+// done (or of the sources that end the search, when further along), or without
+// a plan, the rounds done and the current batch's query i
+func (p *searchProgress) estimate(i, urls int) int {
+	var fraction float64
+	if planned := min(p.plannedQueries, p.totalQueries); planned > 0 {
+		perQuery := p.stepsGuess
+		if p.finished > 0 {
+			perQuery = float64(p.finishedSteps) / float64(p.finished)
+		}
+		partial := 0.0
+		if perQuery > 0 {
+			partial = min(float64(p.steps-p.finishedSteps)/perQuery, 0.95) // A query isn't done until the next starts
+		}
+		fraction = (float64(p.current) + partial) / float64(planned)
+		if p.targetURLs > 0 {
+			fraction = max(fraction, float64(urls)/float64(p.targetURLs))
+		}
+	} else if p.totalRounds > 0 {
+		fraction = float64(max(p.round-1, 0)) / float64(p.totalRounds)
+		if p.batchSize > 0 {
+			fraction += float64(i) / float64(p.batchSize*p.totalRounds)
+		}
+	}
+	percent := percentSearch + int(min(fraction, 1)*(percentReport-percentSearch))
+	p.percent = max(p.percent, percent)
+	return p.percent
+}
+
+// countDuplicate adds a skipped duplicate result to the run's progress
+func (a *DeepResearcher) countDuplicate() {
+	a.mu.Lock()
+	a.searchProgress.duplicates++
+	a.mu.Unlock()
+}
+
+// emitSearchProgress sends a detailed "searching" event for one step (StepSearch,
+// StepFetch, or StepSummarize) of the current batch's query at index i. page is
+// the result page being requested, pageURL the page being fetched or summarized.
+func (a *DeepResearcher) emitSearchProgress(step string, i int, query string, page int, pageURL string) {
+	if a.config.OnProgress == nil {
+		return
+	}
+
+	a.mu.Lock()
+	urls := len(a.sources)
+	percent := a.searchProgress.step(i, urls)
+	p := a.searchProgress
+	a.mu.Unlock()
+
+	done := p.queriesDone + i
+	event := ProgressEvent{
+		Phase:        "searching",
+		Step:         step,
+		Round:        p.round,
+		TotalRounds:  p.totalRounds,
+		URLsFound:    urls,
+		TargetURLs:   a.config.MinResults,
+		Query:        query,
+		QueryIndex:   done + 1,
+		TotalQueries: p.totalQueries,
+		Page:         page,
+		URL:          pageURL,
+		Duplicates:   p.duplicates,
+		Percent:      percent,
+	}
+	if p.totalQueries > 0 {
+		event.RemainingQueries = max(p.totalQueries-done, 0)
+		if searched := done - p.startDone; searched > 0 {
+			perQuery := time.Since(p.started).Seconds() / float64(searched)
+			event.ETASeconds = int(perQuery * float64(event.RemainingQueries))
+		}
+	}
+
+	switch step {
+	case StepSearch:
+		event.Message = fmt.Sprintf("Searching %q (page %d)", truncateQuery(query, 60), page)
+	case StepFetch:
+		event.Message = fmt.Sprintf("Fetching %s", pageURL)
+	case StepSummarize:
+		event.Message = fmt.Sprintf("Summarizing %s", pageURL)
+	}
+	if p.totalQueries > 0 {
+		event.Message = fmt.Sprintf("Query %d/%d: %s", event.QueryIndex, p.totalQueries, event.Message)
+	}
+	a.emitProgress(event)
+}
+
+// emitQueryDone sends a StepQueryDone event for the current batch's query at
+// index i with what it yielded
+func (a *DeepResearcher) emitQueryDone(i int, stat QueryStat) {
+	if a.config.OnProgress == nil {
+		return
+	}
+
+	a.mu.Lock()
+	urls := len(a.sources)
+	p := a.searchProgress
+	percent := a.searchProgress.estimate(0, urls)
+	a.mu.Unlock()
+
+	event := ProgressEvent{
+		Phase:        "searching",
+		Step:         StepQueryDone,
+		Round:        p.round,
+		TotalRounds:  p.totalRounds,
+		URLsFound:    urls,
+		TargetURLs:   a.config.MinResults,
+		Query:        stat.Query,
+		QueryIndex:   p.queriesDone + i + 1,
+		TotalQueries: p.totalQueries,
+		Duplicates:   p.duplicates,
+		Percent:      percent,
+		Stat:         &stat,
+		Message:      fmt.Sprintf("Searched %q: %d new sources of %d results", truncateQuery(stat.Query, 60), stat.NewURLs, stat.Results),
+	}
+	if stat.Errors > 0 {
+		event.Message += fmt.Sprintf(", %d failed searches", stat.Errors)
+	}
+	if p.totalQueries > 0 {
+		event.Message = fmt.Sprintf("Query %d/%d: %s", event.QueryIndex, p.totalQueries, event.Message)
+	}
+	a.emitProgress(event)
+}
@@ -0,0 +1,20 @@
+package agent
+
+import "strings"
+
+// CleanQueries tidies a user-edited search query list: whitespace is trimmed,
+// blank entries are dropped, and repeated queries (compared case-insensitively)
+// keep only their first occurrence
+func CleanQueries(queries []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, q := range queries {
+		q = strings.Join(strings.Fields(q), " ")
+		if q == "" || seen[strings.ToLower(q)] {
+			continue
+		}
+		seen[strings.ToLower(q)] = true
+		out = append(out, q)
+	}
+	return out
+}
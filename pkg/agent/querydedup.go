@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultQueryDedupThreshold is the cosine similarity used when planned-query
+// deduplication is enabled without an explicit threshold
+const DefaultQueryDedupThreshold = 0.92
+
+// dedupQueries drops planned queries that mean the same as an earlier one
+// ("apartamente de vanzare" vs "apartamente vanzare"), keeping the first query of
+// each cluster of similar ones. Only queries with the same search operators
+// (site:, filetype:, ...) are compared, so site variants are never merged. The
+// queries are returned unchanged when QueryDedup is 0 or embeddings are unavailable.
+func (a *DeepResearcher) dedupQueries(ctx context.Context, queries []string) []string {
+	if a.config.QueryDedup <= 0 || len(queries) < 2 {
+		return queries
+	}
+	embedder, ok := a.llmClient.(llm.Embedder)
+	a.mu.Lock()
+	disabled := a.embeddingsDisabled
+	a.mu.Unlock()
+	if !ok || disabled {
+		return queries
+	}
+
+	vectors := make([][]float64, 0, len(queries))
+	for start := 0; start < len(queries); start += embeddingBatchSize {
+		batch := queries[start:min(start+embeddingBatchSize, len(queries))]
+		embedded, err := embedder.Embeddings(ctx, batch)
+		if err == nil && len(embedded) != len(batch) {
+			err = fmt.Errorf("got %d embeddings for %d inputs", len(embedded), len(batch))
+		}
+		if err != nil {
+			a.log.Warn("⚠️ Embeddings unavailable, keeping all planned queries", "error", err)
+			a.mu.Lock()
+			a.embeddingsDisabled = true
+			a.mu.Unlock()
+			return queries
+		}
+		vectors = append(vectors, embedded...)
+	}
+
+	var kept []string
+	keptVectors := make(map[string][][]float64) // By the queries' search operators
+	for i, q := range queries {
+		ops := queryOperators(q)
+		duplicate := false
+		for _, v := range keptVectors[ops] {
+			if llm.CosineSimilarity(vectors[i], v) >= a.config.QueryDedup {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			a.log.Debug("♊ Similar query, skipping", "query", q)
+			continue
+		}
+		kept = append(kept, q)
+		keptVectors[ops] = append(keptVectors[ops], vectors[i])
+	}
+
+	if dropped := len(queries) - len(kept); dropped > 0 {
+		a.log.Info("♊ Merged similar queries", "kept", len(kept), "dropped", dropped)
+	}
+	return kept
+}
+
+// queryOperators returns a query's search operators (words like "site:olx.ro"),
+// sorted and lowercased, as a key for queries that target the same results
+func queryOperators(query string) string {
+	var ops []string
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if name, value, ok := strings.Cut(word, ":"); ok && name != "" && value != "" {
+			ops = append(ops, word)
+		}
+	}
+	sort.Strings(ops)
+	return strings.Join(ops, " ")
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryStat is what one search query yielded over a run. A query searched in
+// several rounds is listed once, with its numbers added up.
+type QueryStat struct {
+	Query      string
+	Results    int // Results the engines returned across all pages (before domain filters)
+	NewURLs    int // Results kept as new sources
+	Duplicates int // Results whose URL was already seen, or near-duplicate pages (exhaustive mode)
+	Pages      int // Result pages requested
+	Errors     int // Failed searches
+	Irrelevant int // Results dropped as unrelated to the topic (Config.RelevanceFilter)
+}
+
+// recordQueries adds a search pass's per-query numbers to the run's stats
+func (a *DeepResearcher) recordQueries(stats ...QueryStat) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, s := range stats {
+		found := false
+		for i := range a.queryStats {
+			if a.queryStats[i].Query == s.Query {
+				a.queryStats[i].Results += s.Results
+				a.queryStats[i].NewURLs += s.NewURLs
+				a.queryStats[i].Duplicates += s.Duplicates
+				a.queryStats[i].Pages += s.Pages
+				a.queryStats[i].Errors += s.Errors
+				a.queryStats[i].Irrelevant += s.Irrelevant
+				found = true
+				break
+			}
+		}
+		if !found {
+			a.queryStats = append(a.queryStats, s)
+		}
+	}
+}
+
+// QueryStats returns the per-query yield of the running (or last) research so
+// far; a round's queries are added when the round's searches finish
+func (a *DeepResearcher) QueryStats() []QueryStat {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]QueryStat(nil), a.queryStats...)
+}
+
+// RenderQueryStatsTable renders per-query yield as a markdown table, in the order the queries ran
+func RenderQueryStatsTable(stats []QueryStat) string {
+	if len(stats) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| Query | Results | New URLs | Duplicates | Irrelevant | Pages | Errors |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|\n")
+	var total QueryStat
+	for _, s := range stats {
+		fmt.Fprintf(&sb, "| %s | %d | %d | %d | %d | %d | %d |\n", formatRecordValue("", s.Query), s.Results, s.NewURLs, s.Duplicates, s.Irrelevant, s.Pages, s.Errors)
+		total.Results += s.Results
+		total.NewURLs += s.NewURLs
+		total.Duplicates += s.Duplicates
+		total.Irrelevant += s.Irrelevant
+		total.Pages += s.Pages
+		total.Errors += s.Errors
+	}
+	fmt.Fprintf(&sb, "| **Total** | %d | %d | %d | %d | %d | %d |\n", total.Results, total.NewURLs, total.Duplicates, total.Irrelevant, total.Pages, total.Errors)
+	return sb.String()
+}
+
+// appendQueryStats adds the per-query yield appendix to the report when queries were run
+func appendQueryStats(report string, stats []QueryStat) string {
+	table := RenderQueryStatsTable(stats)
+	if table == "" {
+		return report
+	}
+	return report + fmt.Sprintf("\n\n## Appendix: Query Yield (%d queries)\n\n%s", len(stats), table)
+}
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"deep-research/pkg/search"
+	"errors"
+	"time"
+)
+
+// errTooOld is fetchPage's error for a page published before Config.MaxAgeDays
+var errTooOld = errors.New("published before the maximum age")
+
+// tooOld reports whether a source published then is older than MaxAgeDays allows.
+// Undated sources (zero) are never too old.
+func (c Config) tooOld(published time.Time) bool {
+	if c.MaxAgeDays <= 0 || published.IsZero() {
+		return false
+	}
+	return published.Before(time.Now().AddDate(0, 0, -c.MaxAgeDays))
+}
+
+// resultPublished is a search result's publication date as far as it's known
+// before fetching the page: the engine's, else a date in its URL
+func resultPublished(r search.Result) time.Time {
+	if !r.Published.IsZero() {
+		return r.Published
+	}
+	return search.ExtractPublished(r.URL, "")
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/search"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Relevance filters, the values of Config.RelevanceFilter
+const (
+	RelevanceKeywords = "keywords" // Drop results sharing no word with the query or the topic
+	RelevanceLLM      = "llm"      // Also ask the summarizer model which of the remaining results are relevant
+)
+
+// RelevanceFilters are the accepted Config.RelevanceFilter values besides "" (off)
+var RelevanceFilters = []string{RelevanceKeywords, RelevanceLLM}
+
+// ValidateRelevanceFilter rejects unknown relevance filters
+func ValidateRelevanceFilter(filter string) error {
+	if filter != "" && !slices.Contains(RelevanceFilters, filter) {
+		return fmt.Errorf("unknown relevance filter %q (use %s)", filter, strings.Join(RelevanceFilters, ", "))
+	}
+	return nil
+}
+
+// relevanceBatch caps the results classified in one LLM call
+const relevanceBatch = 20
+
+// relevanceResponse is what the model returns for a batch of results
+type relevanceResponse struct {
+	Relevant []int `json:"relevant"`
+}
+
+// relevantResults drops the results that are obviously unrelated to the run's
+// topic (Config.RelevanceFilter) before their snippets reach the context: by
+// keyword overlap, then, with RelevanceLLM, by asking the summarizer. A failed
+// classification keeps the results.
+func (a *DeepResearcher) relevantResults(ctx context.Context, query string, results []search.Result) []search.Result {
+	if a.config.RelevanceFilter == "" || len(results) == 0 {
+		return results
+	}
+	a.mu.Lock()
+	topic := a.progress.topic
+	a.mu.Unlock()
+
+	stems := relevanceStems(query + " " + topic)
+	kept := results
+	if len(stems) > 0 {
+		kept = make([]search.Result, 0, len(results))
+		for _, r := range results {
+			if sharesStem(r.Title+" "+r.Content+" "+r.URL, stems) {
+				kept = append(kept, r)
+			}
+		}
+	}
+
+	if a.config.RelevanceFilter == RelevanceLLM && len(kept) > 0 {
+		var classified []search.Result
+		for batch := range slices.Chunk(kept, relevanceBatch) {
+			relevant, err := a.classifyResults(ctx, topic, query, batch)
+			if err != nil {
+				a.log.Debug("🧹 Relevance classification failed; keeping the results", "query", query, "error", err)
+				relevant = batch
+			}
+			classified = append(classified, relevant...)
+		}
+		kept = classified
+	}
+
+	if dropped := len(results) - len(kept); dropped > 0 {
+		a.log.Debug("🧹 Dropped irrelevant results", "query", truncateQuery(query, 40), "dropped", dropped, "kept", len(kept))
+	}
+	return kept
+}
+
+// classifyResults asks the summarizer which of results could help research topic
+func (a *DeepResearcher) classifyResults(ctx context.Context, topic, query string, results []search.Result) ([]search.Result, error) {
+	var list strings.Builder
+	for i, r := range results {
+		snippet := []rune(strings.Join(strings.Fields(r.Content), " "))
+		fmt.Fprintf(&list, "%d. %s\n   %s\n   %s\n", i+1, r.Title, r.URL, string(snippet[:min(len(snippet), 300)]))
+	}
+
+	prompt := fmt.Sprintf(`Research topic: %s
+Search query: %s
+
+Which of these search results could be relevant to the research topic? Judge by the title, URL, and snippet. Leave out results that are clearly about something else (a different product, place, or meaning of a word, spam, or unrelated listings); when unsure, include the result.
+
+%s
+Respond ONLY with valid JSON listing the numbers of the relevant results:
+{"relevant": [1, 3]}`, topic, query, list.String())
+
+	var resp relevanceResponse
+	err := a.chatJSON(a.withCall(ctx, CallSummarization), a.summarizer, []llm.Message{
+		{Role: "system", Content: "You screen search results for a researcher. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, relevanceSchema, &resp)
+	if err != nil {
+		return nil, jsonError("relevance", err)
+	}
+
+	relevant := make(map[int]bool, len(resp.Relevant))
+	for _, n := range resp.Relevant {
+		relevant[n] = true
+	}
+	var kept []search.Result
+	for i, r := range results {
+		if relevant[i+1] {
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// relevanceStems are the first letters of text's words long enough to carry
+// meaning, so "apartament" and "apartamente" match. Search operators such as
+// site: and -term are skipped.
+func relevanceStems(text string) []string {
+	var words []string
+	for _, field := range strings.Fields(text) {
+		if strings.Contains(field, ":") || strings.HasPrefix(field, "-") {
+			continue
+		}
+		words = append(words, field)
+	}
+	var stems []string
+	for word := range termCounts(strings.Join(words, " ")) {
+		if len([]rune(word)) >= 4 {
+			stems = append(stems, stem(word))
+		}
+	}
+	slices.Sort(stems)
+	return slices.Compact(stems)
+}
+
+// sharesStem reports whether any of text's words starts like one of stems
+func sharesStem(text string, stems []string) bool {
+	for word := range termCounts(text) {
+		if len([]rune(word)) >= 4 && slices.Contains(stems, stem(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stem is a word's first five letters
+func stem(word string) string {
+	runes := []rune(word)
+	return string(runes[:min(len(runes), 5)])
+}
@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ReportData is what a Config.ReportTemplate is executed with. Besides these
+// fields the template can call:
+//
+//	{{section "Heading" "what it covers"}}  the body of the report's section with that heading
+//	{{rest}}                                 the sections not inserted yet, with their "## " headings
+//
+// The sections a template inserts with section make up the report's outline,
+// in the template's order, instead of the ones the writer would pick.
+type ReportData struct {
+	Topic    string
+	Title    string          // The report's title (its "# " heading, else the topic)
+	Date     time.Time       // When the report was written
+	Body     string          // The report's Markdown below the title
+	Sections []ReportSection // The report's "## " sections, appendices included
+	Sources  []ReportSource  // The bibliography, numbered as the report cites them
+}
+
+// ReportSection is a "## " section of the report
+type ReportSection struct {
+	Heading string
+	Body    string // The section's Markdown below its heading
+}
+
+// ReportSource is a bibliography entry of ReportData
+type ReportSource struct {
+	Number int // What the report cites the source as, e.g. [3]
+	Source
+}
+
+// reportTemplate is a parsed Config.ReportTemplate
+type reportTemplate struct {
+	tmpl     *template.Template
+	sections []outlineSection // Inserted with section, in template order
+}
+
+// ValidateReportTemplate rejects a report template that doesn't parse
+func ValidateReportTemplate(text string) error {
+	_, err := parseReportTemplate(text)
+	return err
+}
+
+// parseReportTemplate parses text and, by executing it once without data,
+// collects the sections it inserts
+func parseReportTemplate(text string) (*reportTemplate, error) {
+	var sections []outlineSection
+	collect := template.FuncMap{
+		"section": func(heading string, focus ...string) string {
+			for _, s := range sections {
+				if strings.EqualFold(s.Heading, strings.TrimSpace(heading)) {
+					return ""
+				}
+			}
+			sections = append(sections, outlineSection{Heading: strings.TrimSpace(heading), Focus: strings.Join(focus, " ")})
+			return ""
+		},
+		"rest": func() string { return "" },
+	}
+	tmpl, err := template.New("report").Funcs(collect).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report template: %w", err)
+	}
+	tmpl.Execute(io.Discard, ReportData{}) // Templates indexing into the data fail here; the sections before count
+	return &reportTemplate{tmpl: tmpl, sections: sections}, nil
+}
+
+// render executes the template for the report
+func (t *reportTemplate) render(data ReportData) (string, error) {
+	used := make([]bool, len(data.Sections))
+	find := func(heading string) int {
+		heading = strings.TrimSpace(heading)
+		for i, s := range data.Sections {
+			if strings.EqualFold(s.Heading, heading) {
+				return i
+			}
+		}
+		// The writer may have numbered or extended the heading
+		for i, s := range data.Sections {
+			if !used[i] && strings.Contains(strings.ToLower(s.Heading), strings.ToLower(heading)) {
+				return i
+			}
+		}
+		return -1
+	}
+	funcs := template.FuncMap{
+		"section": func(heading string, _ ...string) string {
+			i := find(heading)
+			if i < 0 {
+				return ""
+			}
+			used[i] = true
+			return data.Sections[i].Body
+		},
+		"rest": func() string {
+			var parts []string
+			for i, s := range data.Sections {
+				if !used[i] {
+					used[i] = true
+					parts = append(parts, "## "+s.Heading+"\n\n"+s.Body)
+				}
+			}
+			return strings.Join(parts, "\n\n")
+		},
+	}
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Funcs(funcs).Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()) + "\n", nil
+}
+
+// templateGuidance asks the writer for the template's sections ("" without any)
+func (a *DeepResearcher) templateGuidance() string {
+	if a.template == nil || len(a.template.sections) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nUse exactly these sections, in this order, with these headings:")
+	for _, s := range a.template.sections {
+		sb.WriteString("\n- " + s.Heading)
+		if s.Focus != "" {
+			sb.WriteString(": " + s.Focus)
+		}
+	}
+	return sb.String()
+}
+
+// templateOutline replaces the sections of the writer's outline with the
+// template's, keeping the sources the writer assigned to a matching heading
+func (a *DeepResearcher) templateOutline(outline reportOutline) reportOutline {
+	if a.template == nil || len(a.template.sections) == 0 {
+		return outline
+	}
+	sections := make([]outlineSection, len(a.template.sections))
+	for i, s := range a.template.sections {
+		sections[i] = s
+		for _, planned := range outline.Sections {
+			if strings.EqualFold(planned.Heading, s.Heading) {
+				sections[i].Sources = planned.Sources
+				if s.Focus == "" {
+					sections[i].Focus = planned.Focus
+				}
+			}
+		}
+		if sections[i].Focus == "" {
+			sections[i].Focus = s.Heading
+		}
+	}
+	outline.Sections = sections
+	return outline
+}
+
+// applyTemplate renders the finished report with Config.ReportTemplate. The
+// report is returned as is, and templated is false, without a template or
+// when it fails to render.
+func (a *DeepResearcher) applyTemplate(topic, report string, sources []Source) (result string, templated bool) {
+	if a.template == nil {
+		return report, false
+	}
+	data := ReportData{Topic: topic, Title: topic, Date: time.Now()}
+
+	// Split the report into its title and "## " sections (outside code blocks)
+	var body, current []string
+	heading, inCode, started := "", false, false
+	flush := func() {
+		if heading != "" {
+			data.Sections = append(data.Sections, ReportSection{Heading: heading, Body: strings.TrimSpace(strings.Join(current, "\n"))})
+		}
+		current = nil
+	}
+	for _, line := range strings.Split(report, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+		}
+		first := !started && trimmed != ""
+		started = started || first
+		switch {
+		case first && strings.HasPrefix(trimmed, "# "):
+			data.Title = strings.TrimSpace(strings.TrimPrefix(trimmed, "# "))
+			continue
+		case !inCode && strings.HasPrefix(trimmed, "## "):
+			flush()
+			heading = strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))
+		default:
+			current = append(current, line)
+		}
+		body = append(body, line)
+	}
+	flush()
+	data.Body = strings.TrimSpace(strings.Join(body, "\n"))
+
+	seen := make(map[string]bool)
+	for i, s := range sources {
+		if !seen[s.URL] {
+			seen[s.URL] = true
+			data.Sources = append(data.Sources, ReportSource{Number: i + 1, Source: s})
+		}
+	}
+
+	rendered, err := a.template.render(data)
+	if err != nil {
+		a.log.Warn("⚠️ Report template failed, keeping the default report", "error", err)
+		return report, false
+	}
+	a.log.Info("📐 Report rendered with the template", "sections", len(data.Sections), "sources", len(data.Sources))
+	return rendered, true
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// deadEndQueries is how many searched queries of a pattern must have found no
+// new URL before the pattern counts as a dead end
+const deadEndQueries = 2
+
+// patternYield is what the searched queries sharing a pattern found
+type patternYield struct {
+	queries int
+	pages   int
+	newURLs int
+}
+
+// rate is the pattern's new URLs per results page
+func (y patternYield) rate() float64 {
+	return float64(y.newURLs) / float64(y.pages)
+}
+
+func (y patternYield) add(other patternYield) patternYield {
+	return patternYield{queries: y.queries + other.queries, pages: y.pages + other.pages, newURLs: y.newURLs + other.newURLs}
+}
+
+// deadEnd reports whether the pattern's queries keep finding nothing new
+func (y patternYield) deadEnd() bool {
+	return y.queries >= deadEndQueries && y.newURLs == 0
+}
+
+// queryPatterns are what a query shares with its expanded variants: its site:
+// platform and its words, so a platform or synonym that keeps finding new URLs
+// lifts every query using it
+func queryPatterns(query string) []string {
+	var patterns, words []string
+	for _, field := range strings.Fields(strings.ToLower(query)) {
+		switch {
+		case strings.HasPrefix(field, "site:"):
+			patterns = append(patterns, field)
+		case strings.Contains(field, ":") || strings.HasPrefix(field, "-"):
+			// Other search operators say little about the query
+		default:
+			words = append(words, field)
+		}
+	}
+	for word := range termCounts(strings.Join(words, " ")) {
+		patterns = append(patterns, word)
+	}
+	return patterns
+}
+
+// patternYields adds up the run's query stats per pattern, and over all queries
+func (a *DeepResearcher) patternYields() (map[string]patternYield, patternYield) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	yields := make(map[string]patternYield)
+	var overall patternYield
+	for _, s := range a.queryStats {
+		if s.Pages == 0 {
+			continue // Never got a results page, e.g. every search failed
+		}
+		stat := patternYield{queries: 1, pages: s.Pages, newURLs: s.NewURLs}
+		overall = overall.add(stat)
+		for _, p := range queryPatterns(s.Query) {
+			yields[p] = yields[p].add(stat)
+		}
+	}
+	return yields, overall
+}
+
+// scoreQuery is the mean yield of a query's patterns, with prior standing in
+// for the untried ones. A query is a dead end when its platform is, or when
+// every one of its patterns is.
+func scoreQuery(query string, yields map[string]patternYield, prior float64) (score float64, deadEnd bool) {
+	patterns := queryPatterns(query)
+	if len(patterns) == 0 {
+		return prior, false
+	}
+	allDead, platformDead := true, false
+	for _, p := range patterns {
+		y, tried := yields[p]
+		if !tried {
+			score += prior
+			allDead = false
+			continue
+		}
+		score += y.rate()
+		if !y.deadEnd() {
+			allDead = false
+		} else if strings.HasPrefix(p, "site:") {
+			platformDead = true
+		}
+	}
+	return score / float64(len(patterns)), allDead || platformDead
+}
+
+// scheduleQueries reorders the run's remaining queries (of total, from next
+// on) by what their patterns yielded so far: queries whose platform and words
+// keep finding new URLs run first, untried ones rank as the run's average. Once
+// a round stalls (fewer new URLs than it ran queries), the queries of dead-end
+// patterns are skipped. Returns the new total; the skipped queries are counted
+// in Usage.DeadEndQueries. Off with Config.FixedQueryOrder.
+func (a *DeepResearcher) scheduleQueries(plan *ResearchPlan, next, total, roundNewURLs, roundQueries int) int {
+	if a.config.FixedQueryOrder || next >= total {
+		return total
+	}
+	yields, overall := a.patternYields()
+	if overall.pages == 0 {
+		return total
+	}
+	stalled := roundNewURLs < roundQueries
+
+	type scored struct {
+		query string
+		score float64
+	}
+	var kept []scored
+	var dead []string
+	for _, q := range plan.SearchQueries[next:total] {
+		score, deadEnd := scoreQuery(q, yields, overall.rate())
+		if stalled && deadEnd {
+			dead = append(dead, q)
+			continue
+		}
+		kept = append(kept, scored{q, score})
+	}
+	slices.SortStableFunc(kept, func(x, y scored) int { return cmp.Compare(y.score, x.score) })
+
+	remaining := make([]string, 0, total-next)
+	for _, s := range kept {
+		remaining = append(remaining, s.query)
+	}
+	if len(remaining) > 0 && remaining[0] != plan.SearchQueries[next] {
+		a.log.Info("🧭 Reordered queries by yield", "next", truncateQuery(remaining[0], 50), "remaining", len(remaining))
+	}
+	// Skipped queries stay in the plan after total, so a resumed run gets them back
+	plan.SearchQueries = slices.Concat(plan.SearchQueries[:next], remaining, dead, plan.SearchQueries[total:])
+
+	if len(dead) > 0 {
+		a.log.Info("🪦 Skipping dead-end queries", "skipped", len(dead), "kept", len(remaining), "round_new_urls", roundNewURLs)
+		a.budget.mu.Lock()
+		a.budget.deadEndQueries += len(dead)
+		a.budget.mu.Unlock()
+
+		a.mu.Lock()
+		a.searchProgress.totalQueries -= len(dead)
+		a.mu.Unlock()
+	}
+	return next + len(remaining)
+}
@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"deep-research/pkg/llm"
+	"encoding/json"
+)
+
+// The JSON schemas of the structured LLM responses (see chatJSON). They mirror
+// the formats the prompts describe; backends with structured output are held
+// to them, the others get them in the prompt.
+var (
+	planSchema = schema("research_plan", `{
+		"type": "object",
+		"properties": {
+			"clarifying_questions": {"type": "array", "items": {"type": "string"}},
+			"understanding_summary": {"type": "string"},
+			"research_steps": {"type": "array", "items": {"type": "string"}},
+			"expected_outcome": {"type": "string"}
+		},
+		"required": ["clarifying_questions", "understanding_summary", "research_steps", "expected_outcome"]
+	}`)
+
+	questionsSchema = schema("clarifying_questions", `{
+		"type": "object",
+		"properties": {
+			"clarifying_questions": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["clarifying_questions"]
+	}`)
+
+	exhaustivePlanSchema = schema("exhaustive_research_plan", `{
+		"type": "object",
+		"properties": {
+			"clarifying_questions": {"type": "array", "items": {"type": "string"}},
+			"understanding_summary": {"type": "string"},
+			"research_steps": {"type": "array", "items": {"type": "string"}},
+			"expected_outcome": {"type": "string"},
+			"search_queries": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["clarifying_questions", "understanding_summary", "research_steps", "expected_outcome", "search_queries"]
+	}`)
+
+	comparisonPlanSchema = schema("comparison_plan", `{
+		"type": "object",
+		"properties": {
+			"clarifying_questions": {"type": "array", "items": {"type": "string"}},
+			"understanding_summary": {"type": "string"},
+			"criteria": {"type": "array", "items": {"type": "string"}},
+			"queries": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}}
+		},
+		"required": ["clarifying_questions", "understanding_summary", "criteria", "queries"]
+	}`)
+
+	decisionSchema = schema("research_decision", `{
+		"type": "object",
+		"properties": {
+			"final_answer": {"type": "boolean"},
+			"queries": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["final_answer", "queries"]
+	}`)
+
+	queryExpansionSchema = schema("query_expansion", `{
+		"type": "object",
+		"properties": {
+			"synonyms": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}},
+			"platforms": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["synonyms", "platforms"]
+	}`)
+
+	queriesSchema = schema("search_queries", `{
+		"type": "object",
+		"properties": {
+			"queries": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["queries"]
+	}`)
+
+	outlineSchema = schema("report_outline", `{
+		"type": "object",
+		"properties": {
+			"title": {"type": "string"},
+			"sections": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"heading": {"type": "string"},
+						"focus": {"type": "string"},
+						"sources": {"type": "array", "items": {"type": "integer"}}
+					},
+					"required": ["heading", "focus", "sources"]
+				}
+			}
+		},
+		"required": ["title", "sections"]
+	}`)
+
+	comparisonMatrixSchema = schema("comparison_matrix", `{
+		"type": "object",
+		"properties": {
+			"summary": {"type": "string"},
+			"rows": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"criterion": {"type": "string"},
+						"values": {"type": "array", "items": {"type": "string"}}
+					},
+					"required": ["criterion", "values"]
+				}
+			}
+		},
+		"required": ["summary", "rows"]
+	}`)
+
+	graphSchema = schema("knowledge_graph", `{
+		"type": "object",
+		"properties": {
+			"entities": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string"},
+						"type": {"type": "string"},
+						"sources": {"type": "array", "items": {"type": "integer"}}
+					},
+					"required": ["name", "type", "sources"]
+				}
+			},
+			"relations": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"from": {"type": "string"},
+						"to": {"type": "string"},
+						"type": {"type": "string"},
+						"sources": {"type": "array", "items": {"type": "integer"}}
+					},
+					"required": ["from", "to", "type", "sources"]
+				}
+			}
+		},
+		"required": ["entities", "relations"]
+	}`)
+
+	claimsSchema = schema("source_claims", `{
+		"type": "object",
+		"properties": {
+			"facts": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"fact": {"type": "string"},
+						"claims": {
+							"type": "array",
+							"items": {
+								"type": "object",
+								"properties": {
+									"value": {"type": "string"},
+									"sources": {"type": "array", "items": {"type": "integer"}}
+								},
+								"required": ["value", "sources"]
+							}
+						}
+					},
+					"required": ["fact", "claims"]
+				}
+			}
+		},
+		"required": ["facts"]
+	}`)
+
+	evaluationSchema = schema("report_evaluation", `{
+		"type": "object",
+		"properties": {
+			"coverage": {"type": "integer"},
+			"specificity": {"type": "integer"},
+			"citations": {"type": "integer"},
+			"score": {"type": "integer"},
+			"gaps": {"type": "array", "items": {"type": "string"}},
+			"queries": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["coverage", "specificity", "citations", "score", "gaps", "queries"]
+	}`)
+
+	relevanceSchema = schema("result_relevance", `{
+		"type": "object",
+		"properties": {
+			"relevant": {"type": "array", "items": {"type": "integer"}}
+		},
+		"required": ["relevant"]
+	}`)
+
+	locationSchema = schema("source_locations", `{
+		"type": "object",
+		"properties": {
+			"locations": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"source": {"type": "integer"},
+						"location": {"type": "string"}
+					},
+					"required": ["source", "location"]
+				}
+			}
+		},
+		"required": ["locations"]
+	}`)
+
+	sitemapPatternSchema = schema("sitemap_pattern", `{
+		"type": "object",
+		"properties": {
+			"pattern": {"type": "string"},
+			"reason": {"type": "string"}
+		},
+		"required": ["pattern", "reason"]
+	}`)
+)
+
+// schema compacts a JSON Schema literal into an llm.Schema
+func schema(name, literal string) llm.Schema {
+	var compact json.RawMessage
+	if err := json.Unmarshal([]byte(literal), &compact); err != nil {
+		panic("invalid " + name + " schema: " + err.Error())
+	}
+	data, _ := json.Marshal(compact)
+	return llm.Schema{Name: name, Schema: data}
+}
+
+// recordSchema is the schema of a record with the extraction fields. A field
+// may hold any JSON value (null when the page doesn't say).
+func recordSchema(fields []string) llm.Schema {
+	properties := make(map[string]any, len(fields))
+	for _, f := range fields {
+		properties[f] = map[string]any{}
+	}
+	data, _ := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   fields,
+	})
+	return llm.Schema{Name: "record", Schema: data}
+}
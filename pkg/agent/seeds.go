@@ -0,0 +1,253 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/search"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLinksPerSeed caps the item links followed from each seed page (Config.FollowLinks)
+const maxLinksPerSeed = 10
+
+// followSteps are the plan steps of Config.FollowLinks, Config.NextPages, and Config.CrawlDepth
+func (a *DeepResearcher) followSteps() []string {
+	switch {
+	case a.config.CrawlDepth > 0:
+		return []string{fmt.Sprintf("Crawl up to %d links deep from each page (next pages and categories of the same site) and summarize up to %d item pages found", a.config.CrawlDepth, a.config.listingItems(maxLinksPerSeed))}
+	case a.config.FollowLinks && a.config.NextPages > 0:
+		return []string{fmt.Sprintf("Follow up to %d item links found on each page and up to %d of its next pages, and summarize those too", a.config.listingItems(maxLinksPerSeed), a.config.NextPages)}
+	case a.config.FollowLinks:
+		return []string{fmt.Sprintf("Follow up to %d item links found on each page and summarize those too", maxLinksPerSeed)}
+	}
+	return nil
+}
+
+// seedPlan describes a seed-URL run. There are no queries to generate, so no
+// LLM call is made; user feedback is carried into the report context.
+func (a *DeepResearcher) seedPlan(topic, additionalContext string) ResearchPlan {
+	summary := fmt.Sprintf("Analyze the %d provided pages for: %s", len(a.config.SeedURLs), topic)
+	if additionalContext != "" {
+		summary += fmt.Sprintf("\n\nAdditional context from user:\n%s", additionalContext)
+	}
+
+	steps := []string{fmt.Sprintf("Fetch and summarize the %d provided URLs", len(a.config.SeedURLs))}
+	steps = append(steps, a.followSteps()...)
+	steps = append(steps, "Write the report from the page summaries")
+
+	return ResearchPlan{
+		UnderstandingSummary: summary,
+		ResearchSteps:        steps,
+		ExpectedOutcome:      "A report built only from the provided pages, citing the page behind each fact",
+	}
+}
+
+// runSeeds researches the given pages (Config.SeedURLs, or those picked from
+// sitemaps) instead of searching: each page (plus, with Config.FollowLinks or
+// Config.CrawlDepth, the item links found on it or crawled from it) is fetched
+// and summarized, then the report is written from the summaries. On
+// cancellation (or when a budget runs out) the report is written from the
+// pages summarized so far.
+func (a *DeepResearcher) runSeeds(parent context.Context, topic string, plan ResearchPlan, urls []string) (ResearchResult, error) {
+	fetcher, ok := a.searcher.(search.ContentFetcher)
+	if !ok {
+		return ResearchResult{}, errors.New("seed URLs need a searcher that can fetch pages")
+	}
+	linkExtractor, canExtract := a.searcher.(search.LinkExtractor)
+	if (a.config.FollowLinks || a.config.CrawlDepth > 0) && !canExtract {
+		a.log.Warn("⚠️ Searcher can't extract links, only the seed pages will be fetched")
+	}
+
+	a.mu.Lock()
+	a.sources = append(make([]Source, 0, len(a.config.Documents)+len(urls)), a.documentSources()...)
+	a.records = nil
+	a.findings = nil
+	a.queryStats = nil
+	a.seenURLs = make(map[string]bool)
+	var seeds []string
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" || a.seenURLs[normalizeURL(u)] {
+			continue
+		}
+		a.seenURLs[normalizeURL(u)] = true
+		seeds = append(seeds, u)
+	}
+	a.mu.Unlock()
+
+	a.startProgress(topic, "", 0)
+	ctx, stopBudget := a.startBudget(parent)
+	defer stopBudget()
+
+	a.log.Info("📑 Researching listed pages", "pages", len(seeds), "topic", topic)
+
+	parallel := max(a.config.ParallelQuery, 1)
+	sem := make(chan struct{}, parallel)
+	findings := make([]string, len(seeds)) // Per seed, so the context follows the user's order
+	var wg sync.WaitGroup
+	var done, failed int
+	var progressMu sync.Mutex
+
+	for i, seed := range seeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			pages := []search.ListingLink{{URL: seed, Title: seed}}
+			if (a.config.FollowLinks || a.config.CrawlDepth > 0) && canExtract {
+				links, err := a.listingLinks(ctx, linkExtractor, seed, a.config.listingItems(maxLinksPerSeed))
+				if err != nil {
+					a.log.Warn("⚠️ No links extracted", "url", seed, "error", err)
+				}
+				for _, link := range links {
+					if !a.config.allowsURL(link.URL) {
+						continue
+					}
+					a.mu.Lock()
+					seen := a.seenURLs[normalizeURL(link.URL)]
+					a.seenURLs[normalizeURL(link.URL)] = true
+					a.mu.Unlock()
+					if !seen {
+						pages = append(pages, link)
+					}
+				}
+			}
+
+			// Pages are summarized while the next ones are fetched
+			var read []*deepPage
+			summarizer := a.summarizeStage(ctx, nil)
+			for _, page := range pages {
+				if ctx.Err() != nil {
+					break
+				}
+				a.log.Debug("📄 Fetching", "url", page.URL)
+				content, archiveURL, published, err := a.fetchPage(ctx, fetcher, page.URL)
+				if errors.Is(err, errTooOld) {
+					a.log.Info("⏳ Skipped, published too long ago", "url", page.URL, "published", published.Format(time.DateOnly))
+					continue
+				}
+				if err != nil || len(content) < 50 {
+					if err == nil {
+						err = errors.New("no readable content")
+					}
+					a.log.Warn("❌ Fetch failed", "url", page.URL, "error", err)
+					progressMu.Lock()
+					failed++
+					progressMu.Unlock()
+					continue
+				}
+				if a.isNearDuplicate(ctx, page.URL, content) {
+					continue
+				}
+				p := &deepPage{url: page.URL, title: page.Title, content: content, archiveURL: archiveURL, fetchedAt: time.Now(), published: published}
+				summarizer.send(p)
+				read = append(read, p)
+			}
+			summarizer.wait()
+
+			var sb strings.Builder
+			for _, p := range read {
+				sb.WriteString(fmt.Sprintf("- PAGE: %s\n  URL: %s\n  Details: %s\n\n", p.title, p.url, p.summary))
+				a.mu.Lock()
+				a.sources = append(a.sources, Source{Title: p.title, URL: p.url, Summary: p.summary, FetchedAt: p.fetchedAt, ArchiveURL: p.archiveURL, Published: p.published})
+				a.mu.Unlock()
+			}
+			findings[i] = sb.String()
+
+			progressMu.Lock()
+			done++
+			a.emitProgress(ProgressEvent{
+				Phase:     "searching",
+				URLsFound: a.sourceCount(),
+				Message:   fmt.Sprintf("Fetched %d/%d listed pages", done, len(seeds)),
+				Percent:   5 + 80*done/len(seeds),
+			})
+			progressMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	a.mu.Lock()
+	sources := make([]Source, len(a.sources))
+	copy(sources, a.sources)
+	records := append([]map[string]any(nil), a.records...)
+	a.mu.Unlock()
+
+	usage := a.finishBudget(ctx)
+	cancelled := ctx.Err() != nil
+	if len(sources) == 0 {
+		if cancelled {
+			return ResearchResult{}, context.Cause(ctx)
+		}
+		return ResearchResult{}, fmt.Errorf("none of the %d listed URLs could be fetched", len(seeds))
+	}
+	a.log.Info("📊 Pages summarized", "pages", len(sources), "failed", failed)
+	a.normalizePrices(sources, records)
+
+	researchContext := fmt.Sprintf(`User Query: %s
+
+Research Plan:
+- Understanding: %s
+- Expected Outcome: %s
+
+Page summaries:
+%s`, topic, plan.UnderstandingSummary, plan.ExpectedOutcome, strings.Join(findings, ""))
+
+	// A cancelled run still gets its partial report
+	reportMessage := "Writing final report..."
+	reportCtx, err := reportContext(parent)
+	if err != nil {
+		return ResearchResult{}, err
+	}
+	if cancelled {
+		stopReason := "cancelled"
+		if usage.Exhausted != "" {
+			stopReason = usage.Exhausted
+		}
+		reportMessage = fmt.Sprintf("Writing partial report (%s)...", stopReason)
+		researchContext += fmt.Sprintf("\n\n--- NOTE: Research stopped early (%s). Results may be incomplete. ---\n", stopReason)
+	}
+	a.emitProgress(ProgressEvent{
+		Phase:     "writing_report",
+		URLsFound: len(sources),
+		Message:   reportMessage,
+		Percent:   90,
+	})
+	sources, records, areaNote := a.filterArea(reportCtx, sources, records)
+	researchContext += areaNote
+	conflicts, consensus := a.analyzeClaims(reportCtx, sources)
+	researchContext += conflictNote(conflicts)
+	a.log.Info("✍️ Writing Final Report")
+
+	report, err := a.writeReport(reportCtx, topic, researchContext, sources)
+	if err != nil {
+		return ResearchResult{}, err
+	}
+	result := a.finishResult(reportCtx, topic, plan, report, gathered{sources: sources, records: records, conflicts: conflicts, consensus: consensus})
+	result.Usage = a.withTokens(usage)
+
+	a.emitProgress(ProgressEvent{
+		Phase:     "complete",
+		URLsFound: len(sources),
+		Message:   fmt.Sprintf("Research complete! Summarized %d pages.", len(sources)),
+		Percent:   100,
+	})
+
+	return result, nil
+}
+
+// sourceCount returns the number of sources collected so far
+func (a *DeepResearcher) sourceCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.sources)
+}
@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/search"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultSitemapPages = 100   // Pages a sitemap run researches when Config.MaxSitemapPages is 0
+	maxSitemapURLs      = 50000 // URLs read from one site's sitemaps
+	patternSampleSize   = 150   // Sitemap URLs shown to the planner to pick a pattern from
+)
+
+// SitemapPlan is what a sitemap run researches: the pages of Config.SitemapSites
+// whose URLs match Pattern
+type SitemapPlan struct {
+	Pattern string   `json:"pattern"` // Regular expression the page URLs match ("" = every page)
+	Found   int      `json:"found"`   // URLs listed in the sitemaps
+	Matched int      `json:"matched"` // URLs matching Pattern and the domain and age filters
+	Pages   []string `json:"pages"`   // The pages to research, most recently changed first
+}
+
+// sitemapPlan reads the sitemaps of Config.SitemapSites and picks the pages to
+// research: those matching Config.SitemapPattern, or a pattern the planner picks
+// for the topic from a sample of the URLs
+func (a *DeepResearcher) sitemapPlan(ctx context.Context, topic, additionalContext string) (ResearchPlan, error) {
+	reader, ok := a.searcher.(search.SitemapReader)
+	if !ok {
+		return ResearchPlan{}, errors.New("sitemap sites need a searcher that can read sitemaps")
+	}
+
+	var urls []search.SitemapURL
+	seen := make(map[string]bool)
+	found := 0
+	for _, site := range a.config.SitemapSites {
+		a.log.Info("🗺️ Reading sitemaps", "site", site)
+		listed, err := reader.SitemapURLs(ctx, site, maxSitemapURLs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ResearchPlan{}, ctx.Err()
+			}
+			a.log.Warn("⚠️ No sitemap pages", "site", site, "error", err)
+			continue
+		}
+		found += len(listed)
+		for _, u := range listed {
+			key := normalizeURL(u.URL)
+			if seen[key] || !a.config.allowsURL(u.URL) || a.config.tooOld(u.LastMod) {
+				continue
+			}
+			seen[key] = true
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return ResearchPlan{}, fmt.Errorf("no usable pages in the sitemaps of %s", strings.Join(a.config.SitemapSites, ", "))
+	}
+	a.log.Info("🗺️ Sitemap pages", "listed", found, "usable", len(urls))
+
+	pattern := a.config.SitemapPattern
+	if pattern == "" {
+		pattern = a.chooseSitemapPattern(ctx, topic, additionalContext, urls)
+	}
+	matched := urls
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return ResearchPlan{}, fmt.Errorf("invalid sitemap pattern: %w", err)
+		}
+		matched = nil
+		for _, u := range urls {
+			if re.MatchString(u.URL) {
+				matched = append(matched, u)
+			}
+		}
+		if len(matched) == 0 {
+			return ResearchPlan{}, fmt.Errorf("none of the %d sitemap pages match %q", len(urls), pattern)
+		}
+	}
+
+	// The most recently changed pages first; undated ones keep their sitemap order after them
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].LastMod.After(matched[j].LastMod) })
+	limit := a.config.MaxSitemapPages
+	if limit <= 0 {
+		limit = defaultSitemapPages
+	}
+	pages := make([]string, 0, min(limit, len(matched)))
+	for _, u := range matched[:min(limit, len(matched))] {
+		pages = append(pages, u.URL)
+	}
+
+	summary := fmt.Sprintf("Analyze %d pages from the sitemaps of %s for: %s", len(pages), strings.Join(a.config.SitemapSites, ", "), topic)
+	if additionalContext != "" {
+		summary += fmt.Sprintf("\n\nAdditional context from user:\n%s", additionalContext)
+	}
+	steps := []string{fmt.Sprintf("Read the sitemaps of %s (%d pages listed)", strings.Join(a.config.SitemapSites, ", "), found)}
+	if pattern != "" {
+		steps = append(steps, fmt.Sprintf("Keep the %d pages whose URLs match %s", len(matched), pattern))
+	}
+	steps = append(steps, fmt.Sprintf("Fetch and summarize the %d most recently changed", len(pages)))
+	steps = append(steps, a.followSteps()...)
+	steps = append(steps, "Write the report from the page summaries")
+
+	return ResearchPlan{
+		UnderstandingSummary: summary,
+		ResearchSteps:        steps,
+		ExpectedOutcome:      "A report built from the sites' own pages, citing the page behind each fact",
+		Sitemap:              &SitemapPlan{Pattern: pattern, Found: found, Matched: len(matched), Pages: pages},
+	}, nil
+}
+
+// chooseSitemapPattern asks the planner for a regular expression matching the
+// sitemap URLs worth researching for the topic. It returns "" (every page) when
+// the planner fails or its pattern is invalid or matches nothing.
+func (a *DeepResearcher) chooseSitemapPattern(ctx context.Context, topic, additionalContext string, urls []search.SitemapURL) string {
+	// An even spread over the list, so every section of the site is represented
+	step := max(len(urls)/patternSampleSize, 1)
+	var sample []string
+	for i := 0; i < len(urls) && len(sample) < patternSampleSize; i += step {
+		sample = append(sample, urls[i].URL)
+	}
+	contextInfo := ""
+	if additionalContext != "" {
+		contextInfo = fmt.Sprintf("\n\nAdditional context from user:\n%s", additionalContext)
+	}
+
+	prompt := fmt.Sprintf(`You are choosing which pages of a website to research.
+
+User's research request: "%s"%s
+
+A sample of the %d URLs in the site's sitemap:
+%s
+
+Write a regular expression (Go RE2 syntax: no lookaheads or backreferences) that matches the URLs of the pages with the data the request needs, usually the listing or detail pages (e.g. "/listing/\d+", "/products/[^/]+$"), and not the category, tag, author, or legal pages. Prefer a pattern built from the URL structure shown over one naming specific items.
+
+Respond ONLY with valid JSON:
+{
+  "pattern": "...",
+  "reason": "one sentence"
+}`, topic, contextInfo, len(urls), strings.Join(sample, "\n"))
+
+	var resp struct {
+		Pattern string `json:"pattern"`
+		Reason  string `json:"reason"`
+	}
+	err := a.chatJSON(a.withCall(ctx, CallPlanning), a.writer, []llm.Message{
+		{Role: "system", Content: "You are a web crawling assistant. Output only valid JSON."},
+		{Role: "user", Content: prompt},
+	}, sitemapPatternSchema, &resp)
+	if err != nil {
+		a.log.Warn("⚠️ Sitemap pattern failed, keeping every page", "error", jsonError("sitemap pattern", err))
+		return ""
+	}
+	re, err := regexp.Compile(resp.Pattern)
+	if err != nil {
+		a.log.Warn("⚠️ Invalid sitemap pattern, keeping every page", "pattern", resp.Pattern, "error", err)
+		return ""
+	}
+	matches := 0
+	for _, u := range urls {
+		if re.MatchString(u.URL) {
+			matches++
+		}
+	}
+	if matches == 0 {
+		a.log.Warn("⚠️ Sitemap pattern matches nothing, keeping every page", "pattern", resp.Pattern)
+		return ""
+	}
+	a.log.Info("🗺️ Sitemap pattern", "pattern", resp.Pattern, "matches", matches, "reason", resp.Reason)
+	return resp.Pattern
+}
+
+// runSitemap researches the pages picked by the plan (see sitemapPlan) like seed URLs
+func (a *DeepResearcher) runSitemap(parent context.Context, topic string, plan ResearchPlan) (ResearchResult, error) {
+	if plan.Sitemap == nil || len(plan.Sitemap.Pages) == 0 {
+		return ResearchResult{}, fmt.Errorf("no sitemap pages in plan - set Config.SitemapSites before planning")
+	}
+	return a.runSeeds(parent, topic, plan, plan.Sitemap.Pages)
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"deep-research/pkg/search"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// sourceIndexFile lists a source archive's pages, one JSON object per line
+const sourceIndexFile = "index.jsonl"
+
+// archivedPage is one line of a source archive's index
+type archivedPage struct {
+	URL        string    `json:"url"`
+	Text       string    `json:"text"`                 // File with the text the research read, relative to the archive
+	Raw        string    `json:"raw,omitempty"`        // File with the page as downloaded (Config.ArchiveHTML)
+	ArchiveURL string    `json:"archiveUrl,omitempty"` // Wayback Machine snapshot read because the page was gone
+	Published  time.Time `json:"published,omitzero"`   // Publication date found on the page
+	FetchedAt  time.Time `json:"fetchedAt"`
+}
+
+// sourceArchive saves the pages a run fetches under Config.SourcesDir, so the
+// claims in its report can be checked after the live pages change or
+// disappear. It is opened on the first save; a resumed run adds to the same
+// index. The zero value is ready to use.
+type sourceArchive struct {
+	mu       sync.Mutex
+	opened   bool
+	disabled bool            // Set after the first write failure
+	saved    map[string]bool // URLs already archived
+}
+
+// archivePage saves a fetched page's text (and, with Config.ArchiveHTML, its
+// body as downloaded) to the source archive, once per URL
+func (a *DeepResearcher) archivePage(pageURL, text, archiveURL string, info *search.PageInfo) {
+	dir := a.config.SourcesDir
+	if dir == "" {
+		return
+	}
+	s := &a.archive
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disabled {
+		return
+	}
+	if err := s.save(dir, a.config.ArchiveHTML, pageURL, text, archiveURL, info); err != nil {
+		a.log.Warn("⚠️ Could not archive sources; no more pages will be saved", "dir", dir, "error", err)
+		s.disabled = true
+	}
+}
+
+func (s *sourceArchive) save(dir string, raw bool, pageURL, text, archiveURL string, info *search.PageInfo) error {
+	if !s.opened {
+		if err := s.open(dir); err != nil {
+			return err
+		}
+	}
+	if s.saved[pageURL] {
+		return nil
+	}
+
+	name := fmt.Sprintf("%04d-%s", len(s.saved)+1, pageSlug(pageURL))
+	page := archivedPage{URL: pageURL, Text: name + ".txt", ArchiveURL: archiveURL, Published: info.Published, FetchedAt: time.Now()}
+	if err := os.WriteFile(filepath.Join(dir, page.Text), []byte(text), 0644); err != nil {
+		return fmt.Errorf("failed to write page text: %w", err)
+	}
+	if raw && info.Body != nil {
+		page.Raw = name + ".html"
+		if strings.Contains(info.ContentType, "pdf") || bytes.HasPrefix(info.Body, []byte("%PDF")) {
+			page.Raw = name + ".pdf"
+		}
+		if err := os.WriteFile(filepath.Join(dir, page.Raw), info.Body, 0644); err != nil {
+			return fmt.Errorf("failed to write page body: %w", err)
+		}
+	}
+
+	line, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index entry: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, sourceIndexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open source index: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write source index: %w", err)
+	}
+	s.saved[pageURL] = true
+	return f.Close()
+}
+
+// open creates the archive directory and reads back the pages an earlier
+// attempt at the run already archived
+func (s *sourceArchive) open(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create sources directory: %w", err)
+	}
+	s.saved = make(map[string]bool)
+	f, err := os.Open(filepath.Join(dir, sourceIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		s.opened = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open source index: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var page archivedPage
+		if json.Unmarshal(scanner.Bytes(), &page) == nil && page.URL != "" {
+			s.saved[page.URL] = true
+		}
+	}
+	s.opened = true
+	return scanner.Err()
+}
+
+// pageSlug is a short file-name-safe rendering of a page's host and path
+func pageSlug(pageURL string) string {
+	name := pageURL
+	if u, err := url.Parse(pageURL); err == nil && u.Host != "" {
+		name = strings.TrimPrefix(u.Hostname(), "www.") + u.Path
+	}
+	slug := strings.Trim(strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, name), "-")
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		slug = "page"
+	}
+	return slug
+}
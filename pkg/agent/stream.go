@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"strings"
+)
+
+// PhaseReportChunk is the phase of the progress events that stream the report (Config.StreamReport)
+const PhaseReportChunk = "report_chunk"
+
+// reportStream sends the report to Config.OnProgress while the writer
+// generates it: a PhaseReportChunk event per finished paragraph, without the
+// model's <think> block. A nil reportStream sends nothing.
+type reportStream struct {
+	a       *DeepResearcher
+	heading string          // Heading sent before a response that doesn't start with one
+	text    strings.Builder // The response being generated
+	sent    int             // Bytes of the response's visible text already sent
+	started bool            // Whether anything has been sent since the last restart
+}
+
+// newReportStream returns the report's stream, or nil when the report isn't
+// streamed (Config.StreamReport unset, or a draft)
+func (a *DeepResearcher) newReportStream(ctx context.Context) *reportStream {
+	if !a.config.StreamReport || a.config.OnProgress == nil || isDraft(ctx) {
+		return nil
+	}
+	return &reportStream{a: a}
+}
+
+// send streams literal Markdown, e.g. the report's title
+func (s *reportStream) send(chunk string) {
+	if s == nil || chunk == "" {
+		return
+	}
+	s.started = true
+	s.a.emitProgress(ProgressEvent{Phase: PhaseReportChunk, Chunk: chunk})
+}
+
+// section sets the heading sent before the next response if it doesn't start with one
+func (s *reportStream) section(heading string) {
+	if s != nil {
+		s.heading = heading
+	}
+}
+
+// delta takes the next piece of the response; complete paragraphs are sent
+func (s *reportStream) delta(piece string) {
+	if s == nil {
+		return
+	}
+	s.text.WriteString(piece)
+	if strings.Contains(piece, "\n") {
+		s.flush(false)
+	}
+}
+
+// end sends the rest of the response, ready for the next one (e.g. the next section)
+func (s *reportStream) end() {
+	if s == nil {
+		return
+	}
+	s.flush(true)
+	s.text.Reset()
+	s.sent = 0
+	s.heading = ""
+}
+
+// restart tells the listener to discard what was streamed, before the report is written again
+func (s *reportStream) restart() {
+	if s == nil {
+		return
+	}
+	s.text.Reset()
+	s.sent = 0
+	if s.started {
+		s.started = false
+		s.a.emitProgress(ProgressEvent{Phase: PhaseReportChunk, Restart: true})
+	}
+}
+
+// flush sends the visible text not sent yet: up to the last paragraph break,
+// or all of it once the response is final
+func (s *reportStream) flush(final bool) {
+	visible := s.text.String()
+	if start := strings.Index(visible, "<think>"); start != -1 {
+		end := strings.Index(visible, "</think>")
+		if end == -1 {
+			visible = visible[:start] // Still thinking
+		} else {
+			visible = visible[:start] + visible[end+len("</think>"):]
+		}
+	}
+	visible = strings.TrimLeft(visible, " \t\r\n")
+	if len(visible) <= s.sent {
+		return
+	}
+
+	pending := visible[s.sent:]
+	if !final {
+		cut := strings.LastIndex(pending, "\n\n")
+		if cut == -1 {
+			return
+		}
+		pending = pending[:cut+2]
+	}
+	if s.sent == 0 && s.heading != "" && !strings.HasPrefix(pending, "#") {
+		s.send("## " + s.heading + "\n\n")
+	}
+	s.sent += len(pending)
+	if final {
+		pending = strings.TrimRight(pending, " \t\r\n") + "\n\n"
+	}
+	s.send(pending)
+}
+
+// chatStream is chat for a response of the report: it is streamed as it is
+// generated when the provider can stream, or sent whole once it is done. Without
+// a stream it is plain chat.
+func (a *DeepResearcher) chatStream(ctx context.Context, p llm.Provider, messages []llm.Message, stream *reportStream) (string, error) {
+	if stream == nil {
+		return a.chat(ctx, p, messages)
+	}
+	p = callProvider(ctx, p)
+	streamer, ok := p.(llm.Streamer)
+	if !ok {
+		resp, err := a.chat(ctx, p, messages)
+		if err == nil {
+			stream.delta(resp)
+		}
+		return resp, err
+	}
+	if err := a.spend(ctx, true); err != nil {
+		return "", err
+	}
+	return streamer.ChatStream(ctx, callMessages(ctx, messages), stream.delta)
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"unicode/utf8"
+)
+
+// defaultContextLength is assumed when Config.ContextLength is unset
+const defaultContextLength = 32768
+
+// maxContextTokens returns the model's context window in tokens
+func (c Config) maxContextTokens() int {
+	if c.ContextLength <= 0 {
+		return defaultContextLength
+	}
+	return c.ContextLength
+}
+
+// countTokens counts tokens with the model's own tokenizer when the provider
+// exposes one (llm.Tokenizer), falling back to llm.EstimateTokens. The tokenizer
+// is not retried after its first failure.
+func (a *DeepResearcher) countTokens(ctx context.Context, text string) int {
+	if tokenizer, ok := a.llmClient.(llm.Tokenizer); ok {
+		a.mu.Lock()
+		disabled := a.tokenizerDisabled
+		a.mu.Unlock()
+
+		if !disabled {
+			n, err := tokenizer.CountTokens(ctx, text)
+			if err == nil {
+				return n
+			}
+			if ctx.Err() == nil {
+				a.mu.Lock()
+				if !a.tokenizerDisabled {
+					a.log.Warn("⚠️ Model tokenizer unavailable, estimating token counts instead", "error", err)
+				}
+				a.tokenizerDisabled = true
+				a.mu.Unlock()
+			}
+		}
+	}
+	return llm.EstimateTokens(text)
+}
+
+// charsForTokens converts a token budget to a character budget using the
+// measured chars-per-token ratio of text (so chunking and truncation match
+// the actual tokenizer instead of a fixed guess)
+func charsForTokens(text string, tokens, maxTokens int) int {
+	if tokens <= 0 {
+		return len(text)
+	}
+	return int(float64(len(text)) * float64(maxTokens) / float64(tokens))
+}
+
+// truncateToTokens cuts text to fit within maxTokens, re-measuring after each cut
+func (a *DeepResearcher) truncateToTokens(ctx context.Context, text string, maxTokens int) string {
+	tokens := a.countTokens(ctx, text)
+	for tokens > maxTokens && len(text) > 0 {
+		// Aim slightly under the budget so the loop usually runs once
+		limit := charsForTokens(text, tokens, maxTokens) * 95 / 100
+		if limit >= len(text) {
+			limit = len(text) - 1
+		}
+		for limit > 0 && !utf8.RuneStart(text[limit]) {
+			limit--
+		}
+		text = text[:max(limit, 0)]
+		tokens = a.countTokens(ctx, text)
+	}
+	return text
+}
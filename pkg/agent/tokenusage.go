@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"deep-research/pkg/llm"
+	"maps"
+	"math"
+	"sync"
+)
+
+// tokenCounter totals the tokens a researcher's chat calls used, by call type
+// (see withCall), over its lifetime: planning and the report are included
+type tokenCounter struct {
+	mu     sync.Mutex
+	byCall map[string]llm.TokenUsage
+}
+
+func (t *tokenCounter) add(callType string, u llm.TokenUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.byCall == nil {
+		t.byCall = make(map[string]llm.TokenUsage)
+	}
+	t.byCall[callType] = t.byCall[callType].Add(u)
+}
+
+// totals returns the tokens used so far, overall and by call type
+func (t *tokenCounter) totals() (llm.TokenUsage, map[string]llm.TokenUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total llm.TokenUsage
+	for _, u := range t.byCall {
+		total = total.Add(u)
+	}
+	return total, maps.Clone(t.byCall)
+}
+
+// cost is the price of u in USD at Config.PromptPrice and CompletionPrice
+func (c Config) cost(u llm.TokenUsage) float64 {
+	cost := (float64(u.PromptTokens)*c.PromptPrice + float64(u.CompletionTokens)*c.CompletionPrice) / 1e6
+	return math.Round(cost*1e6) / 1e6
+}
+
+// withTokens adds the tokens the researcher has used so far, and their cost, to usage
+func (a *DeepResearcher) withTokens(usage Usage) Usage {
+	total, byCall := a.tokens.totals()
+	usage.PromptTokens = total.PromptTokens
+	usage.CompletionTokens = total.CompletionTokens
+	usage.Tokens = byCall
+	usage.Cost = a.config.cost(total)
+	return usage
+}
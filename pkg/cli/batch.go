@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// batchTopic is a topic of a --topics-file with the options it sets
+type batchTopic struct {
+	Topic   string
+	Options []batchOption // In file order
+}
+
+// batchOption is a flag a topic sets, e.g. loops: 4
+type batchOption struct {
+	Flag   string
+	Values []string // One per item of a list ("include-domains: [a.com, b.com]")
+}
+
+// batchRun is one topic's run in a batch: its flags, for the profile to fill
+// in only what neither the command line nor the topic set, and what came of it
+type batchRun struct {
+	flags   *pflag.FlagSet
+	jobID   string
+	report  string
+	sources int
+	elapsed time.Duration
+	err     error
+	skipped bool // Not started: the batch was interrupted
+}
+
+// batchFlags can't be used in a batch, on the command line or per topic: they
+// concern a single run's output, and the topics share the console
+var batchFlags = []string{"output", "json", "tui", "progress-out"}
+
+// runBatch researches every topic of --topics-file with the command line's
+// options and the topic's own, --topics-parallel at a time, auto-approving the
+// plans. A failed topic doesn't stop the others; Ctrl+C finishes the running
+// ones and skips the rest. The reports are listed in results/<time>_batch.md.
+func runBatch(cmd *cobra.Command, opts *researchOptions) error {
+	for _, name := range append(batchFlags, "topic", "checkpoint") {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--topics-file can't be combined with --%s", name)
+		}
+	}
+	if opts.topicsParallel < 1 {
+		return fmt.Errorf("--topics-parallel must be at least 1")
+	}
+	topics, err := readTopicsFile(opts.topicsFile)
+	if err != nil {
+		return err
+	}
+
+	// Each topic gets its own copy of the options, with its own settings applied
+	runs := make([]*batchRun, len(topics))
+	runOpts := make([]*researchOptions, len(topics))
+	for i, t := range topics {
+		if runOpts[i], err = opts.forTopic(cmd.Flags(), t); err != nil {
+			return fmt.Errorf("%s: topic %q: %w", opts.topicsFile, t.Topic, err)
+		}
+		runs[i] = runOpts[i].batch
+	}
+	fmt.Printf("🗃️ Researching %d topics from %s (%d at a time)\n", len(topics), opts.topicsFile, opts.topicsParallel)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	stopped := make(chan struct{})
+	go func() {
+		// The running topics finish on their own; a second Ctrl+C quits as usual
+		if _, ok := <-interrupt; ok {
+			signal.Stop(interrupt)
+			fmt.Println("\n⏹️ Skipping the topics not started yet")
+			close(stopped)
+		}
+	}()
+
+	started := time.Now()
+	sem := make(chan struct{}, opts.topicsParallel)
+	var wg sync.WaitGroup
+	for i, t := range topics {
+		sem <- struct{}{}
+		select {
+		case <-stopped:
+			runs[i].skipped = true
+			<-sem
+			continue
+		default:
+		}
+		fmt.Printf("\n🗃️ Topic %d/%d: %s\n", i+1, len(topics), t.Topic)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			start := time.Now()
+			runs[i].err = runResearch(cmd, runOpts[i], nil, "")
+			runs[i].elapsed = time.Since(start)
+			if runs[i].err != nil {
+				fmt.Printf("❌ Topic %q failed: %v\n", topics[i].Topic, runs[i].err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	indexPath, err := writeBatchIndex(topics, runs, started)
+	if err != nil {
+		fmt.Printf("⚠️ Could not write the batch index: %v\n", err)
+	}
+
+	failed, skipped := 0, 0
+	for _, r := range runs {
+		switch {
+		case r.skipped:
+			skipped++
+		case r.err != nil:
+			failed++
+		}
+	}
+	fmt.Printf("\n🗃️ Batch done in %v: %d of %d topics researched", time.Since(started).Round(time.Second), len(topics)-failed-skipped, len(topics))
+	if skipped > 0 {
+		fmt.Printf(", %d skipped", skipped)
+	}
+	fmt.Println()
+	if err == nil {
+		fmt.Printf("📇 Index saved to: %s\n", indexPath)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d topics failed", failed, len(topics))
+	}
+	return nil
+}
+
+// forTopic copies the options for t's run: the command line's, with t's options
+// set on top. The copy's flag set marks both as given, so --profile (the
+// command line's or the topic's) only fills in the rest.
+func (o *researchOptions) forTopic(cmdFlags *pflag.FlagSet, t batchTopic) (*researchOptions, error) {
+	topicOpts := &researchOptions{}
+	flags := pflag.NewFlagSet(t.Topic, pflag.ContinueOnError)
+	topicOpts.addFlags(flags)
+	*topicOpts = *o // The flags stay bound to the copy's fields
+	cmdFlags.Visit(func(f *pflag.Flag) {
+		if tf := flags.Lookup(f.Name); tf != nil {
+			tf.Changed = true
+		}
+	})
+
+	for _, opt := range t.Options {
+		f := flags.Lookup(opt.Flag)
+		if f == nil || opt.Flag == "topics-file" {
+			return nil, fmt.Errorf("unknown option %q", opt.Flag)
+		}
+		for _, name := range batchFlags {
+			if opt.Flag == name {
+				return nil, fmt.Errorf("--%s can't be set per topic", name)
+			}
+		}
+		for _, v := range opt.Values {
+			if err := flags.Set(opt.Flag, v); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", opt.Flag, err)
+			}
+		}
+	}
+
+	topicOpts.topic = t.Topic
+	topicOpts.autoApprove = true
+	topicOpts.batch = &batchRun{flags: flags}
+	return topicOpts, nil
+}
+
+// readTopicsFile reads a --topics-file: a YAML list (.yaml or .yml) whose items
+// are topics, or maps of a topic and its options named like the flags
+// ({topic: ..., profile: listing-hunt, loops: 4}); else one topic per line,
+// skipping blank lines and # comments
+func readTopicsFile(path string) ([]batchTopic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topics file: %w", err)
+	}
+
+	var topics []batchTopic
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if topics, err = parseTopicsYAML(data); err != nil {
+			return nil, fmt.Errorf("invalid topics file %s: %w", path, err)
+		}
+	default:
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				topics = append(topics, batchTopic{Topic: line})
+			}
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("no topics in %s", path)
+	}
+
+	// Runs are named after their topic and start time, so a topic can't run twice at once
+	seen := make(map[string]bool)
+	for _, t := range topics {
+		if seen[strings.ToLower(t.Topic)] {
+			return nil, fmt.Errorf("%s: topic %q is listed twice", path, t.Topic)
+		}
+		seen[strings.ToLower(t.Topic)] = true
+	}
+	return topics, nil
+}
+
+// parseTopicsYAML reads the YAML form of a topics file
+func parseTopicsYAML(data []byte) ([]batchTopic, error) {
+	var items []yaml.Node
+	if err := yaml.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	var topics []batchTopic
+	for _, item := range items {
+		switch item.Kind {
+		case yaml.ScalarNode:
+			if topic := strings.TrimSpace(item.Value); topic != "" {
+				topics = append(topics, batchTopic{Topic: topic})
+			}
+		case yaml.MappingNode:
+			var t batchTopic
+			for i := 0; i+1 < len(item.Content); i += 2 {
+				key, value := item.Content[i].Value, item.Content[i+1]
+				if key == "topic" {
+					t.Topic = strings.TrimSpace(value.Value)
+					continue
+				}
+				values, err := optionValues(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s: %w", value.Line, key, err)
+				}
+				t.Options = append(t.Options, batchOption{Flag: key, Values: values})
+			}
+			if t.Topic == "" {
+				return nil, fmt.Errorf("line %d: an item without a topic", item.Line)
+			}
+			topics = append(topics, t)
+		default:
+			return nil, fmt.Errorf("line %d: expected a topic or a map with a topic", item.Line)
+		}
+	}
+	return topics, nil
+}
+
+// optionValues are the flag values of a topic option: a scalar as is, each
+// item of a list, and each key=value of a map (like --call-temperature's)
+func optionValues(n *yaml.Node) ([]string, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return []string{n.Value}, nil
+	case yaml.SequenceNode:
+		var values []string
+		for _, item := range n.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("expected a list of values")
+			}
+			values = append(values, item.Value)
+		}
+		return values, nil
+	case yaml.MappingNode:
+		var values []string
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			values = append(values, n.Content[i].Value+"="+n.Content[i+1].Value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("expected a value, a list, or a map")
+}
+
+// writeBatchIndex writes results/<time>_batch.md: a table of the batch's
+// topics with how each run went, a link to its report, and its job ID
+func writeBatchIndex(topics []batchTopic, runs []*batchRun, started time.Time) (string, error) {
+	if err := os.MkdirAll("results", 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join("results", started.Format("20060102_150405")+"_batch.md")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Research Batch %s\n\n", started.Format("2006-01-02 15:04"))
+	b.WriteString("| # | Topic | Status | Sources | Time | Report | Job ID |\n")
+	b.WriteString("|---|-------|--------|---------|------|--------|--------|\n")
+	for i, r := range runs {
+		status, sources, elapsed, report := "✅ complete", fmt.Sprint(r.sources), r.elapsed.Round(time.Second).String(), ""
+		switch {
+		case r.skipped:
+			status, sources, elapsed = "⏭️ skipped", "", ""
+		case r.err != nil:
+			status = "❌ " + strings.ReplaceAll(r.err.Error(), "|", `\|`)
+		}
+		if r.report != "" {
+			link := r.report
+			if rel, err := filepath.Rel(filepath.Dir(path), r.report); err == nil {
+				link = filepath.ToSlash(rel)
+			}
+			report = fmt.Sprintf("[%s](%s)", filepath.Base(r.report), strings.ReplaceAll(link, " ", "%20"))
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s | %s |\n", i+1, strings.ReplaceAll(topics[i].Topic, "|", `\|`), status, sources, elapsed, report, r.jobID)
+	}
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,559 @@
+// Package cli holds the deep-research commands. The deep-research binary and
+// the standalone server binary both run them, so they parse the same flags, env
+// vars, and config file.
+package cli
+
+import (
+	"context"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/logging"
+	"deep-research/pkg/proxy"
+	"deep-research/pkg/retry"
+	"deep-research/pkg/search"
+	"deep-research/pkg/server"
+	"deep-research/pkg/store"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Main runs the command args name (e.g. ["run", "--topic", "..."]) and exits
+// with status 1 if it fails
+func Main(args []string) {
+	root := newRootCmd()
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		fmt.Printf("\n❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// MainServe runs the serve command with args, for the standalone server binary
+func MainServe(args []string) {
+	Main(append([]string{"serve"}, args...))
+}
+
+func newRootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "deep-research",
+		Short:         "Local deep research agent (LM Studio/Ollama + SearXNG)",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfig(cmd)
+		},
+	}
+	root.PersistentFlags().String("config", "", "Config file of flag defaults and research profiles (default: ./deep-research.yaml, else ~/.config/deep-research/deep-research.yaml; env: DEEP_RESEARCH_CONFIG)")
+	root.PersistentFlags().String("db", getEnv("DB_PATH", filepath.Join("results", "deep-research.db")), "SQLite job database (env: DB_PATH)")
+	root.PersistentFlags().String("log-level", getEnv("LOG_LEVEL", "info"), "Research progress detail: debug (every query page and fetch), info, warn, or error (env: LOG_LEVEL)")
+	root.PersistentFlags().String("log-format", getEnv("LOG_FORMAT", logging.FormatText), "Research progress output: text or json lines (env: LOG_FORMAT)")
+
+	root.AddCommand(
+		newRunCmd(),
+		newServeCmd(),
+		newResumeCmd(),
+		newListCmd(),
+		newTagCmd(),
+		newCollectionsCmd(),
+		newExportCmd(),
+		newDiffCmd(),
+		newModelsCmd(),
+		newMCPCmd(),
+	)
+	// The standalone server binary called --searx-url --searxng-url
+	root.SetGlobalNormalizationFunc(func(fs *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "searxng-url" {
+			name = "searx-url"
+		}
+		return pflag.NormalizedName(name)
+	})
+	return root
+}
+
+// backendOptions holds the LLM and search flags shared by every command that talks to them.
+// Defaults come from the same env vars the web server has always used.
+type backendOptions struct {
+	lmURL            string
+	llmProvider      string
+	apiKey           string
+	model            string
+	embeddingModel   string
+	summarizerModel  string
+	summarizerURL    string
+	writerModel      string
+	writerURL        string
+	callModels       map[string]string // --call-model: a model per call type, "provider:model" for another provider
+	searxURL         string
+	engines          []string
+	braveAPIKey      string
+	googleAPIKey     string
+	googleCX         string
+	cacheDir         string
+	cacheTTL         time.Duration
+	llmCacheTTL      time.Duration
+	noCache          bool
+	fetchRate        float64
+	fetchBurst       int
+	fetchConcurrency int
+	summarizeWorkers int
+	planRepairs      int
+	roundDigestChars int
+	ignoreRobots     bool
+	noWayback        bool
+	noEnrich         bool
+	proxies          proxy.Settings
+	useMock          bool
+	renderJS         bool
+	browserPath      string
+	renderPool       int
+	renderTimeout    time.Duration
+	extractorsDir    string
+	geocoder         string // --geocoder: a Nominatim URL or a gazetteer file
+	contextLen       int
+	retries          int
+	retryBackoff     time.Duration
+	promptPrice      float64
+	completionPrice  float64
+	logger           *slog.Logger // Search fallbacks and cache failures (nil = console on stdout)
+}
+
+func (o *backendOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.lmURL, "lm-url", os.Getenv("LM_URL"), "LLM API base URL (default: the provider's local URL, WSL host aware; env: LM_URL)")
+	fs.StringVar(&o.llmProvider, "llm-provider", getEnv("LLM_PROVIDER", llm.ProviderLMStudio), "LLM backend: lmstudio (OpenAI-compatible), ollama, llamacpp (llama.cpp's native API), openai, azure, openrouter, or anthropic (env: LLM_PROVIDER)")
+	fs.StringVar(&o.apiKey, "api-key", os.Getenv("LLM_API_KEY"), "API key for openai, azure, openrouter, or anthropic (env: LLM_API_KEY, else OPENAI_API_KEY, AZURE_OPENAI_API_KEY, OPENROUTER_API_KEY, or ANTHROPIC_API_KEY)")
+	fs.StringVar(&o.model, "model", getEnv("LLM_MODEL", "local-model"), "Model name (optional for LM Studio; env: LLM_MODEL)")
+	fs.StringVar(&o.embeddingModel, "embedding-model", os.Getenv("EMBEDDING_MODEL"), "Embedding model for merging similar planned queries, near-duplicate page detection in deep mode, and for picking the findings of each report section, e.g. nomic-embed-text (env: EMBEDDING_MODEL)")
+	fs.StringVar(&o.summarizerModel, "summarizer-model", os.Getenv("SUMMARIZER_MODEL"), "Deep mode: smaller, faster model for per-page summaries (default: --model; env: SUMMARIZER_MODEL)")
+	fs.StringVar(&o.summarizerURL, "summarizer-url", os.Getenv("SUMMARIZER_URL"), "LLM API base URL serving --summarizer-model (default: --lm-url; env: SUMMARIZER_URL)")
+	fs.StringVar(&o.writerModel, "writer-model", os.Getenv("WRITER_MODEL"), "Larger model for planning and the final report (default: --model; env: WRITER_MODEL)")
+	fs.StringVar(&o.writerURL, "writer-url", os.Getenv("WRITER_URL"), "LLM API base URL serving --writer-model (default: --lm-url; env: WRITER_URL)")
+	fs.StringToStringVar(&o.callModels, "call-model", getEnvMap("CALL_MODELS"), "Model per call type (planning, summarization, compression, report), e.g. report=anthropic:claude-sonnet-4-5 to write the report with Claude while the rest stays local; a provider other than --llm-provider is reached at its default URL with the key in its env var, e.g. ANTHROPIC_API_KEY (env: CALL_MODELS)")
+	fs.StringVar(&o.searxURL, "searx-url", getEnv("SEARX_URL", "http://localhost:8080"), "SearXNG base URL, or several comma-separated ones to rotate searches across, skipping any that rate-limits for 5 minutes (env: SEARX_URL)")
+	fs.StringSliceVar(&o.engines, "engines", strings.Split(getEnv("SEARCH_ENGINES", search.EngineSearXNG), ","), "Search engines to aggregate: searxng, brave, duckduckgo, google (env: SEARCH_ENGINES)")
+	fs.StringVar(&o.braveAPIKey, "brave-api-key", os.Getenv("BRAVE_API_KEY"), "Brave Search API key for the brave engine (env: BRAVE_API_KEY)")
+	fs.StringVar(&o.googleAPIKey, "google-api-key", os.Getenv("GOOGLE_API_KEY"), "Google Custom Search API key for the google engine (env: GOOGLE_API_KEY)")
+	fs.StringVar(&o.googleCX, "google-cx", os.Getenv("GOOGLE_CX"), "Google Programmable Search Engine ID (cx) for the google engine (env: GOOGLE_CX)")
+	fs.StringVar(&o.cacheDir, "cache-dir", getEnv("SEARCH_CACHE_DIR", filepath.Join("results", "cache")), "Disk cache for search results and fetched pages (env: SEARCH_CACHE_DIR)")
+	fs.Float64Var(&o.fetchRate, "fetch-rate", getEnvFloat("FETCH_RATE", 1), "Deep mode: page fetches per second allowed to each host; 0 = unlimited (env: FETCH_RATE)")
+	fs.IntVar(&o.fetchBurst, "fetch-burst", getEnvInt("FETCH_BURST", 1), "Deep mode: fetches a host may get back-to-back before --fetch-rate applies (env: FETCH_BURST)")
+	fs.IntVar(&o.fetchConcurrency, "fetch-concurrency", getEnvInt("FETCH_CONCURRENCY", 8), "Deep mode: max page fetches in flight across all hosts and queries; 0 = unlimited (env: FETCH_CONCURRENCY)")
+	fs.IntVar(&o.summarizeWorkers, "summarize-workers", getEnvInt("SUMMARIZE_WORKERS", agent.DefaultSummarizeWorkers), "Deep mode: max page summaries and record extractions sent to the LLM at once, across all queries; 0 = unlimited (env: SUMMARIZE_WORKERS)")
+	fs.IntVar(&o.planRepairs, "plan-repairs", getEnvInt("PLAN_REPAIRS", llm.JSONRepairs), "Times a plan (or other planning call) that isn't valid JSON is sent back to the model with the parse error for correction before the run fails; 0 = none (env: PLAN_REPAIRS)")
+	fs.IntVar(&o.roundDigestChars, "round-digest-chars", getEnvInt("ROUND_DIGEST_CHARS", agent.DefaultRoundDigestChars), "Exhaustive mode: a round's results longer than this enter the research context as a digest of at most this many characters, written by the summarizer; the raw results stay in the findings log. 0 = keep the raw results (env: ROUND_DIGEST_CHARS)")
+	fs.BoolVar(&o.ignoreRobots, "ignore-robots", false, "Deep mode: fetch pages even where a site's robots.txt disallows them, and ignore its Crawl-delay")
+	fs.BoolVar(&o.noWayback, "no-wayback", false, "Deep mode: don't read the Wayback Machine's snapshot of pages that answer 403, 404, or 410")
+	fs.BoolVar(&o.noEnrich, "no-enrich-snippets", false, "Don't fill in search results' short or missing snippets from their pages' OpenGraph and meta descriptions")
+	fs.DurationVar(&o.cacheTTL, "cache-ttl", getEnvDuration("SEARCH_CACHE_TTL", 24*time.Hour), "How long cached searches and pages are reused; 0 disables the cache (env: SEARCH_CACHE_TTL)")
+	fs.DurationVar(&o.llmCacheTTL, "llm-cache-ttl", getEnvDuration("LLM_CACHE_TTL", llm.DefaultCacheTTL), "How long cached LLM responses (in <cache-dir>/llm) are reused for identical prompts; 0 disables the LLM cache (env: LLM_CACHE_TTL)")
+	fs.BoolVar(&o.noCache, "no-cache", false, "Bypass the search, page, and LLM caches: every request goes to the backends and nothing is stored")
+	fs.StringVar(&o.proxies.Global, "proxy", "", "Proxy for every backend without its own: an http://, https://, or socks5:// URL, a comma-separated list to rotate through per request, or \"direct\" (default: HTTPS_PROXY/HTTP_PROXY/NO_PROXY from the environment)")
+	fs.StringVar(&o.proxies.Search, "search-proxy", os.Getenv("SEARCH_PROXY"), "Proxy for search engine APIs such as SearXNG (default: --proxy; env: SEARCH_PROXY)")
+	fs.StringVar(&o.proxies.Fetch, "fetch-proxy", os.Getenv("FETCH_PROXY"), "Deep mode: proxy for page fetches, robots.txt, and Wayback lookups; a list rotates to spread fetches over several IPs (default: --proxy; env: FETCH_PROXY)")
+	fs.StringVar(&o.proxies.LLM, "llm-proxy", os.Getenv("LLM_PROXY"), "Proxy for the LLM servers (default: --proxy; env: LLM_PROXY)")
+	fs.StringVar(&o.geocoder, "geocoder", os.Getenv("GEOCODER"), "Geocoder for --geo-area: the URL of a Nominatim server, or a gazetteer file for offline geocoding (a GeoNames cities or postal code dump, or name,lat,lon CSV) (default: OpenStreetMap's Nominatim; env: GEOCODER)")
+	fs.Float64Var(&o.promptPrice, "prompt-price", getEnvFloat("LLM_PROMPT_PRICE", 0), "USD per million prompt tokens, to report what a run cost; 0 = not priced (env: LLM_PROMPT_PRICE)")
+	fs.Float64Var(&o.completionPrice, "completion-price", getEnvFloat("LLM_COMPLETION_PRICE", 0), "USD per million completion tokens (env: LLM_COMPLETION_PRICE)")
+	fs.IntVar(&o.contextLen, "ctx", 32768, "Context length for LLM (serve: for requests that don't set contextLen)")
+	fs.IntVar(&o.retries, "retries", 3, "Attempts per LLM/search request before giving up (1 = no retries)")
+	fs.DurationVar(&o.retryBackoff, "retry-backoff", time.Second, "Initial backoff between retries (doubles each attempt, with jitter)")
+}
+
+// addClientFlags registers the flags that only apply to searches run in-process (not used by serve)
+func (o *backendOptions) addClientFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.useMock, "mock", false, "Use mock search (for testing without SearXNG)")
+}
+
+// addFetchFlags registers the page-fetching flags of the commands that research
+func (o *backendOptions) addFetchFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.renderJS, "render-js", false, "Deep mode: render pages in headless Chrome/Chromium so JavaScript-built content is fetched")
+	fs.StringVar(&o.browserPath, "browser-path", os.Getenv("CHROME_PATH"), "Chrome/Chromium executable for --render-js (default: found on PATH; env: CHROME_PATH)")
+	fs.IntVar(&o.renderPool, "render-pool", 2, "Max browser instances rendering pages at once with --render-js")
+	fs.DurationVar(&o.renderTimeout, "render-timeout", 30*time.Second, "Per-page timeout for --render-js")
+	fs.StringVar(&o.extractorsDir, "extractors-dir", getEnv("EXTRACTORS_DIR", search.DefaultExtractorsDir), "Deep mode: directory of YAML site rules picking listing links, titles, prices, and fields on specific sites (env: EXTRACTORS_DIR)")
+}
+
+// baseURL returns the configured LLM URL or the provider's default
+func (o *backendOptions) baseURL() string {
+	if o.lmURL != "" {
+		return o.lmURL
+	}
+	return llm.DefaultBaseURL(o.llmProvider)
+}
+
+func (o *backendOptions) retryPolicy() retry.Policy {
+	policy := retry.DefaultPolicy()
+	policy.MaxAttempts = o.retries
+	policy.InitialBackoff = o.retryBackoff
+	if o.retries <= 1 {
+		policy.ThrottledAttempts = 1 // No retries means none for throttling either
+	} else {
+		policy.ThrottledAttempts = max(policy.ThrottledAttempts, o.retries)
+	}
+	return policy
+}
+
+// llmAPIKey returns --api-key or, if unset, the provider's conventional key env var
+func (o *backendOptions) llmAPIKey() string {
+	if o.apiKey != "" {
+		return o.apiKey
+	}
+	if env := llm.APIKeyEnv(o.llmProvider); env != "" {
+		return os.Getenv(env)
+	}
+	return ""
+}
+
+// checkModel rejects the local placeholder model name for hosted providers, which need a real one
+func (o *backendOptions) checkModel() error {
+	if llm.IsCloud(o.llmProvider) && (o.model == "" || o.model == "local-model") {
+		return fmt.Errorf("--model is required with --llm-provider %s (see `deep-research models`)", o.llmProvider)
+	}
+	return nil
+}
+
+// llmConfig returns the client settings for the configured provider
+func (o *backendOptions) llmConfig() (llm.Config, error) {
+	transport, err := proxy.Transport(o.proxies.Resolve().LLM)
+	if err != nil {
+		return llm.Config{}, fmt.Errorf("invalid --llm-proxy: %w", err)
+	}
+	return llm.Config{
+		BaseURL:        o.baseURL(),
+		APIKey:         o.llmAPIKey(),
+		Model:          o.model,
+		EmbeddingModel: o.embeddingModel,
+		Temperature:    0.0,
+		ContextLength:  o.contextLen,
+		Timeout:        5 * time.Minute, // Long timeout for reasoning
+		Retry:          o.retryPolicy(),
+		Transport:      transport,
+	}, nil
+}
+
+// newLLM creates the configured LLM provider
+func (o *backendOptions) newLLM() (llm.Provider, error) {
+	if err := o.checkModel(); err != nil {
+		return nil, err
+	}
+	cfg, err := o.llmConfig()
+	if err != nil {
+		return nil, err
+	}
+	if o.lmURL == "" && !llm.IsCloud(o.llmProvider) && llm.IsWSL() {
+		fmt.Printf("🐧 Detected WSL. Defaulting LLM URL to host: %s\n", cfg.BaseURL)
+		fmt.Println("⚠️  Ensure the LLM server is listening on 0.0.0.0 (LM Studio: Settings -> Local Server -> Network Support)")
+	}
+
+	client, err := llm.NewProvider(o.llmProvider, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if o.proxies.Explicit() {
+		fmt.Printf("🔀 LLM requests via %s\n", proxy.Describe(o.proxies.Resolve().LLM))
+	}
+	if o.llmProvider == llm.ProviderOllama {
+		fmt.Printf("🦙 Using Ollama at %s\n", cfg.BaseURL)
+	} else if llm.IsCloud(o.llmProvider) {
+		fmt.Printf("☁️ Using %s model %s\n", o.llmProvider, o.model)
+	}
+	o.probeModel(client)
+	if o.summarizerModel != "" || o.summarizerURL != "" {
+		fmt.Printf("📄 Page summaries: %s\n", modelLabel(o.summarizerModel, o.summarizerURL, o.model))
+	}
+	if o.writerModel != "" || o.writerURL != "" {
+		fmt.Printf("✍️ Plan and report: %s\n", modelLabel(o.writerModel, o.writerURL, o.model))
+	}
+
+	if o.llmCacheTTL > 0 && o.cacheDir != "" && !o.noCache {
+		fmt.Printf("🗄️ Caching LLM responses in %s (TTL %s)\n", filepath.Join(o.cacheDir, "llm"), o.llmCacheTTL)
+	}
+	return o.cached(client), nil
+}
+
+// modelProbeTimeout bounds the startup check of the LLM server's models
+const modelProbeTimeout = 10 * time.Second
+
+// probeModel asks the LLM server for its models and warns when --model isn't
+// among them or --ctx exceeds its context length. It only warns: a server that
+// can't be reached fails the run on its first call anyway.
+func (o *backendOptions) probeModel(client llm.Provider) {
+	ctx, cancel := context.WithTimeout(context.Background(), modelProbeTimeout)
+	defer cancel()
+	status, err := llm.InspectModel(ctx, client, o.model)
+	if err != nil {
+		fmt.Printf("⚠️ Could not list the LLM server's models: %v\n", err)
+		return
+	}
+	if status.Found && status.ContextLength > 0 {
+		fmt.Printf("🧠 Model %s: context length %d tokens\n", status.ID, status.ContextLength)
+	}
+	for _, warning := range status.Warnings(o.contextLen) {
+		fmt.Printf("⚠️ %s\n", warning)
+	}
+}
+
+// callProviders creates the providers of --call-model (nil without any)
+func (o *backendOptions) callProviders() (map[string]llm.Provider, error) {
+	if len(o.callModels) == 0 {
+		return nil, nil
+	}
+	cfg, err := o.llmConfig()
+	if err != nil {
+		return nil, err
+	}
+	providers, err := agent.NewCallProviders(o.llmProvider, cfg, o.callModels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --call-model: %w", err)
+	}
+	for _, callType := range agent.CallTypes {
+		if p, ok := providers[callType]; ok {
+			fmt.Printf("🎯 %s calls: %s\n", callType, o.callModels[callType])
+			providers[callType] = o.cached(p)
+		}
+	}
+	return providers, nil
+}
+
+// cached wraps client in the LLM response cache, unless it is off
+func (o *backendOptions) cached(client llm.Provider) llm.Provider {
+	if o.llmCacheTTL > 0 && o.cacheDir != "" && !o.noCache {
+		return llm.NewCachedProvider(client, llm.CacheConfig{Dir: filepath.Join(o.cacheDir, "llm"), TTL: o.llmCacheTTL, Logger: o.logger})
+	}
+	return client
+}
+
+// modelLabel describes a role's model override for startup output
+func modelLabel(model, baseURL, defaultModel string) string {
+	if model == "" {
+		model = defaultModel
+	}
+	if baseURL == "" {
+		return model
+	}
+	return fmt.Sprintf("%s at %s", model, baseURL)
+}
+
+// rateLimit returns the page-fetch limits
+func (o *backendOptions) rateLimit() search.RateLimitConfig {
+	return search.RateLimitConfig{PerHost: o.fetchRate, Burst: o.fetchBurst, Concurrency: o.fetchConcurrency}
+}
+
+// jobSetup returns the backend's part of a research job's setup; the caller
+// adds what it prepared for the job
+func (o *backendOptions) jobSetup(callProviders map[string]llm.Provider) server.JobSetup {
+	return server.JobSetup{
+		EmbeddingModel:   o.embeddingModel,
+		SummarizerModel:  o.summarizerModel,
+		SummarizerURL:    o.summarizerURL,
+		WriterModel:      o.writerModel,
+		WriterURL:        o.writerURL,
+		CallProviders:    callProviders,
+		FetchWorkers:     o.fetchConcurrency,
+		SummarizeWorkers: o.summarizeWorkers,
+		PlanRepairs:      o.planRepairs,
+		RoundDigestChars: o.roundDigestChars,
+		PromptPrice:      o.promptPrice,
+		CompletionPrice:  o.completionPrice,
+		Logger:           o.logger,
+	}
+}
+
+// browserConfig returns the page-rendering settings (nil without --render-js)
+func (o *backendOptions) browserConfig() (*search.BrowserConfig, error) {
+	if !o.renderJS {
+		return nil, nil
+	}
+	fetchProxy, err := proxy.Func(o.proxies.Resolve().Fetch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --fetch-proxy: %w", err)
+	}
+	return &search.BrowserConfig{
+		ExecPath: o.browserPath,
+		PoolSize: o.renderPool,
+		Timeout:  o.renderTimeout,
+		Proxy:    fetchProxy,
+		Logger:   o.logger,
+	}, nil
+}
+
+// newSearcher creates the configured search engines (or the mock engine with --mock)
+func (o *backendOptions) newSearcher() (search.Searcher, error) {
+	if o.useMock {
+		fmt.Println("⚠️ Using Mock Search Engine")
+		return &search.MockClient{}, nil
+	}
+	proxies := o.proxies.Resolve()
+	searchTransport, err := proxy.Transport(proxies.Search)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --search-proxy: %w", err)
+	}
+	fetchTransport, err := proxy.Transport(proxies.Fetch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --fetch-proxy: %w", err)
+	}
+	browser, err := o.browserConfig()
+	if err != nil {
+		return nil, err
+	}
+	extractors, err := search.LoadExtractors(o.extractorsDir)
+	if err != nil {
+		return nil, err
+	}
+	cache := search.CacheConfig{Dir: o.cacheDir, TTL: o.cacheTTL, Logger: o.logger}
+	if o.noCache {
+		cache = search.CacheConfig{}
+	}
+
+	if o.proxies.Explicit() {
+		fmt.Printf("🔀 Searches via %s, pages via %s\n", proxy.Describe(proxies.Search), proxy.Describe(proxies.Fetch))
+	}
+	if len(o.engines) == 1 && o.engines[0] == search.EngineSearXNG {
+		if instances := search.SearXNGInstances(o.searxURL); len(instances) > 1 {
+			fmt.Printf("🔎 Rotating across %d SearXNG instances: %s\n", len(instances), strings.Join(instances, ", "))
+		} else {
+			fmt.Printf("🔎 Using SearXNG at %s\n", o.searxURL)
+		}
+	} else {
+		fmt.Printf("🔎 Using search engines: %s\n", strings.Join(o.engines, ", "))
+	}
+	if len(extractors) > 0 {
+		names := make([]string, len(extractors))
+		for i, e := range extractors {
+			names[i] = e.Name
+		}
+		fmt.Printf("🧩 Site rules: %s\n", strings.Join(names, ", "))
+	}
+	if o.ignoreRobots {
+		fmt.Println("⚠️ Ignoring robots.txt")
+	}
+	if cache.TTL > 0 && cache.Dir != "" {
+		fmt.Printf("🗄️ Caching searches and pages in %s (TTL %s)\n", cache.Dir, cache.TTL)
+	}
+
+	// A browser that can't start is logged, and pages are fetched over plain HTTP
+	return search.NewStack(search.StackConfig{
+		Engines: o.engines,
+		Search: search.Config{
+			SearXURL:       o.searxURL,
+			BraveAPIKey:    o.braveAPIKey,
+			GoogleKey:      o.googleAPIKey,
+			GoogleCX:       o.googleCX,
+			Retry:          o.retryPolicy(),
+			Transport:      searchTransport,
+			FetchTransport: fetchTransport,
+			Logger:         o.logger,
+			Extractors:     extractors,
+		},
+		Browser:      browser,
+		RateLimit:    o.rateLimit(),
+		IgnoreRobots: o.ignoreRobots,
+		NoEnrich:     o.noEnrich,
+		Cache:        cache,
+		NoWayback:    o.noWayback,
+	})
+}
+
+// newLogger builds the research progress logger from the --log-level and --log-format flags
+func newLogger(cmd *cobra.Command) (*slog.Logger, error) {
+	levelName, _ := cmd.Flags().GetString("log-level")
+	format, _ := cmd.Flags().GetString("log-format")
+	level, err := logging.ParseLevel(levelName)
+	if err != nil {
+		return nil, err
+	}
+	return logging.New(logging.Stdout, level, format)
+}
+
+// openStore opens the job database from the --db flag; jobs simply aren't recorded if it fails
+func openStore(cmd *cobra.Command) *store.Store {
+	dbPath, _ := cmd.Flags().GetString("db")
+	if dbPath == "" {
+		return nil
+	}
+	jobStore, err := store.Open(dbPath)
+	if err != nil {
+		fmt.Printf("⚠️ Job history disabled: %v\n", err)
+		return nil
+	}
+	return jobStore
+}
+
+// saveJob records a job in the database if one is open
+func saveJob(jobStore *store.Store, job store.Job) {
+	if jobStore == nil {
+		return
+	}
+	if err := jobStore.SaveJob(job); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+}
+
+// sanitizeFilename removes or replaces characters that are not safe for filenames
+func sanitizeFilename(s string) string {
+	// Replace spaces with underscores
+	s = strings.ReplaceAll(s, " ", "_")
+	// Remove any character that's not alphanumeric, underscore, or hyphen
+	reg := regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	s = reg.ReplaceAllString(s, "")
+	return strings.ToLower(s)
+}
+
+// safeTopicName returns a filename-safe, length-capped version of the topic
+func safeTopicName(topic string) string {
+	safeTopic := sanitizeFilename(topic)
+	if len(safeTopic) > 50 {
+		safeTopic = safeTopic[:50]
+	}
+	return safeTopic
+}
+
+func getEnv(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return defaultVal
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return defaultVal
+}
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return f
+	}
+	return defaultVal
+}
+
+// getEnvMap reads comma-separated key=value pairs, e.g. "report=anthropic:claude-sonnet-4-5" (nil when unset)
+func getEnvMap(key string) map[string]string {
+	var m map[string]string
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			if m == nil {
+				m = make(map[string]string)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
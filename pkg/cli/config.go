@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"deep-research/pkg/config"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// settings is the loaded config file (empty when there is none)
+var settings = &config.File{}
+
+// envInUsage and envName find the env vars named in a flag's help, e.g. "(env: LM_URL)"
+var (
+	envInUsage = regexp.MustCompile(`\(env: ([^)]*)\)`)
+	envName    = regexp.MustCompile(`[A-Z][A-Z0-9_]+`)
+)
+
+// loadConfig reads the config file and fills in the flags of cmd the command line
+// left unset. Flags beat their env vars, which beat the file. Flags set from the
+// file don't count as given, so a --profile still replaces those defaults.
+func loadConfig(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("config")
+	file, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if file.Path == "" {
+		return nil
+	}
+
+	if unknown := file.Unknown(knownFlags(cmd.Root())); len(unknown) > 0 {
+		return fmt.Errorf("%s: unknown settings %s (settings are flag names, e.g. lm-url)", file.Path, strings.Join(unknown, ", "))
+	}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		value, ok := file.Get(f.Name)
+		if !ok || f.Changed || f.Name == "config" || err != nil {
+			return
+		}
+		if m := envInUsage.FindStringSubmatch(f.Usage); m != nil {
+			for _, env := range envName.FindAllString(m[1], -1) {
+				if os.Getenv(env) != "" {
+					return
+				}
+			}
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("%s: invalid %s: %w", file.Path, f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	settings = file
+	return nil
+}
+
+// knownFlags returns the names of the flags of cmd and all its subcommands
+func knownFlags(cmd *cobra.Command) map[string]bool {
+	known := make(map[string]bool)
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		c.Flags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		c.PersistentFlags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		for _, sub := range c.Commands() {
+			visit(sub)
+		}
+	}
+	visit(cmd)
+	return known
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// TestConfigSetsEveryFlag checks that every flag of every command, serve's
+// included, can be set from the config file, so an option added to one command
+// can't be missing from the file or from the other binary
+func TestConfigSetsEveryFlag(t *testing.T) {
+	for _, cmd := range newRootCmd().Commands() {
+		t.Run(cmd.Name(), func(t *testing.T) {
+			var lines []string
+			cmd.Flags().VisitAll(func(f *pflag.Flag) {
+				if f.Name == "help" {
+					return
+				}
+				value := f.DefValue
+				switch f.Value.Type() {
+				case "stringSlice", "stringArray":
+					value = ""
+				case "stringToString":
+					value = "search=x"
+				case "stringToInt":
+					value = "search=1"
+				}
+				lines = append(lines, fmt.Sprintf("%s: %q", f.Name, value))
+			})
+			if err := runWithConfig(t, cmd.Name(), strings.Join(lines, "\n"), nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestConfigServeSettings(t *testing.T) {
+	t.Setenv("SEARXNG_URL", "")
+	var got *cobra.Command
+	yaml := "ctx: 8192\nrender-js: true\nretries: 5\nsearx-url: http://file.example\n"
+	if err := runWithConfig(t, "serve", yaml, func(cmd *cobra.Command) { got = cmd }, "--retries", "2"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"ctx":       "8192",
+		"render-js": "true",
+		"retries":   "2", // The command line beats the file
+		"searx-url": "http://file.example",
+	} {
+		if value := got.Flags().Lookup(name).Value.String(); value != want {
+			t.Errorf("--%s = %q, want %q", name, value, want)
+		}
+	}
+}
+
+// runWithConfig runs the command name of a new root with a config file of yaml
+// and the extra args, calling ran with the command instead of its RunE
+func runWithConfig(t *testing.T, name, yaml string, ran func(*cobra.Command), args ...string) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "deep-research.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := newRootCmd()
+	for _, cmd := range root.Commands() {
+		cmd.Args = cobra.ArbitraryArgs
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if ran != nil {
+				ran(cmd)
+			}
+			return nil
+		}
+		cmd.PreRunE = nil
+	}
+	root.SetArgs(append([]string{name, "--config", path}, args...))
+	return root.Execute()
+}
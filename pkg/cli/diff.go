@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/server"
+	"deep-research/pkg/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newDiffCmd() *cobra.Command {
+	var backend backendOptions
+	var outputFile, format string
+	var noSummary bool
+	cmd := &cobra.Command{
+		Use:   "diff <earlier result> <later result>",
+		Short: "Compare two research runs: new and removed sources, changed values, and what's new",
+		Long: `Compare two research runs on the same topic. Each result is a job id from the
+database (see ` + "`deep-research list`" + `) or a JSON file written by ` + "`run --json`" + ` or
+` + "`export --format json`" + `. The diff lists the sources only one run found, the
+extracted values that changed on pages both runs read (e.g. a listing's price),
+and an LLM-written summary of what's new.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "md" && format != "json" {
+				return fmt.Errorf("unknown format %q (use md or json)", format)
+			}
+			// Without --output stdout carries the diff; everything printed along the way goes to stderr
+			out := os.Stdout
+			if outputFile == "" {
+				os.Stdout = os.Stderr
+			}
+			earlierTopic, earlier, err := loadResult(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			topic, later, err := loadResult(cmd, args[1])
+			if err != nil {
+				return err
+			}
+			if topic == "" {
+				topic = earlierTopic
+			} else if earlierTopic != "" && earlierTopic != topic {
+				fmt.Printf("⚠️ The runs have different topics: %q and %q\n", earlierTopic, topic)
+			}
+
+			var diff agent.ResultDiff
+			if noSummary {
+				diff = agent.DiffResults(earlier, later)
+			} else {
+				if backend.logger, err = newLogger(cmd); err != nil {
+					return err
+				}
+				llmClient, err := backend.newLLM()
+				if err != nil {
+					return err
+				}
+				callProviders, err := backend.callProviders()
+				if err != nil {
+					return err
+				}
+				req := server.ResearchRequest{ContextLen: backend.contextLen}
+				researcher := agent.NewDeepResearcher(llmClient, nil, req.AgentConfig(backend.jobSetup(callProviders)))
+				if diff, err = researcher.Diff(context.Background(), topic, earlier, later); err != nil {
+					fmt.Printf("⚠️ %v; listing the differences only\n", err)
+				}
+			}
+
+			var data []byte
+			if format == "json" {
+				if data, err = json.MarshalIndent(diff, "", "  "); err != nil {
+					return err
+				}
+			} else {
+				data = []byte(diff.Markdown(topic))
+			}
+			if outputFile == "" {
+				_, err = out.Write(data)
+				return err
+			}
+			if err := os.WriteFile(outputFile, data, 0644); err != nil {
+				return fmt.Errorf("could not write to file: %w", err)
+			}
+			fmt.Printf("📄 Diff saved to: %s\n", outputFile)
+			return nil
+		},
+	}
+	backend.addFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (default: stdout)")
+	cmd.Flags().StringVarP(&format, "format", "f", "md", "Output format: md or json")
+	cmd.Flags().BoolVar(&noSummary, "no-summary", false, "Only list the differences; don't ask the LLM to summarize what's new")
+	return cmd
+}
+
+// loadResult reads a research result and its topic from a JSON file (a result
+// from `run --json`, or a job from `export --format json`) or, when arg is no
+// file, the job database
+func loadResult(cmd *cobra.Command, arg string) (string, agent.ResearchResult, error) {
+	if data, err := os.ReadFile(arg); err == nil {
+		var job store.Job
+		if err := json.Unmarshal(data, &job); err == nil && job.Result != nil {
+			return job.Topic, *job.Result, nil
+		}
+		var result agent.ResearchResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return "", agent.ResearchResult{}, fmt.Errorf("%s: not a research result: %w", arg, err)
+		}
+		return "", result, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", agent.ResearchResult{}, err
+	}
+
+	jobStore, err := openJobStore(cmd)
+	if err != nil {
+		return "", agent.ResearchResult{}, err
+	}
+	defer jobStore.Close()
+	job, err := jobStore.GetJob(arg)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", agent.ResearchResult{}, fmt.Errorf("%q is neither a file nor a job (see `deep-research list`)", arg)
+	}
+	if err != nil {
+		return "", agent.ResearchResult{}, err
+	}
+	if job.Result == nil {
+		return "", agent.ResearchResult{}, fmt.Errorf("job %q has no result (status: %s)", job.ID, job.Status)
+	}
+	return job.Topic, *job.Result, nil
+}
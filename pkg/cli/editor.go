@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bufio"
+	"deep-research/pkg/agent"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// queriesFileHeader explains the file opened by editQueries
+const queriesFileHeader = `# Search queries for this research plan, one per line.
+# Delete lines to drop queries, edit them, or add new ones; save and close the
+# editor to continue. Blank lines and lines starting with # are ignored.
+`
+
+// editQueries opens the queries in $VISUAL or $EDITOR (vi, or notepad on
+// Windows, when neither is set) and returns the edited list
+func editQueries(queries []string) ([]string, error) {
+	f, err := os.CreateTemp("", "deep-research-queries-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queries file: %w", err)
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	_, err = f.WriteString(queriesFileHeader + "\n" + strings.Join(queries, "\n") + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write queries file: %w", err)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	edited, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries file: %w", err)
+	}
+	defer edited.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(edited)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read queries file: %w", err)
+	}
+	return agent.CleanQueries(lines), nil
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"deep-research/pkg/report"
+	"deep-research/pkg/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newListCmd() *cobra.Command {
+	var filter store.JobFilter
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List research jobs recorded in the database",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobStore, err := openJobStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer jobStore.Close()
+
+			jobs, err := jobStore.ListJobs(filter)
+			if err != nil {
+				return err
+			}
+			if len(jobs) == 0 {
+				if filter != (store.JobFilter{}) {
+					fmt.Println("No matching jobs.")
+				} else {
+					fmt.Println("No jobs yet.")
+				}
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tSTATUS\tSOURCES\tSTARTED\tDURATION\tTAGS\tTOPIC")
+			for _, j := range jobs {
+				duration := time.Duration(j.DurationSeconds) * time.Second
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", j.ID, j.Status, j.SourceCount, j.StartedAt.Local().Format("2006-01-02 15:04"), duration, strings.Join(j.Tags, ","), j.Topic)
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&filter.Query, "query", "", "Only jobs whose topic, tags, or report contain all of these words")
+	cmd.Flags().StringVar(&filter.Tag, "tag", "", "Only jobs with this tag")
+	return cmd
+}
+
+func newTagCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tag <job id> [tag...]",
+		Short: "Set the tags of a recorded job (no tags clears them)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobStore, err := openJobStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer jobStore.Close()
+
+			tags, err := jobStore.SetTags(args[0], args[1:])
+			if errors.Is(err, store.ErrNotFound) {
+				return fmt.Errorf("job %s not found", args[0])
+			}
+			if err != nil {
+				return err
+			}
+			if len(tags) == 0 {
+				fmt.Printf("🏷️ Cleared the tags of %s\n", args[0])
+			} else {
+				fmt.Printf("🏷️ Tagged %s: %s\n", args[0], strings.Join(tags, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+func newCollectionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "collections",
+		Short: "List the topic collections (--collection) recorded in the database",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobStore, err := openJobStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer jobStore.Close()
+
+			collections, err := jobStore.ListCollections()
+			if err != nil {
+				return err
+			}
+			if len(collections) == 0 {
+				fmt.Println("No collections yet.")
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tSOURCES\tLAST RUN")
+			for _, c := range collections {
+				fmt.Fprintf(tw, "%s\t%d\t%s\n", c.Name, c.SourceCount, c.LastRun.Local().Format("2006-01-02 15:04"))
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func newExportCmd() *cobra.Command {
+	var outputFile, format string
+	cmd := &cobra.Command{
+		Use:   "export <job id>",
+		Short: "Export a finished job's report from the database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobStore, err := openJobStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer jobStore.Close()
+
+			job, err := jobStore.GetJob(args[0])
+			if errors.Is(err, store.ErrNotFound) {
+				return fmt.Errorf("job %q not found (see `deep-research list`)", args[0])
+			}
+			if err != nil {
+				return err
+			}
+			if job.Result == nil {
+				return fmt.Errorf("job %q has no report (status: %s)", job.ID, job.Status)
+			}
+
+			var data []byte
+			if format == "json" {
+				if data, err = json.MarshalIndent(job, "", "  "); err != nil {
+					return err
+				}
+			} else {
+				reportFormat, err := report.ParseFormat(format)
+				if err != nil {
+					return fmt.Errorf("unknown format %q (use md, html, pdf, csv, xlsx, dot, graphml, or json)", format)
+				}
+				if data, err = report.Render(reportFormat, job.Topic, *job.Result); err != nil {
+					return err
+				}
+			}
+
+			if outputFile == "" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.WriteFile(outputFile, data, 0644); err != nil {
+				return fmt.Errorf("could not write to file: %w", err)
+			}
+			fmt.Printf("📄 Report saved to: %s\n", outputFile)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (default: stdout)")
+	cmd.Flags().StringVarP(&format, "format", "f", "md", "Export format: md, html, pdf, csv, xlsx, dot, graphml, or json")
+	return cmd
+}
+
+// openJobStore opens the job database from the --db flag, failing if it can't
+func openJobStore(cmd *cobra.Command) (*store.Store, error) {
+	dbPath, _ := cmd.Flags().GetString("db")
+	if dbPath == "" {
+		return nil, fmt.Errorf("no job database configured (--db)")
+	}
+	return store.Open(dbPath)
+}
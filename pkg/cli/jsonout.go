@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"deep-research/pkg/agent"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// eventWriter writes progress events as NDJSON, one event per line (safe for
+// concurrent use)
+type eventWriter struct {
+	mu     sync.Mutex
+	events *json.Encoder
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{events: json.NewEncoder(w)}
+}
+
+// progress writes a progress event line
+func (w *eventWriter) progress(event agent.ProgressEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events.Encode(event)
+}
+
+// fail reports an error as a final "error" progress event
+func (w *eventWriter) fail(err error) {
+	w.progress(agent.ProgressEvent{Phase: "error", Message: err.Error()})
+}
+
+// jsonOutput implements --json: the console output printed throughout the agent
+// is discarded, progress events go to stderr as NDJSON (one event per line), and
+// the final result goes to stdout as a single JSON document
+type jsonOutput struct {
+	*eventWriter
+	stdout *os.File // The real stdout; os.Stdout points at the null device
+}
+
+// newJSONOutput silences os.Stdout for the rest of the process
+func newJSONOutput() (*jsonOutput, error) {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	out := &jsonOutput{eventWriter: newEventWriter(os.Stderr), stdout: os.Stdout}
+	os.Stdout = null
+	return out, nil
+}
+
+// result writes the research result to stdout
+func (o *jsonOutput) result(result agent.ResearchResult) error {
+	return json.NewEncoder(o.stdout).Encode(result)
+}
+
+// progressOutput implements --progress-out: progress events go as NDJSON to a
+// file or an inherited file descriptor while the console output stays as is
+type progressOutput struct {
+	*eventWriter
+	file *os.File
+}
+
+// openProgressOutput opens target: "fd:N" for file descriptor N (e.g. "fd:3",
+// opened by the calling script), "-" for stderr, else a file path, created or
+// truncated
+func openProgressOutput(target string) (*progressOutput, error) {
+	var file *os.File
+	switch {
+	case target == "-":
+		file = os.Stderr
+	case strings.HasPrefix(target, "fd:"):
+		fd, err := strconv.Atoi(strings.TrimPrefix(target, "fd:"))
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid --progress-out %q: expected fd:N", target)
+		}
+		file = os.NewFile(uintptr(fd), target)
+		if _, err := file.Stat(); err != nil {
+			return nil, fmt.Errorf("invalid --progress-out %q: file descriptor %d isn't open", target, fd)
+		}
+	default:
+		var err error
+		if file, err = os.Create(target); err != nil {
+			return nil, fmt.Errorf("failed to open progress output: %w", err)
+		}
+	}
+	return &progressOutput{eventWriter: newEventWriter(file), file: file}, nil
+}
+
+// Close closes the file; stderr and file descriptors are left to the caller
+func (o *progressOutput) Close() error {
+	if o.file == os.Stderr || strings.HasPrefix(o.file.Name(), "fd:") {
+		return nil
+	}
+	return o.file.Close()
+}
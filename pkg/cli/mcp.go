@@ -0,0 +1,572 @@
+package cli
+
+import (
+	"context"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/document"
+	"deep-research/pkg/mcp"
+	"deep-research/pkg/report"
+	"deep-research/pkg/search"
+	"deep-research/pkg/server"
+	"deep-research/pkg/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newMCPCmd() *cobra.Command {
+	var backend backendOptions
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve deep research as MCP tools over stdio",
+		Long: `Serve deep research as Model Context Protocol tools over stdio, for
+Claude Desktop and other MCP clients.
+
+Tools: create_plan (plan a job for review), run_research (start an approved
+plan, or plan and run a topic directly), and get_results (progress while a
+job runs, then the report). Research runs in the background, one job at a
+time; jobs are recorded in the database like CLI runs. Console output goes
+to stderr, which clients usually keep as the server log.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// stdout carries the protocol; everything printed along the way is logging
+			protocol := os.Stdout
+			os.Stdout = os.Stderr
+
+			if err := backend.checkModel(); err != nil {
+				return err
+			}
+			var err error
+			if backend.logger, err = newLogger(cmd); err != nil {
+				return err
+			}
+			jobStore := openStore(cmd)
+			if jobStore != nil {
+				defer jobStore.Close()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			tools := &mcpTools{backend: &backend, store: jobStore, ctx: ctx, jobs: make(map[string]*mcpJob)}
+			defer tools.stop(cancel)
+
+			server := mcp.NewServer("deep-research", buildVersion(), mcpInstructions)
+			tools.register(server)
+			fmt.Println("🔌 MCP server ready on stdio")
+			return server.Serve(ctx, os.Stdin, protocol)
+		},
+	}
+	backend.addFlags(cmd.Flags())
+	backend.addFetchFlags(cmd.Flags())
+	backend.addClientFlags(cmd.Flags())
+	return cmd
+}
+
+// mcpInstructions tells the client's model how the tools fit together
+const mcpInstructions = `Deep research searches the web over several rounds and writes a cited report. It takes minutes to hours.
+Call create_plan to review the research plan (answer its clarifying questions by calling create_plan again with feedback), then run_research with its job_id. run_research returns immediately; call get_results with the job_id to check progress and, once complete, get the report.`
+
+// buildVersion returns the module version the binary was built from
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// researchArgs are the per-job settings accepted by create_plan and run_research
+type researchArgs struct {
+	Topic          string   `json:"topic"`
+	Feedback       string   `json:"feedback,omitempty"` // Extra details or answers to the clarifying questions
+	Simple         bool     `json:"simple,omitempty"`
+	Deep           bool     `json:"deep,omitempty"`
+	Loops          int      `json:"loops,omitempty"`
+	MinResults     int      `json:"min_results,omitempty"`
+	IncludeDomains []string `json:"include_domains,omitempty"`
+	ExcludeDomains []string `json:"exclude_domains,omitempty"`
+	Categories     []string `json:"categories,omitempty"`
+	TimeRange      string   `json:"time_range,omitempty"`
+	MaxAgeDays     int      `json:"max_age_days,omitempty"`
+	ReportLanguage string   `json:"report_language,omitempty"`
+	ContextFiles   []string `json:"context_files,omitempty"` // Local files the research builds on
+}
+
+// mcpJob is a job created through the MCP tools
+type mcpJob struct {
+	ID         string
+	Args       researchArgs
+	Status     string // "awaiting_approval", "running", "complete", "cancelled", "error"
+	Plan       agent.ResearchPlan
+	Progress   agent.ProgressEvent
+	Result     *agent.ResearchResult
+	Error      string
+	StartedAt  time.Time
+	researcher *agent.DeepResearcher
+}
+
+// mcpTools holds the jobs behind the MCP tools
+type mcpTools struct {
+	backend *backendOptions
+	store   *store.Store    // Job database (nil when disabled)
+	ctx     context.Context // Cancelled when the client disconnects
+
+	mu      sync.Mutex
+	jobs    map[string]*mcpJob
+	running string         // ID of the job researching ("" = idle)
+	latest  string         // ID of the most recently created job
+	wg      sync.WaitGroup // Running research
+}
+
+var researchArgsSchema = map[string]any{
+	"topic":           map[string]any{"type": "string", "description": "What to research, as specific as possible"},
+	"feedback":        map[string]any{"type": "string", "description": "Extra details for the planner, e.g. answers to the plan's clarifying questions"},
+	"simple":          map[string]any{"type": "boolean", "description": "Quick research without pre-generated query variants (less thorough)"},
+	"deep":            map[string]any{"type": "boolean", "description": "Fetch and summarize every result page (much slower, more detailed)"},
+	"loops":           map[string]any{"type": "integer", "description": "Research rounds (default 5)"},
+	"min_results":     map[string]any{"type": "integer", "description": "Unique URLs to collect before stopping early (default 20)"},
+	"include_domains": map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Only use results from these domains and their subdomains"},
+	"exclude_domains": map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Never use results from these domains"},
+	"categories":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "SearXNG categories to search, e.g. [\"news\"] for current events"},
+	"time_range":      map[string]any{"type": "string", "enum": search.TimeRanges, "description": "Only results from the last day, week, month, or year"},
+	"max_age_days":    map[string]any{"type": "integer", "minimum": 0, "description": "Drop sources published more than this many days ago (undated sources are kept)"},
+	"report_language": map[string]any{"type": "string", "description": "Language to write summaries and the report in, e.g. \"English\", whatever language the topic is searched in"},
+	"context_files":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Paths of local PDF, Markdown, or text files to build on; the report cites them as provided documents"},
+}
+
+func (t *mcpTools) register(server *mcp.Server) {
+	server.AddTool(mcp.Tool{
+		Name:        "create_plan",
+		Description: "Create a research plan for a topic without starting research. Returns a job_id, the planner's understanding, clarifying questions, research steps, and search queries. Pass the job_id to run_research to start; to revise, call create_plan again with feedback.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": researchArgsSchema,
+			"required":   []string{"topic"},
+		},
+		Handler: t.createPlan,
+	})
+
+	runProps := map[string]any{
+		"job_id":         map[string]any{"type": "string", "description": "Job from create_plan to run with its plan (the other settings come from create_plan)"},
+		"search_queries": map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Replace the plan's search queries"},
+	}
+	for k, v := range researchArgsSchema {
+		runProps[k] = v
+	}
+	server.AddTool(mcp.Tool{
+		Name:        "run_research",
+		Description: "Start research in the background and return its job_id right away. Give the job_id of a plan from create_plan, or a topic to plan and run without review. One job runs at a time; poll get_results for progress and the report.",
+		InputSchema: map[string]any{"type": "object", "properties": runProps},
+		Handler:     t.runResearch,
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "get_results",
+		Description: "Get a job's status: progress while it runs, the plan while it awaits run_research, or the final Markdown report with its numbered bibliography. Works for earlier jobs in the database too. Defaults to the most recent job.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"job_id": map[string]any{"type": "string", "description": "Job to report on (default: the most recent)"}},
+		},
+		Handler: t.getResults,
+	})
+}
+
+// createPlan implements the create_plan tool
+func (t *mcpTools) createPlan(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args researchArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	job, err := t.plan(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Job ID: %s\n\n%s\nCall run_research with job_id %q to start, optionally with search_queries to replace the queries. To revise the plan, call create_plan again with feedback.",
+		job.ID, formatPlan(job.Plan), job.ID), nil
+}
+
+// runResearch implements the run_research tool
+func (t *mcpTools) runResearch(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		researchArgs
+		JobID         string   `json:"job_id"`
+		SearchQueries []string `json:"search_queries"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.JobID == "" && strings.TrimSpace(args.Topic) == "" {
+		return "", errors.New("job_id or topic is required")
+	}
+
+	t.mu.Lock()
+	running := t.running
+	t.mu.Unlock()
+	if running != "" {
+		return "", fmt.Errorf("job %s is still running; call get_results to follow it", running)
+	}
+
+	var job *mcpJob
+	var err error
+	if args.JobID != "" {
+		if job, err = t.loadPlan(args.JobID); err != nil {
+			return "", err
+		}
+	} else if job, err = t.plan(ctx, args.researchArgs); err != nil {
+		return "", err
+	}
+	queries := agent.CleanQueries(args.SearchQueries)
+	if len(args.SearchQueries) > 0 && len(queries) == 0 {
+		return "", errors.New("search_queries has no usable queries")
+	}
+
+	t.mu.Lock()
+	if t.running != "" {
+		t.mu.Unlock()
+		return "", fmt.Errorf("job %s is still running; call get_results to follow it", t.running)
+	}
+	if job.Status != "awaiting_approval" {
+		t.mu.Unlock()
+		return "", fmt.Errorf("job %s is %s, not awaiting research", job.ID, job.Status)
+	}
+	if len(queries) > 0 {
+		job.Plan.SearchQueries = queries
+	}
+	job.Status = "running"
+	t.running = job.ID
+	t.latest = job.ID
+	t.wg.Add(1)
+	t.mu.Unlock()
+	t.save(job)
+
+	go t.execute(job)
+
+	withQueries := ""
+	if n := len(job.Plan.SearchQueries); n > 0 {
+		withQueries = fmt.Sprintf(" with %d search queries", n)
+	}
+	return fmt.Sprintf("Research started for job %s%s. It takes minutes to hours; call get_results with job_id %q to check progress and get the report.", job.ID, withQueries, job.ID), nil
+}
+
+// getResults implements the get_results tool
+func (t *mcpTools) getResults(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	t.mu.Lock()
+	if args.JobID == "" {
+		args.JobID = t.latest
+	}
+	job, ok := t.jobs[args.JobID]
+	var snapshot mcpJob
+	if ok {
+		snapshot = *job
+	}
+	t.mu.Unlock()
+	if args.JobID == "" {
+		return "", errors.New("no jobs yet; call create_plan or run_research first")
+	}
+
+	// Jobs from other runs (CLI, web server, earlier sessions) come from the database
+	if !ok {
+		stored, err := t.loadStored(args.JobID)
+		if err != nil {
+			return "", err
+		}
+		snapshot = mcpJob{ID: stored.ID, Status: stored.Status, Result: stored.Result, Error: stored.Error}
+		if stored.Plan != nil {
+			snapshot.Plan = *stored.Plan
+		}
+		if stored.Progress != nil {
+			snapshot.Progress = *stored.Progress
+		}
+	}
+
+	switch {
+	case snapshot.Result != nil:
+		header := fmt.Sprintf("Job %s is %s: %d sources.", snapshot.ID, snapshot.Status, len(snapshot.Result.Sources))
+		if snapshot.Status == "cancelled" {
+			header += " The research was stopped early, so this is a partial report."
+		}
+		return header + "\n\n" + report.Markdown(*snapshot.Result), nil
+	case snapshot.Status == "error":
+		return "", fmt.Errorf("job %s failed: %s", snapshot.ID, snapshot.Error)
+	case snapshot.Status == "awaiting_approval":
+		return fmt.Sprintf("Job %s is waiting to be started with run_research.\n\n%s", snapshot.ID, formatPlan(snapshot.Plan)), nil
+	case snapshot.Status == "running":
+		p := snapshot.Progress
+		status := fmt.Sprintf("Job %s is running (%d%%, %d sources so far)", snapshot.ID, p.Percent, p.URLsFound)
+		if p.Message != "" {
+			status += ": " + p.Message
+		}
+		return status + "\nCall get_results again later for the report.", nil
+	default:
+		return fmt.Sprintf("Job %s is %s and has no report.", snapshot.ID, snapshot.Status), nil
+	}
+}
+
+// plan creates a job and its plan from the tool arguments
+func (t *mcpTools) plan(ctx context.Context, args researchArgs) (*mcpJob, error) {
+	args.Topic = strings.TrimSpace(args.Topic)
+	if args.Topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	if err := search.ValidateTimeRange(args.TimeRange); err != nil {
+		return nil, err
+	}
+	if args.MaxAgeDays < 0 {
+		return nil, errors.New("max_age_days must not be negative")
+	}
+	job := &mcpJob{
+		ID:        fmt.Sprintf("%s_%s", time.Now().Format("20060102_150405"), safeTopicName(args.Topic)),
+		Args:      args,
+		StartedAt: time.Now(),
+	}
+	researcher, err := t.newResearcher(job)
+	if err != nil {
+		return nil, err
+	}
+	job.researcher = researcher
+
+	fmt.Printf("📋 Creating research plan for: %s\n", args.Topic)
+	plan, err := researcher.CreatePlanWithAnswers(ctx, args.Topic, nil, args.Feedback)
+	if err != nil {
+		return nil, fmt.Errorf("error creating plan: %w", err)
+	}
+	job.Plan = plan
+	job.Status = "awaiting_approval"
+
+	t.mu.Lock()
+	t.jobs[job.ID] = job
+	t.latest = job.ID
+	t.mu.Unlock()
+	t.save(job)
+	return job, nil
+}
+
+// loadPlan returns a job awaiting research, from this session or the database
+func (t *mcpTools) loadPlan(id string) (*mcpJob, error) {
+	t.mu.Lock()
+	job, ok := t.jobs[id]
+	t.mu.Unlock()
+	if ok {
+		return job, nil
+	}
+
+	stored, err := t.loadStored(id)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Plan == nil {
+		return nil, fmt.Errorf("job %s has no plan", id)
+	}
+	job = &mcpJob{ID: stored.ID, Status: stored.Status, Plan: *stored.Plan, StartedAt: stored.StartedAt}
+	if stored.Status == "interrupted" {
+		job.Status = "awaiting_approval" // Stopped by a restart; the plan can still run
+	}
+	if len(stored.Config) > 0 {
+		json.Unmarshal(stored.Config, &job.Args)
+	}
+	if job.Args.Topic == "" {
+		job.Args.Topic = stored.Topic
+	}
+	if job.researcher, err = t.newResearcher(job); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.jobs[job.ID] = job
+	t.mu.Unlock()
+	return job, nil
+}
+
+// loadStored looks a job up in the database
+func (t *mcpTools) loadStored(id string) (*store.Job, error) {
+	if t.store == nil {
+		return nil, fmt.Errorf("job %q not found", id)
+	}
+	stored, err := t.store.GetJob(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("job %q not found", id)
+	}
+	return stored, err
+}
+
+// newResearcher sets up the agent for a job, with the CLI's defaults for unset arguments
+func (t *mcpTools) newResearcher(job *mcpJob) (*agent.DeepResearcher, error) {
+	llmClient, err := t.backend.newLLM()
+	if err != nil {
+		return nil, err
+	}
+	callProviders, err := t.backend.callProviders()
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := t.backend.newSearcher()
+	if err != nil {
+		return nil, err
+	}
+
+	args := job.Args
+	var documents []document.Document
+	for _, path := range args.ContextFiles {
+		doc, err := document.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, doc)
+	}
+	if args.Loops <= 0 {
+		args.Loops = 5
+	}
+	if args.MinResults <= 0 {
+		args.MinResults = 20
+	}
+
+	// Exhaustive runs checkpoint like the CLI, so `deep-research resume <job id>` works
+	checkpointPath := ""
+	if !args.Simple {
+		checkpointPath = filepath.Join("results", job.ID+".checkpoint.json")
+	}
+	req := server.ResearchRequest{
+		Loops:          args.Loops,
+		Parallel:       5,
+		ContextLen:     t.backend.contextLen,
+		DeepMode:       args.Deep,
+		MinResults:     args.MinResults,
+		DelayMs:        500,
+		SimpleMode:     args.Simple,
+		IncludeDomains: args.IncludeDomains,
+		ExcludeDomains: args.ExcludeDomains,
+		Categories:     args.Categories,
+		TimeRange:      args.TimeRange,
+		MaxAgeDays:     args.MaxAgeDays,
+		ReportLanguage: args.ReportLanguage,
+	}
+	setup := t.backend.jobSetup(callProviders)
+	setup.CheckpointPath = checkpointPath
+	setup.Documents = documents
+	setup.OnProgress = func(event agent.ProgressEvent) {
+		t.mu.Lock()
+		job.Progress = event
+		t.mu.Unlock()
+		if t.store != nil {
+			if err := t.store.AddProgressEvent(job.ID, event); err != nil {
+				fmt.Printf("⚠️ %v\n", err)
+			}
+		}
+	}
+	return agent.NewDeepResearcher(llmClient, searcher, req.AgentConfig(setup)), nil
+}
+
+// execute runs a job's research; a disconnecting client cancels it, leaving a partial report
+func (t *mcpTools) execute(job *mcpJob) {
+	defer t.wg.Done()
+
+	var result agent.ResearchResult
+	var err error
+	if job.Args.Simple {
+		result, err = job.researcher.RunWithContext(t.ctx, job.Args.Topic, job.Plan)
+	} else {
+		result, err = job.researcher.RunExhaustiveWithContext(t.ctx, job.Args.Topic, job.Plan)
+	}
+
+	t.mu.Lock()
+	switch {
+	case result.Report != "":
+		job.Result = &result
+		job.Status = "complete"
+		if t.ctx.Err() != nil {
+			job.Status = "cancelled"
+		}
+	case err != nil:
+		job.Status = "error"
+		job.Error = err.Error()
+	default:
+		job.Status = "error"
+		job.Error = "no report was written"
+	}
+	t.running = ""
+	t.mu.Unlock()
+
+	if job.Result != nil && t.store != nil {
+		if err := t.store.SaveResult(job.ID, result); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		}
+	}
+	t.save(job)
+	fmt.Printf("🏁 Job %s: %s\n", job.ID, job.Status)
+}
+
+// save records a job in the database
+func (t *mcpTools) save(job *mcpJob) {
+	t.mu.Lock()
+	config, _ := json.Marshal(job.Args)
+	plan := job.Plan
+	record := store.Job{
+		ID:        job.ID,
+		Topic:     job.Args.Topic,
+		Status:    job.Status,
+		Error:     job.Error,
+		Config:    config,
+		Plan:      &plan,
+		StartedAt: job.StartedAt,
+	}
+	t.mu.Unlock()
+	saveJob(t.store, record)
+}
+
+// stop cancels running research once the client has gone and waits for its
+// partial report to be saved
+func (t *mcpTools) stop(cancel context.CancelFunc) {
+	cancel()
+	t.mu.Lock()
+	running := t.running
+	t.mu.Unlock()
+	if running != "" {
+		fmt.Printf("🛑 Client disconnected - writing a partial report for job %s...\n", running)
+	}
+	t.wg.Wait()
+}
+
+// formatPlan renders a plan for the client's model
+func formatPlan(plan agent.ResearchPlan) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Understanding: %s\n", plan.UnderstandingSummary)
+	if len(plan.ClarifyingQuestions) > 0 {
+		sb.WriteString("\nClarifying questions:\n")
+		for i, q := range plan.ClarifyingQuestions {
+			fmt.Fprintf(&sb, "%d. %s\n", i+1, q)
+		}
+	}
+	if len(plan.Answers) > 0 {
+		sb.WriteString("\nAnswers so far:\n")
+		for _, qa := range plan.Answers {
+			fmt.Fprintf(&sb, "- %s → %s\n", qa.Question, qa.Answer)
+		}
+	}
+	sb.WriteString("\nResearch steps:\n")
+	for i, step := range plan.ResearchSteps {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, step)
+	}
+	if plan.ExpectedOutcome != "" {
+		fmt.Fprintf(&sb, "\nExpected outcome: %s\n", plan.ExpectedOutcome)
+	}
+	if len(plan.SearchQueries) > 0 {
+		fmt.Fprintf(&sb, "\nSearch queries (%d):\n", len(plan.SearchQueries))
+		for _, q := range plan.SearchQueries {
+			fmt.Fprintf(&sb, "- %s\n", q)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newModelsCmd() *cobra.Command {
+	var backend backendOptions
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "List the models served by the configured LLM provider",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := backend.llmConfig()
+			if err != nil {
+				return err
+			}
+			client, err := llm.NewProvider(backend.llmProvider, cfg)
+			if err != nil {
+				return err
+			}
+			if describer, ok := client.(llm.ModelDescriber); ok {
+				models, err := describer.DescribeModels(context.Background())
+				if err != nil {
+					return fmt.Errorf("failed to list models: %w", err)
+				}
+				if len(models) == 0 {
+					fmt.Println("No models available.")
+				}
+				for _, m := range models {
+					fmt.Println(modelLine(m))
+				}
+				return nil
+			}
+			lister, ok := client.(llm.ModelLister)
+			if !ok {
+				return fmt.Errorf("provider %s can't list its models", backend.llmProvider)
+			}
+
+			models, err := lister.ListModels(context.Background())
+			if err != nil {
+				return fmt.Errorf("failed to list models: %w", err)
+			}
+			if len(models) == 0 {
+				fmt.Println("No models available.")
+				return nil
+			}
+			for _, m := range models {
+				fmt.Println(m)
+			}
+			return nil
+		},
+	}
+	backend.addFlags(cmd.Flags())
+	return cmd
+}
+
+// modelLine formats a model for the list, e.g. "qwen3:8b  (context 40960, loaded)"
+func modelLine(m llm.ModelInfo) string {
+	var details []string
+	if m.ContextLength > 0 {
+		details = append(details, fmt.Sprintf("context %d", m.ContextLength))
+	}
+	if m.Loaded {
+		details = append(details, "loaded")
+	}
+	if len(details) == 0 {
+		return m.ID
+	}
+	return fmt.Sprintf("%s  (%s)", m.ID, strings.Join(details, ", "))
+}
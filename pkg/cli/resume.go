@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"deep-research/pkg/agent"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func newResumeCmd() *cobra.Command {
+	var opts researchOptions
+	cmd := &cobra.Command{
+		Use:   "resume <job id | checkpoint file>",
+		Short: "Resume an interrupted exhaustive run from its checkpoint",
+		Long: `Resume an interrupted exhaustive run (power loss, LLM crash, Ctrl+C).
+
+The argument is either a checkpoint file or a job ID, which is looked up as
+results/<job id>.checkpoint.json. Topic and plan come from the checkpoint, so
+no planning happens; agent flags (--deep, --schema, ...) should match the
+original run.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := resolveCheckpoint(args[0])
+			checkpoint, err := agent.LoadCheckpoint(path)
+			if err != nil {
+				return fmt.Errorf("error loading checkpoint: %w", err)
+			}
+			return runResearch(cmd, &opts, checkpoint, path)
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	return cmd
+}
+
+// resolveCheckpoint maps a job ID to its checkpoint file; existing paths are used as-is
+func resolveCheckpoint(arg string) string {
+	if _, err := os.Stat(arg); err == nil {
+		return arg
+	}
+	return filepath.Join("results", arg+".checkpoint.json")
+}
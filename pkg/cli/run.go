@@ -0,0 +1,1128 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/document"
+	"deep-research/pkg/geo"
+	"deep-research/pkg/price"
+	"deep-research/pkg/profile"
+	"deep-research/pkg/report"
+	"deep-research/pkg/search"
+	"deep-research/pkg/server"
+	"deep-research/pkg/store"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// researchOptions holds the agent flags shared by `run` and `resume`
+type researchOptions struct {
+	backend        backendOptions
+	maxLoops       int
+	parallel       int
+	outputFile     string
+	format         string
+	singlePass     bool
+	deepMode       bool
+	resultLinks    bool
+	schema         string
+	entities       bool
+	conflicts      bool
+	images         bool
+	evaluate       bool
+	reviseBelow    int
+	dedupThreshold float64
+	queryDedup     float64
+	relevance      string  // --relevance-filter
+	currency       string  // --currency
+	rates          string  // --rates
+	geoArea        string  // --geo-area
+	geoRadius      float64 // --geo-radius
+	geoFilter      string  // --geo-filter
+	fixedOrder     bool    // --fixed-query-order
+	archiveSources bool
+	archiveHTML    bool
+	simpleMode     bool
+	minResults     int
+	delayMs        int
+	maxPages       int
+	topic          string
+	topicsFile     string // --topics-file
+	topicsParallel int    // --topics-parallel
+	batch          *batchRun
+	urlsFile       string
+	followLinks    bool
+	crawlDepth     int
+	crawlPages     int
+	nextPages      int
+	listings       int // --listings-per-query
+	sitemap        []string
+	sitemapPattern string
+	sitemapPages   int
+	contextFiles   []string
+	compare        []string
+	includeDomains []string
+	excludeDomains []string
+	categories     []string
+	searxEngines   []string
+	timeRange      string
+	maxAgeDays     int
+	autoApprove    bool
+	checkpointFile string
+	jsonOutput     bool
+	progressOut    string
+	tui            bool
+	profile        string
+	profilesDir    string
+	planningPrompt string // From --profile
+	reportFormat   string // From --profile: how the report is structured
+	reportTemplate string // --report-template's file, or from --profile: the template's text
+	reportLanguage string
+	templateFile   string
+	maxLLMCalls    int
+	maxHTTP        int
+	maxDuration    time.Duration
+	reportReserve  time.Duration
+	collection     string
+	onlyNew        bool
+	callTemp       map[string]string // --call-temperature, per call type
+	callMaxTokens  map[string]int
+	callPrompts    []string // --call-system-prompt type=prompt entries
+}
+
+func (o *researchOptions) addFlags(fs *pflag.FlagSet) {
+	o.backend.addFlags(fs)
+	o.backend.addFetchFlags(fs)
+	o.backend.addClientFlags(fs)
+	fs.IntVar(&o.maxLoops, "loops", 5, "Max research loops")
+	fs.IntVar(&o.parallel, "parallel", 5, "Max parallel searches")
+	fs.StringVarP(&o.outputFile, "output", "o", "", "Output file path (default: results/<job id>.<format>)")
+	fs.StringVar(&o.format, "format", "md", "Report format: md, html, pdf, csv, xlsx, dot, or graphml (csv/xlsx: sources and extracted records; dot/graphml: the --entities graph)")
+	fs.BoolVar(&o.singlePass, "single-pass-report", false, "Write the report in one prompt when the findings fit, instead of outlining it and writing each section separately")
+	fs.BoolVar(&o.deepMode, "deep", false, "Deep mode: fetch and summarize each page (slower but more thorough)")
+	fs.BoolVar(&o.resultLinks, "result-links", false, "Emphasize including direct links to individual listings in results")
+	fs.Float64Var(&o.dedupThreshold, "dedup-threshold", agent.DefaultDedupThreshold, "Deep mode: cosine similarity at which pages count as near-duplicates (needs --embedding-model)")
+	fs.Float64Var(&o.queryDedup, "query-dedup", agent.DefaultQueryDedupThreshold, "Cosine similarity at which planned queries count as the same and only one is searched (needs --embedding-model; 0 = off)")
+	fs.StringVar(&o.currency, "currency", "", "Convert the prices found in extracted records, summaries, and snippets to this currency (e.g. EUR), so listings can be sorted and compared by price; records get a price_<currency> column (default: off)")
+	fs.StringVar(&o.rates, "rates", "", "With --currency: exchange rates to use: ecb (the European Central Bank's daily reference rates) or a JSON file like {\"EUR\": 1, \"RON\": 4.97} (default: a built-in approximate table)")
+	fs.StringVar(&o.geoArea, "geo-area", "", "Locate the listings (by their records' address fields, else their summaries) and flag or drop those outside this place, e.g. \"Cluj-Napoca, Romania\" or 46.77,23.59 (default: off)")
+	fs.Float64Var(&o.geoRadius, "geo-radius", 0, "With --geo-area: the area's radius in km from its center (default: the place's bounds from the geocoder, else 10 km)")
+	fs.StringVar(&o.geoFilter, "geo-filter", "", "With --geo-area: flag (mark listings outside the area in the report) or drop (leave them out) (default: flag)")
+	fs.StringVar(&o.relevance, "relevance-filter", "", "Drop search results unrelated to the topic before they reach the context: keywords (no word in common with the query or topic) or llm (also a yes/no from the summarizer model per results page)")
+	fs.BoolVar(&o.fixedOrder, "fixed-query-order", false, "Search the planned queries in the order planned, instead of running those of high-yield platforms and words first and skipping dead ends once the research stalls")
+	fs.BoolVar(&o.entities, "entities", false, "Extract the people, companies, products, and locations in the findings and their relations; a GraphViz .dot of them is written next to the report")
+	fs.BoolVar(&o.conflicts, "conflicts", false, "Compare the sources' claims about the same facts (prices, dates, specs); the report gets a Conflicting Information section")
+	fs.BoolVar(&o.evaluate, "evaluate", false, "Critique the report against the plan's expected outcome (coverage, specificity, citations) and, if it scores low, search for its gaps and revise it once")
+	fs.IntVar(&o.reviseBelow, "revise-below", agent.DefaultReviseBelow, "With --evaluate: score out of 10 under which the report is revised")
+	fs.BoolVar(&o.images, "images", false, "Also search SearXNG's images category for each query; the report gets a Media appendix of the images found (e.g. for product research)")
+	fs.BoolVar(&o.archiveSources, "archive-sources", false, "Save the text of every fetched page under results/<job id>/sources/ with an index.jsonl, so the report can be audited after the pages change")
+	fs.BoolVar(&o.archiveHTML, "archive-html", false, "With --archive-sources: also save each page as downloaded (HTML or PDF); implies --archive-sources")
+	fs.StringVar(&o.schema, "schema", "", "Deep mode: fields to extract per page as a table (e.g. \"price, address, sqm, url\" or a JSON schema)")
+
+	// Simple mode flag (exhaustive is the default)
+	fs.BoolVar(&o.simpleMode, "simple", false, "Simple mode: quick research without query expansion (not recommended)")
+	fs.IntVar(&o.minResults, "min-results", 20, "Minimum unique URLs to find before stopping")
+	fs.IntVar(&o.delayMs, "delay", 500, "Milliseconds delay between search requests (rate limiting; page fetches use --fetch-rate)")
+	fs.IntVar(&o.maxPages, "pages", 0, "Max pages per query (0 = auto: keep fetching until no more results)")
+	fs.StringVar(&o.urlsFile, "urls-file", "", "Research the URLs listed in this file (one per line) instead of searching")
+	fs.BoolVar(&o.followLinks, "follow-links", false, "With --urls-file or --sitemap: also fetch the item links found on each page")
+	fs.IntVar(&o.crawlDepth, "crawl-depth", 0, "Deep mode, --urls-file, or --sitemap: follow in-domain \"next\", pagination, and category links this many hops from each index page and collect the items they list (0 = off)")
+	fs.IntVar(&o.crawlPages, "crawl-pages", 0, fmt.Sprintf("With --crawl-depth: most item pages collected per start page (0 = %d)", agent.DefaultCrawlPages))
+	fs.IntVar(&o.nextPages, "next-pages", 0, fmt.Sprintf("Deep mode or --follow-links: also read up to this many next pages of each listing index page (rel=next, \"next\"/\"›\" links, page= numbers) for its items (0 = off, at most %d)", agent.MaxNextPages))
+	fs.IntVar(&o.listings, "listings-per-query", agent.DefaultListingsPerQuery, "Deep mode: most listing pages read per search query (in exhaustive mode: item links taken from each listing index page among the results)")
+	fs.StringSliceVar(&o.sitemap, "sitemap", nil, "Research the pages listed in these sites' sitemaps instead of searching (domains, site URLs, or sitemap URLs; comma-separated)")
+	fs.StringVar(&o.sitemapPattern, "sitemap-pattern", "", "With --sitemap: regular expression the page URLs must match (default: the planner picks one for the topic)")
+	fs.IntVar(&o.sitemapPages, "sitemap-pages", 100, "With --sitemap: most pages to research, most recently changed first")
+	fs.StringArrayVar(&o.contextFiles, "context-file", nil, "A PDF, Markdown, or text file of what you already know: the plan builds on it and the report cites it as a provided document (repeatable)")
+	fs.StringSliceVar(&o.compare, "compare", nil, "Research each of these separately and write a comparison matrix plus a section per entity (comma-separated, at least two)")
+	fs.StringSliceVar(&o.includeDomains, "include-domains", nil, "Only use search results and pages from these domains and their subdomains (comma-separated)")
+	fs.StringSliceVar(&o.excludeDomains, "exclude-domains", nil, "Never use search results or pages from these domains, e.g. pinterest.com (comma-separated)")
+	fs.StringSliceVar(&o.categories, "categories", nil, "SearXNG categories to search, e.g. news or science,it (comma-separated; default: the instance's)")
+	fs.StringSliceVar(&o.searxEngines, "searx-engines", nil, "SearXNG engines to query, e.g. google,wikipedia (comma-separated; default: the instance's)")
+	fs.StringVar(&o.timeRange, "time-range", "", "SearXNG: only results from the last day, week, month, or year")
+	fs.StringVar(&o.reportLanguage, "report-language", "", "Write page summaries and the report in this language, e.g. English, whatever language the topic is searched in (default: the model decides)")
+	fs.StringVar(&o.templateFile, "report-template", "", "Go text/template file the finished report is rendered with: front matter, section order ({{section \"Heading\"}}), bibliography style, branding (see README)")
+	fs.IntVar(&o.maxAgeDays, "max-age-days", 0, "Drop sources published more than this many days ago, by the date on the page, in its URL, or from the search engine; undated sources are kept (0 = no limit)")
+	fs.StringVar(&o.profile, "profile", "", "Research profile bundling defaults, planning and report instructions, and a schema: market-research, literature-review, listing-hunt, competitive-analysis, or one from --profiles-dir (flags given explicitly win)")
+	fs.StringVar(&o.profilesDir, "profiles-dir", getEnv("PROFILES_DIR", profile.DefaultDir), "Directory of YAML research profiles (env: PROFILES_DIR)")
+	fs.IntVar(&o.maxLLMCalls, "max-llm-calls", 0, "Stop researching after this many LLM calls and write the report from what was found (0 = no limit)")
+	fs.IntVar(&o.maxHTTP, "max-http-requests", 0, "Stop researching after this many searches and page fetches and write the report (0 = no limit)")
+	fs.DurationVar(&o.maxDuration, "max-duration", 0, "Finish the research, report included, within this long, e.g. 20m: queries that won't fit are dropped (0 = no limit)")
+	fs.DurationVar(&o.reportReserve, "report-reserve", 0, "With --max-duration: time kept for writing the report (0 = a quarter of --max-duration, at least 1m)")
+	fs.StringVar(&o.collection, "collection", "", "Knowledge base the run belongs to: its sources are remembered in the job database and the report gets a \"What Changed Since Last Run\" section")
+	fs.BoolVar(&o.onlyNew, "only-new", false, "With --collection: skip the results and pages the collection already has, so only new sources are researched")
+	fs.StringToStringVar(&o.callTemp, "call-temperature", nil, "LLM temperature per call type: planning, summarization, compression, report, e.g. report=0.7 (default: 0 for all)")
+	fs.StringToIntVar(&o.callMaxTokens, "call-max-tokens", nil, "Longest LLM response per call type, e.g. report=8000 (default: the backend's)")
+	fs.StringArrayVar(&o.callPrompts, "call-system-prompt", nil, "System prompt replacing the built-in ones for a call type, as type=prompt, e.g. \"report=You are a financial analyst.\" (repeatable)")
+	fs.BoolVar(&o.jsonOutput, "json", false, "Machine-readable output: NDJSON progress events on stderr, the result as JSON on stdout (run: needs --topic, implies --yes)")
+	fs.StringVar(&o.progressOut, "progress-out", "", "Also write every progress event as NDJSON to this file, fd:N for an open file descriptor (e.g. fd:3), or - for stderr, keeping the console output")
+	fs.BoolVar(&o.tui, "tui", false, "Show a live dashboard while researching (queries, unique URLs vs the target, recent log lines; p pauses, f finishes now, q quits) instead of scrolling output")
+}
+
+func newRunCmd() *cobra.Command {
+	var opts researchOptions
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Plan and run a research job from the terminal",
+		Long: `Plan and run a research job from the terminal.
+
+Without --topic the topic is read interactively; without --yes the plan is
+shown for approval (or revision) before research starts. With --topics-file
+every topic in the file is researched in turn, without asking.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.topicsFile != "" {
+				return runBatch(cmd, &opts)
+			}
+			return runResearch(cmd, &opts, nil, "")
+		},
+	}
+	opts.addFlags(cmd.Flags())
+
+	// Non-interactive mode flags
+	cmd.Flags().StringVar(&opts.topic, "topic", "", "Research topic (skips interactive prompt)")
+	cmd.Flags().BoolVar(&opts.autoApprove, "yes", false, "Auto-approve research plan without confirmation (use with --topic)")
+	cmd.Flags().StringVar(&opts.checkpointFile, "checkpoint", "", "Checkpoint file path (default: results/<job id>.checkpoint.json)")
+	cmd.Flags().StringVar(&opts.topicsFile, "topics-file", "", "Research every topic in this file, one per line (or a YAML list of topics with their own options), auto-approving the plans; writes a report per topic and an index of them")
+	cmd.Flags().IntVar(&opts.topicsParallel, "topics-parallel", 1, "With --topics-file: topics researched at once")
+	return cmd
+}
+
+// request is the research job the flags describe, in the server's terms, so
+// the CLI and the server turn it into the same agent configuration. Thresholds
+// of 0 turn near-duplicate detection and query merging off, as the flags say.
+func (o *researchOptions) request(seedURLs, sitemapSites, compare []string, callSettings map[string]agent.CallSettings) server.ResearchRequest {
+	offIfZero := func(threshold float64) float64 {
+		if threshold == 0 {
+			return -1
+		}
+		return threshold
+	}
+	return server.ResearchRequest{
+		Loops:            o.maxLoops,
+		Parallel:         o.parallel,
+		ContextLen:       o.backend.contextLen,
+		DeepMode:         o.deepMode,
+		ResultLinks:      o.resultLinks,
+		MinResults:       o.minResults,
+		DelayMs:          o.delayMs,
+		SimpleMode:       o.simpleMode,
+		MaxPages:         o.maxPages,
+		ExtractionSchema: o.schema,
+		ExtractEntities:  o.entities,
+		FindConflicts:    o.conflicts,
+		CollectImages:    o.images,
+		EvaluateReport:   o.evaluate,
+		ReviseBelow:      o.reviseBelow,
+		SinglePassReport: o.singlePass,
+		DedupThreshold:   offIfZero(o.dedupThreshold),
+		QueryDedup:       offIfZero(o.queryDedup),
+		RelevanceFilter:  o.relevance,
+		Currency:         o.currency,
+		Rates:            o.rates,
+		GeoArea:          o.geoArea,
+		GeoRadius:        o.geoRadius,
+		GeoFilter:        o.geoFilter,
+		FixedQueryOrder:  o.fixedOrder,
+		ArchiveSources:   o.archiveSources,
+		ArchiveHTML:      o.archiveHTML,
+		AutoApprove:      o.autoApprove,
+		SeedURLs:         seedURLs,
+		FollowLinks:      o.followLinks,
+		NextPages:        o.nextPages,
+		CrawlDepth:       o.crawlDepth,
+		CrawlPages:       o.crawlPages,
+		ListingsPerQuery: o.listings,
+		SitemapSites:     sitemapSites,
+		SitemapPattern:   o.sitemapPattern,
+		SitemapPages:     o.sitemapPages,
+		Compare:          compare,
+		IncludeDomains:   o.includeDomains,
+		ExcludeDomains:   o.excludeDomains,
+		Categories:       o.categories,
+		SearXEngines:     o.searxEngines,
+		TimeRange:        o.timeRange,
+		MaxAgeDays:       o.maxAgeDays,
+		Profile:          o.profile,
+		PlanningPrompt:   o.planningPrompt,
+		ReportStructure:  o.reportFormat,
+		ReportTemplate:   o.reportTemplate,
+		ReportLanguage:   o.reportLanguage,
+		MaxLLMCalls:      o.maxLLMCalls,
+		MaxHTTPRequests:  o.maxHTTP,
+		MaxMinutes:       o.maxDuration.Minutes(),
+		ReserveMinutes:   o.reportReserve.Minutes(),
+		NoCache:          o.backend.noCache,
+		Collection:       o.collection,
+		OnlyNew:          o.onlyNew,
+		CallSettings:     callSettings,
+	}
+}
+
+// runResearch plans (unless resuming from a checkpoint) and executes a research job,
+// writes the report to disk, and records the job in the database
+func runResearch(cmd *cobra.Command, opts *researchOptions, checkpoint *agent.Checkpoint, checkpointPath string) (err error) {
+	format, err := report.ParseFormat(opts.format)
+	if err != nil {
+		return err
+	}
+	if err := search.ValidateTimeRange(opts.timeRange); err != nil {
+		return err
+	}
+	if err := agent.ValidateRelevanceFilter(opts.relevance); err != nil {
+		return fmt.Errorf("invalid --relevance-filter: %w", err)
+	}
+	if opts.maxAgeDays < 0 {
+		return fmt.Errorf("--max-age-days must not be negative")
+	}
+	if err := agent.ValidateCrawl(opts.crawlDepth, opts.crawlPages); err != nil {
+		return fmt.Errorf("invalid --crawl-depth or --crawl-pages: %w", err)
+	}
+	if err := agent.ValidateNextPages(opts.nextPages); err != nil {
+		return fmt.Errorf("invalid --next-pages: %w", err)
+	}
+	if opts.listings < 1 {
+		return fmt.Errorf("--listings-per-query must be at least 1")
+	}
+	if opts.onlyNew && opts.collection == "" {
+		return fmt.Errorf("--only-new needs --collection")
+	}
+	callSettings, err := opts.callSettings()
+	if err != nil {
+		return err
+	}
+
+	// --json: there is no one to approve the plan, and errors become the last event
+	var out *jsonOutput
+	var onProgress func(agent.ProgressEvent)
+	if opts.jsonOutput {
+		if checkpoint == nil && opts.topic == "" {
+			return fmt.Errorf("--json needs --topic")
+		}
+		opts.autoApprove = true
+		if out, err = newJSONOutput(); err != nil {
+			return err
+		}
+		onProgress = out.progress
+		defer func() {
+			if err != nil {
+				out.fail(err)
+			}
+		}()
+	}
+	var dash *dashboard
+	if opts.tui {
+		if opts.jsonOutput {
+			return fmt.Errorf("--tui and --json can't be combined")
+		}
+		dash = &dashboard{}
+		onProgress = dash.progress
+	}
+	// --progress-out: the events also go to a file for scripts to follow
+	if opts.progressOut != "" {
+		var progress *progressOutput
+		if progress, err = openProgressOutput(opts.progressOut); err != nil {
+			return err
+		}
+		defer progress.Close()
+		if console := onProgress; console != nil {
+			onProgress = func(event agent.ProgressEvent) {
+				console(event)
+				progress.progress(event)
+			}
+		} else {
+			onProgress = progress.progress
+		}
+		defer func() {
+			if err != nil {
+				progress.fail(err)
+			}
+		}()
+	}
+
+	// Profile settings fill in whatever wasn't given on the command line
+	if opts.profile != "" {
+		p, err := profile.Find(opts.profilesDir, opts.profile, settings.Profiles...)
+		if err != nil {
+			return err
+		}
+		flags := cmd.Flags()
+		if opts.batch != nil {
+			flags = opts.batch.flags
+		}
+		opts.applyProfile(flags, p)
+		fmt.Printf("🧭 Profile: %s - %s\n", p.Name, p.Description)
+	}
+	if opts.templateFile != "" {
+		data, err := os.ReadFile(opts.templateFile)
+		if err != nil {
+			return fmt.Errorf("failed to read report template: %w", err)
+		}
+		opts.reportTemplate = string(data)
+	}
+	if err := agent.ValidateReportTemplate(opts.reportTemplate); err != nil {
+		return err
+	}
+
+	// A comparison researches each entity with its own queries
+	var compare []string
+	if len(opts.compare) > 0 && checkpoint == nil {
+		for _, e := range opts.compare {
+			if e = strings.TrimSpace(e); e != "" {
+				compare = append(compare, e)
+			}
+		}
+		if len(compare) < 2 {
+			return fmt.Errorf("--compare needs at least two entities")
+		}
+		if opts.urlsFile != "" || len(opts.sitemap) > 0 {
+			return fmt.Errorf("--compare can't be combined with --urls-file or --sitemap")
+		}
+		fmt.Printf("⚖️ Comparing: %s\n", strings.Join(compare, " vs "))
+	}
+
+	// Seed URLs replace searching; every page is fetched and summarized as in deep mode
+	var seedURLs []string
+	var sitemapSites []string
+	if len(opts.sitemap) > 0 && checkpoint == nil {
+		for _, site := range opts.sitemap {
+			if site = strings.TrimSpace(site); site != "" {
+				sitemapSites = append(sitemapSites, site)
+			}
+		}
+		if opts.urlsFile != "" {
+			return fmt.Errorf("--sitemap and --urls-file can't be combined")
+		}
+		if _, err := regexp.Compile(opts.sitemapPattern); err != nil {
+			return fmt.Errorf("invalid --sitemap-pattern: %w", err)
+		}
+		fmt.Printf("🗺️ Researching pages from the sitemaps of %s (no searching)\n", strings.Join(sitemapSites, ", "))
+		opts.deepMode = true
+	} else if opts.urlsFile != "" && checkpoint == nil {
+		if seedURLs, err = readURLsFile(opts.urlsFile); err != nil {
+			return err
+		}
+		fmt.Printf("📑 Researching %d URLs from %s (no searching)\n", len(seedURLs), opts.urlsFile)
+		opts.deepMode = true
+	} else if opts.deepMode {
+		fmt.Println("🔬 Deep mode enabled: will fetch and summarize each page individually")
+	}
+	// Provided documents ground the plan and the report (give them again to resume)
+	var documents []document.Document
+	for _, path := range opts.contextFiles {
+		doc, err := document.Load(path)
+		if err != nil {
+			return fmt.Errorf("failed to read --context-file: %w", err)
+		}
+		documents = append(documents, doc)
+		fmt.Printf("📎 Provided document: %s (%d words)\n", doc.Name, doc.Words())
+	}
+	if opts.crawlDepth > 0 {
+		if !opts.deepMode {
+			return fmt.Errorf("--crawl-depth needs --deep, --urls-file, or --sitemap")
+		}
+		pages := opts.crawlPages
+		if pages == 0 {
+			pages = agent.DefaultCrawlPages
+		}
+		fmt.Printf("🕸️ Crawling %d links deep from each index page (up to %d item pages each)\n", opts.crawlDepth, pages)
+	} else if opts.nextPages > 0 {
+		if !opts.deepMode {
+			return fmt.Errorf("--next-pages needs --deep or --follow-links")
+		}
+		fmt.Printf("📃 Following up to %d next pages of each listing\n", opts.nextPages)
+	}
+	if opts.deepMode {
+		fmt.Printf("👷 Deep mode workers: %s fetches, %s summaries at once\n", workerCount(opts.backend.fetchConcurrency), workerCount(opts.backend.summarizeWorkers))
+	}
+	if opts.resultLinks {
+		fmt.Println("🔗 Result links mode: will emphasize direct listing URLs in output")
+	}
+	if opts.schema != "" {
+		if opts.deepMode {
+			fmt.Printf("🧾 Structured extraction enabled: %s\n", opts.schema)
+		} else {
+			fmt.Println("⚠️  --schema only applies in deep mode (--deep); ignoring")
+		}
+	}
+	if opts.entities {
+		fmt.Println("🕸️ Entity extraction enabled: the report gets a knowledge graph")
+	}
+	if opts.conflicts {
+		fmt.Println("⚖️ Conflict analysis enabled: the report lists the facts the sources disagree on")
+	}
+	if opts.images {
+		fmt.Println("🖼️ Image search enabled: the report gets a Media appendix")
+	}
+	if opts.evaluate {
+		fmt.Printf("🧐 Report evaluation enabled: revised once if it scores below %d/10\n", opts.reviseBelow)
+	}
+	if len(opts.includeDomains) > 0 {
+		fmt.Printf("🌐 Only using results from: %s\n", strings.Join(opts.includeDomains, ", "))
+	}
+	if len(opts.excludeDomains) > 0 {
+		fmt.Printf("🚫 Excluding results from: %s\n", strings.Join(opts.excludeDomains, ", "))
+	}
+	if len(opts.categories) > 0 || len(opts.searxEngines) > 0 || opts.timeRange != "" {
+		fmt.Printf("🗞️ SearXNG filters: %s\n", describeFilters(opts.categories, opts.searxEngines, opts.timeRange))
+	}
+	var priceRates price.Rates
+	if opts.currency != "" {
+		if priceRates, err = price.LoadRates(context.Background(), opts.rates); err != nil {
+			return fmt.Errorf("invalid --rates: %w", err)
+		}
+		if err := agent.ValidatePriceCurrency(opts.currency, priceRates); err != nil {
+			return fmt.Errorf("invalid --currency: %w", err)
+		}
+		rates := opts.rates
+		if rates == "" {
+			rates = "built-in"
+		}
+		fmt.Printf("💱 Prices converted to %s (%s exchange rates)\n", strings.ToUpper(opts.currency), rates)
+	} else if opts.rates != "" {
+		return fmt.Errorf("--rates needs --currency")
+	}
+	if err := agent.ValidateGeo(opts.geoArea, opts.geoRadius, opts.geoFilter); err != nil {
+		return fmt.Errorf("invalid geo filtering: %w", err)
+	}
+	var geocoder geo.Geocoder
+	if opts.geoArea != "" {
+		if geocoder, err = geo.NewGeocoder(opts.backend.geocoder); err != nil {
+			return fmt.Errorf("invalid --geocoder: %w", err)
+		}
+		action := "flagged"
+		if opts.geoFilter == agent.GeoDrop {
+			action = "dropped"
+		}
+		extent := "its bounds"
+		if opts.geoRadius > 0 {
+			extent = fmt.Sprintf("%g km", opts.geoRadius)
+		}
+		fmt.Printf("📍 Listings outside %s (%s) are %s\n", opts.geoArea, extent, action)
+	}
+	if opts.relevance != "" {
+		fmt.Printf("🧹 Relevance filter: %s (unrelated search results are dropped)\n", opts.relevance)
+	}
+	if opts.archiveSources || opts.archiveHTML {
+		fmt.Println("🗄️ Source archive enabled: fetched pages are saved next to the report")
+	}
+	if opts.maxAgeDays > 0 {
+		fmt.Printf("📅 Only sources published in the last %d days\n", opts.maxAgeDays)
+	}
+	if opts.reportLanguage != "" {
+		fmt.Printf("🌍 Report language: %s\n", opts.reportLanguage)
+	}
+	if opts.reportTemplate != "" {
+		fmt.Println("📐 Report template: the finished report is rendered with it")
+	}
+	if opts.maxLLMCalls > 0 || opts.maxHTTP > 0 {
+		var limits []string
+		if opts.maxLLMCalls > 0 {
+			limits = append(limits, fmt.Sprintf("%d LLM calls", opts.maxLLMCalls))
+		}
+		if opts.maxHTTP > 0 {
+			limits = append(limits, fmt.Sprintf("%d HTTP requests", opts.maxHTTP))
+		}
+		fmt.Printf("💸 Budget: at most %s of research, then the report is written\n", strings.Join(limits, ", "))
+	}
+	if opts.maxDuration > 0 {
+		fmt.Printf("⏱️ Time limit: finishing within %s, report included\n", opts.maxDuration)
+	}
+	if len(callSettings) > 0 {
+		fmt.Printf("🌡️ LLM call settings: %s\n", describeCallSettings(callSettings))
+	}
+	if len(seedURLs) == 0 && len(sitemapSites) == 0 && len(compare) == 0 {
+		if opts.simpleMode {
+			fmt.Println("⚡ Simple mode: quick research without query expansion (less thorough)")
+		} else {
+			fmt.Println("🔥 Exhaustive mode (default): pre-generating queries, forcing all loops, deduplicating URLs")
+			pagesDesc := "auto (until empty)"
+			if opts.maxPages > 0 {
+				pagesDesc = fmt.Sprintf("%d", opts.maxPages)
+			}
+			fmt.Printf("   Min results: %d | Delay: %dms | Pages per query: %s\n", opts.minResults, opts.delayMs, pagesDesc)
+		}
+	}
+
+	// 1. Setup LLM and search
+	if opts.backend.logger, err = newLogger(cmd); err != nil {
+		return err
+	}
+	llmClient, err := opts.backend.newLLM()
+	if err != nil {
+		return err
+	}
+	callProviders, err := opts.backend.callProviders()
+	if err != nil {
+		return err
+	}
+	searcher, err := opts.backend.newSearcher()
+	if err != nil {
+		return err
+	}
+
+	// 2. Get Input (a checkpoint already carries its topic and plan)
+	reader := bufio.NewReader(os.Stdin)
+	var topic string
+
+	if checkpoint != nil {
+		topic = checkpoint.Topic
+		fmt.Printf("\n♻️ Resuming research topic: %s\n", topic)
+	} else if opts.topic != "" {
+		topic = opts.topic
+		fmt.Printf("\n🧪 Research topic: %s\n", topic)
+	} else {
+		fmt.Print("\n🧪 Enter research topic: ")
+		topic, _ = reader.ReadString('\n')
+		topic = strings.TrimSpace(topic)
+	}
+
+	if topic == "" {
+		return fmt.Errorf("please enter a topic")
+	}
+
+	// The job ID doubles as the checkpoint and report file name stem
+	jobID := strings.TrimSuffix(filepath.Base(checkpointPath), ".checkpoint.json")
+	if checkpoint == nil {
+		jobID = fmt.Sprintf("%s_%s", time.Now().Format("20060102_150405"), safeTopicName(topic))
+
+		// Checkpoints are only written by exhaustive runs
+		checkpointPath = opts.checkpointFile
+		if checkpointPath == "" && !opts.simpleMode && len(seedURLs) == 0 && len(sitemapSites) == 0 && len(compare) == 0 {
+			checkpointPath = filepath.Join("results", jobID+".checkpoint.json")
+		}
+	}
+
+	if opts.batch != nil {
+		opts.batch.jobID = jobID
+	}
+
+	// Fetched pages are archived per job, next to its report
+	sourcesDir := ""
+	if opts.archiveSources || opts.archiveHTML {
+		sourcesDir = filepath.Join("results", jobID, "sources")
+	}
+
+	// Near-duplicate detection and query merging need an embedding model
+	if opts.backend.embeddingModel != "" && opts.deepMode && opts.dedupThreshold > 0 {
+		fmt.Printf("♊ Near-duplicate detection: %s (threshold %.2f)\n", opts.backend.embeddingModel, opts.dedupThreshold)
+	}
+	if opts.backend.embeddingModel != "" && !opts.simpleMode && opts.queryDedup > 0 {
+		fmt.Printf("♊ Similar query merging: %s (threshold %.2f)\n", opts.backend.embeddingModel, opts.queryDedup)
+	}
+
+	// Record the job so it shows up in `list` and can be exported later
+	jobStore := openStore(cmd)
+	if jobStore != nil {
+		defer jobStore.Close()
+	}
+
+	// A collection remembers the sources of earlier runs on the topic
+	var knowledge *agent.Knowledge
+	if opts.collection != "" {
+		if jobStore == nil {
+			return fmt.Errorf("--collection needs the job database (--db)")
+		}
+		if knowledge, err = jobStore.Knowledge(opts.collection); err != nil {
+			return err
+		}
+		if knowledge.LastRun.IsZero() {
+			fmt.Printf("📚 Collection %q: first run\n", opts.collection)
+		} else {
+			fmt.Printf("📚 Collection %q: %d known sources, last run %s\n", opts.collection, len(knowledge.Sources), knowledge.LastRun.Local().Format("2006-01-02 15:04"))
+		}
+		if opts.onlyNew {
+			fmt.Println("🆕 Only new sources: skipping what the collection already has")
+		}
+	}
+
+	// 3. Setup Agent
+	req := opts.request(seedURLs, sitemapSites, compare, callSettings)
+	setup := opts.backend.jobSetup(callProviders)
+	setup.CheckpointPath = checkpointPath
+	setup.SourcesDir = sourcesDir
+	setup.PriceRates = priceRates
+	setup.Geocoder = geocoder
+	setup.Knowledge = knowledge
+	setup.Documents = documents
+	setup.OnProgress = onProgress
+	researcher := agent.NewDeepResearcher(llmClient, searcher, req.AgentConfig(setup))
+
+	// 4. Planning Phase - Interactive Loop
+	var plan agent.ResearchPlan
+	var answers []agent.QuestionAnswer
+	additionalContext := ""
+
+planning:
+	for checkpoint == nil {
+		fmt.Println("\n📋 Creating research plan...")
+		var err error
+
+		// Simple or exhaustive planner per --simple; answers and details from earlier rounds are carried along
+		plan, err = researcher.CreatePlanWithAnswers(context.Background(), topic, answers, additionalContext)
+		if err != nil {
+			return fmt.Errorf("error creating plan: %w", err)
+		}
+
+		printPlan(plan, !opts.simpleMode)
+
+		// Auto-approve if --yes flag is set
+		if opts.autoApprove {
+			fmt.Println("\n✅ Plan auto-approved (--yes flag)! Starting research...")
+			break
+		}
+
+		// Ask for approval (editing the queries keeps the plan; everything else regenerates it)
+		for {
+			fmt.Println("\nOptions:")
+			fmt.Println("  [Enter]  - Approve and start research")
+			if len(plan.ClarifyingQuestions) > 0 {
+				fmt.Println("  [a]      - Answer the clarifying questions")
+			}
+			if len(plan.SearchQueries) > 0 {
+				fmt.Println("  [e]      - Edit the search queries in $EDITOR")
+			}
+			fmt.Println("  [r]      - Revise plan (provide more details)")
+			fmt.Println("  [q]      - Quit")
+			fmt.Print("\nYour choice: ")
+
+			choice, _ := reader.ReadString('\n')
+			choice = strings.TrimSpace(strings.ToLower(choice))
+
+			if choice == "" {
+				fmt.Println("\n✅ Plan approved! Starting research...")
+				break planning
+			} else if choice == "q" {
+				fmt.Println("Research cancelled.")
+				return nil
+			} else if choice == "e" && len(plan.SearchQueries) > 0 {
+				queries, err := editQueries(plan.SearchQueries)
+				if err != nil {
+					fmt.Printf("⚠️ %v\n", err)
+				} else if len(queries) == 0 {
+					fmt.Println("⚠️ No queries left - keeping the previous list")
+				} else {
+					fmt.Printf("✏️ %d queries (was %d)\n", len(queries), len(plan.SearchQueries))
+					plan.SearchQueries = queries
+					printPlan(plan, !opts.simpleMode)
+				}
+				continue
+			} else if choice == "a" && len(plan.ClarifyingQuestions) > 0 {
+				answers = agent.MergeAnswers(answers, askClarifyingQuestions(reader, plan.ClarifyingQuestions))
+			} else if choice == "r" {
+				fmt.Print("\n📝 Enter additional details or answer the questions above:\n> ")
+				additionalContext, _ = reader.ReadString('\n')
+				additionalContext = strings.TrimSpace(additionalContext)
+			} else {
+				// Treat any other input as additional context
+				additionalContext = choice
+			}
+			continue planning
+		}
+	}
+	if checkpoint != nil {
+		plan = checkpoint.Plan
+	}
+
+	saveJob(jobStore, store.Job{ID: jobID, Topic: topic, Status: "running", Plan: &plan, StartedAt: time.Now()})
+
+	// 5. Execute Research
+	// First Ctrl+C finishes now (searching stops and the report is written from
+	// what was gathered), a second one kills the process
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	finish := func() {
+		signal.Stop(interrupt)
+		if researcher.Finish() {
+			fmt.Println("\n⏹️ Finishing now - writing the report from what was gathered (press Ctrl+C again to quit immediately)...")
+		} else {
+			fmt.Println("\n🛑 The report is already being written (press Ctrl+C again to quit immediately)...")
+		}
+	}
+	go func() {
+		if _, ok := <-interrupt; ok {
+			finish()
+		}
+	}()
+	closeDashboard := func() {}
+	if dash != nil {
+		if closeDashboard, err = dash.start(topic, researcher, finish); err != nil {
+			return err
+		}
+		defer closeDashboard()
+	}
+
+	start := time.Now()
+	var result agent.ResearchResult
+
+	// Use simple Run only if --simple flag is set
+	// RunExhaustive is the default
+	if checkpoint != nil {
+		fmt.Printf("💾 Continuing from %s\n", checkpointPath)
+		result, err = researcher.ResumeExhaustive(checkpointPath)
+	} else if opts.simpleMode {
+		result, err = researcher.Run(topic, plan)
+	} else {
+		result, err = researcher.RunExhaustive(topic, plan)
+	}
+	closeDashboard()
+	if err != nil && result.Report == "" {
+		saveJob(jobStore, store.Job{ID: jobID, Topic: topic, Status: "error", Error: err.Error(), Plan: &plan, StartedAt: start})
+		return err
+	}
+
+	if opts.batch != nil {
+		opts.batch.sources = len(result.Sources)
+	}
+
+	// 6. Build final output with bibliography
+	finalOutput := report.Markdown(result)
+	data := []byte(finalOutput)
+	if format != report.FormatMarkdown {
+		if data, err = report.Render(format, topic, result); err != nil {
+			return err
+		}
+	}
+
+	// 7. Determine output file path
+	outPath := opts.outputFile
+	if outPath == "" {
+		// Create results directory
+		if err := os.MkdirAll("results", 0755); err != nil {
+			fmt.Printf("⚠️ Could not create results directory: %v\n", err)
+		}
+		outPath = filepath.Join("results", jobID+"."+format.Extension())
+	}
+
+	// 8. Write to file
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		fmt.Printf("⚠️ Could not write to file: %v\n", err)
+	} else {
+		fmt.Printf("\n📄 Report saved to: %s\n", outPath)
+		if opts.batch != nil {
+			opts.batch.report = outPath
+		}
+	}
+
+	// Extracted records also go to a spreadsheet next to the report
+	if len(result.Records) > 0 && format != report.FormatCSV && format != report.FormatXLSX {
+		csvPath := strings.TrimSuffix(outPath, filepath.Ext(outPath)) + ".csv"
+		if data, err := report.CSV(result); err != nil {
+			fmt.Printf("⚠️ Could not export listings: %v\n", err)
+		} else if err := os.WriteFile(csvPath, data, 0644); err != nil {
+			fmt.Printf("⚠️ Could not write to file: %v\n", err)
+		} else {
+			fmt.Printf("📊 Listings saved to: %s\n", csvPath)
+		}
+	}
+
+	// So does the knowledge graph
+	if len(result.Entities) > 0 && format != report.FormatDOT && format != report.FormatGraphML {
+		dotPath := strings.TrimSuffix(outPath, filepath.Ext(outPath)) + ".dot"
+		if data, err := report.DOT(topic, result); err != nil {
+			fmt.Printf("⚠️ Could not export the knowledge graph: %v\n", err)
+		} else if err := os.WriteFile(dotPath, data, 0644); err != nil {
+			fmt.Printf("⚠️ Could not write to file: %v\n", err)
+		} else {
+			fmt.Printf("🕸️ Knowledge graph saved to: %s (%d entities, %d relations)\n", dotPath, len(result.Entities), len(result.Relations))
+		}
+	}
+
+	// And the claim map, for auditing the report's figures against their sources
+	if len(result.Claims) > 0 {
+		claimsPath := strings.TrimSuffix(outPath, filepath.Ext(outPath)) + ".claims.json"
+		if data, err := json.MarshalIndent(result.Claims, "", "  "); err != nil {
+			fmt.Printf("⚠️ Could not export the claims: %v\n", err)
+		} else if err := os.WriteFile(claimsPath, data, 0644); err != nil {
+			fmt.Printf("⚠️ Could not write to file: %v\n", err)
+		} else {
+			fmt.Printf("🔎 Claim map saved to: %s (%d claims)\n", claimsPath, len(result.Claims))
+		}
+	}
+
+	// Pages saved for auditing the report
+	if sourcesDir != "" {
+		if _, err := os.Stat(filepath.Join(sourcesDir, "index.jsonl")); err == nil {
+			fmt.Printf("🗄️ Sources archived to: %s\n", sourcesDir)
+		}
+	}
+
+	if jobStore != nil {
+		if err := jobStore.SaveResult(jobID, result); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		}
+		saveJob(jobStore, store.Job{ID: jobID, Topic: topic, Status: "complete", Plan: &plan, StartedAt: start})
+		fmt.Printf("🗂️ Job ID: %s\n", jobID)
+	}
+	if knowledge != nil {
+		updated := knowledge.Update(result, jobID, time.Now())
+		if err := jobStore.SaveKnowledge(updated); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		} else {
+			fmt.Printf("📚 Collection %q: %d sources (%d new this run)\n", opts.collection, len(updated.Sources), len(updated.Sources)-len(knowledge.Sources))
+		}
+	}
+
+	if out != nil {
+		return out.result(result)
+	}
+
+	// 9. Print to console
+	fmt.Printf("\n\n%s\n", strings.Repeat("=", 50))
+	fmt.Println(finalOutput)
+	fmt.Printf("%s\n", strings.Repeat("=", 50))
+	fmt.Printf("⏱️ Completed in %v\n", time.Since(start))
+	fmt.Printf("💸 Research used %d LLM calls and %d HTTP requests\n", result.Usage.LLMCalls, result.Usage.HTTPRequests)
+	if usage := result.Usage; usage.PromptTokens+usage.CompletionTokens > 0 {
+		var phases []string
+		for _, callType := range agent.CallTypes {
+			if tokens := usage.Tokens[callType].Total(); tokens > 0 {
+				phases = append(phases, fmt.Sprintf("%s %d", callType, tokens))
+			}
+		}
+		fmt.Printf("🔢 Tokens: %d prompt + %d completion (%s)\n", usage.PromptTokens, usage.CompletionTokens, strings.Join(phases, ", "))
+		if usage.Cost > 0 {
+			fmt.Printf("💵 Estimated cost: $%.4f\n", usage.Cost)
+		}
+	}
+	if result.Usage.Exhausted != "" {
+		fmt.Printf("⚠️ Stopped early: %s (the report covers what was found until then)\n", result.Usage.Exhausted)
+	}
+	if result.Usage.QueriesSkipped > 0 {
+		fmt.Printf("⏱️ Skipped %d planned queries to finish within the time box\n", result.Usage.QueriesSkipped)
+	}
+	if result.Usage.DeadEndQueries > 0 {
+		fmt.Printf("🪦 Skipped %d planned queries whose platforms or wording stopped finding new URLs\n", result.Usage.DeadEndQueries)
+	}
+	if eval := result.Evaluation; eval != nil {
+		if eval.Revised {
+			fmt.Printf("🧐 Report scored %d/10 after revision (was %d/10)\n", eval.Score, eval.InitialScore)
+		} else {
+			fmt.Printf("🧐 Report scored %d/10\n", eval.Score)
+		}
+	}
+	return nil
+}
+
+// applyProfile fills in the profile's settings for every flag not given on the command line
+func (o *researchOptions) applyProfile(fs *pflag.FlagSet, p profile.Profile) {
+	unset := func(flag string) bool { return !fs.Changed(flag) }
+	if p.Loops > 0 && unset("loops") {
+		o.maxLoops = p.Loops
+	}
+	if p.Parallel > 0 && unset("parallel") {
+		o.parallel = p.Parallel
+	}
+	if p.MinResults > 0 && unset("min-results") {
+		o.minResults = p.MinResults
+	}
+	if p.MaxPages > 0 && unset("pages") {
+		o.maxPages = p.MaxPages
+	}
+	if p.ListingsPerQuery > 0 && unset("listings-per-query") {
+		o.listings = p.ListingsPerQuery
+	}
+	if p.DeepMode && unset("deep") {
+		o.deepMode = true
+	}
+	if p.ResultLinks && unset("result-links") {
+		o.resultLinks = true
+	}
+	if p.SimpleMode && unset("simple") {
+		o.simpleMode = true
+	}
+	if p.ExtractionSchema != "" && unset("schema") {
+		o.schema = p.ExtractionSchema
+	}
+	if len(p.IncludeDomains) > 0 && unset("include-domains") {
+		o.includeDomains = p.IncludeDomains
+	}
+	if len(p.ExcludeDomains) > 0 && unset("exclude-domains") {
+		o.excludeDomains = p.ExcludeDomains
+	}
+	if len(p.Categories) > 0 && unset("categories") {
+		o.categories = p.Categories
+	}
+	if p.TimeRange != "" && unset("time-range") {
+		o.timeRange = p.TimeRange
+	}
+	o.planningPrompt = p.Planning
+	o.reportFormat = p.Report
+	o.reportTemplate = p.Template
+}
+
+// describeFilters summarizes the SearXNG search filters for the console
+func describeFilters(categories, engines []string, timeRange string) string {
+	var parts []string
+	if len(categories) > 0 {
+		parts = append(parts, "categories "+strings.Join(categories, ", "))
+	}
+	if len(engines) > 0 {
+		parts = append(parts, "engines "+strings.Join(engines, ", "))
+	}
+	if timeRange != "" {
+		parts = append(parts, "past "+timeRange)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// callSettings collects --call-temperature, --call-max-tokens, and
+// --call-system-prompt into the agent's per-call-type settings
+func (o *researchOptions) callSettings() (map[string]agent.CallSettings, error) {
+	settings := make(map[string]agent.CallSettings)
+	for callType, value := range o.callTemp {
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --call-temperature %s=%s", callType, value)
+		}
+		s := settings[callType]
+		s.Temperature = &t
+		settings[callType] = s
+	}
+	for callType, maxTokens := range o.callMaxTokens {
+		s := settings[callType]
+		s.MaxTokens = maxTokens
+		settings[callType] = s
+	}
+	for _, entry := range o.callPrompts {
+		callType, prompt, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(prompt) == "" {
+			return nil, fmt.Errorf("invalid --call-system-prompt %q (use type=prompt)", entry)
+		}
+		s := settings[callType]
+		s.SystemPrompt = strings.TrimSpace(prompt)
+		settings[callType] = s
+	}
+	if err := agent.ValidateCallSettings(settings); err != nil {
+		return nil, fmt.Errorf("invalid LLM call settings: %w", err)
+	}
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// describeCallSettings summarizes the per-call-type LLM settings for the console
+func describeCallSettings(settings map[string]agent.CallSettings) string {
+	var parts []string
+	for _, callType := range agent.CallTypes {
+		s, ok := settings[callType]
+		if !ok {
+			continue
+		}
+		var set []string
+		if s.Temperature != nil {
+			set = append(set, fmt.Sprintf("temperature %g", *s.Temperature))
+		}
+		if s.MaxTokens > 0 {
+			set = append(set, fmt.Sprintf("max %d tokens", s.MaxTokens))
+		}
+		if s.SystemPrompt != "" {
+			set = append(set, "own system prompt")
+		}
+		parts = append(parts, callType+" "+strings.Join(set, ", "))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// askClarifyingQuestions prompts for an answer to each question; blank answers are skipped
+func askClarifyingQuestions(reader *bufio.Reader, questions []string) []agent.QuestionAnswer {
+	fmt.Println("\n✍️  Answer each question (press Enter to skip):")
+	var answers []agent.QuestionAnswer
+	for i, q := range questions {
+		fmt.Printf("\n   %d. %s\n   > ", i+1, q)
+		answer, _ := reader.ReadString('\n')
+		answers = append(answers, agent.QuestionAnswer{Question: q, Answer: strings.TrimSpace(answer)})
+	}
+	return answers
+}
+
+// printPlan displays a research plan for approval
+func printPlan(plan agent.ResearchPlan, showQueries bool) {
+	fmt.Println("\n" + strings.Repeat("─", 50))
+	fmt.Println("📝 RESEARCH PLAN")
+	fmt.Println(strings.Repeat("─", 50))
+
+	fmt.Printf("\n🎯 Understanding: %s\n", plan.UnderstandingSummary)
+
+	if len(plan.ClarifyingQuestions) > 0 {
+		fmt.Println("\n❓ Clarifying Questions:")
+		for i, q := range plan.ClarifyingQuestions {
+			fmt.Printf("   %d. %s\n", i+1, q)
+		}
+	}
+
+	if len(plan.Answers) > 0 {
+		fmt.Println("\n✍️  Your Answers:")
+		for _, qa := range plan.Answers {
+			fmt.Printf("   • %s → %s\n", qa.Question, qa.Answer)
+		}
+	}
+
+	fmt.Println("\n📌 Research Steps:")
+	for i, step := range plan.ResearchSteps {
+		fmt.Printf("   %d. %s\n", i+1, step)
+	}
+
+	fmt.Printf("\n📊 Expected Outcome: %s\n", plan.ExpectedOutcome)
+
+	// Show search queries (unless in simple mode)
+	if showQueries && len(plan.SearchQueries) > 0 {
+		fmt.Printf("\n🔎 Search Queries (%d total):\n", len(plan.SearchQueries))
+		displayCount := 10
+		if len(plan.SearchQueries) < displayCount {
+			displayCount = len(plan.SearchQueries)
+		}
+		for i := 0; i < displayCount; i++ {
+			fmt.Printf("   %d. %s\n", i+1, plan.SearchQueries[i])
+		}
+		if len(plan.SearchQueries) > displayCount {
+			fmt.Printf("   ... and %d more queries\n", len(plan.SearchQueries)-displayCount)
+		}
+	}
+
+	fmt.Println(strings.Repeat("─", 50))
+}
+
+// readURLsFile reads one URL per line, skipping blank lines and # comments
+func readURLsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read URLs file: %w", err)
+	}
+	var urls []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
+			return nil, fmt.Errorf("%s: not an http(s) URL: %q", path, line)
+		}
+		urls = append(urls, line)
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no URLs in %s", path)
+	}
+	return urls, nil
+}
+
+// workerCount describes a worker limit for startup output (0 = unlimited)
+func workerCount(limit int) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(limit)
+}
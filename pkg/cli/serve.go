@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"deep-research/pkg/geo"
+	"deep-research/pkg/profile"
+	"deep-research/pkg/server"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd() *cobra.Command {
+	var backend backendOptions
+	var port string
+	var maxQueue int
+	var authToken, usersFile, profilesDir string
+	var chat chatOptions
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start the web UI and JSON/SSE API",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := backend.checkModel(); err != nil {
+				return err
+			}
+			var err error
+			if backend.logger, err = newLogger(cmd); err != nil {
+				return err
+			}
+			browser, err := backend.browserConfig()
+			if err != nil {
+				return err
+			}
+			dbPath, _ := cmd.Flags().GetString("db")
+			geocoder, err := geo.NewGeocoder(backend.geocoder)
+			if err != nil {
+				return fmt.Errorf("invalid --geocoder: %w", err)
+			}
+			if backend.noCache {
+				backend.cacheTTL, backend.llmCacheTTL = 0, 0
+			}
+			return server.Run(server.Options{
+				Port:            port,
+				LMURL:           backend.baseURL(),
+				LLMProvider:     backend.llmProvider,
+				APIKey:          backend.llmAPIKey(),
+				Model:           backend.model,
+				EmbeddingModel:  backend.embeddingModel,
+				ContextLen:      backend.contextLen,
+				SummarizerModel: backend.summarizerModel,
+				SummarizerURL:   backend.summarizerURL,
+				WriterModel:     backend.writerModel,
+				WriterURL:       backend.writerURL,
+				CallModels:      backend.callModels,
+				SearXURL:        backend.searxURL,
+				Engines:         backend.engines,
+				BraveAPIKey:     backend.braveAPIKey,
+				GoogleAPIKey:    backend.googleAPIKey,
+				GoogleCX:        backend.googleCX,
+				CacheDir:        backend.cacheDir,
+				CacheTTL:        backend.cacheTTL,
+				LLMCacheTTL:     backend.llmCacheTTL,
+				RateLimit:       backend.rateLimit(),
+				SummaryWorkers:  backend.summarizeWorkers,
+				PlanRepairs:     backend.planRepairs,
+				RoundDigest:     backend.roundDigestChars,
+				PromptPrice:     backend.promptPrice,
+				CompletionPrice: backend.completionPrice,
+				IgnoreRobots:    backend.ignoreRobots,
+				NoWayback:       backend.noWayback,
+				NoEnrich:        backend.noEnrich,
+				Retry:           backend.retryPolicy(),
+				Browser:         browser,
+				Proxies:         backend.proxies,
+				DBPath:          dbPath,
+				MaxQueue:        maxQueue,
+				AuthToken:       authToken,
+				UsersFile:       usersFile,
+				ProfilesDir:     profilesDir,
+				ExtractorsDir:   backend.extractorsDir,
+				Geocoder:        geocoder,
+				Profiles:        settings.Profiles,
+				Logger:          backend.logger,
+
+				SlackWebhook:       chat.slackWebhook,
+				DiscordWebhook:     chat.discordWebhook,
+				PublicURL:          chat.publicURL,
+				SlackSigningSecret: chat.slackSecret,
+				DiscordPublicKey:   chat.discordKey,
+			})
+		},
+	}
+	backend.addFlags(cmd.Flags())
+	backend.addFetchFlags(cmd.Flags())
+	cmd.Flags().StringVar(&port, "port", getEnv("PORT", "8081"), "Port to listen on (env: PORT)")
+	cmd.Flags().IntVar(&maxQueue, "max-queue", getEnvInt("MAX_QUEUE", 10), "Max research requests waiting while a job runs; 0 rejects them (env: MAX_QUEUE)")
+	cmd.Flags().StringVar(&authToken, "auth-token", os.Getenv("AUTH_TOKEN"), "Bearer token required on /api/* routes; the web UI asks for it (env: AUTH_TOKEN)")
+	cmd.Flags().StringVar(&usersFile, "users-file", os.Getenv("USERS_FILE"), "File of name:token lines, each a token accepted on /api/* (env: USERS_FILE)")
+	cmd.Flags().StringVar(&profilesDir, "profiles-dir", getEnv("PROFILES_DIR", profile.DefaultDir), "Directory of YAML research profiles (env: PROFILES_DIR)")
+	cmd.Flags().StringVar(&chat.slackWebhook, "slack-webhook", os.Getenv("SLACK_WEBHOOK_URL"), "Slack incoming webhook told when a plan is ready and a job completes or fails (env: SLACK_WEBHOOK_URL)")
+	cmd.Flags().StringVar(&chat.discordWebhook, "discord-webhook", os.Getenv("DISCORD_WEBHOOK_URL"), "Discord webhook told when a plan is ready and a job completes or fails (env: DISCORD_WEBHOOK_URL)")
+	cmd.Flags().StringVar(&chat.publicURL, "public-url", os.Getenv("PUBLIC_URL"), "Base URL users reach the server at, for report links in chat messages (env: PUBLIC_URL)")
+	cmd.Flags().StringVar(&chat.slackSecret, "slack-signing-secret", os.Getenv("SLACK_SIGNING_SECRET"), "Slack app signing secret; enables the /research slash command at /slack/commands (env: SLACK_SIGNING_SECRET)")
+	cmd.Flags().StringVar(&chat.discordKey, "discord-public-key", os.Getenv("DISCORD_PUBLIC_KEY"), "Discord app public key; enables the /research command at /discord/interactions (env: DISCORD_PUBLIC_KEY)")
+	return cmd
+}
+
+// chatOptions are the serve command's Slack and Discord settings
+type chatOptions struct {
+	slackWebhook, discordWebhook, publicURL string
+	slackSecret, discordKey                 string
+}
@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"bufio"
+	"deep-research/pkg/agent"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dashboardLogLines is how many recent log lines the dashboard keeps
+const dashboardLogLines = 200
+
+// dashboard implements --tui: while research runs, a full-screen view of the
+// queries being searched, the unique URLs found against the target, the phase,
+// and the latest log lines replaces the scrolling console output. Everything
+// printed to stdout meanwhile (the agent's log included) becomes those log lines.
+type dashboard struct {
+	mu      sync.Mutex
+	program *tea.Program // nil until start
+}
+
+// progress forwards a progress event to the dashboard; those sent before start
+// are dropped (safe for concurrent use)
+func (d *dashboard) progress(event agent.ProgressEvent) {
+	d.mu.Lock()
+	p := d.program
+	d.mu.Unlock()
+	if p != nil {
+		p.Send(event)
+	}
+}
+
+// start shows the dashboard for researcher's run on topic: p pauses and resumes it, f
+// (or the first Ctrl+C) calls finish so the report is written from what was
+// found, and q (or a second Ctrl+C) quits without a report. stop closes the
+// dashboard and restores the console; calls after the first do nothing.
+func (d *dashboard) start(topic string, researcher *agent.DeepResearcher, finish func()) (stop func(), err error) {
+	console := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to redirect output for --tui: %w", err)
+	}
+	os.Stdout = w
+
+	m := &dashboardModel{topic: topic, researcher: researcher, finish: finish, started: time.Now(), rows: make(map[int]*queryRow)}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(console))
+	d.mu.Lock()
+	d.program = p
+	d.mu.Unlock()
+
+	// Whatever is printed while the dashboard is up shows as log lines
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				p.Send(logLine(line))
+			}
+		}
+		io.Copy(io.Discard, r) // A line over the buffer ends the scan; keep the pipe drained
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := p.Run(); err != nil {
+			fmt.Fprintf(console, "⚠️ Dashboard failed: %v\n", err)
+		}
+		if m.aborted {
+			os.Stdout = console
+			fmt.Println("🛑 Research cancelled.")
+			os.Exit(130)
+		}
+	}()
+
+	return sync.OnceFunc(func() {
+		d.mu.Lock()
+		d.program = nil
+		d.mu.Unlock()
+		p.Quit()
+		<-done
+		os.Stdout = console
+		w.Close()
+		<-logsDone
+		r.Close()
+	}), nil
+}
+
+// logLine is a line printed while the dashboard is up
+type logLine string
+
+// dashboardTick redraws the dashboard's clock and query yields
+type dashboardTick time.Time
+
+// queryRow is a query of the dashboard's table
+type queryRow struct {
+	index   int
+	query   string
+	pages   int // Result pages requested
+	fetched int // Pages fetched (deep mode)
+}
+
+// dashboardModel is the dashboard's bubbletea model
+type dashboardModel struct {
+	topic      string
+	researcher *agent.DeepResearcher
+	finish     func()
+	started    time.Time
+	width      int
+	height     int
+
+	last      agent.ProgressEvent // Latest event
+	rows      map[int]*queryRow   // By 1-based query index
+	current   int                 // Index of the query searched last
+	stats     map[string]agent.QueryStat
+	logs      []string
+	finishing bool // finish was called
+	aborted   bool // Quit without a report
+}
+
+func (m *dashboardModel) Init() tea.Cmd {
+	return tick()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return dashboardTick(t) })
+}
+
+func (m *dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "p", " ":
+			if m.researcher.Paused() {
+				m.researcher.Resume()
+			} else {
+				m.researcher.Pause()
+			}
+		case "f", "ctrl+c":
+			if m.finishing && msg.String() == "ctrl+c" {
+				m.aborted = true
+				return m, tea.Quit
+			}
+			if !m.finishing {
+				m.finishing = true
+				m.finish()
+			}
+		case "q":
+			m.aborted = true
+			return m, tea.Quit
+		}
+
+	case agent.ProgressEvent:
+		if msg.Phase == agent.PhaseReportChunk {
+			break // The report is shown when the dashboard closes
+		}
+		m.last = msg
+		if msg.QueryIndex > 0 {
+			row := m.rows[msg.QueryIndex]
+			if row == nil {
+				row = &queryRow{index: msg.QueryIndex, query: msg.Query}
+				m.rows[msg.QueryIndex] = row
+			}
+			switch msg.Step {
+			case agent.StepSearch:
+				row.pages = max(row.pages, msg.Page)
+			case agent.StepFetch:
+				row.fetched++
+			}
+			if msg.Step != agent.StepQueryDone {
+				m.current = msg.QueryIndex
+			}
+		}
+
+	case logLine:
+		m.logs = append(m.logs, string(msg))
+		if len(m.logs) > dashboardLogLines {
+			m.logs = m.logs[len(m.logs)-dashboardLogLines:]
+		}
+
+	case dashboardTick:
+		m.stats = make(map[string]agent.QueryStat)
+		for _, s := range m.researcher.QueryStats() {
+			m.stats[s.Query] = s
+		}
+		return m, tick()
+	}
+	return m, nil
+}
+
+func (m *dashboardModel) View() string {
+	width := m.width
+	if width <= 0 {
+		width = 100
+	}
+	height := m.height
+	if height <= 0 {
+		height = 30
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔬 %s\n", clip(m.topic, width-3))
+
+	phase := m.last.Phase
+	if phase == "" {
+		phase = "starting"
+	}
+	status := fmt.Sprintf("Phase: %s", phase)
+	if m.last.TotalRounds > 0 {
+		status += fmt.Sprintf(" · Round %d/%d", m.last.Round, m.last.TotalRounds)
+	}
+	status += fmt.Sprintf(" · %s elapsed", time.Since(m.started).Round(time.Second))
+	if m.last.ETASeconds > 0 && phase == "searching" {
+		status += fmt.Sprintf(" · ~%s left", (time.Duration(m.last.ETASeconds) * time.Second).Round(time.Second))
+	}
+	switch {
+	case m.researcher.Paused():
+		status += " · ⏸️ PAUSED"
+	case m.finishing:
+		status += " · ⏹️ finishing"
+	}
+	fmt.Fprintln(&b, clip(status, width))
+
+	urls := fmt.Sprintf("Unique URLs: %d", m.last.URLsFound)
+	if m.last.TargetURLs > 0 {
+		urls += fmt.Sprintf(" / %d target  %s", m.last.TargetURLs, bar(m.last.URLsFound, m.last.TargetURLs, 20))
+	}
+	urls += fmt.Sprintf("  · %d%% done · %d duplicates", m.last.Percent, m.last.Duplicates)
+	if m.last.Tokens > 0 {
+		urls += fmt.Sprintf(" · %d tokens", m.last.Tokens)
+	}
+	if m.last.Cost > 0 {
+		urls += fmt.Sprintf(" ($%.4f)", m.last.Cost)
+	}
+	fmt.Fprintln(&b, clip(urls, width))
+	fmt.Fprintln(&b, clip(m.last.Message, width))
+	b.WriteString("\n")
+
+	// Query table: the latest queries, as many as fit above the log
+	logRows := max(min(len(m.logs), height/3), 3)
+	tableRows := max(height-10-logRows, 3)
+	queryWidth := max(width-40, 20)
+	fmt.Fprintf(&b, "%5s  %-*s %6s %7s %5s %5s\n", "#", queryWidth, "Query", "Pages", "Fetched", "New", "Dup")
+	first := max(m.current-tableRows+1, 1)
+	for i := first; i <= m.current; i++ {
+		row := m.rows[i]
+		if row == nil {
+			continue
+		}
+		newURLs, dups := "…", ""
+		if s, ok := m.stats[row.query]; ok {
+			newURLs, dups = fmt.Sprint(s.NewURLs), fmt.Sprint(s.Duplicates)
+		} else if i < m.current {
+			newURLs = "✓"
+		}
+		fmt.Fprintf(&b, "%5d  %-*s %6d %7d %5s %5s\n", row.index, queryWidth, clip(row.query, queryWidth), row.pages, row.fetched, newURLs, dups)
+	}
+	if total := m.last.TotalQueries; total > 0 {
+		fmt.Fprintf(&b, "       %d of %d queries searched, %d remaining\n", max(m.current-1, 0), total, m.last.RemainingQueries)
+	}
+	b.WriteString("\n")
+
+	for _, line := range m.logs[max(len(m.logs)-logRows, 0):] {
+		fmt.Fprintln(&b, clip(line, width))
+	}
+	b.WriteString("\n[p] pause/resume   [f] finish now (write the report)   [q] quit without a report")
+	return b.String()
+}
+
+// clip cuts s to at most n runes
+func clip(s string, n int) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// bar draws done out of total as a width-character progress bar
+func bar(done, total, width int) string {
+	filled := min(done*width/max(total, 1), width)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
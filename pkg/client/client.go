@@ -0,0 +1,387 @@
+// Package client is a typed Go client for the web server's API, following
+// pkg/server/openapi.json (served at /api/openapi.json). Requests and responses
+// use the server's own types, so the client can't drift from the server.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/profile"
+	"deep-research/pkg/server"
+	"deep-research/pkg/store"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client calls a deep-research server
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// New creates a client for the server at baseURL (e.g. "http://localhost:8080").
+// token is sent as a bearer token when the server requires auth ("" = none).
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{}, // No timeout: planning and follow-ups wait on the LLM
+	}
+}
+
+// WithHTTPClient returns the client using httpClient for its requests
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	clone := *c
+	clone.httpClient = httpClient
+	return &clone
+}
+
+// Error is an API error response (the server's ErrorResponse envelope)
+type Error struct {
+	StatusCode int
+	Code       string // e.g. "not_found"
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// Event is a progress event of a job's event stream (see Events)
+type Event struct {
+	JobID string `json:"jobId,omitempty"`
+	Seq   int    `json:"seq"` // Position in the job's event log (1 = first)
+	agent.ProgressEvent
+}
+
+// StartResearch starts a job and waits for its plan. A job that has to wait for
+// the current one is queued instead: its Position is then 1 or more.
+func (c *Client) StartResearch(ctx context.Context, req server.ResearchRequest) (*server.QueuedJob, error) {
+	var job server.QueuedJob
+	if err := c.do(ctx, http.MethodPost, "/api/research", req, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Approve starts researching the current job's plan
+func (c *Client) Approve(ctx context.Context) (*server.StatusResponse, error) {
+	var status server.StatusResponse
+	return &status, c.do(ctx, http.MethodPost, "/api/approve", nil, &status)
+}
+
+// Revise regenerates the current job's plan with feedback
+func (c *Client) Revise(ctx context.Context, feedback string) (*server.ResearchJob, error) {
+	var job server.ResearchJob
+	return &job, c.do(ctx, http.MethodPost, "/api/revise", server.ReviseRequest{Feedback: feedback}, &job)
+}
+
+// Answer answers the clarifying questions of a job awaiting answers
+// (ResearchRequest.AskQuestions), or of the plan awaiting approval, and plans
+// from the answers
+func (c *Client) Answer(ctx context.Context, req server.AnswerRequest) (*server.ResearchJob, error) {
+	var job server.ResearchJob
+	return &job, c.do(ctx, http.MethodPost, "/api/answer", req, &job)
+}
+
+// AnswersBeforePlan answers the questions of a job awaiting answers
+// (ResearchRequest.AskQuestions) and builds its plan, like Answer
+func (c *Client) AnswersBeforePlan(ctx context.Context, req server.AnswerRequest) (*server.ResearchJob, error) {
+	var job server.ResearchJob
+	return &job, c.do(ctx, http.MethodPost, "/api/answers", req, &job)
+}
+
+// SetQueries replaces the search queries of the plan awaiting approval
+func (c *Client) SetQueries(ctx context.Context, queries []string) (*server.ResearchJob, error) {
+	var job server.ResearchJob
+	return &job, c.do(ctx, http.MethodPost, "/api/plan/queries", server.QueriesRequest{Queries: queries}, &job)
+}
+
+// Cancel cancels planning, or stops running research without writing a report
+func (c *Client) Cancel(ctx context.Context) (*server.StatusResponse, error) {
+	var status server.StatusResponse
+	return &status, c.do(ctx, http.MethodPost, "/api/cancel", nil, &status)
+}
+
+// Finish stops searching and has the report written from what was found
+func (c *Client) Finish(ctx context.Context) (*server.StatusResponse, error) {
+	var status server.StatusResponse
+	return &status, c.do(ctx, http.MethodPost, "/api/finish", nil, &status)
+}
+
+// Reset clears the finished current job
+func (c *Client) Reset(ctx context.Context) (*server.StatusResponse, error) {
+	var status server.StatusResponse
+	return &status, c.do(ctx, http.MethodPost, "/api/reset", nil, &status)
+}
+
+// Status returns the current job
+func (c *Client) Status(ctx context.Context) (*server.ResearchJob, error) {
+	var job server.ResearchJob
+	return &job, c.do(ctx, http.MethodGet, "/api/status", nil, &job)
+}
+
+// Results returns a job's results ("" = the current job's)
+func (c *Client) Results(ctx context.Context, id string) (*agent.ResearchResult, error) {
+	var result agent.ResearchResult
+	return &result, c.do(ctx, http.MethodGet, jobPath(id, "results", "/api/results"), nil, &result)
+}
+
+// Export downloads a job's report, sources, records, or knowledge graph in a
+// report format such as "md", "pdf", or "graphml" (id "" = the current job)
+func (c *Client) Export(ctx context.Context, id, format string) ([]byte, error) {
+	path := jobPath(id, "export", "/api/results/export") + "?" + url.Values{"format": {format}}.Encode()
+	resp, err := c.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Partial has a draft report written from what the running job has gathered so far
+func (c *Client) Partial(ctx context.Context) (*agent.Draft, error) {
+	var draft agent.Draft
+	return &draft, c.do(ctx, http.MethodGet, "/api/results/partial", nil, &draft)
+}
+
+// FollowUp asks a question about a job's report ("" = the current job's)
+func (c *Client) FollowUp(ctx context.Context, id string, req server.FollowUpRequest) (*agent.FollowUpAnswer, error) {
+	var answer agent.FollowUpAnswer
+	return &answer, c.do(ctx, http.MethodPost, jobPath(id, "followup", "/api/followup"), req, &answer)
+}
+
+// Jobs lists the persisted jobs, most recent first
+func (c *Client) Jobs(ctx context.Context) ([]store.JobSummary, error) {
+	var jobs []store.JobSummary
+	return jobs, c.do(ctx, http.MethodGet, "/api/jobs", nil, &jobs)
+}
+
+// SearchJobs lists the persisted jobs matching filter (words in the topic,
+// tags, or report, and a tag), most recent first
+func (c *Client) SearchJobs(ctx context.Context, filter store.JobFilter) ([]store.JobSummary, error) {
+	query := url.Values{}
+	if filter.Query != "" {
+		query.Set("query", filter.Query)
+	}
+	if filter.Tag != "" {
+		query.Set("tag", filter.Tag)
+	}
+	path := "/api/jobs"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var jobs []store.JobSummary
+	return jobs, c.do(ctx, http.MethodGet, path, nil, &jobs)
+}
+
+// SetTags replaces a persisted job's tags and returns them as stored
+func (c *Client) SetTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	var resp server.TagsRequest
+	err := c.do(ctx, http.MethodPut, "/api/jobs/"+url.PathEscape(id)+"/tags", server.TagsRequest{Tags: tags}, &resp)
+	return resp.Tags, err
+}
+
+// Job returns a persisted job
+func (c *Client) Job(ctx context.Context, id string) (*store.Job, error) {
+	var job store.Job
+	return &job, c.do(ctx, http.MethodGet, "/api/jobs/"+url.PathEscape(id), nil, &job)
+}
+
+// Collections lists the topic collections, most recently run first
+func (c *Client) Collections(ctx context.Context) ([]store.CollectionSummary, error) {
+	var collections []store.CollectionSummary
+	return collections, c.do(ctx, http.MethodGet, "/api/collections", nil, &collections)
+}
+
+// UploadDocument uploads a PDF, Markdown, or text file to ground research with;
+// attach it to a job by ID in ResearchRequest.Documents
+func (c *Client) UploadDocument(ctx context.Context, name string, content io.Reader) (*store.DocumentSummary, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	resp, err := c.sendBody(ctx, http.MethodPost, "/api/documents", form.FormDataContentType(), &body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var saved []store.DocumentSummary
+	if err := json.NewDecoder(resp.Body).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("failed to decode POST /api/documents response: %w", err)
+	}
+	if len(saved) != 1 {
+		return nil, fmt.Errorf("POST /api/documents stored %d documents, expected 1", len(saved))
+	}
+	return &saved[0], nil
+}
+
+// Documents lists the uploaded documents, most recent first
+func (c *Client) Documents(ctx context.Context) ([]store.DocumentSummary, error) {
+	var documents []store.DocumentSummary
+	return documents, c.do(ctx, http.MethodGet, "/api/documents", nil, &documents)
+}
+
+// DeleteDocument deletes an uploaded document
+func (c *Client) DeleteDocument(ctx context.Context, id string) (*server.StatusResponse, error) {
+	var status server.StatusResponse
+	return &status, c.do(ctx, http.MethodDelete, "/api/documents/"+url.PathEscape(id), nil, &status)
+}
+
+// Queue lists the jobs waiting to start, next first
+func (c *Client) Queue(ctx context.Context) ([]server.QueuedJob, error) {
+	var jobs []server.QueuedJob
+	return jobs, c.do(ctx, http.MethodGet, "/api/queue", nil, &jobs)
+}
+
+// RemoveQueued removes a job from the queue
+func (c *Client) RemoveQueued(ctx context.Context, id string) (*server.StatusResponse, error) {
+	var status server.StatusResponse
+	return &status, c.do(ctx, http.MethodDelete, "/api/queue/"+url.PathEscape(id), nil, &status)
+}
+
+// Profiles lists the research profiles
+func (c *Client) Profiles(ctx context.Context) ([]profile.Profile, error) {
+	var profiles []profile.Profile
+	return profiles, c.do(ctx, http.MethodGet, "/api/profiles", nil, &profiles)
+}
+
+// AuthStatus reports whether the server requires auth and who the token belongs to
+func (c *Client) AuthStatus(ctx context.Context) (*server.AuthStatus, error) {
+	var status server.AuthStatus
+	return &status, c.do(ctx, http.MethodGet, "/api/auth/status", nil, &status)
+}
+
+// Health probes the server's dependencies: /readyz when ready is set (an
+// *Error with status 503 when any is down), else /healthz
+func (c *Client) Health(ctx context.Context, ready bool) (*server.HealthReport, error) {
+	path := "/healthz"
+	if ready {
+		path = "/readyz"
+	}
+	var report server.HealthReport
+	return &report, c.do(ctx, http.MethodGet, path, nil, &report)
+}
+
+// Events follows a job's progress ("" = the current job), calling onEvent with
+// each event after seq since (0 = the whole log) until the job completes, ctx is
+// done, or onEvent returns an error
+func (c *Client) Events(ctx context.Context, id string, since int, onEvent func(Event) error) error {
+	path := jobPath(id, "events", "/api/progress")
+	if since > 0 {
+		path += "?since=" + strconv.Itoa(since)
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue // id:, event:, and the blank lines between events
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		if err := onEvent(ev); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+	return ctx.Err()
+}
+
+// jobPath is the job-scoped route of a job, or the current job's route when id is ""
+func jobPath(id, action, current string) string {
+	if id == "" {
+		return current
+	}
+	return "/api/jobs/" + url.PathEscape(id) + "/" + action
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request with an optional JSON body and returns the response, or
+// an *Error for a non-2xx status
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	if body == nil {
+		return c.sendBody(ctx, method, path, "", nil)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return c.sendBody(ctx, method, path, "application/json", bytes.NewReader(data))
+}
+
+// sendBody sends a request with a body of contentType (none when body is nil)
+// and returns the response, or an *Error for a non-2xx status
+func (c *Client) sendBody(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// decodeError reads an error response: the ErrorResponse envelope, or the body
+// as the message for anything else (e.g. a proxy's error page)
+func decodeError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var envelope server.ErrorResponse
+	if json.Unmarshal(data, &envelope) == nil && envelope.Error.Code != "" {
+		return &Error{StatusCode: resp.StatusCode, Code: envelope.Error.Code, Message: envelope.Error.Message}
+	}
+	return &Error{StatusCode: resp.StatusCode, Code: "http_" + strconv.Itoa(resp.StatusCode), Message: strings.TrimSpace(string(data))}
+}
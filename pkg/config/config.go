@@ -0,0 +1,147 @@
+// Package config reads deep-research.yaml, the settings file shared by the CLI
+// and the web server. Each top-level key is the name of a command-line flag
+// (lm-url, engines, loops, exclude-domains, ...) and sets its default; flags and
+// their env vars still win. A "profiles" list adds research profiles.
+package config
+
+import (
+	"bytes"
+	"deep-research/pkg/profile"
+	"deep-research/pkg/search"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the config file looked for by Find
+const FileName = "deep-research.yaml"
+
+// EnvPath is the env var that points at a config file instead of the search path
+const EnvPath = "DEEP_RESEARCH_CONFIG"
+
+// File is a loaded config file
+type File struct {
+	Path     string            // Where it was read from (empty when there is none)
+	Settings map[string]string // By flag name; lists are joined with commas
+	Profiles []profile.Profile // Inline research profiles
+}
+
+// SearchPaths returns where Find looks, in order: the working directory, then
+// ~/.config/deep-research/
+func SearchPaths() []string {
+	paths := []string{FileName, strings.TrimSuffix(FileName, ".yaml") + ".yml"}
+	if home, err := os.UserHomeDir(); err == nil {
+		dir := filepath.Join(home, ".config", "deep-research")
+		paths = append(paths, filepath.Join(dir, FileName), filepath.Join(dir, "config.yaml"))
+	}
+	return paths
+}
+
+// Find returns the first config file on the search path, or "" if there is none
+func Find() string {
+	for _, path := range SearchPaths() {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads the config file at path; an empty path means $DEEP_RESEARCH_CONFIG,
+// else the first file Find finds. No file at all gives an empty File.
+func Load(path string) (*File, error) {
+	if path == "" {
+		path = os.Getenv(EnvPath)
+	}
+	if path == "" {
+		path = Find()
+	}
+	file := &File{Path: path, Settings: make(map[string]string)}
+	if path == "" {
+		return file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("config file %s not found", path)
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var raw struct {
+		Profiles []profile.Profile `yaml:"profiles"`
+		Settings map[string]any    `yaml:",inline"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, value := range raw.Settings {
+		s, err := settingString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		file.Settings[name] = s
+	}
+	for i, p := range raw.Profiles {
+		if p.Name == "" {
+			return nil, fmt.Errorf("%s: profile %d has no name", path, i+1)
+		}
+		if err := search.ValidateTimeRange(p.TimeRange); err != nil {
+			return nil, fmt.Errorf("%s: profile %s: %w", path, p.Name, err)
+		}
+	}
+	file.Profiles = raw.Profiles
+	return file, nil
+}
+
+// settingString flattens a YAML value to the text a flag would be given
+func settingString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := settingString(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		return "", fmt.Errorf("expected a value or a list, not a mapping (settings are flag names, e.g. lm-url)")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// Get returns the setting for a flag name
+func (f *File) Get(name string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	value, ok := f.Settings[name]
+	return value, ok
+}
+
+// Unknown returns the settings not named in known, sorted
+func (f *File) Unknown(known map[string]bool) []string {
+	var unknown []string
+	for name := range f.Settings {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
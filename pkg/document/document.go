@@ -0,0 +1,79 @@
+// Package document reads the files a user provides as prior knowledge for a
+// research run (agent.Config.Documents): PDF, Markdown, and plain text.
+package document
+
+import (
+	"bytes"
+	"deep-research/pkg/pdf"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxSize is the largest file Parse accepts
+const MaxSize = 20 << 20
+
+// ErrUnsupported is Parse's error for files that are neither PDF nor text
+var ErrUnsupported = errors.New("unsupported document type (use PDF, Markdown, or plain text)")
+
+// Document is a provided file's text
+type Document struct {
+	Name string // File name, e.g. "brief.pdf"
+	Text string
+}
+
+// Words returns the number of words in the document
+func (d Document) Words() int {
+	return len(strings.Fields(d.Text))
+}
+
+// Load reads and parses the file at path
+func Load(path string) (Document, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Document{}, err
+	}
+	if info.Size() > MaxSize {
+		return Document{}, fmt.Errorf("%s: larger than %d MB", path, MaxSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, err
+	}
+	return Parse(filepath.Base(path), data)
+}
+
+// blankLinesRe matches runs of blank lines
+var blankLinesRe = regexp.MustCompile(`\n\s*\n\s*`)
+
+// Parse extracts the text of a file: a PDF's text (see pdf.Text), else the
+// file itself when it is UTF-8 text such as Markdown
+func Parse(name string, data []byte) (Document, error) {
+	if len(data) > MaxSize {
+		return Document{}, fmt.Errorf("%s: larger than %d MB", name, MaxSize>>20)
+	}
+
+	var text string
+	switch {
+	case strings.EqualFold(filepath.Ext(name), ".pdf") || bytes.HasPrefix(data, []byte("%PDF-")):
+		var err error
+		if text, err = pdf.Text(data); err != nil {
+			return Document{}, fmt.Errorf("%s: %w", name, err)
+		}
+	case utf8.Valid(data):
+		text = string(data)
+	default:
+		return Document{}, fmt.Errorf("%s: %w", name, ErrUnsupported)
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSpace(blankLinesRe.ReplaceAllString(text, "\n\n"))
+	if text == "" {
+		return Document{}, fmt.Errorf("%s: no text found", name)
+	}
+	return Document{Name: name, Text: text}, nil
+}
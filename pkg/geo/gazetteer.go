@@ -0,0 +1,138 @@
+package geo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Gazetteer geocodes offline from a list of places and postal codes
+type Gazetteer struct {
+	places map[string][]gazetteerEntry // By lowercased name or postal code
+}
+
+// gazetteerEntry is a place of the gazetteer
+type gazetteerEntry struct {
+	name       string
+	point      Point
+	population int
+}
+
+// LoadGazetteer reads a gazetteer file: a GeoNames cities or allCountries
+// dump (tab-separated, https://download.geonames.org/export/dump/), a GeoNames
+// postal code dump (https://download.geonames.org/export/zip/), or CSV lines of
+// name,lat,lon with an optional population
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g := &Gazetteer{places: make(map[string][]gazetteerEntry)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := g.add(text); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(g.places) == 0 {
+		return nil, fmt.Errorf("no places in %s", path)
+	}
+	return g, nil
+}
+
+// add reads a line of a gazetteer file
+func (g *Gazetteer) add(line string) error {
+	fields := strings.Split(line, "\t")
+	var names []string
+	var lat, lon, population string
+	switch {
+	case len(fields) >= 15:
+		// GeoNames: name, ASCII name, alternate names, latitude, longitude, ..., population
+		names = append([]string{fields[1], fields[2]}, strings.Split(fields[3], ",")...)
+		lat, lon, population = fields[4], fields[5], fields[14]
+	case len(fields) >= 11:
+		// GeoNames postal codes: country, postal code, place name, ..., latitude, longitude
+		names = []string{fields[1]}
+		lat, lon = fields[9], fields[10]
+	default:
+		fields = strings.Split(line, ",")
+		if len(fields) < 3 {
+			return fmt.Errorf("expected name,lat,lon")
+		}
+		names = fields[:1]
+		lat, lon = fields[1], fields[2]
+		if len(fields) > 3 {
+			population = fields[3]
+		}
+	}
+	point, ok := ParsePoint(lat + "," + lon)
+	if !ok {
+		return fmt.Errorf("invalid coordinates %s,%s", lat, lon)
+	}
+	entry := gazetteerEntry{name: strings.TrimSpace(names[0]), point: point}
+	entry.population, _ = strconv.Atoi(strings.TrimSpace(population))
+	for _, name := range names {
+		if key := gazetteerKey(name); key != "" {
+			g.places[key] = append(g.places[key], entry)
+		}
+	}
+	return nil
+}
+
+// Geocode finds the first comma-separated part of query that is a known place
+// or postal code ("Str. Horea 5, Cluj-Napoca" finds Cluj-Napoca); a part
+// without a match is searched for a postal code ("400001 Cluj"). Of the places
+// with the name, the one closest to near wins, else the most populous.
+func (g *Gazetteer) Geocode(ctx context.Context, query string, near *Point) (Place, error) {
+	for _, part := range strings.Split(query, ",") {
+		candidates := []string{part}
+		for _, word := range strings.Fields(part) {
+			if len(word) >= 3 && strings.ContainsFunc(word, unicode.IsDigit) {
+				candidates = append(candidates, word)
+			}
+		}
+		for _, c := range candidates {
+			if entry, ok := g.best(g.places[gazetteerKey(c)], near); ok {
+				return Place{Name: entry.name, Point: entry.point}, nil
+			}
+		}
+	}
+	return Place{}, ErrNotFound
+}
+
+// best picks the entry closest to near, else the most populous
+func (g *Gazetteer) best(entries []gazetteerEntry, near *Point) (gazetteerEntry, bool) {
+	if len(entries) == 0 {
+		return gazetteerEntry{}, false
+	}
+	best := entries[0]
+	for _, e := range entries[1:] {
+		if near != nil {
+			if Distance(e.point, *near) < Distance(best.point, *near) {
+				best = e
+			}
+		} else if e.population > best.population {
+			best = e
+		}
+	}
+	return best, true
+}
+
+// gazetteerKey normalizes a place name or postal code for lookup
+func gazetteerKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,97 @@
+// Package geo geocodes the places listings are in ("Str. Horea 5, Cluj-Napoca",
+// "400001", "Florești") and measures how far they are from an area, with
+// OpenStreetMap's Nominatim (or a compatible server) or an offline GeoNames
+// gazetteer.
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when a geocoder doesn't know a place
+var ErrNotFound = errors.New("place not found")
+
+// earthRadiusKm is the Earth's mean radius
+const earthRadiusKm = 6371.0
+
+// Point is a position in degrees
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// String formats the point like "46.7712,23.6236"
+func (p Point) String() string {
+	return strconv.FormatFloat(p.Lat, 'f', 4, 64) + "," + strconv.FormatFloat(p.Lon, 'f', 4, 64)
+}
+
+// Box is an area's bounding box in degrees
+type Box struct {
+	South, North float64
+	West, East   float64
+}
+
+// Contains reports whether p lies in the box
+func (b Box) Contains(p Point) bool {
+	return p.Lat >= b.South && p.Lat <= b.North && p.Lon >= b.West && p.Lon <= b.East
+}
+
+// Place is a geocoded place
+type Place struct {
+	Name  string // The geocoder's name for it, e.g. "Florești, Cluj, Romania"
+	Point Point
+	Box   *Box // Its bounds, when the geocoder knows them (nil for a gazetteer's points)
+}
+
+// Geocoder finds where a place is. near, when set, is a hint to prefer the
+// place of that name closest to it (there are many Florești).
+type Geocoder interface {
+	Geocode(ctx context.Context, query string, near *Point) (Place, error)
+}
+
+// Distance is the great-circle distance between a and b in kilometers
+func Distance(a, b Point) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := rad(b.Lat-a.Lat), rad(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// ParsePoint reads coordinates written as "lat,lon", e.g. "46.77,23.59"
+func ParsePoint(s string) (Point, bool) {
+	latText, lonText, ok := strings.Cut(s, ",")
+	if !ok {
+		return Point{}, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return Point{}, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonText), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return Point{}, false
+	}
+	return Point{Lat: lat, Lon: lon}, true
+}
+
+// NewGeocoder returns the geocoder of source: "" for Nominatim at
+// DefaultNominatimURL, the http(s) URL of a Nominatim-compatible server, or the
+// path of a gazetteer file (see LoadGazetteer)
+func NewGeocoder(source string) (Geocoder, error) {
+	switch {
+	case source == "":
+		return NewNominatim(DefaultNominatimURL), nil
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		return NewNominatim(source), nil
+	}
+	g, err := LoadGazetteer(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gazetteer: %w", err)
+	}
+	return g, nil
+}
@@ -0,0 +1,129 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultNominatimURL is OpenStreetMap's public Nominatim server
+const DefaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+// nominatimInterval spaces the requests to a Nominatim server; the public one
+// allows one per second
+const nominatimInterval = time.Second
+
+// Nominatim geocodes with a Nominatim server. Requests are spaced a second
+// apart and answers are cached for the geocoder's lifetime.
+type Nominatim struct {
+	baseURL string
+	client  *http.Client
+
+	mu    sync.Mutex
+	last  time.Time
+	cache map[string]nominatimAnswer
+}
+
+// nominatimAnswer is a cached answer to a query
+type nominatimAnswer struct {
+	place Place
+	err   error
+}
+
+// NewNominatim returns a geocoder for the Nominatim server at baseURL
+func NewNominatim(baseURL string) *Nominatim {
+	return &Nominatim{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		cache:   make(map[string]nominatimAnswer),
+	}
+}
+
+// Geocode looks up query's best match; with near, matches within about 100 km
+// of it are preferred
+func (n *Nominatim) Geocode(ctx context.Context, query string, near *Point) (Place, error) {
+	params := url.Values{"q": {query}, "format": {"jsonv2"}, "limit": {"1"}}
+	if near != nil {
+		params.Set("viewbox", fmt.Sprintf("%f,%f,%f,%f", near.Lon-1, near.Lat+1, near.Lon+1, near.Lat-1))
+	}
+	key := params.Encode()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if answer, ok := n.cache[key]; ok {
+		return answer.place, answer.err
+	}
+	if wait := nominatimInterval - time.Since(n.last); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return Place{}, ctx.Err()
+		}
+	}
+	place, err := n.search(ctx, key)
+	n.last = time.Now()
+	if err == nil || err == ErrNotFound {
+		n.cache[key] = nominatimAnswer{place, err}
+	}
+	return place, err
+}
+
+// search sends a search request with the encoded params
+func (n *Nominatim) search(ctx context.Context, params string) (Place, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", n.baseURL+"/search?"+params, nil)
+	if err != nil {
+		return Place{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Nominatim's usage policy requires an identifying User-Agent
+	req.Header.Set("User-Agent", "deep-research (https://github.com/clglavan/deep-research)")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return Place{}, fmt.Errorf("geocoding failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Place{}, fmt.Errorf("geocoding failed (status %d)", resp.StatusCode)
+	}
+
+	var results []struct {
+		DisplayName string   `json:"display_name"`
+		Lat         string   `json:"lat"`
+		Lon         string   `json:"lon"`
+		BoundingBox []string `json:"boundingbox"` // south, north, west, east
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&results); err != nil {
+		return Place{}, fmt.Errorf("failed to parse geocoding response: %w", err)
+	}
+	if len(results) == 0 {
+		return Place{}, ErrNotFound
+	}
+	r := results[0]
+	lat, err := strconv.ParseFloat(r.Lat, 64)
+	if err != nil {
+		return Place{}, fmt.Errorf("invalid latitude %q", r.Lat)
+	}
+	lon, err := strconv.ParseFloat(r.Lon, 64)
+	if err != nil {
+		return Place{}, fmt.Errorf("invalid longitude %q", r.Lon)
+	}
+	place := Place{Name: r.DisplayName, Point: Point{Lat: lat, Lon: lon}}
+	if len(r.BoundingBox) == 4 {
+		var bounds [4]float64
+		valid := true
+		for i, s := range r.BoundingBox {
+			bounds[i], err = strconv.ParseFloat(s, 64)
+			valid = valid && err == nil
+		}
+		if valid {
+			place.Box = &Box{South: bounds[0], North: bounds[1], West: bounds[2], East: bounds[3]}
+		}
+	}
+	return place, nil
+}
@@ -0,0 +1,270 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAnthropicURL is the base URL of the Anthropic API
+	DefaultAnthropicURL = "https://api.anthropic.com/v1"
+
+	// DefaultAnthropicMaxTokens is the response limit sent when none is
+	// configured: the Messages API requires one
+	DefaultAnthropicMaxTokens = 8192
+
+	// anthropicVersion is the Messages API version requested
+	anthropicVersion = "2023-06-01"
+
+	// statusOverloaded is the status Anthropic answers with when it is overloaded
+	statusOverloaded = 529
+)
+
+// AnthropicClient talks to the Anthropic Messages API (/v1/messages)
+type AnthropicClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// NewAnthropicClient creates a new Anthropic client
+func NewAnthropicClient(cfg Config) *AnthropicClient {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 120 * time.Second
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultAnthropicURL
+	}
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	if !slices.Contains(cfg.Retry.RetryableStatus, statusOverloaded) {
+		cfg.Retry.RetryableStatus = append(slices.Clip(cfg.Retry.RetryableStatus), statusOverloaded)
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &AnthropicClient{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: cfg.Transport,
+		},
+	}
+}
+
+// anthropicRequest represents the Messages API request. System prompts go in
+// System rather than in Messages, which only hold user and assistant turns.
+type anthropicRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+// anthropicUsage is the usage of a Messages API response
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u anthropicUsage) tokens() TokenUsage {
+	return TokenUsage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens}
+}
+
+// anthropicError is the error of a failed request or stream
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// anthropicResponse represents the Messages API response
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage anthropicUsage  `json:"usage"`
+	Error *anthropicError `json:"error,omitempty"`
+}
+
+// anthropicEvent is one server-sent event of a streamed response
+type anthropicEvent struct {
+	Type    string `json:"type"` // message_start, content_block_delta, message_delta, message_stop, ping, error
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"` // message_start
+	Delta struct {
+		Type string `json:"type"` // text_delta (thinking and other deltas are skipped)
+		Text string `json:"text"`
+	} `json:"delta"` // content_block_delta
+	Usage anthropicUsage  `json:"usage"` // message_delta: the output tokens so far
+	Error *anthropicError `json:"error,omitempty"`
+}
+
+// request builds the Messages API request for messages, with the generation
+// settings of ctx
+func (c *AnthropicClient) request(ctx context.Context, messages []Message, stream bool) anthropicRequest {
+	temperature, maxTokens := c.config.generation(ctx)
+	if maxTokens <= 0 {
+		maxTokens = DefaultAnthropicMaxTokens
+	}
+	req := anthropicRequest{
+		Model:       c.config.Model,
+		MaxTokens:   maxTokens,
+		Temperature: min(temperature, 1), // The API's range is 0-1
+		Stream:      stream,
+	}
+	var system []string
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+			continue
+		}
+		req.Messages = append(req.Messages, m)
+	}
+	req.System = strings.Join(system, "\n\n")
+	return req
+}
+
+// Chat sends a chat request to the Messages API. A prompt too large for the
+// model's context window fails with a *ContextOverflowError.
+func (c *AnthropicClient) Chat(ctx context.Context, messages []Message) (string, error) {
+	jsonBody, err := json.Marshal(c.request(ctx, messages, false))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	body, err := c.post(ctx, c.config.BaseURL+"/messages", jsonBody)
+	if err != nil {
+		return "", detectOverflow(err)
+	}
+
+	var msgResp anthropicResponse
+	if err := json.Unmarshal(body, &msgResp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if msgResp.Error != nil {
+		return "", detectOverflow(fmt.Errorf("API returned error: %s", msgResp.Error.Message))
+	}
+
+	var text strings.Builder
+	for _, block := range msgResp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if len(msgResp.Content) == 0 {
+		return "", fmt.Errorf("no content in response")
+	}
+	recordUsage(ctx, msgResp.Usage.tokens())
+	return text.String(), nil
+}
+
+// ChatStream sends a chat request to the Messages API with streaming enabled
+// and passes each piece of the response to onDelta as the server sends it
+func (c *AnthropicClient) ChatStream(ctx context.Context, messages []Message, onDelta func(string)) (string, error) {
+	jsonBody, err := json.Marshal(c.request(ctx, messages, true))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := openStream(ctx, c.httpClient, c.config.Retry, c.config.BaseURL+"/messages", jsonBody, c.setHeaders)
+	if err != nil {
+		return "", detectOverflow(err)
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	var usage anthropicUsage
+	err = readLines(resp.Body, func(line []byte) error {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			return nil // The event: lines repeat the data's type
+		}
+		var event anthropicEvent
+		if err := json.Unmarshal(bytes.TrimSpace(data), &event); err != nil {
+			return fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		switch event.Type {
+		case "error":
+			if event.Error == nil {
+				return fmt.Errorf("API returned an error")
+			}
+			return detectOverflow(fmt.Errorf("API returned error: %s", event.Error.Message))
+		case "message_start":
+			usage = event.Message.Usage
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				full.WriteString(event.Delta.Text)
+				onDelta(event.Delta.Text)
+			}
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+		case "message_stop":
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	recordUsage(ctx, usage.tokens())
+	return full.String(), nil
+}
+
+// WithModel returns a client for model at baseURL (empty = keep this client's)
+func (c *AnthropicClient) WithModel(baseURL, model string) Provider {
+	return NewAnthropicClient(c.config.withModel(baseURL, model))
+}
+
+// ListModels returns the model IDs from the Anthropic /models endpoint
+func (c *AnthropicClient) ListModels(ctx context.Context) ([]string, error) {
+	body, err := c.send(ctx, "GET", c.config.BaseURL+"/models?limit=1000", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	models := make([]string, len(resp.Data))
+	for i, m := range resp.Data {
+		models[i] = m.ID
+	}
+	slices.Sort(models)
+	return models, nil
+}
+
+// cacheIdentity is the provider, model, and generation settings a response depends on
+func (c *AnthropicClient) cacheIdentity(ctx context.Context) string {
+	temperature, maxTokens := c.config.generation(ctx)
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%g\x00%d", ProviderAnthropic, c.config.BaseURL, c.config.Model, temperature, maxTokens)
+}
+
+// post sends a JSON POST request, retrying transient failures per the configured policy
+func (c *AnthropicClient) post(ctx context.Context, url string, jsonBody []byte) ([]byte, error) {
+	return c.send(ctx, "POST", url, jsonBody)
+}
+
+// send performs one API request with retries and returns the body of the 200 response
+func (c *AnthropicClient) send(ctx context.Context, method, url string, jsonBody []byte) ([]byte, error) {
+	return sendRequest(ctx, c.httpClient, c.config.Retry, method, url, jsonBody, c.setHeaders)
+}
+
+// setHeaders adds the API key and version the Messages API requires
+func (c *AnthropicClient) setHeaders(req *http.Request) {
+	req.Header.Set("x-api-key", c.config.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
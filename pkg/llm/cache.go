@@ -0,0 +1,277 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"deep-research/pkg/logging"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached responses are reused unless CacheConfig.TTL says otherwise
+const DefaultCacheTTL = 7 * 24 * time.Hour
+
+// CacheConfig configures the on-disk response cache
+type CacheConfig struct {
+	Dir    string        // Cache directory (created on first write)
+	TTL    time.Duration // How long entries stay fresh (default DefaultCacheTTL)
+	Logger *slog.Logger  // Where cache hits (debug) and write failures are reported (nil = console on stdout)
+}
+
+// CachedProvider wraps a Provider with a disk cache so plan revisions, resumed
+// runs, and repeated page summaries don't redo identical inference. Responses
+// are keyed by a hash of the model, its generation settings, and the messages.
+// Errors and empty responses are never cached. Providers other than Client,
+// OllamaClient, and LlamaCppClient can't be told apart by model, so their calls
+// are not cached.
+type CachedProvider struct {
+	Provider
+	config CacheConfig
+}
+
+// cacheIdentifier is implemented by providers whose output is determined by the
+// messages plus the settings it returns for a call made with ctx
+type cacheIdentifier interface {
+	cacheIdentity(ctx context.Context) string
+}
+
+// cacheEntry is the on-disk format of one cached response
+type cacheEntry struct {
+	Key      string    `json:"key"`
+	StoredAt time.Time `json:"storedAt"`
+	Response string    `json:"response"`
+}
+
+var (
+	errNoEmbedder  = errors.New("wrapped LLM provider can't compute embeddings")
+	errNoTokenizer = errors.New("wrapped LLM provider can't count tokens")
+	errNoLister    = errors.New("wrapped LLM provider can't list models")
+)
+
+// NewCachedProvider wraps p with a disk cache in cfg.Dir
+func NewCachedProvider(p Provider, cfg CacheConfig) *CachedProvider {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultCacheTTL
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	return &CachedProvider{Provider: p, config: cfg}
+}
+
+// Chat returns the cached response to messages or asks the wrapped provider
+func (c *CachedProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	id, ok := c.Provider.(cacheIdentifier)
+	if !ok {
+		return c.Provider.Chat(ctx, messages)
+	}
+
+	key, err := c.key(ctx, id, messages)
+	if err != nil {
+		return "", err
+	}
+	return c.cached(key, func() (string, error) {
+		return c.Provider.Chat(ctx, messages)
+	})
+}
+
+// ChatJSON returns the cached response to messages under schema or asks the
+// wrapped provider for it (see RequestJSON)
+func (c *CachedProvider) ChatJSON(ctx context.Context, messages []Message, schema Schema) (string, error) {
+	id, ok := c.Provider.(cacheIdentifier)
+	if !ok {
+		return RequestJSON(ctx, c.Provider, messages, schema)
+	}
+
+	// The schema is part of the request, so it's part of the key
+	key, err := c.key(ctx, id, append(messages[:len(messages):len(messages)], Message{Role: "schema", Content: string(schema.Schema)}))
+	if err != nil {
+		return "", err
+	}
+	return c.cached(key, func() (string, error) {
+		return RequestJSON(ctx, c.Provider, messages, schema)
+	})
+}
+
+// cached returns the response stored under key, or asks for it and stores it
+func (c *CachedProvider) cached(key string, ask func() (string, error)) (string, error) {
+	if resp, ok := c.get(key); ok {
+		c.config.Logger.Debug("🗄️ LLM cache hit", "key", key[:12])
+		return resp, nil
+	}
+
+	resp, err := ask()
+	if err != nil {
+		return "", err
+	}
+	if resp != "" {
+		c.put(key, resp)
+	}
+	return resp, nil
+}
+
+// ChatStream streams the wrapped provider's response to messages, caching it
+// like Chat. A cached response is passed to onDelta all at once; so is the
+// response of a wrapped provider that can't stream.
+func (c *CachedProvider) ChatStream(ctx context.Context, messages []Message, onDelta func(string)) (string, error) {
+	stream := func() (string, error) {
+		if streamer, ok := c.Provider.(Streamer); ok {
+			return streamer.ChatStream(ctx, messages, onDelta)
+		}
+		resp, err := c.Provider.Chat(ctx, messages)
+		if err == nil {
+			onDelta(resp)
+		}
+		return resp, err
+	}
+
+	id, ok := c.Provider.(cacheIdentifier)
+	if !ok {
+		return stream()
+	}
+	key, err := c.key(ctx, id, messages)
+	if err != nil {
+		return "", err
+	}
+	if resp, ok := c.get(key); ok {
+		c.config.Logger.Debug("🗄️ LLM cache hit", "key", key[:12])
+		onDelta(resp)
+		return resp, nil
+	}
+
+	resp, err := stream()
+	if err != nil {
+		return "", err
+	}
+	if resp != "" {
+		c.put(key, resp)
+	}
+	return resp, nil
+}
+
+// key is the cache key of messages sent with ctx to the provider identified by id
+func (c *CachedProvider) key(ctx context.Context, id cacheIdentifier, messages []Message) (string, error) {
+	msgs, err := json.Marshal(messages)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal messages: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(id.cacheIdentity(ctx)+"\x00"), msgs...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// WithModel returns the wrapped provider's client for model at baseURL, cached in the same directory
+func (c *CachedProvider) WithModel(baseURL, model string) Provider {
+	selector, ok := c.Provider.(ModelSelector)
+	if !ok {
+		return c
+	}
+	return NewCachedProvider(selector.WithModel(baseURL, model), c.config)
+}
+
+// Embeddings computes embeddings through the wrapped provider (uncached)
+func (c *CachedProvider) Embeddings(ctx context.Context, inputs []string) ([][]float64, error) {
+	embedder, ok := c.Provider.(Embedder)
+	if !ok {
+		return nil, errNoEmbedder
+	}
+	return embedder.Embeddings(ctx, inputs)
+}
+
+// CountTokens counts tokens through the wrapped provider
+func (c *CachedProvider) CountTokens(ctx context.Context, text string) (int, error) {
+	tokenizer, ok := c.Provider.(Tokenizer)
+	if !ok {
+		return 0, errNoTokenizer
+	}
+	return tokenizer.CountTokens(ctx, text)
+}
+
+// ListModels lists the wrapped provider's models
+func (c *CachedProvider) ListModels(ctx context.Context) ([]string, error) {
+	lister, ok := c.Provider.(ModelLister)
+	if !ok {
+		return nil, errNoLister
+	}
+	return lister.ListModels(ctx)
+}
+
+// cacheIdentity is the provider, model, and generation settings a response depends on
+func (c *Client) cacheIdentity(ctx context.Context) string {
+	temperature, maxTokens := c.config.generation(ctx)
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%g\x00%d", c.config.Provider, c.config.BaseURL, c.config.Model, temperature, maxTokens)
+}
+
+// cacheIdentity is the provider, model, and generation settings a response depends on
+func (c *OllamaClient) cacheIdentity(ctx context.Context) string {
+	temperature, maxTokens := c.config.generation(ctx)
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%g\x00%d", ProviderOllama, c.config.BaseURL, c.config.Model, temperature, maxTokens)
+}
+
+// cacheIdentity is the provider, model, and generation settings a response depends on
+func (c *LlamaCppClient) cacheIdentity(ctx context.Context) string {
+	temperature, maxTokens := c.config.generation(ctx)
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%g\x00%d", ProviderLlamaCpp, c.config.BaseURL, c.config.Model, temperature, maxTokens)
+}
+
+// path returns the cache file for key
+func (c *CachedProvider) path(key string) string {
+	return filepath.Join(c.config.Dir, key[:2], key+".json")
+}
+
+// get returns the fresh response stored under key. Expired entries are removed.
+func (c *CachedProvider) get(key string) (string, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return "", false
+	}
+	if time.Since(entry.StoredAt) > c.config.TTL {
+		os.Remove(path)
+		return "", false
+	}
+	return entry.Response, true
+}
+
+// put stores resp under key; the cache is best-effort, so failures are only logged
+func (c *CachedProvider) put(key, resp string) {
+	if err := c.write(key, resp); err != nil {
+		c.config.Logger.Warn("⚠️ LLM cache write failed", "error", err)
+	}
+}
+
+// write atomically replaces the cache file for key
+func (c *CachedProvider) write(key, resp string) error {
+	data, err := json.Marshal(cacheEntry{Key: key, StoredAt: time.Now(), Response: resp})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	err = errors.Join(err, tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -2,28 +2,36 @@ package llm
 
 import (
 	"bytes"
+	"context"
+	"deep-research/pkg/retry"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
 // Config holds the configuration for the LLM client
 type Config struct {
-	BaseURL       string
-	APIKey        string
-	Model         string
-	Temperature   float64
-	MaxTokens     int
-	ContextLength int // n_ctx for LM Studio
-	Timeout       time.Duration
+	Provider       string // API flavor of Client: lmstudio (default), openai, azure, or openrouter (set by NewProvider)
+	BaseURL        string
+	APIKey         string // Sent as a bearer token (Azure: api-key header); omitted when empty
+	Model          string
+	EmbeddingModel string // Model used by Embeddings (optional; embeddings are unavailable without it)
+	Temperature    float64
+	MaxTokens      int
+	ContextLength  int // n_ctx for LM Studio (hosted APIs reject it, so it isn't sent to them)
+	Timeout        time.Duration
+	Retry          retry.Policy      // Retry policy for transient failures (zero value = retry.DefaultPolicy())
+	Transport      http.RoundTripper // Transport for API requests, e.g. through a proxy (nil = http.DefaultTransport)
 }
 
 // Client is the LLM client
 type Client struct {
 	config     Config
 	httpClient *http.Client
+	noSchema   atomic.Bool // The backend rejected response_format, so ChatJSON asks in the prompt
 }
 
 // NewClient creates a new LLM client
@@ -31,10 +39,17 @@ func NewClient(cfg Config) *Client {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 120 * time.Second
 	}
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	if cfg.Provider == ProviderAzure {
+		cfg.BaseURL = azureBaseURL(cfg.BaseURL)
+	}
 	return &Client{
 		config: cfg,
 		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
+			Timeout:   cfg.Timeout,
+			Transport: cfg.Transport,
 		},
 	}
 }
@@ -47,12 +62,19 @@ type Message struct {
 
 // ChatRequest represents the OpenAI chat completion request
 type ChatRequest struct {
-	Model         string    `json:"model"`
-	Messages      []Message `json:"messages"`
-	Temperature   float64   `json:"temperature"`
-	MaxTokens     int       `json:"max_tokens,omitempty"`
-	Stream        bool      `json:"stream"`
-	ContextLength int       `json:"n_ctx,omitempty"` // LM Studio context length
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Temperature    float64         `json:"temperature"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Stream         bool            `json:"stream"`
+	ContextLength  int             `json:"n_ctx,omitempty"`           // LM Studio context length
+	ResponseFormat *responseFormat `json:"response_format,omitempty"` // Structured output (ChatJSON)
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`  // Hosted APIs: report usage at the end of a stream
+}
+
+// streamOptions asks for a final stream chunk with the call's token usage
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // ChatResponse represents the OpenAI chat completion response
@@ -60,20 +82,45 @@ type ChatResponse struct {
 	Choices []struct {
 		Message Message `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
 }
 
-// Chat sends a chat request to the LLM
-func (c *Client) Chat(messages []Message) (string, error) {
+// Chat sends a chat request to the LLM. A prompt too large for the model's
+// context window fails with a *ContextOverflowError.
+func (c *Client) Chat(ctx context.Context, messages []Message) (string, error) {
+	return c.chat(ctx, messages, nil)
+}
+
+// ChatJSON is Chat with the response constrained to schema through
+// response_format. If the backend rejects it, this and later requests ask for
+// the JSON in the prompt instead.
+func (c *Client) ChatJSON(ctx context.Context, messages []Message, schema Schema) (string, error) {
+	if !c.noSchema.Load() {
+		resp, err := c.chat(ctx, messages, &responseFormat{Type: "json_schema", JSONSchema: &jsonSchemaFormat{Name: schema.Name, Schema: schema.Schema}})
+		if !rejectsSchema(err) {
+			return resp, err
+		}
+		c.noSchema.Store(true)
+	}
+	return c.chat(ctx, withSchemaPrompt(messages, schema), nil)
+}
+
+// chat sends one chat completion request, with format when it isn't nil
+func (c *Client) chat(ctx context.Context, messages []Message, format *responseFormat) (string, error) {
+	temperature, maxTokens := c.config.generation(ctx)
 	reqBody := ChatRequest{
-		Model:         c.config.Model,
-		Messages:      messages,
-		Temperature:   c.config.Temperature,
-		MaxTokens:     c.config.MaxTokens,
-		ContextLength: c.config.ContextLength,
-		Stream:        false,
+		Model:          c.config.Model,
+		Messages:       messages,
+		Temperature:    temperature,
+		MaxTokens:      maxTokens,
+		Stream:         false,
+		ResponseFormat: format,
+	}
+	if !IsCloud(c.config.Provider) {
+		reqBody.ContextLength = c.config.ContextLength
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -82,27 +129,9 @@ func (c *Client) Chat(messages []Message) (string, error) {
 	}
 
 	url := fmt.Sprintf("%s/chat/completions", c.config.BaseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
-
-	resp, err := c.httpClient.Do(req)
+	body, err := c.post(ctx, url, jsonBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return "", detectOverflow(err)
 	}
 
 	var chatResp ChatResponse
@@ -111,12 +140,77 @@ func (c *Client) Chat(messages []Message) (string, error) {
 	}
 
 	if chatResp.Error != nil {
-		return "", fmt.Errorf("API returned error: %s", chatResp.Error.Message)
+		return "", detectOverflow(fmt.Errorf("API returned error: %s", chatResp.Error.Message))
 	}
 
 	if len(chatResp.Choices) == 0 {
 		return "", fmt.Errorf("no choices in response")
 	}
+	recordUsage(ctx, chatResp.Usage.tokens())
 
 	return chatResp.Choices[0].Message.Content, nil
 }
+
+// post sends a JSON POST request, retrying transient failures per the configured policy
+func (c *Client) post(ctx context.Context, url string, jsonBody []byte) ([]byte, error) {
+	return c.send(ctx, "POST", url, jsonBody)
+}
+
+// get sends a GET request, retrying transient failures per the configured policy
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	return c.send(ctx, "GET", url, nil)
+}
+
+// send performs one API request with retries and returns the body of the 200 response
+func (c *Client) send(ctx context.Context, method, url string, jsonBody []byte) ([]byte, error) {
+	return sendRequest(ctx, c.httpClient, c.config.Retry, method, url, jsonBody, c.setAuth)
+}
+
+// sendRequest performs one API request, retrying transient failures per policy,
+// and returns the body of the 200 response. setAuth, if set, adds the
+// provider's credentials.
+func sendRequest(ctx context.Context, client *http.Client, policy retry.Policy, method, url string, jsonBody []byte, setAuth func(*http.Request)) ([]byte, error) {
+	var body []byte
+	err := policy.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(jsonBody))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if setAuth != nil {
+			setAuth(req)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return retry.ResponseError(resp, "API error (status %d): %s", resp.StatusCode, string(body))
+		}
+		return nil
+	})
+	return body, err
+}
+
+// setAuth adds the API key in the form the provider expects
+func (c *Client) setAuth(req *http.Request) {
+	switch {
+	case c.config.APIKey == "":
+	case c.config.Provider == ProviderAzure:
+		req.Header.Set("api-key", c.config.APIKey)
+	default:
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
+	}
+	if c.config.Provider == ProviderOpenRouter {
+		req.Header.Set("X-Title", "deep-research") // App name shown in OpenRouter's usage logs
+	}
+}
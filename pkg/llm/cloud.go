@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Default API URLs of the hosted providers. Azure OpenAI has none: its URL is
+// the resource endpoint, e.g. https://my-resource.openai.azure.com
+const (
+	DefaultOpenAIURL     = "https://api.openai.com/v1"
+	DefaultOpenRouterURL = "https://openrouter.ai/api/v1"
+)
+
+// IsCloud reports whether provider is a hosted API that requires an API key
+func IsCloud(provider string) bool {
+	switch strings.ToLower(provider) {
+	case ProviderOpenAI, ProviderAzure, ProviderOpenRouter, ProviderAnthropic:
+		return true
+	}
+	return false
+}
+
+// APIKeyEnv returns the environment variable conventionally holding the
+// provider's API key ("" for local providers)
+func APIKeyEnv(provider string) string {
+	switch strings.ToLower(provider) {
+	case ProviderOpenAI:
+		return "OPENAI_API_KEY"
+	case ProviderAzure:
+		return "AZURE_OPENAI_API_KEY"
+	case ProviderOpenRouter:
+		return "OPENROUTER_API_KEY"
+	case ProviderAnthropic:
+		return "ANTHROPIC_API_KEY"
+	}
+	return ""
+}
+
+// cloudConfig validates cfg for a hosted provider and fills in its defaults
+func cloudConfig(provider string, cfg Config) (Config, error) {
+	if cfg.APIKey == "" {
+		return cfg, fmt.Errorf("%s needs an API key (--api-key or %s)", provider, APIKeyEnv(provider))
+	}
+	switch provider {
+	case ProviderOpenAI:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = DefaultOpenAIURL
+		}
+	case ProviderOpenRouter:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = DefaultOpenRouterURL
+		}
+	case ProviderAnthropic:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = DefaultAnthropicURL
+		}
+	case ProviderAzure:
+		if cfg.BaseURL == "" {
+			return cfg, fmt.Errorf("azure needs the resource endpoint as the LLM URL, e.g. https://my-resource.openai.azure.com")
+		}
+	}
+	cfg.Provider = provider
+	return cfg, nil
+}
+
+// azureBaseURL turns an Azure OpenAI resource endpoint into the base URL of its
+// OpenAI-compatible v1 API, where the model is the deployment name. URLs already
+// pointing at the v1 API are kept.
+func azureBaseURL(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, "/openai/v1") {
+		return endpoint
+	}
+	return strings.TrimSuffix(endpoint, "/openai") + "/openai/v1"
+}
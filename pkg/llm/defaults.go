@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// DefaultLMStudioURL is the default base URL of a local LM Studio server
+const DefaultLMStudioURL = "http://localhost:1234/v1"
+
+// DefaultBaseURL returns the default API URL for a provider ("" for Azure, whose
+// URL is the resource endpoint). Under WSL a local model server usually runs on
+// the Windows host, so localhost is replaced with the host's IP.
+func DefaultBaseURL(provider string) string {
+	url := DefaultLMStudioURL
+	switch provider {
+	case ProviderOllama:
+		url = DefaultOllamaURL
+	case ProviderLlamaCpp:
+		url = DefaultLlamaCppURL
+	case ProviderOpenAI:
+		return DefaultOpenAIURL
+	case ProviderOpenRouter:
+		return DefaultOpenRouterURL
+	case ProviderAnthropic:
+		return DefaultAnthropicURL
+	case ProviderAzure:
+		return ""
+	}
+	if IsWSL() {
+		if host := WSLHostIP(); host != "" {
+			url = strings.Replace(url, "localhost", host, 1)
+		}
+	}
+	return url
+}
+
+// IsWSL reports whether we are running inside Windows Subsystem for Linux
+func IsWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	data, err := os.ReadFile("/proc/version")
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(data)), "microsoft")
+}
+
+// WSLHostIP returns the IP of the Windows host as seen from WSL, or "" if unknown
+func WSLHostIP() string {
+	// Method 1: Check 'ip route' for the default gateway (most reliable for WSL2)
+	// Output format: "default via 172.x.x.x dev eth0 ..."
+	out, err := exec.Command("ip", "route", "show", "default").Output()
+	if err == nil {
+		fields := strings.Fields(string(out))
+		for i, field := range fields {
+			if field == "via" && i+1 < len(fields) {
+				return fields[i+1]
+			}
+		}
+	}
+
+	// Method 2: Fall back to the resolv.conf nameserver
+	file, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "nameserver") {
+			parts := strings.Fields(line)
+			if len(parts) >= 2 {
+				return parts[1]
+			}
+		}
+	}
+	return ""
+}
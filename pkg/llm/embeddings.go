@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Embedder is implemented by providers that can compute text embeddings
+// (requires Config.EmbeddingModel, e.g. "nomic-embed-text")
+type Embedder interface {
+	Embeddings(ctx context.Context, inputs []string) ([][]float64, error)
+}
+
+// embeddingRequest is shared by the OpenAI /embeddings and Ollama /api/embed endpoints
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// openAIEmbeddingResponse represents the OpenAI /embeddings response
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Embeddings computes one vector per input via the OpenAI-compatible /embeddings endpoint
+func (c *Client) Embeddings(ctx context.Context, inputs []string) ([][]float64, error) {
+	if c.config.EmbeddingModel == "" {
+		return nil, fmt.Errorf("no embedding model configured")
+	}
+
+	jsonBody, err := json.Marshal(embeddingRequest{Model: c.config.EmbeddingModel, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	body, err := c.post(ctx, fmt.Sprintf("%s/embeddings", c.config.BaseURL), jsonBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var embResp openAIEmbeddingResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if embResp.Error != nil {
+		return nil, fmt.Errorf("API returned error: %s", embResp.Error.Message)
+	}
+	if len(embResp.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embResp.Data))
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// ollamaEmbedResponse represents the Ollama /api/embed response
+type ollamaEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Embeddings computes one vector per input via Ollama's native /api/embed endpoint
+func (c *OllamaClient) Embeddings(ctx context.Context, inputs []string) ([][]float64, error) {
+	if c.config.EmbeddingModel == "" {
+		return nil, fmt.Errorf("no embedding model configured")
+	}
+
+	jsonBody, err := json.Marshal(embeddingRequest{Model: c.config.EmbeddingModel, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	body, err := c.post(ctx, fmt.Sprintf("%s/api/embed", c.config.BaseURL), jsonBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var embResp ollamaEmbedResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if embResp.Error != "" {
+		return nil, fmt.Errorf("API returned error: %s", embResp.Error)
+	}
+	if len(embResp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embResp.Embeddings))
+	}
+	return embResp.Embeddings, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors (0 if either is empty or sizes differ)
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
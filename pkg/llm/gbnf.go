@@ -0,0 +1,338 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// gbnfPrimitives are the rules every grammar from a schema ends with. Whitespace
+// between tokens is capped, so a constrained model can't stall in an endless
+// run of newlines.
+var gbnfPrimitives = []struct{ name, body string }{
+	{"space", `| " " | "\n" [ \t]{0,20}`},
+	{"char", `[^"\\\x7F\x00-\x1F] | [\\] (["\\/bfnrt] | "u" [0-9a-fA-F]{4})`},
+	{"string", `"\"" char* "\"" space`},
+	{"integral-part", `[0] | [1-9] [0-9]{0,15}`},
+	{"integer", `("-"? integral-part) space`},
+	{"decimal-part", `[0-9]{1,16}`},
+	{"number", `("-"? integral-part) ("." decimal-part)? ([eE] [-+]? integral-part)? space`},
+	{"boolean", `("true" | "false") space`},
+	{"null", `"null" space`},
+	{"value", `object | array | string | number | boolean | null`},
+	{"object", `"{" space (string ":" space value ("," space string ":" space value)*)? "}" space`},
+	{"array", `"[" space (value ("," space value)*)? "]" space`},
+}
+
+// jsonSchemaNode is the part of a JSON Schema grammarFromSchema understands
+type jsonSchemaNode struct {
+	Type                 json.RawMessage            `json:"type"` // A type or a list of them
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"` // A schema, or a boolean
+	Items                json.RawMessage            `json:"items"`
+	MinItems             int                        `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+	Enum                 []json.RawMessage          `json:"enum"`
+	Const                json.RawMessage            `json:"const"`
+	AnyOf                []json.RawMessage          `json:"anyOf"`
+	OneOf                []json.RawMessage          `json:"oneOf"`
+	Ref                  string                     `json:"$ref"`
+	AllOf                []json.RawMessage          `json:"allOf"`
+	Pattern              string                     `json:"pattern"`
+}
+
+// grammarBuilder collects the rules of a grammar, sharing identical ones
+type grammarBuilder struct {
+	rules map[string]string // By name
+	names map[string]string // By body
+	order []string
+}
+
+// grammarFromSchema converts a JSON Schema to a GBNF grammar for llama.cpp's
+// /completion, so the response can't be anything but JSON matching it. Objects
+// get their properties in the order of "required", then the optional ones in
+// name order. Schemas using what the converter doesn't cover ($ref, allOf,
+// string patterns) are an error, for the caller to send the schema to the
+// server to convert instead.
+func grammarFromSchema(schema json.RawMessage) (string, error) {
+	g := &grammarBuilder{rules: make(map[string]string), names: make(map[string]string)}
+	root, err := g.visit(schema, "root")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if root != "root" {
+		fmt.Fprintf(&b, "root ::= %s\n", root)
+	}
+	for _, name := range g.order {
+		fmt.Fprintf(&b, "%s ::= %s\n", name, g.rules[name])
+	}
+	for _, p := range gbnfPrimitives {
+		fmt.Fprintf(&b, "%s ::= %s\n", p.name, p.body)
+	}
+	return b.String(), nil
+}
+
+// visit returns the grammar expression matching schema, adding the rules it
+// needs; name is the rule name to use for a compound schema
+func (g *grammarBuilder) visit(schema json.RawMessage, name string) (string, error) {
+	var accept bool
+	if json.Unmarshal(schema, &accept) == nil {
+		if !accept {
+			return "", fmt.Errorf("%s: schema false matches nothing", name)
+		}
+		return "value", nil
+	}
+	var node jsonSchemaNode
+	if err := json.Unmarshal(schema, &node); err != nil {
+		return "", fmt.Errorf("%s: invalid schema: %w", name, err)
+	}
+
+	switch {
+	case node.Ref != "" || len(node.AllOf) > 0:
+		return "", fmt.Errorf("%s: $ref and allOf aren't supported", name)
+	case node.Pattern != "":
+		return "", fmt.Errorf("%s: string patterns aren't supported", name)
+	case node.Const != nil:
+		return gbnfLiteral(jsonText(node.Const)) + " space", nil
+	case len(node.Enum) > 0:
+		options := make([]string, len(node.Enum))
+		for i, v := range node.Enum {
+			options[i] = gbnfLiteral(jsonText(v))
+		}
+		return g.rule(name, "("+strings.Join(options, " | ")+") space"), nil
+	case len(node.AnyOf) > 0 || len(node.OneOf) > 0:
+		return g.alternatives(append(node.AnyOf, node.OneOf...), name)
+	}
+
+	var types []string
+	if node.Type != nil {
+		var single string
+		if json.Unmarshal(node.Type, &single) == nil {
+			types = []string{single}
+		} else if err := json.Unmarshal(node.Type, &types); err != nil {
+			return "", fmt.Errorf("%s: invalid type: %w", name, err)
+		}
+	}
+	if len(types) == 0 {
+		switch {
+		case node.Properties != nil || node.AdditionalProperties != nil:
+			types = []string{"object"}
+		case node.Items != nil:
+			types = []string{"array"}
+		default:
+			return "value", nil
+		}
+	}
+
+	options := make([]string, len(types))
+	for i, t := range types {
+		ruleName := name
+		if len(types) > 1 {
+			ruleName = fmt.Sprintf("%s-%d", name, i)
+		}
+		var err error
+		switch t {
+		case "object":
+			options[i], err = g.object(node, ruleName)
+		case "array":
+			options[i], err = g.array(node, ruleName)
+		case "string", "integer", "number", "boolean", "null":
+			options[i] = t
+		default:
+			err = fmt.Errorf("%s: unknown type %q", name, t)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(options) == 1 {
+		return options[0], nil
+	}
+	return g.rule(name, strings.Join(options, " | ")), nil
+}
+
+// alternatives is a rule matching any of the schemas
+func (g *grammarBuilder) alternatives(schemas []json.RawMessage, name string) (string, error) {
+	options := make([]string, len(schemas))
+	for i, s := range schemas {
+		var err error
+		if options[i], err = g.visit(s, fmt.Sprintf("%s-%d", name, i)); err != nil {
+			return "", err
+		}
+	}
+	return g.rule(name, strings.Join(options, " | ")), nil
+}
+
+// object is a rule matching an object with node's properties: the required
+// ones, then any of the optional ones, then (with additionalProperties) more
+// keys of its schema
+func (g *grammarBuilder) object(node jsonSchemaNode, name string) (string, error) {
+	if node.Properties == nil && node.AdditionalProperties == nil {
+		return "object", nil
+	}
+
+	var required, optional []string
+	for _, key := range node.Required {
+		if _, ok := node.Properties[key]; ok && !slices.Contains(required, key) {
+			required = append(required, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(node.Properties)) {
+		if !slices.Contains(required, key) {
+			optional = append(optional, key)
+		}
+	}
+
+	member := func(key string) (string, error) {
+		value, err := g.visit(node.Properties[key], name+"-"+gbnfName(key))
+		if err != nil {
+			return "", err
+		}
+		return gbnfLiteral(jsonText(key)) + ` space ":" space ` + value, nil
+	}
+
+	var members []string
+	for _, key := range required {
+		m, err := member(key)
+		if err != nil {
+			return "", err
+		}
+		members = append(members, m)
+	}
+	body := strings.Join(members, ` "," space `)
+
+	if len(optional) > 0 {
+		// tail-i matches the optional properties from i on, any of them, in order
+		tails := make([]string, len(optional))
+		for i := range optional {
+			tails[i] = fmt.Sprintf("%s-tail-%d", name, i)
+		}
+		for i := len(optional) - 1; i >= 0; i-- {
+			m, err := member(optional[i])
+			if err != nil {
+				return "", err
+			}
+			if i == len(optional)-1 {
+				g.define(tails[i], m)
+			} else {
+				g.define(tails[i], fmt.Sprintf(`%s ("," space %s)? | %s`, m, tails[i+1], tails[i+1]))
+			}
+		}
+		if body == "" {
+			body = tails[0] + "?"
+		} else {
+			body += ` ("," space ` + tails[0] + `)?`
+		}
+	}
+
+	var extra bool
+	if node.AdditionalProperties != nil && json.Unmarshal(node.AdditionalProperties, &extra) != nil {
+		extra = true // A schema
+	}
+	if extra {
+		value, err := g.visit(node.AdditionalProperties, name+"-additional")
+		if err != nil {
+			return "", err
+		}
+		kv := g.rule(name+"-additional-kv", "string"+` ":" space `+value)
+		switch {
+		case body == "":
+			body = kv + ` ("," space ` + kv + `)*`
+			body = "(" + body + ")?"
+		case len(required) > 0:
+			body += ` ("," space ` + kv + `)*`
+		default:
+			return "", fmt.Errorf("%s: additionalProperties next to only optional properties isn't supported", name)
+		}
+	}
+
+	return g.rule(name, `"{" space `+body+` "}" space`), nil
+}
+
+// array is a rule matching an array of node's items, minItems to maxItems of them
+func (g *grammarBuilder) array(node jsonSchemaNode, name string) (string, error) {
+	if node.Items == nil {
+		if node.MinItems == 0 && node.MaxItems == nil {
+			return "array", nil
+		}
+		node.Items = json.RawMessage(`{}`)
+	}
+	item, err := g.visit(node.Items, name+"-item")
+	if err != nil {
+		return "", err
+	}
+
+	if node.MaxItems != nil && *node.MaxItems == 0 {
+		return g.rule(name, `"[" space "]" space`), nil
+	}
+	var more string
+	switch {
+	case node.MaxItems != nil:
+		more = fmt.Sprintf(`("," space %s){%d,%d}`, item, max(node.MinItems-1, 0), max(*node.MaxItems-1, 0))
+	case node.MinItems > 1:
+		more = fmt.Sprintf(`("," space %s){%d,}`, item, node.MinItems-1)
+	default:
+		more = `("," space ` + item + `)*`
+	}
+	items := item + " " + more
+	if node.MinItems == 0 {
+		items = "(" + items + ")?"
+	}
+	return g.rule(name, `"[" space `+items+` "]" space`), nil
+}
+
+// rule adds a rule with body under name (or a unique variant of it) and returns
+// its name; a rule with the same body is reused
+func (g *grammarBuilder) rule(name, body string) string {
+	if existing, ok := g.names[body]; ok {
+		return existing
+	}
+	unique := name
+	for n := 1; g.rules[unique] != ""; n++ {
+		unique = fmt.Sprintf("%s%d", name, n)
+	}
+	g.define(unique, body)
+	return unique
+}
+
+// define adds a rule under exactly name
+func (g *grammarBuilder) define(name, body string) {
+	g.rules[name] = body
+	g.names[body] = name
+	g.order = append(g.order, name)
+}
+
+// gbnfName makes a property name usable in a rule name (letters, digits, and -)
+func gbnfName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, key)
+	if name == "" {
+		return "key"
+	}
+	return name
+}
+
+// gbnfLiteral quotes s as a GBNF string literal
+func gbnfLiteral(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s) + `"`
+}
+
+// jsonText is v as compact JSON, the way a model writes it (no \u escapes for <, >, and &)
+func jsonText(v any) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// schemaRules is the grammar without the primitives every grammar ends with
+func schemaRules(t *testing.T, grammar string) string {
+	t.Helper()
+	var primitives strings.Builder
+	for _, p := range gbnfPrimitives {
+		primitives.WriteString(p.name + " ::= " + p.body + "\n")
+	}
+	rules, ok := strings.CutSuffix(grammar, primitives.String())
+	if !ok {
+		t.Fatalf("grammar doesn't end with the primitives:\n%s", grammar)
+	}
+	return rules
+}
+
+func TestGrammarFromSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			"required properties in required order",
+			`{"type":"object","properties":{"a":{"type":"string"},"b":{"type":"integer"}},"required":["b","a"]}`,
+			`root ::= "{" space "\"b\"" space ":" space integer "," space "\"a\"" space ":" space string "}" space` + "\n",
+		},
+		{
+			"optional properties",
+			`{"type":"object","properties":{"x":{"type":"string"},"y":{"type":"boolean"}}}`,
+			`root-tail-1 ::= "\"y\"" space ":" space boolean` + "\n" +
+				`root-tail-0 ::= "\"x\"" space ":" space string ("," space root-tail-1)? | root-tail-1` + "\n" +
+				`root ::= "{" space root-tail-0? "}" space` + "\n",
+		},
+		{
+			"required then optional",
+			`{"type":"object","properties":{"a":{"type":"string"},"b":{"type":"number"}},"required":["a"]}`,
+			`root-tail-0 ::= "\"b\"" space ":" space number` + "\n" +
+				`root ::= "{" space "\"a\"" space ":" space string ("," space root-tail-0)? "}" space` + "\n",
+		},
+		{
+			"bounded array",
+			`{"type":"array","items":{"type":"string"},"minItems":1,"maxItems":3}`,
+			`root ::= "[" space string ("," space string){0,2} "]" space` + "\n",
+		},
+		{
+			"array with a minimum",
+			`{"type":"array","items":{"type":"integer"},"minItems":2}`,
+			`root ::= "[" space integer ("," space integer){1,} "]" space` + "\n",
+		},
+		{
+			"empty array",
+			`{"type":"array","items":{"type":"string"},"maxItems":0}`,
+			`root ::= "[" space "]" space` + "\n",
+		},
+		{
+			"enum",
+			`{"enum":["a","b\"c"]}`,
+			`root ::= ("\"a\"" | "\"b\\\"c\"") space` + "\n",
+		},
+		{
+			"const without HTML escapes, additional properties",
+			`{"type":"object","properties":{"k":{"const":"<&>"}},"required":["k"],"additionalProperties":{"type":"number"}}`,
+			`root-additional-kv ::= string ":" space number` + "\n" +
+				`root ::= "{" space "\"k\"" space ":" space "\"<&>\"" space ("," space root-additional-kv)* "}" space` + "\n",
+		},
+		{
+			"anyOf",
+			`{"anyOf":[{"type":"string"},{"type":"null"}]}`,
+			`root ::= string | null` + "\n",
+		},
+		{
+			"type list",
+			`{"type":["string","null"]}`,
+			`root ::= string | null` + "\n",
+		},
+		{
+			"identical objects share a rule",
+			`{"type":"object","properties":{"p":{"type":"object","properties":{"q":{"type":"string"}},"required":["q"]},"r":{"type":"object","properties":{"q":{"type":"string"}},"required":["q"]}},"required":["p","r"]}`,
+			`root-p ::= "{" space "\"q\"" space ":" space string "}" space` + "\n" +
+				`root ::= "{" space "\"p\"" space ":" space root-p "," space "\"r\"" space ":" space root-p "}" space` + "\n",
+		},
+		{
+			"property names made rule names",
+			`{"type":"object","properties":{"my key!":{"enum":[1,2]}},"required":["my key!"]}`,
+			`root-my-key- ::= ("1" | "2") space` + "\n" +
+				`root ::= "{" space "\"my key!\"" space ":" space root-my-key- "}" space` + "\n",
+		},
+		{
+			"primitive root",
+			`{"type":"string"}`,
+			`root ::= string` + "\n",
+		},
+		{
+			"any value",
+			`{}`,
+			`root ::= value` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grammar, err := grammarFromSchema(json.RawMessage(tt.schema))
+			if err != nil {
+				t.Fatalf("grammarFromSchema: %v", err)
+			}
+			if got := schemaRules(t, grammar); got != tt.want {
+				t.Errorf("rules =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrammarFromSchemaUnsupported(t *testing.T) {
+	for _, schema := range []string{
+		`{"$ref":"#/definitions/x"}`,
+		`{"allOf":[{"type":"string"}]}`,
+		`{"type":"string","pattern":"^a+$"}`,
+		`{"type":"date"}`,
+		`false`,
+		`{"type":"object","properties":{"a":{"type":"string"}},"additionalProperties":true}`,
+		`{"type":"object","properties":{"a":{"$ref":"#"}},"required":["a"]}`,
+		`not json`,
+	} {
+		if _, err := grammarFromSchema(json.RawMessage(schema)); err == nil {
+			t.Errorf("grammarFromSchema(%s) succeeded", schema)
+		}
+	}
+}
+
+// TestGrammarFromSchemaRulesDefined checks that every rule a grammar refers to
+// is defined, on a schema shaped like the agent's own
+func TestGrammarFromSchemaRulesDefined(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"title": {"type": "string"},
+			"sections": {
+				"type": "array",
+				"minItems": 1,
+				"items": {
+					"type": "object",
+					"properties": {
+						"heading": {"type": "string"},
+						"points": {"type": "array", "items": {"type": "string"}},
+						"kind": {"enum": ["intro", "body", "conclusion"]},
+						"weight": {"type": ["number", "null"]}
+					},
+					"required": ["heading", "points"]
+				}
+			},
+			"meta": {"type": "object", "additionalProperties": {"type": "string"}}
+		},
+		"required": ["title", "sections"]
+	}`
+	grammar, err := grammarFromSchema(json.RawMessage(schema))
+	if err != nil {
+		t.Fatalf("grammarFromSchema: %v", err)
+	}
+
+	defined := make(map[string]bool)
+	var bodies []string
+	for _, line := range strings.Split(strings.TrimSpace(grammar), "\n") {
+		name, body, ok := strings.Cut(line, " ::= ")
+		if !ok {
+			t.Fatalf("malformed rule %q", line)
+		}
+		if defined[name] {
+			t.Errorf("rule %s defined twice", name)
+		}
+		defined[name] = true
+		bodies = append(bodies, body)
+	}
+	if !defined["root"] {
+		t.Error("no root rule")
+	}
+
+	// Names are what's left once literals, character classes, and repetition counts are removed
+	literals := regexp.MustCompile(`"(\\.|[^"\\])*"|\[(\\.|[^\]\\])*\]|\{[0-9,]*\}`)
+	names := regexp.MustCompile(`[a-z][a-z0-9-]*`)
+	for _, body := range bodies {
+		for _, name := range names.FindAllString(literals.ReplaceAllString(body, " "), -1) {
+			if !defined[name] {
+				t.Errorf("rule %s is used but not defined in %q", name, body)
+			}
+		}
+	}
+}
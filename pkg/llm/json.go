@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// JSONRepairs is how many times ChatJSON sends a response that didn't parse back
+// to the model, with the parse error, before giving up
+const JSONRepairs = 2
+
+// Schema is the JSON a ChatJSON response must match
+type Schema struct {
+	Name   string          // Identifier sent to the API (letters, digits, _ and -), e.g. "research_plan"
+	Schema json.RawMessage // JSON Schema of the response
+}
+
+// JSONChatter is implemented by providers that can constrain a chat response to
+// a JSON schema (structured output). If the backend rejects the schema, the JSON
+// is asked for in the prompt instead.
+type JSONChatter interface {
+	ChatJSON(ctx context.Context, messages []Message, schema Schema) (string, error)
+}
+
+// ParseError is ChatJSON's error when the model's response still isn't the
+// requested JSON after JSONRepairs repairs
+type ParseError struct {
+	Response string // The last response
+	Err      error
+	Repairs  int // How many times the model was asked to correct its response
+}
+
+func (e *ParseError) Error() string {
+	if e.Repairs > 0 {
+		return fmt.Sprintf("%v (after %d repairs). Response: %s", e.Err, e.Repairs, e.Response)
+	}
+	return fmt.Sprintf("%v. Response: %s", e.Err, e.Response)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// responseFormat is the OpenAI response_format requesting structured output
+type responseFormat struct {
+	Type       string            `json:"type"` // "json_schema"
+	JSONSchema *jsonSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// jsonSchemaFormat is the schema of a json_schema response_format
+type jsonSchemaFormat struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// ChatJSON asks p for a JSON response matching schema and decodes it into out.
+// Providers that implement JSONChatter constrain the response natively; the
+// others get the schema in the prompt. JSON wrapped in prose, code fences, or
+// <think> blocks is still found; a response that doesn't decode is sent back
+// with the parse error for the model to correct, up to JSONRepairs times (or
+// Options.JSONRepairs), before ChatJSON fails with a *ParseError.
+func ChatJSON(ctx context.Context, p Provider, messages []Message, schema Schema, out any) error {
+	limit := JSONRepairs
+	if n := OptionsFromContext(ctx).JSONRepairs; n != nil {
+		limit = max(*n, 0)
+	}
+	for repairs := 0; ; repairs++ {
+		resp, err := RequestJSON(ctx, p, messages, schema)
+		if err != nil {
+			return err
+		}
+		err = DecodeJSON(resp, out)
+		if err == nil {
+			return nil
+		}
+		if repairs >= limit || ctx.Err() != nil {
+			return &ParseError{Response: resp, Err: err, Repairs: repairs}
+		}
+		messages = append(messages[:len(messages):len(messages)],
+			Message{Role: "assistant", Content: resp},
+			Message{Role: "user", Content: fmt.Sprintf("That response isn't valid JSON in the requested format (%v). Reply with only the corrected JSON, no other text.", err)},
+		)
+	}
+}
+
+// RequestJSON sends one chat request for JSON matching schema: constrained by
+// p if it is a JSONChatter, else asked for in the prompt. The response is
+// returned as is; see DecodeJSON.
+func RequestJSON(ctx context.Context, p Provider, messages []Message, schema Schema) (string, error) {
+	if chatter, ok := p.(JSONChatter); ok {
+		return chatter.ChatJSON(ctx, messages, schema)
+	}
+	return p.Chat(ctx, withSchemaPrompt(messages, schema))
+}
+
+// DecodeJSON decodes the JSON in a model response into out. Reasoning before a
+// closing </think> tag is skipped, and so are code fences and prose around the
+// JSON: the first complete JSON object or array in the response is used.
+func DecodeJSON(resp string, out any) error {
+	if end := strings.LastIndex(resp, "</think>"); end != -1 {
+		resp = resp[end+len("</think>"):]
+	}
+	resp = strings.TrimSpace(resp)
+	if json.Valid([]byte(resp)) {
+		return json.Unmarshal([]byte(resp), out)
+	}
+	for i := 0; i < len(resp); i++ {
+		if resp[i] != '{' && resp[i] != '[' {
+			continue
+		}
+		var value json.RawMessage
+		if json.NewDecoder(strings.NewReader(resp[i:])).Decode(&value) == nil {
+			return json.Unmarshal(value, out)
+		}
+	}
+	if resp == "" {
+		return errors.New("empty response")
+	}
+	return errors.New("no JSON object in the response")
+}
+
+// withSchemaPrompt returns messages with the last one asking for JSON matching
+// schema, for backends without structured output
+func withSchemaPrompt(messages []Message, schema Schema) []Message {
+	if len(messages) == 0 {
+		return messages
+	}
+	prompted := append([]Message(nil), messages...)
+	last := &prompted[len(prompted)-1]
+	last.Content += "\n\nRespond with only JSON matching this JSON Schema, without code fences or any other text:\n" + string(schema.Schema)
+	return prompted
+}
+
+// rejectsSchema reports whether err is a backend refusing a structured output
+// request (rather than failing it for another reason, like a context overflow)
+func rejectsSchema(err error) bool {
+	var status *retry.StatusError
+	if errors.Is(err, ErrContextOverflow) || !errors.As(err, &status) {
+		return false
+	}
+	switch status.StatusCode {
+	case http.StatusBadRequest, http.StatusNotImplemented, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
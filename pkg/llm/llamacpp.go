@@ -0,0 +1,254 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLlamaCppURL is the default base URL of a local llama.cpp server (llama-server)
+const DefaultLlamaCppURL = "http://localhost:8080"
+
+// LlamaCppClient talks to llama.cpp's native /completion endpoint. Messages are
+// turned into a prompt with the model's chat template (/apply-template), and
+// ChatJSON constrains the response with a GBNF grammar, so plans, decisions,
+// and the other JSON responses always parse.
+type LlamaCppClient struct {
+	config     Config
+	httpClient *http.Client
+	noTemplate atomic.Bool // The server has no /apply-template (older builds), so prompts use ChatML
+	noSchema   atomic.Bool // The server rejected a grammar, so ChatJSON asks in the prompt
+}
+
+// NewLlamaCppClient creates a new llama.cpp client
+func NewLlamaCppClient(cfg Config) *LlamaCppClient {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 120 * time.Second
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultLlamaCppURL
+	}
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	// Accept OpenAI-style base URLs (http://host:8080/v1) as well
+	cfg.BaseURL = strings.TrimSuffix(strings.TrimSuffix(cfg.BaseURL, "/"), "/v1")
+	return &LlamaCppClient{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: cfg.Transport,
+		},
+	}
+}
+
+// llamaCppCompletionRequest represents the llama.cpp /completion request
+type llamaCppCompletionRequest struct {
+	Model       string          `json:"model,omitempty"` // Only routers like llama-swap use it
+	Prompt      string          `json:"prompt"`
+	Temperature float64         `json:"temperature"`
+	NPredict    int             `json:"n_predict,omitempty"`
+	CachePrompt bool            `json:"cache_prompt"` // Reuse the KV cache of a shared prompt prefix
+	Stream      bool            `json:"stream"`
+	Grammar     string          `json:"grammar,omitempty"`     // GBNF grammar the response must match (ChatJSON)
+	JSONSchema  json.RawMessage `json:"json_schema,omitempty"` // Schema for the server to convert, when grammarFromSchema can't
+}
+
+// llamaCppCompletionResponse represents the llama.cpp /completion response (and
+// each event of a streamed one)
+type llamaCppCompletionResponse struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	TokensEvaluated int    `json:"tokens_evaluated"` // Prompt tokens (sent once stopped)
+	TokensPredicted int    `json:"tokens_predicted"` // Generated tokens (sent once stopped)
+	Error           *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// tokens is the usage of a finished response
+func (r llamaCppCompletionResponse) tokens() TokenUsage {
+	return TokenUsage{PromptTokens: r.TokensEvaluated, CompletionTokens: r.TokensPredicted}
+}
+
+// Chat sends a completion request to llama.cpp. A prompt too large for the
+// server's context window fails with a *ContextOverflowError.
+func (c *LlamaCppClient) Chat(ctx context.Context, messages []Message) (string, error) {
+	return c.complete(ctx, messages, llamaCppCompletionRequest{}, nil)
+}
+
+// ChatJSON is Chat with the response constrained to schema by a GBNF grammar
+// (or, for schemas grammarFromSchema doesn't cover, the server's own conversion
+// of it). If the server rejects it, this and later requests ask for the JSON
+// in the prompt instead.
+func (c *LlamaCppClient) ChatJSON(ctx context.Context, messages []Message, schema Schema) (string, error) {
+	if !c.noSchema.Load() {
+		var constraint llamaCppCompletionRequest
+		if grammar, err := grammarFromSchema(schema.Schema); err == nil {
+			constraint.Grammar = grammar
+		} else {
+			constraint.JSONSchema = schema.Schema
+		}
+		resp, err := c.complete(ctx, messages, constraint, nil)
+		if !rejectsSchema(err) {
+			return resp, err
+		}
+		c.noSchema.Store(true)
+	}
+	return c.complete(ctx, withSchemaPrompt(messages, schema), llamaCppCompletionRequest{}, nil)
+}
+
+// ChatStream sends a completion request with streaming enabled and passes each
+// piece of the response to onDelta as the server sends it
+func (c *LlamaCppClient) ChatStream(ctx context.Context, messages []Message, onDelta func(string)) (string, error) {
+	return c.complete(ctx, messages, llamaCppCompletionRequest{Stream: true}, onDelta)
+}
+
+// complete sends one /completion request for messages, with req's grammar or
+// schema and streaming to onDelta when req.Stream is set
+func (c *LlamaCppClient) complete(ctx context.Context, messages []Message, req llamaCppCompletionRequest, onDelta func(string)) (string, error) {
+	prompt, err := c.prompt(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+	temperature, maxTokens := c.config.generation(ctx)
+	req.Model = c.model()
+	req.Prompt = prompt
+	req.Temperature = temperature
+	req.NPredict = maxTokens
+	req.CachePrompt = true
+
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	url := fmt.Sprintf("%s/completion", c.config.BaseURL)
+
+	if !req.Stream {
+		body, err := c.post(ctx, url, jsonBody)
+		if err != nil {
+			return "", detectOverflow(err)
+		}
+		var compResp llamaCppCompletionResponse
+		if err := json.Unmarshal(body, &compResp); err != nil {
+			return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if compResp.Error != nil {
+			return "", detectOverflow(fmt.Errorf("API returned error: %s", compResp.Error.Message))
+		}
+		recordUsage(ctx, compResp.tokens())
+		return compResp.Content, nil
+	}
+
+	resp, err := openStream(ctx, c.httpClient, c.config.Retry, url, jsonBody, c.setAuth)
+	if err != nil {
+		return "", detectOverflow(err)
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	err = readLines(resp.Body, func(line []byte) error {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			return nil // Comments and other SSE fields
+		}
+		var chunk llamaCppCompletionResponse
+		if err := json.Unmarshal(bytes.TrimSpace(data), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return detectOverflow(fmt.Errorf("API returned error: %s", chunk.Error.Message))
+		}
+		if chunk.Content != "" {
+			full.WriteString(chunk.Content)
+			onDelta(chunk.Content)
+		}
+		if chunk.Stop {
+			recordUsage(ctx, chunk.tokens())
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return full.String(), nil
+}
+
+// prompt renders messages with the model's chat template via /apply-template.
+// Servers without the endpoint get a ChatML prompt, which most recent models
+// (Qwen, Hermes, and the like) are trained on.
+func (c *LlamaCppClient) prompt(ctx context.Context, messages []Message) (string, error) {
+	if !c.noTemplate.Load() {
+		jsonBody, err := json.Marshal(map[string][]Message{"messages": messages})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body, err := c.post(ctx, fmt.Sprintf("%s/apply-template", c.config.BaseURL), jsonBody)
+		var status *retry.StatusError
+		switch {
+		case errors.As(err, &status) && status.StatusCode == http.StatusNotFound:
+			c.noTemplate.Store(true)
+		case err != nil:
+			return "", err
+		default:
+			var resp struct {
+				Prompt string `json:"prompt"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return "", fmt.Errorf("failed to unmarshal template response: %w", err)
+			}
+			return resp.Prompt, nil
+		}
+	}
+
+	var b strings.Builder
+	for _, m := range messages {
+		fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>\n", m.Role, m.Content)
+	}
+	b.WriteString("<|im_start|>assistant\n")
+	return b.String(), nil
+}
+
+// model is the model to name in requests: none for the placeholder, since a
+// llama.cpp server serves the model it was started with
+func (c *LlamaCppClient) model() string {
+	if c.config.Model == "local-model" {
+		return ""
+	}
+	return c.config.Model
+}
+
+// CountTokens tokenizes text with the server's model via its /tokenize endpoint
+func (c *LlamaCppClient) CountTokens(ctx context.Context, text string) (int, error) {
+	return countTokens(ctx, c.post, c.config.BaseURL+"/tokenize", text)
+}
+
+// post sends a JSON POST request, retrying transient failures per the configured policy
+func (c *LlamaCppClient) post(ctx context.Context, url string, jsonBody []byte) ([]byte, error) {
+	return c.send(ctx, "POST", url, jsonBody)
+}
+
+// get sends a GET request, retrying transient failures per the configured policy
+func (c *LlamaCppClient) get(ctx context.Context, url string) ([]byte, error) {
+	return c.send(ctx, "GET", url, nil)
+}
+
+// send performs one API request with retries and returns the body of the 200 response
+func (c *LlamaCppClient) send(ctx context.Context, method, url string, jsonBody []byte) ([]byte, error) {
+	return sendRequest(ctx, c.httpClient, c.config.Retry, method, url, jsonBody, c.setAuth)
+}
+
+// setAuth sends the API key of a server started with --api-key, if one is set
+func (c *LlamaCppClient) setAuth(req *http.Request) {
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
+	}
+}
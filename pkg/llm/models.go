@@ -0,0 +1,426 @@
+package llm
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ModelSelector is implemented by providers that can hand out a client for a
+// different model (optionally on a different server) with otherwise identical
+// settings, e.g. a small model for page summaries next to a large report writer
+type ModelSelector interface {
+	WithModel(baseURL, model string) Provider
+}
+
+// withModel returns cfg with the non-empty overrides applied
+func (cfg Config) withModel(baseURL, model string) Config {
+	if baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	if model != "" {
+		cfg.Model = model
+	}
+	return cfg
+}
+
+// WithModel returns a client for model at baseURL (empty = keep this client's)
+func (c *Client) WithModel(baseURL, model string) Provider {
+	return NewClient(c.config.withModel(baseURL, model))
+}
+
+// WithModel returns a client for model at baseURL (empty = keep this client's)
+func (c *OllamaClient) WithModel(baseURL, model string) Provider {
+	return NewOllamaClient(c.config.withModel(baseURL, model))
+}
+
+// WithModel returns a client for model at baseURL (empty = keep this client's)
+func (c *LlamaCppClient) WithModel(baseURL, model string) Provider {
+	return NewLlamaCppClient(c.config.withModel(baseURL, model))
+}
+
+// ModelRef is a model, optionally on another provider than the main one
+type ModelRef struct {
+	Provider string // "" = the main provider
+	Model    string
+}
+
+// ParseModelRef reads "provider:model", e.g. "anthropic:claude-sonnet-4-5".
+// Without a known provider before the first colon, the whole string is a model
+// of the main provider, so Ollama tags like "qwen3:8b" stay intact.
+func ParseModelRef(s string) ModelRef {
+	s = strings.TrimSpace(s)
+	if provider, model, ok := strings.Cut(s, ":"); ok && slices.Contains(Providers, strings.ToLower(provider)) {
+		return ModelRef{Provider: strings.ToLower(provider), Model: strings.TrimSpace(model)}
+	}
+	return ModelRef{Model: s}
+}
+
+// NewModelProvider creates the client for ref next to the main provider (named
+// provider, configured with cfg). A model of the main provider keeps cfg's URL
+// and API key; another provider gets its default URL and the API key in its
+// APIKeyEnv variable. Both keep cfg's timeout, retries, and transport.
+func NewModelProvider(provider string, cfg Config, ref ModelRef) (Provider, error) {
+	if ref.Model == "" {
+		return nil, fmt.Errorf("no model given")
+	}
+	cfg.Model = ref.Model
+	if ref.Provider == "" || strings.EqualFold(ref.Provider, provider) {
+		return NewProvider(provider, cfg)
+	}
+	cfg.Provider = ""
+	cfg.BaseURL = DefaultBaseURL(ref.Provider)
+	cfg.APIKey = ""
+	if env := APIKeyEnv(ref.Provider); env != "" {
+		cfg.APIKey = os.Getenv(env)
+	}
+	return NewProvider(ref.Provider, cfg)
+}
+
+// ModelLister is implemented by providers that can list the models they serve
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// ListModels returns the model IDs served by the OpenAI-compatible /models endpoint
+// (Azure lists the models available to the resource; deployments are named separately)
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	body, err := c.get(ctx, fmt.Sprintf("%s/models", c.config.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	models := make([]string, len(resp.Data))
+	for i, m := range resp.Data {
+		models[i] = m.ID
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// ListModels returns the locally available models from Ollama's /api/tags endpoint
+func (c *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	body, err := c.get(ctx, fmt.Sprintf("%s/api/tags", c.config.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	models := make([]string, len(resp.Models))
+	for i, m := range resp.Models {
+		models[i] = m.Name
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// ModelInfo describes a model a provider serves
+type ModelInfo struct {
+	ID            string `json:"id"`
+	ContextLength int    `json:"contextLength,omitempty"` // Tokens a prompt and its answer may take (0 = not reported)
+	Loaded        bool   `json:"loaded,omitempty"`        // Loaded in memory (LM Studio, Ollama, and llama.cpp report it)
+}
+
+// ModelDescriber is implemented by providers that can describe the models they
+// serve, with the context lengths their servers report
+type ModelDescriber interface {
+	DescribeModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// DescribeModels returns the models of the OpenAI-compatible /models endpoint
+// with the context length it reports (OpenRouter's context_length, vLLM's
+// max_model_len, llama.cpp's n_ctx_train). LM Studio's own /api/v0/models is
+// tried first: it says which models are loaded and with what context length,
+// which is the one that applies (LM Studio ignores n_ctx once a model is loaded).
+func (c *Client) DescribeModels(ctx context.Context) ([]ModelInfo, error) {
+	if c.config.Provider == "" || c.config.Provider == ProviderLMStudio {
+		if models, err := c.lmStudioModels(ctx); err == nil {
+			return models, nil
+		}
+	}
+	body, err := c.get(ctx, fmt.Sprintf("%s/models", c.config.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+			MaxModelLen   int    `json:"max_model_len"`
+			Meta          struct {
+				NCtxTrain int `json:"n_ctx_train"`
+			} `json:"meta"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	models := make([]ModelInfo, len(resp.Data))
+	for i, m := range resp.Data {
+		models[i] = ModelInfo{ID: m.ID, ContextLength: cmp.Or(m.ContextLength, m.MaxModelLen, m.Meta.NCtxTrain)}
+	}
+	sortModels(models)
+	return models, nil
+}
+
+// lmStudioModels lists the models from LM Studio's REST API, next to its
+// OpenAI-compatible /v1 endpoints
+func (c *Client) lmStudioModels(ctx context.Context) ([]ModelInfo, error) {
+	base := strings.TrimSuffix(strings.TrimSuffix(c.config.BaseURL, "/"), "/v1")
+	body, err := c.get(ctx, base+"/api/v0/models")
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID                  string `json:"id"`
+			Type                string `json:"type"`
+			State               string `json:"state"`
+			MaxContextLength    int    `json:"max_context_length"`
+			LoadedContextLength int    `json:"loaded_context_length"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("no models")
+	}
+	var models []ModelInfo
+	for _, m := range resp.Data {
+		if m.Type == "embeddings" {
+			continue
+		}
+		models = append(models, ModelInfo{
+			ID:            m.ID,
+			ContextLength: cmp.Or(m.LoadedContextLength, m.MaxContextLength),
+			Loaded:        m.State == "loaded",
+		})
+	}
+	sortModels(models)
+	return models, nil
+}
+
+// DescribeModels returns the locally available models from Ollama's /api/tags
+// with the maximum context length of each from /api/show (Ollama loads a model
+// with the num_ctx each request asks for, so the maximum is what applies) and
+// whether /api/ps lists it as loaded
+func (c *OllamaClient) DescribeModels(ctx context.Context) ([]ModelInfo, error) {
+	names, err := c.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]bool)
+	if body, err := c.get(ctx, fmt.Sprintf("%s/api/ps", c.config.BaseURL)); err == nil {
+		var resp struct {
+			Models []struct {
+				Name string `json:"name"`
+			} `json:"models"`
+		}
+		if json.Unmarshal(body, &resp) == nil {
+			for _, m := range resp.Models {
+				loaded[m.Name] = true
+			}
+		}
+	}
+
+	models := make([]ModelInfo, len(names))
+	for i, name := range names {
+		models[i] = ModelInfo{ID: name, Loaded: loaded[name]}
+		if n, err := c.contextLength(ctx, name); err == nil {
+			models[i].ContextLength = n
+		}
+	}
+	return models, nil
+}
+
+// contextLength reads a model's maximum context length ("<architecture>.context_length")
+// from Ollama's /api/show
+func (c *OllamaClient) contextLength(ctx context.Context, model string) (int, error) {
+	jsonBody, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return 0, err
+	}
+	body, err := c.post(ctx, fmt.Sprintf("%s/api/show", c.config.BaseURL), jsonBody)
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		ModelInfo map[string]any `json:"model_info"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	for key, value := range resp.ModelInfo {
+		if n, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("no context length for %s", model)
+}
+
+// ListModels returns the model the llama.cpp server serves (or, behind a router
+// like llama-swap, the models it routes to) from its /v1/models endpoint
+func (c *LlamaCppClient) ListModels(ctx context.Context) ([]string, error) {
+	models, err := c.DescribeModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(models))
+	for i, m := range models {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+// DescribeModels returns the models of llama.cpp's /v1/models with their
+// training context length. A plain server has its one model loaded, with the
+// context size it was started with (/props' n_ctx), which is the one that applies.
+func (c *LlamaCppClient) DescribeModels(ctx context.Context) ([]ModelInfo, error) {
+	body, err := c.get(ctx, fmt.Sprintf("%s/v1/models", c.config.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID   string `json:"id"`
+			Meta struct {
+				NCtxTrain int `json:"n_ctx_train"`
+			} `json:"meta"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	models := make([]ModelInfo, len(resp.Data))
+	for i, m := range resp.Data {
+		models[i] = ModelInfo{ID: m.ID, ContextLength: m.Meta.NCtxTrain}
+	}
+
+	if len(models) == 1 {
+		models[0].Loaded = true
+		if body, err := c.get(ctx, fmt.Sprintf("%s/props", c.config.BaseURL)); err == nil {
+			var props struct {
+				DefaultGenerationSettings struct {
+					NCtx int `json:"n_ctx"`
+				} `json:"default_generation_settings"`
+			}
+			if json.Unmarshal(body, &props) == nil && props.DefaultGenerationSettings.NCtx > 0 {
+				models[0].ContextLength = props.DefaultGenerationSettings.NCtx
+			}
+		}
+	}
+	sortModels(models)
+	return models, nil
+}
+
+// sortModels sorts models by ID
+func sortModels(models []ModelInfo) {
+	slices.SortFunc(models, func(a, b ModelInfo) int { return strings.Compare(a.ID, b.ID) })
+}
+
+// ModelStatus is what a provider's model list says about the configured model
+type ModelStatus struct {
+	Model         string      `json:"model"`                   // The configured model
+	Found         bool        `json:"found"`                   // Whether the provider serves it
+	ID            string      `json:"id,omitempty"`            // The served model it is, e.g. "llama3:latest" for "llama3", or the loaded one for the placeholder
+	ContextLength int         `json:"contextLength,omitempty"` // Its context length per the server (0 = not reported)
+	Models        []ModelInfo `json:"models"`                  // Every model the provider lists
+}
+
+// InspectModel lists the provider's models (described where it can, see
+// ModelDescriber) and looks model up among them. An Ollama tag matches without
+// ":latest"; LM Studio's placeholder model ("" or "local-model") matches the
+// model it has loaded.
+func InspectModel(ctx context.Context, p Provider, model string) (ModelStatus, error) {
+	status := ModelStatus{Model: model}
+	if describer, ok := p.(ModelDescriber); ok {
+		models, err := describer.DescribeModels(ctx)
+		if err != nil {
+			return status, err
+		}
+		status.Models = models
+	} else if lister, ok := p.(ModelLister); ok {
+		ids, err := lister.ListModels(ctx)
+		if err != nil {
+			return status, err
+		}
+		for _, id := range ids {
+			status.Models = append(status.Models, ModelInfo{ID: id})
+		}
+	} else {
+		return status, fmt.Errorf("the provider can't list its models")
+	}
+
+	for _, m := range status.Models {
+		if m.ID == model || strings.TrimSuffix(m.ID, ":latest") == model {
+			status.Found, status.ID, status.ContextLength = true, m.ID, m.ContextLength
+			return status, nil
+		}
+	}
+	if model == "" || model == "local-model" {
+		// The placeholder gets whichever model is loaded, or with a server that
+		// doesn't say, whichever it serves
+		served := slices.IndexFunc(status.Models, func(m ModelInfo) bool { return m.Loaded })
+		if served < 0 && len(status.Models) == 1 {
+			served = 0
+		}
+		if served >= 0 {
+			m := status.Models[served]
+			status.Found, status.ID, status.ContextLength = true, m.ID, m.ContextLength
+		} else {
+			status.Found = len(status.Models) > 0
+		}
+	}
+	return status, nil
+}
+
+// Warnings explains what is wrong with the configured model: the provider
+// doesn't serve it, or contextLength (the configured context length, 0 to skip
+// the check) exceeds its own
+func (s ModelStatus) Warnings(contextLength int) []string {
+	var warnings []string
+	if !s.Found {
+		ids := make([]string, 0, min(len(s.Models), 10))
+		for _, m := range s.Models[:min(len(s.Models), 10)] {
+			ids = append(ids, m.ID)
+		}
+		switch {
+		case len(ids) == 0:
+			warnings = append(warnings, fmt.Sprintf("Model %q isn't served: the LLM server lists no models (is one loaded?)", s.Model))
+		case len(s.Models) > len(ids):
+			warnings = append(warnings, fmt.Sprintf("Model %q isn't served; available: %s, ... (%d models)", s.Model, strings.Join(ids, ", "), len(s.Models)))
+		default:
+			warnings = append(warnings, fmt.Sprintf("Model %q isn't served; available: %s", s.Model, strings.Join(ids, ", ")))
+		}
+	}
+	if contextLength > 0 && s.ContextLength > 0 && contextLength > s.ContextLength {
+		warnings = append(warnings, fmt.Sprintf("The context length %d exceeds the model's %d tokens; longer prompts will be truncated or rejected", contextLength, s.ContextLength))
+	}
+	return warnings
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultOllamaURL is the default base URL of a local Ollama server
+const DefaultOllamaURL = "http://localhost:11434"
+
+// OllamaClient talks to Ollama's native /api/chat endpoint
+type OllamaClient struct {
+	config     Config
+	httpClient *http.Client
+	noSchema   atomic.Bool // The server rejected a format schema (before Ollama 0.5), so ChatJSON asks in the prompt
+}
+
+// NewOllamaClient creates a new Ollama client
+func NewOllamaClient(cfg Config) *OllamaClient {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 120 * time.Second
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOllamaURL
+	}
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	// Accept OpenAI-style base URLs (http://host:11434/v1) as well
+	cfg.BaseURL = strings.TrimSuffix(strings.TrimSuffix(cfg.BaseURL, "/"), "/v1")
+	return &OllamaClient{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: cfg.Transport,
+		},
+	}
+}
+
+// ollamaOptions maps generation settings to Ollama's option names
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumCtx      int     `json:"num_ctx,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+// ollamaChatRequest represents the Ollama /api/chat request
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []Message       `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"` // JSON Schema the response must match (ChatJSON)
+	Options  ollamaOptions   `json:"options"`
+}
+
+// ollamaChatResponse represents the Ollama /api/chat response
+type ollamaChatResponse struct {
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	Error           string  `json:"error,omitempty"`
+	PromptEvalCount int     `json:"prompt_eval_count"` // Prompt tokens (sent once done)
+	EvalCount       int     `json:"eval_count"`        // Generated tokens (sent once done)
+}
+
+// tokens is the usage of a finished response
+func (r ollamaChatResponse) tokens() TokenUsage {
+	return TokenUsage{PromptTokens: r.PromptEvalCount, CompletionTokens: r.EvalCount}
+}
+
+// Chat sends a chat request to Ollama. A prompt too large for the model's
+// context window fails with a *ContextOverflowError.
+func (c *OllamaClient) Chat(ctx context.Context, messages []Message) (string, error) {
+	return c.chat(ctx, messages, nil)
+}
+
+// ChatJSON is Chat with the response constrained to schema through Ollama's
+// format parameter. If the server rejects it, this and later requests ask for
+// the JSON in the prompt instead.
+func (c *OllamaClient) ChatJSON(ctx context.Context, messages []Message, schema Schema) (string, error) {
+	if !c.noSchema.Load() {
+		resp, err := c.chat(ctx, messages, schema.Schema)
+		if !rejectsSchema(err) {
+			return resp, err
+		}
+		c.noSchema.Store(true)
+	}
+	return c.chat(ctx, withSchemaPrompt(messages, schema), nil)
+}
+
+// chat sends one /api/chat request, constrained to the format schema when it isn't nil
+func (c *OllamaClient) chat(ctx context.Context, messages []Message, format json.RawMessage) (string, error) {
+	temperature, maxTokens := c.config.generation(ctx)
+	reqBody := ollamaChatRequest{
+		Model:    c.config.Model,
+		Messages: messages,
+		Stream:   false,
+		Format:   format,
+		Options: ollamaOptions{
+			Temperature: temperature,
+			NumCtx:      c.config.ContextLength,
+			NumPredict:  maxTokens,
+		},
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/chat", c.config.BaseURL)
+	body, err := c.post(ctx, url, jsonBody)
+	if err != nil {
+		return "", detectOverflow(err)
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if chatResp.Error != "" {
+		return "", detectOverflow(fmt.Errorf("API returned error: %s", chatResp.Error))
+	}
+
+	recordUsage(ctx, chatResp.tokens())
+	return chatResp.Message.Content, nil
+}
+
+// post sends a JSON POST request, retrying transient failures per the configured policy
+func (c *OllamaClient) post(ctx context.Context, url string, jsonBody []byte) ([]byte, error) {
+	return c.send(ctx, "POST", url, jsonBody)
+}
+
+// get sends a GET request, retrying transient failures per the configured policy
+func (c *OllamaClient) get(ctx context.Context, url string) ([]byte, error) {
+	return c.send(ctx, "GET", url, nil)
+}
+
+// send performs one API request with retries and returns the body of the 200 response
+func (c *OllamaClient) send(ctx context.Context, method, url string, jsonBody []byte) ([]byte, error) {
+	return sendRequest(ctx, c.httpClient, c.config.Retry, method, url, jsonBody, nil)
+}
@@ -0,0 +1,39 @@
+package llm
+
+import "context"
+
+// Options override a provider's generation settings (Config.Temperature and
+// Config.MaxTokens) and ChatJSON's repairs for the calls made with a context,
+// e.g. a warmer report than the JSON planning calls of the same run
+type Options struct {
+	Temperature *float64 // nil = Config.Temperature
+	MaxTokens   int      // 0 = Config.MaxTokens
+	JSONRepairs *int     // Times ChatJSON sends back a response that didn't parse (nil = JSONRepairs)
+}
+
+type optionsKey struct{}
+
+// WithOptions returns a context whose chat calls use opts over the provider's settings
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// OptionsFromContext returns the options attached with WithOptions (zero if none)
+func OptionsFromContext(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options)
+	return opts
+}
+
+// generation returns the temperature and max tokens of a call made with ctx:
+// cfg's, unless WithOptions overrides them
+func (cfg Config) generation(ctx context.Context) (float64, int) {
+	temperature, maxTokens := cfg.Temperature, cfg.MaxTokens
+	opts := OptionsFromContext(ctx)
+	if opts.Temperature != nil {
+		temperature = *opts.Temperature
+	}
+	if opts.MaxTokens > 0 {
+		maxTokens = opts.MaxTokens
+	}
+	return temperature, maxTokens
+}
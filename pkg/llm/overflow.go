@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrContextOverflow matches (errors.Is) a ContextOverflowError
+var ErrContextOverflow = errors.New("prompt exceeds the model's context window")
+
+// ContextOverflowError is returned by Chat when the backend rejected the request
+// because the prompt didn't fit in the model's context window. The token counts
+// are filled in when the backend reports them.
+type ContextOverflowError struct {
+	ContextLength int   // The model's context window in tokens (0 = not reported)
+	PromptTokens  int   // Tokens the request needed (0 = not reported)
+	Err           error // The backend's error
+}
+
+func (e *ContextOverflowError) Error() string {
+	switch {
+	case e.ContextLength > 0 && e.PromptTokens > 0:
+		return fmt.Sprintf("%v (%d tokens, context length %d): %v", ErrContextOverflow, e.PromptTokens, e.ContextLength, e.Err)
+	case e.ContextLength > 0:
+		return fmt.Sprintf("%v (context length %d): %v", ErrContextOverflow, e.ContextLength, e.Err)
+	default:
+		return fmt.Sprintf("%v: %v", ErrContextOverflow, e.Err)
+	}
+}
+
+func (e *ContextOverflowError) Unwrap() error { return e.Err }
+
+// Is makes errors.Is(err, ErrContextOverflow) true
+func (e *ContextOverflowError) Is(target error) bool { return target == ErrContextOverflow }
+
+var (
+	// overflowMarkers are (lowercased) phrases the backends use for context overflows:
+	// LM Studio, llama.cpp server, Ollama, and the OpenAI-compatible hosted APIs
+	overflowMarkers = []string{
+		"context_length_exceeded",
+		"exceed_context_size_error",
+		"maximum context length",
+		"exceeds the available context size",
+		"exceeds maximum context length",
+		"context length of only",
+		"when context the overflows",
+		"context window exceeded",
+		"greater than the context length",
+		"prompt is too long",
+		"too many tokens",
+	}
+
+	// contextLengthRes capture the model's context window from overflow messages
+	contextLengthRes = []*regexp.Regexp{
+		regexp.MustCompile(`maximum context length is (\d+)`),
+		regexp.MustCompile(`context length of only (\d+)`),
+		regexp.MustCompile(`"n_ctx"\s*:\s*(\d+)`),
+		regexp.MustCompile(`context (?:size|length|window) (?:of |is )?\(?(\d+)`),
+	}
+
+	// promptTokensRes capture the size of the rejected prompt
+	promptTokensRes = []*regexp.Regexp{
+		regexp.MustCompile(`resulted in (\d+) tokens`),
+		regexp.MustCompile(`"n_prompt_tokens"\s*:\s*(\d+)`),
+		regexp.MustCompile(`keep the first (\d+) tokens`),
+		regexp.MustCompile(`requested (\d+) tokens`),
+		regexp.MustCompile(`prompt is too long: (\d+) tokens`),
+	}
+)
+
+// detectOverflow returns err as a *ContextOverflowError when its message (which
+// carries the backend's error payload) reports a context overflow, else err unchanged
+func detectOverflow(err error) error {
+	if err == nil || errors.Is(err, ErrContextOverflow) {
+		return err
+	}
+	msg := strings.ToLower(err.Error())
+	found := false
+	for _, marker := range overflowMarkers {
+		if strings.Contains(msg, marker) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return err
+	}
+	return &ContextOverflowError{
+		ContextLength: firstNumber(msg, contextLengthRes),
+		PromptTokens:  firstNumber(msg, promptTokensRes),
+		Err:           err,
+	}
+}
+
+// firstNumber returns the number captured by the first matching pattern (0 = none)
+func firstNumber(msg string, patterns []*regexp.Regexp) int {
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Provider is the interface for LLM backends
+type Provider interface {
+	Chat(ctx context.Context, messages []Message) (string, error)
+}
+
+// Provider names accepted by NewProvider
+const (
+	ProviderLMStudio   = "lmstudio"
+	ProviderOllama     = "ollama"
+	ProviderOpenAI     = "openai"
+	ProviderAzure      = "azure"
+	ProviderOpenRouter = "openrouter"
+	ProviderAnthropic  = "anthropic"
+	ProviderLlamaCpp   = "llamacpp"
+)
+
+// Providers are the provider names NewProvider accepts
+var Providers = []string{ProviderLMStudio, ProviderOllama, ProviderOpenAI, ProviderAzure, ProviderOpenRouter, ProviderAnthropic, ProviderLlamaCpp}
+
+// NewProvider creates the LLM backend identified by name: "lmstudio" (any
+// OpenAI-compatible server), "ollama", "llamacpp" (llama.cpp's native API), or
+// one of the hosted APIs "openai", "azure", "openrouter" and "anthropic", which
+// require cfg.APIKey
+func NewProvider(name string, cfg Config) (Provider, error) {
+	name = strings.ToLower(name)
+	switch name {
+	case "", ProviderLMStudio:
+		return NewClient(cfg), nil
+	case ProviderOllama:
+		return NewOllamaClient(cfg), nil
+	case ProviderLlamaCpp:
+		return NewLlamaCppClient(cfg), nil
+	case ProviderOpenAI, ProviderAzure, ProviderOpenRouter:
+		cfg, err := cloudConfig(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewClient(cfg), nil
+	case ProviderAnthropic:
+		cfg, err := cloudConfig(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnthropicClient(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q (supported: %s)", name, strings.Join(Providers, ", "))
+	}
+}
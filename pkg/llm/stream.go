@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Streamer is implemented by providers that can stream a chat response while
+// it is generated: onDelta gets each new piece of text as it arrives, and the
+// whole response is returned as Chat would
+type Streamer interface {
+	ChatStream(ctx context.Context, messages []Message, onDelta func(string)) (string, error)
+}
+
+// errStreamDone ends readLines early, at the stream's end marker
+var errStreamDone = errors.New("stream done")
+
+// chatStreamChunk is one server-sent event of an OpenAI streaming chat completion
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"` // Last chunk, when stream_options asked for it
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ChatStream sends a chat request with streaming enabled and passes each piece
+// of the response to onDelta as the server sends it
+func (c *Client) ChatStream(ctx context.Context, messages []Message, onDelta func(string)) (string, error) {
+	temperature, maxTokens := c.config.generation(ctx)
+	reqBody := ChatRequest{
+		Model:       c.config.Model,
+		Messages:    messages,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		Stream:      true,
+	}
+	if !IsCloud(c.config.Provider) {
+		reqBody.ContextLength = c.config.ContextLength
+	} else {
+		reqBody.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/chat/completions", c.config.BaseURL)
+	resp, err := openStream(ctx, c.httpClient, c.config.Retry, url, jsonBody, c.setAuth)
+	if err != nil {
+		return "", detectOverflow(err)
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	var usage TokenUsage
+	err = readLines(resp.Body, func(line []byte) error {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			return nil // Comments and other SSE fields
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk chatStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return detectOverflow(fmt.Errorf("API returned error: %s", chunk.Error.Message))
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.tokens()
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			full.WriteString(chunk.Choices[0].Delta.Content)
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	recordUsage(ctx, usage)
+	return full.String(), nil
+}
+
+// ChatStream sends a chat request to Ollama with streaming enabled and passes
+// each piece of the response to onDelta as the server sends it
+func (c *OllamaClient) ChatStream(ctx context.Context, messages []Message, onDelta func(string)) (string, error) {
+	temperature, maxTokens := c.config.generation(ctx)
+	reqBody := ollamaChatRequest{
+		Model:    c.config.Model,
+		Messages: messages,
+		Stream:   true,
+		Options: ollamaOptions{
+			Temperature: temperature,
+			NumCtx:      c.config.ContextLength,
+			NumPredict:  maxTokens,
+		},
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/chat", c.config.BaseURL)
+	resp, err := openStream(ctx, c.httpClient, c.config.Retry, url, jsonBody, func(*http.Request) {})
+	if err != nil {
+		return "", detectOverflow(err)
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	err = readLines(resp.Body, func(line []byte) error {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return detectOverflow(fmt.Errorf("API returned error: %s", chunk.Error))
+		}
+		if chunk.Message.Content != "" {
+			full.WriteString(chunk.Message.Content)
+			onDelta(chunk.Message.Content)
+		}
+		if chunk.Done {
+			recordUsage(ctx, chunk.tokens())
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return full.String(), nil
+}
+
+// openStream sends a streaming POST request, retrying transient failures per
+// policy until the server answers 200, and returns the response to read the
+// stream from. Nothing is retried once the stream has started.
+func openStream(ctx context.Context, client *http.Client, policy retry.Policy, url string, jsonBody []byte, setAuth func(*http.Request)) (*http.Response, error) {
+	var resp *http.Response
+	err := policy.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		setAuth(req)
+
+		r, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		if r.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(r.Body)
+			r.Body.Close()
+			return retry.ResponseError(r, "API error (status %d): %s", r.StatusCode, string(body))
+		}
+		resp = r
+		return nil
+	})
+	return resp, err
+}
+
+// readLines calls onLine with each non-empty line of a streamed body until the
+// body ends or onLine returns errStreamDone
+func readLines(body io.Reader, onLine func([]byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := onLine(line); err != nil {
+			if errors.Is(err, errStreamDone) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
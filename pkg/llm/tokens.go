@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer is implemented by providers that can count tokens with the loaded
+// model's own tokenizer
+type Tokenizer interface {
+	CountTokens(ctx context.Context, text string) (int, error)
+}
+
+// tokenizeRequest is the llama.cpp-style /tokenize request body
+type tokenizeRequest struct {
+	Content string `json:"content"`
+}
+
+// tokenizeResponse is the llama.cpp-style /tokenize response
+type tokenizeResponse struct {
+	Tokens []json.RawMessage `json:"tokens"`
+}
+
+// CountTokens tokenizes text with the server's model via its /tokenize endpoint
+// (served next to the OpenAI-compatible /v1 API by LM Studio and llama.cpp)
+func (c *Client) CountTokens(ctx context.Context, text string) (int, error) {
+	if IsCloud(c.config.Provider) {
+		return 0, fmt.Errorf("%s has no tokenize endpoint", c.config.Provider)
+	}
+	root := strings.TrimSuffix(strings.TrimSuffix(c.config.BaseURL, "/"), "/v1")
+	return countTokens(ctx, c.post, root+"/tokenize", text)
+}
+
+// countTokens counts the tokens of text with the llama.cpp-style /tokenize
+// endpoint at url, sending the request with post
+func countTokens(ctx context.Context, post func(context.Context, string, []byte) ([]byte, error), url, text string) (int, error) {
+	jsonBody, err := json.Marshal(tokenizeRequest{Content: text})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	body, err := post(ctx, url, jsonBody)
+	if err != nil {
+		return 0, err
+	}
+
+	var tokResp tokenizeResponse
+	if err := json.Unmarshal(body, &tokResp); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if tokResp.Tokens == nil {
+		return 0, fmt.Errorf("no tokens in response")
+	}
+	return len(tokResp.Tokens), nil
+}
+
+// EstimateTokens approximates a BPE token count without a tokenizer. Text is split
+// the way tiktoken's cl100k pre-tokenizer does (words with their leading space,
+// digit groups of up to three, punctuation runs, newlines) and each piece is costed
+// by length and script, so code, URLs, and CJK text aren't undercounted the way a
+// flat chars-per-token ratio is. It errs on the high side.
+func EstimateTokens(text string) int {
+	tokens := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\n' || r == '\r':
+			// A run of line breaks is one token
+			for i < len(text) && (text[i] == '\n' || text[i] == '\r') {
+				i++
+			}
+			tokens++
+
+		case unicode.IsSpace(r):
+			// Single spaces attach to the following word; longer runs of indentation cost extra
+			n := 0
+			for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+				i++
+				n++
+			}
+			if n > 1 {
+				tokens += (n + 2) / 4
+			}
+			if n == 0 {
+				i += size
+			}
+
+		case unicode.IsLetter(r):
+			ascii, wide, other := 0, 0, 0
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsLetter(r) && r != '\'' {
+					break
+				}
+				switch {
+				case r < utf8.RuneSelf:
+					ascii++
+				case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+					wide++
+				default:
+					other++
+				}
+				i += size
+			}
+			// Common English words are one token; long or rare ones split into ~6-letter pieces
+			tokens += max(1, (ascii+5)/6+(wide*3+1)/2+(other+1)/2)
+
+		case unicode.IsDigit(r):
+			n := 0
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+				n++
+			}
+			tokens += (n + 2) / 3
+
+		default:
+			// Punctuation and symbols: short runs like "://" or "**" merge into one token
+			n := 0
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+					break
+				}
+				if r >= utf8.RuneSelf {
+					n++ // emoji and other symbols are usually several byte-level tokens
+				}
+				i += size
+				n++
+			}
+			tokens += (n + 1) / 2
+		}
+	}
+	return tokens
+}
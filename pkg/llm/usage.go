@@ -0,0 +1,49 @@
+package llm
+
+import "context"
+
+// TokenUsage is the tokens chat calls used, as the backend reported them
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Add returns the sum of u and other
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{PromptTokens: u.PromptTokens + other.PromptTokens, CompletionTokens: u.CompletionTokens + other.CompletionTokens}
+}
+
+// Total is the prompt and completion tokens together
+func (u TokenUsage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+type usageKey struct{}
+
+// WithUsageRecorder returns a context whose chat calls report the tokens each
+// used to record (calls answered from the cache, or by backends that don't
+// report usage, aren't recorded)
+func WithUsageRecorder(ctx context.Context, record func(TokenUsage)) context.Context {
+	return context.WithValue(ctx, usageKey{}, record)
+}
+
+// recordUsage reports a call's usage to the recorder attached to ctx, if any
+func recordUsage(ctx context.Context, u TokenUsage) {
+	if record, ok := ctx.Value(usageKey{}).(func(TokenUsage)); ok && u.Total() > 0 {
+		record(u)
+	}
+}
+
+// openAIUsage is the usage field of OpenAI-compatible chat responses (and of
+// the last chunk of a stream that asked for it)
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *openAIUsage) tokens() TokenUsage {
+	if u == nil {
+		return TokenUsage{}
+	}
+	return TokenUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+}
@@ -0,0 +1,148 @@
+// Package logging builds the slog loggers the agent and search packages write
+// their progress to: the console output the CLI has always printed (one line
+// per message, attributes appended as key=value) or JSON lines.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log formats accepted by New
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Stdout writes to whatever os.Stdout is at the time of each write, so a logger
+// created early follows later redirections (--json output, the MCP server)
+var Stdout io.Writer = stdout{}
+
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+
+// New returns a logger writing messages at level and above to w, as console
+// lines (FormatText) or JSON objects (FormatJSON), one per line
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return slog.New(NewConsoleHandler(w, level)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (use text or json)", format)
+	}
+}
+
+// ParseLevel parses debug, info, warn, or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (use debug, info, warn, or error)", s)
+	}
+	return level, nil
+}
+
+// Default is the console logger at info level on stdout, used when no logger is configured
+func Default() *slog.Logger {
+	return slog.New(NewConsoleHandler(Stdout, slog.LevelInfo))
+}
+
+// Discard returns a logger that drops every message
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// ConsoleHandler prints each record as its message followed by its attributes
+// as key=value, without timestamps or levels (the messages carry emoji for that)
+type ConsoleHandler struct {
+	w      io.Writer
+	level  slog.Leveler
+	prefix string // Pre-formatted attributes from WithAttrs
+	group  string // Key prefix from WithGroup, e.g. "fetch."
+	mu     *sync.Mutex
+}
+
+// NewConsoleHandler returns a handler writing records at level and above to w
+func NewConsoleHandler(w io.Writer, level slog.Leveler) *ConsoleHandler {
+	return &ConsoleHandler{w: w, level: level, mu: &sync.Mutex{}}
+}
+
+// Enabled reports whether records at level are printed
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle prints one record
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(r.Message)
+	sb.WriteString(h.prefix)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&sb, h.group, a)
+		return true
+	})
+	sb.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, sb.String())
+	return err
+}
+
+// WithAttrs returns a handler that appends attrs to every record
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var sb strings.Builder
+	for _, a := range attrs {
+		appendAttr(&sb, h.group, a)
+	}
+	h2 := *h
+	h2.prefix += sb.String()
+	return &h2
+}
+
+// WithGroup returns a handler that prefixes later attribute keys with name
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group += name + "."
+	return &h2
+}
+
+// appendAttr writes " key=value", quoting values that contain spaces
+func appendAttr(sb *strings.Builder, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			appendAttr(sb, group+a.Key+".", ga)
+		}
+		return
+	}
+
+	var value string
+	switch a.Value.Kind() {
+	case slog.KindDuration:
+		value = a.Value.Duration().Round(time.Millisecond).String()
+	case slog.KindFloat64:
+		value = strconv.FormatFloat(a.Value.Float64(), 'f', -1, 64)
+	default:
+		value = a.Value.String()
+	}
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(sb, " %s%s=%s", group, a.Key, value)
+}
@@ -0,0 +1,251 @@
+// Package mcp serves tools over the Model Context Protocol's stdio transport:
+// newline-delimited JSON-RPC 2.0 messages on stdin and stdout.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// protocolVersions are the MCP revisions this server speaks, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is a callable tool. Handler receives the call's arguments object and
+// returns text for the model; an error is reported to the model as a failed call.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema map[string]any // JSON Schema of the arguments object
+	Handler     func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// Server is an MCP server exposing a set of tools
+type Server struct {
+	name         string
+	version      string
+	instructions string
+	tools        []Tool
+
+	writeMu sync.Mutex
+	w       io.Writer
+
+	mu      sync.Mutex
+	pending map[string]context.CancelFunc // In-flight tool calls by request ID
+}
+
+// NewServer creates a server that introduces itself with name and version;
+// instructions (optional) tell the client's model how to use the tools
+func NewServer(name, version, instructions string) *Server {
+	return &Server{
+		name:         name,
+		version:      version,
+		instructions: instructions,
+		pending:      make(map[string]context.CancelFunc),
+	}
+}
+
+// AddTool registers a tool
+func (s *Server) AddTool(tool Tool) {
+	s.tools = append(s.tools, tool)
+}
+
+// request is an incoming JSON-RPC request or notification (no ID)
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// content is a tool result content block (only text is produced)
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type callResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError"`
+}
+
+// Serve handles messages from r and writes responses to w until r is exhausted.
+// Tool calls run concurrently and are cancelled when ctx ends or the client
+// cancels them; Serve waits for the ones in flight before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+	var calls sync.WaitGroup
+	defer calls.Wait()
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			s.handle(ctx, line, &calls)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+	}
+}
+
+// handle dispatches one message
+func (s *Server) handle(ctx context.Context, line []byte, calls *sync.WaitGroup) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		if line[0] == '[' {
+			s.fail(nil, codeInvalidRequest, "Batch requests are not supported")
+		} else {
+			s.fail(nil, codeParseError, "Parse error: "+err.Error())
+		}
+		return
+	}
+	notification := len(req.ID) == 0
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := protocolVersions[0]
+		if slices.Contains(protocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		s.reply(req.ID, map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+			"instructions":    s.instructions,
+		})
+
+	case "ping":
+		s.reply(req.ID, map[string]any{})
+
+	case "tools/list":
+		tools := make([]map[string]any, len(s.tools))
+		for i, t := range s.tools {
+			tools[i] = map[string]any{"name": t.Name, "description": t.Description, "inputSchema": t.InputSchema}
+		}
+		s.reply(req.ID, map[string]any{"tools": tools})
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.fail(req.ID, codeInvalidParams, "Invalid params: "+err.Error())
+			return
+		}
+		i := slices.IndexFunc(s.tools, func(t Tool) bool { return t.Name == params.Name })
+		if i == -1 {
+			s.fail(req.ID, codeInvalidParams, fmt.Sprintf("Unknown tool: %s", params.Name))
+			return
+		}
+		if len(params.Arguments) == 0 || string(params.Arguments) == "null" {
+			params.Arguments = json.RawMessage("{}")
+		}
+
+		callCtx, cancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.pending[string(req.ID)] = cancel
+		s.mu.Unlock()
+
+		calls.Add(1)
+		go func() {
+			defer calls.Done()
+			defer s.finish(req.ID)
+
+			text, err := s.tools[i].Handler(callCtx, params.Arguments)
+			if callCtx.Err() != nil && ctx.Err() == nil {
+				return // Cancelled by the client, which expects no response
+			}
+			if err != nil {
+				s.reply(req.ID, callResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true})
+				return
+			}
+			s.reply(req.ID, callResult{Content: []content{{Type: "text", Text: text}}})
+		}()
+
+	case "notifications/cancelled":
+		var params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		json.Unmarshal(req.Params, &params)
+		s.mu.Lock()
+		cancel := s.pending[string(params.RequestID)]
+		s.mu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+
+	default:
+		// Other notifications (initialized, roots changed, ...) need no handling
+		if !notification {
+			s.fail(req.ID, codeMethodNotFound, "Method not found: "+req.Method)
+		}
+	}
+}
+
+// finish forgets a completed tool call
+func (s *Server) finish(id json.RawMessage) {
+	s.mu.Lock()
+	if cancel := s.pending[string(id)]; cancel != nil {
+		cancel()
+		delete(s.pending, string(id))
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) reply(id json.RawMessage, result any) {
+	s.send(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) fail(id json.RawMessage, code int, message string) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	s.send(response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+// send writes one message per line (messages must not contain raw newlines,
+// which json.Marshal guarantees)
+func (s *Server) send(msg response) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		data, _ = json.Marshal(response{JSONRPC: "2.0", ID: msg.ID, Error: &rpcError{Code: -32603, Message: err.Error()}})
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.w.Write(append(data, '\n'))
+}
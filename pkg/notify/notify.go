@@ -0,0 +1,124 @@
+// Package notify posts research job updates to chat: Slack and Discord incoming
+// webhooks for plan-ready, completed, and failed jobs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxDiscordContent is the longest message a Discord webhook accepts
+const maxDiscordContent = 2000
+
+// Config holds the webhook URLs messages are posted to
+type Config struct {
+	SlackWebhook   string            // Slack incoming webhook URL (empty = no Slack messages)
+	DiscordWebhook string            // Discord webhook URL (empty = no Discord messages)
+	Transport      http.RoundTripper // Transport for the webhooks, e.g. through a proxy (nil = http.DefaultTransport)
+	Retry          retry.Policy      // Retry policy for transient failures (zero value = retry.DefaultPolicy())
+}
+
+// Message is one update: a line of text and an optional link, e.g. to the report
+type Message struct {
+	Text      string // Plain text; *bold* is rendered on both platforms
+	Link      string // URL shown after the text (optional)
+	LinkLabel string // The link's text (empty = the URL)
+}
+
+// Notifier posts messages to the configured webhooks
+type Notifier struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// New creates a notifier; it posts nothing when cfg has no webhook
+func New(cfg Config) *Notifier {
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	return &Notifier{config: cfg, httpClient: &http.Client{Timeout: 15 * time.Second, Transport: cfg.Transport}}
+}
+
+// Enabled reports whether any webhook is configured
+func (n *Notifier) Enabled() bool {
+	return n != nil && (n.config.SlackWebhook != "" || n.config.DiscordWebhook != "")
+}
+
+// Send posts msg to every configured webhook; the error joins the failed ones
+func (n *Notifier) Send(ctx context.Context, msg Message) error {
+	if !n.Enabled() {
+		return nil
+	}
+	var errs []error
+	if n.config.SlackWebhook != "" {
+		if err := n.post(ctx, n.config.SlackWebhook, map[string]string{"text": slackText(msg)}); err != nil {
+			errs = append(errs, fmt.Errorf("slack: %w", err))
+		}
+	}
+	if n.config.DiscordWebhook != "" {
+		if err := n.post(ctx, n.config.DiscordWebhook, map[string]string{"content": discordText(msg)}); err != nil {
+			errs = append(errs, fmt.Errorf("discord: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// slackText renders msg in Slack's mrkdwn (links are <url|label>)
+func slackText(msg Message) string {
+	if msg.Link == "" {
+		return msg.Text
+	}
+	if msg.LinkLabel == "" {
+		return fmt.Sprintf("%s\n<%s>", msg.Text, msg.Link)
+	}
+	return fmt.Sprintf("%s\n<%s|%s>", msg.Text, msg.Link, msg.LinkLabel)
+}
+
+// discordText renders msg in Discord's Markdown, cut to the content limit
+func discordText(msg Message) string {
+	link := ""
+	switch {
+	case msg.Link != "" && msg.LinkLabel != "":
+		link = fmt.Sprintf("\n[%s](%s)", msg.LinkLabel, msg.Link)
+	case msg.Link != "":
+		link = "\n" + msg.Link
+	}
+	// Discord bolds with **, Slack with *
+	text := bytes.ReplaceAll([]byte(msg.Text), []byte("*"), []byte("**"))
+	if room := maxDiscordContent - len(link); len(text) > room {
+		text = append(bytes.ToValidUTF8(text[:room-3], nil), "..."...)
+	}
+	return string(text) + link
+}
+
+// post sends one JSON payload to a webhook
+func (n *Notifier) post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return n.config.Retry.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := n.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return retry.ResponseError(resp, "webhook returned status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+		}
+		return nil
+	})
+}
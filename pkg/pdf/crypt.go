@@ -0,0 +1,237 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+)
+
+// Many published PDFs are encrypted with an empty user password (an owner
+// password only restricts printing or copying), so they can be read by anyone.
+// pdfCrypt implements the standard security handler for that case: RC4 and
+// AES-128 (revisions 2-4) and AES-256 (revisions 5-6).
+
+// pdfPasswordPad pads passwords to 32 bytes (ISO 32000-1, algorithm 2)
+var pdfPasswordPad = []byte{
+	0x28, 0xbf, 0x4e, 0x5e, 0x4e, 0x75, 0x8a, 0x41, 0x64, 0x00, 0x4e, 0x56, 0xff, 0xfa, 0x01, 0x08,
+	0x2e, 0x2e, 0x00, 0xb6, 0xd0, 0x68, 0x3e, 0x80, 0x2f, 0x0c, 0xa9, 0xfe, 0x64, 0x53, 0x69, 0x7a,
+}
+
+// errPDFPassword is returned for PDFs that can't be opened without a password
+var errPDFPassword = errors.New("PDF is password-protected")
+
+// pdfCrypt decrypts the streams of an encrypted PDF
+type pdfCrypt struct {
+	key    []byte
+	aes    bool
+	perObj bool // Revisions 2-4 derive a key per object; AES-256 uses the file key
+}
+
+// newPDFCrypt derives the file key for the empty user password
+func newPDFCrypt(enc pdfDict, id []byte) (*pdfCrypt, error) {
+	if enc.name("Filter") != "Standard" {
+		return nil, fmt.Errorf("unsupported PDF security handler %q", enc.name("Filter"))
+	}
+	v, _ := enc["V"].(float64)
+	r, _ := enc["R"].(float64)
+	o, _ := enc["O"].(pdfString)
+	u, _ := enc["U"].(pdfString)
+
+	// Version 4+ names its crypt filters; the stream filter decides the cipher
+	method := "V2"
+	if v >= 4 {
+		stmF := enc.name("StmF")
+		if stmF == "" || stmF == "Identity" {
+			return nil, nil // Streams aren't encrypted
+		}
+		if cf, ok := enc["CF"].(pdfDict); ok {
+			if f, ok := cf[pdfName(stmF)].(pdfDict); ok && f.name("CFM") != "" {
+				method = f.name("CFM")
+			}
+		}
+	}
+
+	switch method {
+	case "None":
+		return nil, nil
+	case "AESV3":
+		key, err := pdfAES256Key(int(r), u, enc["UE"])
+		if err != nil {
+			return nil, err
+		}
+		return &pdfCrypt{key: key, aes: true}, nil
+	case "V2", "AESV2":
+	default:
+		return nil, fmt.Errorf("unsupported PDF encryption method %s", method)
+	}
+	if v >= 5 {
+		return nil, fmt.Errorf("unsupported PDF encryption version %d", int(v))
+	}
+
+	n := 5
+	if length, _ := enc["Length"].(float64); v >= 2 && length >= 40 {
+		n = int(length) / 8
+	}
+	if method == "AESV2" {
+		n = 16
+	}
+	n = min(max(n, 5), 16)
+
+	// Algorithm 2: the file key from the (empty) user password
+	p, _ := enc["P"].(float64)
+	perms := uint32(int32(p))
+	h := md5.New()
+	h.Write(pdfPasswordPad)
+	h.Write(o[:min(len(o), 32)])
+	h.Write([]byte{byte(perms), byte(perms >> 8), byte(perms >> 16), byte(perms >> 24)})
+	h.Write(id)
+	if encryptMetadata, ok := enc["EncryptMetadata"].(bool); ok && !encryptMetadata && r >= 4 {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := h.Sum(nil)
+	if r >= 3 {
+		for range 50 {
+			sum := md5.Sum(key[:n])
+			key = sum[:]
+		}
+	}
+	key = key[:n]
+
+	if !pdfCheckUserKey(key, int(r), u, id) {
+		return nil, errPDFPassword
+	}
+	return &pdfCrypt{key: key, aes: method == "AESV2", perObj: true}, nil
+}
+
+// pdfCheckUserKey verifies the file key against the /U entry (algorithms 4 and 5)
+func pdfCheckUserKey(key []byte, r int, u, id []byte) bool {
+	if r == 2 {
+		c, err := rc4.NewCipher(key)
+		if err != nil || len(u) < 32 {
+			return false
+		}
+		out := make([]byte, 32)
+		c.XORKeyStream(out, pdfPasswordPad)
+		return bytes.Equal(out, u[:32])
+	}
+
+	h := md5.New()
+	h.Write(pdfPasswordPad)
+	h.Write(id)
+	out := h.Sum(nil)
+	tmp := make([]byte, len(key))
+	for i := range 20 {
+		for j := range key {
+			tmp[j] = key[j] ^ byte(i)
+		}
+		c, err := rc4.NewCipher(tmp)
+		if err != nil {
+			return false
+		}
+		c.XORKeyStream(out, out)
+	}
+	return len(u) >= 16 && bytes.Equal(out, u[:16])
+}
+
+// pdfAES256Key derives the AES-256 file key for the empty user password
+func pdfAES256Key(r int, u pdfString, ue any) ([]byte, error) {
+	ueKey, _ := ue.(pdfString)
+	if len(u) < 48 || len(ueKey) < 32 {
+		return nil, errors.New("malformed PDF encryption dictionary")
+	}
+	hash := func(salt []byte) []byte {
+		if r >= 6 {
+			return pdfHash2B(salt)
+		}
+		sum := sha256.Sum256(salt)
+		return sum[:]
+	}
+
+	if !bytes.Equal(hash(u[32:40]), u[:32]) {
+		return nil, errPDFPassword
+	}
+	block, err := aes.NewCipher(hash(u[40:48]))
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(key, ueKey[:32])
+	return key, nil
+}
+
+// pdfHash2B is the iterated hash of ISO 32000-2 algorithm 2.B for the empty
+// user password
+func pdfHash2B(salt []byte) []byte {
+	sum := sha256.Sum256(salt)
+	k := sum[:]
+	for round := 1; ; round++ {
+		k1 := bytes.Repeat(k, 64)
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+
+		mod := 0
+		for _, b := range e[:16] {
+			mod += int(b)
+		}
+		switch mod % 3 {
+		case 0:
+			s := sha256.Sum256(e)
+			k = s[:]
+		case 1:
+			s := sha512.Sum384(e)
+			k = s[:]
+		case 2:
+			s := sha512.Sum512(e)
+			k = s[:]
+		}
+		if round >= 64 && int(e[len(e)-1]) <= round-32 {
+			return k[:32]
+		}
+	}
+}
+
+// decrypt decrypts the data of a stream belonging to object ref
+func (c *pdfCrypt) decrypt(ref pdfRef, data []byte) ([]byte, error) {
+	key := c.key
+	if c.perObj {
+		b := append([]byte(nil), c.key...)
+		b = append(b, byte(ref.num), byte(ref.num>>8), byte(ref.num>>16), byte(ref.gen), byte(ref.gen>>8))
+		if c.aes {
+			b = append(b, "sAlT"...)
+		}
+		sum := md5.Sum(b)
+		key = sum[:min(len(c.key)+5, 16)]
+	}
+
+	if !c.aes {
+		rc, err := rc4.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		rc.XORKeyStream(out, data)
+		return out, nil
+	}
+
+	// AES-CBC with the IV in the first block and PKCS#5 padding
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("malformed AES-encrypted PDF stream")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(out, data[aes.BlockSize:])
+	if pad := int(out[len(out)-1]); pad >= 1 && pad <= aes.BlockSize {
+		out = out[:len(out)-pad]
+	}
+	return out, nil
+}
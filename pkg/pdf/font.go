@@ -0,0 +1,452 @@
+package pdf
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfFont maps the character codes of a font to text and glyph widths
+type pdfFont struct {
+	toUnicode    *pdfCMap        // ToUnicode CMap (nil if the font has none)
+	codes        [256]string     // Simple fonts: text of each code from the font's encoding
+	composite    bool            // Type0 font with multi-byte codes
+	utf16        bool            // Composite font whose CMap encodes UCS-2/UTF-16 directly
+	widths       map[int]float64 // Glyph widths in 1/1000 em by code (CID for composite fonts)
+	defaultWidth float64
+}
+
+// font loads (and caches) the font behind a resource entry
+func (doc *pdfDocument) font(v any) *pdfFont {
+	ref, isRef := v.(pdfRef)
+	if isRef {
+		if f, ok := doc.fonts[ref]; ok {
+			return f
+		}
+	}
+
+	fd := doc.dict(v)
+	f := &pdfFont{widths: make(map[int]float64), defaultWidth: 500}
+	if fd == nil {
+		return f
+	}
+	if tu, ok := doc.resolve(fd["ToUnicode"]).(*pdfStream); ok {
+		if data, err := doc.decode(tu); err == nil {
+			f.toUnicode = parseCMap(data)
+		}
+	}
+
+	if fd.name("Subtype") == "Type0" {
+		f.composite = true
+		enc, _ := doc.resolve(fd["Encoding"]).(pdfName)
+		f.utf16 = strings.Contains(string(enc), "UCS2") || strings.Contains(string(enc), "UTF16")
+		f.defaultWidth = 1000
+		if descendants, ok := doc.resolve(fd["DescendantFonts"]).(pdfArray); ok && len(descendants) > 0 {
+			doc.loadCIDWidths(f, doc.dict(descendants[0]))
+		}
+	} else {
+		f.codes = winAnsiEncoding
+		switch enc := doc.resolve(fd["Encoding"]).(type) {
+		case pdfName:
+			f.codes = pdfEncoding(enc)
+		case pdfDict:
+			if base, ok := enc["BaseEncoding"].(pdfName); ok {
+				f.codes = pdfEncoding(base)
+			}
+			if diffs, ok := doc.resolve(enc["Differences"]).(pdfArray); ok {
+				code := 0
+				for _, d := range diffs {
+					switch d := doc.resolve(d).(type) {
+					case float64:
+						code = int(d)
+					case pdfName:
+						if code >= 0 && code < 256 {
+							f.codes[code] = glyphText(string(d))
+						}
+						code++
+					}
+				}
+			}
+		}
+
+		first := int(doc.number(fd["FirstChar"]))
+		if widths, ok := doc.resolve(fd["Widths"]).(pdfArray); ok {
+			for i, w := range widths {
+				f.widths[first+i] = doc.number(w)
+			}
+		} else if strings.Contains(fd.name("BaseFont"), "Courier") {
+			f.defaultWidth = 600
+		} else {
+			// Standard fonts may omit their widths; Helvetica's are close enough for spacing
+			for i, w := range HelveticaWidths {
+				f.widths[32+i] = float64(w)
+			}
+		}
+		if desc := doc.dict(fd["FontDescriptor"]); desc != nil {
+			if w := doc.number(desc["MissingWidth"]); w > 0 {
+				f.defaultWidth = w
+			}
+		}
+	}
+
+	if isRef {
+		doc.fonts[ref] = f
+	}
+	return f
+}
+
+// loadCIDWidths reads a CIDFont's /DW and /W widths
+func (doc *pdfDocument) loadCIDWidths(f *pdfFont, cidFont pdfDict) {
+	if cidFont == nil {
+		return
+	}
+	if dw := doc.number(cidFont["DW"]); dw > 0 {
+		f.defaultWidth = dw
+	}
+	w, _ := doc.resolve(cidFont["W"]).(pdfArray)
+	for i := 0; i < len(w); {
+		first := int(doc.number(w[i]))
+		if i+1 >= len(w) {
+			return
+		}
+		// Either "c [w1 w2 ...]" or "cFirst cLast w"
+		if list, ok := doc.resolve(w[i+1]).(pdfArray); ok {
+			for j, width := range list {
+				f.widths[first+j] = doc.number(width)
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last, width := int(doc.number(w[i+1])), doc.number(w[i+2])
+		for c := first; c <= last && c-first < 65536; c++ {
+			f.widths[c] = width
+		}
+		i += 3
+	}
+}
+
+// decode returns the text of a shown string and its advance in text space units
+func (f *pdfFont) decode(s []byte, size, charSpace, wordSpace float64) (string, float64) {
+	var b strings.Builder
+	var width float64
+	if f.composite && f.utf16 && f.toUnicode == nil {
+		for i := 0; i+1 < len(s); i += 2 {
+			width += f.width(int(s[i])<<8|int(s[i+1]))*size/1000 + charSpace
+		}
+		return decodeUTF16(s), width
+	}
+
+	for len(s) > 0 {
+		n := 1
+		if f.toUnicode != nil {
+			n = f.toUnicode.codeLength(s, f.composite)
+		} else if f.composite {
+			n = 2
+		}
+		n = min(n, len(s))
+		code := s[:n]
+		s = s[n:]
+
+		var c int
+		for _, by := range code {
+			c = c<<8 | int(by)
+		}
+		width += f.width(c)*size/1000 + charSpace
+		if n == 1 && code[0] == ' ' {
+			width += wordSpace
+		}
+
+		if f.toUnicode != nil {
+			if text, ok := f.toUnicode.lookup(code); ok {
+				b.WriteString(text)
+				continue
+			}
+		}
+		if !f.composite {
+			b.WriteString(f.codes[code[0]])
+		}
+	}
+	return b.String(), width
+}
+
+// width returns the width of a glyph in 1/1000 em
+func (f *pdfFont) width(code int) float64 {
+	if w, ok := f.widths[code]; ok && w > 0 {
+		return w
+	}
+	return f.defaultWidth
+}
+
+// pdfCMap is a parsed ToUnicode CMap
+type pdfCMap struct {
+	codespaces []pdfCodeRange
+	chars      map[string]string // Code bytes to text
+	ranges     []pdfCMapRange
+}
+
+// pdfCodeRange is a range of equal-length codes
+type pdfCodeRange struct {
+	lo, hi []byte
+}
+
+// pdfCMapRange maps a range of codes to consecutive text (dst) or to a list
+type pdfCMapRange struct {
+	pdfCodeRange
+	dst  []uint16 // UTF-16 of the first code's text; the last unit increments along the range
+	list []string
+}
+
+func (r pdfCodeRange) contains(code []byte) bool {
+	return len(code) == len(r.lo) && bytes.Compare(code, r.lo) >= 0 && bytes.Compare(code, r.hi) <= 0
+}
+
+// parseCMap reads the codespace ranges and bfchar/bfrange mappings of a CMap
+func parseCMap(data []byte) *pdfCMap {
+	cm := &pdfCMap{chars: make(map[string]string)}
+	l := &pdfLexer{data: data}
+	var ops []any
+	for {
+		obj, err := l.object()
+		if err != nil {
+			break
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			ops = append(ops, obj)
+			continue
+		}
+
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(ops); i += 2 {
+				lo, ok1 := ops[i].(pdfString)
+				hi, ok2 := ops[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					cm.codespaces = append(cm.codespaces, pdfCodeRange{lo, hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(ops); i += 2 {
+				src, ok := ops[i].(pdfString)
+				if !ok {
+					continue
+				}
+				switch dst := ops[i+1].(type) {
+				case pdfString:
+					cm.chars[string(src)] = decodeUTF16(dst)
+				case pdfName:
+					cm.chars[string(src)] = glyphText(string(dst))
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(ops); i += 3 {
+				lo, ok1 := ops[i].(pdfString)
+				hi, ok2 := ops[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 {
+					continue
+				}
+				r := pdfCMapRange{pdfCodeRange: pdfCodeRange{lo, hi}}
+				switch dst := ops[i+2].(type) {
+				case pdfString:
+					r.dst = utf16Units(dst)
+				case pdfArray:
+					for _, d := range dst {
+						s, _ := d.(pdfString)
+						r.list = append(r.list, decodeUTF16(s))
+					}
+				}
+				cm.ranges = append(cm.ranges, r)
+			}
+		}
+		ops = ops[:0]
+	}
+	return cm
+}
+
+// codeLength returns the byte length of the code at the start of s
+func (cm *pdfCMap) codeLength(s []byte, composite bool) int {
+	for _, r := range cm.codespaces {
+		if len(s) >= len(r.lo) && r.contains(s[:len(r.lo)]) {
+			return len(r.lo)
+		}
+	}
+	if len(cm.codespaces) > 0 {
+		return len(cm.codespaces[0].lo)
+	}
+	if composite {
+		return 2
+	}
+	return 1
+}
+
+// lookup returns the text of a code
+func (cm *pdfCMap) lookup(code []byte) (string, bool) {
+	if text, ok := cm.chars[string(code)]; ok {
+		return text, true
+	}
+	for _, r := range cm.ranges {
+		if !r.contains(code) {
+			continue
+		}
+		offset := 0
+		for i := range code {
+			offset = offset<<8 | int(code[i]) - int(r.lo[i])
+		}
+		if r.list != nil {
+			if offset < len(r.list) {
+				return r.list[offset], true
+			}
+			return "", false
+		}
+		if len(r.dst) == 0 {
+			return "", false
+		}
+		units := append([]uint16(nil), r.dst...)
+		units[len(units)-1] += uint16(offset)
+		return string(utf16.Decode(units)), true
+	}
+	return "", false
+}
+
+// utf16Units splits big-endian UTF-16 into code units
+func utf16Units(b []byte) []uint16 {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return units
+}
+
+// decodeUTF16 decodes big-endian UTF-16 text; odd-length strings are taken as Latin-1
+func decodeUTF16(b []byte) string {
+	if len(b)%2 == 1 {
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	return strings.ReplaceAll(string(utf16.Decode(utf16Units(b))), "\x00", "")
+}
+
+// Encodings of simple fonts. WinAnsi is used when a font names none, which is
+// what the standard fonts effectively use for Latin text.
+var (
+	winAnsiEncoding   [256]string
+	macRomanEncoding  [256]string
+	pdfGlyphs         = make(map[string]string) // Glyph names to text
+	winAnsiHighGlyphs = [32]string{"Euro", "", "quotesinglbase", "florin", "quotedblbase", "ellipsis", "dagger", "daggerdbl", "circumflex", "perthousand", "Scaron", "guilsinglleft", "OE", "", "Zcaron", "", "", "quoteleft", "quoteright", "quotedblleft", "quotedblright", "bullet", "endash", "emdash", "tilde", "trademark", "scaron", "guilsinglright", "oe", "", "zcaron", "Ydieresis"}
+)
+
+// Glyph names of 0x20-0x7E (letters are named by themselves) and 0xA0-0xFF,
+// where WinAnsi matches Unicode
+const (
+	asciiGlyphNames  = "space exclam quotedbl numbersign dollar percent ampersand quotesingle parenleft parenright asterisk plus comma hyphen period slash zero one two three four five six seven eight nine colon semicolon less equal greater question at"
+	asciiGlyphNames2 = "bracketleft backslash bracketright asciicircum underscore grave"
+	asciiGlyphNames3 = "braceleft bar braceright asciitilde"
+	latin1GlyphNames = "nbspace exclamdown cent sterling currency yen brokenbar section dieresis copyright ordfeminine guillemotleft logicalnot sfthyphen registered macron " +
+		"degree plusminus twosuperior threesuperior acute mu paragraph periodcentered cedilla onesuperior ordmasculine guillemotright onequarter onehalf threequarters questiondown " +
+		"Agrave Aacute Acircumflex Atilde Adieresis Aring AE Ccedilla Egrave Eacute Ecircumflex Edieresis Igrave Iacute Icircumflex Idieresis " +
+		"Eth Ntilde Ograve Oacute Ocircumflex Otilde Odieresis multiply Oslash Ugrave Uacute Ucircumflex Udieresis Yacute Thorn germandbls " +
+		"agrave aacute acircumflex atilde adieresis aring ae ccedilla egrave eacute ecircumflex edieresis igrave iacute icircumflex idieresis " +
+		"eth ntilde ograve oacute ocircumflex otilde odieresis divide oslash ugrave uacute ucircumflex udieresis yacute thorn ydieresis"
+	winAnsiHigh  = "€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ"
+	macRomanHigh = "ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ"
+)
+
+func init() {
+	for c := 0x20; c < 0x7f; c++ {
+		winAnsiEncoding[c] = string(rune(c))
+	}
+	for i, r := range []rune(winAnsiHigh) {
+		if r != 0 {
+			winAnsiEncoding[0x80+i] = string(r)
+		}
+	}
+	for c := 0xa0; c <= 0xff; c++ {
+		winAnsiEncoding[c] = string(rune(c))
+	}
+	winAnsiEncoding[0xa0] = " "
+	winAnsiEncoding[0xad] = "" // Soft hyphen
+
+	copy(macRomanEncoding[:0x80], winAnsiEncoding[:0x80])
+	for i, r := range []rune(macRomanHigh) {
+		macRomanEncoding[0x80+i] = string(r)
+	}
+
+	for i, name := range strings.Fields(asciiGlyphNames) {
+		pdfGlyphs[name] = string(rune(0x20 + i))
+	}
+	for i, name := range strings.Fields(asciiGlyphNames2) {
+		pdfGlyphs[name] = string(rune('[' + i))
+	}
+	for i, name := range strings.Fields(asciiGlyphNames3) {
+		pdfGlyphs[name] = string(rune('{' + i))
+	}
+	for i, name := range strings.Fields(latin1GlyphNames) {
+		pdfGlyphs[name] = winAnsiEncoding[0xa0+i]
+	}
+	for i, name := range winAnsiHighGlyphs {
+		if name != "" {
+			pdfGlyphs[name] = winAnsiEncoding[0x80+i]
+		}
+	}
+	for name, text := range map[string]string{
+		"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+		"minus": "−", "fraction": "⁄", "dotlessi": "ı", "Lslash": "Ł", "lslash": "ł",
+		"quoteright": "’", "quoteleft": "‘", "space": " ", "nbspace": " ",
+	} {
+		pdfGlyphs[name] = text
+	}
+}
+
+// pdfEncoding returns the code table of a named base encoding
+func pdfEncoding(name pdfName) [256]string {
+	if name == "MacRomanEncoding" {
+		return macRomanEncoding
+	}
+	return winAnsiEncoding
+}
+
+// glyphText maps a glyph name to text following the Adobe Glyph List conventions:
+// known names, "uniXXXX" and "uXXXX" names, ligatures joined by "_", and
+// variants such as "a.sc" (the suffix is ignored)
+func glyphText(name string) string {
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	if strings.Contains(name, "_") {
+		var b strings.Builder
+		for _, part := range strings.Split(name, "_") {
+			b.WriteString(glyphText(part))
+		}
+		return b.String()
+	}
+	if text, ok := pdfGlyphs[name]; ok {
+		return text
+	}
+	if len(name) == 1 {
+		return name // Letters are named by themselves
+	}
+	if strings.HasPrefix(name, "uni") && len(name) >= 7 && (len(name)-3)%4 == 0 {
+		var units []uint16
+		for i := 3; i < len(name); i += 4 {
+			v, err := strconv.ParseUint(name[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			units = append(units, uint16(v))
+		}
+		return string(utf16.Decode(units))
+	}
+	if name[0] == 'u' && len(name) >= 5 && len(name) <= 7 {
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,1007 @@
+// Package pdf reads the text of PDF files and holds the font metrics shared by
+// the PDF reader and the report's PDF writer
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PDF text extraction for fetched pages and provided documents. This is a small
+// reader for the parts of the format that carry text: objects (including
+// compressed object streams), the Flate/ASCIIHex/ASCII85 stream filters, the
+// standard security handler (crypt.go), the page tree, font encodings and
+// ToUnicode CMaps (font.go), and the text operators of content streams. Layout
+// is approximated from glyph positions: a move to another baseline starts a new
+// line, and a horizontal gap wider than a fraction of the font size is a space.
+
+// Limits for hostile files, which must fail with an error rather than exhaust
+// the stack or memory (recover can't catch either)
+const (
+	maxNesting     = 256       // Arrays and dictionaries nested in each other; real files use a few levels
+	maxStreamSize  = 32 << 20  // Bytes one stream may decode to
+	maxDecodedSize = 256 << 20 // Bytes all of a file's streams may decode to
+)
+
+var (
+	errNesting     = errors.New("PDF objects nested too deeply")
+	errStreamSize  = errors.New("PDF stream too large once decompressed")
+	errDecodedSize = fmt.Errorf("PDF streams decompress to more than %d MB", maxDecodedSize>>20)
+)
+
+// Detect reports whether a fetched document is a PDF, by content type or by the
+// %PDF- header (servers often send PDFs as application/octet-stream)
+func Detect(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "application/pdf" || mediaType == "application/x-pdf" {
+			return true
+		}
+	}
+	return bytes.HasPrefix(bytes.TrimLeft(body[:min(len(body), 1024)], " \t\r\n\x00"), []byte("%PDF-"))
+}
+
+// ExtractText returns the text of a PDF, page by page. Pages stop being read
+// once maxLength characters (0 = no limit) have been collected.
+func ExtractText(data []byte, maxLength int) (text string, err error) {
+	// Malformed files are common; a parser bug on one must not take the agent down
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", err
+	}
+
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return "", errors.New("PDF has no pages")
+	}
+
+	var out pdfText
+	for _, page := range pages {
+		doc.pageText(page, &out)
+		out.pageBreak()
+		if maxLength > 0 && out.b.Len() >= maxLength {
+			break
+		}
+	}
+
+	text = normalizePDFText(out.b.String())
+	if text == "" {
+		return "", errors.New("PDF has no extractable text (scanned images or unsupported fonts)")
+	}
+	return text, nil
+}
+
+// Text returns the text of a whole PDF, e.g. a document the user provides
+func Text(data []byte) (string, error) {
+	return ExtractText(data, 0)
+}
+
+// PDF object model. Numbers are float64, booleans bool, and null nil.
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string // Content stream operators and other bare words
+	pdfDelim   byte   // Closing ']' or '>' ('>>') returned while parsing containers
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+)
+
+// pdfRef is an indirect reference ("12 0 R")
+type pdfRef struct {
+	num, gen int
+}
+
+// pdfStream is a stream object with its still-encoded data
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+	ref  pdfRef // Object the stream belongs to (its decryption key depends on it)
+}
+
+// name returns the name stored under key ("" if it's missing or not a direct name)
+func (d pdfDict) name(key pdfName) string {
+	n, _ := d[key].(pdfName)
+	return string(n)
+}
+
+// pdfLexer reads PDF objects from data
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	refs  bool // Parse "n g R" as references (off for content streams)
+	depth int  // Containers being parsed (see maxNesting)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace skips whitespace and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// object parses the next object. Closing delimiters are returned as pdfDelim.
+func (l *pdfLexer) object() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return l.dict()
+		}
+		return l.hexString(), nil
+	case c == '>':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+		}
+		return pdfDelim('>'), nil
+	case c == '[':
+		l.pos++
+		return l.array()
+	case c == ']':
+		l.pos++
+		return pdfDelim(']'), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.number(), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++ // Stray delimiter such as ')' or '{'
+		return pdfKeyword(c), nil
+	}
+	switch word := string(l.data[start:l.pos]); word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return pdfKeyword(word), nil
+	}
+}
+
+// number parses a number, or a reference when refs are enabled
+func (l *pdfLexer) number() any {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && (l.data[l.pos] >= '0' && l.data[l.pos] <= '9' || l.data[l.pos] == '.') {
+		l.pos++
+	}
+	f, err := strconv.ParseFloat(string(l.data[start:l.pos]), 64)
+	if err != nil {
+		return 0.0
+	}
+
+	if l.refs && f >= 0 && f == math.Trunc(f) {
+		save := l.pos
+		if gen, ok := l.integer(); ok {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isPDFSpace(l.data[l.pos+1]) || isPDFDelim(l.data[l.pos+1])) {
+				l.pos++
+				return pdfRef{num: int(f), gen: gen}
+			}
+		}
+		l.pos = save
+	}
+	return f
+}
+
+// integer parses an unsigned integer, leaving the position unchanged if there is none
+func (l *pdfLexer) integer() (int, bool) {
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	if l.pos == start || (l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos])) {
+		l.pos = start
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(l.data[start:l.pos]))
+	return n, err == nil
+}
+
+// name parses a /Name, decoding #xx escapes
+func (l *pdfLexer) name() pdfName {
+	l.pos++ // '/'
+	var b []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || isPDFDelim(c) {
+			break
+		}
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return pdfName(b)
+}
+
+// literalString parses a (string) with balanced parentheses and escapes
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // '('
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return b
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					n := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				}
+				// \( \) \\ and unknown escapes stand for the character itself
+			}
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// hexString parses a <hex string>
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // '<'
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; (c >= '0' && c <= '9') || (c|0x20 >= 'a' && c|0x20 <= 'f') {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	hex.Decode(b, digits)
+	return b
+}
+
+// dict parses the entries of a << dictionary >>
+func (l *pdfLexer) dict() (pdfDict, error) {
+	if l.depth >= maxNesting {
+		return nil, errNesting
+	}
+	l.depth++
+	defer func() { l.depth-- }()
+	d := pdfDict{}
+	for {
+		key, err := l.object()
+		if err != nil {
+			return d, err
+		}
+		if key == pdfDelim('>') {
+			return d, nil
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			continue // Malformed entry
+		}
+		val, err := l.object()
+		if err != nil {
+			return d, err
+		}
+		if val == pdfDelim('>') {
+			return d, nil
+		}
+		d[name] = val
+	}
+}
+
+// array parses the elements of an [ array ]
+func (l *pdfLexer) array() (pdfArray, error) {
+	if l.depth >= maxNesting {
+		return nil, errNesting
+	}
+	l.depth++
+	defer func() { l.depth-- }()
+	var a pdfArray
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return a, err
+		}
+		if obj == pdfDelim(']') {
+			return a, nil
+		}
+		if _, ok := obj.(pdfDelim); ok {
+			continue
+		}
+		a = append(a, obj)
+	}
+}
+
+// stream reads the stream data following a dictionary, if there is any
+func (l *pdfLexer) stream(d pdfDict) *pdfStream {
+	save := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = save
+		return nil
+	}
+	l.pos += len("stream")
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	// Trust /Length only when "endstream" follows it; it may be an indirect
+	// reference or simply wrong, in which case the keyword is searched for
+	end := -1
+	if n, ok := d["Length"].(float64); ok && n >= 0 && start+int(n) <= len(l.data) {
+		rest := bytes.TrimLeft(l.data[start+int(n):], " \t\r\n\f\x00")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			end = start + int(n)
+		}
+	}
+	if end < 0 {
+		i := bytes.Index(l.data[start:], []byte("endstream"))
+		if i < 0 {
+			end = len(l.data)
+		} else {
+			end = start + i
+			if end > start && l.data[end-1] == '\n' {
+				end--
+			}
+			if end > start && l.data[end-1] == '\r' {
+				end--
+			}
+		}
+	}
+
+	l.pos = end
+	if i := bytes.Index(l.data[end:], []byte("endstream")); i >= 0 {
+		l.pos = end + i + len("endstream")
+	}
+	return &pdfStream{dict: d, raw: l.data[start:end]}
+}
+
+// pdfDocument is a parsed PDF file
+type pdfDocument struct {
+	objects map[int]any // By object number; later definitions (incremental updates) win
+	trailer pdfDict
+	crypt   *pdfCrypt // nil unless the file is encrypted
+	fonts   map[pdfRef]*pdfFont
+	decoded int // Bytes decoded from streams so far (see maxDecodedSize)
+}
+
+// pdfObjRe matches the header of an indirect object ("12 0 obj")
+var pdfObjRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// parsePDF reads every object in the file. Objects are found by scanning for
+// their headers instead of trusting the cross-reference table, which is often
+// broken in files found on the web.
+func parsePDF(data []byte) (*pdfDocument, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+
+	doc := &pdfDocument{objects: make(map[int]any), fonts: make(map[pdfRef]*pdfFont)}
+	type trailerAt struct {
+		offset int
+		dict   pdfDict
+	}
+	var trailers []trailerAt
+	var objStreams []*pdfStream
+
+	for pos := 0; pos < len(data); {
+		m := pdfObjRe.FindSubmatchIndex(data[pos:])
+		if m == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+m[2] : pos+m[3]]))
+		gen, _ := strconv.Atoi(string(data[pos+m[4] : pos+m[5]]))
+		offset := pos + m[0]
+		pos += m[1]
+
+		l := &pdfLexer{data: data, pos: pos, refs: true}
+		obj, err := l.object()
+		if err != nil {
+			continue
+		}
+		if d, ok := obj.(pdfDict); ok {
+			if s := l.stream(d); s != nil {
+				s.ref = pdfRef{num: num, gen: gen}
+				obj = s
+				pos = l.pos // Don't look for objects inside the stream data
+				switch d.name("Type") {
+				case "ObjStm":
+					objStreams = append(objStreams, s)
+				case "XRef":
+					trailers = append(trailers, trailerAt{offset, d}) // Cross-reference streams carry the trailer
+				}
+			}
+		}
+		doc.objects[num] = obj
+	}
+
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		i += j + len("trailer")
+		l := &pdfLexer{data: data, pos: i, refs: true}
+		if d, ok := mustObject(l).(pdfDict); ok && (d["Root"] != nil || d["Size"] != nil) {
+			trailers = append(trailers, trailerAt{i, d})
+		}
+	}
+
+	// Merge trailers, preferring later (incremental update) entries
+	sort.Slice(trailers, func(i, j int) bool { return trailers[i].offset > trailers[j].offset })
+	doc.trailer = pdfDict{}
+	for _, t := range trailers {
+		for k, v := range t.dict {
+			if _, ok := doc.trailer[k]; !ok {
+				doc.trailer[k] = v
+			}
+		}
+	}
+
+	if enc := doc.dict(doc.trailer["Encrypt"]); enc != nil {
+		var id []byte
+		if ids, ok := doc.resolve(doc.trailer["ID"]).(pdfArray); ok && len(ids) > 0 {
+			id, _ = ids[0].(pdfString)
+		}
+		crypt, err := newPDFCrypt(enc, id)
+		if err != nil {
+			return nil, err
+		}
+		doc.crypt = crypt
+	}
+
+	for _, s := range objStreams {
+		doc.loadObjectStream(s)
+	}
+	return doc, nil
+}
+
+// mustObject parses the next object, ignoring errors
+func mustObject(l *pdfLexer) any {
+	obj, _ := l.object()
+	return obj
+}
+
+// loadObjectStream adds the objects compressed into an object stream
+func (doc *pdfDocument) loadObjectStream(s *pdfStream) {
+	data, err := doc.decode(s)
+	if err != nil {
+		return
+	}
+	n := int(doc.number(s.dict["N"]))
+	first := int(doc.number(s.dict["First"]))
+
+	l := &pdfLexer{data: data, refs: true}
+	nums := make([]int, 0, n)
+	offsets := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		num, ok1 := l.integer()
+		off, ok2 := l.integer()
+		if !ok1 || !ok2 {
+			break
+		}
+		nums = append(nums, num)
+		offsets = append(offsets, off)
+	}
+
+	for i, num := range nums {
+		if _, ok := doc.objects[num]; ok {
+			continue
+		}
+		if pos := first + offsets[i]; pos >= 0 && pos < len(data) {
+			l.pos = pos
+			if obj, err := l.object(); err == nil {
+				doc.objects[num] = obj
+			}
+		}
+	}
+}
+
+// resolve follows indirect references
+func (doc *pdfDocument) resolve(v any) any {
+	for range 32 {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = doc.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves v to a dictionary (a stream's dictionary for streams)
+func (doc *pdfDocument) dict(v any) pdfDict {
+	switch v := doc.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// number resolves v to a number (0 if it isn't one)
+func (doc *pdfDocument) number(v any) float64 {
+	f, _ := doc.resolve(v).(float64)
+	return f
+}
+
+// decode decrypts a stream and applies its filters
+func (doc *pdfDocument) decode(s *pdfStream) ([]byte, error) {
+	data := s.raw
+	if doc.crypt != nil && s.dict.name("Type") != "XRef" {
+		var err error
+		if data, err = doc.crypt.decrypt(s.ref, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var filters pdfArray
+	switch f := doc.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = pdfArray{f}
+	case pdfArray:
+		filters = f
+	}
+	for _, f := range filters {
+		name, _ := doc.resolve(f).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			if doc.decoded >= maxDecodedSize {
+				return nil, errDecodedSize
+			}
+			data, err = inflate(data, min(maxStreamSize, maxDecodedSize-doc.decoded))
+		case "ASCIIHexDecode", "AHx":
+			data = (&pdfLexer{data: append(append([]byte{'<'}, data...), '>')}).hexString()
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		case "Crypt":
+			// Identity crypt filter; encryption was handled above
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	doc.decoded += len(data)
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping what could be read from truncated
+// streams. Data that decompresses to more than limit bytes is an error.
+func inflate(data []byte, limit int) ([]byte, error) {
+	var r io.Reader
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data)) // Some writers omit the zlib header
+	} else {
+		r = zr
+	}
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if len(out) > limit {
+		return nil, errStreamSize
+	}
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeASCII85 decodes ASCII base-85 data terminated by "~>"
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	data = bytes.Map(func(r rune) rune {
+		if isPDFSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, data)
+	out := make([]byte, 4*len(data)+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// pages returns the page dictionaries in reading order
+func (doc *pdfDocument) pages() []pdfDict {
+	var pages []pdfDict
+	if root := doc.dict(doc.trailer["Root"]); root != nil {
+		doc.walkPages(root["Pages"], make(map[int]bool), &pages, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// No usable page tree: take every page object in object order
+	nums := make([]int, 0, len(doc.objects))
+	for num, obj := range doc.objects {
+		if d, ok := obj.(pdfDict); ok && d.name("Type") == "Page" {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		pages = append(pages, doc.objects[num].(pdfDict))
+	}
+	return pages
+}
+
+// walkPages appends the leaves of a page tree node
+func (doc *pdfDocument) walkPages(node any, visited map[int]bool, pages *[]pdfDict, depth int) {
+	if ref, ok := node.(pdfRef); ok {
+		if visited[ref.num] {
+			return
+		}
+		visited[ref.num] = true
+	}
+	d := doc.dict(node)
+	if d == nil || depth > 64 {
+		return
+	}
+	kids, ok := doc.resolve(d["Kids"]).(pdfArray)
+	if !ok || d.name("Type") == "Page" {
+		*pages = append(*pages, d)
+		return
+	}
+	for _, kid := range kids {
+		doc.walkPages(kid, visited, pages, depth+1)
+	}
+}
+
+// inherited looks key up on the page and then its ancestors in the page tree
+func (doc *pdfDocument) inherited(page pdfDict, key pdfName) any {
+	for d, i := page, 0; d != nil && i < 64; d, i = doc.dict(d["Parent"]), i+1 {
+		if v, ok := d[key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// pageText appends the text of one page
+func (doc *pdfDocument) pageText(page pdfDict, out *pdfText) {
+	var contents []byte
+	var streams pdfArray
+	switch c := doc.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		streams = pdfArray{c}
+	case pdfArray:
+		streams = c
+	}
+	for _, s := range streams {
+		if s, ok := doc.resolve(s).(*pdfStream); ok {
+			if data, err := doc.decode(s); err == nil {
+				contents = append(append(contents, data...), '\n')
+			}
+		}
+	}
+	doc.runContent(contents, doc.dict(doc.inherited(page, "Resources")), out, 0)
+}
+
+// pdfTextState tracks the text position while a content stream is interpreted
+type pdfTextState struct {
+	font                 *pdfFont
+	size                 float64 // Font size (Tf)
+	charSpace, wordSpace float64 // Tc, Tw
+	scale                float64 // Horizontal scaling (Tz / 100)
+	leading              float64 // TL
+	sx, sy               float64 // Text matrix scale
+	x, y                 float64 // Current position
+	lineX, lineY         float64 // Start of the current line
+	endX, endY           float64 // Where the last shown text ended
+	shown                bool    // Whether text was shown yet
+}
+
+// runContent interprets a content stream, appending the text it shows.
+// Form XObjects are interpreted recursively.
+func (doc *pdfDocument) runContent(data []byte, resources pdfDict, out *pdfText, depth int) {
+	fonts := doc.dict(resources["Font"])
+	st := pdfTextState{scale: 1, sx: 1, sy: 1, size: 1}
+	l := &pdfLexer{data: data}
+	var ops []any
+
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			ops = append(ops, obj)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			st.x, st.y, st.lineX, st.lineY, st.sx, st.sy = 0, 0, 0, 0, 1, 1
+		case "Tf":
+			if len(ops) >= 2 {
+				if name, ok := ops[len(ops)-2].(pdfName); ok && fonts != nil {
+					st.font = doc.font(fonts[name])
+				}
+				st.size = pdfNum(ops[len(ops)-1])
+			}
+		case "Tc":
+			st.charSpace = lastNum(ops)
+		case "Tw":
+			st.wordSpace = lastNum(ops)
+		case "Tz":
+			st.scale = lastNum(ops) / 100
+		case "TL":
+			st.leading = lastNum(ops)
+		case "Td", "TD":
+			if len(ops) >= 2 {
+				tx, ty := pdfNum(ops[len(ops)-2]), pdfNum(ops[len(ops)-1])
+				st.lineX += tx * st.sx
+				st.lineY += ty * st.sy
+				st.x, st.y = st.lineX, st.lineY
+				if op == "TD" {
+					st.leading = -ty
+				}
+			}
+		case "Tm":
+			if len(ops) >= 6 {
+				n := len(ops) - 6
+				st.sx = math.Hypot(pdfNum(ops[n]), pdfNum(ops[n+1]))
+				st.sy = math.Hypot(pdfNum(ops[n+2]), pdfNum(ops[n+3]))
+				if st.sx == 0 {
+					st.sx = 1
+				}
+				if st.sy == 0 {
+					st.sy = 1
+				}
+				st.lineX, st.lineY = pdfNum(ops[n+4]), pdfNum(ops[n+5])
+				st.x, st.y = st.lineX, st.lineY
+			}
+		case "T*":
+			st.nextLine(out)
+		case "Tj":
+			if len(ops) >= 1 {
+				st.show(ops[len(ops)-1], out)
+			}
+		case "'":
+			st.nextLine(out)
+			if len(ops) >= 1 {
+				st.show(ops[len(ops)-1], out)
+			}
+		case "\"":
+			if len(ops) >= 3 {
+				st.wordSpace, st.charSpace = pdfNum(ops[len(ops)-3]), pdfNum(ops[len(ops)-2])
+				st.nextLine(out)
+				st.show(ops[len(ops)-1], out)
+			}
+		case "TJ":
+			if len(ops) >= 1 {
+				if arr, ok := ops[len(ops)-1].(pdfArray); ok {
+					for _, item := range arr {
+						if n, ok := item.(float64); ok {
+							st.x -= n / 1000 * st.size * st.scale * st.sx // Kerning; large values are word gaps
+						} else {
+							st.show(item, out)
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(ops) >= 1 && depth < 8 {
+				name, _ := ops[len(ops)-1].(pdfName)
+				xobj, ok := doc.resolve(doc.dict(resources["XObject"])[name]).(*pdfStream)
+				if ok && xobj.dict.name("Subtype") == "Form" {
+					if formData, err := doc.decode(xobj); err == nil {
+						formResources := doc.dict(xobj.dict["Resources"])
+						if formResources == nil {
+							formResources = resources
+						}
+						out.separate('\n')
+						doc.runContent(formData, formResources, out, depth+1)
+						out.separate('\n')
+					}
+				}
+			}
+		case "ID":
+			l.skipInlineImage()
+		}
+		ops = ops[:0]
+	}
+}
+
+// nextLine moves to the start of the next line (T*)
+func (st *pdfTextState) nextLine(out *pdfText) {
+	st.lineY -= st.leading * st.sy
+	st.x, st.y = st.lineX, st.lineY
+	if st.leading == 0 {
+		out.separate('\n')
+	}
+}
+
+// show appends a shown string, separated from the previous one by a line break
+// or space when the position moved to another line or past a gap
+func (st *pdfTextState) show(v any, out *pdfText) {
+	s, ok := v.(pdfString)
+	if !ok || st.font == nil {
+		return
+	}
+	text, width := st.font.decode(s, st.size, st.charSpace, st.wordSpace)
+	em := math.Abs(st.size)
+	if st.shown {
+		switch {
+		case math.Abs(st.y-st.endY) > 0.5*em*st.sy:
+			out.separate('\n')
+		case math.Abs(st.x-st.endX) > 0.2*em*st.sx:
+			out.separate(' ')
+		}
+	}
+	out.write(text)
+
+	st.x += width * st.scale * st.sx
+	st.endX, st.endY, st.shown = st.x, st.y, true
+}
+
+// skipInlineImage skips the binary data of an inline image (BI ... ID data EI)
+func (l *pdfLexer) skipInlineImage() {
+	for i := l.pos; i+2 <= len(l.data); i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && i > 0 && isPDFSpace(l.data[i-1]) &&
+			(i+2 == len(l.data) || isPDFSpace(l.data[i+2]) || isPDFDelim(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+func pdfNum(v any) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+// lastNum returns the last operand as a number
+func lastNum(ops []any) float64 {
+	if len(ops) == 0 {
+		return 0
+	}
+	return pdfNum(ops[len(ops)-1])
+}
+
+// pdfText collects extracted text, inserting at most one separator between chunks
+type pdfText struct {
+	b   strings.Builder
+	sep byte // Pending separator: ' ' or '\n' (0 = none)
+}
+
+// separate requests a separator before the next text; line breaks win over spaces
+func (t *pdfText) separate(c byte) {
+	if t.sep != '\n' {
+		t.sep = c
+	}
+}
+
+func (t *pdfText) write(s string) {
+	if s == "" {
+		return
+	}
+	if t.sep != 0 && t.b.Len() > 0 {
+		t.b.WriteByte(t.sep)
+	}
+	t.sep = 0
+	t.b.WriteString(s)
+}
+
+// pageBreak ends a page with a blank line
+func (t *pdfText) pageBreak() {
+	if t.b.Len() > 0 {
+		t.b.WriteString("\n\n")
+	}
+	t.sep = 0
+}
+
+var (
+	pdfSpacesRe     = regexp.MustCompile(`[ \t\x{00a0}]+`)
+	pdfBlankLinesRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// normalizePDFText collapses runs of spaces and blank lines
+func normalizePDFText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(pdfSpacesRe.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(pdfBlankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,383 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// helloContent is the content stream of the test documents' single page
+const helloContent = "BT /F1 12 Tf 72 720 Td (Hello World) Tj 0 -14 Td (Second line) Tj ET"
+
+// buildPDF assembles a PDF with objects numbered from 1 ("" for one stored
+// elsewhere, like an object stream) and trailer as the body of its trailer
+// dictionary. There is no cross-reference table, which the reader doesn't need.
+func buildPDF(objects []string, trailer string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		if obj == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	fmt.Fprintf(&b, "trailer\n<< %s >>\n%%%%EOF\n", trailer)
+	return b.Bytes()
+}
+
+// streamObject is the body of a stream object with data and the extra entries
+// of its dictionary
+func streamObject(dict string, data []byte) string {
+	return fmt.Sprintf("<< /Length %d %s >>\nstream\n%s\nendstream", len(data), dict, data)
+}
+
+// pageObjects are a catalog, page tree, page, and font, with the page's
+// content in object 5
+func pageObjects(content string) []string {
+	return []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		content,
+	}
+}
+
+func deflate(t testing.TB, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w, err := zlib.NewWriterLevel(&b, zlib.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func assertHello(t *testing.T, data []byte) {
+	t.Helper()
+	text, err := Text(data)
+	if err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+	if !strings.Contains(text, "Hello World") || !strings.Contains(text, "Second line") {
+		t.Errorf("Text = %q, want both lines of the page", text)
+	}
+}
+
+func TestTextPlain(t *testing.T) {
+	assertHello(t, buildPDF(pageObjects(streamObject("", []byte(helloContent))), "/Root 1 0 R"))
+}
+
+func TestTextFlate(t *testing.T) {
+	content := streamObject("/Filter /FlateDecode", deflate(t, []byte(helloContent)))
+	assertHello(t, buildPDF(pageObjects(content), "/Root 1 0 R"))
+}
+
+func TestTextObjectStream(t *testing.T) {
+	// Objects 1-4 compressed into object stream 6, as PDF 1.5 writers do
+	objects := pageObjects(streamObject("", []byte(helloContent)))
+	var offsets, bodies strings.Builder
+	for i, obj := range objects[:4] {
+		fmt.Fprintf(&offsets, "%d %d ", i+1, bodies.Len())
+		bodies.WriteString(obj + "\n")
+	}
+	data := offsets.String() + bodies.String()
+	objStm := streamObject(fmt.Sprintf("/Type /ObjStm /N 4 /First %d /Filter /FlateDecode", offsets.Len()), deflate(t, []byte(data)))
+	objects = []string{"", "", "", "", objects[4], objStm}
+	assertHello(t, buildPDF(objects, "/Root 1 0 R"))
+}
+
+func TestExtractTextStopsAtMaxLength(t *testing.T) {
+	objects := pageObjects(streamObject("", []byte(helloContent)))
+	objects[1] = "<< /Type /Pages /Kids [3 0 R 6 0 R] /Count 2 >>"
+	objects = append(objects,
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 7 0 R >>",
+		streamObject("", []byte("BT /F1 12 Tf 72 720 Td (Page two) Tj ET")))
+	data := buildPDF(objects, "/Root 1 0 R")
+
+	text, err := ExtractText(data, 5)
+	if err != nil {
+		t.Fatalf("ExtractText: %v", err)
+	}
+	if !strings.Contains(text, "Hello World") || strings.Contains(text, "Page two") {
+		t.Errorf("ExtractText(data, 5) = %q, want only the first page", text)
+	}
+	if text, _ := ExtractText(data, 0); !strings.Contains(text, "Page two") {
+		t.Errorf("ExtractText(data, 0) = %q, want both pages", text)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"application/pdf", "", true},
+		{"application/octet-stream", "%PDF-1.4\n", true},
+		{"text/html", "<html>", false},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.contentType, []byte(tt.body)); got != tt.want {
+			t.Errorf("Detect(%q, %q) = %v, want %v", tt.contentType, tt.body, got, tt.want)
+		}
+	}
+}
+
+// TestMalformed checks that broken files are errors, not panics or hangs
+func TestMalformed(t *testing.T) {
+	valid := buildPDF(pageObjects(streamObject("", []byte(helloContent))), "/Root 1 0 R")
+	tests := map[string][]byte{
+		"empty":         nil,
+		"not a PDF":     []byte("<html><body>Not found</body></html>"),
+		"header only":   []byte("%PDF-1.7\n"),
+		"garbage":       append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("\x00\xff(<[/"), 4096)...),
+		"truncated":     valid[:len(valid)/2],
+		"no pages":      buildPDF([]string{"<< /Type /Catalog >>"}, "/Root 1 0 R"),
+		"bad flate":     buildPDF(pageObjects(streamObject("/Filter /FlateDecode", []byte("not zlib"))), "/Root 1 0 R"),
+		"unknown crypt": buildPDF(append(pageObjects(streamObject("", []byte(helloContent))), "<< /Filter /Adobe.PubSec >>"), "/Root 1 0 R /Encrypt 6 0 R"),
+		"cyclic page tree": buildPDF([]string{
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [2 0 R 3 0 R] /Parent 3 0 R >>",
+			"<< /Type /Pages /Kids [2 0 R] /Parent 2 0 R >>",
+		}, "/Root 1 0 R"),
+		"unterminated string": buildPDF(pageObjects(streamObject("", []byte("BT /F1 12 Tf (Hello"))), "/Root 1 0 R"),
+		"self-referencing length": buildPDF(append(pageObjects("<< /Length 6 0 R >>\nstream\n"+helloContent+"\nendstream"), "6 0 R"),
+			"/Root 1 0 R"),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _ = Text(data) // Either result will do; the test is that it returns
+		})
+	}
+	if _, err := Text([]byte("<html></html>")); err == nil {
+		t.Error("Text of HTML succeeded")
+	}
+}
+
+// TestDeepNesting checks that arrays and dictionaries nested past maxNesting
+// are an error instead of exhausting the stack
+func TestDeepNesting(t *testing.T) {
+	deep := 20 << 20
+	tests := map[string][]byte{
+		"arrays":              append([]byte("%PDF-1.4\n1 0 obj\n"), bytes.Repeat([]byte("["), deep)...),
+		"dictionaries":        append([]byte("%PDF-1.4\n1 0 obj\n"), bytes.Repeat([]byte("<< /A "), deep/6)...),
+		"content arrays":      buildPDF(pageObjects(streamObject("", bytes.Repeat([]byte("["), deep))), "/Root 1 0 R"),
+		"content dictionary":  buildPDF(pageObjects(streamObject("", bytes.Repeat([]byte("<</A "), deep/5))), "/Root 1 0 R"),
+		"trailer dictionary":  append([]byte("%PDF-1.4\ntrailer\n"), bytes.Repeat([]byte("<< /Root "), deep/9)...),
+		"object stream array": buildPDF([]string{streamObject("/Type /ObjStm /N 1 /First 4", append([]byte("1 0 "), bytes.Repeat([]byte("["), deep)...))}, ""),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Text(data); err == nil {
+				t.Error("Text succeeded")
+			}
+		})
+	}
+
+	l := &pdfLexer{data: bytes.Repeat([]byte("["), maxNesting+1)}
+	if _, err := l.object(); !errors.Is(err, errNesting) {
+		t.Errorf("object() of %d nested arrays: err = %v, want %v", maxNesting+1, err, errNesting)
+	}
+	l = &pdfLexer{data: []byte(strings.Repeat("[", maxNesting) + strings.Repeat("]", maxNesting))}
+	if _, err := l.object(); err != nil {
+		t.Errorf("object() of %d nested arrays: %v", maxNesting, err)
+	}
+}
+
+// TestDecompressionBomb checks that a small stream inflating to more than
+// maxStreamSize is cut off instead of read into memory
+func TestDecompressionBomb(t *testing.T) {
+	bomb := deflate(t, make([]byte, maxStreamSize+1<<20))
+	if _, err := inflate(bomb, maxStreamSize); !errors.Is(err, errStreamSize) {
+		t.Errorf("inflate: err = %v, want %v", err, errStreamSize)
+	}
+
+	data := buildPDF(pageObjects(streamObject("/Filter /FlateDecode", bomb)), "/Root 1 0 R")
+	if _, err := Text(data); err == nil {
+		t.Error("Text of a page with a decompression bomb succeeded")
+	}
+
+	// Once the file's budget is spent, even small streams aren't decompressed
+	doc := &pdfDocument{decoded: maxDecodedSize}
+	s := &pdfStream{dict: pdfDict{"Filter": pdfName("FlateDecode")}, raw: deflate(t, []byte(helloContent))}
+	if _, err := doc.decode(s); !errors.Is(err, errDecodedSize) {
+		t.Errorf("decode past the budget: err = %v, want %v", err, errDecodedSize)
+	}
+}
+
+func TestEncrypted(t *testing.T) {
+	id := []byte("0123456789abcdef")
+	owner := bytes.Repeat([]byte{0x5a}, 32)
+	const perms = -4
+
+	tests := []struct {
+		name    string
+		v, r    int
+		length  int // Bytes of the RC4 key
+		aes     bool
+		encrypt string // Extra entries of the encryption dictionary
+	}{
+		{"RC4 40-bit", 1, 2, 5, false, ""},
+		{"RC4 128-bit", 2, 3, 16, false, "/Length 128"},
+		{"AES-128", 4, 4, 16, true, "/Length 128 /CF << /StdCF << /CFM /AESV2 /Length 16 >> >> /StmF /StdCF /StrF /StdCF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := fileKey(owner, perms, id, tt.r, tt.length)
+			u := userEntry(t, key, tt.r, id)
+			content := encryptStream(t, key, pdfRef{num: 5}, []byte(helloContent), tt.aes)
+			enc := fmt.Sprintf("<< /Filter /Standard /V %d /R %d /O <%x> /U <%x> /P %d %s >>", tt.v, tt.r, owner, u, perms, tt.encrypt)
+			objects := append(pageObjects(streamObject("", content)), enc)
+			trailer := fmt.Sprintf("/Root 1 0 R /Encrypt 6 0 R /ID [<%x> <%x>]", id, id)
+			assertHello(t, buildPDF(objects, trailer))
+
+			// With a user password, the file key from the empty one doesn't match /U
+			u[0] ^= 0xff
+			enc = fmt.Sprintf("<< /Filter /Standard /V %d /R %d /O <%x> /U <%x> /P %d %s >>", tt.v, tt.r, owner, u, perms, tt.encrypt)
+			objects[5] = enc
+			if _, err := Text(buildPDF(objects, trailer)); !errors.Is(err, errPDFPassword) {
+				t.Errorf("Text with a user password: err = %v, want %v", err, errPDFPassword)
+			}
+		})
+	}
+
+	for _, r := range []int{5, 6} {
+		t.Run(fmt.Sprintf("AES-256 R%d", r), func(t *testing.T) {
+			hash := func(salt []byte) []byte {
+				if r == 6 {
+					return pdfHash2B(salt)
+				}
+				sum := sha256.Sum256(salt)
+				return sum[:]
+			}
+			key := bytes.Repeat([]byte{0x42}, 32)
+			validation, keySalt := []byte("vsalt123"), []byte("ksalt456")
+			u := append(append(hash(validation), validation...), keySalt...)
+			block, err := aes.NewCipher(hash(keySalt))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ue := make([]byte, 32)
+			cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(ue, key)
+
+			content := encryptStream(t, key, pdfRef{}, []byte(helloContent), true)
+			enc := fmt.Sprintf("<< /Filter /Standard /V 5 /R %d /Length 256 /CF << /StdCF << /CFM /AESV3 /Length 32 >> >> /StmF /StdCF /StrF /StdCF /O <%x> /U <%x> /UE <%x> /P %d >>",
+				r, bytes.Repeat([]byte{0}, 48), u, ue, perms)
+			objects := append(pageObjects(streamObject("", content)), enc)
+			assertHello(t, buildPDF(objects, "/Root 1 0 R /Encrypt 6 0 R"))
+
+			u[0] ^= 0xff
+			objects[5] = fmt.Sprintf("<< /Filter /Standard /V 5 /R %d /CF << /StdCF << /CFM /AESV3 >> >> /StmF /StdCF /U <%x> /UE <%x> >>", r, u, ue)
+			if _, err := Text(buildPDF(objects, "/Root 1 0 R /Encrypt 6 0 R")); !errors.Is(err, errPDFPassword) {
+				t.Errorf("Text with a user password: err = %v, want %v", err, errPDFPassword)
+			}
+		})
+	}
+}
+
+// fileKey is the file key of algorithm 2 for the empty user password
+func fileKey(owner []byte, perms int32, id []byte, r, n int) []byte {
+	p := uint32(perms)
+	h := md5.New()
+	h.Write(pdfPasswordPad)
+	h.Write(owner)
+	h.Write([]byte{byte(p), byte(p >> 8), byte(p >> 16), byte(p >> 24)})
+	h.Write(id)
+	key := h.Sum(nil)
+	if r >= 3 {
+		for range 50 {
+			sum := md5.Sum(key[:n])
+			key = sum[:]
+		}
+	}
+	return key[:n]
+}
+
+// userEntry is the /U entry for the file key (algorithms 4 and 5)
+func userEntry(t *testing.T, key []byte, r int, id []byte) []byte {
+	t.Helper()
+	if r == 2 {
+		c, err := rc4.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := make([]byte, 32)
+		c.XORKeyStream(u, pdfPasswordPad)
+		return u
+	}
+	sum := md5.Sum(append(append([]byte(nil), pdfPasswordPad...), id...))
+	u := sum[:]
+	for i := range 20 {
+		k := make([]byte, len(key))
+		for j := range key {
+			k[j] = key[j] ^ byte(i)
+		}
+		c, err := rc4.NewCipher(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.XORKeyStream(u, u)
+	}
+	return append(u, make([]byte, 16)...)
+}
+
+// encryptStream encrypts the data of stream ref: with the object's own key
+// for RC4 and AES-128, or with key itself for AES-256 (a 32-byte key)
+func encryptStream(t *testing.T, key []byte, ref pdfRef, data []byte, useAES bool) []byte {
+	t.Helper()
+	if len(key) < 32 {
+		b := append([]byte(nil), key...)
+		b = append(b, byte(ref.num), byte(ref.num>>8), byte(ref.num>>16), byte(ref.gen), byte(ref.gen>>8))
+		if useAES {
+			b = append(b, "sAlT"...)
+		}
+		sum := md5.Sum(b)
+		key = sum[:min(len(key)+5, 16)]
+	}
+	if !useAES {
+		c, err := rc4.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, len(data))
+		c.XORKeyStream(out, data)
+		return out
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	plain := append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	iv := []byte("0123456789abcdef")
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+	return append(iv, out...)
+}
+
+// FuzzText checks that no input makes the reader panic or hang; go test runs
+// the seeds, go test -fuzz=FuzzText explores from them
+func FuzzText(f *testing.F) {
+	f.Add(buildPDF(pageObjects(streamObject("", []byte(helloContent))), "/Root 1 0 R"))
+	f.Add(buildPDF(pageObjects(streamObject("/Filter /FlateDecode", deflate(f, []byte(helloContent)))), "/Root 1 0 R"))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n[[[[<< /A [ (x\\"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ExtractText(data, 1000)
+	})
+}
@@ -0,0 +1,159 @@
+// Package price finds prices in listing text ("€ 1.250", "450 lei", "$1,299.99",
+// "150k EUR") and converts them between currencies with a rate table: a
+// built-in one, the ECB's daily reference rates, or a JSON file.
+package price
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Amount is a price in a currency (ISO 4217 code, e.g. EUR)
+type Amount struct {
+	Value    float64
+	Currency string
+}
+
+// String formats the amount like "1250 EUR" or "1299.99 USD"
+func (a Amount) String() string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", a.Value), "0"), ".") + " " + a.Currency
+}
+
+// currencyNames are the symbols and names a price's currency is written as,
+// lowercased, by ISO code
+var currencyNames = map[string][]string{
+	"EUR": {"€", "eur", "euro", "euros"},
+	"USD": {"$", "us$", "usd"},
+	"GBP": {"£", "gbp"},
+	"RON": {"ron", "lei", "leu"},
+	"CHF": {"chf"},
+	"PLN": {"zł", "zl", "pln"},
+	"HUF": {"huf"},
+	"CZK": {"kč", "czk"},
+	"BGN": {"лв", "bgn"},
+	"MDL": {"mdl"},
+	"SEK": {"sek"},
+	"NOK": {"nok"},
+	"DKK": {"dkk"},
+	"CAD": {"cad", "c$"},
+	"AUD": {"aud", "a$"},
+	"JPY": {"¥", "jpy"},
+}
+
+// currencyByName maps each symbol and name in currencyNames to its code
+var currencyByName = func() map[string]string {
+	m := make(map[string]string)
+	for code, names := range currencyNames {
+		for _, name := range names {
+			m[name] = code
+		}
+	}
+	return m
+}()
+
+// priceRe matches a currency before or after a number, with thousands
+// separators (".", ",", spaces) and an optional "k" for thousands
+var priceRe = func() *regexp.Regexp {
+	names := slices.Collect(maps.Keys(currencyByName))
+	// Longest first, so "us$" wins over "$"
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	for i, name := range names {
+		names[i] = regexp.QuoteMeta(name)
+	}
+	currency := `(` + strings.Join(names, "|") + `)`
+	number := `(\d{1,3}(?:[.,\s\x{00a0}\x{202f}']\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?)(\s?k\b)?`
+	return regexp.MustCompile(`(?i)(?:` + currency + `\s?` + number + `|` + number + `\s?` + currency + `)`)
+}()
+
+// Parse finds the first price in text: a number with a currency symbol, code,
+// or name right before or after it. A lone number is not a price.
+func Parse(text string) (Amount, bool) {
+	for _, m := range priceRe.FindAllStringSubmatchIndex(text, -1) {
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return text[m[2*i]:m[2*i+1]]
+		}
+		name, digits, thousands := group(1), group(2), group(3)
+		if name == "" {
+			digits, thousands, name = group(4), group(5), group(6)
+		}
+		if !isWord(text, m[0], m[1], name) {
+			continue
+		}
+		value, ok := parseNumber(digits)
+		if !ok || value <= 0 {
+			continue
+		}
+		if thousands != "" {
+			value *= 1000
+		}
+		return Amount{Value: value, Currency: currencyByName[strings.ToLower(name)]}, true
+	}
+	return Amount{}, false
+}
+
+// isWord reports whether the match at text[start:end] stands on its own: a
+// currency written in letters ("lei", "eur") isn't part of a longer word
+func isWord(text string, start, end int, name string) bool {
+	if !isLetters(name) {
+		return true
+	}
+	letter := func(i int) bool {
+		return i >= 0 && i < len(text) && isLetters(text[i:i+1])
+	}
+	return !letter(start-1) && !letter(end)
+}
+
+// isLetters reports whether s is made of ASCII letters only
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// parseNumber reads a number written with thousands separators in either
+// convention: "1.250.000", "1,250,000.50", "1 250", "1.299,99", "12,5"
+func parseNumber(s string) (float64, bool) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "'", "").Replace(s)
+	lastDot, lastComma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	decimal := byte(0)
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// Both: the last one separates the decimals
+		decimal = s[max(lastDot, lastComma)]
+	case lastDot >= 0 || lastComma >= 0:
+		// One kind: it separates the decimals unless every group after it has three digits
+		sep := s[max(lastDot, lastComma)]
+		parts := strings.Split(s, string(sep))
+		grouped := true
+		for _, p := range parts[1:] {
+			grouped = grouped && len(p) == 3
+		}
+		if !grouped {
+			decimal = sep
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			b.WriteByte(s[i])
+		case s[i] == decimal:
+			b.WriteByte('.')
+		}
+	}
+	v, err := strconv.ParseFloat(b.String(), 64)
+	return v, err == nil
+}
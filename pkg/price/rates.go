@@ -0,0 +1,129 @@
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// RatesECB is the rates source that fetches the ECB's daily reference rates
+const RatesECB = "ecb"
+
+// ecbURL serves the ECB's euro foreign exchange reference rates of the last working day
+const ecbURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// Rates are exchange rates: how many units of each currency (by ISO code) one
+// unit of a common base buys. Only the ratios matter, so any base works as
+// long as every rate uses it.
+type Rates map[string]float64
+
+// DefaultRates is the built-in rate table, in units per euro. The rates are
+// approximate and change daily; use RatesECB or a file where it matters.
+func DefaultRates() Rates {
+	return Rates{
+		"EUR": 1,
+		"USD": 1.10,
+		"GBP": 0.85,
+		"RON": 4.97,
+		"CHF": 0.94,
+		"PLN": 4.27,
+		"HUF": 395,
+		"CZK": 25.0,
+		"BGN": 1.9558,
+		"MDL": 19.4,
+		"SEK": 11.2,
+		"NOK": 11.7,
+		"DKK": 7.46,
+		"CAD": 1.52,
+		"AUD": 1.68,
+		"JPY": 160,
+	}
+}
+
+// Convert returns amount in currency to; false when either currency has no rate
+func (r Rates) Convert(amount Amount, to string) (Amount, bool) {
+	to = strings.ToUpper(to)
+	from, ok := r[amount.Currency]
+	target, ok2 := r[to]
+	if !ok || !ok2 || from <= 0 {
+		return Amount{}, false
+	}
+	return Amount{Value: math.Round(amount.Value/from*target*100) / 100, Currency: to}, true
+}
+
+// Has reports whether the table has a rate for currency
+func (r Rates) Has(currency string) bool {
+	return r[strings.ToUpper(currency)] > 0
+}
+
+// LoadRates returns the rate table of source: "" for DefaultRates, RatesECB to
+// fetch the ECB's, or the path of a JSON file like {"EUR": 1, "RON": 4.97}
+func LoadRates(ctx context.Context, source string) (Rates, error) {
+	switch source {
+	case "":
+		return DefaultRates(), nil
+	case RatesECB:
+		return FetchECBRates(ctx)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+	var rates Rates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("invalid rates file %s: %w", source, err)
+	}
+	normalized := make(Rates, len(rates))
+	for code, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rates file %s: the rate of %s must be positive", source, code)
+		}
+		normalized[strings.ToUpper(code)] = rate
+	}
+	return normalized, nil
+}
+
+// FetchECBRates fetches the ECB's euro reference rates of the last working day
+func FetchECBRates(ctx context.Context) (Rates, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", ecbURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ECB rates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch ECB rates (status %d)", resp.StatusCode)
+	}
+
+	var doc struct {
+		Cubes []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube>Cube>Cube"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse ECB rates: %w", err)
+	}
+	rates := Rates{"EUR": 1}
+	for _, c := range doc.Cubes {
+		if c.Currency != "" && c.Rate > 0 {
+			rates[c.Currency] = c.Rate
+		}
+	}
+	if len(rates) == 1 {
+		return nil, fmt.Errorf("no rates in the ECB's response")
+	}
+	return rates, nil
+}
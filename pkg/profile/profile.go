@@ -0,0 +1,145 @@
+// Package profile loads research profiles: named bundles of research settings,
+// planning instructions, an extraction schema, and a report structure for one
+// kind of research (market research, literature reviews, listing hunts, ...).
+package profile
+
+import (
+	"bytes"
+	"deep-research/pkg/search"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed builtin/*.yaml
+var builtinFS embed.FS
+
+// DefaultDir is where user profiles are read from unless configured otherwise
+const DefaultDir = "profiles"
+
+// ErrNotFound is returned by Find for an unknown profile name
+var ErrNotFound = errors.New("profile not found")
+
+// Profile is one YAML file. Zero values leave the setting to the user (or its default);
+// set values replace defaults but never what the user chose explicitly.
+type Profile struct {
+	Name             string   `yaml:"name" json:"name"` // Defaults to the file name without extension
+	Description      string   `yaml:"description" json:"description"`
+	Loops            int      `yaml:"loops" json:"loops,omitempty"`
+	Parallel         int      `yaml:"parallel" json:"parallel,omitempty"`
+	MinResults       int      `yaml:"min_results" json:"minResults,omitempty"`
+	MaxPages         int      `yaml:"max_pages" json:"maxPages,omitempty"`
+	ListingsPerQuery int      `yaml:"listings_per_query" json:"listingsPerQuery,omitempty"` // Deep mode: listing pages read per query (exhaustive: item links per listing index page)
+	DeepMode         bool     `yaml:"deep_mode" json:"deepMode,omitempty"`
+	ResultLinks      bool     `yaml:"result_links" json:"resultLinks,omitempty"`
+	SimpleMode       bool     `yaml:"simple_mode" json:"simpleMode,omitempty"`
+	ExtractionSchema string   `yaml:"extraction_schema" json:"extractionSchema,omitempty"` // Deep mode: fields to extract per page
+	IncludeDomains   []string `yaml:"include_domains" json:"includeDomains,omitempty"`
+	ExcludeDomains   []string `yaml:"exclude_domains" json:"excludeDomains,omitempty"`
+	Categories       []string `yaml:"categories" json:"categories,omitempty"` // SearXNG categories
+	TimeRange        string   `yaml:"time_range" json:"timeRange,omitempty"`  // SearXNG: day, week, month, or year
+	Planning         string   `yaml:"planning" json:"planning,omitempty"`     // Extra instructions for the planner
+	Report           string   `yaml:"report" json:"report,omitempty"`         // How the report should be structured
+	Template         string   `yaml:"template" json:"template,omitempty"`     // text/template the finished report is rendered with (see agent.ReportData)
+	Builtin          bool     `yaml:"-" json:"builtin"`                       // Shipped with the binary (not overridden by a file)
+}
+
+// Load returns the built-in profiles merged with extra ones (e.g. from the config
+// file) and the *.yaml/*.yml files in dir, sorted by name. A profile named like
+// an earlier one replaces it. A missing dir just means no user profiles.
+func Load(dir string, extra ...Profile) ([]Profile, error) {
+	byName := make(map[string]Profile)
+
+	builtin, err := fs.Glob(builtinFS, "builtin/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range builtin {
+		data, err := builtinFS.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p, err := parse(path, data)
+		if err != nil {
+			return nil, err
+		}
+		p.Builtin = true
+		byName[p.Name] = p
+	}
+	for _, p := range extra {
+		byName[p.Name] = p
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read profiles: %w", err)
+		}
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read profile: %w", err)
+			}
+			p, err := parse(path, data)
+			if err != nil {
+				return nil, err
+			}
+			byName[p.Name] = p
+		}
+	}
+
+	profiles := make([]Profile, 0, len(byName))
+	for _, p := range byName {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// Find loads the profiles and returns the one with the given name
+func Find(dir, name string, extra ...Profile) (Profile, error) {
+	profiles, err := Load(dir, extra...)
+	if err != nil {
+		return Profile{}, err
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return Profile{}, fmt.Errorf("%w: %q (available: %s)", ErrNotFound, name, strings.Join(names, ", "))
+}
+
+// parse decodes one profile file, rejecting unknown keys so typos don't go unnoticed
+func parse(path string, data []byte) (Profile, error) {
+	var p Profile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := search.ValidateTimeRange(p.TimeRange); err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
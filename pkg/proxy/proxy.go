@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Direct is the setting that sends requests straight to their server, ignoring HTTPS_PROXY
+const Direct = "direct"
+
+// Settings are the proxies of each backend. A setting is "" (use Global, else
+// HTTPS_PROXY, HTTP_PROXY, and NO_PROXY from the environment), Direct, or one or
+// more comma-separated proxy URLs (http://, https://, socks5://, socks5h://)
+// that requests take in turn. NO_PROXY hosts and loopback addresses are always
+// reached directly.
+type Settings struct {
+	Global string // Every backend without a setting of its own
+	Search string // Search engine APIs: SearXNG, Brave, Google, DuckDuckGo
+	Fetch  string // Page fetches, robots.txt, Wayback Machine lookups, and --render-js
+	LLM    string // LLM servers, including the summarizer and writer servers
+}
+
+// Resolve returns the settings with Global filled in for the backends that have none
+func (s Settings) Resolve() Settings {
+	for _, setting := range []*string{&s.Search, &s.Fetch, &s.LLM} {
+		if *setting == "" {
+			*setting = s.Global
+		}
+	}
+	return s
+}
+
+// Validate checks that every setting is Direct or a list of proxy URLs
+func (s Settings) Validate() error {
+	for _, setting := range []struct{ name, value string }{
+		{"proxy", s.Global}, {"search proxy", s.Search}, {"fetch proxy", s.Fetch}, {"LLM proxy", s.LLM},
+	} {
+		if _, err := Parse(setting.value); err != nil {
+			return fmt.Errorf("invalid %s: %w", setting.name, err)
+		}
+	}
+	return nil
+}
+
+// Explicit reports whether any backend has a proxy setting (rather than the environment's)
+func (s Settings) Explicit() bool {
+	return s.Global != "" || s.Search != "" || s.Fetch != "" || s.LLM != ""
+}
+
+// Parse returns the proxy URLs of a setting: none for "" and Direct
+func Parse(setting string) ([]*url.URL, error) {
+	setting = strings.TrimSpace(setting)
+	if setting == "" || strings.EqualFold(setting, Direct) {
+		return nil, nil
+	}
+	var proxies []*url.URL
+	for _, raw := range strings.Split(setting, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("%q: scheme must be http, https, socks5, or socks5h", raw)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("%q: missing host", raw)
+		}
+		proxies = append(proxies, u)
+	}
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("%q: no proxy URLs", setting)
+	}
+	return proxies, nil
+}
+
+// Func returns the http.Transport Proxy function of a setting: nil for "" (the
+// environment decides), or a function that rotates through the setting's
+// proxies, one request each
+func Func(setting string) (func(*http.Request) (*url.URL, error), error) {
+	proxies, err := Parse(setting)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(setting) == "" {
+		return nil, nil
+	}
+	if len(proxies) == 0 {
+		return func(*http.Request) (*url.URL, error) { return nil, nil }, nil
+	}
+
+	// httpproxy applies NO_PROXY and the loopback exemption; any proxy URL does for that
+	bypass := (&httpproxy.Config{
+		HTTPProxy:  proxies[0].String(),
+		HTTPSProxy: proxies[0].String(),
+		NoProxy:    noProxy(),
+	}).ProxyFunc()
+	var next atomic.Uint64
+	return func(req *http.Request) (*url.URL, error) {
+		if u, err := bypass(req.URL); err != nil || u == nil {
+			return nil, err
+		}
+		return proxies[(next.Add(1)-1)%uint64(len(proxies))], nil
+	}, nil
+}
+
+// Transport returns an HTTP transport sending requests through a setting's
+// proxies; nil for "", so clients keep http.DefaultTransport (which honors
+// HTTPS_PROXY)
+func Transport(setting string) (http.RoundTripper, error) {
+	proxyFunc, err := Func(setting)
+	if err != nil || proxyFunc == nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+	return transport, nil
+}
+
+// Describe is a setting for startup output, with passwords redacted
+func Describe(setting string) string {
+	proxies, err := Parse(setting)
+	switch {
+	case err != nil:
+		return "invalid"
+	case strings.TrimSpace(setting) == "":
+		return "environment"
+	case len(proxies) == 0:
+		return Direct
+	}
+	names := make([]string, len(proxies))
+	for i, u := range proxies {
+		names[i] = u.Redacted()
+	}
+	if len(names) > 1 {
+		return fmt.Sprintf("%s (rotating)", strings.Join(names, ", "))
+	}
+	return names[0]
+}
+
+// noProxy returns NO_PROXY (or no_proxy) from the environment
+func noProxy() string {
+	if v := os.Getenv("NO_PROXY"); v != "" {
+		return v
+	}
+	return os.Getenv("no_proxy")
+}
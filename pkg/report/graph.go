@@ -0,0 +1,129 @@
+package report
+
+import (
+	"bytes"
+	"deep-research/pkg/agent"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// entityStyles are the DOT node shapes and fill colors per entity type
+var entityStyles = map[string][2]string{
+	"person":       {"ellipse", "#fde68a"},
+	"company":      {"box", "#bfdbfe"},
+	"organization": {"box", "#c7d2fe"},
+	"product":      {"component", "#bbf7d0"},
+	"location":     {"house", "#fecaca"},
+}
+
+// DOT renders the knowledge graph (agent.Config.ExtractEntities) as a GraphViz
+// digraph: one node per entity, shaped and colored by type, and one labeled
+// edge per relation. Tooltips list the sources, numbered as in the bibliography.
+func DOT(title string, result agent.ResearchResult) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(title))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=filled, fontname=\"Helvetica\", fontsize=11];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=9, color=\"#6b7280\"];\n")
+
+	ids := make(map[string]string, len(result.Entities))
+	for i, e := range result.Entities {
+		id := fmt.Sprintf("n%d", i+1)
+		ids[e.Name] = id
+		style, ok := entityStyles[e.Type]
+		if !ok {
+			style = [2]string{"ellipse", "#e5e7eb"}
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s, fillcolor=%s, tooltip=%s];\n",
+			id, dotQuote(e.Name), style[0], dotQuote(style[1]), dotQuote(e.Type+sourceList(e.Sources)))
+	}
+	for _, r := range result.Relations {
+		from, to := ids[r.From], ids[r.To]
+		if from == "" || to == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, tooltip=%s];\n", from, to, dotQuote(r.Type), dotQuote(r.Type+sourceList(r.Sources)))
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+// GraphML renders the knowledge graph as GraphML for Gephi, yEd, or Cytoscape.
+// Nodes carry the entity's name, type, and source numbers; edges the relation's
+// type and source numbers.
+func GraphML(title string, result agent.ResearchResult) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">` + "\n")
+	b.WriteString(`  <key id="name" for="node" attr.name="name" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="type" for="node" attr.name="type" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="sources" for="node" attr.name="sources" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="relation" for="edge" attr.name="relation" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="edge_sources" for="edge" attr.name="sources" attr.type="string"/>` + "\n")
+	fmt.Fprintf(&b, "  <graph id=%s edgedefault=\"directed\">\n", xmlAttr(title))
+
+	ids := make(map[string]string, len(result.Entities))
+	for i, e := range result.Entities {
+		id := fmt.Sprintf("n%d", i+1)
+		ids[e.Name] = id
+		fmt.Fprintf(&b, "    <node id=%q>\n", id)
+		fmt.Fprintf(&b, "      <data key=\"name\">%s</data>\n", xmlText(e.Name))
+		fmt.Fprintf(&b, "      <data key=\"type\">%s</data>\n", xmlText(e.Type))
+		fmt.Fprintf(&b, "      <data key=\"sources\">%s</data>\n", joinNumbers(e.Sources))
+		b.WriteString("    </node>\n")
+	}
+	for i, r := range result.Relations {
+		from, to := ids[r.From], ids[r.To]
+		if from == "" || to == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "    <edge id=\"e%d\" source=%q target=%q>\n", i+1, from, to)
+		fmt.Fprintf(&b, "      <data key=\"relation\">%s</data>\n", xmlText(r.Type))
+		fmt.Fprintf(&b, "      <data key=\"edge_sources\">%s</data>\n", joinNumbers(r.Sources))
+		b.WriteString("    </edge>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+	return b.Bytes(), nil
+}
+
+// dotQuote quotes s as a DOT string
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// xmlText escapes s for XML character data
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xmlAttr quotes s as an XML attribute value
+func xmlAttr(s string) string {
+	return `"` + xmlText(s) + `"`
+}
+
+// sourceList is ", sources [1] [3]" for a tooltip, or "" without sources
+func sourceList(sources []int) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	refs := make([]string, len(sources))
+	for i, n := range sources {
+		refs[i] = fmt.Sprintf("[%d]", n)
+	}
+	return ", sources " + strings.Join(refs, " ")
+}
+
+// joinNumbers joins source numbers with commas
+func joinNumbers(numbers []int) string {
+	s := make([]string, len(numbers))
+	for i, n := range numbers {
+		s[i] = fmt.Sprint(n)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"bytes"
+	"deep-research/pkg/agent"
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+)
+
+//go:embed templates/report.html.tmpl
+var htmlTemplateSource string
+
+//go:embed templates/report.css
+var reportCSS string
+
+var htmlTemplate = template.Must(template.New("report").Parse(htmlTemplateSource))
+
+// HTML renders the result as a standalone HTML page with embedded styles.
+// Links and numeric citations ([3]) are clickable; citations jump to the bibliography.
+func HTML(title string, result agent.ResearchResult) ([]byte, error) {
+	doc := newDocument(title, result)
+
+	data := struct {
+		Title        string
+		Generated    string
+		CSS          template.CSS
+		Body         template.HTML
+		Sources      []entry
+		Bibliography bool
+	}{
+		Title:        doc.Title,
+		Generated:    doc.GeneratedAt.Format("January 2, 2006 15:04"),
+		CSS:          template.CSS(reportCSS),
+		Body:         template.HTML(renderHTMLBlocks(doc.Blocks, len(result.Sources))),
+		Sources:      doc.Sources,
+		Bibliography: doc.Bibliography,
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderHTMLBlocks renders the report body; all text is escaped here since the
+// result is injected into the template as trusted HTML
+func renderHTMLBlocks(blocks []block, numSources int) string {
+	var sb strings.Builder
+	for _, b := range blocks {
+		switch b.kind {
+		case blockHeading:
+			fmt.Fprintf(&sb, "<h%d>%s</h%d>\n", b.level, renderHTMLInline(b.text, numSources), b.level)
+		case blockParagraph:
+			fmt.Fprintf(&sb, "<p>%s</p>\n", renderHTMLInline(b.text, numSources))
+		case blockQuote:
+			fmt.Fprintf(&sb, "<blockquote><p>%s</p></blockquote>\n", renderHTMLInline(b.text, numSources))
+		case blockCode:
+			fmt.Fprintf(&sb, "<pre><code>%s</code></pre>\n", html.EscapeString(b.text))
+		case blockRule:
+			sb.WriteString("<hr>\n")
+		case blockList:
+			renderHTMLList(&sb, b.items, numSources)
+		case blockTable:
+			renderHTMLTable(&sb, b.rows, numSources)
+		}
+	}
+	return sb.String()
+}
+
+// renderHTMLList renders list items, opening and closing nested lists as the depth changes
+func renderHTMLList(sb *strings.Builder, items []listItem, numSources int) {
+	var open []string // tag of each open list, outermost first
+	closeTo := func(depth int) {
+		for len(open) > depth {
+			fmt.Fprintf(sb, "</li></%s>\n", open[len(open)-1])
+			open = open[:len(open)-1]
+		}
+	}
+
+	for i, item := range items {
+		depth := min(item.depth, len(open)) // can't skip levels
+		if i > 0 && depth < len(open) {
+			// Same level or shallower: close the previous item (and any deeper lists)
+			closeTo(depth + 1)
+			sb.WriteString("</li>\n")
+		}
+		if depth == len(open) {
+			// New list, nested inside the previous item when i > 0
+			tag := "ul"
+			if item.ordered {
+				tag = "ol"
+			}
+			fmt.Fprintf(sb, "<%s>\n", tag)
+			open = append(open, tag)
+		}
+		if item.ordered {
+			fmt.Fprintf(sb, `<li value="%d">%s`, item.number, renderHTMLInline(item.text, numSources))
+		} else {
+			fmt.Fprintf(sb, "<li>%s", renderHTMLInline(item.text, numSources))
+		}
+	}
+	closeTo(0)
+}
+
+func renderHTMLTable(sb *strings.Builder, rows [][]string, numSources int) {
+	sb.WriteString("<div class=\"table-wrap\"><table>\n<thead><tr>")
+	for _, cell := range rows[0] {
+		fmt.Fprintf(sb, "<th>%s</th>", renderHTMLInline(cell, numSources))
+	}
+	sb.WriteString("</tr></thead>\n<tbody>\n")
+	for _, row := range rows[1:] {
+		sb.WriteString("<tr>")
+		for _, cell := range row {
+			fmt.Fprintf(sb, "<td>%s</td>", renderHTMLInline(cell, numSources))
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</tbody>\n</table></div>\n")
+}
+
+func renderHTMLInline(text string, numSources int) string {
+	var sb strings.Builder
+	for _, s := range parseInline(text, numSources) {
+		out := html.EscapeString(s.text)
+		if s.code {
+			out = "<code>" + out + "</code>"
+		}
+		if s.italic {
+			out = "<em>" + out + "</em>"
+		}
+		if s.bold {
+			out = "<strong>" + out + "</strong>"
+		}
+		if href, ok := safeHref(s.href); ok {
+			class := ""
+			if strings.HasPrefix(href, "#source-") {
+				class = ` class="citation"`
+			}
+			out = fmt.Sprintf(`<a href="%s"%s>%s</a>`, html.EscapeString(href), class, out)
+		}
+		sb.WriteString(out)
+	}
+	return sb.String()
+}
@@ -0,0 +1,314 @@
+package report
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The LLM writes reports in plain Markdown: headings, paragraphs, lists, quotes,
+// fenced code, rules, and pipe tables, with **bold**, *italic*, `code`,
+// [text](url) links, bare URLs, and numeric citations like [3]. This is a small
+// parser for exactly that subset, shared by the HTML and PDF renderers.
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockList
+	blockQuote
+	blockCode
+	blockRule
+	blockTable
+)
+
+type block struct {
+	kind  blockKind
+	level int        // heading level (1-6)
+	text  string     // paragraph, heading, quote, and code text
+	items []listItem // list items
+	rows  [][]string // table rows (first row is the header)
+}
+
+type listItem struct {
+	depth   int    // nesting level (0 = top)
+	ordered bool   // numbered item
+	number  int    // item number for ordered items
+	text    string // item text (continuation lines joined with spaces)
+}
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	ruleRe      = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	bulletRe    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe   = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	tableSepRe  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	citationRe  = regexp.MustCompile(`^\[(\d+(?:\s*,\s*\d+)*)\]`)
+	linkRe      = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	bareURLRe   = regexp.MustCompile(`^https?://[^\s<>"]+`)
+	markupStrip = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// parseBlocks splits Markdown into blocks
+func parseBlocks(md string) []block {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+
+	var blocks []block
+	var para []string
+	flushPara := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, block{kind: blockParagraph, text: strings.Join(para, " ")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flushPara()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flushPara()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, block{kind: blockCode, text: strings.Join(code, "\n")})
+
+		case headingRe.MatchString(trimmed):
+			flushPara()
+			m := headingRe.FindStringSubmatch(trimmed)
+			blocks = append(blocks, block{kind: blockHeading, level: len(m[1]), text: m[2]})
+
+		case ruleRe.MatchString(trimmed):
+			flushPara()
+			blocks = append(blocks, block{kind: blockRule})
+
+		case strings.HasPrefix(trimmed, ">"):
+			flushPara()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			blocks = append(blocks, block{kind: blockQuote, text: strings.Join(quote, " ")})
+
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSepRe.MatchString(lines[i+1]):
+			flushPara()
+			rows := [][]string{splitRow(trimmed)}
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, splitRow(strings.TrimSpace(lines[i])))
+			}
+			i--
+			blocks = append(blocks, block{kind: blockTable, rows: rows})
+
+		case isListItem(line):
+			flushPara()
+			var items []listItem
+			for ; i < len(lines); i++ {
+				if item, ok := parseListItem(lines[i]); ok {
+					items = append(items, item)
+					continue
+				}
+				next := strings.TrimSpace(lines[i])
+				// Indented (or lazy) continuation of the previous item
+				if next != "" && len(items) > 0 && !startsBlock(next) {
+					items[len(items)-1].text += " " + next
+					continue
+				}
+				break
+			}
+			i--
+			blocks = append(blocks, block{kind: blockList, items: items})
+
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flushPara()
+	return blocks
+}
+
+// startsBlock reports whether a trimmed line opens a non-paragraph block
+func startsBlock(trimmed string) bool {
+	return headingRe.MatchString(trimmed) || ruleRe.MatchString(trimmed) ||
+		strings.HasPrefix(trimmed, "|") || strings.HasPrefix(trimmed, ">") ||
+		strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+func isListItem(line string) bool {
+	_, ok := parseListItem(line)
+	return ok
+}
+
+func parseListItem(line string) (listItem, bool) {
+	if ruleRe.MatchString(strings.TrimSpace(line)) {
+		return listItem{}, false
+	}
+	if m := bulletRe.FindStringSubmatch(line); m != nil {
+		return listItem{depth: indentDepth(m[1]), text: m[2]}, true
+	}
+	if m := orderedRe.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return listItem{depth: indentDepth(m[1]), ordered: true, number: n, text: m[3]}, true
+	}
+	return listItem{}, false
+}
+
+// indentDepth converts leading whitespace to a nesting level (2+ spaces or a tab per level)
+func indentDepth(indent string) int {
+	width := 0
+	for _, r := range indent {
+		if r == '\t' {
+			width += 4
+		} else {
+			width++
+		}
+	}
+	return width / 2
+}
+
+func splitRow(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// span is a run of inline text with one style
+type span struct {
+	text   string
+	bold   bool
+	italic bool
+	code   bool
+	href   string // link target ("#source-N" for citations)
+}
+
+// parseInline splits inline Markdown into styled spans. Numeric citations are
+// linked to the bibliography when they refer to one of the numSources sources.
+func parseInline(s string, numSources int) []span {
+	var spans []span
+	var buf strings.Builder
+	bold, italic := false, false
+	flush := func() {
+		if buf.Len() > 0 {
+			spans = append(spans, span{text: buf.String(), bold: bold, italic: italic})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '`':
+			if j := strings.IndexByte(rest[1:], '`'); j >= 0 {
+				flush()
+				spans = append(spans, span{text: rest[1 : j+1], code: true})
+				i += j + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**"):
+			// Only toggle on when there's a closing marker
+			if bold || strings.Contains(rest[2:], "**") {
+				flush()
+				bold = !bold
+				i += 2
+				continue
+			}
+
+		case rest[0] == '*':
+			if italic || (len(rest) > 1 && rest[1] != ' ' && strings.IndexByte(rest[1:], '*') >= 0) {
+				flush()
+				italic = !italic
+				i++
+				continue
+			}
+
+		case rest[0] == '[':
+			if m := citationRe.FindStringSubmatch(rest); m != nil && citationsInRange(m[1], numSources) {
+				flush()
+				spans = append(spans, span{text: "[", bold: bold, italic: italic})
+				for k, num := range strings.Split(m[1], ",") {
+					num = strings.TrimSpace(num)
+					if k > 0 {
+						spans = append(spans, span{text: ", ", bold: bold, italic: italic})
+					}
+					spans = append(spans, span{text: num, bold: bold, italic: italic, href: "#source-" + num})
+				}
+				spans = append(spans, span{text: "]", bold: bold, italic: italic})
+				i += len(m[0])
+				continue
+			}
+			if m := linkRe.FindStringSubmatch(rest); m != nil {
+				flush()
+				spans = append(spans, span{text: stripMarkup(m[1]), bold: bold, italic: italic, href: m[2]})
+				i += len(m[0])
+				continue
+			}
+
+		case strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://"):
+			if m := bareURLRe.FindString(rest); m != "" {
+				u := trimURL(m)
+				flush()
+				spans = append(spans, span{text: u, bold: bold, italic: italic, href: u})
+				i += len(u)
+				continue
+			}
+		}
+
+		buf.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return spans
+}
+
+// citationsInRange reports whether every number in "1, 2" refers to a source
+func citationsInRange(list string, numSources int) bool {
+	for _, num := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil || n < 1 || n > numSources {
+			return false
+		}
+	}
+	return true
+}
+
+// trimURL drops trailing punctuation that belongs to the sentence, not the URL
+func trimURL(u string) string {
+	for len(u) > 0 {
+		last := u[len(u)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"*", last) >= 0:
+			u = u[:len(u)-1]
+		case last == ')' && strings.Count(u, "(") < strings.Count(u, ")"):
+			u = u[:len(u)-1]
+		default:
+			return u
+		}
+	}
+	return u
+}
+
+// stripMarkup removes emphasis and code markers from inline text
+func stripMarkup(s string) string {
+	return strings.TrimSpace(markupStrip.Replace(s))
+}
+
+// safeHref allows only web, mail, and in-document links
+func safeHref(href string) (string, bool) {
+	lower := strings.ToLower(strings.TrimSpace(href))
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:") || strings.HasPrefix(lower, "#") {
+		return strings.TrimSpace(href), true
+	}
+	return "", false
+}
@@ -0,0 +1,625 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/pdf"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// The PDF renderer lays the parsed report out with the 14 standard PDF fonts
+// (Helvetica and Courier, WinAnsi encoding), so it needs no external tools or
+// font files. Links become URI annotations and citations jump to the bibliography.
+
+// Page geometry (A4, in points)
+const (
+	pdfPageWidth    = 595.28
+	pdfPageHeight   = 841.89
+	pdfMargin       = 56.0
+	pdfContentWidth = pdfPageWidth - 2*pdfMargin
+
+	pdfBodySize    = 10.5
+	pdfBodyLeading = pdfBodySize * 1.45
+	pdfBlockGap    = 7.0
+)
+
+type pdfFont int
+
+const (
+	fontRegular pdfFont = iota
+	fontBold
+	fontItalic
+	fontBoldItalic
+	fontMono
+)
+
+// pdfFontNames are the base fonts, referenced as /F1../F5 in content streams
+var pdfFontNames = [...]string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique", "Courier"}
+
+type rgb [3]float64
+
+func (c rgb) String() string {
+	return fmt.Sprintf("%.3f %.3f %.3f", c[0], c[1], c[2])
+}
+
+var (
+	colorText    = rgb{0.12, 0.16, 0.20}
+	colorMuted   = rgb{0.38, 0.43, 0.49}
+	colorLink    = rgb{0.12, 0.37, 0.75}
+	colorRule    = rgb{0.85, 0.89, 0.93}
+	colorSurface = rgb{0.96, 0.97, 0.98}
+)
+
+// pdfWord is a WinAnsi-encoded word with one font
+type pdfWord struct {
+	text      string
+	font      pdfFont
+	href      string
+	space     bool // preceded by a space
+	breakable bool // a line may break before this word
+}
+
+type pdfLink struct {
+	rect [4]float64
+	uri  string // external target
+	dest string // internal target ("source-N")
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+	links   []pdfLink
+}
+
+// pdfDest is a position links can jump to
+type pdfDest struct {
+	page int
+	y    float64
+}
+
+type pdfWriter struct {
+	pages []*pdfPage
+	page  *pdfPage
+	y     float64 // top of the next line
+	dests map[string]pdfDest
+}
+
+// PDF renders the result as a paginated A4 PDF with a bibliography.
+// Accented letters outside Windows-1252 (ă, ș, ő, ...) lose their accent, other
+// characters outside it (e.g. CJK) are replaced, and emoji are dropped.
+func PDF(title string, result agent.ResearchResult) ([]byte, error) {
+	doc := newDocument(title, result)
+	numSources := len(result.Sources)
+
+	w := &pdfWriter{dests: make(map[string]pdfDest)}
+	w.newPage()
+
+	// Title block
+	w.paragraph(plainWords("RESEARCH REPORT", true), 8, 0, 14, colorLink)
+	w.paragraph(plainWords(doc.Title, true), 22, 0, 28, colorText)
+	meta := fmt.Sprintf("Generated %s · %d sources", doc.GeneratedAt.Format("January 2, 2006 15:04"), len(doc.Sources))
+	w.paragraph(plainWords(meta, false), 9, 0, 14, colorMuted)
+	w.y -= 6
+	w.hline(pdfMargin, pdfMargin+pdfContentWidth, w.y, 1.5, colorRule)
+	w.y -= 18
+
+	for _, b := range doc.Blocks {
+		w.block(b, numSources)
+	}
+	if doc.Bibliography && len(doc.Sources) > 0 {
+		w.bibliography(doc.Sources)
+	}
+
+	return w.bytes(doc)
+}
+
+func (w *pdfWriter) newPage() {
+	w.page = &pdfPage{}
+	w.pages = append(w.pages, w.page)
+	w.y = pdfPageHeight - pdfMargin
+}
+
+// ensure starts a new page unless height points fit above the bottom margin
+func (w *pdfWriter) ensure(height float64) {
+	if w.y-height < pdfMargin {
+		w.newPage()
+	}
+}
+
+func (w *pdfWriter) block(b block, numSources int) {
+	switch b.kind {
+	case blockHeading:
+		size := [...]float64{0, 18, 15, 12.5, 11, 11, 11}[b.level]
+		w.y -= size * 0.6
+		w.ensure(size*1.3 + pdfBodyLeading*2) // keep headings with what follows
+		w.paragraph(inlineWords(b.text, numSources, true, false), size, 0, size*1.3, colorText)
+		if b.level <= 2 {
+			w.hline(pdfMargin, pdfMargin+pdfContentWidth, w.y+2, 0.75, colorRule)
+		}
+		w.y -= 5
+
+	case blockParagraph:
+		w.paragraph(inlineWords(b.text, numSources, false, false), pdfBodySize, 0, pdfBodyLeading, colorText)
+		w.y -= pdfBlockGap
+
+	case blockQuote:
+		for _, line := range wrapWords(inlineWords(b.text, numSources, false, true), pdfContentWidth-14, pdfBodySize) {
+			w.ensure(pdfBodyLeading)
+			w.fillRect(pdfMargin, w.y-pdfBodyLeading, 3, pdfBodyLeading, colorRule)
+			w.drawLine(line, pdfMargin+14, w.y-pdfBodySize, pdfBodySize, colorMuted)
+			w.y -= pdfBodyLeading
+		}
+		w.y -= pdfBlockGap
+
+	case blockCode:
+		w.code(b.text)
+
+	case blockRule:
+		w.y -= 6
+		w.hline(pdfMargin, pdfMargin+pdfContentWidth, w.y, 0.75, colorRule)
+		w.y -= 12
+
+	case blockList:
+		for _, item := range b.items {
+			marker := "\x95" // bullet
+			if item.ordered {
+				marker = strconv.Itoa(item.number) + "."
+			}
+			w.listItem(marker, inlineWords(item.text, numSources, false, false), 18*float64(item.depth+1), pdfBodySize, colorText)
+		}
+		w.y -= pdfBlockGap
+
+	case blockTable:
+		w.table(b.rows, numSources)
+	}
+}
+
+// paragraph wraps and draws words, breaking pages between lines
+func (w *pdfWriter) paragraph(words []pdfWord, size, indent, leading float64, color rgb) {
+	for _, line := range wrapWords(words, pdfContentWidth-indent, size) {
+		w.ensure(leading)
+		w.drawLine(line, pdfMargin+indent, w.y-size, size, color)
+		w.y -= leading
+	}
+}
+
+// listItem draws a hanging-indent item with its marker right-aligned before the text
+func (w *pdfWriter) listItem(marker string, words []pdfWord, indent, size float64, color rgb) {
+	leading := size * 1.45
+	for i, line := range wrapWords(words, pdfContentWidth-indent, size) {
+		w.ensure(leading)
+		if i == 0 {
+			markerX := pdfMargin + indent - 5 - textWidth(fontRegular, marker, size)
+			w.drawLine([]pdfWord{{text: marker, font: fontRegular}}, markerX, w.y-size, size, color)
+		}
+		w.drawLine(line, pdfMargin+indent, w.y-size, size, color)
+		w.y -= leading
+	}
+	w.y -= 2
+}
+
+func (w *pdfWriter) code(text string) {
+	const size = 8.5
+	const leading = size * 1.4
+	maxChars := int(math.Floor((pdfContentWidth - 16) / (0.6 * size)))
+
+	w.y -= 2
+	for _, line := range strings.Split(toWinAnsi(strings.ReplaceAll(text, "\t", "    ")), "\n") {
+		for first := true; first || line != ""; first = false {
+			chunk := line
+			if len(chunk) > maxChars {
+				chunk = chunk[:maxChars]
+			}
+			line = line[len(chunk):]
+
+			w.ensure(leading)
+			w.fillRect(pdfMargin, w.y-leading, pdfContentWidth, leading, colorSurface)
+			w.drawLine([]pdfWord{{text: chunk, font: fontMono}}, pdfMargin+8, w.y-size-1, size, colorText)
+			w.y -= leading
+		}
+	}
+	w.y -= pdfBlockGap + 2
+}
+
+func (w *pdfWriter) table(rows [][]string, numSources int) {
+	const size = 8.5
+	const leading = size * 1.35
+	const pad = 4.0
+
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	colWidth := pdfContentWidth / float64(cols)
+
+	w.ensure(leading + 2*pad)
+	w.hline(pdfMargin, pdfMargin+pdfContentWidth, w.y, 0.5, colorRule)
+	for r, row := range rows {
+		cells := make([][][]pdfWord, cols)
+		lines := 1
+		for c := range cells {
+			text := ""
+			if c < len(row) {
+				text = row[c]
+			}
+			cells[c] = wrapWords(inlineWords(text, numSources, r == 0, false), colWidth-2*pad, size)
+			lines = max(lines, len(cells[c]))
+		}
+		height := float64(lines)*leading + 2*pad
+
+		if w.y-height < pdfMargin {
+			w.newPage()
+			w.hline(pdfMargin, pdfMargin+pdfContentWidth, w.y, 0.5, colorRule)
+		}
+		if r == 0 {
+			w.fillRect(pdfMargin, w.y-height, pdfContentWidth, height, colorSurface)
+		}
+		for c, cellLines := range cells {
+			for l, line := range cellLines {
+				w.drawLine(line, pdfMargin+float64(c)*colWidth+pad, w.y-pad-float64(l)*leading-size, size, colorText)
+			}
+		}
+		w.y -= height
+		w.hline(pdfMargin, pdfMargin+pdfContentWidth, w.y, 0.5, colorRule)
+	}
+	w.y -= pdfBlockGap + 4
+}
+
+func (w *pdfWriter) bibliography(sources []entry) {
+	w.block(block{kind: blockHeading, level: 2, text: "Bibliography"}, 0)
+
+	const indent = 24.0
+	for _, src := range sources {
+		w.ensure(pdfBodyLeading * 2)
+		w.dests[fmt.Sprintf("source-%d", src.Number)] = pdfDest{page: len(w.pages) - 1, y: w.y + 4}
+
+		title := plainWords(src.Title, false)
+		if src.Document {
+			w.listItem(strconv.Itoa(src.Number)+".", append(title, plainWords("(provided document)", false)...), indent, pdfBodySize, colorText)
+			w.y -= 6
+			continue
+		}
+		for i := range title {
+			title[i].href = src.URL
+		}
+		if src.Published != "" {
+			title = append(title, plainWords("(published "+src.Published+")", false)...)
+		}
+		w.listItem(strconv.Itoa(src.Number)+".", title, indent, pdfBodySize, colorText)
+		w.y += 2
+		w.paragraph([]pdfWord{{text: toWinAnsi(src.URL), font: fontRegular}}, 8, indent, 11, colorMuted)
+		if src.Summary != "" {
+			w.paragraph(plainWords(src.Summary, false), 9, indent, 12.5, colorMuted)
+		}
+		if src.ArchiveURL != "" {
+			archived := append(plainWords("Page gone; read from the archived copy:", false),
+				pdfWord{text: toWinAnsi(src.ArchiveURL), font: fontRegular, href: src.ArchiveURL, space: true, breakable: true})
+			w.paragraph(archived, 8, indent, 11, colorMuted)
+		}
+		w.y -= 6
+	}
+}
+
+// drawLine draws one wrapped line with its baseline at y
+func (w *pdfWriter) drawLine(line []pdfWord, x, baseline, size float64, color rgb) {
+	for i, word := range line {
+		if i > 0 && word.space {
+			x += textWidth(line[i-1].font, " ", size)
+		}
+		width := textWidth(word.font, word.text, size)
+		c := color
+		if word.href != "" {
+			c = colorLink
+		}
+		fmt.Fprintf(&w.page.content, "BT %s rg /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", c, word.font+1, size, x, baseline, pdfEscape(word.text))
+		if word.href != "" {
+			w.addLink(x, baseline-size*0.25, x+width, baseline+size*0.85, word.href)
+		}
+		x += width
+	}
+}
+
+func (w *pdfWriter) addLink(x1, y1, x2, y2 float64, href string) {
+	href, ok := safeHref(href)
+	if !ok {
+		return
+	}
+	link := pdfLink{rect: [4]float64{x1, y1, x2, y2}}
+	if strings.HasPrefix(href, "#") {
+		link.dest = href[1:]
+	} else {
+		link.uri = href
+	}
+	w.page.links = append(w.page.links, link)
+}
+
+func (w *pdfWriter) fillRect(x, y, width, height float64, color rgb) {
+	fmt.Fprintf(&w.page.content, "%s rg %.2f %.2f %.2f %.2f re f\n", color, x, y, width, height)
+}
+
+func (w *pdfWriter) hline(x1, x2, y, thickness float64, color rgb) {
+	fmt.Fprintf(&w.page.content, "%s RG %.2f w %.2f %.2f m %.2f %.2f l S\n", color, thickness, x1, y, x2, y)
+}
+
+// bytes serializes the document: catalog, page tree, fonts, info, then a page and content stream per page
+func (w *pdfWriter) bytes(doc document) ([]byte, error) {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	const fontObj = 3
+	const infoObj = fontObj + len(pdfFontNames)
+	pageObj := func(i int) int { return infoObj + 1 + 2*i }
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(w.pages))
+	for i := range w.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj(i))
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages)))
+
+	var fonts strings.Builder
+	for i, name := range pdfFontNames {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", i+1, fontObj+i)
+	}
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (deep-research) /CreationDate (D:%s) >>",
+		pdfEscape(toWinAnsi(doc.Title)), doc.GeneratedAt.Format("20060102150405")))
+
+	for i, page := range w.pages {
+		// Footer: page number
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(w.pages))
+		fmt.Fprintf(&page.content, "BT %s rg /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n",
+			colorMuted, pdfPageWidth-pdfMargin-textWidth(fontRegular, footer, 8), pdfMargin/2, footer)
+
+		var annots strings.Builder
+		for _, link := range page.links {
+			rect := fmt.Sprintf("%.2f %.2f %.2f %.2f", link.rect[0], link.rect[1], link.rect[2], link.rect[3])
+			if link.dest != "" {
+				dest, ok := w.dests[link.dest]
+				if !ok {
+					continue
+				}
+				fmt.Fprintf(&annots, "<< /Type /Annot /Subtype /Link /Rect [%s] /Border [0 0 0] /Dest [%d 0 R /XYZ null %.2f null] >> ", rect, pageObj(dest.page), dest.y)
+			} else {
+				fmt.Fprintf(&annots, "<< /Type /Annot /Subtype /Link /Rect [%s] /Border [0 0 0] /A << /S /URI /URI (%s) >> >> ", rect, pdfEscape(link.uri))
+			}
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << %s>> >> /Contents %d 0 R /Annots [%s] >>",
+			pdfPageWidth, pdfPageHeight, fonts.String(), pageObj(i)+1, annots.String()))
+
+		var stream bytes.Buffer
+		zw := zlib.NewWriter(&stream)
+		if _, err := zw.Write(page.content.Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to compress PDF page: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress PDF page: %w", err)
+		}
+		obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, infoObj, xref)
+	return out.Bytes(), nil
+}
+
+// inlineWords parses inline Markdown into words; bold/italic set the base style
+func inlineWords(text string, numSources int, bold, italic bool) []pdfWord {
+	spans := parseInline(text, numSources)
+	for i := range spans {
+		spans[i].bold = spans[i].bold || bold
+		spans[i].italic = spans[i].italic || italic
+	}
+	return spanWords(spans)
+}
+
+// plainWords splits literal text (no Markdown) into words
+func plainWords(text string, bold bool) []pdfWord {
+	return spanWords([]span{{text: text, bold: bold}})
+}
+
+func spanWords(spans []span) []pdfWord {
+	var words []pdfWord
+	pendingSpace := false
+	for _, s := range spans {
+		font := fontRegular
+		switch {
+		case s.code:
+			font = fontMono
+		case s.bold && s.italic:
+			font = fontBoldItalic
+		case s.bold:
+			font = fontBold
+		case s.italic:
+			font = fontItalic
+		}
+
+		text := toWinAnsi(s.text)
+		for i := 0; i < len(text); {
+			if isSpace(text[i]) {
+				pendingSpace = true
+				i++
+				continue
+			}
+			j := i
+			for j < len(text) && !isSpace(text[j]) {
+				j++
+			}
+			words = append(words, pdfWord{text: text[i:j], font: font, href: s.href, space: pendingSpace, breakable: pendingSpace})
+			pendingSpace = false
+			i = j
+		}
+	}
+	return words
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// wrapWords greedily fills lines up to maxWidth, splitting words too long for a line
+func wrapWords(words []pdfWord, maxWidth, size float64) [][]pdfWord {
+	var lines [][]pdfWord
+	var line []pdfWord
+	lineWidth := 0.0
+	for _, word := range splitLongWords(words, maxWidth, size) {
+		width := textWidth(word.font, word.text, size)
+		gap := 0.0
+		if len(line) > 0 && word.space {
+			gap = textWidth(line[len(line)-1].font, " ", size)
+		}
+		if len(line) > 0 && word.breakable && lineWidth+gap+width > maxWidth {
+			lines = append(lines, line)
+			line, lineWidth, gap = nil, 0, 0
+		}
+		line = append(line, word)
+		lineWidth += gap + width
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitLongWords breaks words wider than a line (typically URLs) into line-sized pieces
+func splitLongWords(words []pdfWord, maxWidth, size float64) []pdfWord {
+	var out []pdfWord
+	for _, word := range words {
+		for len(word.text) > 1 && textWidth(word.font, word.text, size) > maxWidth {
+			n := 1
+			for n < len(word.text) && textWidth(word.font, word.text[:n+1], size) <= maxWidth {
+				n++
+			}
+			head := word
+			head.text = word.text[:n]
+			out = append(out, head)
+			word.text = word.text[n:]
+			word.space, word.breakable = false, true
+		}
+		out = append(out, word)
+	}
+	return out
+}
+
+// textWidth measures WinAnsi text in points
+func textWidth(font pdfFont, s string, size float64) float64 {
+	total := 0
+	for i := 0; i < len(s); i++ {
+		total += charWidth(font, s[i])
+	}
+	return float64(total) * size / 1000
+}
+
+func charWidth(font pdfFont, b byte) int {
+	if font == fontMono {
+		return 600
+	}
+	bold := font == fontBold || font == fontBoldItalic
+	if b >= 32 && b <= 126 {
+		if bold {
+			return pdf.HelveticaBoldWidths[b-32]
+		}
+		return pdf.HelveticaWidths[b-32]
+	}
+	switch b {
+	case 0x85, 0x97: // ellipsis, em dash
+		return 1000
+	case 0x91, 0x92: // single quotes
+		if bold {
+			return 278
+		}
+		return 222
+	case 0x93, 0x94: // double quotes
+		return 500
+	case 0x95: // bullet
+		return 350
+	case 0xA0: // no-break space
+		return 278
+	}
+	return 556
+}
+
+// winAnsiSpecials maps the Unicode characters at 0x80-0x9F in Windows-1252
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsiFallbacks spells out common symbols Windows-1252 lacks
+var winAnsiFallbacks = strings.NewReplacer(
+	"→", "->", "←", "<-", "↔", "<->", "⇒", "=>",
+	"≥", ">=", "≤", "<=", "≠", "!=", "≈", "~",
+	"✓", "v", "✔", "v", "✗", "x", "✘", "x",
+	"‑", "-", "−", "-", "\u2009", " ", "\u202f", " ",
+	"ł", "l", "Ł", "L", "đ", "d", "Đ", "D", "ħ", "h", "Ħ", "H", "ı", "i",
+)
+
+// toWinAnsi converts UTF-8 to Windows-1252 for the standard fonts. Letters it
+// lacks are written without their accents (ș as s, ő as o), emoji are dropped,
+// and other unsupported characters become "?".
+func toWinAnsi(s string) string {
+	s = norm.NFC.String(winAnsiFallbacks.Replace(s))
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n' || r == '\t':
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7F:
+			// control characters
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			b.WriteByte(byte(r))
+		case winAnsiSpecials[r] != 0:
+			b.WriteByte(winAnsiSpecials[r])
+		case r >= 0x1F000 || (r >= 0x2600 && r < 0x2800) || r == 0xFE0F || r == 0x200D:
+			// emoji, pictographs, and their joiners
+		case unicode.Is(unicode.Mn, r):
+			// accents NFC couldn't combine with their letter
+		default:
+			if folded := foldAccents(r); folded != "" {
+				b.WriteString(folded)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
+
+// foldAccents returns r without its diacritics when that leaves ASCII letters,
+// e.g. "s" for ș (comma below) and "o" for ő, else ""
+func foldAccents(r rune) string {
+	var b strings.Builder
+	for _, base := range norm.NFD.String(string(r)) {
+		switch {
+		case unicode.Is(unicode.Mn, base):
+		case base < 0x80 && unicode.IsLetter(base):
+			b.WriteRune(base)
+		default:
+			return ""
+		}
+	}
+	return b.String()
+}
+
+// pdfEscape escapes a PDF literal string
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\r", `\r`).Replace(s)
+}
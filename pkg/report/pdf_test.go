@@ -0,0 +1,22 @@
+package report
+
+import "testing"
+
+func TestToWinAnsi(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Café – 450 €", "Caf\xe9 \x96 450 \x80"},
+		{"Brașov, Timișoara, Pitești", "Brasov, Timisoara, Pitesti"},
+		{"Brașov şi Ţara", "Brasov si Tara"},
+		{"Győr, Łódź, Đakovo", "Gyor, L\xf3dz, Dakovo"},
+		{"café", "caf\xe9"},
+		{"ă → b", "a -> b"},
+		{"東京 🏠", "?? "},
+	}
+	for _, tt := range tests {
+		if got := toWinAnsi(tt.in); got != tt.want {
+			t.Errorf("toWinAnsi(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
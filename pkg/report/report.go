@@ -0,0 +1,199 @@
+// Package report renders research results for sharing: Markdown, styled HTML,
+// and PDF, each with a deduplicated bibliography and clickable citations, the
+// collected sources and extracted records as CSV or XLSX listings, and the
+// extracted entities and relations as a GraphViz DOT or GraphML graph.
+package report
+
+import (
+	"deep-research/pkg/agent"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Format is an export format
+type Format string
+
+const (
+	FormatMarkdown Format = "md"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf"
+	FormatCSV      Format = "csv"     // Listings: one row per source
+	FormatXLSX     Format = "xlsx"    // Listings as an Excel workbook
+	FormatDOT      Format = "dot"     // Knowledge graph for GraphViz
+	FormatGraphML  Format = "graphml" // Knowledge graph for Gephi, yEd, or Cytoscape
+)
+
+// ParseFormat accepts "md"/"markdown", "html", "pdf", "csv", "xlsx", "dot", and "graphml" (case-insensitive)
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	case "pdf":
+		return FormatPDF, nil
+	case "csv":
+		return FormatCSV, nil
+	case "xlsx", "excel":
+		return FormatXLSX, nil
+	case "dot", "gv", "graphviz":
+		return FormatDOT, nil
+	case "graphml":
+		return FormatGraphML, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q (use md, html, pdf, csv, xlsx, dot, or graphml)", s)
+	}
+}
+
+// ContentType returns the MIME type for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	case FormatGraphML:
+		return "application/graphml+xml; charset=utf-8"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension for the format (without the dot)
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Render renders the result in the given format. The title (usually the
+// research topic) heads HTML and PDF exports unless the report starts with
+// its own top-level heading. CSV and XLSX hold the listings, DOT and GraphML
+// the knowledge graph, not the report.
+func Render(format Format, title string, result agent.ResearchResult) ([]byte, error) {
+	switch format {
+	case FormatMarkdown:
+		return []byte(Markdown(result)), nil
+	case FormatHTML:
+		return HTML(title, result)
+	case FormatPDF:
+		return PDF(title, result)
+	case FormatCSV:
+		return CSV(result)
+	case FormatXLSX:
+		return XLSX(result)
+	case FormatDOT:
+		return DOT(title, result)
+	case FormatGraphML:
+		return GraphML(title, result)
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// Markdown builds the final report with a deduplicated bibliography. A report
+// rendered with a template (Templated) is returned as is: the template lays out
+// its own bibliography.
+func Markdown(result agent.ResearchResult) string {
+	if result.Templated {
+		return result.Report
+	}
+	var finalOutput strings.Builder
+	finalOutput.WriteString(result.Report)
+	finalOutput.WriteString("\n\n---\n\n## Bibliography\n\n")
+
+	for _, src := range bibliography(result.Sources) {
+		if src.Document {
+			finalOutput.WriteString(fmt.Sprintf("%d. %s *(provided document)*\n", src.Number, src.Title))
+			continue
+		}
+		finalOutput.WriteString(fmt.Sprintf("%d. [%s](%s)", src.Number, src.Title, src.URL))
+		if src.Published != "" {
+			finalOutput.WriteString(fmt.Sprintf(" (published %s)", src.Published))
+		}
+		finalOutput.WriteString("\n")
+		// Deep mode: what this source contributed
+		if src.Summary != "" {
+			finalOutput.WriteString(fmt.Sprintf("   > %s\n", src.Summary))
+		}
+		if src.ArchiveURL != "" {
+			finalOutput.WriteString(fmt.Sprintf("   (page gone; read from the [archived copy](%s))\n", src.ArchiveURL))
+		}
+	}
+	return finalOutput.String()
+}
+
+// entry is a bibliography entry; Number is the source's position in
+// ResearchResult.Sources so citations like [3] resolve the same way in every format
+type entry struct {
+	Number     int
+	Title      string
+	URL        string
+	Summary    string
+	Published  string // Publication date as YYYY-MM-DD ("" = unknown)
+	ArchiveURL string // Wayback Machine snapshot read because the page was gone
+	Document   bool   // A document the user provided; URL isn't a link
+}
+
+// bibliography deduplicates sources by URL, keeping the first occurrence's number
+func bibliography(sources []agent.Source) []entry {
+	var entries []entry
+	seen := make(map[string]bool)
+	for i, src := range sources {
+		if seen[src.URL] {
+			continue
+		}
+		seen[src.URL] = true
+		title := src.Title
+		if title == "" {
+			title = src.URL
+		}
+		var published string
+		if !src.Published.IsZero() {
+			published = src.Published.Format(time.DateOnly)
+		}
+		entries = append(entries, entry{
+			Number:     i + 1,
+			Title:      title,
+			URL:        src.URL,
+			Summary:    strings.Join(strings.Fields(src.Summary), " "),
+			Published:  published,
+			ArchiveURL: src.ArchiveURL,
+			Document:   src.Document,
+		})
+	}
+	return entries
+}
+
+// document is the parsed report shared by the HTML and PDF renderers
+type document struct {
+	Title        string
+	Blocks       []block
+	Sources      []entry
+	Bibliography bool // Sources are listed after the report (not when the report's template lists them)
+	GeneratedAt  time.Time
+}
+
+// newDocument parses the report, promoting a leading "# Heading" to the title
+func newDocument(title string, result agent.ResearchResult) document {
+	blocks := parseBlocks(result.Report)
+	if len(blocks) > 0 && blocks[0].kind == blockHeading && blocks[0].level == 1 {
+		title = stripMarkup(blocks[0].text)
+		blocks = blocks[1:]
+	}
+	if strings.TrimSpace(title) == "" {
+		title = "Research Report"
+	}
+	return document{
+		Title:        title,
+		Blocks:       blocks,
+		Sources:      bibliography(result.Sources),
+		Bibliography: !result.Templated,
+		GeneratedAt:  time.Now(),
+	}
+}
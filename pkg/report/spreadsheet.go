@@ -0,0 +1,350 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"deep-research/pkg/agent"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCellChars is Excel's limit on the text in one cell
+const maxCellChars = 32767
+
+// cell is one spreadsheet value; numbers stay numeric in XLSX
+type cell struct {
+	text    string
+	numeric bool
+}
+
+// listings turns the result into one row per source (deduplicated like the
+// bibliography): its number, title, and URL, the fields extracted from its page
+// (deep mode with a schema), its normalized price (agent.Config.PriceCurrency,
+// e.g. a price_eur column), a summary, its publication date, when it was
+// fetched, and the archived copy read if the page was gone. Records that don't
+// match a source get rows of their own at the end.
+func listings(result agent.ResearchResult) (header []string, rows [][]cell) {
+	fields := recordFields(result)
+	header = append([]string{"#", "title", "url"}, fields...)
+	// Sources priced from their text have no record; their price goes in the
+	// records' normalized column, or one of its own
+	priceField, extraPrice := priceColumn(result, fields)
+	if extraPrice {
+		header = append(header, priceField)
+	}
+	header = append(header, "summary", "published", "fetched_at", "archived_url")
+
+	byURL := make(map[string]map[string]any, len(result.Records))
+	for _, rec := range result.Records {
+		if u, ok := recordURL(rec); ok {
+			if _, dup := byURL[listingKey(u)]; !dup {
+				byURL[listingKey(u)] = rec
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	used := make(map[string]bool)
+	for i, src := range result.Sources {
+		// Provided documents aren't listings
+		if seen[src.URL] || src.Document {
+			continue
+		}
+		seen[src.URL] = true
+
+		row := []cell{numberCell(float64(i + 1)), textCell(strings.Join(strings.Fields(src.Title), " ")), textCell(src.URL)}
+		rec := byURL[listingKey(src.URL)]
+		if rec != nil {
+			used[listingKey(src.URL)] = true
+		}
+		for _, f := range fields {
+			if f == priceField && rec[f] == nil && src.Price != nil {
+				row = append(row, numberCell(src.Price.Value))
+				continue
+			}
+			row = append(row, valueCell(rec[f]))
+		}
+		if extraPrice {
+			row = append(row, priceCell(src))
+		}
+		summary := src.Summary
+		if summary == "" {
+			summary = src.Snippet
+		}
+		row = append(row, textCell(strings.Join(strings.Fields(summary), " ")), dateCell(src.Published), timeCell(src.FetchedAt), textCell(src.ArchiveURL))
+		rows = append(rows, row)
+	}
+
+	for _, rec := range result.Records {
+		u, _ := recordURL(rec)
+		if u != "" && used[listingKey(u)] {
+			continue
+		}
+		row := []cell{textCell(""), textCell(""), textCell(u)}
+		for _, f := range fields {
+			row = append(row, valueCell(rec[f]))
+		}
+		if extraPrice {
+			row = append(row, textCell(""))
+		}
+		row = append(row, textCell(""), textCell(""), textCell(""), textCell(""))
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// recordFields returns the extracted fields to add as columns, in schema order
+// (title and url already have columns of their own)
+func recordFields(result agent.ResearchResult) []string {
+	fields := result.RecordFields
+	if len(fields) == 0 && len(result.Records) > 0 {
+		// Results saved before RecordFields existed: the union of the record keys
+		seen := make(map[string]bool)
+		for _, rec := range result.Records {
+			for k := range rec {
+				if !seen[k] {
+					seen[k] = true
+					fields = append(fields, k)
+				}
+			}
+		}
+		sort.Strings(fields)
+	}
+
+	var out []string
+	for _, f := range fields {
+		if !strings.EqualFold(f, "url") && !strings.EqualFold(f, "title") {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// priceColumn names the column of the sources' normalized prices, e.g.
+// price_eur, and reports whether it is a column of its own rather than one of
+// the records' fields ("" and false when no source has a price)
+func priceColumn(result agent.ResearchResult, fields []string) (string, bool) {
+	for _, src := range result.Sources {
+		if src.Price != nil {
+			name := "price_" + strings.ToLower(src.Price.Currency)
+			return name, !slices.Contains(fields, name)
+		}
+	}
+	return "", false
+}
+
+// priceCell is a source's normalized price (empty without one)
+func priceCell(src agent.Source) cell {
+	if src.Price == nil {
+		return cell{}
+	}
+	return numberCell(src.Price.Value)
+}
+
+// recordURL returns the page URL of an extracted record
+func recordURL(rec map[string]any) (string, bool) {
+	for k, v := range rec {
+		if strings.EqualFold(k, "url") {
+			s, ok := v.(string)
+			return s, ok && s != ""
+		}
+	}
+	return "", false
+}
+
+// listingKey matches a record's URL to its source despite trailing slashes
+func listingKey(u string) string {
+	return strings.TrimSuffix(strings.TrimSpace(u), "/")
+}
+
+func textCell(s string) cell {
+	if len(s) > maxCellChars {
+		s = strings.ToValidUTF8(s[:maxCellChars], "")
+	}
+	return cell{text: s}
+}
+
+// numberCell is an empty cell for NaN and ±Inf, which spreadsheets can't hold
+func numberCell(n float64) cell {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return cell{}
+	}
+	return cell{text: strconv.FormatFloat(n, 'f', -1, 64), numeric: true}
+}
+
+func timeCell(t time.Time) cell {
+	if t.IsZero() {
+		return cell{}
+	}
+	return cell{text: t.Format(time.RFC3339)}
+}
+
+// dateCell formats a date as YYYY-MM-DD (empty when zero)
+func dateCell(t time.Time) cell {
+	if t.IsZero() {
+		return cell{}
+	}
+	return cell{text: t.Format(time.DateOnly)}
+}
+
+// valueCell converts an extracted value (JSON-decoded) to a cell
+func valueCell(v any) cell {
+	switch val := v.(type) {
+	case nil:
+		return cell{}
+	case string:
+		return textCell(strings.Join(strings.Fields(val), " "))
+	case float64:
+		return numberCell(val)
+	case bool:
+		return textCell(strconv.FormatBool(val))
+	default:
+		b, _ := json.Marshal(val)
+		return textCell(string(b))
+	}
+}
+
+// CSV renders the listings as comma-separated values with a header row
+func CSV(result agent.ResearchResult) ([]byte, error) {
+	header, rows := listings(result)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	record := make([]string, len(header))
+	for i, h := range header {
+		record[i] = csvText(h)
+	}
+	if err := w.Write(record); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, c := range row {
+			record[i] = c.text
+			if !c.numeric {
+				record[i] = csvText(c.text)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvText keeps a text cell from being read as a formula when the CSV is
+// opened in a spreadsheet: one starting with =, +, -, @, a tab, or a carriage
+// return gets a leading '
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// XLSX renders the listings as an Excel workbook with one sheet, a bold frozen
+// header row, and a filter on every column
+func XLSX(result agent.ResearchResult) ([]byte, error) {
+	header, rows := listings(result)
+
+	var sheet bytes.Buffer
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	sheet.WriteString(`<sheetData>`)
+	headerCells := make([]cell, len(header))
+	for i, h := range header {
+		headerCells[i] = textCell(h)
+	}
+	writeXLSXRow(&sheet, 1, headerCells, 1)
+	for i, row := range rows {
+		writeXLSXRow(&sheet, i+2, row, 0)
+	}
+	sheet.WriteString(`</sheetData>`)
+	fmt.Fprintf(&sheet, `<autoFilter ref="A1:%s%d"/>`, columnName(len(header)-1), len(rows)+1)
+	sheet.WriteString(`</worksheet>`)
+
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Listings" sheetId="1" r:id="rId1"/></sheets>` +
+			fmt.Sprintf(`<definedNames><definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">Listings!$A$1:$%s$%d</definedName></definedNames>`, columnName(len(header)-1), len(rows)+1) +
+			`</workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`},
+		{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(f.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXLSXRow writes one <row>; style 1 is the bold header style
+func writeXLSXRow(buf *bytes.Buffer, r int, cells []cell, style int) {
+	fmt.Fprintf(buf, `<row r="%d">`, r)
+	for i, c := range cells {
+		ref := fmt.Sprintf("%s%d", columnName(i), r)
+		styleAttr := ""
+		if style != 0 {
+			styleAttr = fmt.Sprintf(` s="%d"`, style)
+		}
+		switch {
+		case c.numeric:
+			fmt.Fprintf(buf, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, c.text)
+		case c.text != "":
+			fmt.Fprintf(buf, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, styleAttr)
+			xml.EscapeText(buf, []byte(c.text))
+			buf.WriteString(`</t></is></c>`)
+		}
+	}
+	buf.WriteString(`</row>`)
+}
+
+// columnName returns the spreadsheet column letters for a 0-based index (0 = A, 26 = AA)
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
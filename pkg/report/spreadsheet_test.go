@@ -0,0 +1,44 @@
+package report
+
+import (
+	"bytes"
+	"deep-research/pkg/agent"
+	"encoding/csv"
+	"math"
+	"testing"
+)
+
+func TestCSVFormulaCells(t *testing.T) {
+	result := agent.ResearchResult{Sources: []agent.Source{
+		{Title: `=HYPERLINK("https://evil.example","Click")`, URL: "https://a.example"},
+		{Title: "+40 721 000 000", URL: "https://b.example"},
+		{Title: "-5% off", URL: "https://c.example"},
+		{Title: "@SUM(A1:A2)", URL: "https://d.example"},
+		{Title: "Flat in Cluj", URL: "https://e.example"},
+	}}
+	data, err := CSV(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`'=HYPERLINK("https://evil.example","Click")`, "'+40 721 000 000", "'-5% off", "'@SUM(A1:A2)", "Flat in Cluj"}
+	for i, title := range want {
+		if got := rows[i+1][1]; got != title {
+			t.Errorf("row %d title = %q, want %q", i+1, got, title)
+		}
+	}
+}
+
+func TestNumberCellRejectsNonFinite(t *testing.T) {
+	for _, n := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if c := numberCell(n); c.text != "" || c.numeric {
+			t.Errorf("numberCell(%v) = %+v, want an empty cell", n, c)
+		}
+	}
+	if c := numberCell(-2.5); c.text != "-2.5" || !c.numeric {
+		t.Errorf("numberCell(-2.5) = %+v", c)
+	}
+}
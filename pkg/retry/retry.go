@@ -0,0 +1,198 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Policy configures retries with exponential backoff and jitter
+type Policy struct {
+	MaxAttempts     int           // Total attempts including the first (1 = no retries)
+	InitialBackoff  time.Duration // Delay before the first retry
+	MaxBackoff      time.Duration // Upper bound for a single delay
+	Multiplier      float64       // Backoff growth factor per attempt
+	Jitter          float64       // Random +/- fraction applied to each delay (0-1)
+	RetryableStatus []int         // HTTP status codes worth retrying
+
+	// Throttled responses (429, 503) wait at least their Retry-After, up to
+	// MaxRetryAfter per wait (0 = as long as asked), and get ThrottledAttempts
+	// attempts instead of MaxAttempts (0 = MaxAttempts)
+	ThrottledAttempts int
+	MaxRetryAfter     time.Duration
+}
+
+// DefaultPolicy returns the policy used when none is configured:
+// 3 attempts, 1s → 2s backoff (±20%), retrying timeouts, throttling, and 5xx gateway errors;
+// throttled requests get 6 attempts and wait out Retry-After delays of up to 2 minutes
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:       3,
+		InitialBackoff:    time.Second,
+		MaxBackoff:        30 * time.Second,
+		Multiplier:        2,
+		Jitter:            0.2,
+		RetryableStatus:   []int{408, 429, 500, 502, 503, 504},
+		ThrottledAttempts: 6,
+		MaxRetryAfter:     2 * time.Minute,
+	}
+}
+
+// StatusError is returned for unexpected HTTP status codes so callers can decide whether to retry
+type StatusError struct {
+	StatusCode int
+	Msg        string
+	RetryAfter time.Duration // How long the server asked to wait before trying again (0 = not said)
+}
+
+func (e *StatusError) Error() string {
+	return e.Msg
+}
+
+// NewStatusError creates a StatusError with a formatted message
+func NewStatusError(code int, format string, args ...any) *StatusError {
+	return &StatusError{StatusCode: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+// ResponseError creates a StatusError for resp, with the delay its Retry-After header asks for
+func ResponseError(resp *http.Response, format string, args ...any) *StatusError {
+	err := NewStatusError(resp.StatusCode, format, args...)
+	err.RetryAfter = ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return err
+}
+
+// ParseRetryAfter reads a Retry-After header, either seconds or an HTTP date
+// (0 when it's missing, invalid, or already past)
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// IsThrottled reports whether err is a 429 Too Many Requests or 503 Service Unavailable response
+func IsThrottled(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusServiceUnavailable)
+}
+
+// RetryAfter returns the delay err's Retry-After header asked for (0 if none)
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// Do runs fn until it succeeds, returns a non-retryable error, attempts run out,
+// or ctx is cancelled (which also interrupts the backoff sleep). Throttled
+// responses get ThrottledAttempts and wait at least as long as they ask.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= p.attemptsFor(err) || !p.IsRetryable(err) || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-time.After(p.delay(attempt, err)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if attempt > 1 && p.IsRetryable(err) {
+		return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+	}
+	return err
+}
+
+// attemptsFor is how many attempts a request failing with err gets in all
+func (p Policy) attemptsFor(err error) int {
+	attempts := max(p.MaxAttempts, 1)
+	if p.ThrottledAttempts > 0 && IsThrottled(err) {
+		attempts = p.ThrottledAttempts
+	}
+	return attempts
+}
+
+// delay is the wait before retry number attempt after err: the backoff, or
+// the server's Retry-After when that's longer (capped by MaxRetryAfter)
+func (p Policy) delay(attempt int, err error) time.Duration {
+	delay := p.Backoff(attempt)
+	retryAfter := RetryAfter(err)
+	if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+		retryAfter = p.MaxRetryAfter
+	}
+	return max(delay, retryAfter)
+}
+
+// Backoff returns the delay before retry number attempt (1-based)
+func (p Policy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// IsRetryable reports whether err is a transient failure: a retryable HTTP status,
+// a network timeout, a refused/reset connection, or a truncated response
+func (p Policy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		for _, code := range p.RetryableStatus {
+			if code == statusErr.StatusCode {
+				return true
+			}
+		}
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultBraveURL is the Brave Search API web search endpoint
+const DefaultBraveURL = "https://api.search.brave.com/res/v1/web/search"
+
+// BraveClient implements the Searcher interface for the Brave Search API
+type BraveClient struct {
+	APIKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+	Retry      retry.Policy // Retry policy for searches
+}
+
+// NewBraveClient creates a new Brave Search client
+func NewBraveClient(apiKey string) *BraveClient {
+	return &BraveClient{
+		APIKey:  apiKey,
+		BaseURL: DefaultBraveURL,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		Retry: retry.DefaultPolicy(),
+	}
+}
+
+type braveResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			PageAge     string `json:"page_age"` // Publication date, e.g. "2024-03-15T10:00:00"
+		} `json:"results"`
+	} `json:"web"`
+}
+
+// Search performs a search on Brave (page 1)
+func (b *BraveClient) Search(ctx context.Context, query string) ([]Result, error) {
+	return b.SearchWithPage(ctx, query, 1)
+}
+
+// SearchWithPage performs a paginated search on Brave (the API serves at most 10 pages)
+func (b *BraveClient) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
+	if page > 10 {
+		return nil, nil
+	}
+
+	params := url.Values{}
+	params.Add("q", query)
+	if page > 1 {
+		params.Add("offset", fmt.Sprintf("%d", page-1))
+	}
+	u := fmt.Sprintf("%s?%s", b.BaseURL, params.Encode())
+
+	var bResp braveResponse
+	err := b.Retry.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Subscription-Token", b.APIKey)
+
+		resp, err := b.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return retry.ResponseError(resp, "brave returned status %d", resp.StatusCode)
+		}
+
+		bResp = braveResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(&bResp); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, r := range bResp.Web.Results {
+		results = append(results, Result{
+			Title:     r.Title,
+			URL:       r.URL,
+			Content:   stripTags(r.Description),
+			Published: ParseDate(r.PageAge),
+		})
+	}
+	return results, nil
+}
+
+// stripTags removes the inline markup Brave uses to highlight matches (<strong>) and decodes entities
+func stripTags(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return s
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	return strings.TrimSpace(doc.Text())
+}
@@ -0,0 +1,256 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/logging"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// browserCandidates are the Chrome/Chromium executables looked up on PATH
+var browserCandidates = []string{
+	"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "microsoft-edge",
+}
+
+// BrowserConfig configures headless-browser page rendering
+type BrowserConfig struct {
+	ExecPath string                                // Chrome/Chromium binary (default: found on PATH)
+	PoolSize int                                   // Max concurrent browser instances (default 2)
+	Timeout  time.Duration                         // Per-page render timeout (default 30s)
+	Proxy    func(*http.Request) (*url.URL, error) // Picks each page's proxy, as http.Transport.Proxy does (nil = the browser's own settings)
+	Logger   *slog.Logger                          // Where render fallbacks are reported (nil = console on stdout)
+
+	Extractors []Extractor // Site rules for listing links and detail fields (see LoadExtractors)
+}
+
+// BrowserSearcher wraps a Searcher so deep-mode page fetches and listing-link
+// extraction run through headless Chrome, which executes the page's JavaScript
+// before its DOM is read. Pages the browser can't render (crash, timeout, empty
+// DOM) fall back to the wrapped searcher's plain HTTP fetcher.
+type BrowserSearcher struct {
+	Searcher
+	config BrowserConfig
+	slots  chan struct{} // one slot per concurrent browser instance
+}
+
+// NewBrowserSearcher wraps s with headless-browser fetching. Fails if no
+// Chrome/Chromium executable can be found.
+func NewBrowserSearcher(s Searcher, cfg BrowserConfig) (*BrowserSearcher, error) {
+	if cfg.ExecPath == "" {
+		path, err := FindBrowser()
+		if err != nil {
+			return nil, err
+		}
+		cfg.ExecPath = path
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 2
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &BrowserSearcher{
+		Searcher: s,
+		config:   cfg,
+		slots:    make(chan struct{}, cfg.PoolSize),
+	}, nil
+}
+
+// proxyFlag is the Chrome flag routing pageURL through its proxy. Chrome takes
+// no credentials in --proxy-server, so proxies needing them can't be used here.
+func (b *BrowserSearcher) proxyFlag(pageURL string) string {
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "--no-proxy-server"
+	}
+	u, err := b.config.Proxy(req)
+	if err != nil || u == nil {
+		return "--no-proxy-server"
+	}
+	scheme := u.Scheme
+	if scheme == "socks5h" {
+		scheme = "socks5" // Chrome resolves hostnames through SOCKS5 proxies anyway
+	}
+	return fmt.Sprintf("--proxy-server=%s://%s", scheme, u.Host)
+}
+
+// ExecPath returns the browser executable in use
+func (b *BrowserSearcher) ExecPath() string {
+	return b.config.ExecPath
+}
+
+// FindBrowser locates a Chrome/Chromium executable (CHROME_PATH, then PATH, then the usual install locations)
+func FindBrowser() (string, error) {
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path, nil
+	}
+	for _, name := range browserCandidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	var installed []string
+	switch runtime.GOOS {
+	case "darwin":
+		installed = []string{
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		}
+	case "windows":
+		installed = []string{
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files (x86)\Microsoft\Edge\Application\msedge.exe`,
+		}
+	}
+	for _, path := range installed {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("no Chrome/Chromium executable found (install one or set CHROME_PATH)")
+}
+
+// FetchPageContent renders the page and extracts its text, falling back to plain
+// HTTP. PDFs are always fetched over plain HTTP, since the browser only shows its viewer.
+func (b *BrowserSearcher) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	fetcher, canFetch := b.Searcher.(ContentFetcher)
+	if canFetch && isPDFURL(pageURL) {
+		return fetcher.FetchPageContent(ctx, pageURL, maxLength)
+	}
+
+	html, err := b.render(ctx, pageURL)
+	if errors.Is(err, errPDFViewer) && canFetch {
+		return fetcher.FetchPageContent(ctx, pageURL, maxLength)
+	}
+	if err != nil {
+		if !canFetch || ctx.Err() != nil {
+			return "", err
+		}
+		b.config.Logger.Warn("⚠️ Browser render failed, fetching without JavaScript", "url", pageURL, "error", err)
+		return fetcher.FetchPageContent(ctx, pageURL, maxLength)
+	}
+
+	text := pageText(b.config.Extractors, pageURL, html)
+	recordPublished(ctx, ExtractPublished(pageURL, html))
+	recordBody(ctx, []byte(html), "text/html; charset=utf-8")
+	if maxLength > 0 && len(text) > maxLength {
+		text = text[:maxLength] + "..."
+	}
+	return text, nil
+}
+
+// ExtractListingLinks renders an index page and extracts item links from the
+// final DOM, falling back to plain HTTP
+func (b *BrowserSearcher) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	html, err := b.render(ctx, pageURL)
+	if err != nil {
+		extractor, ok := b.Searcher.(LinkExtractor)
+		if !ok || ctx.Err() != nil {
+			return nil, err
+		}
+		return extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+	}
+	return listingLinks(b.config.Extractors, pageURL, html, maxLinks), nil
+}
+
+// SitemapURLs reads the site's sitemaps over plain HTTP; they are XML, not pages to render
+func (b *BrowserSearcher) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	reader, ok := b.Searcher.(SitemapReader)
+	if !ok {
+		return nil, errNoSitemapReader
+	}
+	return reader.SitemapURLs(ctx, site, maxURLs)
+}
+
+// render loads the page in a fresh headless browser instance (its own throwaway
+// profile, so no cookies or cache leak between pages) and returns the rendered DOM
+func (b *BrowserSearcher) render(ctx context.Context, pageURL string) (string, error) {
+	// Wait for a free slot in the pool
+	select {
+	case b.slots <- struct{}{}:
+		defer func() { <-b.slots }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	profileDir, err := os.MkdirTemp("", "deep-research-browser-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create browser profile: %w", err)
+	}
+	defer os.RemoveAll(profileDir)
+
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+
+	// Give scripts most of the timeout to build the page before the DOM is dumped
+	budget := b.config.Timeout * 2 / 3
+	args := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--disable-dev-shm-usage",
+		"--disable-extensions",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--mute-audio",
+		"--hide-scrollbars",
+		"--user-data-dir=" + profileDir,
+		"--user-agent=Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		fmt.Sprintf("--virtual-time-budget=%d", budget.Milliseconds()),
+		"--dump-dom",
+	}
+	if runtime.GOOS == "linux" && os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox") // Chrome refuses to start sandboxed as root (e.g. in containers)
+	}
+	if b.config.Proxy != nil {
+		args = append(args, b.proxyFlag(pageURL))
+	}
+	args = append(args, pageURL)
+
+	cmd := exec.CommandContext(ctx, b.config.ExecPath, args...)
+	cmd.WaitDelay = 5 * time.Second
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("browser timed out after %s", b.config.Timeout)
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+			msg = msg[i+1:]
+		}
+		return "", fmt.Errorf("browser failed: %w (%s)", err, msg)
+	}
+
+	html := string(out)
+	if !strings.Contains(strings.ToLower(html), "<body") {
+		return "", errors.New("browser returned an empty document")
+	}
+	if strings.Contains(html, `type="application/pdf"`) {
+		return "", errPDFViewer
+	}
+	return html, nil
+}
+
+// errPDFViewer is returned by render when the URL served a PDF, which the
+// browser shows in its viewer instead of a DOM
+var errPDFViewer = errors.New("page is a PDF")
+
+// isPDFURL reports whether a URL's path names a PDF file
+func isPDFURL(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	return err == nil && strings.HasSuffix(strings.ToLower(u.Path), ".pdf")
+}
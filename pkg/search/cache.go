@@ -0,0 +1,213 @@
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"deep-research/pkg/logging"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheConfig configures the on-disk search and page cache
+type CacheConfig struct {
+	Dir    string        // Cache directory (created on first write)
+	TTL    time.Duration // How long entries stay fresh (default 24h)
+	Logger *slog.Logger  // Where cache write failures are reported (nil = console on stdout)
+}
+
+// CachedSearcher wraps a Searcher with a disk cache so re-running or resuming a
+// topic doesn't repeat identical requests. Search results are keyed by query,
+// page, and search filters, fetched pages (and their publication dates) and extracted listing links by URL. Errors and empty result
+// pages are never cached.
+type CachedSearcher struct {
+	Searcher
+	config CacheConfig
+}
+
+// cacheEntry is the on-disk format of one cached response
+type cacheEntry struct {
+	Key      string          `json:"key"`
+	StoredAt time.Time       `json:"storedAt"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// NewCachedSearcher wraps s with a disk cache in cfg.Dir
+func NewCachedSearcher(s Searcher, cfg CacheConfig) *CachedSearcher {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	return &CachedSearcher{Searcher: s, config: cfg}
+}
+
+// Search returns the cached first page of results or queries the wrapped searcher
+func (c *CachedSearcher) Search(ctx context.Context, query string) ([]Result, error) {
+	return c.SearchWithPage(ctx, query, 1)
+}
+
+// SearchWithPage returns cached results for query, page, and search filters or queries the wrapped searcher
+func (c *CachedSearcher) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
+	key := fmt.Sprintf("search\x00%s\x00%d", query, page) + FiltersFromContext(ctx).cacheKey()
+	var results []Result
+	if c.get(key, &results) {
+		return results, nil
+	}
+
+	results, err := c.Searcher.SearchWithPage(ctx, query, page)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		c.put(key, results)
+	}
+	return results, nil
+}
+
+// FetchPageContent returns a cached copy of the page or fetches it through the wrapped searcher
+func (c *CachedSearcher) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	fetcher, ok := c.Searcher.(ContentFetcher)
+	if !ok {
+		return "", errNoFetcher
+	}
+
+	key := fmt.Sprintf("page\x00%s\x00%d", pageURL, maxLength)
+	dateKey := "published\x00" + pageURL
+	var text string
+	if c.get(key, &text) {
+		var published time.Time
+		if c.get(dateKey, &published) {
+			recordPublished(ctx, published)
+		}
+		return text, nil
+	}
+
+	pageCtx, info := WithPageInfo(ctx)
+	text, err := fetcher.FetchPageContent(pageCtx, pageURL, maxLength)
+	if err != nil {
+		return "", err
+	}
+	recordPublished(ctx, info.Published)
+	recordBody(ctx, info.Body, info.ContentType)
+	if text != "" {
+		c.put(key, text)
+		if !info.Published.IsZero() {
+			c.put(dateKey, info.Published)
+		}
+	}
+	return text, nil
+}
+
+// ExtractListingLinks returns cached links for the page or extracts them through the wrapped searcher
+func (c *CachedSearcher) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	extractor, ok := c.Searcher.(LinkExtractor)
+	if !ok {
+		return nil, errNoLinkExtractor
+	}
+
+	key := fmt.Sprintf("links\x00%s\x00%d", pageURL, maxLinks)
+	var links []ListingLink
+	if c.get(key, &links) {
+		return links, nil
+	}
+
+	links, err := extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) > 0 {
+		c.put(key, links)
+	}
+	return links, nil
+}
+
+// SitemapURLs returns the cached page list for the site or reads it through the wrapped searcher
+func (c *CachedSearcher) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	reader, ok := c.Searcher.(SitemapReader)
+	if !ok {
+		return nil, errNoSitemapReader
+	}
+
+	key := fmt.Sprintf("sitemap\x00%s\x00%d", site, maxURLs)
+	var urls []SitemapURL
+	if c.get(key, &urls) {
+		return urls, nil
+	}
+
+	urls, err := reader.SitemapURLs(ctx, site, maxURLs)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, urls)
+	return urls, nil
+}
+
+// path returns the cache file for key
+func (c *CachedSearcher) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.config.Dir, name[:2], name+".json")
+}
+
+// get decodes the fresh entry for key into v. Expired entries are removed.
+func (c *CachedSearcher) get(key string, v any) bool {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return false
+	}
+	if time.Since(entry.StoredAt) > c.config.TTL {
+		os.Remove(path)
+		return false
+	}
+	return json.Unmarshal(entry.Value, v) == nil
+}
+
+// put stores v under key; the cache is best-effort, so failures are only logged
+func (c *CachedSearcher) put(key string, v any) {
+	if err := c.write(key, v); err != nil {
+		c.config.Logger.Warn("⚠️ Cache write failed", "error", err)
+	}
+}
+
+// write atomically replaces the cache file for key
+func (c *CachedSearcher) write(key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cacheEntry{Key: key, StoredAt: time.Now(), Value: value})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	err = errors.Join(err, tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,207 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var errNoCrawler = errors.New("no configured search engine supports crawling")
+
+// PageLinks are the links of an index page a crawl follows
+type PageLinks struct {
+	Items []ListingLink // Detail pages, as ExtractListingLinks finds them
+	Pages []string      // Index pages of the same site to crawl next: its "next" pages first, then other pages of the listing and its categories
+	Next  string        // The listing's next page (see nextPage), "" on its last page
+}
+
+// Crawler is implemented by searchers that can read an index page's links for
+// a crawl: the items it lists and the pages of the listing behind it
+type Crawler interface {
+	CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error)
+}
+
+// CrawlLinks reads an index page's item links (up to maxLinks) and the index pages it leads to
+func (s *SearXNGClient) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	body, _, err := s.fetchPage(ctx, pageURL, "en-US,en;q=0.9")
+	if err != nil {
+		return PageLinks{}, err
+	}
+	return crawlLinks(s.Extractors, pageURL, string(body), maxLinks), nil
+}
+
+// CrawlLinks renders an index page and reads its links from the final DOM,
+// falling back to plain HTTP
+func (b *BrowserSearcher) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	html, err := b.render(ctx, pageURL)
+	if err != nil {
+		crawler, ok := b.Searcher.(Crawler)
+		if !ok || ctx.Err() != nil {
+			return PageLinks{}, err
+		}
+		return crawler.CrawlLinks(ctx, pageURL, maxLinks)
+	}
+	return crawlLinks(b.config.Extractors, pageURL, html, maxLinks), nil
+}
+
+// CrawlLinks reads the page's links if robots.txt allows fetching it, once its Crawl-delay has passed
+func (r *RobotsSearcher) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	crawler, ok := r.Searcher.(Crawler)
+	if !ok {
+		return PageLinks{}, errNoCrawler
+	}
+	if err := r.check(ctx, pageURL); err != nil {
+		return PageLinks{}, err
+	}
+	return crawler.CrawlLinks(ctx, pageURL, maxLinks)
+}
+
+// CrawlLinks reads the page's links once its host has a free token and a global slot is available
+func (r *RateLimitedSearcher) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	crawler, ok := r.Searcher.(Crawler)
+	if !ok {
+		return PageLinks{}, errNoCrawler
+	}
+	release, err := r.acquire(ctx, pageURL)
+	if err != nil {
+		return PageLinks{}, err
+	}
+	defer release()
+	return crawler.CrawlLinks(ctx, pageURL, maxLinks)
+}
+
+// CrawlLinks returns the page's cached links or reads them through the wrapped searcher
+func (c *CachedSearcher) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	crawler, ok := c.Searcher.(Crawler)
+	if !ok {
+		return PageLinks{}, errNoCrawler
+	}
+
+	key := fmt.Sprintf("crawl\x00%s\x00%d", pageURL, maxLinks)
+	var links PageLinks
+	if c.get(key, &links) {
+		return links, nil
+	}
+
+	links, err := crawler.CrawlLinks(ctx, pageURL, maxLinks)
+	if err != nil {
+		return PageLinks{}, err
+	}
+	if len(links.Items) > 0 || len(links.Pages) > 0 {
+		c.put(key, links)
+	}
+	return links, nil
+}
+
+// CrawlLinks reads the page's links through the wrapped searcher
+func (s *SnippetSearcher) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	crawler, ok := s.Searcher.(Crawler)
+	if !ok {
+		return PageLinks{}, errNoCrawler
+	}
+	return crawler.CrawlLinks(ctx, pageURL, maxLinks)
+}
+
+// CrawlLinks reads the live page's links through the wrapped searcher
+func (w *WaybackSearcher) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	crawler, ok := w.Searcher.(Crawler)
+	if !ok {
+		return PageLinks{}, errNoCrawler
+	}
+	return crawler.CrawlLinks(ctx, pageURL, maxLinks)
+}
+
+// CrawlLinks delegates to the first engine that can crawl
+func (m *MultiSearcher) CrawlLinks(ctx context.Context, pageURL string, maxLinks int) (PageLinks, error) {
+	for _, engine := range m.Engines {
+		if crawler, ok := engine.Searcher.(Crawler); ok {
+			return crawler.CrawlLinks(ctx, pageURL, maxLinks)
+		}
+	}
+	return PageLinks{}, errNoCrawler
+}
+
+// IsIndexPage reports whether a URL looks like a listing's index page (a
+// category, search, or numbered listing page) rather than an item
+func IsIndexPage(link string) bool {
+	return paginationRe.MatchString(link) || isLikelyCategoryPage(link)
+}
+
+// crawlSkip are (lowercased) URL parts of pages a crawl never follows: accounts,
+// site information, and re-sorted copies of the same listing
+var crawlSkip = []string{
+	"/login", "/register", "/signup", "/account", "/cart", "/checkout",
+	"/contact", "/about", "/help", "/faq",
+	"/terms", "/privacy", "/cookie",
+	"/sort", "sort=", "order=", "/order",
+}
+
+// crawlLinks reads an index page: its item links (see listingLinks), its next
+// page, and the index pages of the same host it links to. Those are the pages
+// the category filter keeps out of the items (pagination, categories,
+// listings), "next" links first, minus accounts, site information, and
+// re-sorted listings.
+func crawlLinks(extractors []Extractor, pageURL, html string, maxLinks int) PageLinks {
+	links := PageLinks{Items: listingLinks(extractors, pageURL, html, maxLinks)}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return links
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return links
+	}
+
+	seen := map[string]bool{pageURL: true}
+	for _, item := range links.Items {
+		seen[item.URL] = true
+	}
+	var next, other []string
+	doc.Find("a[href], link[rel][href]").Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		ref, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") || ref.Host != base.Host {
+			return
+		}
+		ref.Fragment = ""
+		link := ref.String()
+		if seen[link] || crawlSkipped(link) {
+			return
+		}
+
+		rel, _ := sel.Attr("rel")
+		label, _ := sel.Attr("aria-label")
+		text := strings.ToLower(strings.Join(strings.Fields(sel.Text()), " "))
+		switch {
+		case isNextLink(rel, label, text):
+			next = append(next, link)
+		case goquery.NodeName(sel) == "link":
+			return // Stylesheets, feeds, alternates
+		case strings.Count(link, "&") > 2:
+			return // Filtered search pages
+		case paginationRe.MatchString(link) || isLikelyCategoryPage(link):
+			other = append(other, link)
+		default:
+			return
+		}
+		seen[link] = true
+	})
+	links.Pages = append(next, other...)
+	links.Next = nextPage(pageURL, next, other)
+	return links
+}
+
+// crawlSkipped reports whether a crawl never follows the link
+func crawlSkipped(link string) bool {
+	lower := strings.ToLower(link)
+	for _, part := range crawlSkip {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PageInfo is what a page fetch learned about the page besides its text; see WithPageInfo
+type PageInfo struct {
+	Published   time.Time // Publication date found on the page or in its URL (zero = unknown)
+	Body        []byte    // The page as downloaded (HTML, or the PDF); nil when served from the page cache
+	ContentType string    // Body's content type
+}
+
+type pageInfoKey struct{}
+
+// WithPageInfo returns a context whose page fetches record the page's
+// publication date and downloaded body in the returned PageInfo
+func WithPageInfo(ctx context.Context) (context.Context, *PageInfo) {
+	info := &PageInfo{}
+	return context.WithValue(ctx, pageInfoKey{}, info), info
+}
+
+// recordPublished stores a fetched page's publication date in the context's PageInfo, if any
+func recordPublished(ctx context.Context, published time.Time) {
+	if info, ok := ctx.Value(pageInfoKey{}).(*PageInfo); ok && !published.IsZero() {
+		info.Published = published
+	}
+}
+
+// recordBody stores a downloaded page in the context's PageInfo, if any
+func recordBody(ctx context.Context, body []byte, contentType string) {
+	if info, ok := ctx.Value(pageInfoKey{}).(*PageInfo); ok && body != nil {
+		info.Body = body
+		info.ContentType = contentType
+	}
+}
+
+// publishedMeta are the <meta> names and properties that carry a publication
+// date, most reliable first
+var publishedMeta = []string{
+	"article:published_time",
+	"og:published_time",
+	"datepublished",
+	"citation_publication_date",
+	"citation_date",
+	"dc.date.issued",
+	"dcterms.issued",
+	"dc.date",
+	"dcterms.date",
+	"dcterms.created",
+	"parsely-pub-date",
+	"sailthru.date",
+	"publish-date",
+	"publish_date",
+	"pubdate",
+	"date",
+}
+
+var (
+	// urlDateRe matches dates in article URLs: /2024/03/15/, /2024-03-15-, /20240315/
+	urlDateRe = regexp.MustCompile(`/((?:19|20)\d{2})[/-](0[1-9]|1[0-2])[/-](0[1-9]|[12]\d|3[01])(?:[/_.-]|$)|/((?:19|20)\d{2})(0[1-9]|1[0-2])(0[1-9]|[12]\d|3[01])/`)
+	// urlMonthRe matches year and month only: /2024/03/
+	urlMonthRe = regexp.MustCompile(`/((?:19|20)\d{2})/(0[1-9]|1[0-2])/`)
+)
+
+// dateLayouts are the date formats found in meta tags and JSON-LD
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"2006.01.02",
+	"20060102",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// ExtractPublished finds a page's publication date: in its meta tags, else its
+// JSON-LD datePublished, else a date in its URL. Dates before 1990 or in the
+// future are ignored; zero means none was found.
+func ExtractPublished(pageURL, rawHTML string) time.Time {
+	if rawHTML != "" {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHTML)); err == nil {
+			if t := metaPublished(doc); !t.IsZero() {
+				return t
+			}
+			if t := jsonLDPublished(doc); !t.IsZero() {
+				return t
+			}
+		}
+	}
+	return urlPublished(pageURL)
+}
+
+// metaPublished returns the date of the most reliable publication date meta tag
+func metaPublished(doc *goquery.Document) time.Time {
+	found := make(map[string]string)
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		for _, attr := range []string{"property", "name", "itemprop"} {
+			if key, ok := s.Attr(attr); ok {
+				key = strings.ToLower(strings.TrimSpace(key))
+				if _, seen := found[key]; !seen {
+					found[key], _ = s.Attr("content")
+				}
+			}
+		}
+	})
+	for _, key := range publishedMeta {
+		if t := ParseDate(found[key]); !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// jsonLDPublished returns the first datePublished (else dateCreated) in the page's JSON-LD
+func jsonLDPublished(doc *goquery.Document) time.Time {
+	var published, created time.Time
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		var data any
+		if json.Unmarshal([]byte(s.Text()), &data) != nil {
+			return true
+		}
+		published, created = findLDDates(data, published, created)
+		return published.IsZero()
+	})
+	if !published.IsZero() {
+		return published
+	}
+	return created
+}
+
+// findLDDates walks JSON-LD (objects, arrays, @graph) for the first datePublished and dateCreated
+func findLDDates(v any, published, created time.Time) (time.Time, time.Time) {
+	switch v := v.(type) {
+	case map[string]any:
+		if s, ok := v["datePublished"].(string); ok && published.IsZero() {
+			published = ParseDate(s)
+		}
+		if s, ok := v["dateCreated"].(string); ok && created.IsZero() {
+			created = ParseDate(s)
+		}
+		for _, child := range v {
+			if !published.IsZero() {
+				break
+			}
+			published, created = findLDDates(child, published, created)
+		}
+	case []any:
+		for _, child := range v {
+			if !published.IsZero() {
+				break
+			}
+			published, created = findLDDates(child, published, created)
+		}
+	}
+	return published, created
+}
+
+// urlPublished returns the date in an article URL such as /2024/03/15/slug,
+// or the first of the month for /2024/03/slug
+func urlPublished(pageURL string) time.Time {
+	if m := urlDateRe.FindStringSubmatch(pageURL); m != nil {
+		if m[1] != "" {
+			return ParseDate(m[1] + "-" + m[2] + "-" + m[3])
+		}
+		return ParseDate(m[4] + "-" + m[5] + "-" + m[6])
+	}
+	if m := urlMonthRe.FindStringSubmatch(pageURL); m != nil {
+		return ParseDate(m[1] + "-" + m[2] + "-01")
+	}
+	return time.Time{}
+}
+
+// ParseDate parses a publication date in the formats pages commonly use; zero
+// when it isn't one, or is before 1990 or more than a day in the future
+func ParseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if t.Year() < 1990 || t.After(time.Now().Add(24*time.Hour)) {
+			return time.Time{}
+		}
+		return t.UTC()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/retry"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultDuckDuckGoURL is DuckDuckGo's JavaScript-free HTML endpoint
+const DefaultDuckDuckGoURL = "https://html.duckduckgo.com/html/"
+
+// duckDuckGoPageSize is the number of results DuckDuckGo HTML returns per page
+const duckDuckGoPageSize = 30
+
+// DuckDuckGoClient implements the Searcher interface by scraping DuckDuckGo's HTML results
+type DuckDuckGoClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Retry      retry.Policy // Retry policy for searches
+}
+
+// NewDuckDuckGoClient creates a new DuckDuckGo HTML client
+func NewDuckDuckGoClient() *DuckDuckGoClient {
+	return &DuckDuckGoClient{
+		BaseURL: DefaultDuckDuckGoURL,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		Retry: retry.DefaultPolicy(),
+	}
+}
+
+// Search performs a search on DuckDuckGo (page 1)
+func (d *DuckDuckGoClient) Search(ctx context.Context, query string) ([]Result, error) {
+	return d.SearchWithPage(ctx, query, 1)
+}
+
+// SearchWithPage performs a paginated search on DuckDuckGo
+func (d *DuckDuckGoClient) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
+	form := url.Values{}
+	form.Add("q", query)
+	if page > 1 {
+		offset := (page - 1) * duckDuckGoPageSize
+		form.Add("s", fmt.Sprintf("%d", offset))
+		form.Add("dc", fmt.Sprintf("%d", offset+1))
+	}
+
+	var doc *goquery.Document
+	err := d.Retry.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", d.BaseURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+		resp, err := d.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return retry.ResponseError(resp, "duckduckgo returned status %d", resp.StatusCode)
+		}
+
+		doc, err = goquery.NewDocumentFromReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	doc.Find(".result").Each(func(_ int, sel *goquery.Selection) {
+		if sel.HasClass("result--ad") {
+			return
+		}
+		link := sel.Find("a.result__a").First()
+		resultURL := resolveDuckDuckGoURL(link.AttrOr("href", ""))
+		if resultURL == "" {
+			return
+		}
+		results = append(results, Result{
+			Title:   strings.TrimSpace(link.Text()),
+			URL:     resultURL,
+			Content: strings.TrimSpace(sel.Find(".result__snippet").Text()),
+		})
+	})
+	return results, nil
+}
+
+// resolveDuckDuckGoURL unwraps DuckDuckGo's redirect links (//duckduckgo.com/l/?uddg=<target>)
+func resolveDuckDuckGoURL(href string) string {
+	if href == "" {
+		return ""
+	}
+	if strings.HasPrefix(href, "//") {
+		href = "https:" + href
+	}
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := parsed.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+	return href
+}
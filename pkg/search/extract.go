@@ -0,0 +1,252 @@
+package search
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Readability-style heuristics: class/id names that usually mark boilerplate
+// versus the main content block
+var (
+	unlikelyCandidatesRe = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|consent|disqus|extra|footer|gdpr|header|legends|menu|modal|navbar|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tweet|twitter|advert|ad-break|agegate`)
+	maybeCandidateRe     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|listing|detail|product|price`)
+	positiveWeightRe     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story|listing|detail|product|description`)
+	negativeWeightRe     = regexp.MustCompile(`(?i)hidden|^hid$|hid$|hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// Elements that never contain readable article content
+const boilerplateSelector = "script, style, noscript, iframe, svg, canvas, template, form, button, select, input, nav, footer, aside, [role=navigation], [role=banner], [role=contentinfo], [aria-hidden=true]"
+
+// minCandidateChars is the minimum text length for the best-scoring block to be
+// trusted; shorter winners usually mean a listing/index page, so fall back to the body
+const minCandidateChars = 500
+
+// extractTextFromHTML extracts the main readable content of a page.
+// It parses the DOM, strips boilerplate (nav, footer, sidebars, cookie banners),
+// scores block containers readability-style to find the main content, and renders
+// it as text while preserving headings, paragraphs, and list items.
+func extractTextFromHTML(rawHTML string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHTML))
+	if err != nil {
+		return ""
+	}
+
+	doc.Find(boilerplateSelector).Remove()
+
+	// Drop unlikely candidates by class/id unless they also look like content
+	doc.Find("body *").Each(func(_ int, sel *goquery.Selection) {
+		if goquery.NodeName(sel) == "body" || goquery.NodeName(sel) == "article" || goquery.NodeName(sel) == "main" {
+			return
+		}
+		matchString := sel.AttrOr("class", "") + " " + sel.AttrOr("id", "")
+		if strings.TrimSpace(matchString) == "" {
+			return
+		}
+		if unlikelyCandidatesRe.MatchString(matchString) && !maybeCandidateRe.MatchString(matchString) {
+			sel.Remove()
+		}
+	})
+
+	body := doc.Find("body")
+	if body.Length() == 0 {
+		body = doc.Selection
+	}
+
+	content := body
+	if best := bestCandidate(doc); best != nil {
+		if len(strings.TrimSpace(best.Text())) >= minCandidateChars {
+			content = best
+		}
+	}
+
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	text := renderText(content)
+	if title != "" && !strings.Contains(text, title) {
+		text = "# " + title + "\n\n" + text
+	}
+	return text
+}
+
+// bestCandidate scores paragraph parents and grandparents (as Readability does)
+// and returns the highest-scoring block, or nil when nothing scores
+func bestCandidate(doc *goquery.Document) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	nodes := make(map[*html.Node]*goquery.Selection)
+
+	initScore := func(sel *goquery.Selection) {
+		node := sel.Get(0)
+		if _, ok := scores[node]; ok {
+			return
+		}
+		score := classWeight(sel)
+		switch goquery.NodeName(sel) {
+		case "article", "main":
+			score += 10
+		case "div":
+			score += 5
+		case "pre", "td", "blockquote", "section":
+			score += 3
+		case "ol", "ul", "dl", "dd", "dt", "li", "form":
+			score -= 3
+		case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+			score -= 5
+		}
+		scores[node] = score
+		nodes[node] = sel
+	}
+
+	doc.Find("p, pre, td, li, dd").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		parent := p.Parent()
+		if parent.Length() == 0 {
+			return
+		}
+		grandparent := parent.Parent()
+
+		// Base score: one point, plus commas and length (capped)
+		score := 1.0 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+		initScore(parent)
+		scores[parent.Get(0)] += score
+		if grandparent.Length() > 0 && goquery.NodeName(grandparent) != "html" {
+			initScore(grandparent)
+			scores[grandparent.Get(0)] += score / 2
+		}
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for node, score := range scores {
+		sel := nodes[node]
+		// Penalize link-heavy blocks (navigation lists, tag clouds)
+		score *= 1 - linkDensity(sel)
+		if score > bestScore {
+			bestScore = score
+			best = sel
+		}
+	}
+	return best
+}
+
+// classWeight scores an element by its class and id names
+func classWeight(sel *goquery.Selection) float64 {
+	weight := 0.0
+	for _, attr := range []string{"class", "id"} {
+		val := sel.AttrOr(attr, "")
+		if val == "" {
+			continue
+		}
+		if negativeWeightRe.MatchString(val) {
+			weight -= 25
+		}
+		if positiveWeightRe.MatchString(val) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the fraction of an element's text that sits inside links
+func linkDensity(sel *goquery.Selection) float64 {
+	textLen := len(strings.TrimSpace(sel.Text()))
+	if textLen == 0 {
+		return 0
+	}
+	linkLen := 0
+	sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLen += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(linkLen) / float64(textLen)
+}
+
+// renderText converts a DOM subtree to plain text, keeping block structure:
+// headings become "#" lines, list items become "- " lines, table cells are
+// separated by " | ", and block elements start new lines
+func renderText(sel *goquery.Selection) string {
+	var sb strings.Builder
+	for _, n := range sel.Nodes {
+		renderNode(&sb, n)
+	}
+
+	// Normalize whitespace within lines and collapse blank-line runs
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(sb.String(), "\n") {
+		line = strings.TrimSuffix(strings.Join(strings.Fields(line), " "), " |")
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// renderNode writes one node (recursively) into the text builder
+func renderNode(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		return
+	case html.ElementNode, html.DocumentNode:
+	default:
+		return
+	}
+
+	prefix, suffix := "", ""
+	switch n.Data {
+	case "h1":
+		prefix, suffix = "\n\n# ", "\n\n"
+	case "h2":
+		prefix, suffix = "\n\n## ", "\n\n"
+	case "h3", "h4", "h5", "h6":
+		prefix, suffix = "\n\n### ", "\n\n"
+	case "p", "div", "section", "article", "main", "blockquote", "pre", "table", "ul", "ol", "dl", "header", "figure":
+		prefix, suffix = "\n\n", "\n\n"
+	case "li", "dt", "dd":
+		prefix = "\n- "
+	case "tr":
+		prefix, suffix = "\n", "\n"
+	case "td", "th":
+		suffix = " | "
+	case "br":
+		sb.WriteString("\n")
+		return
+	case "hr":
+		sb.WriteString("\n\n---\n\n")
+		return
+	case "img":
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			sb.WriteString(" [image: " + alt + "] ")
+		}
+		return
+	}
+
+	sb.WriteString(prefix)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderNode(sb, c)
+	}
+	sb.WriteString(suffix)
+}
+
+// attr returns the value of an attribute on a raw html node
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,271 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultExtractorsDir is where site extraction rules are read from unless configured otherwise
+const DefaultExtractorsDir = "extractors"
+
+// regexPrefix marks a field rule as a regular expression over the page's text
+// rather than a CSS selector
+const regexPrefix = "regex:"
+
+// Extractor is one site's extraction rules, a YAML file in the extractors
+// directory. On the site's index pages they pick the item links (consulted
+// before the generic URL heuristics); on its detail pages they pull the title,
+// price, and other fields ahead of the page text. A field rule is a CSS
+// selector (the first match's text) or "regex:" and a pattern matched against
+// the page text (its first group, or the whole match).
+type Extractor struct {
+	Name        string            `yaml:"name"`         // Defaults to the file name without extension
+	Domains     []string          `yaml:"domains"`      // Hosts the rules apply to, subdomains included
+	Links       string            `yaml:"links"`        // CSS selector of the item links on index pages (a elements, or elements holding one)
+	LinkPattern string            `yaml:"link_pattern"` // Regex an item link's URL must match (alone: picks the item links among all of the page's links)
+	LinkTitle   string            `yaml:"link_title"`   // CSS selector of a link's title within the matched element (default: its text)
+	Title       string            `yaml:"title"`        // Detail pages: the item's title
+	Price       string            `yaml:"price"`        // Detail pages: the item's price
+	Fields      map[string]string `yaml:"fields"`       // Detail pages: more fields by name, e.g. rooms, surface
+
+	linkPattern *regexp.Regexp
+	patterns    map[string]*regexp.Regexp // Compiled "regex:" field rules by rule
+}
+
+// LoadExtractors reads the *.yaml/*.yml files in dir, sorted by name. A
+// missing dir just means no site rules.
+func LoadExtractors(dir string) ([]Extractor, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extractors directory: %w", err)
+	}
+
+	var extractors []Extractor
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		e, err := parseExtractor(path, data)
+		if err != nil {
+			return nil, err
+		}
+		extractors = append(extractors, e)
+	}
+	sort.Slice(extractors, func(i, j int) bool { return extractors[i].Name < extractors[j].Name })
+	return extractors, nil
+}
+
+// parseExtractor decodes one rules file, rejecting unknown keys and invalid patterns
+func parseExtractor(path string, data []byte) (Extractor, error) {
+	var e Extractor
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&e); err != nil && !errors.Is(err, io.EOF) {
+		return Extractor{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if e.Name == "" {
+		e.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(e.Domains) == 0 {
+		return Extractor{}, fmt.Errorf("%s: no domains", path)
+	}
+	for i, d := range e.Domains {
+		e.Domains[i] = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "www.")
+	}
+	if e.LinkPattern != "" {
+		re, err := regexp.Compile(e.LinkPattern)
+		if err != nil {
+			return Extractor{}, fmt.Errorf("%s: invalid link_pattern: %w", path, err)
+		}
+		e.linkPattern = re
+	}
+	e.patterns = make(map[string]*regexp.Regexp)
+	for name, rule := range e.detailRules() {
+		if pattern, ok := strings.CutPrefix(rule, regexPrefix); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return Extractor{}, fmt.Errorf("%s: invalid %s pattern: %w", path, name, err)
+			}
+			e.patterns[rule] = re
+		}
+	}
+	return e, nil
+}
+
+// extractorFor returns the rules for pageURL's site (the first whose domains
+// include its host), or nil
+func extractorFor(extractors []Extractor, pageURL string) *Extractor {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for i := range extractors {
+		for _, d := range extractors[i].Domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				return &extractors[i]
+			}
+		}
+	}
+	return nil
+}
+
+// detailRules are the rules applied to detail pages by field name
+func (e *Extractor) detailRules() map[string]string {
+	rules := make(map[string]string, len(e.Fields)+2)
+	for name, rule := range e.Fields {
+		rules[name] = rule
+	}
+	if e.Title != "" {
+		rules["title"] = e.Title
+	}
+	if e.Price != "" {
+		rules["price"] = e.Price
+	}
+	return rules
+}
+
+// listingLinks extracts item links from an index page with the site's rules,
+// or nil when it has none (or they match nothing) so the generic heuristics run
+func (e *Extractor) listingLinks(pageURL, html string, maxLinks int) []ListingLink {
+	if e.Links == "" && e.linkPattern == nil {
+		return nil
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+
+	selector := e.Links
+	if selector == "" {
+		selector = "a[href]"
+	}
+	seen := make(map[string]bool)
+	var links []ListingLink
+	doc.Find(selector).EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		anchor := sel
+		if goquery.NodeName(sel) != "a" {
+			anchor = sel.Find("a[href]").First()
+		}
+		href, ok := anchor.Attr("href")
+		if !ok {
+			return true
+		}
+		ref, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") {
+			return true
+		}
+		ref.Fragment = ""
+		link := ref.String()
+		if seen[link] || (e.linkPattern != nil && !e.linkPattern.MatchString(link)) {
+			return true
+		}
+		seen[link] = true
+
+		titleSel := sel
+		if e.LinkTitle != "" {
+			titleSel = sel.Find(e.LinkTitle).First()
+		}
+		title := strings.Join(strings.Fields(titleSel.Text()), " ")
+		if title == "" {
+			title = extractTitleFromURL(link)
+		}
+		links = append(links, ListingLink{URL: link, Title: title})
+		return maxLinks <= 0 || len(links) < maxLinks
+	})
+	return links
+}
+
+// detailFields returns the fields the site's rules find on a detail page as
+// "name: value" lines (title and price first), or "" when none match
+func (e *Extractor) detailFields(html, text string) string {
+	rules := e.detailRules()
+	if len(rules) == 0 {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		if name != "title" && name != "price" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{"title", "price"}, names...)
+
+	var sb strings.Builder
+	for _, name := range names {
+		rule, ok := rules[name]
+		if !ok {
+			continue
+		}
+		var value string
+		if re := e.patterns[rule]; re != nil {
+			if m := re.FindStringSubmatch(text); len(m) > 1 {
+				value = m[1]
+			} else if len(m) == 1 {
+				value = m[0]
+			}
+		} else {
+			value = doc.Find(rule).First().Text()
+		}
+		if value = strings.Join(strings.Fields(value), " "); value != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", name, value)
+		}
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "Site fields (" + e.Name + " rules):\n" + sb.String() + "\n"
+}
+
+// pageText is a detail page's readable text, led by the fields its site's rules find
+func pageText(extractors []Extractor, pageURL, html string) string {
+	text := extractTextFromHTML(html)
+	if e := extractorFor(extractors, pageURL); e != nil {
+		text = e.detailFields(html, text) + text
+	}
+	return text
+}
+
+// listingLinks extracts item links from an index page with its site's rules,
+// falling back to the generic heuristics of extractListingLinks
+func listingLinks(extractors []Extractor, pageURL, html string, maxLinks int) []ListingLink {
+	if e := extractorFor(extractors, pageURL); e != nil {
+		if links := e.listingLinks(pageURL, html, maxLinks); len(links) > 0 {
+			return links
+		}
+	}
+	return extractListingLinks(pageURL, html, maxLinks)
+}
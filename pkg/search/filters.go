@@ -0,0 +1,59 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SearXNG categories the research treats specially: news results carry
+// publication dates, image results an image URL (Result.ImageURL)
+const (
+	CategoryGeneral = "general"
+	CategoryNews    = "news"
+	CategoryImages  = "images"
+)
+
+// TimeRanges are the accepted Filters.TimeRange values
+var TimeRanges = []string{"day", "week", "month", "year"}
+
+// Filters narrow searches on engines that support them (SearXNG): its categories
+// (general, news, science, it, ...), the SearXNG engines to query (google, bing,
+// wikipedia, ...), and how recent results must be (Google CSE honors the time
+// range too). The other engines ignore them.
+type Filters struct {
+	Categories []string
+	Engines    []string
+	TimeRange  string // One of TimeRanges (empty = any time)
+}
+
+type filtersKey struct{}
+
+// WithFilters returns a context whose searches are narrowed by f
+func WithFilters(ctx context.Context, f Filters) context.Context {
+	return context.WithValue(ctx, filtersKey{}, f)
+}
+
+// FiltersFromContext returns the filters attached with WithFilters (zero if none)
+func FiltersFromContext(ctx context.Context) Filters {
+	f, _ := ctx.Value(filtersKey{}).(Filters)
+	return f
+}
+
+// ValidateTimeRange rejects time ranges SearXNG doesn't know
+func ValidateTimeRange(timeRange string) error {
+	if timeRange != "" && !slices.Contains(TimeRanges, timeRange) {
+		return fmt.Errorf("unknown time range %q (use %s)", timeRange, strings.Join(TimeRanges, ", "))
+	}
+	return nil
+}
+
+// cacheKey distinguishes filtered searches in the cache ("" without filters,
+// so unfiltered entries keep their keys)
+func (f Filters) cacheKey() string {
+	if len(f.Categories) == 0 && len(f.Engines) == 0 && f.TimeRange == "" {
+		return ""
+	}
+	return fmt.Sprintf("\x00%s\x00%s\x00%s", strings.Join(f.Categories, ","), strings.Join(f.Engines, ","), f.TimeRange)
+}
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultGoogleCSEURL is the Google Custom Search JSON API endpoint
+const DefaultGoogleCSEURL = "https://www.googleapis.com/customsearch/v1"
+
+// googleCSEPageSize is how many results the API returns per request (its maximum)
+const googleCSEPageSize = 10
+
+// googleDateRestrict maps Filters.TimeRange to the API's dateRestrict values
+var googleDateRestrict = map[string]string{"day": "d1", "week": "w1", "month": "m1", "year": "y1"}
+
+// GoogleCSEClient implements the Searcher interface for a Google Programmable
+// Search Engine (Custom Search JSON API)
+type GoogleCSEClient struct {
+	APIKey     string
+	CX         string // Search engine ID
+	BaseURL    string
+	HTTPClient *http.Client
+	Retry      retry.Policy // Retry policy for searches
+}
+
+// NewGoogleCSEClient creates a new Google Custom Search client for the search engine cx
+func NewGoogleCSEClient(apiKey, cx string) *GoogleCSEClient {
+	return &GoogleCSEClient{
+		APIKey:  apiKey,
+		CX:      cx,
+		BaseURL: DefaultGoogleCSEURL,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		Retry: retry.DefaultPolicy(),
+	}
+}
+
+type googleCSEResponse struct {
+	Items []struct {
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		Snippet string `json:"snippet"`
+	} `json:"items"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Search performs a search on Google (page 1)
+func (g *GoogleCSEClient) Search(ctx context.Context, query string) ([]Result, error) {
+	return g.SearchWithPage(ctx, query, 1)
+}
+
+// SearchWithPage performs a paginated search on Google. The API serves the first
+// 100 results, so pages past 10 are empty. A time range filter is passed as dateRestrict.
+func (g *GoogleCSEClient) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
+	if page < 1 {
+		page = 1
+	}
+	start := (page-1)*googleCSEPageSize + 1
+	if start+googleCSEPageSize-1 > 100 {
+		return nil, nil
+	}
+
+	params := url.Values{}
+	params.Add("key", g.APIKey)
+	params.Add("cx", g.CX)
+	params.Add("q", query)
+	params.Add("num", fmt.Sprintf("%d", googleCSEPageSize))
+	if start > 1 {
+		params.Add("start", fmt.Sprintf("%d", start))
+	}
+	if restrict, ok := googleDateRestrict[FiltersFromContext(ctx).TimeRange]; ok {
+		params.Add("dateRestrict", restrict)
+	}
+	u := fmt.Sprintf("%s?%s", g.BaseURL, params.Encode())
+
+	var gResp googleCSEResponse
+	err := g.Retry.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := g.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		gResp = googleCSEResponse{}
+		if resp.StatusCode != http.StatusOK {
+			// The body explains quota and key errors; the key itself is never echoed
+			if json.NewDecoder(resp.Body).Decode(&gResp) == nil && gResp.Error != nil && gResp.Error.Message != "" {
+				return retry.ResponseError(resp, "google returned status %d: %s", resp.StatusCode, gResp.Error.Message)
+			}
+			return retry.ResponseError(resp, "google returned status %d", resp.StatusCode)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&gResp); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, item := range gResp.Items {
+		results = append(results, Result{
+			Title:   item.Title,
+			URL:     item.Link,
+			Content: strings.Join(strings.Fields(item.Snippet), " "),
+		})
+	}
+	return results, nil
+}
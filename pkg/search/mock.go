@@ -1,14 +1,17 @@
 package search
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type MockClient struct{}
 
-func (m *MockClient) Search(query string) ([]Result, error) {
-	return m.SearchWithPage(query, 1)
+func (m *MockClient) Search(ctx context.Context, query string) ([]Result, error) {
+	return m.SearchWithPage(ctx, query, 1)
 }
 
-func (m *MockClient) SearchWithPage(query string, page int) ([]Result, error) {
+func (m *MockClient) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
 	return []Result{
 		{
 			Title:   fmt.Sprintf("Mock Result for %s (page %d)", query, page),
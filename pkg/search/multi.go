@@ -0,0 +1,224 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/retry"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Engine names accepted by NewSearcher
+const (
+	EngineSearXNG    = "searxng"
+	EngineBrave      = "brave"
+	EngineDuckDuckGo = "duckduckgo"
+	EngineGoogle     = "google"
+)
+
+// Config holds the settings NewSearcher needs to build engines by name
+type Config struct {
+	SearXURL    string       // SearXNG base URL, or several comma-separated ones to rotate searches across
+	BraveAPIKey string       // Brave Search API key (required for the brave engine)
+	GoogleKey   string       // Google Custom Search API key (required for the google engine)
+	GoogleCX    string       // Google Programmable Search Engine ID (required for the google engine)
+	Retry       retry.Policy // Retry policy for every engine (zero value = retry.DefaultPolicy())
+
+	Transport      http.RoundTripper // Transport for searches, e.g. through a proxy (nil = http.DefaultTransport)
+	FetchTransport http.RoundTripper // Transport for page fetches (nil = http.DefaultTransport)
+	Logger         *slog.Logger      // Where SearXNG instance cooldowns are reported (nil = console on stdout)
+	Extractors     []Extractor       // Site rules for deep-mode listing links and detail fields (see LoadExtractors)
+}
+
+// NewSearcher creates the named search engines ("searxng", "brave", "duckduckgo", "google").
+// A single engine is returned as-is; several are combined in a MultiSearcher.
+func NewSearcher(names []string, cfg Config) (Searcher, error) {
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+
+	var engines []Engine
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case EngineSearXNG:
+			client := NewSearXNGClient(cfg.SearXURL)
+			client.Retry = cfg.Retry
+			client.HTTPClient.Transport = cfg.Transport
+			client.PageClient.Transport = cfg.FetchTransport
+			client.Logger = cfg.Logger
+			client.Extractors = cfg.Extractors
+			engines = append(engines, Engine{Name: name, Searcher: client})
+		case EngineBrave:
+			if cfg.BraveAPIKey == "" {
+				return nil, fmt.Errorf("the brave engine needs an API key")
+			}
+			client := NewBraveClient(cfg.BraveAPIKey)
+			client.Retry = cfg.Retry
+			client.HTTPClient.Transport = cfg.Transport
+			engines = append(engines, Engine{Name: name, Searcher: client})
+		case EngineDuckDuckGo:
+			client := NewDuckDuckGoClient()
+			client.Retry = cfg.Retry
+			client.HTTPClient.Transport = cfg.Transport
+			engines = append(engines, Engine{Name: name, Searcher: client})
+		case EngineGoogle:
+			if cfg.GoogleKey == "" || cfg.GoogleCX == "" {
+				return nil, fmt.Errorf("the google engine needs an API key and a search engine ID (cx)")
+			}
+			client := NewGoogleCSEClient(cfg.GoogleKey, cfg.GoogleCX)
+			client.Retry = cfg.Retry
+			client.HTTPClient.Transport = cfg.Transport
+			engines = append(engines, Engine{Name: name, Searcher: client})
+		default:
+			return nil, fmt.Errorf("unknown search engine %q (supported: %s, %s, %s, %s)", name, EngineSearXNG, EngineBrave, EngineDuckDuckGo, EngineGoogle)
+		}
+	}
+
+	switch len(engines) {
+	case 0:
+		return nil, fmt.Errorf("no search engines configured")
+	case 1:
+		return engines[0].Searcher, nil
+	default:
+		return NewMultiSearcher(engines...), nil
+	}
+}
+
+// Engine is a named search backend used by MultiSearcher
+type Engine struct {
+	Name     string
+	Searcher Searcher
+}
+
+// MultiSearcher fans each query out to several engines concurrently and merges
+// the results, deduplicating by URL and tagging each result with its engine(s).
+// Page fetching and link extraction (deep mode) are delegated to the first
+// engine that supports them.
+type MultiSearcher struct {
+	Engines []Engine
+}
+
+// NewMultiSearcher creates a searcher that aggregates the given engines
+func NewMultiSearcher(engines ...Engine) *MultiSearcher {
+	return &MultiSearcher{Engines: engines}
+}
+
+// Search queries every engine (page 1)
+func (m *MultiSearcher) Search(ctx context.Context, query string) ([]Result, error) {
+	return m.SearchWithPage(ctx, query, 1)
+}
+
+// SearchWithPage queries every engine concurrently. It only fails when all engines
+// fail; results are interleaved by rank so each engine's best hits come first.
+func (m *MultiSearcher) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
+	perEngine := make([][]Result, len(m.Engines))
+	errs := make([]error, len(m.Engines))
+
+	var wg sync.WaitGroup
+	for i, engine := range m.Engines {
+		wg.Add(1)
+		go func(i int, engine Engine) {
+			defer wg.Done()
+			results, err := engine.Searcher.SearchWithPage(ctx, query, page)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", engine.Name, err)
+				return
+			}
+			for j := range results {
+				results[j].Engine = engine.Name
+			}
+			perEngine[i] = results
+		}(i, engine)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == len(m.Engines) {
+		return nil, errors.Join(errs...)
+	}
+
+	// Interleave by rank, merging duplicates into the first occurrence
+	var merged []Result
+	index := make(map[string]int)
+	for rank := 0; ; rank++ {
+		added := false
+		for _, results := range perEngine {
+			if rank >= len(results) {
+				continue
+			}
+			added = true
+			r := results[rank]
+			key := dedupKey(r.URL)
+			if i, ok := index[key]; ok {
+				merged[i].Engine += "," + r.Engine
+				if len(r.Content) > len(merged[i].Content) {
+					merged[i].Content = r.Content
+				}
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, r)
+		}
+		if !added {
+			break
+		}
+	}
+	return merged, nil
+}
+
+// FetchPageContent delegates to the first engine that can fetch pages
+func (m *MultiSearcher) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	for _, engine := range m.Engines {
+		if fetcher, ok := engine.Searcher.(ContentFetcher); ok {
+			return fetcher.FetchPageContent(ctx, pageURL, maxLength)
+		}
+	}
+	return "", errNoFetcher
+}
+
+// ExtractListingLinks delegates to the first engine that can extract listing links
+func (m *MultiSearcher) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	for _, engine := range m.Engines {
+		if extractor, ok := engine.Searcher.(LinkExtractor); ok {
+			return extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+		}
+	}
+	return nil, errNoLinkExtractor
+}
+
+// SitemapURLs delegates to the first engine that can read sitemaps
+func (m *MultiSearcher) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	for _, engine := range m.Engines {
+		if reader, ok := engine.Searcher.(SitemapReader); ok {
+			return reader.SitemapURLs(ctx, site, maxURLs)
+		}
+	}
+	return nil, errNoSitemapReader
+}
+
+// dedupKey normalizes a URL for cross-engine deduplication (scheme, "www.", fragment, trailing slash)
+func dedupKey(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
+	path := strings.TrimSuffix(parsed.Path, "/")
+	key := host + path
+	if parsed.RawQuery != "" {
+		key += "?" + parsed.RawQuery
+	}
+	return key
+}
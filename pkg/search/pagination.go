@@ -0,0 +1,103 @@
+package search
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// nextLabels are (lowercased) texts and labels of "next page" links
+	nextLabels = []string{"next", "next page", "more", "older", "load more", "›", "»", ">", "→", "următoarea", "urmatoarea", "înainte", "inainte", "weiter", "suivant", "siguiente", "avanti", "następna", "volgende", "próxima", "proxima"}
+
+	// paginationRe matches the page number of a listing page's URL: the
+	// parameter (if any) and the number
+	paginationRe = regexp.MustCompile(`(?i)(?:[?&](page|pagina|pag|pg|p|offset|start)=(\d+)|/(?:page|pagina|p)/(\d+)/?$|/page-(\d+))`)
+
+	// pagePathRe matches the page number at the end of a listing page's path
+	pagePathRe = regexp.MustCompile(`(?i)(?:/(?:page|pagina|p)/\d+/?|/page-\d+)$`)
+)
+
+// pageParams are the query parameters paginationRe reads page numbers from
+var pageParams = []string{"page", "pagina", "pag", "pg", "p", "offset", "start"}
+
+// isNextLink reports whether a link's rel, aria-label, or text marks it as the next page
+func isNextLink(rel, label, text string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		if r == "next" {
+			return true
+		}
+	}
+	label = strings.ToLower(strings.TrimSpace(label))
+	if strings.HasPrefix(label, "next") {
+		return true
+	}
+	return slices.Contains(nextLabels, text) || slices.Contains(nextLabels, label)
+}
+
+// nextPage picks the next page of the listing at pageURL: the first link marked
+// as next, else the pagination link of the same listing with the lowest page
+// number above pageURL's (page=3 on page 2, offset=40 at offset 20). Returns ""
+// when there is none.
+func nextPage(pageURL string, marked, links []string) string {
+	if len(marked) > 0 {
+		return marked[0]
+	}
+	listing := listingKey(pageURL)
+	current, offset, numbered := pageNumber(pageURL)
+	best, bestNumber := "", 0
+	for _, link := range links {
+		n, linkOffset, ok := pageNumber(link)
+		if !ok || listingKey(link) != listing {
+			continue
+		}
+		from := current
+		if !numbered || linkOffset != offset {
+			from = 1 // An unnumbered page is the listing's first
+			if linkOffset {
+				from = 0
+			}
+		}
+		if n > from && (best == "" || n < bestNumber) {
+			best, bestNumber = link, n
+		}
+	}
+	return best
+}
+
+// pageNumber reads a listing page's number from its URL; offset reports
+// whether it counts items (offset=, start=) rather than pages
+func pageNumber(link string) (n int, offset, ok bool) {
+	m := paginationRe.FindStringSubmatch(link)
+	if m == nil {
+		return 0, false, false
+	}
+	for _, digits := range m[2:] {
+		if digits != "" {
+			n, _ = strconv.Atoi(digits)
+		}
+	}
+	param := strings.ToLower(m[1])
+	return n, param == "offset" || param == "start", true
+}
+
+// listingKey is a page's URL without its page number, the same for every page
+// of a listing
+func listingKey(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	query := u.Query()
+	for param := range query {
+		if slices.Contains(pageParams, strings.ToLower(param)) {
+			query.Del(param)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.Path = strings.TrimSuffix(pagePathRe.ReplaceAllString(u.Path, ""), "/")
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,163 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig configures page-fetch rate limiting
+type RateLimitConfig struct {
+	PerHost     float64 // Fetches per second allowed to each host (0 = unlimited)
+	Burst       int     // Fetches a host may receive back-to-back after being idle (default 1)
+	Concurrency int     // Max fetches in flight across all hosts (0 = unlimited)
+}
+
+// RateLimitedSearcher wraps a Searcher so deep-mode page fetches and listing-link
+// extraction are throttled per host with a token bucket, plus a global cap on
+// concurrent fetches. A slow or throttled site only delays fetches to that site.
+// Searches themselves are not limited.
+type RateLimitedSearcher struct {
+	Searcher
+	config  RateLimitConfig
+	slots   chan struct{} // nil when concurrency is unlimited
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket tracks one host's available fetches
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimitedSearcher wraps s with per-host rate limiting
+func NewRateLimitedSearcher(s Searcher, cfg RateLimitConfig) *RateLimitedSearcher {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	r := &RateLimitedSearcher{
+		Searcher: s,
+		config:   cfg,
+		buckets:  make(map[string]*tokenBucket),
+	}
+	if cfg.Concurrency > 0 {
+		r.slots = make(chan struct{}, cfg.Concurrency)
+	}
+	return r
+}
+
+// FetchPageContent fetches the page once its host has a free token and a global slot is available
+func (r *RateLimitedSearcher) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	fetcher, ok := r.Searcher.(ContentFetcher)
+	if !ok {
+		return "", errNoFetcher
+	}
+	release, err := r.acquire(ctx, pageURL)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return fetcher.FetchPageContent(ctx, pageURL, maxLength)
+}
+
+// ExtractListingLinks extracts links once the page's host has a free token and a global slot is available
+func (r *RateLimitedSearcher) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	extractor, ok := r.Searcher.(LinkExtractor)
+	if !ok {
+		return nil, errNoLinkExtractor
+	}
+	release, err := r.acquire(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+}
+
+// SitemapURLs reads the site's sitemaps once its host has a free token and a global slot is available
+func (r *RateLimitedSearcher) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	reader, ok := r.Searcher.(SitemapReader)
+	if !ok {
+		return nil, errNoSitemapReader
+	}
+	host := site
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	release, err := r.acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return reader.SitemapURLs(ctx, site, maxURLs)
+}
+
+// acquire waits for a token from the page's host, then for a global slot.
+// Waiting for a token doesn't hold a slot, so other hosts keep being fetched.
+func (r *RateLimitedSearcher) acquire(ctx context.Context, pageURL string) (func(), error) {
+	if err := r.waitForHost(ctx, hostKey(pageURL)); err != nil {
+		return nil, err
+	}
+	if r.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case r.slots <- struct{}{}:
+		return func() { <-r.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// waitForHost takes a token from host's bucket, sleeping until one is available
+func (r *RateLimitedSearcher) waitForHost(ctx context.Context, host string) error {
+	rate := r.config.PerHost
+	if rate <= 0 {
+		return nil
+	}
+	burst := float64(r.config.Burst)
+
+	r.mu.Lock()
+	now := time.Now()
+	b, ok := r.buckets[host]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		r.buckets[host] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	b.tokens-- // Reserve a token; a negative balance is the queue of waiting fetches
+	wait := time.Duration(0)
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / rate * float64(time.Second))
+	}
+	r.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the reserved token back
+		r.mu.Lock()
+		b.tokens++
+		r.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// hostKey returns the host a URL's fetch is throttled under ("www." is ignored)
+func hostKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
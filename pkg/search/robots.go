@@ -0,0 +1,361 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/logging"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowedByRobots is returned (wrapped) for pages a site's robots.txt doesn't let us fetch
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// maxRobotsSize is how much of a robots.txt is read (RFC 9309 asks for at least 500 KiB)
+const maxRobotsSize = 512 * 1024
+
+// RobotsConfig configures robots.txt compliance
+type RobotsConfig struct {
+	UserAgent     string            // Product token matched against User-agent lines (default "deep-research")
+	MaxCrawlDelay time.Duration     // Longest Crawl-delay honored; longer ones are capped so one site can't stall a run (default 1m)
+	Timeout       time.Duration     // Timeout for fetching a robots.txt (default 10s)
+	Transport     http.RoundTripper // Transport for robots.txt fetches, e.g. through a proxy (nil = http.DefaultTransport)
+	Logger        *slog.Logger      // Where skipped pages are reported (nil = console on stdout)
+}
+
+// RobotsSearcher wraps a Searcher so deep-mode page fetches and listing-link
+// extraction honor each site's robots.txt: pages under a Disallow rule are not
+// fetched, and fetches to a site are spaced by its Crawl-delay. Each robots.txt
+// is fetched once and kept for the searcher's lifetime. A missing robots.txt (4xx)
+// or one that can't be reached allows everything; a server error (5xx) disallows
+// the whole site, as RFC 9309 asks. Searches themselves are not affected.
+type RobotsSearcher struct {
+	Searcher
+	config RobotsConfig
+	client *http.Client
+	mu     sync.Mutex
+	robots map[string]*robotsEntry // By origin (scheme://host)
+	next   map[string]time.Time    // When each origin's next fetch may start (Crawl-delay)
+}
+
+// robotsEntry is one origin's robots.txt; ready is closed once rules is set
+type robotsEntry struct {
+	ready chan struct{}
+	rules *robotsRules
+}
+
+// robotsRules are the rules of the robots.txt group that applies to us
+type robotsRules struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// NewRobotsSearcher wraps s with robots.txt compliance
+func NewRobotsSearcher(s Searcher, cfg RobotsConfig) *RobotsSearcher {
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "deep-research"
+	}
+	if cfg.MaxCrawlDelay <= 0 {
+		cfg.MaxCrawlDelay = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	return &RobotsSearcher{
+		Searcher: s,
+		config:   cfg,
+		client:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		robots:   make(map[string]*robotsEntry),
+		next:     make(map[string]time.Time),
+	}
+}
+
+// FetchPageContent fetches the page if robots.txt allows it, once its Crawl-delay has passed
+func (r *RobotsSearcher) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	fetcher, ok := r.Searcher.(ContentFetcher)
+	if !ok {
+		return "", errNoFetcher
+	}
+	if err := r.check(ctx, pageURL); err != nil {
+		return "", err
+	}
+	return fetcher.FetchPageContent(ctx, pageURL, maxLength)
+}
+
+// ExtractListingLinks extracts links if robots.txt allows fetching the page, once its Crawl-delay has passed
+func (r *RobotsSearcher) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	extractor, ok := r.Searcher.(LinkExtractor)
+	if !ok {
+		return nil, errNoLinkExtractor
+	}
+	if err := r.check(ctx, pageURL); err != nil {
+		return nil, err
+	}
+	return extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+}
+
+// SitemapURLs reads the site's sitemaps, which are meant for crawlers; the pages
+// they list are still checked against robots.txt when fetched
+func (r *RobotsSearcher) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	reader, ok := r.Searcher.(SitemapReader)
+	if !ok {
+		return nil, errNoSitemapReader
+	}
+	return reader.SitemapURLs(ctx, site, maxURLs)
+}
+
+// check returns ErrDisallowedByRobots for disallowed pages, else waits out the site's Crawl-delay
+func (r *RobotsSearcher) check(ctx context.Context, pageURL string) error {
+	parsed, err := url.Parse(pageURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil // Not something robots.txt covers; let the fetcher report it
+	}
+	origin := parsed.Scheme + "://" + strings.ToLower(parsed.Host)
+
+	rules, err := r.rules(ctx, origin)
+	if err != nil {
+		return err
+	}
+	if !rules.allows(parsed.EscapedPath(), parsed.RawQuery) {
+		r.config.Logger.Debug("🤖 Skipping page disallowed by robots.txt", "url", pageURL)
+		return fmt.Errorf("%s: %w", pageURL, ErrDisallowedByRobots)
+	}
+	return r.waitForCrawlDelay(ctx, origin, rules.crawlDelay)
+}
+
+// rules returns origin's robots.txt rules, fetching them on first use. Concurrent
+// callers wait for the one fetch; if that caller is cancelled, the next one retries.
+func (r *RobotsSearcher) rules(ctx context.Context, origin string) (*robotsRules, error) {
+	for {
+		r.mu.Lock()
+		entry, ok := r.robots[origin]
+		if !ok {
+			entry = &robotsEntry{ready: make(chan struct{})}
+			r.robots[origin] = entry
+			r.mu.Unlock()
+
+			rules := r.fetchRobots(ctx, origin)
+			if ctx.Err() != nil {
+				r.mu.Lock()
+				delete(r.robots, origin)
+				r.mu.Unlock()
+				close(entry.ready)
+				return nil, ctx.Err()
+			}
+			entry.rules = rules
+			close(entry.ready)
+			return rules, nil
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if entry.rules != nil {
+			return entry.rules, nil
+		}
+	}
+}
+
+// fetchRobots downloads and parses origin's robots.txt
+func (r *RobotsSearcher) fetchRobots(ctx context.Context, origin string) *robotsRules {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; "+r.config.UserAgent+")")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		r.config.Logger.Debug("🤖 robots.txt unreachable, allowing all pages", "origin", origin, "error", err)
+		return &robotsRules{}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		r.config.Logger.Warn("🤖 robots.txt returned a server error, skipping the site", "origin", origin, "status", resp.StatusCode)
+		return &robotsRules{disallow: []string{"/"}}
+	case resp.StatusCode >= 400:
+		return &robotsRules{}
+	case resp.StatusCode >= 300:
+		return &robotsRules{} // Redirects the client gave up following
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		return &robotsRules{}
+	}
+	rules := parseRobots(string(body), r.config.UserAgent)
+	if rules.crawlDelay > r.config.MaxCrawlDelay {
+		r.config.Logger.Warn("🤖 Capping long Crawl-delay", "origin", origin, "crawl_delay", rules.crawlDelay, "max", r.config.MaxCrawlDelay)
+		rules.crawlDelay = r.config.MaxCrawlDelay
+	}
+	if rules.crawlDelay > 0 {
+		r.config.Logger.Debug("🤖 Honoring Crawl-delay", "origin", origin, "crawl_delay", rules.crawlDelay)
+	}
+	return rules
+}
+
+// waitForCrawlDelay reserves origin's next fetch slot, sleeping until it comes up
+func (r *RobotsSearcher) waitForCrawlDelay(ctx context.Context, origin string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	start := now
+	if next := r.next[origin]; next.After(now) {
+		start = next
+	}
+	r.next[origin] = start.Add(delay)
+	r.mu.Unlock()
+
+	wait := start.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRobots returns the rules of the group for userAgent, or of the "*" group
+// if no group names it. Groups naming the same agent are merged.
+func parseRobots(body, userAgent string) *robotsRules {
+	agent := strings.ToLower(userAgent)
+	var own, wildcard robotsRules
+	var ownFound bool
+
+	var groupAgents []string
+	inRules := false // Whether the current group's rules have started (a User-agent line then opens a new group)
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if inRules {
+				groupAgents, inRules = nil, false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+			continue
+		}
+		if key != "allow" && key != "disallow" && key != "crawl-delay" {
+			continue // Sitemap and unknown lines don't end a group
+		}
+		inRules = true
+
+		var targets []*robotsRules
+		for _, a := range groupAgents {
+			switch {
+			case a == "*":
+				targets = append(targets, &wildcard)
+			case a != "" && strings.Contains(agent, a):
+				targets = append(targets, &own)
+				ownFound = true
+			}
+		}
+		for _, t := range targets {
+			switch key {
+			case "allow":
+				if value != "" {
+					t.allow = append(t.allow, value)
+				}
+			case "disallow":
+				if value != "" {
+					t.disallow = append(t.disallow, value)
+				}
+			case "crawl-delay":
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					t.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if ownFound {
+		return &own
+	}
+	return &wildcard
+}
+
+// allows reports whether a path (and query) may be fetched: the longest matching
+// rule wins, and Allow wins a tie
+func (r *robotsRules) allows(path, query string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if query != "" {
+		path += "?" + query
+	}
+
+	longestDisallow := -1
+	for _, pattern := range r.disallow {
+		if len(pattern) > longestDisallow && robotsMatch(pattern, path) {
+			longestDisallow = len(pattern)
+		}
+	}
+	if longestDisallow < 0 {
+		return true
+	}
+	for _, pattern := range r.allow {
+		if len(pattern) >= longestDisallow && robotsMatch(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// robotsMatch matches a robots.txt path pattern against a path: patterns match
+// path prefixes, "*" matches any run of characters, and a trailing "$" anchors the end
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	if len(parts) == 1 {
+		return !anchored || pos == len(path)
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return len(path)-pos >= len(last) && strings.HasSuffix(path, last)
+	}
+	return strings.Contains(path[pos:], last)
+}
@@ -1,20 +1,36 @@
 package search
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
 // Result represents a single search result
 type Result struct {
 	Title       string
 	URL         string
 	Content     string
-	FullContent string // Fetched page content (if available)
+	FullContent string    // Fetched page content (if available)
+	Engine      string    // Engine(s) that returned the result, e.g. "searxng,brave" (set by MultiSearcher)
+	Published   time.Time // Publication date reported by the engine (zero = unknown)
+	Category    string    // SearXNG category the result came from, e.g. CategoryNews or CategoryImages (empty = unknown)
+	ImageURL    string    // Image results: the image itself (URL is the page showing it, Title its alt text)
 }
 
 // Searcher is the interface for search engines
 type Searcher interface {
-	Search(query string) ([]Result, error)
-	SearchWithPage(query string, page int) ([]Result, error) // Paginated search
+	Search(ctx context.Context, query string) ([]Result, error)
+	SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) // Paginated search
 }
 
 // ContentFetcher is an interface for fetching page content
 type ContentFetcher interface {
-	FetchPageContent(url string, maxLength int) (string, error)
+	FetchPageContent(ctx context.Context, url string, maxLength int) (string, error)
 }
+
+// Errors returned by wrappers whose underlying searcher can't fetch pages or extract links
+var (
+	errNoFetcher       = errors.New("no configured search engine supports page fetching")
+	errNoLinkExtractor = errors.New("no configured search engine supports link extraction")
+)
@@ -1,65 +1,186 @@
 package search
 
 import (
+	"context"
+	"deep-research/pkg/logging"
+	"deep-research/pkg/pdf"
+	"deep-research/pkg/retry"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
-// SearXNGClient implements the Searcher interface for SearXNG
+// DefaultSearXNGCooldown is how long an instance that rate-limits or blocks searches is skipped
+const DefaultSearXNGCooldown = 5 * time.Minute
+
+// SearXNGClient implements the Searcher interface for SearXNG. Searches rotate
+// across the instances in BaseURL; one answering 429, 503, or 403 is skipped
+// for the cooldown (or its Retry-After, if longer) and the search moves on to
+// the next. A single instance, and any site whose pages answer 429 or 503, is
+// paused for its Retry-After before the request is tried again.
 type SearXNGClient struct {
-	BaseURL    string
-	HTTPClient *http.Client
+	BaseURL    string        // SearXNG base URL, or several comma-separated ones
+	HTTPClient *http.Client  // Client for searches
+	PageClient *http.Client  // Client for page fetches
+	Retry      retry.Policy  // Retry policy for searches and page fetches
+	Categories []string      // SearXNG categories to search, e.g. news (empty = instance default)
+	Engines    []string      // SearXNG engines to query, e.g. google, bing (empty = instance default)
+	TimeRange  string        // Only results from the last day, week, month, or year (empty = any time)
+	Cooldown   time.Duration // How long an instance answering 429, 503, or 403 is skipped (default DefaultSearXNGCooldown)
+	Logger     *slog.Logger  // Where instance cooldowns are reported (nil = console on stdout)
+	Extractors []Extractor   // Site rules for listing links and detail fields (see LoadExtractors)
+
+	mu        sync.Mutex
+	next      int                  // Rotation position of the next search
+	coolUntil map[string]time.Time // Instances skipped until the given time
+	paused    hostPauses           // Instances and page hosts that asked to be left alone for a while
 }
 
-// NewSearXNGClient creates a new SearXNG client
+// NewSearXNGClient creates a new SearXNG client for one or more comma-separated base URLs
 func NewSearXNGClient(baseURL string) *SearXNGClient {
 	return &SearXNGClient{
 		BaseURL: baseURL,
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		PageClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		Retry: retry.DefaultPolicy(),
+	}
+}
+
+// SearXNGInstances splits a comma-separated list of SearXNG base URLs, dropping
+// blanks and trailing slashes
+func SearXNGInstances(baseURL string) []string {
+	var instances []string
+	for _, u := range strings.Split(baseURL, ",") {
+		if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+			instances = append(instances, u)
+		}
 	}
+	return instances
 }
 
 type searxngResponse struct {
 	Results []struct {
-		Title   string `json:"title"`
-		URL     string `json:"url"`
-		Content string `json:"content"`
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		Content       string `json:"content"`
+		PublishedDate string `json:"publishedDate"` // null, or e.g. "2024-03-15T00:00:00"
+		Category      string `json:"category"`      // e.g. "news" or "images"
+
+		// Image results
+		ImgSrc       string `json:"img_src"`
+		ThumbnailSrc string `json:"thumbnail_src"`
+		Pubdate      string `json:"pubdate"` // Some news engines' date field instead of publishedDate
 	} `json:"results"`
 }
 
 // Search performs a search on SearXNG (page 1)
-func (s *SearXNGClient) Search(query string) ([]Result, error) {
-	return s.SearchWithPage(query, 1)
+func (s *SearXNGClient) Search(ctx context.Context, query string) ([]Result, error) {
+	return s.SearchWithPage(ctx, query, 1)
 }
 
-// SearchWithPage performs a paginated search on SearXNG
-func (s *SearXNGClient) SearchWithPage(query string, page int) ([]Result, error) {
+// SearchWithPage performs a paginated search on SearXNG. Filters attached to
+// ctx (WithFilters) take precedence over the client's own.
+func (s *SearXNGClient) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
 	params := url.Values{}
 	params.Add("q", query)
 	params.Add("format", "json")
 	if page > 1 {
 		params.Add("pageno", fmt.Sprintf("%d", page))
 	}
+
+	filters := FiltersFromContext(ctx)
+	if len(filters.Categories) == 0 {
+		filters.Categories = s.Categories
+	}
+	if len(filters.Engines) == 0 {
+		filters.Engines = s.Engines
+	}
+	if filters.TimeRange == "" {
+		filters.TimeRange = s.TimeRange
+	}
+	if len(filters.Categories) > 0 {
+		params.Add("categories", strings.Join(filters.Categories, ","))
+	}
+	if len(filters.Engines) > 0 {
+		params.Add("engines", strings.Join(filters.Engines, ","))
+	}
+	if filters.TimeRange != "" {
+		params.Add("time_range", filters.TimeRange)
+	}
 	// params.Add("language", "en") // Remove language restriction to allow local results
 
-	u := fmt.Sprintf("%s/search?%s", s.BaseURL, params.Encode())
+	var sResp searxngResponse
+	err := s.Retry.Do(ctx, func() error {
+		// Fail over to the next instance while they throttle; with all of them
+		// cooling down, the retry policy's backoff decides when to try again
+		var err error
+		for _, instance := range s.instancesToTry() {
+			sResp, err = s.query(ctx, instance, params)
+			var statusErr *retry.StatusError
+			if !errors.As(err, &statusErr) || (!retry.IsThrottled(err) && statusErr.StatusCode != http.StatusForbidden) {
+				return err
+			}
+			s.paused.throttled(instance, err, s.Retry)
+			s.coolDown(instance, statusErr.StatusCode, statusErr.RetryAfter)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequest("GET", u, nil) // SearXNG usually supports GET for JSON
+	var results []Result
+	for _, r := range sResp.Results {
+		published := ParseDate(r.PublishedDate)
+		if published.IsZero() {
+			published = ParseDate(r.Pubdate)
+		}
+		image := r.ImgSrc
+		if image == "" {
+			image = r.ThumbnailSrc
+		}
+		if strings.HasPrefix(image, "//") {
+			image = "https:" + image
+		}
+		results = append(results, Result{
+			Title:     r.Title,
+			URL:       r.URL,
+			Content:   r.Content,
+			Published: published,
+			Category:  r.Category,
+			ImageURL:  image,
+		})
+	}
+
+	return results, nil
+}
+
+// query runs one search request against a SearXNG instance
+func (s *SearXNGClient) query(ctx context.Context, instance string, params url.Values) (searxngResponse, error) {
+	if err := s.paused.wait(ctx, instance); err != nil {
+		return searxngResponse{}, err
+	}
+	u := fmt.Sprintf("%s/search?%s", instance, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil) // SearXNG usually supports GET for JSON
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return searxngResponse{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// User-Agent is often required
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	
+
 	// Fix for 403 Forbidden: SearXNG bot detection requires X-Forwarded-For or X-Real-IP
 	// when running behind a proxy or in certain Docker configurations.
 	// Since we are calling it locally, we can set it to localhost.
@@ -68,65 +189,100 @@ func (s *SearXNGClient) SearchWithPage(query string, page int) ([]Result, error)
 
 	resp, err := s.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return searxngResponse{}, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("searxng returned status %d", resp.StatusCode)
+		return searxngResponse{}, retry.ResponseError(resp, "searxng returned status %d", resp.StatusCode)
 	}
 
-	// Debug: Print raw response if needed (commented out)
-	// bodyBytes, _ := io.ReadAll(resp.Body)
-	// fmt.Println(string(bodyBytes))
-	// resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
 	var sResp searxngResponse
 	if err := json.NewDecoder(resp.Body).Decode(&sResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return searxngResponse{}, fmt.Errorf("failed to decode response: %w", err)
 	}
+	return sResp, nil
+}
 
-	var results []Result
-	for _, r := range sResp.Results {
-		results = append(results, Result{
-			Title:   r.Title,
-			URL:     r.URL,
-			Content: r.Content,
-		})
+// instancesToTry returns the instances a search may use, in order: those not
+// cooling down, starting one further along the rotation on every search, else
+// the one whose cooldown ends first (so a single instance is never left out)
+func (s *SearXNGClient) instancesToTry() []string {
+	instances := SearXNGInstances(s.BaseURL)
+	if len(instances) <= 1 {
+		return instances
 	}
 
-	return results, nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := s.next % len(instances)
+	s.next++
+
+	now := time.Now()
+	var available []string
+	soonest := ""
+	for i := range instances {
+		instance := instances[(start+i)%len(instances)]
+		until, cooling := s.coolUntil[instance]
+		if !cooling || now.After(until) {
+			available = append(available, instance)
+		} else if soonest == "" || until.Before(s.coolUntil[soonest]) {
+			soonest = instance
+		}
+	}
+	if len(available) == 0 {
+		return []string{soonest}
+	}
+	return available
 }
 
-// FetchPageContent fetches and extracts text content from a URL
-func (s *SearXNGClient) FetchPageContent(pageURL string, maxLength int) (string, error) {
-	req, err := http.NewRequest("GET", pageURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+// coolDown skips a throttling instance for the cooldown, or as long as it
+// asked if that's longer
+func (s *SearXNGClient) coolDown(instance string, status int, retryAfter time.Duration) {
+	if len(SearXNGInstances(s.BaseURL)) <= 1 {
+		return
 	}
+	cooldown := s.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultSearXNGCooldown
+	}
+	cooldown = max(cooldown, retryAfter)
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9,ro;q=0.8")
-
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch page: %w", err)
+	s.mu.Lock()
+	if s.coolUntil == nil {
+		s.coolUntil = make(map[string]time.Time)
 	}
-	defer resp.Body.Close()
+	s.coolUntil[instance] = time.Now().Add(cooldown)
+	s.mu.Unlock()
+	s.logger().Warn("⏸️ SearXNG instance is throttling; skipping it", "instance", instance, "status", status, "for", cooldown)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("page returned status %d", resp.StatusCode)
+// logger returns the configured logger, or the default console logger
+func (s *SearXNGClient) logger() *slog.Logger {
+	if s.Logger == nil {
+		return logging.Default()
 	}
+	return s.Logger
+}
 
-	body, err := io.ReadAll(resp.Body)
+// FetchPageContent fetches and extracts text content from a URL (HTML pages or PDFs)
+func (s *SearXNGClient) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	body, contentType, err := s.fetchPage(ctx, pageURL, "en-US,en;q=0.9,ro;q=0.8")
 	if err != nil {
-		return "", fmt.Errorf("failed to read body: %w", err)
+		return "", err
 	}
+	recordBody(ctx, body, contentType)
 
-	// Extract text from HTML (simple approach)
-	text := extractTextFromHTML(string(body))
+	var text string
+	if pdf.Detect(contentType, body) {
+		if text, err = pdf.ExtractText(body, maxLength); err != nil {
+			return "", err
+		}
+		recordPublished(ctx, ExtractPublished(pageURL, ""))
+	} else {
+		text = pageText(s.Extractors, pageURL, string(body))
+		recordPublished(ctx, ExtractPublished(pageURL, string(body)))
+	}
 	
 	// Truncate if too long
 	if maxLength > 0 && len(text) > maxLength {
@@ -136,36 +292,58 @@ func (s *SearXNGClient) FetchPageContent(pageURL string, maxLength int) (string,
 	return text, nil
 }
 
-// extractTextFromHTML removes HTML tags and extracts readable text
-func extractTextFromHTML(html string) string {
-	// Remove script and style tags with their content
-	scriptRe := regexp.MustCompile(`(?is)<script.*?</script>`)
-	html = scriptRe.ReplaceAllString(html, "")
-	
-	styleRe := regexp.MustCompile(`(?is)<style.*?</style>`)
-	html = styleRe.ReplaceAllString(html, "")
-	
-	// Remove HTML comments
-	commentRe := regexp.MustCompile(`(?s)<!--.*?-->`)
-	html = commentRe.ReplaceAllString(html, "")
-	
-	// Remove all HTML tags
-	tagRe := regexp.MustCompile(`<[^>]*>`)
-	text := tagRe.ReplaceAllString(html, " ")
-	
-	// Decode common HTML entities
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	text = strings.ReplaceAll(text, "&lt;", "<")
-	text = strings.ReplaceAll(text, "&gt;", ">")
-	text = strings.ReplaceAll(text, "&quot;", "\"")
-	text = strings.ReplaceAll(text, "&#39;", "'")
-	
-	// Collapse multiple whitespace into single space
-	spaceRe := regexp.MustCompile(`\s+`)
-	text = spaceRe.ReplaceAllString(text, " ")
-	
-	return strings.TrimSpace(text)
+// maxPageSize caps a page download (HTML, PDF, or sitemap), so one huge file
+// can't exhaust memory
+const maxPageSize = 32 << 20
+
+// fetchPage downloads a web page and returns it with its content type, retrying
+// transient failures per the configured policy. Pages over maxPageSize fail.
+func (s *SearXNGClient) fetchPage(ctx context.Context, pageURL, acceptLanguage string) ([]byte, string, error) {
+	var body []byte
+	var contentType string
+	host := hostKey(pageURL)
+	err := s.Retry.Do(ctx, func() error {
+		// Wait out a 429/503 from this site, whichever request got it
+		if err := s.paused.wait(ctx, host); err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf;q=0.9")
+		req.Header.Set("Accept-Language", acceptLanguage)
+
+		resp, err := s.PageClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch page: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			err := retry.ResponseError(resp, "page returned status %d", resp.StatusCode)
+			if pause := s.paused.throttled(host, err, s.Retry); pause > 0 {
+				s.logger().Debug("⏸️ Site is throttling; pausing its fetches", "host", host, "status", resp.StatusCode, "for", pause)
+			}
+			return err
+		}
+
+		if resp.ContentLength > maxPageSize {
+			return fmt.Errorf("page is too large (%d bytes, limit %d)", resp.ContentLength, maxPageSize)
+		}
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxPageSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
+		if len(body) > maxPageSize {
+			return fmt.Errorf("page is larger than the %d MB limit", maxPageSize>>20)
+		}
+		contentType = resp.Header.Get("Content-Type")
+		return nil
+	})
+	return body, contentType, err
 }
 
 // ListingLink represents an individual item link extracted from an index page
@@ -176,34 +354,16 @@ type ListingLink struct {
 
 // ExtractListingLinks extracts individual item URLs from an index/category page
 // Uses generic patterns to find links that look like individual item pages (not category pages)
-func (s *SearXNGClient) ExtractListingLinks(pageURL string, maxLinks int) ([]ListingLink, error) {
-	req, err := http.NewRequest("GET", pageURL, nil)
+func (s *SearXNGClient) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	body, _, err := s.fetchPage(ctx, pageURL, "en-US,en;q=0.9")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch page: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("page returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
+		return nil, err
 	}
+	return listingLinks(s.Extractors, pageURL, string(body), maxLinks), nil
+}
 
-	html := string(body)
-	
+// extractListingLinks finds links in a page's HTML that look like individual item pages
+func extractListingLinks(pageURL, html string, maxLinks int) []ListingLink {
 	// Extract base URL for resolving relative links
 	parsedURL, _ := url.Parse(pageURL)
 	baseURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
@@ -268,12 +428,12 @@ func (s *SearXNGClient) ExtractListingLinks(pageURL string, maxLinks int) ([]Lis
 			links = append(links, ListingLink{URL: fullURL, Title: title})
 			
 			if len(links) >= maxLinks {
-				return links, nil
+				return links
 			}
 		}
 	}
 	
-	return links, nil
+	return links
 }
 
 // isLikelyCategoryPage checks if a URL looks like a category/search page rather than an item page
@@ -330,5 +490,5 @@ func extractTitleFromURL(listingURL string) string {
 
 // LinkExtractor interface for extracting listing links
 type LinkExtractor interface {
-	ExtractListingLinks(pageURL string, maxLinks int) ([]ListingLink, error)
+	ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error)
 }
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SitemapURL is a page listed in a site's sitemap
+type SitemapURL struct {
+	URL     string
+	LastMod time.Time // When the page last changed, per the sitemap (zero = not given)
+}
+
+// SitemapReader is an interface for listing a site's pages from its sitemaps
+type SitemapReader interface {
+	SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error)
+}
+
+var errNoSitemapReader = errors.New("no configured search engine supports reading sitemaps")
+
+const (
+	maxSitemaps    = 50       // Sitemap files (indexes included) read per site
+	maxSitemapSize = 50 << 20 // The protocol's limit for an uncompressed sitemap
+)
+
+// SitemapURLs lists the pages in a site's sitemaps, up to maxURLs (0 = no limit).
+// site is a domain, a site URL, or the URL of a sitemap. The sitemaps are the
+// ones robots.txt names, else /sitemap.xml; sitemap indexes are followed, and
+// gzipped and plain-text sitemaps are read too.
+func (s *SearXNGClient) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	queue, err := s.sitemapLocations(ctx, site)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []SitemapURL
+	var firstErr error
+	seen := make(map[string]bool)
+	for read := 0; len(queue) > 0 && read < maxSitemaps && (maxURLs <= 0 || len(urls) < maxURLs); {
+		loc := queue[0]
+		queue = queue[1:]
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		read++
+
+		body, _, err := s.fetchPage(ctx, loc, "en-US,en;q=0.9")
+		if err == nil {
+			var pages []SitemapURL
+			var sitemaps []string
+			if pages, sitemaps, err = parseSitemap(body); err == nil {
+				urls = append(urls, pages...)
+				queue = append(queue, sitemaps...)
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("sitemap %s: %w", loc, err)
+		}
+	}
+
+	if len(urls) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, fmt.Errorf("no pages listed in the sitemaps of %s", site)
+	}
+	if maxURLs > 0 && len(urls) > maxURLs {
+		urls = urls[:maxURLs]
+	}
+	return urls, nil
+}
+
+// sitemapLocations returns the sitemaps to start from: site itself when it is a
+// sitemap URL, else those named in the site's robots.txt, else /sitemap.xml
+func (s *SearXNGClient) sitemapLocations(ctx context.Context, site string) ([]string, error) {
+	site = strings.TrimSpace(site)
+	if !strings.Contains(site, "://") {
+		site = "https://" + site
+	}
+	parsed, err := url.Parse(site)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid site %q", site)
+	}
+	if path := strings.ToLower(parsed.Path); strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz") || strings.HasSuffix(path, ".txt") {
+		return []string{site}, nil
+	}
+
+	origin := parsed.Scheme + "://" + parsed.Host
+	if body, _, err := s.fetchPage(ctx, origin+"/robots.txt", "en-US,en;q=0.9"); err == nil {
+		if sitemaps := robotsSitemaps(body); len(sitemaps) > 0 {
+			return sitemaps, nil
+		}
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return []string{origin + "/sitemap.xml"}, nil
+}
+
+// robotsSitemaps returns the Sitemap: lines of a robots.txt
+func robotsSitemaps(robots []byte) []string {
+	var sitemaps []string
+	scanner := bufio.NewScanner(bytes.NewReader(robots))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			if value = strings.TrimSpace(value); value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+	}
+	return sitemaps
+}
+
+// sitemapXML is a <urlset> (pages) or a <sitemapindex> (more sitemaps)
+type sitemapXML struct {
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// parseSitemap returns the pages and the further sitemaps a sitemap lists
+func parseSitemap(data []byte) ([]SitemapURL, []string, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid gzip: %w", err)
+		}
+		if data, err = io.ReadAll(io.LimitReader(r, maxSitemapSize)); err != nil {
+			return nil, nil, fmt.Errorf("invalid gzip: %w", err)
+		}
+	}
+
+	var doc sitemapXML
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		// A plain-text sitemap lists one URL per line
+		var pages []SitemapURL
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+				pages = append(pages, SitemapURL{URL: line})
+			}
+		}
+		if len(pages) == 0 {
+			return nil, nil, fmt.Errorf("not a sitemap: %w", err)
+		}
+		return pages, nil, nil
+	}
+
+	pages := make([]SitemapURL, 0, len(doc.URLs))
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			pages = append(pages, SitemapURL{URL: loc, LastMod: ParseDate(u.LastMod)})
+		}
+	}
+	var sitemaps []string
+	for _, sm := range doc.Sitemaps {
+		if loc := strings.TrimSpace(sm.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+	return pages, sitemaps, nil
+}
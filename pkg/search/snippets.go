@@ -0,0 +1,305 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/logging"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// snippetHeadSize is how much of a page is read for its metadata; the <head>
+// nearly always fits
+const snippetHeadSize = 64 * 1024
+
+// SnippetConfig configures the enrichment of short search-result snippets
+type SnippetConfig struct {
+	MinLength int                  // Snippets shorter than this many characters are enriched (default 40)
+	Workers   int                  // Pages read at once per search (default 8)
+	Timeout   time.Duration        // Timeout for reading a page's metadata (default 5s)
+	Transport http.RoundTripper    // Transport for the page requests, e.g. through the fetch proxy (nil = http.DefaultTransport)
+	RateLimit *RateLimitedSearcher // Rate limiter whose per-host tokens and global slots the page requests wait for (nil = unlimited)
+	Logger    *slog.Logger         // Where enrichment is reported (nil = console on stdout)
+}
+
+// SnippetSearcher wraps a Searcher so results with a short or missing snippet
+// get one from their page's metadata: the start of the page (up to its <head>)
+// is downloaded and its OpenGraph, Twitter, or meta description becomes the
+// snippet, its og:title the missing title, and its publication date (as
+// ExtractPublished finds it) the missing Result.Published. Pages are read
+// concurrently and remembered for the searcher's lifetime; a page that can't be
+// read keeps its result as it was. When the wrapped searcher honors robots.txt,
+// so do the page reads; they wait for Config.RateLimit like page fetches, and
+// count against the context's request budget (see WithRequestBudget).
+type SnippetSearcher struct {
+	Searcher
+	config SnippetConfig
+	client *http.Client
+	mu     sync.Mutex
+	pages  map[string]pageMeta // By URL
+}
+
+// pageMeta is what a page's metadata says about it
+type pageMeta struct {
+	title       string
+	description string
+	published   time.Time
+}
+
+type requestBudgetKey struct{}
+
+// WithRequestBudget returns a context whose searches call spend before each
+// page request they make besides the search itself (SnippetSearcher's metadata
+// reads); when spend fails, the request isn't made
+func WithRequestBudget(ctx context.Context, spend func(context.Context) error) context.Context {
+	return context.WithValue(ctx, requestBudgetKey{}, spend)
+}
+
+// spendRequest counts a page request against the context's request budget, if any
+func spendRequest(ctx context.Context) error {
+	if spend, ok := ctx.Value(requestBudgetKey{}).(func(context.Context) error); ok {
+		return spend(ctx)
+	}
+	return nil
+}
+
+// pageChecker is implemented by searchers that vet page fetches (RobotsSearcher)
+type pageChecker interface {
+	check(ctx context.Context, pageURL string) error
+}
+
+// NewSnippetSearcher wraps s with snippet enrichment
+func NewSnippetSearcher(s Searcher, cfg SnippetConfig) *SnippetSearcher {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 40
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 8
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	return &SnippetSearcher{
+		Searcher: s,
+		config:   cfg,
+		client:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		pages:    make(map[string]pageMeta),
+	}
+}
+
+// Search searches and enriches the results' short snippets
+func (s *SnippetSearcher) Search(ctx context.Context, query string) ([]Result, error) {
+	results, err := s.Searcher.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.enrich(ctx, results), nil
+}
+
+// SearchWithPage searches a result page and enriches the results' short snippets
+func (s *SnippetSearcher) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
+	results, err := s.Searcher.SearchWithPage(ctx, query, page)
+	if err != nil {
+		return nil, err
+	}
+	return s.enrich(ctx, results), nil
+}
+
+// FetchPageContent fetches the page through the wrapped searcher
+func (s *SnippetSearcher) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	fetcher, ok := s.Searcher.(ContentFetcher)
+	if !ok {
+		return "", errNoFetcher
+	}
+	return fetcher.FetchPageContent(ctx, pageURL, maxLength)
+}
+
+// ExtractListingLinks extracts links through the wrapped searcher
+func (s *SnippetSearcher) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	extractor, ok := s.Searcher.(LinkExtractor)
+	if !ok {
+		return nil, errNoLinkExtractor
+	}
+	return extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+}
+
+// SitemapURLs reads the site's sitemaps through the wrapped searcher
+func (s *SnippetSearcher) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	reader, ok := s.Searcher.(SitemapReader)
+	if !ok {
+		return nil, errNoSitemapReader
+	}
+	return reader.SitemapURLs(ctx, site, maxURLs)
+}
+
+// enrich fills in the short snippets, missing titles, and missing dates of
+// results from their pages' metadata, Config.Workers pages at a time
+func (s *SnippetSearcher) enrich(ctx context.Context, results []Result) []Result {
+	var short []int
+	for i, r := range results {
+		if r.ImageURL == "" && (strings.HasPrefix(r.URL, "http://") || strings.HasPrefix(r.URL, "https://")) &&
+			utf8.RuneCountInString(strings.TrimSpace(r.Content)) < s.config.MinLength {
+			short = append(short, i)
+		}
+	}
+	if len(short) == 0 {
+		return results
+	}
+
+	metas := make([]pageMeta, len(short))
+	sem := make(chan struct{}, s.config.Workers)
+	var wg sync.WaitGroup
+	for n, i := range short {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			metas[n] = s.meta(ctx, results[i].URL)
+		}()
+	}
+	wg.Wait()
+
+	enriched := 0
+	for n, i := range short {
+		r, meta := &results[i], metas[n]
+		if len([]rune(meta.description)) > len([]rune(strings.TrimSpace(r.Content))) {
+			r.Content = meta.description
+			enriched++
+		}
+		if strings.TrimSpace(r.Title) == "" && meta.title != "" {
+			r.Title = meta.title
+		}
+		if r.Published.IsZero() {
+			r.Published = meta.published
+		}
+	}
+	if enriched > 0 {
+		s.config.Logger.Debug("📝 Enriched short snippets from page metadata", "enriched", enriched, "short", len(short))
+	}
+	return results
+}
+
+// meta returns the page's metadata, reading it on first use (zero when it
+// can't be read)
+func (s *SnippetSearcher) meta(ctx context.Context, pageURL string) pageMeta {
+	s.mu.Lock()
+	meta, ok := s.pages[pageURL]
+	s.mu.Unlock()
+	if ok {
+		return meta
+	}
+
+	meta, err := s.readMeta(ctx, pageURL)
+	if err != nil {
+		s.config.Logger.Debug("📝 Page metadata unavailable", "url", pageURL, "error", err)
+		if ctx.Err() != nil {
+			return pageMeta{} // Not remembered: the next search may read it
+		}
+	}
+	s.mu.Lock()
+	s.pages[pageURL] = meta
+	s.mu.Unlock()
+	return meta
+}
+
+// readMeta downloads the start of the page and reads its metadata
+func (s *SnippetSearcher) readMeta(ctx context.Context, pageURL string) (pageMeta, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	if checker, ok := s.Searcher.(pageChecker); ok {
+		if err := checker.check(ctx, pageURL); err != nil {
+			return pageMeta{}, err
+		}
+	}
+	if err := spendRequest(ctx); err != nil {
+		return pageMeta{}, err
+	}
+	if s.config.RateLimit != nil {
+		release, err := s.config.RateLimit.acquire(ctx, pageURL)
+		if err != nil {
+			return pageMeta{}, err
+		}
+		defer release()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return pageMeta{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", snippetHeadSize-1))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return pageMeta{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return pageMeta{}, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return pageMeta{}, fmt.Errorf("not HTML (%s)", mediaType)
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, snippetHeadSize))
+	if err != nil && len(head) == 0 {
+		return pageMeta{}, err
+	}
+	return parsePageMeta(pageURL, string(head)), nil
+}
+
+// parsePageMeta reads a page's title, description, and publication date from
+// its meta tags (OpenGraph first, then Twitter, then the plain ones)
+func parsePageMeta(pageURL, rawHTML string) pageMeta {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHTML))
+	if err != nil {
+		return pageMeta{}
+	}
+	found := make(map[string]string)
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		for _, attr := range []string{"property", "name"} {
+			if key, ok := s.Attr(attr); ok {
+				key = strings.ToLower(strings.TrimSpace(key))
+				if _, seen := found[key]; !seen {
+					content, _ := s.Attr("content")
+					found[key] = strings.Join(strings.Fields(content), " ")
+				}
+			}
+		}
+	})
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if v := found[key]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	meta := pageMeta{
+		title:       first("og:title", "twitter:title"),
+		description: first("og:description", "twitter:description", "description"),
+		published:   metaPublished(doc),
+	}
+	if meta.published.IsZero() {
+		meta.published = jsonLDPublished(doc)
+	}
+	if meta.published.IsZero() {
+		meta.published = urlPublished(pageURL)
+	}
+	if meta.title == "" {
+		meta.title = strings.Join(strings.Fields(doc.Find("title").First().Text()), " ")
+	}
+	return meta
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fixedSearcher returns the same results for every query
+type fixedSearcher []Result
+
+func (f fixedSearcher) Search(ctx context.Context, query string) ([]Result, error) {
+	return append([]Result(nil), f...), nil
+}
+
+func (f fixedSearcher) SearchWithPage(ctx context.Context, query string, page int) ([]Result, error) {
+	return f.Search(ctx, query)
+}
+
+// TestSnippetReadsAreLimitedAndBudgeted checks that the metadata reads wait for
+// the rate limiter's global slots and are counted against the request budget
+func TestSnippetReadsAreLimitedAndBudgeted(t *testing.T) {
+	var inFlight, maxInFlight, served atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+		}
+		served.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><meta property="og:description" content="A description long enough to replace the snippet of %s"></head></html>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	var results fixedSearcher
+	for i := range 4 {
+		results = append(results, Result{URL: fmt.Sprintf("%s/page%d", srv.URL, i)})
+	}
+	rateLimit := NewRateLimitedSearcher(results, RateLimitConfig{Concurrency: 1})
+	s := NewSnippetSearcher(rateLimit, SnippetConfig{RateLimit: rateLimit})
+
+	var mu sync.Mutex
+	budget := 3
+	ctx := WithRequestBudget(context.Background(), func(context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if budget == 0 {
+			return errors.New("budget exhausted")
+		}
+		budget--
+		return nil
+	})
+	got, err := s.Search(ctx, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := served.Load(); n != 3 {
+		t.Errorf("served %d page reads, want the budget's 3", n)
+	}
+	if n := maxInFlight.Load(); n != 1 {
+		t.Errorf("%d page reads at once, want the rate limiter's 1", n)
+	}
+	enriched := 0
+	for _, r := range got {
+		if r.Content != "" {
+			enriched++
+		}
+	}
+	if enriched != 3 {
+		t.Errorf("%d results enriched, want 3", enriched)
+	}
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"deep-research/pkg/logging"
+)
+
+// StackConfig configures NewStack: the engines, and the wrappers that make
+// their page fetches polite, enriched, cached, and resilient
+type StackConfig struct {
+	Engines      []string        // Engines to aggregate (empty = SearXNG only)
+	Search       Config          // The engines' settings; its FetchTransport and Logger serve the wrappers too
+	Browser      *BrowserConfig  // Render pages in headless Chrome (nil = plain HTTP fetches)
+	RateLimit    RateLimitConfig // Page-fetch limits per host and overall
+	IgnoreRobots bool            // Fetch pages robots.txt disallows and ignore Crawl-delay
+	NoEnrich     bool            // Don't fill in short search-result snippets from the pages' metadata
+	Cache        CacheConfig     // Cache of searches and pages (no Dir or TTL = none)
+	NoWayback    bool            // Don't fall back to Wayback Machine snapshots of dead pages
+}
+
+// NewStack creates the engines and wraps them, innermost first, in the browser
+// renderer, the rate limiter, robots.txt checks, snippet enrichment (whose page
+// reads wait for the same rate limiter), the cache, and the Wayback fallback. The CLI and the server both build their searcher
+// with it. A browser that can't start is reported and left out.
+func NewStack(cfg StackConfig) (Searcher, error) {
+	engines := cfg.Engines
+	if len(engines) == 0 {
+		engines = []string{EngineSearXNG}
+	}
+	logger := cfg.Search.Logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+
+	searcher, err := NewSearcher(engines, cfg.Search)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Browser != nil {
+		browserConfig := *cfg.Browser
+		if browserConfig.Extractors == nil {
+			browserConfig.Extractors = cfg.Search.Extractors
+		}
+		if browserConfig.Logger == nil {
+			browserConfig.Logger = cfg.Search.Logger
+		}
+		browser, err := NewBrowserSearcher(searcher, browserConfig)
+		if err != nil {
+			logger.Warn("⚠️ Page rendering disabled", "error", err)
+		} else {
+			logger.Info("🌐 Rendering pages", "browser", browser.ExecPath())
+			searcher = browser
+		}
+	}
+
+	fetchTransport := cfg.Search.FetchTransport
+	rateLimit := NewRateLimitedSearcher(searcher, cfg.RateLimit)
+	searcher = rateLimit
+	if !cfg.IgnoreRobots {
+		searcher = NewRobotsSearcher(searcher, RobotsConfig{Transport: fetchTransport, Logger: cfg.Search.Logger})
+	}
+	if !cfg.NoEnrich {
+		searcher = NewSnippetSearcher(searcher, SnippetConfig{Transport: fetchTransport, RateLimit: rateLimit, Logger: cfg.Search.Logger})
+	}
+	if cfg.Cache.TTL > 0 && cfg.Cache.Dir != "" {
+		searcher = NewCachedSearcher(searcher, cfg.Cache)
+	}
+	if !cfg.NoWayback {
+		searcher = NewWaybackSearcher(searcher, WaybackConfig{Transport: fetchTransport, Logger: cfg.Search.Logger})
+	}
+	return searcher, nil
+}
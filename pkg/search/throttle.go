@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/retry"
+	"sync"
+	"time"
+)
+
+// hostPauses holds back requests to hosts that answered 429 or 503, for as long
+// as they asked (Retry-After) or else the retry policy's first backoff, so
+// concurrent requests to a throttling host wait together instead of each
+// spending its retries on it. The zero value is ready to use.
+type hostPauses struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// wait sleeps until host's pause, if any, is over
+func (h *hostPauses) wait(ctx context.Context, host string) error {
+	h.mu.Lock()
+	wait := time.Until(h.until[host])
+	h.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttled pauses host when err is a throttled response and returns how long
+// for (0 when err isn't one). A longer pause already in place is kept.
+func (h *hostPauses) throttled(host string, err error, policy retry.Policy) time.Duration {
+	if !retry.IsThrottled(err) {
+		return 0
+	}
+	pause := retry.RetryAfter(err)
+	if policy.MaxRetryAfter > 0 && pause > policy.MaxRetryAfter {
+		pause = policy.MaxRetryAfter
+	}
+	if pause <= 0 {
+		pause = policy.Backoff(1)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.until == nil {
+		h.until = make(map[string]time.Time)
+	}
+	if until := time.Now().Add(pause); until.After(h.until[host]) {
+		h.until[host] = until
+	}
+	return pause
+}
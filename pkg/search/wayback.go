@@ -0,0 +1,182 @@
+package search
+
+import (
+	"context"
+	"deep-research/pkg/logging"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultWaybackURL is the Internet Archive's Wayback Machine availability API
+const DefaultWaybackURL = "https://archive.org/wayback/available"
+
+// WaybackConfig configures the fallback to archived copies of dead pages
+type WaybackConfig struct {
+	AvailabilityURL string            // Availability API endpoint (default DefaultWaybackURL)
+	Timeout         time.Duration     // Timeout for an availability lookup (default 10s)
+	Transport       http.RoundTripper // Transport for availability lookups, e.g. through a proxy (nil = http.DefaultTransport)
+	Logger          *slog.Logger      // Where archived fetches are reported (nil = console on stdout)
+}
+
+// ArchiveInfo says which archived copy a page was read from; see WithArchiveInfo
+type ArchiveInfo struct {
+	SnapshotURL string    // Wayback Machine URL of the snapshot (empty = the live page was read)
+	Timestamp   time.Time // When the snapshot was taken
+}
+
+type archiveInfoKey struct{}
+
+// WithArchiveInfo returns a context whose page fetches record in the returned
+// ArchiveInfo whether a WaybackSearcher served them from an archived copy
+func WithArchiveInfo(ctx context.Context) (context.Context, *ArchiveInfo) {
+	info := &ArchiveInfo{}
+	return context.WithValue(ctx, archiveInfoKey{}, info), info
+}
+
+// WaybackSearcher wraps a Searcher so deep-mode page fetches that find the page
+// gone (403, 404, or 410) read the Wayback Machine's latest snapshot of it
+// instead. Snapshots are fetched through the wrapped searcher, so rate limits,
+// robots.txt, and the cache apply to them too. Searches are not affected.
+type WaybackSearcher struct {
+	Searcher
+	config WaybackConfig
+	client *http.Client
+}
+
+// NewWaybackSearcher wraps s with the Wayback Machine fallback
+func NewWaybackSearcher(s Searcher, cfg WaybackConfig) *WaybackSearcher {
+	if cfg.AvailabilityURL == "" {
+		cfg.AvailabilityURL = DefaultWaybackURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	return &WaybackSearcher{
+		Searcher: s,
+		config:   cfg,
+		client:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+	}
+}
+
+// FetchPageContent fetches the page, or its latest archived copy if the page is gone
+func (w *WaybackSearcher) FetchPageContent(ctx context.Context, pageURL string, maxLength int) (string, error) {
+	fetcher, ok := w.Searcher.(ContentFetcher)
+	if !ok {
+		return "", errNoFetcher
+	}
+	text, err := fetcher.FetchPageContent(ctx, pageURL, maxLength)
+	if err == nil || !isGone(err) {
+		return text, err
+	}
+
+	snapshot, taken, lookupErr := w.snapshot(ctx, pageURL)
+	if lookupErr != nil {
+		w.config.Logger.Debug("🏛️ Wayback lookup failed", "url", pageURL, "error", lookupErr)
+		return "", err
+	}
+	if snapshot == "" {
+		return "", err
+	}
+
+	archived, archiveErr := fetcher.FetchPageContent(ctx, snapshot, maxLength)
+	if archiveErr != nil {
+		w.config.Logger.Debug("🏛️ Archived copy unavailable", "url", pageURL, "snapshot", snapshot, "error", archiveErr)
+		return "", err
+	}
+	w.config.Logger.Info("🏛️ Page gone, using archived copy", "url", pageURL, "snapshot", taken.Format("2006-01-02"))
+	if info, ok := ctx.Value(archiveInfoKey{}).(*ArchiveInfo); ok {
+		info.SnapshotURL = snapshot
+		info.Timestamp = taken
+	}
+	return archived, nil
+}
+
+// SitemapURLs reads the site's live sitemaps; there is no archived fallback for them
+func (w *WaybackSearcher) SitemapURLs(ctx context.Context, site string, maxURLs int) ([]SitemapURL, error) {
+	reader, ok := w.Searcher.(SitemapReader)
+	if !ok {
+		return nil, errNoSitemapReader
+	}
+	return reader.SitemapURLs(ctx, site, maxURLs)
+}
+
+// ExtractListingLinks extracts links from the live page through the wrapped searcher
+func (w *WaybackSearcher) ExtractListingLinks(ctx context.Context, pageURL string, maxLinks int) ([]ListingLink, error) {
+	extractor, ok := w.Searcher.(LinkExtractor)
+	if !ok {
+		return nil, errNoLinkExtractor
+	}
+	return extractor.ExtractListingLinks(ctx, pageURL, maxLinks)
+}
+
+// isGone reports whether a fetch failed because the page no longer exists or is blocked
+func isGone(err error) bool {
+	var status *retry.StatusError
+	if !errors.As(err, &status) {
+		return false
+	}
+	switch status.StatusCode {
+	case http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// waybackResponse is the availability API's answer
+type waybackResponse struct {
+	ArchivedSnapshots struct {
+		Closest struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Timestamp string `json:"timestamp"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// snapshot returns the URL of the latest successful snapshot of pageURL, in its
+// raw form (without the Wayback Machine's toolbar), or "" if there is none
+func (w *WaybackSearcher) snapshot(ctx context.Context, pageURL string) (string, time.Time, error) {
+	u := fmt.Sprintf("%s?%s", w.config.AvailabilityURL, url.Values{"url": {pageURL}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("wayback returned status %d", resp.StatusCode)
+	}
+
+	var wResp waybackResponse
+	if err := json.NewDecoder(resp.Body).Decode(&wResp); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	closest := wResp.ArchivedSnapshots.Closest
+	if !closest.Available || closest.URL == "" || (closest.Status != "" && closest.Status != "200") {
+		return "", time.Time{}, nil
+	}
+
+	taken, _ := time.Parse("20060102150405", closest.Timestamp)
+	snapshot := closest.URL
+	if closest.Timestamp != "" {
+		// "/web/<timestamp>id_/" serves the page as archived, without the toolbar
+		snapshot = strings.Replace(snapshot, "/web/"+closest.Timestamp+"/", "/web/"+closest.Timestamp+"id_/", 1)
+	}
+	return snapshot, taken, nil
+}
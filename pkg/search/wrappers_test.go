@@ -0,0 +1,65 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWrappersKeepOptionalInterfaces checks that every searcher wrapper offers
+// the optional interfaces of the searcher it wraps, so deep mode, crawling, and
+// sitemap reads still work however the wrappers are stacked
+func TestWrappersKeepOptionalInterfaces(t *testing.T) {
+	base := NewSearXNGClient("http://localhost:8080")
+	wrappers := map[string]func(Searcher) Searcher{
+		"cache":     func(s Searcher) Searcher { return NewCachedSearcher(s, CacheConfig{Dir: t.TempDir()}) },
+		"ratelimit": func(s Searcher) Searcher { return NewRateLimitedSearcher(s, RateLimitConfig{PerHost: 1}) },
+		"robots":    func(s Searcher) Searcher { return NewRobotsSearcher(s, RobotsConfig{}) },
+		"snippets":  func(s Searcher) Searcher { return NewSnippetSearcher(s, SnippetConfig{}) },
+		"wayback":   func(s Searcher) Searcher { return NewWaybackSearcher(s, WaybackConfig{}) },
+		"multi":     func(s Searcher) Searcher { return NewMultiSearcher(Engine{Name: "searxng", Searcher: s}) },
+		"browser": func(s Searcher) Searcher {
+			b, err := NewBrowserSearcher(s, BrowserConfig{ExecPath: "chromium", Timeout: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return b
+		},
+	}
+
+	check := func(t *testing.T, s Searcher) {
+		t.Helper()
+		if _, ok := s.(ContentFetcher); !ok {
+			t.Error("not a ContentFetcher")
+		}
+		if _, ok := s.(LinkExtractor); !ok {
+			t.Error("not a LinkExtractor")
+		}
+		if _, ok := s.(Crawler); !ok {
+			t.Error("not a Crawler")
+		}
+		if _, ok := s.(SitemapReader); !ok {
+			t.Error("not a SitemapReader")
+		}
+	}
+
+	check(t, base)
+	for name, wrap := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			check(t, wrap(base))
+		})
+	}
+
+	// The stack the CLI and the server build
+	t.Run("stack", func(t *testing.T) {
+		s, err := NewStack(StackConfig{
+			Search:    Config{SearXURL: "http://localhost:8080"},
+			Browser:   &BrowserConfig{ExecPath: "chromium", Timeout: time.Second},
+			RateLimit: RateLimitConfig{PerHost: 1},
+			Cache:     CacheConfig{Dir: t.TempDir(), TTL: time.Hour},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, s)
+	})
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// authCookie holds the token of a user logged in through the web UI, so the
+// browser's EventSource and WebSocket requests (which can't set headers) are authenticated too
+const authCookie = "deep_research_token"
+
+// authCookieMaxAge is how long a web UI login lasts
+const authCookieMaxAge = 30 * 24 * time.Hour
+
+// apiUser is a named API token
+type apiUser struct {
+	name  string
+	token string
+}
+
+// LoginRequest is the JSON body for logging in to the web UI
+type LoginRequest struct {
+	Token string `json:"token"`
+}
+
+// AuthStatus tells the web UI whether it has to log in first
+type AuthStatus struct {
+	Required bool   `json:"required"`
+	User     string `json:"user,omitempty"` // Set when the request is authenticated
+}
+
+// loadUsers collects the API tokens: the single token (user "admin") and one
+// "name:token" per line of the users file ("#" starts a comment).
+// No tokens means auth is disabled.
+func loadUsers(token, usersFile string) ([]apiUser, error) {
+	var users []apiUser
+	if token = strings.TrimSpace(token); token != "" {
+		users = append(users, apiUser{name: "admin", token: token})
+	}
+	if usersFile == "" {
+		return users, nil
+	}
+
+	f, err := os.Open(usersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, token, ok := strings.Cut(line, ":")
+		name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+		if !ok || name == "" || token == "" {
+			return nil, fmt.Errorf("%s:%d: expected name:token", usersFile, n)
+		}
+		users = append(users, apiUser{name: name, token: token})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	return users, nil
+}
+
+// authUser returns the user whose token the request carries (bearer header, else login cookie)
+func (s *Server) authUser(r *http.Request) (string, bool) {
+	token := ""
+	if scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(value)
+	} else if cookie, err := r.Cookie(authCookie); err == nil {
+		token = cookie.Value
+	}
+	return s.lookupToken(token)
+}
+
+// lookupToken finds the user a token belongs to, comparing in constant time
+func (s *Server) lookupToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	for _, u := range s.users {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(u.token)) == 1 {
+			return u.name, true
+		}
+	}
+	return "", false
+}
+
+// requireAuth rejects /api/* requests without a valid token when auth is enabled.
+// The embedded UI and the login endpoints stay open so the browser can log in,
+// and so does the OpenAPI document.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	if len(s.users) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") && !strings.HasPrefix(r.URL.Path, "/api/auth/") && r.URL.Path != "/api/openapi.json" {
+			if _, ok := s.authUser(r); !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="deep-research"`)
+				writeError(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleAuthStatus reports whether auth is required and who the request is logged in as
+func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
+	status := AuthStatus{Required: len(s.users) > 0}
+	if status.Required {
+		status.User, _ = s.authUser(r)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleLogin checks a token and stores it in the login cookie
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req LoginRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	token := strings.TrimSpace(req.Token)
+	user, ok := s.lookupToken(token)
+	if !ok {
+		writeError(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(authCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuthStatus{Required: true, User: user})
+}
+
+// handleLogout clears the login cookie
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
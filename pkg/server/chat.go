@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"deep-research/pkg/notify"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxChatRequestAge is how old a signed chat request may be before it's
+// rejected as a possible replay
+const maxChatRequestAge = 5 * time.Minute
+
+// notifyJob posts the current job's new status (plan ready, complete, or
+// failed) to the chat webhooks in the background
+func (s *Server) notifyJob() {
+	if !s.notifier.Enabled() {
+		return
+	}
+	s.mu.RLock()
+	job := *s.currentJob
+	s.mu.RUnlock()
+
+	msg, ok := s.jobMessage(&job)
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := s.notifier.Send(ctx, msg); err != nil {
+			log.Printf("⚠️ Chat notification for job %s failed: %v", job.ID, err)
+		}
+	}()
+}
+
+// jobMessage is the chat message for a job's status, linking to the web UI
+// or the report when the server's public URL is known
+func (s *Server) jobMessage(job *ResearchJob) (notify.Message, bool) {
+	var msg notify.Message
+	switch job.Status {
+	case "awaiting_approval":
+		queries := 0
+		if job.Plan != nil {
+			queries = len(job.Plan.SearchQueries)
+		}
+		msg.Text = fmt.Sprintf("📋 Plan ready for *%s* (job %s): %d search queries awaiting approval", job.Topic, job.ID, queries)
+		if s.publicURL != "" {
+			msg.Link, msg.LinkLabel = s.publicURL+"/", "Review the plan"
+		}
+	case "awaiting_answers":
+		msg.Text = fmt.Sprintf("❓ *%s* (job %s): %d clarifying questions to answer before planning", job.Topic, job.ID, len(job.Questions))
+		if s.publicURL != "" {
+			msg.Link, msg.LinkLabel = s.publicURL+"/", "Answer the questions"
+		}
+	case "complete":
+		msg.Text = fmt.Sprintf("✅ *%s* (job %s): %s", job.Topic, job.ID, job.Progress.Message)
+		if s.publicURL != "" {
+			msg.Link, msg.LinkLabel = s.publicURL+"/api/jobs/"+url.PathEscape(job.ID)+"/export?format=html", "Read the report"
+		}
+	case "error":
+		msg.Text = fmt.Sprintf("❌ Research failed: *%s* (job %s): %s", job.Topic, job.ID, job.Error)
+	default:
+		return msg, false
+	}
+	return msg, true
+}
+
+// handleSlackCommand starts research from a Slack slash command
+// ("/research <topic>"), verified by the app's signing secret
+func (s *Server) handleSlackCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !validSlackSignature(s.slackSecret, r.Header, body, time.Now()) {
+		writeError(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"response_type": "in_channel",
+		"text":          s.chatCommand(form.Get("text")),
+	})
+}
+
+// validSlackSignature checks Slack's request signature: "v0=" and the hex
+// HMAC-SHA256 of "v0:<timestamp>:<body>" keyed with the signing secret
+func validSlackSignature(secret string, header http.Header, body []byte, now time.Time) bool {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	if !recentTimestamp(timestamp, now) {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature")))
+}
+
+// discordInteraction is the part of a Discord interaction the /research command reads
+type discordInteraction struct {
+	Type int `json:"type"` // 1 = ping, 2 = application command
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// handleDiscordInteraction returns the handler for Discord's interactions
+// endpoint: it answers pings and starts research from "/research topic:<topic>",
+// verifying each request with the app's public key
+func (s *Server) handleDiscordInteraction(key ed25519.PublicKey) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			writeError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		timestamp := r.Header.Get("X-Signature-Timestamp")
+		signature, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+		if err != nil || !ed25519.Verify(key, append([]byte(timestamp), body...), signature) {
+			writeError(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+		var interaction discordInteraction
+		if err := json.Unmarshal(body, &interaction); err != nil {
+			writeError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch interaction.Type {
+		case 1:
+			json.NewEncoder(w).Encode(map[string]int{"type": 1})
+		case 2:
+			topic := ""
+			for _, opt := range interaction.Data.Options {
+				if text, ok := opt.Value.(string); ok && opt.Name == "topic" {
+					topic = text
+				}
+			}
+			// Type 4 replies in the channel right away
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": 4,
+				"data": map[string]string{"content": s.chatCommand(topic)},
+			})
+		default:
+			writeError(w, "Unsupported interaction type", http.StatusBadRequest)
+		}
+	}
+}
+
+// recentTimestamp reports whether a Unix timestamp header is within
+// maxChatRequestAge of now
+func recentTimestamp(value string, now time.Time) bool {
+	sec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(sec, 0))
+	return age < maxChatRequestAge && age > -maxChatRequestAge
+}
+
+// chatCommand starts an auto-approved research job on a topic sent from chat
+// and returns the reply: the job id, its queue position, or why it couldn't start
+func (s *Server) chatCommand(topic string) string {
+	topic = strings.TrimSpace(topic)
+	if topic == "" {
+		return "Usage: /research <topic>"
+	}
+
+	req := ResearchRequest{Topic: topic, AutoApprove: true}
+	s.applyDefaults(&req)
+	job := &ResearchJob{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		Topic:     req.Topic,
+		Status:    "planning",
+		StartedAt: time.Now(),
+		Config:    req,
+	}
+
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		return "❌ The research server is shutting down"
+	}
+	if isActive(s.currentJob.Status) {
+		s.mu.Unlock()
+		position, err := s.queueJob(job)
+		if err != nil {
+			return "❌ " + err.Error()
+		}
+		return fmt.Sprintf("⏳ Queued research on %q (job %s, position %d)", topic, job.ID, position)
+	}
+	s.currentJob = job
+	s.mu.Unlock()
+	s.persistJob()
+
+	go s.planJob(req)
+	return fmt.Sprintf("🔎 Started research on %q (job %s)", topic, job.ID)
+}
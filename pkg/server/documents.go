@@ -0,0 +1,101 @@
+package server
+
+import (
+	"deep-research/pkg/document"
+	"deep-research/pkg/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxUploadSize bounds a POST /api/documents body (all its files together)
+const maxUploadSize = 4 * document.MaxSize
+
+// handleDocuments uploads documents to ground research with (POST /api/documents,
+// multipart "file" fields), lists them (GET /api/documents), and deletes one
+// (DELETE /api/documents/{id}). Jobs attach them by ID in ResearchRequest.Documents.
+func (s *Server) handleDocuments(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, "Job persistence is disabled", http.StatusServiceUnavailable)
+		return
+	}
+	id := r.PathValue("id")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		documents, err := s.store.ListDocuments()
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(documents)
+
+	case r.Method == http.MethodPost && id == "":
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			writeError(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		files := r.MultipartForm.File["file"]
+		if len(files) == 0 {
+			writeError(w, `No files: send them as multipart "file" fields`, http.StatusBadRequest)
+			return
+		}
+
+		// Parse every file before saving any, so a bad one uploads nothing
+		var documents []document.Document
+		for _, fh := range files {
+			f, err := fh.Open()
+			if err != nil {
+				writeError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				writeError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			doc, err := document.Parse(fh.Filename, data)
+			if err != nil {
+				writeError(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			documents = append(documents, doc)
+		}
+
+		saved := make([]store.DocumentSummary, 0, len(documents))
+		for i, doc := range documents {
+			now := time.Now()
+			summary, err := s.store.SaveDocument(fmt.Sprintf("%d-%d", now.UnixNano(), i), doc, now)
+			if err != nil {
+				writeError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			saved = append(saved, summary)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(saved)
+
+	case r.Method == http.MethodDelete && id != "":
+		err := s.store.DeleteDocument(id)
+		if errors.Is(err, store.ErrDocumentNotFound) {
+			writeError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(StatusResponse{Status: "deleted"})
+
+	default:
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
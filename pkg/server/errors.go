@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxJSONBody caps the size of JSON request bodies
+const maxJSONBody = 1 << 20
+
+// ErrorResponse is the body of every API error (see openapi.json)
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an API error
+type ErrorDetail struct {
+	Code    string `json:"code"`    // The HTTP status in snake case, e.g. "not_found"
+	Message string `json:"message"` // Human-readable explanation
+}
+
+// writeError sends an API error in the ErrorResponse envelope
+func writeError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorDetail{Code: errorCode(status), Message: message}})
+}
+
+// errorCode is the status's text in snake case, e.g. "service_unavailable"
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	text = strings.ToLower(strings.NewReplacer("-", " ", "'", "").Replace(text))
+	return strings.Join(strings.Fields(text), "_")
+}
+
+// decodeJSON reads a request's JSON body into v, answering 413 when the body
+// is over maxJSONBody and 400 when it isn't valid JSON. It reports whether v
+// was decoded.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBody)
+	err := json.NewDecoder(r.Body).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, fmt.Sprintf("Request body too large (limit %d bytes)", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return false
+	case err != nil:
+		writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
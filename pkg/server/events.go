@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/store"
+	"log"
+	"sync"
+)
+
+// maxEventLogs is how many jobs keep their event log in memory; older logs are
+// replayed from the job database
+const maxEventLogs = 20
+
+// jobEvent is a progress event tagged with its job and its position in that
+// job's event log (Seq 1 = first event)
+type jobEvent struct {
+	JobID string `json:"jobId,omitempty"`
+	Seq   int    `json:"seq"`
+	agent.ProgressEvent
+}
+
+// terminal reports whether no further events follow this one for its job
+func (e jobEvent) terminal() bool {
+	return e.Phase == "complete" || e.Phase == "error" || e.Phase == "cancelled"
+}
+
+// eventLog records every progress event per job so clients that (re)connect
+// late can replay what they missed. Subscribers are only woken up; they read
+// the events from the log, so a slow client can't lose any.
+type eventLog struct {
+	mu    sync.Mutex
+	jobs  map[string][]agent.ProgressEvent
+	order []string // Job IDs in memory, oldest first
+	subs  map[chan struct{}]bool
+	store *store.Store // Fallback for jobs evicted from memory or from a previous run (optional)
+}
+
+func newEventLog(jobStore *store.Store) *eventLog {
+	return &eventLog{
+		jobs:  make(map[string][]agent.ProgressEvent),
+		subs:  make(map[chan struct{}]bool),
+		store: jobStore,
+	}
+}
+
+// append logs an event for a job ("" before a job exists) and wakes up subscribers
+func (l *eventLog) append(jobID string, event agent.ProgressEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.jobs[jobID]; !ok {
+		l.order = append(l.order, jobID)
+		if len(l.order) > maxEventLogs {
+			delete(l.jobs, l.order[0])
+			l.order = l.order[1:]
+		}
+	}
+	l.jobs[jobID] = append(l.jobs[jobID], event)
+
+	for ch := range l.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// Already has a pending wake-up
+		}
+	}
+}
+
+// since returns a job's events after sequence number seq
+func (l *eventLog) since(jobID string, seq int) []jobEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sinceLocked(jobID, seq)
+}
+
+func (l *eventLog) sinceLocked(jobID string, seq int) []jobEvent {
+	events, ok := l.jobs[jobID]
+	if !ok && jobID != "" && l.store != nil {
+		var err error
+		if events, err = l.store.ProgressEvents(jobID); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+
+	var out []jobEvent
+	for i := max(seq, 0); i < len(events); i++ {
+		out = append(out, jobEvent{JobID: jobID, Seq: i + 1, ProgressEvent: events[i]})
+	}
+	return out
+}
+
+// subscribe returns a channel that receives a value whenever events are logged
+func (l *eventLog) subscribe() chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	l.subs[ch] = true
+	return ch
+}
+
+func (l *eventLog) unsubscribe(ch chan struct{}) {
+	l.mu.Lock()
+	delete(l.subs, ch)
+	l.mu.Unlock()
+}
+
+// followEvents sends a job's events after seq, then its live events as they
+// happen, until the job completes or fails, ctx ends, the server shuts down,
+// or send fails. An empty
+// jobID follows the current job. Jobs that are neither current nor queued
+// only get their history.
+func (s *Server) followEvents(ctx context.Context, jobID string, seq int, send func(jobEvent) error) {
+	s.mu.RLock()
+	current := s.currentJob.ID
+	progress := s.currentJob.Progress
+	if jobID == "" {
+		jobID = current
+	}
+	live := jobID == current
+	for _, job := range s.queue {
+		live = live || job.ID == jobID
+	}
+	s.mu.RUnlock()
+
+	wake := s.events.subscribe()
+	defer s.events.unsubscribe(wake)
+	history := s.events.since(jobID, seq)
+
+	// Nothing logged yet: send the current state like before
+	if len(history) == 0 && seq == 0 && jobID == current {
+		history = []jobEvent{{JobID: jobID, ProgressEvent: progress}}
+	}
+
+	for {
+		for _, ev := range history {
+			if err := send(ev); err != nil || ev.terminal() {
+				return
+			}
+			seq = max(seq, ev.Seq)
+		}
+		if !live {
+			return
+		}
+
+		select {
+		case <-wake:
+			history = s.events.since(jobID, seq)
+		case <-ctx.Done():
+			return
+		case <-s.closing:
+			return
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/retry"
+	"deep-research/pkg/search"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthTimeout bounds each dependency probe, so a hung backend reports as down quickly
+const healthTimeout = 5 * time.Second
+
+// providerNames are the display names of the LLM providers in health reports
+var providerNames = map[string]string{
+	"":                     "LM Studio",
+	llm.ProviderLMStudio:   "LM Studio",
+	llm.ProviderOllama:     "Ollama",
+	llm.ProviderOpenAI:     "OpenAI",
+	llm.ProviderAzure:      "Azure OpenAI",
+	llm.ProviderOpenRouter: "OpenRouter",
+	llm.ProviderAnthropic:  "Anthropic",
+	llm.ProviderLlamaCpp:   "llama.cpp",
+}
+
+// HealthCheck is the result of probing one dependency
+type HealthCheck struct {
+	Name      string `json:"name"`             // e.g. "LM Studio" or "SearXNG"
+	URL       string `json:"url"`              // Endpoint probed
+	OK        bool   `json:"ok"`               // Whether it answered
+	LatencyMs int64  `json:"latencyMs"`        // How long the probe took
+	Detail    string `json:"detail,omitempty"` // What was found, e.g. the model count or a missing model
+	Error     string `json:"error,omitempty"`  // Why the probe failed
+}
+
+// HealthReport is the response of /healthz and /readyz
+type HealthReport struct {
+	Status string        `json:"status"` // "ok", or "unavailable" when a dependency is down
+	Checks []HealthCheck `json:"checks"`
+}
+
+// handleHealthz reports the server and its dependencies; it answers 200 as long
+// as the server itself is up, so it suits liveness probes
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.writeHealth(w, r, false)
+}
+
+// handleReadyz reports the dependencies, answering 503 when any is down (or the
+// server is shutting down), so it suits readiness probes and the UI's pre-flight check
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	s.writeHealth(w, r, true)
+}
+
+// writeHealth probes the dependencies and writes the report
+func (s *Server) writeHealth(w http.ResponseWriter, r *http.Request, ready bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := HealthReport{Status: "ok", Checks: s.checkDependencies(r.Context())}
+	for _, c := range report.Checks {
+		if !c.OK {
+			report.Status = "unavailable"
+		}
+	}
+
+	s.mu.Lock()
+	shuttingDown := s.shuttingDown
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if ready && (report.Status != "ok" || shuttingDown) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// checkDependencies probes the LLM servers (the main one, plus the summarizer's
+// and writer's when they run elsewhere) and SearXNG concurrently
+func (s *Server) checkDependencies(ctx context.Context) []HealthCheck {
+	name := s.providerName()
+	probes := []func(context.Context) HealthCheck{
+		func(ctx context.Context) HealthCheck { return s.checkLLM(ctx, name, s.lmURL, s.model) },
+	}
+	if s.summarizerURL != "" && s.summarizerURL != s.lmURL {
+		probes = append(probes, func(ctx context.Context) HealthCheck {
+			return s.checkLLM(ctx, name+" (summarizer)", s.summarizerURL, s.summarizerModel)
+		})
+	}
+	if s.writerURL != "" && s.writerURL != s.lmURL && s.writerURL != s.summarizerURL {
+		probes = append(probes, func(ctx context.Context) HealthCheck {
+			return s.checkLLM(ctx, name+" (writer)", s.writerURL, s.writerModel)
+		})
+	}
+	if len(s.engines) == 0 || slices.Contains(s.engines, search.EngineSearXNG) {
+		probes = append(probes, s.checkSearXNG)
+	}
+
+	checks := make([]HealthCheck, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+			defer cancel()
+			start := time.Now()
+			checks[i] = probe(ctx)
+			checks[i].LatencyMs = time.Since(start).Milliseconds()
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// providerName is the display name of the LLM provider
+func (s *Server) providerName() string {
+	if name := providerNames[strings.ToLower(s.llmProvider)]; name != "" {
+		return name
+	}
+	return s.llmProvider
+}
+
+// checkLLM lists the models served at baseURL, without retries, and notes whether
+// model is among them; providers that can't list models get a one-token completion instead
+func (s *Server) checkLLM(ctx context.Context, name, baseURL, model string) HealthCheck {
+	check := HealthCheck{Name: name, URL: baseURL}
+	if model == "" {
+		model = s.model
+	}
+	provider, err := llm.NewProvider(s.llmProvider, llm.Config{
+		BaseURL:   baseURL,
+		APIKey:    s.apiKey,
+		Model:     model,
+		MaxTokens: 1,
+		Timeout:   healthTimeout,
+		Retry:     retry.Policy{MaxAttempts: 1},
+		Transport: s.llmTransport,
+	})
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	lister, ok := provider.(llm.ModelLister)
+	if !ok {
+		if _, err := provider.Chat(ctx, []llm.Message{{Role: "user", Content: "Reply with OK"}}); err != nil {
+			check.Error = err.Error()
+			return check
+		}
+		check.OK = true
+		return check
+	}
+
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.OK = true
+	check.Detail = fmt.Sprintf("%d models", len(models))
+	if model != "" && !slices.ContainsFunc(models, func(m string) bool {
+		return m == model || strings.TrimSuffix(m, ":latest") == model
+	}) {
+		check.Detail += fmt.Sprintf(", %q not listed", model)
+	}
+	return check
+}
+
+// checkSearXNG runs a one-word search on each instance, without retries; with
+// several instances, it is up while any of them answers
+func (s *Server) checkSearXNG(ctx context.Context) HealthCheck {
+	check := HealthCheck{Name: "SearXNG", URL: s.searxURL}
+	instances := search.SearXNGInstances(s.searxURL)
+	if len(instances) == 0 {
+		check.Error = "no SearXNG URL configured"
+		return check
+	}
+
+	counts := make([]int, len(instances))
+	errs := make([]error, len(instances))
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := search.NewSearXNGClient(instance)
+			client.HTTPClient.Timeout = healthTimeout
+			client.HTTPClient.Transport = s.searchTransport
+			client.Retry.MaxAttempts = 1
+			client.Retry.ThrottledAttempts = 1
+			results, err := client.Search(ctx, "test")
+			counts[i], errs[i] = len(results), err
+		}()
+	}
+	wg.Wait()
+
+	var up []string
+	var failures []string
+	for i, instance := range instances {
+		switch {
+		case errs[i] == nil:
+			up = append(up, fmt.Sprintf("%d results", counts[i]))
+		case len(instances) > 1:
+			failures = append(failures, instance+": "+errs[i].Error())
+		default:
+			failures = append(failures, errs[i].Error())
+		}
+	}
+	if len(up) == 0 {
+		check.Error = strings.Join(failures, "; ")
+		return check
+	}
+	check.OK = true
+	check.Detail = up[0]
+	if len(instances) > 1 {
+		check.Detail = fmt.Sprintf("%d of %d instances answering", len(up), len(instances))
+		if len(failures) > 0 {
+			check.Detail += " (" + strings.Join(failures, "; ") + ")"
+		}
+	}
+	return check
+}
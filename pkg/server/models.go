@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/retry"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// modelsTimeout bounds listing the LLM server's models (Ollama is asked about each)
+const modelsTimeout = 15 * time.Second
+
+// ModelsResponse is the response of GET /api/models
+type ModelsResponse struct {
+	Provider string `json:"provider"` // e.g. "LM Studio"
+	URL      string `json:"url"`
+	llm.ModelStatus
+	ContextLen int      `json:"contextLen"`         // The context length checked against the model's
+	Warnings   []string `json:"warnings,omitempty"` // The model isn't served, or the context length exceeds its own
+}
+
+// handleModels lists the LLM server's models with their context lengths and
+// checks the configured model against them; ?ctx= is the context length to
+// check (default: a research request's, see Options.ContextLen)
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contextLen := s.defaultContextLen()
+	if v := r.URL.Query().Get("ctx"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, "ctx must be a positive number", http.StatusBadRequest)
+			return
+		}
+		contextLen = n
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), modelsTimeout)
+	defer cancel()
+	resp, err := s.inspectModel(ctx, contextLen)
+	if err != nil {
+		writeError(w, "Failed to list the LLM server's models: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// inspectModel lists the main LLM server's models, without retries, and checks
+// the configured model and contextLen against them
+func (s *Server) inspectModel(ctx context.Context, contextLen int) (ModelsResponse, error) {
+	provider, err := llm.NewProvider(s.llmProvider, llm.Config{
+		BaseURL:   s.lmURL,
+		APIKey:    s.apiKey,
+		Model:     s.model,
+		Timeout:   modelsTimeout,
+		Retry:     retry.Policy{MaxAttempts: 1},
+		Transport: s.llmTransport,
+	})
+	if err != nil {
+		return ModelsResponse{}, err
+	}
+	status, err := llm.InspectModel(ctx, provider, s.model)
+	if err != nil {
+		return ModelsResponse{}, err
+	}
+	return ModelsResponse{
+		Provider:    s.providerName(),
+		URL:         s.lmURL,
+		ModelStatus: status,
+		ContextLen:  contextLen,
+		Warnings:    status.Warnings(contextLen),
+	}, nil
+}
+
+// printModel reports the configured model's context length at startup, or why
+// it won't work
+func (s *Server) printModel() {
+	ctx, cancel := context.WithTimeout(context.Background(), modelsTimeout)
+	defer cancel()
+	models, err := s.inspectModel(ctx, s.defaultContextLen())
+	if err != nil {
+		fmt.Printf("   ⚠️ Could not list the LLM server's models: %v\n", err)
+		return
+	}
+	if models.Found && models.ContextLength > 0 {
+		fmt.Printf("   Model:     %s (context length %d)\n", models.ID, models.ContextLength)
+	}
+	for _, warning := range models.Warnings {
+		fmt.Printf("   ⚠️ %s\n", warning)
+	}
+}
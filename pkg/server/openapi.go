@@ -0,0 +1,40 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes the API: every route, request, response, and the
+// ErrorResponse envelope. pkg/client and clients/typescript are written against it.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// handleOpenAPI serves the OpenAPI 3 document (GET /api/openapi.json)
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// withPathID serves a job-scoped route (/api/jobs/{id}/...) with the handler of
+// the route that takes the job as ?id=<job>
+func withPathID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		query := r.URL.Query()
+		query.Set("id", r.PathValue("id"))
+		r.URL.RawQuery = query.Encode()
+		next(w, r)
+	}
+}
+
+// handleNotFound answers API paths without a route in the error envelope
+// (rather than with the web UI's file server)
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, "No such endpoint: "+r.URL.Path, http.StatusNotFound)
+}
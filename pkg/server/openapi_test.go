@@ -0,0 +1,166 @@
+package server
+
+import (
+	"deep-research/pkg/agent"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/profile"
+	"deep-research/pkg/store"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// openAPITypes are the Go types the spec's schemas describe, by schema name
+var openAPITypes = map[string]any{
+	"ErrorResponse":     ErrorResponse{},
+	"StatusResponse":    StatusResponse{},
+	"ResearchRequest":   ResearchRequest{},
+	"CallSettings":      agent.CallSettings{},
+	"ResearchJob":       ResearchJob{},
+	"QueuedJob":         QueuedJob{},
+	"QuestionAnswer":    agent.QuestionAnswer{},
+	"EntityQueries":     agent.EntityQueries{},
+	"ComparisonPlan":    agent.ComparisonPlan{},
+	"SitemapPlan":       agent.SitemapPlan{},
+	"ResearchPlan":      agent.ResearchPlan{},
+	"ReviseRequest":     ReviseRequest{},
+	"QueriesRequest":    QueriesRequest{},
+	"AnswerRequest":     AnswerRequest{},
+	"ProgressEvent":     agent.ProgressEvent{},
+	"JobEvent":          jobEvent{},
+	"Source":            agent.Source{},
+	"CitationCheck":     agent.CitationCheck{},
+	"ReportClaim":       agent.ReportClaim{},
+	"Usage":             agent.Usage{},
+	"TokenUsage":        llm.TokenUsage{},
+	"Comparison":        agent.Comparison{},
+	"KnownSource":       agent.KnownSource{},
+	"FactChange":        agent.FactChange{},
+	"Changes":           agent.Changes{},
+	"Entity":            agent.Entity{},
+	"Relation":          agent.Relation{},
+	"Claim":             agent.Claim{},
+	"Fact":              agent.Fact{},
+	"Evaluation":        agent.Evaluation{},
+	"Image":             agent.Image{},
+	"QueryStat":         agent.QueryStat{},
+	"ResearchResult":    agent.ResearchResult{},
+	"Draft":             agent.Draft{},
+	"FollowUpRequest":   FollowUpRequest{},
+	"FollowUpAnswer":    agent.FollowUpAnswer{},
+	"DiffRequest":       DiffRequest{},
+	"ValueChange":       agent.ValueChange{},
+	"ResultDiff":        agent.ResultDiff{},
+	"Job":               store.Job{},
+	"JobSummary":        store.JobSummary{},
+	"TagsRequest":       TagsRequest{},
+	"CollectionSummary": store.CollectionSummary{},
+	"DocumentSummary":   store.DocumentSummary{},
+	"ReportFile":        ReportFile{},
+	"Profile":           profile.Profile{},
+	"LoginRequest":      LoginRequest{},
+	"AuthStatus":        AuthStatus{},
+	"HealthCheck":       HealthCheck{},
+	"HealthReport":      HealthReport{},
+	"ModelInfo":         llm.ModelInfo{},
+	"ModelsResponse":    ModelsResponse{},
+}
+
+// specSchema is the part of a schema TestOpenAPISchemas compares
+type specSchema struct {
+	Ref        string                     `json:"$ref"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	AllOf      []specSchema               `json:"allOf"`
+}
+
+// TestOpenAPISchemas checks that every schema in openapi.json has exactly the
+// JSON fields of its Go type, so the spec (and the clients written against it)
+// can't drift from what the server sends and accepts
+func TestOpenAPISchemas(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]specSchema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	schemas := spec.Components.Schemas
+
+	for name := range schemas {
+		if _, ok := openAPITypes[name]; !ok {
+			t.Errorf("schema %s has no Go type in openAPITypes", name)
+		}
+	}
+	for name, v := range openAPITypes {
+		schema, ok := schemas[name]
+		if !ok {
+			t.Errorf("no schema %s in openapi.json", name)
+			continue
+		}
+		documented := schemaProperties(t, schemas, schema)
+		fields := jsonFields(reflect.TypeOf(v))
+		for _, field := range fields {
+			if !slices.Contains(documented, field) {
+				t.Errorf("%s: field %q is missing from the schema", name, field)
+			}
+		}
+		for _, property := range documented {
+			if !slices.Contains(fields, property) {
+				t.Errorf("%s: property %q isn't a field of %T", name, property, v)
+			}
+		}
+	}
+}
+
+// schemaProperties lists a schema's properties, those of the schemas it
+// combines with allOf included
+func schemaProperties(t *testing.T, schemas map[string]specSchema, schema specSchema) []string {
+	t.Helper()
+	if schema.Ref != "" {
+		ref, ok := schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok {
+			t.Errorf("unresolved $ref %s", schema.Ref)
+			return nil
+		}
+		return schemaProperties(t, schemas, ref)
+	}
+	var properties []string
+	for key := range schema.Properties {
+		properties = append(properties, key)
+	}
+	for _, part := range schema.AllOf {
+		properties = append(properties, schemaProperties(t, schemas, part)...)
+	}
+	return properties
+}
+
+// jsonFields lists the keys encoding/json writes for a struct type: tagged
+// names, untagged field names, and the fields of embedded structs
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, jsonFields(embedded)...)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"deep-research/pkg/profile"
+	"encoding/json"
+	"net/http"
+)
+
+// handleProfiles lists the research profiles (GET /api/profiles)
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profiles, err := profile.Load(s.profilesDir, s.profiles...)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// applyProfile fills the request's unset fields from the profile. Its planning
+// and report instructions are copied in too, so a queued or persisted job keeps
+// them even if the profile file changes.
+func applyProfile(req *ResearchRequest, p profile.Profile) {
+	if req.Loops <= 0 {
+		req.Loops = p.Loops
+	}
+	if req.Parallel <= 0 {
+		req.Parallel = p.Parallel
+	}
+	if req.MinResults <= 0 {
+		req.MinResults = p.MinResults
+	}
+	if req.MaxPages <= 0 {
+		req.MaxPages = p.MaxPages
+	}
+	if req.ListingsPerQuery <= 0 {
+		req.ListingsPerQuery = p.ListingsPerQuery
+	}
+	req.DeepMode = req.DeepMode || p.DeepMode
+	req.ResultLinks = req.ResultLinks || p.ResultLinks
+	req.SimpleMode = req.SimpleMode || p.SimpleMode
+	if req.ExtractionSchema == "" {
+		req.ExtractionSchema = p.ExtractionSchema
+	}
+	if len(req.IncludeDomains) == 0 {
+		req.IncludeDomains = p.IncludeDomains
+	}
+	if len(req.ExcludeDomains) == 0 {
+		req.ExcludeDomains = p.ExcludeDomains
+	}
+	if len(req.Categories) == 0 {
+		req.Categories = p.Categories
+	}
+	if req.TimeRange == "" {
+		req.TimeRange = p.TimeRange
+	}
+	if req.PlanningPrompt == "" {
+		req.PlanningPrompt = p.Planning
+	}
+	if req.ReportStructure == "" {
+		req.ReportStructure = p.Report
+	}
+	if req.ReportTemplate == "" {
+		req.ReportTemplate = p.Template
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// isActive reports whether a job in this status occupies the server
+// ("cancelled" is transient while a partial report is being written)
+func isActive(status string) bool {
+	switch status {
+	case "planning", "awaiting_answers", "awaiting_approval", "running", "cancelled":
+		return true
+	}
+	return false
+}
+
+// QueuedJob is a job waiting in the queue: the /api/research response for a job
+// that has to wait, and an entry of GET /api/queue
+type QueuedJob struct {
+	*ResearchJob
+	Position int `json:"position"` // 1 = next to start
+}
+
+// errBusy is queueJob's error when queueing is disabled and a job is running
+var errBusy = errors.New("Research already in progress")
+
+// enqueue adds a job behind the current one, or rejects it when the queue is
+// full (409 when queueing is disabled, 503 otherwise)
+func (s *Server) enqueue(w http.ResponseWriter, job *ResearchJob) {
+	position, err := s.queueJob(job)
+	if errors.Is(err, errBusy) {
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(QueuedJob{ResearchJob: job, Position: position})
+}
+
+// queueJob adds a job behind the current one and returns its position, or
+// errBusy or a queue-full error when it can't wait
+func (s *Server) queueJob(job *ResearchJob) (int, error) {
+	s.mu.Lock()
+	if len(s.queue) >= s.maxQueue {
+		s.mu.Unlock()
+		if s.maxQueue == 0 {
+			return 0, errBusy
+		}
+		return 0, fmt.Errorf("Research queue is full (%d jobs waiting)", s.maxQueue)
+	}
+	job.Status = "queued"
+	s.queue = append(s.queue, job)
+	position := len(s.queue)
+	s.mu.Unlock()
+	s.saveJob(job)
+
+	log.Printf("⏳ Queued job %s (position %d): %s", job.ID, position, job.Topic)
+	return position, nil
+}
+
+// startNextQueued starts the oldest queued job once the server is free. Safe to
+// call whenever a job may have finished; it does nothing while one is active.
+func (s *Server) startNextQueued() {
+	s.mu.Lock()
+	if s.shuttingDown || isActive(s.currentJob.Status) || len(s.queue) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	job := s.queue[0]
+	s.queue = s.queue[1:]
+	job.Status = "planning"
+	job.StartedAt = time.Now()
+	s.currentJob = job
+	s.researcher = nil
+	s.cancelFunc = nil
+	s.mu.Unlock()
+	s.persistJob()
+
+	log.Printf("▶️ Starting queued job %s: %s", job.ID, job.Topic)
+	s.planJob(job.Config)
+}
+
+// planJob creates the current job's plan, then starts research right away if
+// the request asked for auto-approval
+func (s *Server) planJob(req ResearchRequest) {
+	s.createPlan(req)
+	if !req.AutoApprove {
+		return
+	}
+
+	s.mu.RLock()
+	ready := s.currentJob.Status == "awaiting_approval"
+	s.mu.RUnlock()
+	if ready {
+		if err := s.startResearch(); err != nil {
+			s.setError(err.Error())
+		}
+	}
+}
+
+// startResearch runs the current job's approved plan in the background
+func (s *Server) startResearch() error {
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		return errors.New("Server is shutting down")
+	}
+	if s.currentJob.Status != "awaiting_approval" {
+		s.mu.Unlock()
+		return errors.New("No plan awaiting approval")
+	}
+	plan := s.currentJob.Plan
+	researcher := s.researcher
+	if plan == nil || researcher == nil {
+		s.mu.Unlock()
+		return errors.New("Plan not found")
+	}
+	topic := s.currentJob.Topic
+	simpleMode := s.currentJob.Config.SimpleMode
+
+	s.currentJob.Status = "running"
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.cancelFunc = cancel
+	s.research.Add(1)
+	s.mu.Unlock()
+	s.persistJob()
+
+	go s.executeResearch(ctx, researcher, topic, *plan, simpleMode)
+	return nil
+}
+
+// handleQueue lists waiting jobs (GET /api/queue) or removes one (DELETE /api/queue/{id})
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		s.mu.RLock()
+		jobs := make([]QueuedJob, len(s.queue))
+		for i, job := range s.queue {
+			jobs[i] = QueuedJob{ResearchJob: job, Position: i + 1}
+		}
+		s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jobs)
+
+	case r.Method == http.MethodDelete && id != "":
+		s.mu.Lock()
+		var removed *ResearchJob
+		for i, job := range s.queue {
+			if job.ID == id {
+				removed = job
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				break
+			}
+		}
+		if removed != nil {
+			removed.Status = "cancelled"
+		}
+		s.mu.Unlock()
+
+		if removed == nil {
+			writeError(w, "Job not queued", http.StatusNotFound)
+			return
+		}
+		s.saveJob(removed)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(StatusResponse{Status: "cancelled"})
+
+	default:
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
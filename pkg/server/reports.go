@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bufio"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/report"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// resultsDir is where reports are saved, the same place the CLI writes them to
+const resultsDir = "results"
+
+// reportFormats are the formats a saved report's files can be in, the main
+// report's first
+var reportFormats = []report.Format{
+	report.FormatMarkdown, report.FormatHTML, report.FormatPDF,
+	report.FormatCSV, report.FormatXLSX, report.FormatDOT, report.FormatGraphML,
+}
+
+// ReportFile is a report saved under results/ (GET /api/reports): a server
+// job's or a CLI run's
+type ReportFile struct {
+	ID         string    `json:"id"`              // Job ID, the files' base name
+	Topic      string    `json:"topic,omitempty"` // From the job database, else the report's first heading
+	Formats    []string  `json:"formats"`         // Saved formats, e.g. ["md", "csv"]
+	Size       int64     `json:"size"`            // Bytes of the main file (the first format)
+	ModifiedAt time.Time `json:"modifiedAt"`      // When the main file was written
+}
+
+// writeReportFiles saves a report with its sources to results/<job id>.md, plus
+// its listings to <job id>.csv and its knowledge graph to <job id>.dot when it
+// has them, like the CLI. Returns the report's path.
+func writeReportFiles(jobID, topic string, result agent.ResearchResult) (string, error) {
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create results directory: %w", err)
+	}
+	path := filepath.Join(resultsDir, jobID+"."+report.FormatMarkdown.Extension())
+	if err := os.WriteFile(path, []byte(report.Markdown(result)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+
+	var extras []report.Format
+	if len(result.Records) > 0 {
+		extras = append(extras, report.FormatCSV)
+	}
+	if len(result.Entities) > 0 {
+		extras = append(extras, report.FormatDOT)
+	}
+	for _, format := range extras {
+		data, err := report.Render(format, topic, result)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(resultsDir, jobID+"."+format.Extension()), data, 0644)
+		}
+		if err != nil {
+			log.Printf("⚠️ Could not save the %s export: %v", format, err)
+		}
+	}
+	return path, nil
+}
+
+// saveReport writes the current job's report files (see writeReportFiles)
+func (s *Server) saveReport(result agent.ResearchResult) {
+	s.mu.RLock()
+	jobID, topic := s.currentJob.ID, s.currentJob.Topic
+	s.mu.RUnlock()
+	if jobID == "" {
+		return
+	}
+	if path, err := writeReportFiles(jobID, topic, result); err != nil {
+		log.Printf("⚠️ %v", err)
+	} else {
+		log.Printf("📄 Report saved to: %s", path)
+	}
+}
+
+// handleReports lists the reports saved under results/, newest first
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reports, err := s.listReports()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// listReports reads results/ for report files, grouping them by base name
+func (s *Server) listReports() ([]ReportFile, error) {
+	entries, err := os.ReadDir(resultsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []ReportFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	byID := make(map[string]*ReportFile)
+	for _, entry := range entries {
+		ext := strings.TrimPrefix(filepath.Ext(entry.Name()), ".")
+		id := strings.TrimSuffix(entry.Name(), "."+ext)
+		if !entry.Type().IsRegular() || id == "" || !slices.Contains(reportFormats, report.Format(ext)) {
+			continue
+		}
+		if byID[id] == nil {
+			byID[id] = &ReportFile{ID: id}
+		}
+		byID[id].Formats = append(byID[id].Formats, ext)
+	}
+
+	reports := make([]ReportFile, 0, len(byID))
+	for _, rf := range byID {
+		slices.SortFunc(rf.Formats, func(a, b string) int {
+			return slices.Index(reportFormats, report.Format(a)) - slices.Index(reportFormats, report.Format(b))
+		})
+		path := filepath.Join(resultsDir, rf.ID+"."+rf.Formats[0])
+		if info, err := os.Stat(path); err == nil {
+			rf.Size, rf.ModifiedAt = info.Size(), info.ModTime()
+		}
+		rf.Topic = s.reportTopic(rf.ID)
+		reports = append(reports, *rf)
+	}
+	slices.SortFunc(reports, func(a, b ReportFile) int { return b.ModifiedAt.Compare(a.ModifiedAt) })
+	return reports, nil
+}
+
+// reportTopic is the topic of the job a report belongs to, else the first
+// heading of its Markdown file ("" for neither)
+func (s *Server) reportTopic(id string) string {
+	if s.store != nil {
+		if job, err := s.store.GetJob(id); err == nil {
+			return job.Topic
+		}
+	}
+	f, err := os.Open(filepath.Join(resultsDir, id+"."+report.FormatMarkdown.Extension()))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for lines := 0; scanner.Scan() && lines < 20; lines++ {
+		if title, ok := strings.CutPrefix(scanner.Text(), "# "); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+// handleReportDownload serves a saved report file (GET
+// /api/reports/{id}/download, ?format= picks one of its formats, default its
+// main one)
+func (s *Server) handleReportDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.PathValue("id")
+	if !filepath.IsLocal(id) || strings.ContainsAny(id, `/\`) {
+		writeError(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	formats := reportFormats
+	if f := r.URL.Query().Get("format"); f != "" {
+		format, err := report.ParseFormat(f)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		formats = []report.Format{format}
+	}
+	for _, format := range formats {
+		path := filepath.Join(resultsDir, id+"."+format.Extension())
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		info, err := f.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			f.Close()
+			continue
+		}
+		defer f.Close()
+
+		name := id
+		if topic := s.reportTopic(id); topic != "" {
+			name = exportFilename(topic)
+		}
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format.Extension()))
+		http.ServeContent(w, r, "", info.ModTime(), f)
+		return
+	}
+	writeError(w, "Report not found", http.StatusNotFound)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"deep-research/pkg/agent"
+	"deep-research/pkg/document"
+	"deep-research/pkg/geo"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/price"
+	"log/slog"
+	"time"
+)
+
+// JobSetup is what a research job's agent.Config takes from outside its
+// ResearchRequest: the deployment's models and limits, and what the caller
+// prepared for the job. The server and the CLI both fill one in.
+type JobSetup struct {
+	EmbeddingModel   string                  // Enables query merging and near-duplicate detection (empty = both off)
+	SummarizerModel  string                  // Deep mode: smaller model for per-page summaries (optional)
+	SummarizerURL    string                  // Base URL serving SummarizerModel (empty = the main one)
+	WriterModel      string                  // Model for planning and the final report (optional)
+	WriterURL        string                  // Base URL serving WriterModel (empty = the main one)
+	CallProviders    map[string]llm.Provider // Provider per call type (see agent.NewCallProviders)
+	FetchWorkers     int                     // Deep mode: max page fetches at once (0 = unlimited)
+	SummarizeWorkers int                     // Deep mode: max page summaries sent to the LLM at once (0 = unlimited)
+	PlanRepairs      int                     // Times a plan that isn't valid JSON is sent back for correction (0 = none)
+	RoundDigestChars int                     // Exhaustive mode: max characters of a round's digest (0 = the raw results)
+	PromptPrice      float64                 // USD per million prompt tokens (0 = not priced)
+	CompletionPrice  float64                 // USD per million completion tokens
+	Geocoder         geo.Geocoder            // Locates listings for GeoArea (nil = OpenStreetMap's Nominatim)
+	Logger           *slog.Logger            // Research progress log (nil = console on stdout)
+
+	CheckpointPath string                    // Where exhaustive runs checkpoint (empty = no checkpoint)
+	SourcesDir     string                    // Where fetched pages are archived (empty = not archived)
+	PriceRates     price.Rates               // Currency: the exchange rates (nil = built-in approximate rates)
+	Knowledge      *agent.Knowledge          // Collection: the sources of earlier runs
+	Documents      []document.Document       // Documents the plan builds on and the report cites
+	OnProgress     func(agent.ProgressEvent) // Progress callback
+	StreamReport   bool                      // Send the report as it is written in progress events
+}
+
+// AgentConfig is the agent configuration for the request. Near-duplicate
+// detection (deep mode) and query merging (exhaustive planning) need an
+// embedding model; a zero threshold means the default, a negative one turns
+// them off.
+func (req ResearchRequest) AgentConfig(setup JobSetup) agent.Config {
+	dedupThreshold := 0.0
+	if setup.EmbeddingModel != "" && req.DeepMode {
+		dedupThreshold = dedupOrDefault(req.DedupThreshold, agent.DefaultDedupThreshold)
+	}
+	queryDedup := 0.0
+	if setup.EmbeddingModel != "" && !req.SimpleMode {
+		queryDedup = dedupOrDefault(req.QueryDedup, agent.DefaultQueryDedupThreshold)
+	}
+
+	return agent.Config{
+		MaxLoops:            req.Loops,
+		ParallelQuery:       req.Parallel,
+		DeepMode:            req.DeepMode,
+		ResultLinks:         req.ResultLinks,
+		SimpleMode:          req.SimpleMode,
+		MinResults:          req.MinResults,
+		DelayMs:             req.DelayMs,
+		MaxPages:            req.MaxPages,
+		ContextLength:       req.ContextLen,
+		CheckpointPath:      setup.CheckpointPath,
+		SourcesDir:          setup.SourcesDir,
+		ArchiveHTML:         req.ArchiveHTML,
+		ExtractionSchema:    req.ExtractionSchema,
+		ExtractEntities:     req.ExtractEntities,
+		FindConflicts:       req.FindConflicts,
+		CollectImages:       req.CollectImages,
+		EvaluateReport:      req.EvaluateReport,
+		ReviseBelow:         req.ReviseBelow,
+		SinglePassReport:    req.SinglePassReport,
+		DedupThreshold:      dedupThreshold,
+		QueryDedup:          queryDedup,
+		RelevanceFilter:     req.RelevanceFilter,
+		FixedQueryOrder:     req.FixedQueryOrder,
+		SummarizerModel:     setup.SummarizerModel,
+		SummarizerURL:       setup.SummarizerURL,
+		WriterModel:         setup.WriterModel,
+		WriterURL:           setup.WriterURL,
+		SeedURLs:            req.SeedURLs,
+		FollowLinks:         req.FollowLinks,
+		NextPages:           req.NextPages,
+		PriceCurrency:       req.Currency,
+		PriceRates:          setup.PriceRates,
+		GeoArea:             req.GeoArea,
+		GeoRadius:           req.GeoRadius,
+		GeoFilter:           req.GeoFilter,
+		Geocoder:            setup.Geocoder,
+		CrawlDepth:          req.CrawlDepth,
+		CrawlPages:          req.CrawlPages,
+		SitemapSites:        req.SitemapSites,
+		SitemapPattern:      req.SitemapPattern,
+		MaxSitemapPages:     req.SitemapPages,
+		CompareEntities:     req.Compare,
+		IncludeDomains:      req.IncludeDomains,
+		ExcludeDomains:      req.ExcludeDomains,
+		Categories:          req.Categories,
+		Engines:             req.SearXEngines,
+		TimeRange:           req.TimeRange,
+		MaxAgeDays:          req.MaxAgeDays,
+		PlanningPrompt:      req.PlanningPrompt,
+		ReportStructure:     req.ReportStructure,
+		ReportTemplate:      req.ReportTemplate,
+		ReportLanguage:      req.ReportLanguage,
+		OnProgress:          setup.OnProgress,
+		StreamReport:        setup.StreamReport,
+		Logger:              setup.Logger,
+		MaxLLMCalls:         req.MaxLLMCalls,
+		MaxHTTPRequests:     req.MaxHTTPRequests,
+		MaxDuration:         minutes(req.MaxMinutes),
+		ReportReserve:       minutes(req.ReserveMinutes),
+		Knowledge:           setup.Knowledge,
+		OnlyNew:             req.OnlyNew,
+		Documents:           setup.Documents,
+		FetchWorkers:        agent.WorkerLimit(setup.FetchWorkers),
+		SummarizeWorkers:    agent.WorkerLimit(setup.SummarizeWorkers),
+		PlanRepairs:         agent.RepairLimit(setup.PlanRepairs),
+		RoundDigestChars:    setup.RoundDigestChars,
+		PromptPrice:         setup.PromptPrice,
+		CompletionPrice:     setup.CompletionPrice,
+		MaxListingsPerQuery: req.ListingsPerQuery,
+		CallSettings:        req.CallSettings,
+		CallProviders:       setup.CallProviders,
+	}
+}
+
+// dedupOrDefault is a request's similarity threshold: fallback for 0, off (0) when negative
+func dedupOrDefault(threshold, fallback float64) float64 {
+	switch {
+	case threshold < 0:
+		return 0
+	case threshold == 0:
+		return fallback
+	}
+	return threshold
+}
+
+// minutes converts a request's minutes to a duration
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
@@ -0,0 +1,1933 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/document"
+	"deep-research/pkg/geo"
+	"deep-research/pkg/llm"
+	"deep-research/pkg/notify"
+	"deep-research/pkg/price"
+	"deep-research/pkg/profile"
+	"deep-research/pkg/proxy"
+	"deep-research/pkg/report"
+	"deep-research/pkg/retry"
+	"deep-research/pkg/search"
+	"deep-research/pkg/store"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/net/websocket"
+	"io"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long a shutdown waits for the running job's partial report
+const shutdownTimeout = 5 * time.Minute
+
+// HTTP server timeouts, so slow or stalled clients can't hold connections
+// forever. Writes get long enough for the answers that wait on the LLM (follow-ups,
+// diffs, exports); the event streams lift the write deadline (see noDeadline).
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 5 * time.Minute // Document uploads included
+	writeTimeout      = 15 * time.Minute
+	idleTimeout       = 2 * time.Minute
+)
+
+//go:embed web/*
+var webFS embed.FS
+
+// ResearchJob represents an active research job
+type ResearchJob struct {
+	ID        string               `json:"id"`
+	Topic     string               `json:"topic"`
+	Status    string               `json:"status"` // "idle", "queued", "planning", "awaiting_answers", "awaiting_approval", "running", "complete", "error", "cancelled", "interrupted"
+	Progress  agent.ProgressEvent  `json:"progress"`
+	Questions []string             `json:"questions,omitempty"` // awaiting_answers: the planner's clarifying questions, answered with POST /api/answer
+	Plan      *agent.ResearchPlan  `json:"plan,omitempty"`
+	Result    *agent.ResearchResult `json:"result,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	StartedAt time.Time            `json:"startedAt"`
+	Config    ResearchRequest      `json:"config"`
+}
+
+// ResearchRequest is the JSON body for starting research
+type ResearchRequest struct {
+	Topic            string   `json:"topic"`
+	Loops            int      `json:"loops"`
+	Parallel         int      `json:"parallel"`
+	ContextLen       int      `json:"contextLen"`
+	DeepMode         bool     `json:"deepMode"`
+	ResultLinks      bool     `json:"resultLinks"`
+	MinResults       int      `json:"minResults"`
+	DelayMs          int      `json:"delayMs"`
+	SimpleMode       bool     `json:"simpleMode"`
+	MaxPages         int      `json:"maxPages"`
+	ExtractionSchema string   `json:"extractionSchema"` // Deep mode: fields to extract per page
+	ExtractEntities  bool     `json:"extractEntities"`  // Extract entities and relations for a knowledge graph (export as dot or graphml)
+	FindConflicts    bool     `json:"findConflicts"`    // Compare the sources' claims and list the facts they disagree on
+	CollectImages    bool     `json:"collectImages"`    // Also search SearXNG's images category; the report gets a Media appendix
+	EvaluateReport   bool     `json:"evaluateReport"`   // Critique the report against the expected outcome and revise it once if it scores low
+	ReviseBelow      int      `json:"reviseBelow"`      // EvaluateReport: score out of 10 under which the report is revised (0 = default)
+	SinglePassReport bool     `json:"singlePassReport"` // Write the report in one prompt when the findings fit (default: outline, then write each section)
+	DedupThreshold   float64  `json:"dedupThreshold"`   // Deep mode: near-duplicate similarity (0 = default, negative = off; needs an embedding model)
+	QueryDedup       float64  `json:"queryDedup"`       // Similarity at which planned queries are merged (0 = default, negative = off; needs an embedding model)
+	RelevanceFilter  string   `json:"relevanceFilter"`  // Drop search results unrelated to the topic: "keywords" or "llm" (empty = off)
+	Currency         string   `json:"currency"`         // Convert the prices found to this currency, e.g. "EUR" (empty = off)
+	Rates            string   `json:"rates"`            // Currency: "ecb" for the ECB's daily rates (empty = built-in approximate rates)
+	GeoArea          string   `json:"geoArea"`          // Flag or drop listings outside this place, e.g. "Cluj-Napoca, Romania" or "46.77,23.59" (empty = off)
+	GeoRadius        float64  `json:"geoRadius"`        // GeoArea: km from its center (0 = the place's bounds)
+	GeoFilter        string   `json:"geoFilter"`        // GeoArea: "flag" (default) marks listings outside it, "drop" leaves them out
+	FixedQueryOrder  bool     `json:"fixedQueryOrder"`  // Search the planned queries in order (default: high-yield platforms and words first, dead ends skipped)
+	ArchiveSources   bool     `json:"archiveSources"`   // Save the text of every fetched page under results/<job id>/sources/ with an index.jsonl
+	ArchiveHTML      bool     `json:"archiveHtml"`      // ArchiveSources: also save each page as downloaded (HTML or PDF); implies ArchiveSources
+	AutoApprove      bool     `json:"autoApprove"`      // Start research as soon as the plan is ready (useful for queued jobs)
+	AskQuestions     bool     `json:"askQuestions"`     // Ask the planner's clarifying questions before planning: the job waits in "awaiting_answers" for POST /api/answer (ignored with AutoApprove)
+	SeedURLs         []string `json:"seedUrls"`         // Research these pages instead of searching
+	FollowLinks      bool     `json:"followLinks"`      // SeedURLs and SitemapSites: also fetch the item links found on each page
+	NextPages        int      `json:"nextPages"`        // Deep mode and FollowLinks: also read this many next pages of each listing index page (0 = off)
+	CrawlDepth       int      `json:"crawlDepth"`       // Deep mode, SeedURLs, and SitemapSites: follow in-domain next, pagination, and category links this many hops (0 = off)
+	CrawlPages       int      `json:"crawlPages"`       // CrawlDepth: most item pages collected per start page (0 = 30)
+	ListingsPerQuery int      `json:"listingsPerQuery"` // Deep mode: most listing pages read per search query, or per listing index page in exhaustive mode (0 = 5)
+	SitemapSites     []string `json:"sitemapSites"`     // Research pages from these sites' sitemaps instead of searching
+	SitemapPattern   string   `json:"sitemapPattern"`   // SitemapSites: regular expression the page URLs must match (empty = the planner picks one)
+	SitemapPages     int      `json:"sitemapPages"`     // SitemapSites: most pages researched (0 = 100)
+	Compare          []string `json:"compare"`          // Research each of these separately and write a comparison (at least two)
+	IncludeDomains   []string `json:"includeDomains"`   // Only use results and pages from these domains (empty = all)
+	ExcludeDomains   []string `json:"excludeDomains"`   // Never use results or pages from these domains
+	Categories       []string `json:"categories"`       // SearXNG categories, e.g. ["news"] (empty = instance default)
+	SearXEngines     []string `json:"searxEngines"`     // SearXNG engines to query (empty = instance default)
+	TimeRange        string   `json:"timeRange"`        // SearXNG: "day", "week", "month", or "year" (empty = any time)
+	MaxAgeDays       int      `json:"maxAgeDays"`       // Drop sources published more than this many days ago; undated ones are kept (0 = no limit)
+	Profile          string   `json:"profile"`          // Research profile filling in the fields left unset (see GET /api/profiles)
+	PlanningPrompt   string   `json:"planningPrompt"`   // Extra planner instructions (default: the profile's)
+	ReportStructure  string   `json:"reportStructure"`  // How the report should be structured (default: the profile's)
+	ReportTemplate   string   `json:"reportTemplate"`   // Go text/template the finished report is rendered with (default: the profile's; empty = none)
+	ReportLanguage   string   `json:"reportLanguage"`   // Language for summaries and the report, e.g. "English" (empty = the model decides)
+	MaxLLMCalls      int      `json:"maxLlmCalls"`      // Stop researching after this many LLM calls and write the report (0 = no limit)
+	MaxHTTPRequests  int      `json:"maxHttpRequests"`  // Stop researching after this many searches and page fetches (0 = no limit)
+	MaxMinutes       float64  `json:"maxMinutes"`       // Finish the job, report included, within this many minutes of approval (0 = no limit)
+	ReserveMinutes   float64  `json:"reserveMinutes"`   // MaxMinutes: minutes kept for writing the report (0 = a quarter of MaxMinutes, at least 1)
+	NoCache          bool     `json:"noCache"`          // Bypass the search, page, and LLM caches for this job
+	Collection       string   `json:"collection"`       // Knowledge base the job belongs to; the report says what changed since its last run
+	OnlyNew          bool     `json:"onlyNew"`          // With Collection: skip the results and pages the collection already has
+	Documents        []string `json:"documents"`        // IDs of uploaded documents (POST /api/documents) the plan builds on and the report cites
+
+	// LLM temperature, max tokens, and system prompt per call type: planning,
+	// summarization, compression, or report (missing = the backend's, temperature 0)
+	CallSettings map[string]agent.CallSettings `json:"callSettings,omitempty"`
+}
+
+// ReviseRequest is the JSON body for revising a plan
+type ReviseRequest struct {
+	Feedback string `json:"feedback"`
+}
+
+// QueriesRequest is the JSON body for replacing the plan's search queries
+type QueriesRequest struct {
+	Queries []string `json:"queries"` // The full edited list, in order
+}
+
+// AnswerRequest is the JSON body for answering the plan's clarifying questions
+type AnswerRequest struct {
+	Answers  []string `json:"answers"`  // One answer per clarifying question, in order ("" = unanswered)
+	Feedback string   `json:"feedback"` // Optional extra free-text feedback
+}
+
+// StatusResponse is the body of the endpoints that only change the job's status
+// (approve, cancel, reset, and removing a queued job)
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// FollowUpRequest is the JSON body for asking a question about a finished report
+type FollowUpRequest struct {
+	Question    string `json:"question"`
+	ID          string `json:"id"`          // Job whose report to ask about (empty = the current job)
+	MaxSearches *int   `json:"maxSearches"` // Supplemental searches allowed (omitted = agent.DefaultFollowUpSearches, 0 = none)
+}
+
+// DiffRequest is the JSON body for comparing two finished jobs
+type DiffRequest struct {
+	Earlier   string `json:"earlier"`   // Job id of the earlier run
+	Later     string `json:"later"`     // Job id of the later run
+	NoSummary bool   `json:"noSummary"` // Only list the differences, without the LLM's "what's new" summary
+}
+
+// TagsRequest is the JSON body for setting a job's tags, and the response
+type TagsRequest struct {
+	Tags []string `json:"tags"` // Replace the job's tags (lowercased, spaces become hyphens; empty clears them)
+}
+
+// Server holds the HTTP server state
+type Server struct {
+	lmURL           string
+	llmProvider     string
+	apiKey          string
+	model           string
+	embedModel      string
+	contextLen      int // Default for requests (0 = defaultContextLen)
+	summarizerModel string
+	summarizerURL   string
+	writerModel     string
+	writerURL       string
+	callModels      map[string]string
+	searxURL        string
+	engines         []string
+	braveAPIKey     string
+	googleAPIKey    string
+	googleCX        string
+	cacheDir        string
+	cacheTTL        time.Duration
+	llmCacheTTL     time.Duration
+	rateLimit       search.RateLimitConfig
+	summaryWorkers  int
+	planRepairs     int
+	roundDigest     int
+	promptPrice     float64
+	completionPrice float64
+	ignoreRobots    bool
+	noWayback       bool
+	noEnrich        bool
+	retry           retry.Policy
+	browser         *search.BrowserConfig
+	proxies         proxy.Settings    // Resolved: every backend's setting filled in
+	llmTransport    http.RoundTripper // LLM requests (nil = the environment's proxy)
+	searchTransport http.RoundTripper // Searches
+	fetchTransport  http.RoundTripper // Page fetches, robots.txt, and Wayback lookups
+	currentJob      *ResearchJob
+	queue           []*ResearchJob // Jobs waiting for the current one to finish
+	maxQueue        int
+	authToken       string
+	usersFile       string
+	profilesDir     string
+	extractorsDir   string
+	geocoder        geo.Geocoder // Shared by the jobs, so Nominatim's rate limit and answers carry over
+	profiles        []profile.Profile
+	logger          *slog.Logger // Research progress log (nil = console on stdout)
+	users           []apiUser    // API tokens (empty = auth disabled); loaded by Handler
+	mu              sync.RWMutex
+	events          *eventLog // Progress events per job, replayed to clients that connect late
+	cancelFunc      context.CancelCauseFunc
+	researcher      *agent.DeepResearcher
+	store           *store.Store   // Job persistence (nil when the database could not be opened)
+	research        sync.WaitGroup // Running executeResearch calls
+	shuttingDown    bool           // Set by Shutdown; no new jobs start
+	closing         chan struct{}  // Closed when Shutdown is done, ending progress streams
+
+	notifier    *notify.Notifier // Chat webhooks (disabled when none is configured)
+	publicURL   string           // Base URL for links in chat messages
+	slackSecret string           // Verifies Slack slash commands (empty = route disabled)
+	discordKey  string           // Verifies Discord interactions (empty = route disabled)
+}
+
+// Options configures the web server
+type Options struct {
+	Port            string                 // Port to listen on (e.g. "8081")
+	LMURL           string                 // LLM API base URL
+	LLMProvider     string                 // llm.ProviderLMStudio, ProviderOllama, ProviderLlamaCpp, ProviderOpenAI, ProviderAzure, ProviderOpenRouter, or ProviderAnthropic
+	APIKey          string                 // API key for the hosted providers (openai, azure, openrouter, anthropic)
+	Model           string                 // Model name passed to the LLM backend
+	EmbeddingModel  string                 // Embedding model for query merging, near-duplicate detection, and report retrieval (optional)
+	ContextLen      int                    // Context window of requests that don't set contextLen (0 = 32768)
+	SummarizerModel string                 // Deep mode: smaller model for per-page summaries (optional)
+	SummarizerURL   string                 // Base URL serving SummarizerModel (empty = LMURL)
+	WriterModel     string                 // Model for planning and the final report (optional)
+	WriterURL       string                 // Base URL serving WriterModel (empty = LMURL)
+	CallModels      map[string]string      // Model per call type, e.g. "report": "anthropic:claude-sonnet-4-5" (see agent.NewCallProviders)
+	SearXURL        string                 // SearXNG base URL, or several comma-separated ones
+	Engines         []string               // Search engines to aggregate (default: searxng only)
+	BraveAPIKey     string                 // Brave Search API key (brave engine)
+	GoogleAPIKey    string                 // Google Custom Search API key (google engine)
+	GoogleCX        string                 // Google Programmable Search Engine ID (google engine)
+	CacheDir        string                 // Disk cache for search results and fetched pages
+	CacheTTL        time.Duration          // How long cache entries are reused (0 disables the cache)
+	LLMCacheTTL     time.Duration          // How long cached LLM responses (in CacheDir/llm) are reused (0 disables the LLM cache)
+	RateLimit       search.RateLimitConfig // Deep mode page-fetch limits (per host and overall)
+	SummaryWorkers  int                    // Deep mode: max page summaries sent to the LLM at once (0 = unlimited)
+	PlanRepairs     int                    // Times a plan that isn't valid JSON is sent back to the model for correction (0 = none)
+	RoundDigest     int                    // Exhaustive mode: max characters of a round's digest in the research context (0 = the raw results)
+	PromptPrice     float64                // USD per million prompt tokens, for the cost in each job's usage (0 = not priced)
+	CompletionPrice float64                // USD per million completion tokens
+	IgnoreRobots    bool                   // Deep mode: fetch pages robots.txt disallows and ignore Crawl-delay
+	NoWayback       bool                   // Deep mode: don't fall back to Wayback Machine snapshots of dead pages
+	NoEnrich        bool                   // Don't fill in short search-result snippets from the pages' metadata
+	Retry           retry.Policy           // Retries of failed searches and LLM requests (zero = retry.DefaultPolicy)
+	Browser         *search.BrowserConfig  // Deep mode: render pages in headless Chrome (nil = plain HTTP fetches)
+	Proxies         proxy.Settings         // Proxies for searches, page fetches, and the LLM (empty = the environment's)
+	DBPath          string                 // SQLite job database (empty disables persistence)
+	MaxQueue        int                    // Max jobs waiting behind the current one (0 = reject new jobs while busy)
+	AuthToken       string                 // Bearer token required on /api/* (user "admin"; empty = no auth unless UsersFile is set)
+	UsersFile       string                 // File of "name:token" lines, each a token accepted on /api/*
+	ProfilesDir     string                 // Directory of YAML research profiles added to the built-in ones
+	ExtractorsDir   string                 // Directory of YAML site rules for deep-mode listing links and detail fields
+	Geocoder        geo.Geocoder           // Locates listings for geoArea requests (nil = OpenStreetMap's Nominatim)
+	Profiles        []profile.Profile      // Profiles from the config file, added to the built-in ones (ProfilesDir files replace them)
+	Logger          *slog.Logger           // Where research progress is logged (nil = console on stdout at info level)
+
+	// Chat integrations (see chat.go)
+	SlackWebhook       string // Slack incoming webhook told when a plan is ready and a job completes or fails
+	DiscordWebhook     string // Discord webhook told the same
+	PublicURL          string // Base URL chat users reach the server at, for links in messages (empty = no links)
+	SlackSigningSecret string // Slack app signing secret; enables the /research slash command at /slack/commands
+	DiscordPublicKey   string // Discord app public key (hex); enables the /research command at /discord/interactions
+}
+
+// New creates a server; call Close when done to release the job database
+func New(opts Options) *Server {
+	server := &Server{
+		lmURL:           opts.LMURL,
+		llmProvider:     opts.LLMProvider,
+		apiKey:          opts.APIKey,
+		model:           opts.Model,
+		embedModel:      opts.EmbeddingModel,
+		contextLen:      opts.ContextLen,
+		summarizerModel: opts.SummarizerModel,
+		summarizerURL:   opts.SummarizerURL,
+		writerModel:     opts.WriterModel,
+		writerURL:       opts.WriterURL,
+		callModels:      opts.CallModels,
+		searxURL:        opts.SearXURL,
+		engines:         opts.Engines,
+		braveAPIKey:     opts.BraveAPIKey,
+		googleAPIKey:    opts.GoogleAPIKey,
+		googleCX:        opts.GoogleCX,
+		cacheDir:        opts.CacheDir,
+		cacheTTL:        opts.CacheTTL,
+		llmCacheTTL:     opts.LLMCacheTTL,
+		rateLimit:       opts.RateLimit,
+		summaryWorkers:  opts.SummaryWorkers,
+		planRepairs:     opts.PlanRepairs,
+		roundDigest:     opts.RoundDigest,
+		promptPrice:     opts.PromptPrice,
+		completionPrice: opts.CompletionPrice,
+		ignoreRobots:    opts.IgnoreRobots,
+		noWayback:       opts.NoWayback,
+		noEnrich:        opts.NoEnrich,
+		retry:           opts.Retry,
+		browser:         opts.Browser,
+		proxies:         opts.Proxies.Resolve(),
+		currentJob:      &ResearchJob{Status: "idle"},
+		maxQueue:        opts.MaxQueue,
+		authToken:       opts.AuthToken,
+		usersFile:       opts.UsersFile,
+		profilesDir:     opts.ProfilesDir,
+		extractorsDir:   opts.ExtractorsDir,
+		geocoder:        opts.Geocoder,
+		profiles:        opts.Profiles,
+		logger:          opts.Logger,
+		closing:         make(chan struct{}),
+		publicURL:       strings.TrimSuffix(opts.PublicURL, "/"),
+		slackSecret:     opts.SlackSigningSecret,
+		discordKey:      opts.DiscordPublicKey,
+	}
+
+	// Invalid proxy settings leave the environment's proxy in place (Run rejects them)
+	server.llmTransport, _ = proxy.Transport(server.proxies.LLM)
+	server.searchTransport, _ = proxy.Transport(server.proxies.Search)
+	server.fetchTransport, _ = proxy.Transport(server.proxies.Fetch)
+	server.notifier = notify.New(notify.Config{
+		SlackWebhook:   opts.SlackWebhook,
+		DiscordWebhook: opts.DiscordWebhook,
+		Transport:      server.searchTransport,
+	})
+
+	// Open job database (the server still works without it, just forgets jobs on restart)
+	if opts.DBPath != "" {
+		jobStore, err := store.Open(opts.DBPath)
+		if err != nil {
+			log.Printf("⚠️ Job persistence disabled: %v", err)
+		} else {
+			server.store = jobStore
+			if n, err := jobStore.MarkInterrupted(); err != nil {
+				log.Printf("⚠️ %v", err)
+			} else if n > 0 {
+				log.Printf("⚠️ Marked %d job(s) from a previous run as interrupted", n)
+			}
+		}
+	}
+	server.events = newEventLog(server.store)
+	return server
+}
+
+// Close releases the job database
+func (s *Server) Close() error {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.Close()
+}
+
+// Handler returns the HTTP handler serving the API and the embedded web UI
+// (behind token auth when a token or users file is configured)
+func (s *Server) Handler() (http.Handler, error) {
+	users, err := loadUsers(s.authToken, s.usersFile)
+	if err != nil {
+		return nil, err
+	}
+	s.users = users
+
+	mux := http.NewServeMux()
+
+	// API routes
+	mux.HandleFunc("/api/research", s.handleResearch)
+	mux.HandleFunc("/api/approve", s.handleApprove)
+	mux.HandleFunc("/api/revise", s.handleRevise)
+	mux.HandleFunc("/api/answer", s.handleAnswer)
+	mux.HandleFunc("/api/answers", s.handleAnswer) // Its former route for questions asked before planning
+	mux.HandleFunc("/api/plan/queries", s.handlePlanQueries)
+	mux.HandleFunc("/api/cancel", s.handleCancel)
+	mux.HandleFunc("/api/finish", s.handleFinish)
+	mux.HandleFunc("/api/reset", s.handleReset)
+	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/progress", s.handleProgress)
+	mux.HandleFunc("/api/ws", s.handleWebSocket)
+	mux.HandleFunc("/api/results", s.handleResults)
+	mux.HandleFunc("/api/results/export", s.handleExport)
+	mux.HandleFunc("/api/results/partial", s.handlePartial)
+	mux.HandleFunc("/api/followup", s.handleFollowUp)
+	mux.HandleFunc("/api/diff", s.handleDiff)
+	mux.HandleFunc("/api/profiles", s.handleProfiles)
+	mux.HandleFunc("/api/reports", s.handleReports)
+	mux.HandleFunc("/api/reports/{id}/download", s.handleReportDownload)
+	mux.HandleFunc("/api/models", s.handleModels)
+	mux.HandleFunc("/api/jobs", s.handleJobs)
+	mux.HandleFunc("/api/collections", s.handleCollections)
+	mux.HandleFunc("/api/documents", s.handleDocuments)
+	mux.HandleFunc("/api/documents/{id}", s.handleDocuments)
+	mux.HandleFunc("/api/queue", s.handleQueue)
+	mux.HandleFunc("/api/queue/{id}", s.handleQueue)
+	mux.HandleFunc("/api/auth/status", s.handleAuthStatus)
+	mux.HandleFunc("/api/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/auth/logout", s.handleLogout)
+	mux.HandleFunc("/api/openapi.json", s.handleOpenAPI)
+
+	// Job-scoped routes; the routes above take ?id=<job> for the same
+	mux.HandleFunc("/api/jobs/{id}", s.handleJob)
+	mux.HandleFunc("/api/jobs/{id}/results", withPathID(s.handleResults))
+	mux.HandleFunc("/api/jobs/{id}/export", withPathID(s.handleExport))
+	mux.HandleFunc("/api/jobs/{id}/events", withPathID(s.handleProgress))
+	mux.HandleFunc("/api/jobs/{id}/ws", withPathID(s.handleWebSocket))
+	mux.HandleFunc("/api/jobs/{id}/followup", withPathID(s.handleFollowUp))
+	mux.HandleFunc("/api/jobs/{id}/tags", s.handleJobTags)
+	mux.HandleFunc("/api/", handleNotFound)
+
+	// Health checks (outside /api, so probes need no token)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+
+	// Chat commands (outside /api; each request is verified by the platform's signature)
+	if s.slackSecret != "" {
+		mux.HandleFunc("/slack/commands", s.handleSlackCommand)
+	}
+	if s.discordKey != "" {
+		key, err := hex.DecodeString(s.discordKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Discord public key: want %d hex-encoded bytes", ed25519.PublicKeySize)
+		}
+		mux.HandleFunc("/discord/interactions", s.handleDiscordInteraction(ed25519.PublicKey(key)))
+	}
+
+	// Serve embedded web files
+	webContent, err := fs.Sub(webFS, "web")
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle("/", http.FileServer(http.FS(webContent)))
+	return s.requireAuth(mux), nil
+}
+
+// Run starts the web server and blocks until it fails or receives SIGINT/SIGTERM,
+// in which case it shuts down gracefully (see Server.Shutdown)
+func Run(opts Options) error {
+	if err := opts.Proxies.Validate(); err != nil {
+		return err
+	}
+	if _, err := agent.NewCallProviders(opts.LLMProvider, llm.Config{BaseURL: opts.LMURL, APIKey: opts.APIKey, Model: opts.Model}, opts.CallModels); err != nil {
+		return fmt.Errorf("invalid call model: %w", err)
+	}
+	server := New(opts)
+	defer server.Close()
+
+	handler, err := server.Handler()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Deep Research Web UI\n")
+	fmt.Printf("   LLM:       %s (%s)\n", opts.LMURL, opts.LLMProvider)
+	server.printModel()
+	if opts.EmbeddingModel != "" {
+		fmt.Printf("   Embedding: %s\n", opts.EmbeddingModel)
+	}
+	if opts.SummarizerModel != "" || opts.SummarizerURL != "" {
+		fmt.Printf("   Summaries: %s\n", strings.TrimSpace(opts.SummarizerModel+" "+opts.SummarizerURL))
+	}
+	if opts.WriterModel != "" || opts.WriterURL != "" {
+		fmt.Printf("   Writer:    %s\n", strings.TrimSpace(opts.WriterModel+" "+opts.WriterURL))
+	}
+	for _, callType := range agent.CallTypes {
+		if model, ok := opts.CallModels[callType]; ok {
+			fmt.Printf("   %-11s%s\n", callType+":", model)
+		}
+	}
+	fmt.Printf("   SearXNG:   %s\n", opts.SearXURL)
+	if len(opts.Engines) > 0 {
+		fmt.Printf("   Engines:   %s\n", strings.Join(opts.Engines, ", "))
+	}
+	if opts.CacheTTL > 0 && opts.CacheDir != "" {
+		fmt.Printf("   Cache:     %s (TTL %s)\n", opts.CacheDir, opts.CacheTTL)
+	}
+	if opts.LLMCacheTTL > 0 && opts.CacheDir != "" {
+		fmt.Printf("   LLM cache: %s (TTL %s)\n", filepath.Join(opts.CacheDir, "llm"), opts.LLMCacheTTL)
+	}
+	if opts.Proxies.Explicit() {
+		fmt.Printf("   Proxies:   searches via %s, pages via %s, LLM via %s\n", proxy.Describe(server.proxies.Search), proxy.Describe(server.proxies.Fetch), proxy.Describe(server.proxies.LLM))
+	}
+	if server.store != nil {
+		fmt.Printf("   Database:  %s\n", opts.DBPath)
+	}
+	if opts.MaxQueue > 0 {
+		fmt.Printf("   Queue:     up to %d waiting jobs\n", opts.MaxQueue)
+	}
+	if len(server.users) > 0 {
+		fmt.Printf("   Auth:      %d API token(s)\n", len(server.users))
+	}
+	fmt.Printf("   Web UI:    http://localhost:%s\n", opts.Port)
+	fmt.Println("\nOpen your browser to start researching!")
+
+	httpServer := &http.Server{
+		Addr:              ":" + opts.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	// First signal shuts down gracefully (a running job still writes its partial
+	// report), a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		stop()
+	}
+	log.Printf("🛑 Shutting down (press Ctrl+C again to quit immediately)...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// The API stays up while the job finishes so clients can follow it
+	err = server.Shutdown(shutdownCtx)
+	if httpErr := httpServer.Shutdown(shutdownCtx); err == nil {
+		err = httpErr
+	}
+	if err == nil {
+		log.Printf("👋 Server stopped")
+	}
+	return err
+}
+
+// handleResearch creates a plan and returns it for approval
+func (s *Server) handleResearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request
+	var req ResearchRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Topic == "" {
+		writeError(w, "Topic is required", http.StatusBadRequest)
+		return
+	}
+	for _, u := range req.SeedURLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			writeError(w, fmt.Sprintf("Not an http(s) URL: %q", u), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Compare) == 1 {
+		writeError(w, "A comparison needs at least two entities", http.StatusBadRequest)
+		return
+	}
+	if len(req.Compare) > 0 && len(req.SeedURLs) > 0 {
+		writeError(w, "compare and seedUrls can't be combined", http.StatusBadRequest)
+		return
+	}
+	if len(req.SitemapSites) > 0 && (len(req.SeedURLs) > 0 || len(req.Compare) > 0) {
+		writeError(w, "sitemapSites can't be combined with seedUrls or compare", http.StatusBadRequest)
+		return
+	}
+	if _, err := regexp.Compile(req.SitemapPattern); err != nil {
+		writeError(w, fmt.Sprintf("Invalid sitemapPattern: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.SitemapPages < 0 {
+		writeError(w, "sitemapPages must not be negative", http.StatusBadRequest)
+		return
+	}
+	if err := agent.ValidateCallSettings(req.CallSettings); err != nil {
+		writeError(w, fmt.Sprintf("Invalid callSettings: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := search.ValidateTimeRange(req.TimeRange); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := agent.ValidateRelevanceFilter(req.RelevanceFilter); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := agent.ValidateCrawl(req.CrawlDepth, req.CrawlPages); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := agent.ValidateNextPages(req.NextPages); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ListingsPerQuery < 0 {
+		writeError(w, "listingsPerQuery must not be negative", http.StatusBadRequest)
+		return
+	}
+	if req.Rates != "" && req.Rates != price.RatesECB {
+		writeError(w, fmt.Sprintf("rates must be empty or %q", price.RatesECB), http.StatusBadRequest)
+		return
+	}
+	if req.Rates == "" {
+		// The ECB's table is checked once it is fetched
+		if err := agent.ValidatePriceCurrency(req.Currency, nil); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	req.GeoArea = strings.TrimSpace(req.GeoArea)
+	if err := agent.ValidateGeo(req.GeoArea, req.GeoRadius, req.GeoFilter); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.MaxAgeDays < 0 {
+		writeError(w, "maxAgeDays must not be negative", http.StatusBadRequest)
+		return
+	}
+	req.Collection = strings.TrimSpace(req.Collection)
+	if req.OnlyNew && req.Collection == "" {
+		writeError(w, "onlyNew needs a collection", http.StatusBadRequest)
+		return
+	}
+	if req.Collection != "" && s.store == nil {
+		writeError(w, "Collections need job persistence (--db)", http.StatusBadRequest)
+		return
+	}
+	if len(req.Documents) > 0 && s.store == nil {
+		writeError(w, "Documents need job persistence (--db)", http.StatusBadRequest)
+		return
+	}
+	for _, id := range req.Documents {
+		if _, err := s.store.Document(id); errors.Is(err, store.ErrDocumentNotFound) {
+			writeError(w, fmt.Sprintf("Unknown document %q", id), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.Profile != "" {
+		p, err := profile.Find(s.profilesDir, req.Profile, s.profiles...)
+		if errors.Is(err, profile.ErrNotFound) {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		applyProfile(&req, p)
+	}
+	if err := agent.ValidateReportTemplate(req.ReportTemplate); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.applyDefaults(&req)
+
+	// Create job
+	job := &ResearchJob{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		Topic:     req.Topic,
+		Status:    "planning",
+		StartedAt: time.Now(),
+		Config:    req,
+	}
+
+	// Queue the job if another one is in progress
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		writeError(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if isActive(s.currentJob.Status) {
+		s.mu.Unlock()
+		s.enqueue(w, job)
+		return
+	}
+	s.currentJob = job
+	s.mu.Unlock()
+	s.persistJob()
+
+	// Create plan synchronously and return for approval
+	s.planJob(req)
+
+	// Return current job with plan
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.currentJob)
+}
+
+// defaultContextLen is the context window of requests that don't set one,
+// without Options.ContextLen
+const defaultContextLen = 32768
+
+// applyDefaults fills in the request fields left unset
+func (s *Server) applyDefaults(req *ResearchRequest) {
+	if req.Loops <= 0 {
+		req.Loops = 5
+	}
+	if req.Parallel <= 0 {
+		req.Parallel = 5
+	}
+	if req.ContextLen <= 0 {
+		req.ContextLen = s.defaultContextLen()
+	}
+	if req.MinResults <= 0 {
+		req.MinResults = 20
+	}
+	if req.DelayMs <= 0 {
+		req.DelayMs = 500
+	}
+}
+
+// createPlan generates the research plan
+func (s *Server) createPlan(req ResearchRequest) {
+	s.mu.RLock()
+	jobID := s.currentJob.ID
+	s.mu.RUnlock()
+
+	// Exhaustive runs checkpoint after every round so they survive crashes
+	checkpointPath := ""
+	if !req.SimpleMode {
+		checkpointPath = filepath.Join("results", jobID+".checkpoint.json")
+	}
+
+	// Fetched pages are archived per job, next to its report
+	sourcesDir := ""
+	if req.ArchiveSources || req.ArchiveHTML {
+		sourcesDir = filepath.Join("results", jobID, "sources")
+	}
+
+	// Setup agent with progress callback
+	researcher, err := s.newResearcher(req, checkpointPath, sourcesDir, s.onProgress)
+	if err != nil {
+		s.setError(fmt.Sprintf("Failed to set up research: %v", err))
+		return
+	}
+
+	// Store researcher for later use
+	s.mu.Lock()
+	s.researcher = researcher
+	s.mu.Unlock()
+
+	// Emit planning event
+	s.onProgress(agent.ProgressEvent{
+		Phase:   "planning",
+		Message: "Creating research plan...",
+		Percent: 2,
+	})
+
+	// Create plan (cancellable via /api/cancel)
+	ctx, cancel := s.planningContext()
+	defer cancel()
+
+	// Ask the clarifying questions first, so the plan is built from the answers
+	if req.AskQuestions && !req.AutoApprove {
+		questions, err := researcher.ClarifyingQuestions(ctx, req.Topic)
+		if ctx.Err() != nil {
+			return // Cancelled - handleCancel already reset the job
+		}
+		if err != nil {
+			s.setError(fmt.Sprintf("Failed to ask clarifying questions: %v", err))
+			return
+		}
+		if len(questions) > 0 {
+			s.mu.Lock()
+			s.currentJob.Questions = questions
+			s.currentJob.Status = "awaiting_answers"
+			s.mu.Unlock()
+			s.persistJob()
+			s.notifyJob()
+			s.onProgress(agent.ProgressEvent{
+				Phase:   "awaiting_answers",
+				Message: fmt.Sprintf("%d clarifying questions. Awaiting answers.", len(questions)),
+				Percent: 3,
+			})
+			return
+		}
+	}
+
+	var plan agent.ResearchPlan
+	if req.SimpleMode {
+		plan, err = researcher.CreatePlanWithContext(ctx, req.Topic, "")
+	} else {
+		plan, err = researcher.CreatePlanExhaustiveWithContext(ctx, req.Topic, "")
+	}
+
+	if ctx.Err() != nil {
+		return // Cancelled - handleCancel already reset the job
+	}
+	if err != nil {
+		s.setError(fmt.Sprintf("Failed to create plan: %v", err))
+		return
+	}
+
+	// Update job with plan and wait for approval
+	s.mu.Lock()
+	s.currentJob.Plan = &plan
+	s.currentJob.Status = "awaiting_approval"
+	s.mu.Unlock()
+	s.persistJob()
+
+	if !req.AutoApprove {
+		s.notifyJob()
+	}
+	s.onProgress(agent.ProgressEvent{
+		Phase:   "awaiting_approval",
+		Message: fmt.Sprintf("Plan ready with %d search queries. Awaiting approval.", len(plan.SearchQueries)),
+		Percent: 5,
+	})
+}
+
+// newResearcher builds a researcher for a request from the server's LLM and search settings
+func (s *Server) newResearcher(req ResearchRequest, checkpointPath, sourcesDir string, onProgress func(agent.ProgressEvent)) (*agent.DeepResearcher, error) {
+	// Setup LLM client
+	llmConfig := llm.Config{
+		BaseURL:        s.lmURL,
+		APIKey:         s.apiKey,
+		Model:          s.model,
+		EmbeddingModel: s.embedModel,
+		Temperature:    0.0,
+		ContextLength:  req.ContextLen,
+		Timeout:        5 * time.Minute,
+		Retry:          s.retry,
+		Transport:      s.llmTransport,
+	}
+	llmClient, err := llm.NewProvider(s.llmProvider, llmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	callProviders, err := agent.NewCallProviders(s.llmProvider, llmConfig, s.callModels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	var priceRates price.Rates
+	if req.Currency != "" {
+		if priceRates, err = price.LoadRates(context.Background(), req.Rates); err != nil {
+			return nil, err
+		}
+		if err := agent.ValidatePriceCurrency(req.Currency, priceRates); err != nil {
+			return nil, err
+		}
+	}
+	if s.llmCacheTTL > 0 && s.cacheDir != "" && !req.NoCache {
+		cache := llm.CacheConfig{Dir: filepath.Join(s.cacheDir, "llm"), TTL: s.llmCacheTTL, Logger: s.logger}
+		llmClient = llm.NewCachedProvider(llmClient, cache)
+		for callType, p := range callProviders {
+			callProviders[callType] = llm.NewCachedProvider(p, cache)
+		}
+	}
+
+	// Setup search engines
+	extractors, err := search.LoadExtractors(s.extractorsDir)
+	if err != nil {
+		return nil, err
+	}
+	cache := search.CacheConfig{Dir: s.cacheDir, TTL: s.cacheTTL, Logger: s.logger}
+	if req.NoCache {
+		cache = search.CacheConfig{}
+	}
+	searcher, err := search.NewStack(search.StackConfig{
+		Engines: s.engines,
+		Search: search.Config{
+			SearXURL:       s.searxURL,
+			BraveAPIKey:    s.braveAPIKey,
+			GoogleKey:      s.googleAPIKey,
+			GoogleCX:       s.googleCX,
+			Retry:          s.retry,
+			Transport:      s.searchTransport,
+			FetchTransport: s.fetchTransport,
+			Logger:         s.logger,
+			Extractors:     extractors,
+		},
+		Browser:      s.browser,
+		RateLimit:    s.rateLimit,
+		IgnoreRobots: s.ignoreRobots,
+		NoEnrich:     s.noEnrich,
+		Cache:        cache,
+		NoWayback:    s.noWayback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search client: %w", err)
+	}
+
+	// A collection remembers the sources of earlier runs on the topic
+	var knowledge *agent.Knowledge
+	if req.Collection != "" && s.store != nil {
+		if knowledge, err = s.store.Knowledge(req.Collection); err != nil {
+			return nil, err
+		}
+	}
+
+	// Uploaded documents ground the plan and the report
+	var documents []document.Document
+	if s.store != nil {
+		for _, id := range req.Documents {
+			doc, err := s.store.Document(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load document %s: %w", id, err)
+			}
+			documents = append(documents, doc)
+		}
+	}
+
+	return agent.NewDeepResearcher(llmClient, searcher, req.AgentConfig(JobSetup{
+		EmbeddingModel:   s.embedModel,
+		SummarizerModel:  s.summarizerModel,
+		SummarizerURL:    s.summarizerURL,
+		WriterModel:      s.writerModel,
+		WriterURL:        s.writerURL,
+		CallProviders:    callProviders,
+		FetchWorkers:     s.rateLimit.Concurrency,
+		SummarizeWorkers: s.summaryWorkers,
+		PlanRepairs:      s.planRepairs,
+		RoundDigestChars: s.roundDigest,
+		PromptPrice:      s.promptPrice,
+		CompletionPrice:  s.completionPrice,
+		Geocoder:         s.geocoder,
+		Logger:           s.logger,
+		CheckpointPath:   checkpointPath,
+		SourcesDir:       sourcesDir,
+		PriceRates:       priceRates,
+		Knowledge:        knowledge,
+		Documents:        documents,
+		OnProgress:       onProgress,
+		StreamReport:     true,
+	})), nil
+}
+
+// handleApprove starts research execution after plan approval
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.startResearch(); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StatusResponse{Status: "running"})
+}
+
+// handleRevise regenerates the plan with user feedback
+func (s *Server) handleRevise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	status := s.currentJob.Status
+	req := s.currentJob.Config
+	var answers []agent.QuestionAnswer
+	if s.currentJob.Plan != nil {
+		answers = s.currentJob.Plan.Answers
+	}
+	s.mu.RUnlock()
+
+	if status != "awaiting_approval" {
+		writeError(w, "No plan awaiting revision", http.StatusBadRequest)
+		return
+	}
+
+	// Parse revision feedback
+	var reviseReq ReviseRequest
+	if !decodeJSON(w, r, &reviseReq) {
+		return
+	}
+
+	// Update status back to planning
+	s.mu.Lock()
+	s.currentJob.Status = "planning"
+	s.currentJob.Plan = nil
+	s.mu.Unlock()
+
+	// Regenerate plan with feedback (keeping earlier answers)
+	s.createPlanWithFeedback(req, answers, reviseReq.Feedback)
+
+	// Return updated job
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.currentJob)
+}
+
+// handlePlanQueries replaces the search queries of the plan awaiting approval,
+// so useless variants can be pruned (or new ones added) before research starts
+func (s *Server) handlePlanQueries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var queriesReq QueriesRequest
+	if !decodeJSON(w, r, &queriesReq) {
+		return
+	}
+	queries := agent.CleanQueries(queriesReq.Queries)
+	if len(queries) == 0 {
+		writeError(w, "The plan needs at least one search query", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if s.currentJob.Status != "awaiting_approval" || s.currentJob.Plan == nil {
+		s.mu.Unlock()
+		writeError(w, "No plan awaiting approval", http.StatusBadRequest)
+		return
+	}
+	if len(s.currentJob.Plan.SearchQueries) == 0 {
+		s.mu.Unlock()
+		writeError(w, "This plan has no search queries (simple mode generates them during research)", http.StatusBadRequest)
+		return
+	}
+	plan := *s.currentJob.Plan
+	plan.SearchQueries = queries
+	s.currentJob.Plan = &plan
+	s.mu.Unlock()
+	s.persistJob()
+
+	log.Printf("✏️ Plan queries edited: %d queries", len(queries))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.currentJob)
+}
+
+// handleAnswer answers clarifying questions by position and plans from the
+// answers: the questions of a job asked before planning (awaiting_answers, see
+// ResearchRequest.AskQuestions), where all blank plans without them, or those
+// of the plan awaiting approval, which is regenerated
+func (s *Server) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	status := s.currentJob.Status
+	req := s.currentJob.Config
+	questions := s.currentJob.Questions
+	var plan agent.ResearchPlan
+	if s.currentJob.Plan != nil {
+		plan = *s.currentJob.Plan
+	}
+	s.mu.RUnlock()
+
+	beforePlanning := status == "awaiting_answers"
+	if !beforePlanning {
+		questions = plan.ClarifyingQuestions
+	}
+	if !beforePlanning && status != "awaiting_approval" {
+		writeError(w, "No questions awaiting answers", http.StatusBadRequest)
+		return
+	}
+
+	var answerReq AnswerRequest
+	if !decodeJSON(w, r, &answerReq) {
+		return
+	}
+	if len(answerReq.Answers) > len(questions) {
+		writeError(w, fmt.Sprintf("Got %d answers for %d questions", len(answerReq.Answers), len(questions)), http.StatusBadRequest)
+		return
+	}
+
+	newAnswers := make([]agent.QuestionAnswer, len(answerReq.Answers))
+	for i, answer := range answerReq.Answers {
+		newAnswers[i] = agent.QuestionAnswer{Question: questions[i], Answer: answer}
+	}
+	answered := agent.MergeAnswers(nil, newAnswers) // Drops blank answers
+	if !beforePlanning && len(answered) == 0 && strings.TrimSpace(answerReq.Feedback) == "" {
+		writeError(w, "At least one answer is required", http.StatusBadRequest)
+		return
+	}
+	answers := agent.MergeAnswers(plan.Answers, answered)
+
+	// Update status back to planning
+	s.mu.Lock()
+	s.currentJob.Status = "planning"
+	s.currentJob.Questions = nil
+	s.currentJob.Plan = nil
+	s.mu.Unlock()
+
+	s.createPlanWithFeedback(req, answers, answerReq.Feedback)
+
+	// Return updated job
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.currentJob)
+}
+
+// createPlanWithFeedback generates a new plan incorporating the user's answers and feedback
+func (s *Server) createPlanWithFeedback(req ResearchRequest, answers []agent.QuestionAnswer, feedback string) {
+	researcher := s.researcher
+	if researcher == nil {
+		s.setError("Researcher not initialized")
+		return
+	}
+
+	// Emit planning event
+	s.onProgress(agent.ProgressEvent{
+		Phase:   "planning",
+		Message: "Revising research plan with your feedback...",
+		Percent: 2,
+	})
+
+	// Create plan with feedback as hint (cancellable via /api/cancel)
+	ctx, cancel := s.planningContext()
+	defer cancel()
+
+	plan, err := researcher.CreatePlanWithAnswers(ctx, req.Topic, answers, feedback)
+
+	if ctx.Err() != nil {
+		return // Cancelled - handleCancel already reset the job
+	}
+	if err != nil {
+		s.setError(fmt.Sprintf("Failed to revise plan: %v", err))
+		return
+	}
+
+	// Update job with new plan
+	s.mu.Lock()
+	s.currentJob.Plan = &plan
+	s.currentJob.Status = "awaiting_approval"
+	s.mu.Unlock()
+	s.persistJob()
+
+	s.onProgress(agent.ProgressEvent{
+		Phase:   "awaiting_approval",
+		Message: fmt.Sprintf("Revised plan ready with %d search queries. Awaiting approval.", len(plan.SearchQueries)),
+		Percent: 5,
+	})
+}
+
+// handleCancel cancels planning, or stops running research and discards it
+// (no report is written; see handleFinish)
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	status := s.currentJob.Status
+	cancelFunc := s.cancelFunc
+	s.mu.RUnlock()
+
+	if status == "running" && cancelFunc != nil {
+		s.mu.Lock()
+		if s.currentJob.Status != "running" || s.cancelFunc == nil {
+			// The run ended (or was cancelled) since the status was read
+			s.mu.Unlock()
+			writeError(w, "Nothing to cancel", http.StatusBadRequest)
+			return
+		}
+		// Abort searching and any report being written; executeResearch resets the job
+		s.cancelFunc(agent.ErrDiscarded)
+		s.currentJob.Status = "cancelled"
+		s.mu.Unlock()
+		s.persistJob()
+
+		s.onProgress(agent.ProgressEvent{
+			Phase:   "cancelling",
+			Message: "Cancelling research (nothing will be saved)...",
+			Percent: 0,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(StatusResponse{Status: "cancelling"})
+		return
+	}
+
+	if status == "awaiting_approval" || status == "awaiting_answers" || status == "planning" {
+		// Abort any in-flight planning call
+		if cancelFunc != nil {
+			cancelFunc(nil)
+		}
+
+		// Record the cancellation, then reset to idle
+		s.mu.Lock()
+		s.currentJob.Status = "cancelled"
+		s.mu.Unlock()
+		s.persistJob()
+
+		s.mu.Lock()
+		s.currentJob = &ResearchJob{Status: "idle"}
+		s.researcher = nil
+		s.cancelFunc = nil
+		s.mu.Unlock()
+		go s.startNextQueued()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(StatusResponse{Status: "cancelled"})
+		return
+	}
+
+	writeError(w, "Nothing to cancel", http.StatusBadRequest)
+}
+
+// handleFinish stops running research's searching now and has the report
+// written from what was gathered, with the full pipeline (see agent.Finish)
+func (s *Server) handleFinish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	status := s.currentJob.Status
+	researcher := s.researcher
+	s.mu.RUnlock()
+
+	if status != "running" || researcher == nil {
+		writeError(w, "Nothing to finish", http.StatusBadRequest)
+		return
+	}
+	if !researcher.Finish() {
+		writeError(w, "The report is already being written", http.StatusConflict)
+		return
+	}
+
+	s.onProgress(agent.ProgressEvent{
+		Phase:   "finishing",
+		Message: "Stopping the search and writing the report from what was gathered...",
+		Percent: 85,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StatusResponse{Status: "finishing"})
+}
+
+// handleReset clears the current job state (useful after errors)
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	status := s.currentJob.Status
+	s.mu.RUnlock()
+
+	// Only allow reset from error, complete, or idle states
+	if status == "running" || status == "planning" {
+		writeError(w, "Cannot reset while research is in progress", http.StatusConflict)
+		return
+	}
+
+	s.mu.Lock()
+	s.currentJob = &ResearchJob{Status: "idle"}
+	s.researcher = nil
+	s.cancelFunc = nil
+	s.mu.Unlock()
+	go s.startNextQueued()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StatusResponse{Status: "idle"})
+}
+
+// planningContext creates a cancellable context for plan generation and registers
+// its cancel func so /api/cancel can abort the in-flight LLM call
+func (s *Server) planningContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.mu.Lock()
+	s.cancelFunc = cancel
+	s.mu.Unlock()
+	return ctx, func() { cancel(nil) }
+}
+
+// executeResearch runs the research with cancellation support
+func (s *Server) executeResearch(ctx context.Context, researcher *agent.DeepResearcher, topic string, plan agent.ResearchPlan, simpleMode bool) {
+	defer s.research.Done()
+	defer s.startNextQueued()
+
+	var result agent.ResearchResult
+	var err error
+
+	if simpleMode {
+		result, err = researcher.RunWithContext(ctx, topic, plan)
+	} else {
+		result, err = researcher.RunExhaustiveWithContext(ctx, topic, plan)
+	}
+
+	if err != nil {
+		// Cancelled and discarded: nothing is saved, the next job can start
+		if errors.Is(context.Cause(ctx), agent.ErrDiscarded) {
+			s.discardJob()
+			return
+		}
+		// Cancelled before anything could be reported (e.g. during report writing)
+		if ctx.Err() == context.Canceled && result.Report == "" {
+			s.setError("Research cancelled before a report could be written")
+			return
+		}
+		// Check if it was a cancellation
+		if ctx.Err() == context.Canceled {
+			// Cancellation already handled, result should contain partial report
+			s.mu.Lock()
+			s.currentJob.Status = "complete"
+			s.currentJob.Result = &result
+			s.mu.Unlock()
+			s.persistResult(result)
+			s.saveReport(result)
+
+			s.onProgress(agent.ProgressEvent{
+				Phase:     "complete",
+				Message:   fmt.Sprintf("Partial report generated with %d sources (search was cancelled).", len(result.Sources)),
+				Percent:   100,
+				URLsFound: len(result.Sources),
+			})
+			s.notifyJob()
+			return
+		}
+		s.setError(fmt.Sprintf("Research failed: %v", err))
+		return
+	}
+
+	// Complete (a cancelled run returns its partial report without an error)
+	s.mu.Lock()
+	s.currentJob.Status = "complete"
+	s.currentJob.Result = &result
+	s.mu.Unlock()
+	s.persistResult(result)
+	s.saveReport(result)
+
+	message := fmt.Sprintf("Research complete! Found %d sources.", len(result.Sources))
+	if ctx.Err() == context.Canceled {
+		message = fmt.Sprintf("Partial report generated with %d sources (search was cancelled).", len(result.Sources))
+	}
+	s.onProgress(agent.ProgressEvent{
+		Phase:     "complete",
+		Message:   message,
+		Percent:   100,
+		URLsFound: len(result.Sources),
+	})
+	s.notifyJob()
+}
+
+// discardJob records the current job as cancelled, without a result, and
+// resets to idle
+func (s *Server) discardJob() {
+	s.mu.Lock()
+	s.currentJob.Status = "cancelled"
+	s.mu.Unlock()
+	s.persistJob()
+
+	s.onProgress(agent.ProgressEvent{
+		Phase:   "cancelled",
+		Message: "Research cancelled; nothing was saved.",
+		Percent: 0,
+	})
+
+	s.mu.Lock()
+	s.currentJob = &ResearchJob{Status: "idle"}
+	s.researcher = nil
+	s.cancelFunc = nil
+	s.mu.Unlock()
+}
+
+// onProgress handles progress events from the agent
+func (s *Server) onProgress(event agent.ProgressEvent) {
+	s.mu.Lock()
+	if event.Phase != agent.PhaseReportChunk {
+		s.currentJob.Progress = event // Report chunks aren't progress; the job stays "writing_report"
+	}
+	jobID := s.currentJob.ID
+	s.mu.Unlock()
+
+	if s.store != nil && jobID != "" {
+		if err := s.store.AddProgressEvent(jobID, event); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+
+	// Log and broadcast to SSE and WebSocket clients
+	s.events.append(jobID, event)
+}
+
+// setError sets the job to error state
+func (s *Server) setError(errMsg string) {
+	s.mu.Lock()
+	s.currentJob.Status = "error"
+	s.currentJob.Error = errMsg
+	s.mu.Unlock()
+	s.persistJob()
+
+	s.onProgress(agent.ProgressEvent{
+		Phase:   "error",
+		Message: errMsg,
+		Percent: 0,
+	})
+	s.notifyJob()
+	go s.startNextQueued()
+}
+
+// handleStatus returns current job status
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.currentJob)
+}
+
+// handleProgress provides SSE stream for real-time progress. It replays the
+// job's event log first: all of it, or what follows the Last-Event-ID header
+// (?since=<seq>) when reconnecting. ?id=<job> follows a job other than the current one.
+// The report streamed while it is written arrives as "report_chunk" events.
+func (s *Server) handleProgress(w http.ResponseWriter, r *http.Request) {
+	since := r.Header.Get("Last-Event-ID")
+	if q := r.URL.Query().Get("since"); q != "" {
+		since = q
+	}
+	seq, _ := strconv.Atoi(since)
+
+	// Set SSE headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	noDeadline(w)
+
+	s.followEvents(r.Context(), r.URL.Query().Get("id"), seq, func(ev jobEvent) error {
+		data, _ := json.Marshal(ev)
+		if ev.Seq > 0 {
+			fmt.Fprintf(w, "id: %d\n", ev.Seq)
+		}
+		if ev.Phase == agent.PhaseReportChunk {
+			fmt.Fprintf(w, "event: %s\n", agent.PhaseReportChunk)
+		}
+		_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+		w.(http.Flusher).Flush()
+		return err
+	})
+}
+
+// handleWebSocket streams progress over a WebSocket (/api/ws?id=<job>&since=<seq>).
+// Like the SSE stream it replays the job's event log before sending live events,
+// so a client that reconnects with the last seq it saw misses nothing.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	seq, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	jobID := r.URL.Query().Get("id")
+	noDeadline(w)
+
+	// No Handshake: any origin may connect, matching the SSE stream's CORS header
+	websocket.Server{Handler: func(conn *websocket.Conn) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		// Clients only send control frames; reading handles them and notices disconnects
+		go func() {
+			io.Copy(io.Discard, conn)
+			cancel()
+		}()
+
+		s.followEvents(ctx, jobID, seq, func(ev jobEvent) error {
+			return websocket.JSON.Send(conn, ev)
+		})
+	}}.ServeHTTP(w, r)
+}
+
+// noDeadline lifts the server's read and write timeouts from a long-lived
+// stream's connection (they stay on it after a WebSocket hijacks it)
+func noDeadline(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+}
+
+// handleResults returns the research results (of the current job, or of ?id=<job>)
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, result, ok := s.jobResult(w, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// jobResult returns the topic and results of the current job (id "" or the
+// current job's ID) or of a persisted job, writing the HTTP error itself when
+// there are none
+func (s *Server) jobResult(w http.ResponseWriter, id string) (string, *agent.ResearchResult, bool) {
+	var topic string
+	var result *agent.ResearchResult
+	s.mu.RLock()
+	current := id == "" || id == s.currentJob.ID
+	if current {
+		topic, result = s.currentJob.Topic, s.currentJob.Result
+	}
+	s.mu.RUnlock()
+
+	if !current {
+		job, ok := s.loadJob(w, id)
+		if !ok {
+			return "", nil, false
+		}
+		topic, result = job.Topic, job.Result
+	}
+	if result == nil {
+		writeError(w, "No results available", http.StatusNotFound)
+		return "", nil, false
+	}
+	return topic, result, true
+}
+
+// handlePartial writes (or returns the cached) draft report of the running job
+// from what it has gathered so far (GET /api/results/partial)
+func (s *Server) handlePartial(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	status, researcher := s.currentJob.Status, s.researcher
+	s.mu.RUnlock()
+
+	if status != "running" || researcher == nil {
+		writeError(w, "No research running", http.StatusConflict)
+		return
+	}
+
+	draft, err := researcher.DraftReport(r.Context())
+	if errors.Is(err, agent.ErrNoDraft) {
+		writeError(w, "Nothing gathered yet - try again after the first round", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+// handleExport downloads the report as md, html, or pdf, its sources and
+// extracted records as csv or xlsx, or its knowledge graph as dot or graphml
+// (GET /api/results/export?format=html, optionally &id=<job>; or GET /api/jobs/{id}/export)
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := report.FormatMarkdown
+	if f := r.URL.Query().Get("format"); f != "" {
+		var err error
+		if format, err = report.ParseFormat(f); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	topic, result, ok := s.jobResult(w, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	data, err := report.Render(format, topic, *result)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := exportFilename(topic) + "." + format.Extension()
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(data)
+}
+
+// handleFollowUp answers a question about the current job's report, or another
+// job's (POST /api/followup with "id" or ?id=<job>, or POST /api/jobs/{id}/followup). The current job's researcher still has its round
+// summaries; a persisted job is answered from its report and sources.
+func (s *Server) handleFollowUp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req FollowUpRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Question) == "" {
+		writeError(w, "Question is required", http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" {
+		req.ID = r.URL.Query().Get("id")
+	}
+	maxSearches := agent.DefaultFollowUpSearches
+	if req.MaxSearches != nil {
+		maxSearches = max(*req.MaxSearches, 0)
+	}
+
+	var result *agent.ResearchResult
+	var researcher *agent.DeepResearcher
+	s.mu.RLock()
+	if req.ID == "" || req.ID == s.currentJob.ID {
+		result, researcher = s.currentJob.Result, s.researcher
+	}
+	s.mu.RUnlock()
+
+	if req.ID != "" && result == nil {
+		job, ok := s.loadJob(w, req.ID)
+		if !ok {
+			return
+		}
+		var config ResearchRequest
+		if len(job.Config) > 0 {
+			if err := json.Unmarshal(job.Config, &config); err != nil {
+				writeError(w, "Invalid job config: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		var err error
+		if researcher, err = s.newResearcher(config, "", "", nil); err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = job.Result
+	}
+	if result == nil || researcher == nil {
+		writeError(w, "No results available", http.StatusNotFound)
+		return
+	}
+
+	answer, err := researcher.FollowUpWithSearches(r.Context(), *result, req.Question, maxSearches)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(answer)
+}
+
+// handleDiff compares two finished jobs (POST /api/diff): the sources only one
+// of them found, the extracted values that changed, and what's new in the later
+// one, summarized by the LLM unless noSummary is set
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DiffRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Earlier == "" || req.Later == "" {
+		writeError(w, "earlier and later job ids are required", http.StatusBadRequest)
+		return
+	}
+
+	earlier, ok := s.loadJob(w, req.Earlier)
+	if !ok {
+		return
+	}
+	later, ok := s.loadJob(w, req.Later)
+	if !ok {
+		return
+	}
+	if earlier.Result == nil || later.Result == nil {
+		writeError(w, "Both jobs need results", http.StatusConflict)
+		return
+	}
+
+	diff := agent.DiffResults(*earlier.Result, *later.Result)
+	if !req.NoSummary {
+		var config ResearchRequest
+		if len(later.Config) > 0 {
+			if err := json.Unmarshal(later.Config, &config); err != nil {
+				writeError(w, "Invalid job config: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		researcher, err := s.newResearcher(config, "", "", nil)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if diff, err = researcher.Diff(r.Context(), later.Topic, *earlier.Result, *later.Result); err != nil {
+			log.Printf("⚠️ Diff summary failed, listing the differences only: %v", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// exportFilename turns a topic into a download-safe base filename
+func exportFilename(topic string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(topic) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			sb.WriteRune(r)
+		case r == ' ' || r == '_':
+			sb.WriteRune('_')
+		}
+	}
+	name := strings.Trim(sb.String(), "_")
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	if name == "" {
+		name = "research-report"
+	}
+	return name
+}
+
+// handleJobs lists all persisted jobs, most recent first
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.store == nil {
+		writeError(w, "Job persistence is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	jobs, err := s.store.ListJobs(store.JobFilter{Query: query.Get("query"), Tag: query.Get("tag")})
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// handleCollections lists the topic collections, most recently run first
+func (s *Server) handleCollections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.store == nil {
+		writeError(w, "Job persistence is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	collections, err := s.store.ListCollections()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collections)
+}
+
+// handleJob returns a single persisted job (GET /api/jobs/{id})
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, ok := s.loadJob(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleJobTags replaces a persisted job's tags (PUT /api/jobs/{id}/tags with
+// {"tags": [...]}) and returns them normalized
+func (s *Server) handleJobTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.store == nil {
+		writeError(w, "Job persistence is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req TagsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	tags, err := s.store.SetTags(r.PathValue("id"), req.Tags)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TagsRequest{Tags: tags})
+}
+
+// loadJob fetches a job from the store, writing the HTTP error itself on failure
+func (s *Server) loadJob(w http.ResponseWriter, id string) (*store.Job, bool) {
+	if s.store == nil {
+		writeError(w, "Job persistence is disabled", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	job, err := s.store.GetJob(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, "Job not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return job, true
+}
+
+// persistJob saves a snapshot of the current job's metadata and plan to the store
+func (s *Server) persistJob() {
+	s.mu.RLock()
+	current := s.currentJob
+	s.mu.RUnlock()
+	s.saveJob(current)
+}
+
+// saveJob saves a snapshot of a job's metadata and plan to the store
+func (s *Server) saveJob(j *ResearchJob) {
+	if s.store == nil {
+		return
+	}
+
+	s.mu.RLock()
+	job := store.Job{
+		ID:        j.ID,
+		Topic:     j.Topic,
+		Status:    j.Status,
+		Error:     j.Error,
+		Plan:      j.Plan,
+		StartedAt: j.StartedAt,
+	}
+	config, _ := json.Marshal(j.Config)
+	s.mu.RUnlock()
+
+	if job.ID == "" {
+		return
+	}
+	job.Config = config
+	if err := s.store.SaveJob(job); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+// persistResult saves the final result and status of the current job to the
+// store, and adds its sources to the job's collection
+func (s *Server) persistResult(result agent.ResearchResult) {
+	if s.store == nil {
+		return
+	}
+
+	s.mu.RLock()
+	jobID := s.currentJob.ID
+	collection := s.currentJob.Config.Collection
+	s.mu.RUnlock()
+
+	if err := s.store.SaveResult(jobID, result); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	s.persistJob()
+
+	if collection != "" {
+		knowledge, err := s.store.Knowledge(collection)
+		if err == nil {
+			err = s.store.SaveKnowledge(knowledge.Update(result, jobID, time.Now()))
+		}
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+}
+
+// defaultContextLen is the context window of requests that don't set contextLen
+func (s *Server) defaultContextLen() int {
+	if s.contextLen > 0 {
+		return s.contextLen
+	}
+	return defaultContextLen
+}
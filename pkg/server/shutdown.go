@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"deep-research/pkg/agent"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Shutdown stops the server from taking new jobs and winds down the current
+// one: running research is cancelled so it writes a partial report, which is
+// saved to the job database and to results/<job id>.md. Queued jobs and plans
+// that were never approved are marked interrupted. Progress streams are closed
+// once the report is done, or when ctx ends first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		return nil
+	}
+	s.shuttingDown = true
+	job := s.currentJob
+	status := job.Status
+	cancelFunc := s.cancelFunc
+	queued := s.queue
+	s.queue = nil
+	for _, q := range queued {
+		q.Status = "interrupted"
+	}
+	s.mu.Unlock()
+	defer close(s.closing)
+
+	for _, q := range queued {
+		s.saveJob(q)
+	}
+	if len(queued) > 0 {
+		log.Printf("⏹️ Marked %d queued job(s) as interrupted", len(queued))
+	}
+
+	switch status {
+	case "planning", "awaiting_answers", "awaiting_approval":
+		if cancelFunc != nil {
+			cancelFunc(nil)
+		}
+		s.mu.Lock()
+		job.Status = "interrupted"
+		s.mu.Unlock()
+		s.persistJob()
+		log.Printf("⏹️ Marked job %s as interrupted (plan not approved)", job.ID)
+		return nil
+
+	case "running", "cancelled":
+		if status == "running" && cancelFunc != nil {
+			cancelFunc(nil)
+			s.mu.Lock()
+			job.Status = "cancelled"
+			s.mu.Unlock()
+			s.persistJob()
+
+			s.onProgress(agent.ProgressEvent{
+				Phase:   "cancelling",
+				Message: "Server shutting down - generating partial report...",
+				Percent: 85,
+			})
+		}
+		log.Printf("⏳ Waiting for job %s to write its partial report...", job.ID)
+
+	default:
+		return nil
+	}
+
+	// Wait for executeResearch to finish writing and persisting the report
+	done := make(chan struct{})
+	go func() {
+		s.research.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("job %s did not finish its report before shutdown: %w", job.ID, ctx.Err())
+	}
+
+	// executeResearch saved the report to results/ (see Server.saveReport)
+	s.mu.RLock()
+	result := job.Result
+	s.mu.RUnlock()
+	if result == nil {
+		return errors.New("job " + job.ID + " ended without a report")
+	}
+	return nil
+}
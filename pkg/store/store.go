@@ -0,0 +1,603 @@
+package store
+
+import (
+	"database/sql"
+	"deep-research/pkg/agent"
+	"deep-research/pkg/document"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver (no cgo)
+)
+
+// ErrNotFound is returned when a job does not exist in the store
+var ErrNotFound = errors.New("job not found")
+
+// ErrDocumentNotFound is returned when an uploaded document does not exist in the store
+var ErrDocumentNotFound = errors.New("document not found")
+
+// Job is a persisted research job
+type Job struct {
+	ID        string                `json:"id"`
+	Topic     string                `json:"topic"`
+	Status    string                `json:"status"`
+	Error     string                `json:"error,omitempty"`
+	Config    json.RawMessage       `json:"config,omitempty"`
+	Plan      *agent.ResearchPlan   `json:"plan,omitempty"`
+	Progress  *agent.ProgressEvent  `json:"progress,omitempty"`
+	Result    *agent.ResearchResult `json:"result,omitempty"`
+	Tags      []string              `json:"tags,omitempty"` // Labels set with SetTags
+	StartedAt time.Time             `json:"startedAt"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// JobSummary is the lightweight view of a job used for listings
+type JobSummary struct {
+	ID              string    `json:"id"`
+	Topic           string    `json:"topic"`
+	Status          string    `json:"status"`
+	SourceCount     int       `json:"sourceCount"`
+	HasReport       bool      `json:"hasReport"`
+	Tags            []string  `json:"tags"`
+	DurationSeconds int       `json:"durationSeconds"` // From start to the last update (the finish, for finished jobs)
+	StartedAt       time.Time `json:"startedAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// JobFilter narrows ListJobs; the zero value lists every job
+type JobFilter struct {
+	Query string // Words that must all appear in the topic, a tag, or the report (case-insensitive)
+	Tag   string // Only jobs with this tag
+}
+
+// maxTagLength caps a job tag's length, in characters
+const maxTagLength = 40
+
+// Store persists jobs, plans, progress events, sources, and reports in SQLite,
+// plus the knowledge base of each topic collection (see agent.Knowledge)
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id         TEXT PRIMARY KEY,
+	topic      TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	error      TEXT NOT NULL DEFAULT '',
+	config     TEXT,
+	plan       TEXT,
+	started_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS progress_events (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_id     TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+	event      TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_progress_job ON progress_events(job_id);
+CREATE TABLE IF NOT EXISTS sources (
+	job_id   TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	title    TEXT NOT NULL,
+	url      TEXT NOT NULL,
+	PRIMARY KEY (job_id, position)
+);
+CREATE TABLE IF NOT EXISTS reports (
+	job_id TEXT PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+	report TEXT NOT NULL,
+	result TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS collections (
+	name     TEXT PRIMARY KEY,
+	last_run TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS collection_sources (
+	collection TEXT NOT NULL REFERENCES collections(name) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	url        TEXT NOT NULL,
+	title      TEXT NOT NULL,
+	summary    TEXT NOT NULL DEFAULT '',
+	record     TEXT,
+	first_seen TIMESTAMP NOT NULL,
+	last_seen  TIMESTAMP NOT NULL,
+	job_id     TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (collection, position)
+);
+CREATE TABLE IF NOT EXISTS job_tags (
+	job_id TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+	tag    TEXT NOT NULL,
+	PRIMARY KEY (job_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_job_tags_tag ON job_tags(tag);
+CREATE TABLE IF NOT EXISTS documents (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	text       TEXT NOT NULL,
+	words      INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+`
+
+// Open opens (or creates) the SQLite database at path and applies the schema
+func Open(path string) (*Store, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveJob inserts or updates a job's metadata, config, and plan
+func (s *Store) SaveJob(job Job) error {
+	var planJSON []byte
+	if job.Plan != nil {
+		var err error
+		if planJSON, err = json.Marshal(job.Plan); err != nil {
+			return fmt.Errorf("failed to marshal plan: %w", err)
+		}
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO jobs (id, topic, status, error, config, plan, started_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			topic = excluded.topic,
+			status = excluded.status,
+			error = excluded.error,
+			config = excluded.config,
+			plan = excluded.plan,
+			updated_at = excluded.updated_at`,
+		job.ID, job.Topic, job.Status, job.Error, nullableText(job.Config), nullableText(planJSON), job.StartedAt, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// AddProgressEvent appends a progress event to a job's event log
+func (s *Store) AddProgressEvent(jobID string, event agent.ProgressEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress event: %w", err)
+	}
+	if _, err := s.db.Exec(`INSERT INTO progress_events (job_id, event, created_at) VALUES (?, ?, ?)`,
+		jobID, string(data), time.Now()); err != nil {
+		return fmt.Errorf("failed to save progress event: %w", err)
+	}
+	return nil
+}
+
+// ProgressEvents returns a job's progress events in emission order
+func (s *Store) ProgressEvents(jobID string) ([]agent.ProgressEvent, error) {
+	rows, err := s.db.Query(`SELECT event FROM progress_events WHERE job_id = ? ORDER BY id`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query progress events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []agent.ProgressEvent
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan progress event: %w", err)
+		}
+		var event agent.ProgressEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse progress event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// SaveResult stores the final report and sources of a job
+func (s *Store) SaveResult(jobID string, result agent.ResearchResult) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM sources WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("failed to clear sources: %w", err)
+	}
+	for i, src := range result.Sources {
+		if _, err := tx.Exec(`INSERT INTO sources (job_id, position, title, url) VALUES (?, ?, ?, ?)`,
+			jobID, i, src.Title, src.URL); err != nil {
+			return fmt.Errorf("failed to save source: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO reports (job_id, report, result) VALUES (?, ?, ?)
+		ON CONFLICT(job_id) DO UPDATE SET report = excluded.report, result = excluded.result`,
+		jobID, result.Report, string(resultJSON)); err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListJobs returns the jobs matching filter, most recent first
+func (s *Store) ListJobs(filter JobFilter) ([]JobSummary, error) {
+	query := `
+		SELECT j.id, j.topic, j.status, j.started_at, j.updated_at,
+			(SELECT COUNT(*) FROM sources src WHERE src.job_id = j.id),
+			EXISTS(SELECT 1 FROM reports r WHERE r.job_id = j.id),
+			COALESCE((SELECT group_concat(t.tag, char(10)) FROM (SELECT tag FROM job_tags WHERE job_id = j.id ORDER BY tag) t), '')
+		FROM jobs j
+		WHERE 1 = 1`
+	var args []any
+	if tag := NormalizeTag(filter.Tag); tag != "" {
+		query += ` AND EXISTS(SELECT 1 FROM job_tags t WHERE t.job_id = j.id AND t.tag = ?)`
+		args = append(args, tag)
+	}
+	for _, word := range strings.Fields(filter.Query) {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(word)) + "%"
+		query += ` AND (lower(j.topic) LIKE ? ESCAPE '\'
+			OR EXISTS(SELECT 1 FROM job_tags t WHERE t.job_id = j.id AND t.tag LIKE ? ESCAPE '\')
+			OR EXISTS(SELECT 1 FROM reports r WHERE r.job_id = j.id AND lower(r.report) LIKE ? ESCAPE '\'))`
+		args = append(args, pattern, pattern, pattern)
+	}
+	query += ` ORDER BY j.started_at DESC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]JobSummary, 0)
+	for rows.Next() {
+		var j JobSummary
+		var tags string
+		if err := rows.Scan(&j.ID, &j.Topic, &j.Status, &j.StartedAt, &j.UpdatedAt, &j.SourceCount, &j.HasReport, &tags); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		j.Tags = make([]string, 0)
+		if tags != "" {
+			j.Tags = strings.Split(tags, "\n")
+		}
+		j.DurationSeconds = int(j.UpdatedAt.Sub(j.StartedAt).Seconds())
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards in a search word
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// NormalizeTag trims a tag, lowercases it, and joins its words with hyphens,
+// so "Q3 Review" and "q3-review" are the same tag
+func NormalizeTag(tag string) string {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if runes := []rune(tag); len(runes) > maxTagLength {
+		tag = string(runes[:maxTagLength])
+	}
+	return tag
+}
+
+// SetTags replaces a job's tags (normalized with NormalizeTag; empty ones are
+// dropped) and returns them sorted
+func (s *Store) SetTags(jobID string, tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = NormalizeTag(tag); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM jobs WHERE id = ?)`, jobID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM job_tags WHERE job_id = ?`, jobID); err != nil {
+		return nil, fmt.Errorf("failed to clear tags: %w", err)
+	}
+	for _, tag := range normalized {
+		if _, err := tx.Exec(`INSERT INTO job_tags (job_id, tag) VALUES (?, ?)`, jobID, tag); err != nil {
+			return nil, fmt.Errorf("failed to save tag: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to save tags: %w", err)
+	}
+	return normalized, nil
+}
+
+// Tags returns a job's tags, sorted
+func (s *Store) Tags(jobID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT tag FROM job_tags WHERE job_id = ? ORDER BY tag`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetJob loads a job with its plan, latest progress event, and result (if any)
+func (s *Store) GetJob(id string) (*Job, error) {
+	var job Job
+	var config, plan sql.NullString
+	err := s.db.QueryRow(`SELECT id, topic, status, error, config, plan, started_at, updated_at FROM jobs WHERE id = ?`, id).
+		Scan(&job.ID, &job.Topic, &job.Status, &job.Error, &config, &plan, &job.StartedAt, &job.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+
+	if config.Valid {
+		job.Config = json.RawMessage(config.String)
+	}
+	if plan.Valid {
+		job.Plan = &agent.ResearchPlan{}
+		if err := json.Unmarshal([]byte(plan.String), job.Plan); err != nil {
+			return nil, fmt.Errorf("failed to parse plan: %w", err)
+		}
+	}
+
+	var lastEvent string
+	err = s.db.QueryRow(`SELECT event FROM progress_events WHERE job_id = ? ORDER BY id DESC LIMIT 1`, id).Scan(&lastEvent)
+	if err == nil {
+		job.Progress = &agent.ProgressEvent{}
+		if err := json.Unmarshal([]byte(lastEvent), job.Progress); err != nil {
+			return nil, fmt.Errorf("failed to parse progress event: %w", err)
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load progress: %w", err)
+	}
+
+	var resultJSON string
+	err = s.db.QueryRow(`SELECT result FROM reports WHERE job_id = ?`, id).Scan(&resultJSON)
+	if err == nil {
+		job.Result = &agent.ResearchResult{}
+		if err := json.Unmarshal([]byte(resultJSON), job.Result); err != nil {
+			return nil, fmt.Errorf("failed to parse result: %w", err)
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load result: %w", err)
+	}
+
+	if job.Tags, err = s.Tags(id); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CollectionSummary is the lightweight view of a collection used for listings
+type CollectionSummary struct {
+	Name        string    `json:"name"`
+	SourceCount int       `json:"sourceCount"`
+	LastRun     time.Time `json:"lastRun"`
+}
+
+// Knowledge loads a collection's sources; a collection that doesn't exist yet
+// is returned empty, with a zero LastRun
+func (s *Store) Knowledge(collection string) (*agent.Knowledge, error) {
+	var lastRun time.Time
+	err := s.db.QueryRow(`SELECT last_run FROM collections WHERE name = ?`, collection).Scan(&lastRun)
+	if errors.Is(err, sql.ErrNoRows) {
+		return agent.NewKnowledge(collection, time.Time{}, nil), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load collection: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT url, title, summary, record, first_seen, last_seen, job_id
+		FROM collection_sources WHERE collection = ? ORDER BY position`, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collection sources: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []agent.KnownSource
+	for rows.Next() {
+		var src agent.KnownSource
+		var record sql.NullString
+		if err := rows.Scan(&src.URL, &src.Title, &src.Summary, &record, &src.FirstSeen, &src.LastSeen, &src.JobID); err != nil {
+			return nil, fmt.Errorf("failed to scan collection source: %w", err)
+		}
+		if record.Valid {
+			if err := json.Unmarshal([]byte(record.String), &src.Record); err != nil {
+				return nil, fmt.Errorf("failed to parse collection record: %w", err)
+			}
+		}
+		sources = append(sources, src)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return agent.NewKnowledge(collection, lastRun, sources), nil
+}
+
+// SaveKnowledge replaces a collection's sources with k's (see agent.Knowledge.Update)
+func (s *Store) SaveKnowledge(k *agent.Knowledge) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO collections (name, last_run) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET last_run = excluded.last_run`,
+		k.Collection, k.LastRun); err != nil {
+		return fmt.Errorf("failed to save collection: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM collection_sources WHERE collection = ?`, k.Collection); err != nil {
+		return fmt.Errorf("failed to clear collection sources: %w", err)
+	}
+	for i, src := range k.Sources {
+		var record []byte
+		if src.Record != nil {
+			if record, err = json.Marshal(src.Record); err != nil {
+				return fmt.Errorf("failed to marshal record: %w", err)
+			}
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO collection_sources (collection, position, url, title, summary, record, first_seen, last_seen, job_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			k.Collection, i, src.URL, src.Title, src.Summary, nullableText(record), src.FirstSeen, src.LastSeen, src.JobID); err != nil {
+			return fmt.Errorf("failed to save collection source: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListCollections returns all collections, most recently run first
+func (s *Store) ListCollections() ([]CollectionSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT c.name, c.last_run,
+			(SELECT COUNT(*) FROM collection_sources src WHERE src.collection = c.name)
+		FROM collections c
+		ORDER BY c.last_run DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := make([]CollectionSummary, 0)
+	for rows.Next() {
+		var c CollectionSummary
+		if err := rows.Scan(&c.Name, &c.LastRun, &c.SourceCount); err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// DocumentSummary is the lightweight view of an uploaded document used for listings
+type DocumentSummary struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Words     int       `json:"words"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SaveDocument stores an uploaded document's text under id
+func (s *Store) SaveDocument(id string, doc document.Document, at time.Time) (DocumentSummary, error) {
+	summary := DocumentSummary{ID: id, Name: doc.Name, Words: doc.Words(), CreatedAt: at}
+	_, err := s.db.Exec(`INSERT INTO documents (id, name, text, words, created_at) VALUES (?, ?, ?, ?, ?)`,
+		id, doc.Name, doc.Text, summary.Words, at)
+	if err != nil {
+		return DocumentSummary{}, fmt.Errorf("failed to save document: %w", err)
+	}
+	return summary, nil
+}
+
+// Document loads an uploaded document's text
+func (s *Store) Document(id string) (document.Document, error) {
+	var doc document.Document
+	err := s.db.QueryRow(`SELECT name, text FROM documents WHERE id = ?`, id).Scan(&doc.Name, &doc.Text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return document.Document{}, ErrDocumentNotFound
+	}
+	if err != nil {
+		return document.Document{}, fmt.Errorf("failed to load document: %w", err)
+	}
+	return doc, nil
+}
+
+// ListDocuments returns all uploaded documents, most recent first
+func (s *Store) ListDocuments() ([]DocumentSummary, error) {
+	rows, err := s.db.Query(`SELECT id, name, words, created_at FROM documents ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make([]DocumentSummary, 0)
+	for rows.Next() {
+		var d DocumentSummary
+		if err := rows.Scan(&d.ID, &d.Name, &d.Words, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
+
+// DeleteDocument removes an uploaded document
+func (s *Store) DeleteDocument(id string) error {
+	res, err := s.db.Exec(`DELETE FROM documents WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDocumentNotFound
+	}
+	return nil
+}
+
+// MarkInterrupted flags jobs left in an active state by a previous process as interrupted
+func (s *Store) MarkInterrupted() (int64, error) {
+	res, err := s.db.Exec(`
+		UPDATE jobs SET status = 'interrupted', updated_at = ?
+		WHERE status IN ('queued', 'planning', 'awaiting_answers', 'awaiting_approval', 'running')`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark interrupted jobs: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// nullableText converts empty byte slices to SQL NULL
+func nullableText(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}